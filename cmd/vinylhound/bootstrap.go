@@ -183,10 +183,6 @@ func ensureDemoAlbums(ctx context.Context, db *sql.DB) error {
 	}
 
 	for _, album := range albums {
-		tracksJSON, err := json.Marshal(album.Tracks)
-		if err != nil {
-			return fmt.Errorf("marshal tracks for %q: %w", album.Title, err)
-		}
 		genresJSON, err := json.Marshal(album.Genres)
 		if err != nil {
 			return fmt.Errorf("marshal genres for %q: %w", album.Title, err)
@@ -194,13 +190,22 @@ func ensureDemoAlbums(ctx context.Context, db *sql.DB) error {
 
 		var albumID int64
 		if err := tx.QueryRowContext(ctx, `
-			INSERT INTO albums (user_id, artist, title, release_year, tracks, genres, rating)
-			VALUES ($1, $2, $3, $4, $5::jsonb, $6::jsonb, $7)
+			INSERT INTO albums (user_id, artist, title, release_year, genres, rating)
+			VALUES ($1, $2, $3, $4, $5::jsonb, $6)
 			RETURNING id
-		`, userID, album.Artist, album.Title, album.Year, string(tracksJSON), string(genresJSON), album.Rating).Scan(&albumID); err != nil {
+		`, userID, album.Artist, album.Title, album.Year, string(genresJSON), album.Rating).Scan(&albumID); err != nil {
 			return fmt.Errorf("insert demo album %q: %w", album.Title, err)
 		}
 
+		for i, title := range album.Tracks {
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO songs (title, artist, album_id, disc_number, track_num)
+				VALUES ($1, $2, $3, 1, $4)
+			`, title, album.Artist, albumID, i+1); err != nil {
+				return fmt.Errorf("insert demo track %q: %w", title, err)
+			}
+		}
+
 		if !preferenceTableExists || (album.UserRating == nil && !album.Favorited) {
 			continue
 		}
@@ -3,10 +3,14 @@ package main
 import (
 	"errors"
 	"fmt"
+	"net/netip"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+
+	"vinylhound/shared/go/config"
 )
 
 // Config contains application-wide settings sourced from the environment.
@@ -14,8 +18,22 @@ type Config struct {
 	DatabaseURL         string
 	Addr                string
 	AllowedOrigins      []string
+	TrustedProxies      []netip.Prefix
 	SpotifyClientID     string
 	SpotifyClientSecret string
+	AppleMusicKeyID     string
+	AppleMusicTeamID    string
+	AppleMusicKeyFile   string
+	MusicBrainzContact  string
+	DiscogsToken        string
+	AccessTokenTTL      time.Duration
+	RefreshTokenTTL     time.Duration
+	PasswordResetOutbox string
+	MediaBackend        string
+	MediaDir            string
+	LoginProtection     config.LoginProtectionConfig
+	SessionStore        config.SessionStoreConfig
+	OIDCProviders       []config.OIDCProviderConfig
 }
 
 func loadConfig() (Config, error) {
@@ -30,12 +48,51 @@ func loadConfig() (Config, error) {
 
 	origins := parseAllowedOrigins(envOrDefault("CORS_ALLOWED_ORIGINS", "http://localhost:5173"))
 
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		return Config{}, err
+	}
+
+	security, err := config.LoadSecurity()
+	if err != nil {
+		return Config{}, err
+	}
+
+	loginProtection, err := config.LoadLoginProtection()
+	if err != nil {
+		return Config{}, err
+	}
+
+	sessionStore, err := config.LoadSessionStore()
+	if err != nil {
+		return Config{}, err
+	}
+
+	oidcProviders, err := config.LoadOIDCProviders()
+	if err != nil {
+		return Config{}, err
+	}
+
 	return Config{
 		DatabaseURL:         dsn,
 		Addr:                addr,
 		AllowedOrigins:      origins,
+		TrustedProxies:      trustedProxies,
 		SpotifyClientID:     os.Getenv("SPOTIFY_CLIENT_ID"),
 		SpotifyClientSecret: os.Getenv("SPOTIFY_CLIENT_SECRET"),
+		AppleMusicKeyID:     os.Getenv("APPLE_MUSIC_KEY_ID"),
+		AppleMusicTeamID:    os.Getenv("APPLE_MUSIC_TEAM_ID"),
+		AppleMusicKeyFile:   os.Getenv("APPLE_MUSIC_PRIVATE_KEY_FILE"),
+		MusicBrainzContact:  os.Getenv("MUSICBRAINZ_CONTACT"),
+		DiscogsToken:        os.Getenv("DISCOGS_TOKEN"),
+		AccessTokenTTL:      security.AccessTokenTTL,
+		RefreshTokenTTL:     security.RefreshTokenTTL,
+		PasswordResetOutbox: os.Getenv("PASSWORD_RESET_OUTBOX"),
+		MediaBackend:        envOrDefault("MEDIA_BACKEND", "fs"),
+		MediaDir:            envOrDefault("MEDIA_DIR", "data/media"),
+		LoginProtection:     loginProtection,
+		SessionStore:        sessionStore,
+		OIDCProviders:       oidcProviders,
 	}, nil
 }
 
@@ -57,3 +114,29 @@ func parseAllowedOrigins(raw string) []string {
 	}
 	return origins
 }
+
+// parseTrustedProxies reads a comma-separated list of proxy addresses and
+// CIDR ranges.
+func parseTrustedProxies(raw string) ([]netip.Prefix, error) {
+	var proxies []netip.Prefix
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if strings.Contains(part, "/") {
+			prefix, err := netip.ParsePrefix(part)
+			if err != nil {
+				return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+			}
+			proxies = append(proxies, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(part)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+		}
+		proxies = append(proxies, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return proxies, nil
+}
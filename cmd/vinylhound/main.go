@@ -21,12 +21,25 @@ func main() {
 	defer db.Close()
 
 	dataStore := store.New(db)
+	dataStore.SetSessionLifetimes(cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	dataStore.SetLoginProtection(cfg.LoginProtection)
+	if cfg.SessionStore.CacheSize > 0 {
+		dataStore.SetSessionStore(store.NewCachedSessionStore(
+			store.NewPostgresSessionStore(db), cfg.SessionStore.CacheSize, cfg.SessionStore.CacheTTL))
+	}
 
 	if err := bootstrapDemoData(context.Background(), db, dataStore); err != nil {
 		log.Fatal(err)
 	}
 
-	handler := newHTTPHandler(cfg, db, dataStore)
+	handler, err := newHTTPHandler(cfg, db, dataStore)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if cfg.SessionStore.SweepInterval > 0 {
+		go runSessionSweeper(context.Background(), dataStore, cfg.SessionStore.SweepInterval)
+	}
 
 	log.Printf("API available at http://localhost%v", cfg.Addr)
 	if err := http.ListenAndServe(cfg.Addr, handler); err != nil {
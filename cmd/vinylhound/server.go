@@ -2,38 +2,62 @@ package main
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"strings"
 
 	"vinylhound/internal/app/albums"
 	"vinylhound/internal/app/artists"
+	"vinylhound/internal/app/catalog"
 	"vinylhound/internal/app/collections"
 	"vinylhound/internal/app/concerts"
+	"vinylhound/internal/app/duplicates"
 	"vinylhound/internal/app/favorites"
+	"vinylhound/internal/app/genres"
+	"vinylhound/internal/app/identities"
+	"vinylhound/internal/app/lookup"
 	"vinylhound/internal/app/places"
 	"vinylhound/internal/app/playlists"
 	"vinylhound/internal/app/ratings"
 	"vinylhound/internal/app/songs"
 	"vinylhound/internal/app/users"
 	"vinylhound/internal/httpapi"
+	"vinylhound/internal/media"
 	"vinylhound/internal/musicapi"
+	"vinylhound/internal/notify"
+	"vinylhound/internal/oidc"
 	"vinylhound/internal/searchservice"
 	"vinylhound/internal/store"
 )
 
-func newHTTPHandler(cfg Config, db *sql.DB, dataStore *store.Store) http.Handler {
+func newHTTPHandler(cfg Config, db *sql.DB, dataStore *store.Store) (http.Handler, error) {
 	// Base services
-	userSvc := users.New(dataStore)
+	userSvc := users.New(dataStore, newNotifier(cfg))
 	albumSvc := albums.New(dataStore)
 	ratingsSvc := ratings.New(dataStore)
 	playlistSvc := playlists.New(dataStore)
 	favoritesSvc := favorites.New(dataStore)
+	artistSvc := artists.New(dataStore)
+	duplicatesSvc := duplicates.New(dataStore)
+	catalogSvc := catalog.New(dataStore)
+	genresSvc := genres.New(dataStore)
+	lookupSvc := lookup.New(dataStore)
+
+	// Cover art and photo storage
+	mediaSvc, err := newMediaService(cfg, dataStore)
+	if err != nil {
+		return nil, err
+	}
 
 	// Derived services
-	artistSvc := artists.New(albumSvc)
 	songSvc := songs.New(albumSvc, dataStore)
-	searchSvc := newSearchService(cfg, db, dataStore)
+	providers, err := newProviderRegistry(cfg)
+	if err != nil {
+		return nil, err
+	}
+	searchSvc := searchservice.NewService(db, providers, dataStore, mediaSvc)
 
 	// Place services
 	placesSvc := places.New(dataStore)
@@ -44,21 +68,153 @@ func newHTTPHandler(cfg Config, db *sql.DB, dataStore *store.Store) http.Handler
 	// Collection service
 	collectionsSvc := collections.New(dataStore)
 
-	return withCORS(cfg.AllowedOrigins, httpapi.New(userSvc, artistSvc, albumSvc, songSvc, ratingsSvc, playlistSvc, favoritesSvc, searchSvc, placesSvc, concertsSvc, collectionsSvc).Routes())
+	// External identity providers
+	identityProviders, err := newIdentityProviders(cfg)
+	if err != nil {
+		return nil, err
+	}
+	identitiesSvc := identities.New(dataStore, identityProviders)
+
+	api := httpapi.New(userSvc, artistSvc, albumSvc, songSvc, ratingsSvc, playlistSvc, favoritesSvc, searchSvc, placesSvc, concertsSvc, collectionsSvc, identitiesSvc, duplicatesSvc, catalogSvc, genresSvc, mediaSvc, lookupSvc)
+	api.SetTrustedProxies(cfg.TrustedProxies)
+	return withCORS(cfg.AllowedOrigins, api.Routes()), nil
+}
+
+// newIdentityProviders builds the OpenID Connect providers from OIDC_PROVIDERS.
+func newIdentityProviders(cfg Config) (*oidc.Registry, error) {
+	configs := make([]oidc.Config, 0, len(cfg.OIDCProviders))
+	for _, p := range cfg.OIDCProviders {
+		configs = append(configs, oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       p.Scopes,
+		})
+	}
+
+	registry, err := oidc.NewRegistry(configs, nil)
+	if err != nil {
+		return nil, err
+	}
+	if names := registry.Names(); len(names) > 0 {
+		log.Printf("OIDC sign-in enabled for: %s", strings.Join(names, ", "))
+	}
+	return registry, nil
+}
+
+// newNotifier picks the development sender for account messages: an outbox
+// file when PASSWORD_RESET_OUTBOX is set, otherwise the server log.
+func newNotifier(cfg Config) notify.Notifier {
+	if cfg.PasswordResetOutbox != "" {
+		log.Printf("Password reset messages written to %s", cfg.PasswordResetOutbox)
+		return notify.NewFileSender(cfg.PasswordResetOutbox)
+	}
+	return notify.NewLogSender(nil)
+}
+
+// newMediaService stores images in the backend named by MEDIA_BACKEND; "fs"
+// keeps them below MEDIA_DIR.
+func newMediaService(cfg Config, dataStore *store.Store) (*media.Service, error) {
+	switch cfg.MediaBackend {
+	case "fs":
+		backend, err := media.NewFSBackend(cfg.MediaDir)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Media stored in %s", cfg.MediaDir)
+		return media.New(dataStore, backend, nil), nil
+	default:
+		return nil, fmt.Errorf("unknown MEDIA_BACKEND %q", cfg.MediaBackend)
+	}
 }
 
-func newSearchService(cfg Config, db *sql.DB, dataStore *store.Store) *searchservice.Service {
-	var spotifyClient musicapi.MusicAPIClient
+// appVersion is sent in the User-Agent of MusicBrainz and Discogs requests.
+const appVersion = "1.0"
+
+// newProviderRegistry registers the music providers whose credentials are
+// configured; search and imports use only these.
+func newProviderRegistry(cfg Config) (*musicapi.Registry, error) {
+	registry := musicapi.NewRegistry()
 
-	// Initialize Spotify client if credentials are provided
 	if cfg.SpotifyClientID != "" && cfg.SpotifyClientSecret != "" {
-		spotifyClient = musicapi.NewSpotifyClient(cfg.SpotifyClientID, cfg.SpotifyClientSecret)
+		err := registry.Register(musicapi.ProviderInfo{
+			ID:           musicapi.ProviderSpotify,
+			Name:         "Spotify",
+			Capabilities: musicapi.CapSearch | musicapi.CapArtistAlbums | musicapi.CapISRCLookup | musicapi.CapBarcodeLookup,
+		}, musicapi.NewSpotifyClient(cfg.SpotifyClientID, cfg.SpotifyClientSecret))
+		if err != nil {
+			return nil, err
+		}
 		log.Println("Spotify client initialized")
 	} else {
 		log.Println("Spotify credentials not provided, Spotify search disabled")
 	}
 
-	return searchservice.NewService(db, spotifyClient, nil, dataStore)
+	if cfg.AppleMusicKeyID != "" && cfg.AppleMusicTeamID != "" && cfg.AppleMusicKeyFile != "" {
+		key, err := os.ReadFile(cfg.AppleMusicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read APPLE_MUSIC_PRIVATE_KEY_FILE: %w", err)
+		}
+		client, err := musicapi.NewAppleMusicClient(cfg.AppleMusicKeyID, cfg.AppleMusicTeamID, string(key))
+		if err != nil {
+			return nil, fmt.Errorf("apple music client: %w", err)
+		}
+		err = registry.Register(musicapi.ProviderInfo{
+			ID:           musicapi.ProviderAppleMusic,
+			Name:         "Apple Music",
+			Capabilities: musicapi.CapSearch | musicapi.CapArtistAlbums | musicapi.CapISRCLookup | musicapi.CapBarcodeLookup,
+		}, client)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("Apple Music client initialized")
+	} else {
+		log.Println("Apple Music credentials not provided, Apple Music search disabled")
+	}
+
+	// MusicBrainz needs no credentials but blocks clients that do not name
+	// a contact in their User-Agent.
+	if cfg.MusicBrainzContact != "" {
+		client, err := musicapi.NewMusicBrainzClient("Vinylhound", appVersion, cfg.MusicBrainzContact)
+		if err != nil {
+			return nil, err
+		}
+		err = registry.Register(musicapi.ProviderInfo{
+			ID:           musicapi.ProviderMusicBrainz,
+			Name:         "MusicBrainz",
+			Capabilities: musicapi.CapSearch | musicapi.CapArtistAlbums | musicapi.CapISRCLookup | musicapi.CapBarcodeLookup,
+		}, client)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("MusicBrainz client initialized")
+	} else {
+		log.Println("MUSICBRAINZ_CONTACT not provided, MusicBrainz search disabled")
+	}
+
+	// Discogs lists pressings, labels and catalog numbers; its search needs
+	// a personal access token.
+	if cfg.DiscogsToken != "" {
+		client, err := musicapi.NewDiscogsClient(cfg.DiscogsToken, "Vinylhound/"+appVersion)
+		if err != nil {
+			return nil, err
+		}
+		err = registry.Register(musicapi.ProviderInfo{
+			ID:           musicapi.ProviderDiscogs,
+			Name:         "Discogs",
+			Capabilities: musicapi.CapSearch | musicapi.CapArtistAlbums | musicapi.CapBarcodeLookup,
+		}, client)
+		if err != nil {
+			return nil, err
+		}
+		log.Println("Discogs client initialized")
+	} else {
+		log.Println("DISCOGS_TOKEN not provided, Discogs search disabled")
+	}
+
+	return registry, nil
 }
 
 func withCORS(allowedOrigins []string, next http.Handler) http.Handler {
@@ -80,8 +236,9 @@ func withCORS(allowedOrigins []string, next http.Handler) http.Handler {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 			w.Header().Set("Vary", "Origin")
 			w.Header().Set("Access-Control-Allow-Credentials", "true")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Authorization")
+			w.Header().Set("Access-Control-Expose-Headers", "Link, X-Next-Cursor")
 			w.Header().Set("Access-Control-Max-Age", "3600")
 		}
 
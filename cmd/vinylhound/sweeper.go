@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"vinylhound/internal/store"
+)
+
+// runSessionSweeper deletes expired sessions every interval until ctx is done.
+// The first sweep runs immediately so rows left by a long downtime go at
+// startup.
+func runSessionSweeper(ctx context.Context, dataStore *store.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := dataStore.DeleteExpiredSessions(ctx)
+		switch {
+		case err != nil:
+			log.Printf("session sweeper: %v", err)
+		case deleted > 0:
+			log.Printf("session sweeper: deleted %d expired sessions", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -3,23 +3,37 @@ package albums
 import (
 	"context"
 
+	"vinylhound/internal/queryspec"
 	"vinylhound/internal/store"
+	"vinylhound/shared/go/models"
 )
 
 // Store captures the persistence needs for album workflows.
 type Store interface {
 	CreateAlbum(token string, album store.Album) (store.Album, error)
-	AlbumsByToken(token string) ([]store.Album, error)
-	ListAlbums(filter store.AlbumFilter) ([]store.Album, error)
+	AlbumsByToken(token string, spec queryspec.Spec, page store.Page) ([]store.Album, string, error)
+	ListAlbums(filter store.AlbumFilter, spec queryspec.Spec, page store.Page) ([]store.Album, string, error)
 	AlbumByID(id int64) (store.Album, error)
+	UpdateAlbum(ctx context.Context, token string, id int64, patch store.AlbumPatch) (store.Album, error)
+	DeleteAlbum(ctx context.Context, token string, id int64) error
+	CreateRelease(ctx context.Context, token string, release models.Release) (models.Release, error)
+	UpdateRelease(ctx context.Context, token string, id int64, release models.Release) (models.Release, error)
+	ReleaseByID(ctx context.Context, id int64) (models.Release, error)
+	ReleasesByAlbum(ctx context.Context, albumID int64) ([]models.Release, error)
 }
 
 // Service coordinates album-related operations.
 type Service interface {
 	Create(ctx context.Context, token string, album store.Album) (store.Album, error)
-	ListByUser(ctx context.Context, token string) ([]store.Album, error)
-	List(ctx context.Context, filter store.AlbumFilter) ([]store.Album, error)
+	ListByUser(ctx context.Context, token string, spec queryspec.Spec, page store.Page) ([]store.Album, string, error)
+	List(ctx context.Context, filter store.AlbumFilter, spec queryspec.Spec, page store.Page) ([]store.Album, string, error)
 	Get(ctx context.Context, id int64) (store.Album, error)
+	Update(ctx context.Context, token string, id int64, patch store.AlbumPatch) (store.Album, error)
+	Delete(ctx context.Context, token string, id int64) error
+	CreateRelease(ctx context.Context, token string, release models.Release) (models.Release, error)
+	UpdateRelease(ctx context.Context, token string, id int64, release models.Release) (models.Release, error)
+	GetRelease(ctx context.Context, id int64) (models.Release, error)
+	ListReleases(ctx context.Context, albumID int64) ([]models.Release, error)
 }
 
 type service struct {
@@ -38,18 +52,18 @@ func (s *service) Create(ctx context.Context, token string, album store.Album) (
 	return s.store.CreateAlbum(token, album)
 }
 
-func (s *service) ListByUser(ctx context.Context, token string) ([]store.Album, error) {
+func (s *service) ListByUser(ctx context.Context, token string, spec queryspec.Spec, page store.Page) ([]store.Album, string, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return s.store.AlbumsByToken(token)
+	return s.store.AlbumsByToken(token, spec, page)
 }
 
-func (s *service) List(ctx context.Context, filter store.AlbumFilter) ([]store.Album, error) {
+func (s *service) List(ctx context.Context, filter store.AlbumFilter, spec queryspec.Spec, page store.Page) ([]store.Album, string, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return s.store.ListAlbums(filter)
+	return s.store.ListAlbums(filter, spec, page)
 }
 
 func (s *service) Get(ctx context.Context, id int64) (store.Album, error) {
@@ -58,3 +72,45 @@ func (s *service) Get(ctx context.Context, id int64) (store.Album, error) {
 	}
 	return s.store.AlbumByID(id)
 }
+
+func (s *service) Update(ctx context.Context, token string, id int64, patch store.AlbumPatch) (store.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return store.Album{}, err
+	}
+	return s.store.UpdateAlbum(ctx, token, id, patch)
+}
+
+func (s *service) Delete(ctx context.Context, token string, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.store.DeleteAlbum(ctx, token, id)
+}
+
+func (s *service) CreateRelease(ctx context.Context, token string, release models.Release) (models.Release, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Release{}, err
+	}
+	return s.store.CreateRelease(ctx, token, release)
+}
+
+func (s *service) UpdateRelease(ctx context.Context, token string, id int64, release models.Release) (models.Release, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Release{}, err
+	}
+	return s.store.UpdateRelease(ctx, token, id, release)
+}
+
+func (s *service) GetRelease(ctx context.Context, id int64) (models.Release, error) {
+	if err := ctx.Err(); err != nil {
+		return models.Release{}, err
+	}
+	return s.store.ReleaseByID(ctx, id)
+}
+
+func (s *service) ListReleases(ctx context.Context, albumID int64) ([]models.Release, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.ReleasesByAlbum(ctx, albumID)
+}
@@ -0,0 +1,33 @@
+package catalog
+
+import (
+	"context"
+
+	"vinylhound/internal/store"
+)
+
+// Store captures the persistence needs for catalog search.
+type Store interface {
+	SearchCatalog(ctx context.Context, search store.CatalogSearch) ([]store.CatalogSearchResult, error)
+}
+
+// Service exposes ranked search across albums, songs and artists.
+type Service interface {
+	Search(ctx context.Context, search store.CatalogSearch) ([]store.CatalogSearchResult, error)
+}
+
+type service struct {
+	store Store
+}
+
+// New constructs a catalog search Service backed by the provided Store.
+func New(store Store) Service {
+	return &service{store: store}
+}
+
+func (s *service) Search(ctx context.Context, search store.CatalogSearch) ([]store.CatalogSearchResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.SearchCatalog(ctx, search)
+}
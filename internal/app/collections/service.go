@@ -3,13 +3,15 @@ package collections
 import (
 	"context"
 
+	"vinylhound/internal/queryspec"
+	"vinylhound/internal/store"
 	"vinylhound/shared/go/models"
 )
 
 // Store defines persistence operations for album collections
 type Store interface {
 	AddToCollection(ctx context.Context, token string, collection *models.AlbumCollection) (*models.AlbumCollection, error)
-	ListCollection(ctx context.Context, token string, filter models.CollectionFilter) ([]*models.AlbumCollectionWithDetails, error)
+	ListCollection(ctx context.Context, token string, filter models.CollectionFilter, spec queryspec.Spec, page store.Page) ([]*models.AlbumCollectionWithDetails, string, error)
 	GetCollectionItem(ctx context.Context, id int64) (*models.AlbumCollectionWithDetails, error)
 	UpdateCollectionItem(ctx context.Context, token string, id int64, collection *models.AlbumCollection) (*models.AlbumCollection, error)
 	RemoveFromCollection(ctx context.Context, token string, id int64) error
@@ -20,7 +22,7 @@ type Store interface {
 // Service coordinates collection-related operations
 type Service interface {
 	Add(ctx context.Context, token string, collection *models.AlbumCollection) (*models.AlbumCollection, error)
-	List(ctx context.Context, token string, filter models.CollectionFilter) ([]*models.AlbumCollectionWithDetails, error)
+	List(ctx context.Context, token string, filter models.CollectionFilter, spec queryspec.Spec, page store.Page) ([]*models.AlbumCollectionWithDetails, string, error)
 	Get(ctx context.Context, id int64) (*models.AlbumCollectionWithDetails, error)
 	Update(ctx context.Context, token string, id int64, collection *models.AlbumCollection) (*models.AlbumCollection, error)
 	Remove(ctx context.Context, token string, id int64) error
@@ -51,11 +53,11 @@ func (s *service) Add(ctx context.Context, token string, collection *models.Albu
 	return s.store.AddToCollection(ctx, token, collection)
 }
 
-func (s *service) List(ctx context.Context, token string, filter models.CollectionFilter) ([]*models.AlbumCollectionWithDetails, error) {
+func (s *service) List(ctx context.Context, token string, filter models.CollectionFilter, spec queryspec.Spec, page store.Page) ([]*models.AlbumCollectionWithDetails, string, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return s.store.ListCollection(ctx, token, filter)
+	return s.store.ListCollection(ctx, token, filter, spec, page)
 }
 
 func (s *service) Get(ctx context.Context, id int64) (*models.AlbumCollectionWithDetails, error) {
@@ -3,19 +3,19 @@ package concerts
 import (
 	"context"
 
+	"vinylhound/internal/queryspec"
+	"vinylhound/internal/store"
 	"vinylhound/shared/go/models"
 )
 
 // Store defines persistence operations for concerts
 type Store interface {
 	CreateConcert(ctx context.Context, token string, concert *models.Concert) (*models.Concert, error)
-	ListConcertsByUser(ctx context.Context, token string, includeVenue bool) ([]*models.ConcertWithDetails, error)
+	ListConcertsByUser(ctx context.Context, token string, includeVenue bool, spec queryspec.Spec, page store.Page) ([]*models.ConcertWithDetails, string, error)
 	GetConcert(ctx context.Context, id int64) (*models.ConcertWithDetails, error)
 	UpdateConcert(ctx context.Context, token string, id int64, concert *models.Concert) (*models.Concert, error)
 	DeleteConcert(ctx context.Context, token string, id int64) error
-	ListUpcomingConcerts(ctx context.Context, token string) ([]*models.ConcertWithDetails, error)
-	ListConcertsByVenue(ctx context.Context, venueID int64) ([]*models.ConcertWithDetails, error)
-	ListConcertsByArtist(ctx context.Context, token string, artistName string) ([]*models.ConcertWithDetails, error)
+	ListConcertsByVenue(ctx context.Context, venueID int64, spec queryspec.Spec, page store.Page) ([]*models.ConcertWithDetails, string, error)
 	MarkConcertAttended(ctx context.Context, token string, concertID int64, rating *int) error
 }
 
@@ -27,13 +27,11 @@ type VenueService interface {
 // Service coordinates concert-related operations
 type Service interface {
 	Create(ctx context.Context, token string, concert *models.Concert) (*models.Concert, error)
-	List(ctx context.Context, token string) ([]*models.ConcertWithDetails, error)
+	List(ctx context.Context, token string, spec queryspec.Spec, page store.Page) ([]*models.ConcertWithDetails, string, error)
 	Get(ctx context.Context, id int64) (*models.ConcertWithDetails, error)
 	Update(ctx context.Context, token string, id int64, concert *models.Concert) (*models.Concert, error)
 	Delete(ctx context.Context, token string, id int64) error
-	ListUpcoming(ctx context.Context, token string) ([]*models.ConcertWithDetails, error)
-	ListByVenue(ctx context.Context, venueID int64) ([]*models.ConcertWithDetails, error)
-	ListByArtist(ctx context.Context, token string, artistName string) ([]*models.ConcertWithDetails, error)
+	ListByVenue(ctx context.Context, venueID int64, spec queryspec.Spec, page store.Page) ([]*models.ConcertWithDetails, string, error)
 	MarkAttended(ctx context.Context, token string, concertID int64, rating *int) error
 }
 
@@ -66,11 +64,11 @@ func (s *service) Create(ctx context.Context, token string, concert *models.Conc
 	return s.store.CreateConcert(ctx, token, concert)
 }
 
-func (s *service) List(ctx context.Context, token string) ([]*models.ConcertWithDetails, error) {
+func (s *service) List(ctx context.Context, token string, spec queryspec.Spec, page store.Page) ([]*models.ConcertWithDetails, string, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return s.store.ListConcertsByUser(ctx, token, true)
+	return s.store.ListConcertsByUser(ctx, token, true, spec, page)
 }
 
 func (s *service) Get(ctx context.Context, id int64) (*models.ConcertWithDetails, error) {
@@ -103,25 +101,11 @@ func (s *service) Delete(ctx context.Context, token string, id int64) error {
 	return s.store.DeleteConcert(ctx, token, id)
 }
 
-func (s *service) ListUpcoming(ctx context.Context, token string) ([]*models.ConcertWithDetails, error) {
-	if err := ctx.Err(); err != nil {
-		return nil, err
-	}
-	return s.store.ListUpcomingConcerts(ctx, token)
-}
-
-func (s *service) ListByVenue(ctx context.Context, venueID int64) ([]*models.ConcertWithDetails, error) {
+func (s *service) ListByVenue(ctx context.Context, venueID int64, spec queryspec.Spec, page store.Page) ([]*models.ConcertWithDetails, string, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, err
-	}
-	return s.store.ListConcertsByVenue(ctx, venueID)
-}
-
-func (s *service) ListByArtist(ctx context.Context, token string, artistName string) ([]*models.ConcertWithDetails, error) {
-	if err := ctx.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return s.store.ListConcertsByArtist(ctx, token, artistName)
+	return s.store.ListConcertsByVenue(ctx, venueID, spec, page)
 }
 
 func (s *service) MarkAttended(ctx context.Context, token string, concertID int64, rating *int) error {
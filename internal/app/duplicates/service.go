@@ -0,0 +1,69 @@
+package duplicates
+
+import (
+	"context"
+
+	"vinylhound/internal/store"
+)
+
+// Store captures the persistence needs for duplicate review and merging.
+type Store interface {
+	ScanAlbumDuplicates(ctx context.Context, token string) (int, error)
+	ListDuplicateCandidates(ctx context.Context, token string, status string, page store.Page) ([]store.DuplicateCandidate, string, error)
+	DismissDuplicateCandidate(ctx context.Context, token string, id int64) error
+	MergeDuplicateCandidate(ctx context.Context, token string, id int64, keepID int64) (store.Album, error)
+	MergeAlbums(ctx context.Context, token string, keepID, mergeID int64) (store.Album, error)
+}
+
+// Service exposes the catalog duplicate review queue.
+type Service interface {
+	Scan(ctx context.Context, token string) (int, error)
+	List(ctx context.Context, token string, status string, page store.Page) ([]store.DuplicateCandidate, string, error)
+	Dismiss(ctx context.Context, token string, id int64) error
+	Merge(ctx context.Context, token string, id int64, keepID int64) (store.Album, error)
+	MergeAlbums(ctx context.Context, token string, keepID, mergeID int64) (store.Album, error)
+}
+
+type service struct {
+	store Store
+}
+
+// New constructs a duplicate review Service backed by the provided Store.
+func New(store Store) Service {
+	return &service{store: store}
+}
+
+func (s *service) Scan(ctx context.Context, token string) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.store.ScanAlbumDuplicates(ctx, token)
+}
+
+func (s *service) List(ctx context.Context, token string, status string, page store.Page) ([]store.DuplicateCandidate, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	return s.store.ListDuplicateCandidates(ctx, token, status, page)
+}
+
+func (s *service) Dismiss(ctx context.Context, token string, id int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.store.DismissDuplicateCandidate(ctx, token, id)
+}
+
+func (s *service) Merge(ctx context.Context, token string, id int64, keepID int64) (store.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return store.Album{}, err
+	}
+	return s.store.MergeDuplicateCandidate(ctx, token, id, keepID)
+}
+
+func (s *service) MergeAlbums(ctx context.Context, token string, keepID, mergeID int64) (store.Album, error) {
+	if err := ctx.Err(); err != nil {
+		return store.Album{}, err
+	}
+	return s.store.MergeAlbums(ctx, token, keepID, mergeID)
+}
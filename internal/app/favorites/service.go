@@ -11,14 +11,16 @@ import (
 type Store interface {
 	AddFavorite(ctx context.Context, token string, songID *int64, albumID *int64) (*models.Favorite, error)
 	RemoveFavorite(ctx context.Context, token string, songID *int64, albumID *int64) error
-	ListFavoriteTracks(ctx context.Context, token string) ([]*models.Favorite, error)
+	ListFavorites(ctx context.Context, token string, page store.Page) ([]*models.Favorite, string, error)
+	ListFavoriteTracks(ctx context.Context, token string, page store.Page) ([]*models.Favorite, string, error)
 }
 
 // Service describes high level favorites operations used by HTTP handlers.
 type Service interface {
 	FavoriteTrack(ctx context.Context, token string, trackID int64) (*models.Favorite, bool, error)
 	UnfavoriteTrack(ctx context.Context, token string, trackID int64) error
-	ListTrackFavorites(ctx context.Context, token string) ([]*models.Favorite, error)
+	List(ctx context.Context, token string, page store.Page) ([]*models.Favorite, string, error)
+	ListTrackFavorites(ctx context.Context, token string, page store.Page) ([]*models.Favorite, string, error)
 }
 
 type service struct {
@@ -56,9 +58,16 @@ func (s *service) UnfavoriteTrack(ctx context.Context, token string, trackID int
 	return err
 }
 
-func (s *service) ListTrackFavorites(ctx context.Context, token string) ([]*models.Favorite, error) {
+func (s *service) List(ctx context.Context, token string, page store.Page) ([]*models.Favorite, string, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return s.store.ListFavoriteTracks(ctx, token)
+	return s.store.ListFavorites(ctx, token, page)
+}
+
+func (s *service) ListTrackFavorites(ctx context.Context, token string, page store.Page) ([]*models.Favorite, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+	return s.store.ListFavoriteTracks(ctx, token, page)
 }
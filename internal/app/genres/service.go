@@ -0,0 +1,60 @@
+package genres
+
+import (
+	"context"
+
+	"vinylhound/internal/store"
+)
+
+// Store captures the persistence needs for the genre taxonomy.
+type Store interface {
+	ListGenres(ctx context.Context) ([]store.Genre, error)
+	GenreByID(ctx context.Context, id int64) (store.Genre, error)
+	CreateGenre(ctx context.Context, token string, genre store.Genre) (store.Genre, error)
+	UpdateGenre(ctx context.Context, token string, id int64, patch store.GenrePatch) (store.Genre, error)
+}
+
+// Service exposes the genre hierarchy and its curation.
+type Service interface {
+	List(ctx context.Context) ([]store.Genre, error)
+	Get(ctx context.Context, id int64) (store.Genre, error)
+	Create(ctx context.Context, token string, genre store.Genre) (store.Genre, error)
+	Update(ctx context.Context, token string, id int64, patch store.GenrePatch) (store.Genre, error)
+}
+
+type service struct {
+	store Store
+}
+
+// New constructs a genre Service backed by the provided Store.
+func New(store Store) Service {
+	return &service{store: store}
+}
+
+func (s *service) List(ctx context.Context) ([]store.Genre, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.ListGenres(ctx)
+}
+
+func (s *service) Get(ctx context.Context, id int64) (store.Genre, error) {
+	if err := ctx.Err(); err != nil {
+		return store.Genre{}, err
+	}
+	return s.store.GenreByID(ctx, id)
+}
+
+func (s *service) Create(ctx context.Context, token string, genre store.Genre) (store.Genre, error) {
+	if err := ctx.Err(); err != nil {
+		return store.Genre{}, err
+	}
+	return s.store.CreateGenre(ctx, token, genre)
+}
+
+func (s *service) Update(ctx context.Context, token string, id int64, patch store.GenrePatch) (store.Genre, error) {
+	if err := ctx.Err(); err != nil {
+		return store.Genre{}, err
+	}
+	return s.store.UpdateGenre(ctx, token, id, patch)
+}
@@ -0,0 +1,178 @@
+package identities
+
+import (
+	"context"
+
+	"vinylhound/internal/oidc"
+	"vinylhound/internal/store"
+)
+
+// Store describes the persistence operations required by the identity service.
+type Store interface {
+	CreateOIDCLoginState(ctx context.Context, token string, state store.OIDCLoginState) error
+	ConsumeOIDCLoginState(ctx context.Context, provider, state, binding, token string) (store.OIDCLoginState, error)
+	AuthenticateExternal(ctx context.Context, identity store.ExternalIdentity, meta store.SessionMetadata) (store.SessionTokens, error)
+	LinkIdentity(ctx context.Context, userID int64, identity store.ExternalIdentity) (store.Identity, error)
+	IdentitiesByToken(ctx context.Context, token string) ([]store.Identity, error)
+	UnlinkIdentity(ctx context.Context, token string, identityID int64) error
+}
+
+// Authorization is a started provider login: the URL to send the browser to
+// and the binding secret the browser must keep, in a cookie, and present
+// with the callback.
+type Authorization struct {
+	URL     string
+	Binding string
+}
+
+// Callback is a provider redirect back to the API. Binding is the secret
+// handed out when the login started; Token is the session of the linking
+// user and is required when the login links an identity.
+type Callback struct {
+	Provider string
+	Code     string
+	State    string
+	Binding  string
+	Token    string
+}
+
+// CallbackResult is the outcome of a completed provider login: session
+// tokens when the user signed in, or the linked identity when an
+// authenticated user connected a new provider.
+type CallbackResult struct {
+	Tokens   *store.SessionTokens
+	Identity *store.Identity
+}
+
+// Service exposes sign-in and account linking through external OpenID
+// Connect providers.
+type Service interface {
+	Providers() []string
+	BeginLogin(ctx context.Context, provider string) (Authorization, error)
+	BeginLink(ctx context.Context, token, provider string) (Authorization, error)
+	Complete(ctx context.Context, callback Callback, meta store.SessionMetadata) (CallbackResult, error)
+	Identities(ctx context.Context, token string) ([]store.Identity, error)
+	Unlink(ctx context.Context, token string, identityID int64) error
+}
+
+type service struct {
+	store     Store
+	providers *oidc.Registry
+}
+
+// New wires a Service backed by the provided Store and provider registry. A
+// nil registry disables external sign-in.
+func New(store Store, providers *oidc.Registry) Service {
+	return &service{store: store, providers: providers}
+}
+
+func (s *service) Providers() []string {
+	return s.providers.Names()
+}
+
+func (s *service) BeginLogin(ctx context.Context, provider string) (Authorization, error) {
+	return s.begin(ctx, "", provider)
+}
+
+func (s *service) BeginLink(ctx context.Context, token, provider string) (Authorization, error) {
+	if token == "" {
+		return Authorization{}, store.ErrUnauthorized
+	}
+	return s.begin(ctx, token, provider)
+}
+
+// begin records the login secrets and returns the provider URL to send the
+// user to.
+func (s *service) begin(ctx context.Context, token, provider string) (Authorization, error) {
+	if err := ctx.Err(); err != nil {
+		return Authorization{}, err
+	}
+
+	p, err := s.providers.Provider(provider)
+	if err != nil {
+		return Authorization{}, err
+	}
+
+	req, err := oidc.NewRequest()
+	if err != nil {
+		return Authorization{}, err
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, req)
+	if err != nil {
+		return Authorization{}, err
+	}
+
+	if err := s.store.CreateOIDCLoginState(ctx, token, store.OIDCLoginState{
+		State:        req.State,
+		Provider:     provider,
+		Nonce:        req.Nonce,
+		CodeVerifier: req.CodeVerifier,
+		Binding:      req.Binding,
+	}); err != nil {
+		return Authorization{}, err
+	}
+
+	return Authorization{URL: authURL, Binding: req.Binding}, nil
+}
+
+func (s *service) Complete(ctx context.Context, callback Callback, meta store.SessionMetadata) (CallbackResult, error) {
+	if err := ctx.Err(); err != nil {
+		return CallbackResult{}, err
+	}
+
+	p, err := s.providers.Provider(callback.Provider)
+	if err != nil {
+		return CallbackResult{}, err
+	}
+
+	// The state is consumed before the code is redeemed so a replayed
+	// callback fails even if the exchange does.
+	pending, err := s.store.ConsumeOIDCLoginState(ctx, callback.Provider, callback.State, callback.Binding, callback.Token)
+	if err != nil {
+		return CallbackResult{}, err
+	}
+
+	verified, err := p.Exchange(ctx, callback.Code, pending.CodeVerifier, pending.Nonce)
+	if err != nil {
+		return CallbackResult{}, err
+	}
+
+	identity := store.ExternalIdentity{
+		Provider:          callback.Provider,
+		Issuer:            verified.Issuer,
+		Subject:           verified.Subject,
+		PreferredUsername: verified.PreferredUsername,
+	}
+	if verified.EmailVerified {
+		identity.Email = verified.Email
+	}
+
+	if pending.UserID != 0 {
+		linked, err := s.store.LinkIdentity(ctx, pending.UserID, identity)
+		if err != nil {
+			return CallbackResult{}, err
+		}
+		return CallbackResult{Identity: &linked}, nil
+	}
+
+	tokens, err := s.store.AuthenticateExternal(ctx, identity, meta)
+	if err != nil {
+		return CallbackResult{}, err
+	}
+	return CallbackResult{Tokens: &tokens}, nil
+}
+
+func (s *service) Identities(ctx context.Context, token string) ([]store.Identity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.IdentitiesByToken(ctx, token)
+}
+
+func (s *service) Unlink(ctx context.Context, token string, identityID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.store.UnlinkIdentity(ctx, token, identityID)
+}
@@ -0,0 +1,33 @@
+package lookup
+
+import (
+	"context"
+
+	"vinylhound/internal/store"
+)
+
+// Store captures the persistence needs for resolving provider IDs.
+type Store interface {
+	LookupExternalID(ctx context.Context, provider, externalID, entityType string) ([]store.ExternalID, error)
+}
+
+// Service resolves provider IDs to local artists, albums and tracks.
+type Service interface {
+	Lookup(ctx context.Context, provider, externalID, entityType string) ([]store.ExternalID, error)
+}
+
+type service struct {
+	store Store
+}
+
+// New constructs a lookup Service backed by the provided Store.
+func New(store Store) Service {
+	return &service{store: store}
+}
+
+func (s *service) Lookup(ctx context.Context, provider, externalID, entityType string) ([]store.ExternalID, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.LookupExternalID(ctx, provider, externalID, entityType)
+}
@@ -3,6 +3,7 @@ package places
 import (
 	"context"
 
+	"vinylhound/internal/store"
 	"vinylhound/shared/go/models"
 )
 
@@ -10,14 +11,14 @@ import (
 type Store interface {
 	// Venue operations
 	CreateVenue(ctx context.Context, token string, venue *models.Venue) (*models.Venue, error)
-	ListVenuesByUser(ctx context.Context, token string) ([]*models.Venue, error)
+	ListVenuesByUser(ctx context.Context, token string, page store.Page) ([]*models.Venue, string, error)
 	GetVenue(ctx context.Context, id int64) (*models.Venue, error)
 	UpdateVenue(ctx context.Context, token string, id int64, venue *models.Venue) (*models.Venue, error)
 	DeleteVenue(ctx context.Context, token string, id int64) error
 
 	// Retailer operations
 	CreateRetailer(ctx context.Context, token string, retailer *models.Retailer) (*models.Retailer, error)
-	ListRetailersByUser(ctx context.Context, token string) ([]*models.Retailer, error)
+	ListRetailersByUser(ctx context.Context, token string, page store.Page) ([]*models.Retailer, string, error)
 	GetRetailer(ctx context.Context, id int64) (*models.Retailer, error)
 	UpdateRetailer(ctx context.Context, token string, id int64, retailer *models.Retailer) (*models.Retailer, error)
 	DeleteRetailer(ctx context.Context, token string, id int64) error
@@ -27,14 +28,14 @@ type Store interface {
 type Service interface {
 	// Venue operations
 	CreateVenue(ctx context.Context, token string, venue *models.Venue) (*models.Venue, error)
-	ListVenues(ctx context.Context, token string) ([]*models.Venue, error)
+	ListVenues(ctx context.Context, token string, page store.Page) ([]*models.Venue, string, error)
 	GetVenue(ctx context.Context, id int64) (*models.Venue, error)
 	UpdateVenue(ctx context.Context, token string, id int64, venue *models.Venue) (*models.Venue, error)
 	DeleteVenue(ctx context.Context, token string, id int64) error
 
 	// Retailer operations
 	CreateRetailer(ctx context.Context, token string, retailer *models.Retailer) (*models.Retailer, error)
-	ListRetailers(ctx context.Context, token string) ([]*models.Retailer, error)
+	ListRetailers(ctx context.Context, token string, page store.Page) ([]*models.Retailer, string, error)
 	GetRetailer(ctx context.Context, id int64) (*models.Retailer, error)
 	UpdateRetailer(ctx context.Context, token string, id int64, retailer *models.Retailer) (*models.Retailer, error)
 	DeleteRetailer(ctx context.Context, token string, id int64) error
@@ -57,11 +58,11 @@ func (s *service) CreateVenue(ctx context.Context, token string, venue *models.V
 	return s.store.CreateVenue(ctx, token, venue)
 }
 
-func (s *service) ListVenues(ctx context.Context, token string) ([]*models.Venue, error) {
+func (s *service) ListVenues(ctx context.Context, token string, page store.Page) ([]*models.Venue, string, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return s.store.ListVenuesByUser(ctx, token)
+	return s.store.ListVenuesByUser(ctx, token, page)
 }
 
 func (s *service) GetVenue(ctx context.Context, id int64) (*models.Venue, error) {
@@ -93,11 +94,11 @@ func (s *service) CreateRetailer(ctx context.Context, token string, retailer *mo
 	return s.store.CreateRetailer(ctx, token, retailer)
 }
 
-func (s *service) ListRetailers(ctx context.Context, token string) ([]*models.Retailer, error) {
+func (s *service) ListRetailers(ctx context.Context, token string, page store.Page) ([]*models.Retailer, string, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return s.store.ListRetailersByUser(ctx, token)
+	return s.store.ListRetailersByUser(ctx, token, page)
 }
 
 func (s *service) GetRetailer(ctx context.Context, id int64) (*models.Retailer, error) {
@@ -3,12 +3,13 @@ package playlists
 import (
 	"context"
 
+	"vinylhound/internal/store"
 	"vinylhound/shared/go/models"
 )
 
 // Store captures the persistence needs for playlist workflows.
 type Store interface {
-	ListPlaylists(ctx context.Context, token string) ([]*models.Playlist, error)
+	ListPlaylists(ctx context.Context, token string, page store.Page) ([]*models.Playlist, string, error)
 	GetPlaylist(ctx context.Context, id int64) (*models.Playlist, error)
 	CreatePlaylist(ctx context.Context, token string, playlist *models.Playlist) (*models.Playlist, error)
 	UpdatePlaylist(ctx context.Context, token string, id int64, playlist *models.Playlist) (*models.Playlist, error)
@@ -19,7 +20,7 @@ type Store interface {
 
 // Service coordinates playlist-related operations.
 type Service interface {
-	List(ctx context.Context, token string) ([]*models.Playlist, error)
+	List(ctx context.Context, token string, page store.Page) ([]*models.Playlist, string, error)
 	Get(ctx context.Context, id int64) (*models.Playlist, error)
 	Create(ctx context.Context, token string, playlist *models.Playlist) (*models.Playlist, error)
 	Update(ctx context.Context, token string, id int64, playlist *models.Playlist) (*models.Playlist, error)
@@ -37,11 +38,11 @@ func New(store Store) Service {
 	return &service{store: store}
 }
 
-func (s *service) List(ctx context.Context, token string) ([]*models.Playlist, error) {
+func (s *service) List(ctx context.Context, token string, page store.Page) ([]*models.Playlist, string, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return s.store.ListPlaylists(ctx, token)
+	return s.store.ListPlaylists(ctx, token, page)
 }
 
 func (s *service) Get(ctx context.Context, id int64) (*models.Playlist, error) {
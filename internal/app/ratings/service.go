@@ -8,14 +8,14 @@ import (
 
 // Store defines the persistence hooks for ratings workflows.
 type Store interface {
-	UpsertAlbumPreference(token string, albumID int64, rating *int, favorited bool) error
-	AlbumPreferencesByToken(token string) ([]store.AlbumPreference, error)
+	UpsertAlbumPreference(token string, albumID int64, releaseID *int64, rating *int, favorited bool) error
+	AlbumPreferencesByToken(token string, page store.Page) ([]store.AlbumPreference, string, error)
 }
 
 // Service coordinates rating updates and queries.
 type Service interface {
-	Upsert(ctx context.Context, token string, albumID int64, rating *int, favorited bool) error
-	ListByUser(ctx context.Context, token string) ([]store.AlbumPreference, error)
+	Upsert(ctx context.Context, token string, albumID int64, releaseID *int64, rating *int, favorited bool) error
+	ListByUser(ctx context.Context, token string, page store.Page) ([]store.AlbumPreference, string, error)
 }
 
 type service struct {
@@ -27,16 +27,16 @@ func New(store Store) Service {
 	return &service{store: store}
 }
 
-func (s *service) Upsert(ctx context.Context, token string, albumID int64, rating *int, favorited bool) error {
+func (s *service) Upsert(ctx context.Context, token string, albumID int64, releaseID *int64, rating *int, favorited bool) error {
 	if err := ctx.Err(); err != nil {
 		return err
 	}
-	return s.store.UpsertAlbumPreference(token, albumID, rating, favorited)
+	return s.store.UpsertAlbumPreference(token, albumID, releaseID, rating, favorited)
 }
 
-func (s *service) ListByUser(ctx context.Context, token string) ([]store.AlbumPreference, error) {
+func (s *service) ListByUser(ctx context.Context, token string, page store.Page) ([]store.AlbumPreference, string, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return s.store.AlbumPreferencesByToken(token)
+	return s.store.AlbumPreferencesByToken(token, page)
 }
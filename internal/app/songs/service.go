@@ -2,16 +2,19 @@ package songs
 
 import (
 	"context"
-	"strings"
 
 	"vinylhound/internal/store"
 )
 
 // Song models a track within an album.
 type Song struct {
+	ID          int64  `json:"id"`
 	AlbumID     int64  `json:"albumId"`
+	DiscNumber  int    `json:"discNumber"`
+	Side        string `json:"side,omitempty"`
 	TrackNumber int    `json:"trackNumber"`
 	Title       string `json:"title"`
+	Duration    int    `json:"duration,omitempty"`
 }
 
 // AlbumProvider exposes the album lookup required for track retrieval.
@@ -21,14 +24,14 @@ type AlbumProvider interface {
 
 // SongStore exposes song database operations.
 type SongStore interface {
-	ListSongs(ctx context.Context, filter store.SongFilter) ([]store.Song, error)
+	ListSongs(ctx context.Context, filter store.SongFilter, page store.Page) ([]store.Song, string, error)
 	GetSong(ctx context.Context, id int64) (store.Song, error)
 }
 
 // Service exposes song-centric operations.
 type Service interface {
 	ListByAlbum(ctx context.Context, albumID int64) ([]Song, error)
-	Search(ctx context.Context, filter store.SongFilter) ([]store.Song, error)
+	Search(ctx context.Context, filter store.SongFilter, page store.Page) ([]store.Song, string, error)
 	Get(ctx context.Context, id int64) (store.Song, error)
 }
 
@@ -55,26 +58,26 @@ func (s *service) ListByAlbum(ctx context.Context, albumID int64) ([]Song, error
 		return nil, err
 	}
 
-	var tracks []Song
-	for idx, title := range album.Tracks {
-		title = strings.TrimSpace(title)
-		if title == "" {
-			continue
-		}
+	tracks := make([]Song, 0, len(album.Tracks))
+	for _, track := range album.Tracks {
 		tracks = append(tracks, Song{
+			ID:          track.SongID,
 			AlbumID:     albumID,
-			TrackNumber: idx + 1,
-			Title:       title,
+			DiscNumber:  track.DiscNumber,
+			Side:        track.Side,
+			TrackNumber: track.Position,
+			Title:       track.Title,
+			Duration:    track.Duration,
 		})
 	}
 	return tracks, nil
 }
 
-func (s *service) Search(ctx context.Context, filter store.SongFilter) ([]store.Song, error) {
+func (s *service) Search(ctx context.Context, filter store.SongFilter, page store.Page) ([]store.Song, string, error) {
 	if err := ctx.Err(); err != nil {
-		return nil, err
+		return nil, "", err
 	}
-	return s.store.ListSongs(ctx, filter)
+	return s.store.ListSongs(ctx, filter, page)
 }
 
 func (s *service) Get(ctx context.Context, id int64) (store.Song, error) {
@@ -1,30 +1,80 @@
 package users
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"vinylhound/internal/notify"
+	"vinylhound/internal/store"
+	"vinylhound/shared/go/models"
+)
 
 // Store describes the persistence operations required by the user service.
 type Store interface {
 	CreateUser(username, password string, content []string) error
-	Authenticate(username, password string) (string, error)
+	Authenticate(username, password string, meta store.SessionMetadata) (store.SessionTokens, error)
+	RefreshSession(ctx context.Context, refreshToken string) (store.SessionTokens, error)
 	ContentByToken(token string) ([]string, error)
 	UpdateContentByToken(token string, content []string) error
+	Logout(ctx context.Context, token string) error
+	SessionsByToken(ctx context.Context, token string) ([]store.Session, error)
+	RevokeSession(ctx context.Context, token string, sessionID int64) error
+	RevokeOtherSessions(ctx context.Context, token string) (int64, error)
+	ChangePassword(ctx context.Context, token, currentPassword, newPassword string) (int64, error)
+	CreatePasswordReset(ctx context.Context, username string) (store.PasswordReset, error)
+	ResetPassword(ctx context.Context, resetToken, newPassword string) error
+	DeleteAccount(ctx context.Context, token, password string, mode store.AccountDeletionMode) error
+	SetUserRole(ctx context.Context, token string, userID int64, role models.Role) error
+	CreateAPIToken(ctx context.Context, token, name string, scopes []string, expiresAt *time.Time) (store.APIToken, string, error)
+	APITokensByToken(ctx context.Context, token string) ([]store.APIToken, error)
+	RevokeAPIToken(ctx context.Context, token string, tokenID int64) error
+	APITokenScopes(ctx context.Context, token string) ([]string, error)
+	CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string, meta store.SessionMetadata) (store.SessionTokens, error)
+	TwoFactorStatusByToken(ctx context.Context, token string) (store.TwoFactorStatus, error)
+	BeginTOTPEnrollment(ctx context.Context, token string) (store.TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(ctx context.Context, token, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, token, code string) ([]string, error)
+	DisableTwoFactor(ctx context.Context, token, code string) error
 }
 
 // Service exposes user-related workflows in an extensible manner.
 type Service interface {
 	Signup(ctx context.Context, username, password string, content []string) error
-	Authenticate(ctx context.Context, username, password string) (string, error)
+	Authenticate(ctx context.Context, username, password string, meta store.SessionMetadata) (store.SessionTokens, error)
+	Refresh(ctx context.Context, refreshToken string) (store.SessionTokens, error)
 	Content(ctx context.Context, token string) ([]string, error)
 	UpdateContent(ctx context.Context, token string, content []string) error
+	Logout(ctx context.Context, token string) error
+	Sessions(ctx context.Context, token string) ([]store.Session, error)
+	RevokeSession(ctx context.Context, token string, sessionID int64) error
+	RevokeOtherSessions(ctx context.Context, token string) (int64, error)
+	ChangePassword(ctx context.Context, token, currentPassword, newPassword string) (int64, error)
+	RequestPasswordReset(ctx context.Context, username string) error
+	ResetPassword(ctx context.Context, resetToken, newPassword string) error
+	DeleteAccount(ctx context.Context, token, password string, mode store.AccountDeletionMode) error
+	SetRole(ctx context.Context, token string, userID int64, role models.Role) error
+	CreateAPIToken(ctx context.Context, token, name string, scopes []string, expiresAt *time.Time) (store.APIToken, string, error)
+	APITokens(ctx context.Context, token string) ([]store.APIToken, error)
+	RevokeAPIToken(ctx context.Context, token string, tokenID int64) error
+	APITokenScopes(ctx context.Context, token string) ([]string, error)
+	CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string, meta store.SessionMetadata) (store.SessionTokens, error)
+	TwoFactorStatus(ctx context.Context, token string) (store.TwoFactorStatus, error)
+	BeginTOTPEnrollment(ctx context.Context, token string) (store.TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(ctx context.Context, token, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, token, code string) ([]string, error)
+	DisableTwoFactor(ctx context.Context, token, code string) error
 }
 
 type service struct {
-	store Store
+	store    Store
+	notifier notify.Notifier
 }
 
-// New wires a Service backed by the provided Store.
-func New(store Store) Service {
-	return &service{store: store}
+// New wires a Service backed by the provided Store. Password reset tokens
+// are delivered through notifier.
+func New(store Store, notifier notify.Notifier) Service {
+	return &service{store: store, notifier: notifier}
 }
 
 func (s *service) Signup(ctx context.Context, username, password string, content []string) error {
@@ -34,11 +84,18 @@ func (s *service) Signup(ctx context.Context, username, password string, content
 	return s.store.CreateUser(username, password, content)
 }
 
-func (s *service) Authenticate(ctx context.Context, username, password string) (string, error) {
+func (s *service) Authenticate(ctx context.Context, username, password string, meta store.SessionMetadata) (store.SessionTokens, error) {
 	if err := ctx.Err(); err != nil {
-		return "", err
+		return store.SessionTokens{}, err
 	}
-	return s.store.Authenticate(username, password)
+	return s.store.Authenticate(username, password, meta)
+}
+
+func (s *service) Refresh(ctx context.Context, refreshToken string) (store.SessionTokens, error) {
+	if err := ctx.Err(); err != nil {
+		return store.SessionTokens{}, err
+	}
+	return s.store.RefreshSession(ctx, refreshToken)
 }
 
 func (s *service) Content(ctx context.Context, token string) ([]string, error) {
@@ -54,3 +111,153 @@ func (s *service) UpdateContent(ctx context.Context, token string, content []str
 	}
 	return s.store.UpdateContentByToken(token, content)
 }
+
+func (s *service) Logout(ctx context.Context, token string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.store.Logout(ctx, token)
+}
+
+func (s *service) Sessions(ctx context.Context, token string) ([]store.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.SessionsByToken(ctx, token)
+}
+
+func (s *service) RevokeSession(ctx context.Context, token string, sessionID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.store.RevokeSession(ctx, token, sessionID)
+}
+
+func (s *service) RevokeOtherSessions(ctx context.Context, token string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.store.RevokeOtherSessions(ctx, token)
+}
+
+func (s *service) ChangePassword(ctx context.Context, token, currentPassword, newPassword string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return s.store.ChangePassword(ctx, token, currentPassword, newPassword)
+}
+
+// RequestPasswordReset issues a reset token and hands it to the notifier.
+// Unknown usernames are not reported so the endpoint cannot be used to probe
+// which accounts exist.
+func (s *service) RequestPasswordReset(ctx context.Context, username string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	reset, err := s.store.CreatePasswordReset(ctx, username)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return s.notifier.SendPasswordReset(ctx, notify.PasswordReset{
+		UserID:    reset.UserID,
+		Username:  reset.Username,
+		Token:     reset.Token,
+		ExpiresAt: reset.ExpiresAt,
+	})
+}
+
+func (s *service) ResetPassword(ctx context.Context, resetToken, newPassword string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.store.ResetPassword(ctx, resetToken, newPassword)
+}
+
+func (s *service) DeleteAccount(ctx context.Context, token, password string, mode store.AccountDeletionMode) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.store.DeleteAccount(ctx, token, password, mode)
+}
+
+func (s *service) SetRole(ctx context.Context, token string, userID int64, role models.Role) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.store.SetUserRole(ctx, token, userID, role)
+}
+
+func (s *service) CreateAPIToken(ctx context.Context, token, name string, scopes []string, expiresAt *time.Time) (store.APIToken, string, error) {
+	if err := ctx.Err(); err != nil {
+		return store.APIToken{}, "", err
+	}
+	return s.store.CreateAPIToken(ctx, token, name, scopes, expiresAt)
+}
+
+func (s *service) APITokens(ctx context.Context, token string) ([]store.APIToken, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.APITokensByToken(ctx, token)
+}
+
+func (s *service) RevokeAPIToken(ctx context.Context, token string, tokenID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.store.RevokeAPIToken(ctx, token, tokenID)
+}
+
+func (s *service) APITokenScopes(ctx context.Context, token string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.APITokenScopes(ctx, token)
+}
+
+func (s *service) CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string, meta store.SessionMetadata) (store.SessionTokens, error) {
+	if err := ctx.Err(); err != nil {
+		return store.SessionTokens{}, err
+	}
+	return s.store.CompleteTwoFactorLogin(ctx, challengeToken, code, meta)
+}
+
+func (s *service) TwoFactorStatus(ctx context.Context, token string) (store.TwoFactorStatus, error) {
+	if err := ctx.Err(); err != nil {
+		return store.TwoFactorStatus{}, err
+	}
+	return s.store.TwoFactorStatusByToken(ctx, token)
+}
+
+func (s *service) BeginTOTPEnrollment(ctx context.Context, token string) (store.TOTPEnrollment, error) {
+	if err := ctx.Err(); err != nil {
+		return store.TOTPEnrollment{}, err
+	}
+	return s.store.BeginTOTPEnrollment(ctx, token)
+}
+
+func (s *service) ConfirmTOTPEnrollment(ctx context.Context, token, code string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.ConfirmTOTPEnrollment(ctx, token, code)
+}
+
+func (s *service) RegenerateRecoveryCodes(ctx context.Context, token, code string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.RegenerateRecoveryCodes(ctx, token, code)
+}
+
+func (s *service) DisableTwoFactor(ctx context.Context, token, code string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.store.DisableTwoFactor(ctx, token, code)
+}
@@ -41,7 +41,8 @@ func CORS(allowedOrigin string) func(http.Handler) http.Handler {
 }
 
 func setCommonHeaders(w http.ResponseWriter) {
-	w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,DELETE,OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "GET,POST,PUT,PATCH,DELETE,OPTIONS")
 	w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Accept")
+	w.Header().Set("Access-Control-Expose-Headers", "Link, X-Next-Cursor")
 	w.Header().Set("Access-Control-Max-Age", "3600")
 }
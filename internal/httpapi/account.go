@@ -0,0 +1,139 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"vinylhound/internal/store"
+)
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+type passwordResetRequest struct {
+	Username string `json:"username"`
+}
+
+type confirmPasswordResetRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type deleteAccountRequest struct {
+	Password string `json:"password"`
+	Mode     string `json:"mode"`
+}
+
+func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	var req changePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+
+	revoked, err := s.users.ChangePassword(r.Context(), token, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		status, message := http.StatusInternalServerError, err.Error()
+		switch {
+		case errors.Is(err, store.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		case errors.Is(err, store.ErrInvalidCredentials):
+			status, message = http.StatusForbidden, "current password is incorrect"
+		case errors.Is(err, store.ErrRecentSignInRequired):
+			status = http.StatusForbidden
+		case errors.Is(err, store.ErrPasswordRequired):
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, errorResponse{Error: message})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		RevokedSessions int64 `json:"revoked_sessions"`
+	}{RevokedSessions: revoked})
+}
+
+func (s *Server) handleRequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req passwordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+	if req.Username == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "username is required"})
+		return
+	}
+
+	if err := s.users.RequestPasswordReset(r.Context(), req.Username); err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+
+	// Accepted whether or not the account exists.
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req confirmPasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+
+	if err := s.users.ResetPassword(r.Context(), req.Token, req.NewPassword); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrInvalidResetToken) || errors.Is(err, store.ErrPasswordRequired) {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleDeleteAccount(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	var req deleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+
+	mode := store.AccountDeletionMode(req.Mode)
+	if mode == "" {
+		mode = store.DeleteAccountCascade
+	}
+
+	if err := s.users.DeleteAccount(r.Context(), token, req.Password, mode); err != nil {
+		status, message := http.StatusInternalServerError, err.Error()
+		switch {
+		case errors.Is(err, store.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		case errors.Is(err, store.ErrInvalidCredentials):
+			status, message = http.StatusForbidden, "password is incorrect"
+		case errors.Is(err, store.ErrRecentSignInRequired):
+			status = http.StatusForbidden
+		case errors.Is(err, store.ErrInvalidDeletionMode):
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, errorResponse{Error: message})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
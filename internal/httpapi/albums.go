@@ -0,0 +1,99 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"vinylhound/internal/store"
+)
+
+// albumPatchRequest carries a partial album update; omitted fields keep
+// their current value.
+type albumPatchRequest struct {
+	Artist      *string               `json:"artist"`
+	Artists     *[]store.ArtistCredit `json:"artists"`
+	Title       *string               `json:"title"`
+	ReleaseYear *int                  `json:"releaseYear"`
+	Genres      *[]string             `json:"genreList"`
+	Rating      *int                  `json:"rating"`
+}
+
+func (req albumPatchRequest) patch() store.AlbumPatch {
+	return store.AlbumPatch{
+		Artist:      req.Artist,
+		Artists:     req.Artists,
+		Title:       req.Title,
+		ReleaseYear: req.ReleaseYear,
+		Genres:      req.Genres,
+		Rating:      req.Rating,
+	}
+}
+
+// albumErrorStatus maps album editing errors to HTTP statuses.
+func albumErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, store.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, store.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, store.ErrInvalidAlbum):
+		return http.StatusBadRequest
+	case errors.Is(err, store.ErrAlbumNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, store.ErrAlbumInUse):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *Server) handleUpdateAlbum(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid album ID"})
+		return
+	}
+
+	var req albumPatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+
+	updated, err := s.albums.Update(r.Context(), token, id, req.patch())
+	if err != nil {
+		writeJSON(w, albumErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (s *Server) handleDeleteAlbum(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid album ID"})
+		return
+	}
+
+	if err := s.albums.Delete(r.Context(), token, id); err != nil {
+		writeJSON(w, albumErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
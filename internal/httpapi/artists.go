@@ -0,0 +1,78 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"vinylhound/internal/app/artists"
+	"vinylhound/internal/musicapi"
+	"vinylhound/internal/store"
+)
+
+// listedArtist is an entry of GET /api/v1/artists: the provider artist fields
+// the list has always returned, plus the catalog ID.
+type listedArtist struct {
+	ID int64 `json:"id"`
+	musicapi.Artist
+}
+
+// handleListArtists returns one page of catalog artists by name, optionally
+// filtered by a name fragment.
+func (s *Server) handleListArtists(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePage(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	list, next, err := s.artists.List(r.Context(), artists.Filter{Name: r.URL.Query().Get("name")}, page)
+	if err != nil {
+		writeJSON(w, listErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	listed := make([]listedArtist, 0, len(list))
+	for _, artist := range list {
+		listed = append(listed, listedArtist{
+			ID: artist.ID,
+			Artist: musicapi.Artist{
+				ExternalID:  artist.ExternalID,
+				Name:        artist.Name,
+				Provider:    musicapi.MusicProvider(artist.Provider),
+				ImageURL:    artist.ImageURL,
+				Biography:   artist.Biography,
+				Genres:      artist.Genres,
+				Popularity:  artist.Popularity,
+				ExternalURL: artist.ExternalURL,
+			},
+		})
+	}
+
+	setNextPage(w, r, next)
+	writeJSON(w, http.StatusOK, struct {
+		Artists    []listedArtist `json:"artists"`
+		NextCursor string         `json:"next_cursor,omitempty"`
+	}{Artists: listed, NextCursor: next})
+}
+
+// handleGetCatalogArtist returns a catalog artist with their discography.
+func (s *Server) handleGetCatalogArtist(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || id <= 0 {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid artist ID"})
+		return
+	}
+
+	discography, err := s.artists.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, store.ErrArtistNotFound) {
+			writeJSON(w, http.StatusNotFound, errorResponse{Error: "artist not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: "failed to load artist"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, discography)
+}
@@ -0,0 +1,42 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"vinylhound/internal/store"
+)
+
+// handleCatalogSearch runs a ranked search across albums, songs and artists.
+// Query params: q (required), type (comma-separated album, song, artist) and
+// limit.
+func (s *Server) handleCatalogSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	search := store.CatalogSearch{Query: query.Get("q")}
+	if types := query.Get("type"); types != "" {
+		search.Types = strings.Split(types, ",")
+	}
+	if limit := query.Get("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid limit parameter"})
+			return
+		}
+		search.Limit = parsed
+	}
+
+	results, err := s.catalog.Search(r.Context(), search)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrInvalidSearch) {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"results": results})
+}
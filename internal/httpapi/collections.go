@@ -2,10 +2,13 @@ package httpapi
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"vinylhound/internal/queryspec"
+	"vinylhound/internal/store"
 	"vinylhound/shared/go/models"
 )
 
@@ -91,14 +94,10 @@ func (s *Server) listCollections(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse query parameters for filtering
+	// Parse query parameters for substring filtering; exact filters, ranges
+	// and the sort order are part of the query spec
 	var filter models.CollectionFilter
 
-	if collectionType := r.URL.Query().Get("type"); collectionType != "" {
-		ct := models.CollectionType(collectionType)
-		filter.CollectionType = &ct
-	}
-
 	if artist := r.URL.Query().Get("artist"); artist != "" {
 		filter.Artist = artist
 	}
@@ -107,56 +106,58 @@ func (s *Server) listCollections(w http.ResponseWriter, r *http.Request) {
 		filter.Genre = genre
 	}
 
-	if yearFrom := r.URL.Query().Get("year_from"); yearFrom != "" {
-		if yf, err := strconv.Atoi(yearFrom); err == nil {
-			filter.YearFrom = &yf
-		}
-	}
-
-	if yearTo := r.URL.Query().Get("year_to"); yearTo != "" {
-		if yt, err := strconv.Atoi(yearTo); err == nil {
-			filter.YearTo = &yt
-		}
+	if search := r.URL.Query().Get("search"); search != "" {
+		filter.SearchTerm = search
 	}
 
-	if condition := r.URL.Query().Get("condition"); condition != "" {
-		cond := models.AlbumCondition(condition)
-		filter.Condition = &cond
+	page, err := parsePage(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	if search := r.URL.Query().Get("search"); search != "" {
-		filter.SearchTerm = search
+	spec, keys, err := parseListQuery(r, store.CollectionQuery, "artist", "genre", "search", "year_from", "year_to")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	if limit := r.URL.Query().Get("limit"); limit != "" {
-		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
-			filter.Limit = l
-		}
+	// year_from and year_to predate the query spec and stand for year>= and
+	// year<=
+	if yearFrom := r.URL.Query().Get("year_from"); yearFrom != "" {
+		spec.Filters = append(spec.Filters, queryspec.Filter{Field: "year", Op: queryspec.Gte, Value: yearFrom})
 	}
 
-	if offset := r.URL.Query().Get("offset"); offset != "" {
-		if o, err := strconv.Atoi(offset); err == nil && o >= 0 {
-			filter.Offset = o
-		}
+	if yearTo := r.URL.Query().Get("year_to"); yearTo != "" {
+		spec.Filters = append(spec.Filters, queryspec.Filter{Field: "year", Op: queryspec.Lte, Value: yearTo})
 	}
 
-	collections, err := s.collections.List(r.Context(), token, filter)
+	collections, next, err := s.collections.List(r.Context(), token, filter, spec, page)
 	if err != nil {
 		if err.Error() == "unauthorized" {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		} else {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), listErrorStatus(err))
 		}
 		return
 	}
 
+	projected, err := project(collections, keys)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	setNextPage(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(struct {
-		Collections []*models.AlbumCollectionWithDetails `json:"collections"`
-		Count       int                                  `json:"count"`
+		Collections any    `json:"collections"`
+		Count       int    `json:"count"`
+		NextCursor  string `json:"next_cursor,omitempty"`
 	}{
-		Collections: collections,
+		Collections: projected,
 		Count:       len(collections),
+		NextCursor:  next,
 	})
 }
 
@@ -184,6 +185,7 @@ func (s *Server) addToCollection(w http.ResponseWriter, r *http.Request) {
 
 	var req struct {
 		AlbumID        int64                   `json:"album_id"`
+		ReleaseID      *int64                  `json:"release_id,omitempty"`
 		CollectionType models.CollectionType   `json:"collection_type"`
 		Notes          string                  `json:"notes,omitempty"`
 		DateAcquired   *string                 `json:"date_acquired,omitempty"`
@@ -208,6 +210,7 @@ func (s *Server) addToCollection(w http.ResponseWriter, r *http.Request) {
 
 	collection := &models.AlbumCollection{
 		AlbumID:        req.AlbumID,
+		ReleaseID:      req.ReleaseID,
 		CollectionType: req.CollectionType,
 		Notes:          req.Notes,
 		PurchasePrice:  req.PurchasePrice,
@@ -227,10 +230,14 @@ func (s *Server) addToCollection(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusConflict)
 			return
 		}
-		if err.Error() == "album not found" {
+		if err.Error() == "album not found" || errors.Is(err, store.ErrReleaseNotFound) {
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		}
+		if errors.Is(err, store.ErrInvalidRelease) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -249,6 +256,7 @@ func (s *Server) updateCollection(w http.ResponseWriter, r *http.Request, id int
 
 	var req struct {
 		Notes         string                  `json:"notes,omitempty"`
+		ReleaseID     *int64                  `json:"release_id,omitempty"`
 		DateAcquired  *string                 `json:"date_acquired,omitempty"`
 		PurchasePrice *float64                `json:"purchase_price,omitempty"`
 		Condition     *models.AlbumCondition  `json:"condition,omitempty"`
@@ -261,6 +269,7 @@ func (s *Server) updateCollection(w http.ResponseWriter, r *http.Request, id int
 
 	collection := &models.AlbumCollection{
 		Notes:         req.Notes,
+		ReleaseID:     req.ReleaseID,
 		PurchasePrice: req.PurchasePrice,
 		Condition:     req.Condition,
 	}
@@ -280,6 +289,18 @@ func (s *Server) updateCollection(w http.ResponseWriter, r *http.Request, id int
 			http.Error(w, err.Error(), http.StatusForbidden)
 			return
 		}
+		if errors.Is(err, store.ErrReleaseNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, store.ErrInvalidRelease) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, store.ErrAlreadyInCollection) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"time"
 
+	"vinylhound/internal/queryspec"
+	"vinylhound/internal/store"
 	"vinylhound/shared/go/models"
 )
 
@@ -73,35 +75,54 @@ func (s *Server) handleListConcerts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Check for query params to filter
-	artistName := r.URL.Query().Get("artist")
-	venueIDStr := r.URL.Query().Get("venue_id")
-	upcoming := r.URL.Query().Get("upcoming") == "true"
+	page, err := parsePage(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	// artist= is an ordinary filter of the query spec; upcoming=true stands
+	// for date>=now, soonest first unless another sort is given
+	spec, keys, err := parseListQuery(r, store.ConcertQuery, "venue_id", "upcoming")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+	if r.URL.Query().Get("upcoming") == "true" {
+		now := time.Now().UTC().Format(time.RFC3339)
+		spec.Filters = append(spec.Filters, queryspec.Filter{Field: "date", Op: queryspec.Gte, Value: now})
+		if len(spec.Sort) == 0 {
+			spec.Sort = []queryspec.Sort{{Field: "date"}}
+		}
+	}
 
 	var concerts []*models.ConcertWithDetails
-	var err error
+	var next string
 
-	if artistName != "" {
-		concerts, err = s.concerts.ListByArtist(r.Context(), token, artistName)
-	} else if venueIDStr != "" {
+	if venueIDStr := r.URL.Query().Get("venue_id"); venueIDStr != "" {
 		venueID, parseErr := strconv.ParseInt(venueIDStr, 10, 64)
 		if parseErr != nil {
 			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid venue_id"})
 			return
 		}
-		concerts, err = s.concerts.ListByVenue(r.Context(), venueID)
-	} else if upcoming {
-		concerts, err = s.concerts.ListUpcoming(r.Context(), token)
+		concerts, next, err = s.concerts.ListByVenue(r.Context(), venueID, spec, page)
 	} else {
-		concerts, err = s.concerts.List(r.Context(), token)
+		concerts, next, err = s.concerts.List(r.Context(), token, spec, page)
+	}
+
+	if err != nil {
+		writeJSON(w, listErrorStatus(err), errorResponse{Error: err.Error()})
+		return
 	}
 
+	projected, err := project(concerts, keys)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
 		return
 	}
 
-	writeJSON(w, http.StatusOK, concerts)
+	setNextPage(w, r, next)
+	writeJSON(w, http.StatusOK, projected)
 }
 
 func (s *Server) handleGetConcert(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,150 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"vinylhound/internal/store"
+)
+
+type mergeDuplicateRequest struct {
+	KeepAlbumID int64 `json:"keepAlbumId"`
+}
+
+type mergeAlbumsRequest struct {
+	KeepAlbumID  int64 `json:"keepAlbumId"`
+	MergeAlbumID int64 `json:"mergeAlbumId"`
+}
+
+// duplicateErrorStatus maps duplicate review errors to HTTP statuses.
+func duplicateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, store.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, store.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, store.ErrInvalidMerge), errors.Is(err, store.ErrInvalidPage):
+		return http.StatusBadRequest
+	case errors.Is(err, store.ErrDuplicateNotFound), errors.Is(err, store.ErrAlbumNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *Server) handleScanDuplicates(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	found, err := s.duplicates.Scan(r.Context(), token)
+	if err != nil {
+		writeJSON(w, duplicateErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"candidates": found})
+}
+
+func (s *Server) handleListDuplicates(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	page, err := parsePage(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	candidates, next, err := s.duplicates.List(r.Context(), token, r.URL.Query().Get("status"), page)
+	if err != nil {
+		writeJSON(w, duplicateErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	setNextPage(w, r, next)
+	writeJSON(w, http.StatusOK, struct {
+		Candidates []store.DuplicateCandidate `json:"candidates"`
+		NextCursor string                     `json:"next_cursor,omitempty"`
+	}{Candidates: candidates, NextCursor: next})
+}
+
+func (s *Server) handleMergeDuplicate(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid candidate ID"})
+		return
+	}
+
+	// The body is optional; without it the older album is kept.
+	var req mergeDuplicateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+
+	album, err := s.duplicates.Merge(r.Context(), token, id, req.KeepAlbumID)
+	if err != nil {
+		writeJSON(w, duplicateErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, album)
+}
+
+func (s *Server) handleDismissDuplicate(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid candidate ID"})
+		return
+	}
+
+	if err := s.duplicates.Dismiss(r.Context(), token, id); err != nil {
+		writeJSON(w, duplicateErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleMergeAlbums(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	var req mergeAlbumsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+
+	album, err := s.duplicates.MergeAlbums(r.Context(), token, req.KeepAlbumID, req.MergeAlbumID)
+	if err != nil {
+		writeJSON(w, duplicateErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, album)
+}
@@ -13,7 +13,8 @@ import (
 	"vinylhound/shared/go/models"
 )
 
-// handleFavorites handles legacy favorites routes (not yet implemented).
+// handleFavorites handles legacy favorites routes; only listing is
+// implemented.
 func (s *Server) handleFavorites(w http.ResponseWriter, r *http.Request) {
 	token := extractToken(r)
 	if token == "" {
@@ -88,7 +89,27 @@ func (s *Server) removeFavorite(w http.ResponseWriter, r *http.Request, token st
 }
 
 func (s *Server) listFavorites(w http.ResponseWriter, r *http.Request, token string) {
-	writeJSON(w, http.StatusNotImplemented, errorResponse{Error: "favorites service not implemented"})
+	page, err := parsePage(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	favorites, next, err := s.favorites.List(r.Context(), token, page)
+	if err != nil {
+		status, message := mapFavoritesError(err)
+		writeJSON(w, status, errorResponse{Error: message})
+		return
+	}
+	if favorites == nil {
+		favorites = []*models.Favorite{}
+	}
+
+	setNextPage(w, r, next)
+	writeJSON(w, http.StatusOK, struct {
+		Favorites  []*models.Favorite `json:"favorites"`
+		NextCursor string             `json:"next_cursor,omitempty"`
+	}{Favorites: favorites, NextCursor: next})
 }
 
 func (s *Server) checkFavorite(w http.ResponseWriter, r *http.Request, token string) {
@@ -137,7 +158,8 @@ type favoriteTrackEnvelope struct {
 }
 
 type favoriteTracksResponse struct {
-	Tracks []favoriteTrackView `json:"tracks"`
+	Tracks     []favoriteTrackView `json:"tracks"`
+	NextCursor string              `json:"next_cursor,omitempty"`
 }
 
 func (s *Server) handleFavoriteTracks(w http.ResponseWriter, r *http.Request) {
@@ -153,7 +175,13 @@ func (s *Server) handleFavoriteTracks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	favorites, err := s.favorites.ListTrackFavorites(r.Context(), token)
+	page, err := parsePage(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	favorites, next, err := s.favorites.ListTrackFavorites(r.Context(), token, page)
 	if err != nil {
 		status, message := mapFavoritesError(err)
 		writeJSON(w, status, errorResponse{Error: message})
@@ -161,7 +189,8 @@ func (s *Server) handleFavoriteTracks(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resp := favoriteTracksResponse{
-		Tracks: make([]favoriteTrackView, 0, len(favorites)),
+		Tracks:     make([]favoriteTrackView, 0, len(favorites)),
+		NextCursor: next,
 	}
 	for _, fav := range favorites {
 		if fav == nil || fav.SongID == nil {
@@ -173,6 +202,7 @@ func (s *Server) handleFavoriteTracks(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
+	setNextPage(w, r, next)
 	writeJSON(w, http.StatusOK, resp)
 }
 
@@ -246,6 +276,8 @@ func mapFavoritesError(err error) (int, string) {
 		return http.StatusNotFound, "favorite not found"
 	case errors.Is(err, store.ErrInvalidFavoriteType):
 		return http.StatusBadRequest, "invalid favorite type"
+	case errors.Is(err, store.ErrInvalidPage):
+		return http.StatusBadRequest, err.Error()
 	default:
 		return http.StatusInternalServerError, err.Error()
 	}
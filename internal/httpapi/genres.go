@@ -0,0 +1,129 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"vinylhound/internal/store"
+)
+
+type genreRequest struct {
+	Name     string   `json:"name"`
+	ParentID *int64   `json:"parentId"`
+	Aliases  []string `json:"aliases"`
+}
+
+// genrePatchRequest carries a partial genre update; omitted fields keep
+// their current value and a parentId of 0 moves the genre to the top level.
+type genrePatchRequest struct {
+	Name     *string   `json:"name"`
+	ParentID *int64    `json:"parentId"`
+	Aliases  *[]string `json:"aliases"`
+}
+
+// genreErrorStatus maps genre taxonomy errors to HTTP statuses.
+func genreErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, store.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, store.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, store.ErrInvalidGenre):
+		return http.StatusBadRequest
+	case errors.Is(err, store.ErrGenreNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, store.ErrGenreConflict):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *Server) handleListGenres(w http.ResponseWriter, r *http.Request) {
+	genres, err := s.genres.List(r.Context())
+	if err != nil {
+		writeJSON(w, genreErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+	if genres == nil {
+		genres = []store.Genre{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string][]store.Genre{"genres": genres})
+}
+
+func (s *Server) handleGetGenre(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid genre ID"})
+		return
+	}
+
+	genre, err := s.genres.Get(r.Context(), id)
+	if err != nil {
+		writeJSON(w, genreErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, genre)
+}
+
+func (s *Server) handleCreateGenre(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	var req genreRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+
+	genre, err := s.genres.Create(r.Context(), token, store.Genre{
+		Name:     req.Name,
+		ParentID: req.ParentID,
+		Aliases:  req.Aliases,
+	})
+	if err != nil {
+		writeJSON(w, genreErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, genre)
+}
+
+func (s *Server) handleUpdateGenre(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid genre ID"})
+		return
+	}
+
+	var req genrePatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+
+	genre, err := s.genres.Update(r.Context(), token, id, store.GenrePatch{
+		Name:     req.Name,
+		ParentID: req.ParentID,
+		Aliases:  req.Aliases,
+	})
+	if err != nil {
+		writeJSON(w, genreErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, genre)
+}
@@ -0,0 +1,183 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"vinylhound/internal/app/identities"
+	"vinylhound/internal/oidc"
+	"vinylhound/internal/store"
+)
+
+// identityErrorStatus maps identity provider and linking errors to HTTP statuses.
+func identityErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, oidc.ErrUnknownProvider), errors.Is(err, store.ErrIdentityNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, store.ErrInvalidLoginState):
+		return http.StatusBadRequest
+	case errors.Is(err, store.ErrUnauthorized), errors.Is(err, oidc.ErrExchange), errors.Is(err, oidc.ErrInvalidIDToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, store.ErrIdentityLinked), errors.Is(err, store.ErrLastLoginMethod):
+		return http.StatusConflict
+	case errors.Is(err, oidc.ErrDiscovery):
+		return http.StatusBadGateway
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *Server) handleListIdentityProviders(w http.ResponseWriter, r *http.Request) {
+	providers := s.identities.Providers()
+	if providers == nil {
+		providers = []string{}
+	}
+	writeJSON(w, http.StatusOK, struct {
+		Providers []string `json:"providers"`
+	}{Providers: providers})
+}
+
+// oidcBindingCookie holds the binding of the provider login started by the
+// browser. It is only sent to the provider's callback.
+const oidcBindingCookie = "vinylhound_oidc_binding"
+
+// setOIDCBindingCookie hands the browser the binding of a started login. It is
+// SameSite=Lax because the provider's redirect to the callback is a
+// cross-site navigation.
+func setOIDCBindingCookie(w http.ResponseWriter, provider, binding string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcBindingCookie,
+		Value:    binding,
+		Path:     oidcCallbackPath(provider),
+		MaxAge:   int(store.OIDCLoginStateTTL.Seconds()),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func oidcCallbackPath(provider string) string {
+	return "/api/v1/auth/oidc/" + provider + "/callback"
+}
+
+// handleIdentityLogin sends the browser to the identity provider.
+func (s *Server) handleIdentityLogin(w http.ResponseWriter, r *http.Request) {
+	provider := r.PathValue("provider")
+	authorization, err := s.identities.BeginLogin(r.Context(), provider)
+	if err != nil {
+		writeJSON(w, identityErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+	setOIDCBindingCookie(w, provider, authorization.Binding)
+	http.Redirect(w, r, authorization.URL, http.StatusFound)
+}
+
+// handleIdentityCallback completes a provider login. It is only accepted
+// from the browser holding the login's binding cookie, and a link also needs
+// the linking user's bearer token. Sign-ins answer like handleLogin; links
+// answer with the newly linked identity.
+func (s *Server) handleIdentityCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if providerErr := query.Get("error"); providerErr != "" {
+		message := "identity provider returned " + providerErr
+		if desc := query.Get("error_description"); desc != "" {
+			message += ": " + desc
+		}
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: message})
+		return
+	}
+
+	code, state := query.Get("code"), query.Get("state")
+	if code == "" || state == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "code and state are required"})
+		return
+	}
+
+	provider := r.PathValue("provider")
+	callback := identities.Callback{Provider: provider, Code: code, State: state, Token: extractToken(r)}
+	if cookie, err := r.Cookie(oidcBindingCookie); err == nil {
+		callback.Binding = cookie.Value
+	}
+
+	result, err := s.identities.Complete(r.Context(), callback, s.sessionMetadata(r))
+	// The binding belongs to this login only, whatever its outcome.
+	http.SetCookie(w, &http.Cookie{Name: oidcBindingCookie, Path: oidcCallbackPath(provider), MaxAge: -1})
+	if err != nil {
+		writeJSON(w, identityErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	if result.Identity != nil {
+		writeJSON(w, http.StatusOK, struct {
+			Identity *store.Identity `json:"identity"`
+		}{Identity: result.Identity})
+		return
+	}
+	writeJSON(w, http.StatusOK, newTokenResponse(*result.Tokens))
+}
+
+func (s *Server) handleListIdentities(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	identities, err := s.identities.Identities(r.Context(), token)
+	if err != nil {
+		writeJSON(w, identityErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+	if identities == nil {
+		identities = []store.Identity{}
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Identities []store.Identity `json:"identities"`
+	}{Identities: identities})
+}
+
+// handleLinkIdentity starts linking a provider to the signed-in account. The
+// client navigates to the returned URL; the provider then redirects to the
+// usual callback, which the client completes with its bearer token.
+func (s *Server) handleLinkIdentity(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	provider := r.PathValue("provider")
+	authorization, err := s.identities.BeginLink(r.Context(), token, provider)
+	if err != nil {
+		writeJSON(w, identityErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	setOIDCBindingCookie(w, provider, authorization.Binding)
+	writeJSON(w, http.StatusOK, struct {
+		AuthorizationURL string `json:"authorization_url"`
+	}{AuthorizationURL: authorization.URL})
+}
+
+func (s *Server) handleUnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	identityID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid identity id"})
+		return
+	}
+
+	if err := s.identities.Unlink(r.Context(), token, identityID); err != nil {
+		writeJSON(w, identityErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,347 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"vinylhound/internal/app/identities"
+	"vinylhound/internal/oidc"
+	"vinylhound/internal/oidc/oidctest"
+	"vinylhound/internal/store"
+)
+
+const identityCallbackURL = "http://vinylhound.test/api/v1/auth/oidc/test/callback"
+
+// stubIdentityStore keeps login states and linked identities in memory.
+type stubIdentityStore struct {
+	states     map[string]store.OIDCLoginState
+	owners     map[string]int64
+	nextUserID int64
+	signIns    int
+}
+
+func newStubIdentityStore() *stubIdentityStore {
+	return &stubIdentityStore{
+		states:     make(map[string]store.OIDCLoginState),
+		owners:     make(map[string]int64),
+		nextUserID: 100,
+	}
+}
+
+// stubSessions maps the session tokens the stub store accepts to their users.
+var stubSessions = map[string]int64{"session-7": 7, "session-8": 8}
+
+func (s *stubIdentityStore) CreateOIDCLoginState(ctx context.Context, token string, state store.OIDCLoginState) error {
+	if token != "" {
+		userID, ok := stubSessions[token]
+		if !ok {
+			return store.ErrUnauthorized
+		}
+		state.UserID = userID
+	}
+	s.states[state.State] = state
+	return nil
+}
+
+func (s *stubIdentityStore) ConsumeOIDCLoginState(ctx context.Context, provider, state, binding, token string) (store.OIDCLoginState, error) {
+	pending, ok := s.states[state]
+	if !ok || pending.Provider != provider || binding == "" || pending.Binding != binding {
+		return store.OIDCLoginState{}, store.ErrInvalidLoginState
+	}
+	delete(s.states, state)
+	if pending.UserID != 0 && stubSessions[token] != pending.UserID {
+		return store.OIDCLoginState{}, store.ErrUnauthorized
+	}
+	return pending, nil
+}
+
+func (s *stubIdentityStore) AuthenticateExternal(ctx context.Context, identity store.ExternalIdentity, meta store.SessionMetadata) (store.SessionTokens, error) {
+	key := identity.Issuer + "|" + identity.Subject
+	if _, ok := s.owners[key]; !ok {
+		s.nextUserID++
+		s.owners[key] = s.nextUserID
+	}
+	s.signIns++
+	return store.SessionTokens{AccessToken: "session-for-" + identity.Subject, RefreshToken: "refresh"}, nil
+}
+
+func (s *stubIdentityStore) LinkIdentity(ctx context.Context, userID int64, identity store.ExternalIdentity) (store.Identity, error) {
+	key := identity.Issuer + "|" + identity.Subject
+	if owner, ok := s.owners[key]; ok && owner != userID {
+		return store.Identity{}, store.ErrIdentityLinked
+	}
+	s.owners[key] = userID
+	return store.Identity{ID: 1, Provider: identity.Provider, Issuer: identity.Issuer, Subject: identity.Subject, Email: identity.Email}, nil
+}
+
+func (s *stubIdentityStore) IdentitiesByToken(ctx context.Context, token string) ([]store.Identity, error) {
+	return nil, nil
+}
+
+func (s *stubIdentityStore) UnlinkIdentity(ctx context.Context, token string, identityID int64) error {
+	return store.ErrLastLoginMethod
+}
+
+func newIdentityTestServer(t *testing.T, provider *oidctest.Server, st *stubIdentityStore) http.Handler {
+	t.Helper()
+	registry, err := oidc.NewRegistry([]oidc.Config{{
+		Name:         "test",
+		Issuer:       provider.Issuer(),
+		ClientID:     provider.ClientID,
+		ClientSecret: provider.ClientSecret,
+		RedirectURL:  identityCallbackURL,
+	}}, nil)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+
+	server := newTestServer(t, nil, nil, nil)
+	server.identities = identities.New(st, registry)
+	return server.Routes()
+}
+
+// completeProviderLogin signs in at the stand-in provider and calls back into
+// the API with the code it returned, the cookies the API set when the login
+// started and, when not empty, a bearer token.
+func completeProviderLogin(t *testing.T, handler http.Handler, provider *oidctest.Server, authURL string, cookies []*http.Cookie, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	code, state, err := provider.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	callback := "/api/v1/auth/oidc/test/callback?" + url.Values{"code": {code}, "state": {state}}.Encode()
+	req := httptest.NewRequest(http.MethodGet, callback, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// startIdentityLink starts linking the test provider with a session token
+// and returns the authorization URL and the cookies set.
+func startIdentityLink(t *testing.T, handler http.Handler, token string) (string, []*http.Cookie) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/me/identities/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var start struct {
+		AuthorizationURL string `json:"authorization_url"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&start); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	return start.AuthorizationURL, rr.Result().Cookies()
+}
+
+func TestIdentityLoginFlow(t *testing.T) {
+	provider := oidctest.NewServer("vinylhound", "s3cret")
+	defer provider.Close()
+	provider.SetUser(oidctest.User{Subject: "ext-42", Email: "ada@example.com", EmailVerified: true})
+
+	st := newStubIdentityStore()
+	handler := newIdentityTestServer(t, provider, st)
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/test/login", nil))
+		if rr.Code != http.StatusFound {
+			t.Fatalf("expected redirect to provider, got %d: %s", rr.Code, rr.Body.String())
+		}
+
+		cookies := rr.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != oidcBindingCookie || !cookies[0].HttpOnly || cookies[0].SameSite != http.SameSiteLaxMode {
+			t.Fatalf("expected the binding cookie, got %+v", cookies)
+		}
+
+		rr = completeProviderLogin(t, handler, provider, rr.Header().Get("Location"), cookies, "")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+		}
+		var payload tokenResponse
+		if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if payload.Token != "session-for-ext-42" {
+			t.Fatalf("unexpected token %q", payload.Token)
+		}
+	}
+
+	if len(st.owners) != 1 || st.signIns != 2 {
+		t.Fatalf("expected one account signed in twice, got %d accounts and %d sign-ins", len(st.owners), st.signIns)
+	}
+}
+
+func TestIdentityCallbackRejectsReplayedState(t *testing.T) {
+	provider := oidctest.NewServer("vinylhound", "s3cret")
+	defer provider.Close()
+
+	handler := newIdentityTestServer(t, provider, newStubIdentityStore())
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/test/login", nil))
+	cookies := rr.Result().Cookies()
+	code, state, err := provider.Authorize(rr.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+
+	callback := "/api/v1/auth/oidc/test/callback?" + url.Values{"code": {code}, "state": {state}}.Encode()
+	for i, want := range []int{http.StatusOK, http.StatusBadRequest} {
+		req := httptest.NewRequest(http.MethodGet, callback, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Fatalf("callback %d: expected status %d, got %d", i+1, want, rr.Code)
+		}
+	}
+}
+
+func TestIdentityLinkFlow(t *testing.T) {
+	provider := oidctest.NewServer("vinylhound", "")
+	defer provider.Close()
+	provider.SetUser(oidctest.User{Subject: "ext-7", Email: "unverified@example.com"})
+
+	st := newStubIdentityStore()
+	handler := newIdentityTestServer(t, provider, st)
+
+	authURL, cookies := startIdentityLink(t, handler, "session-7")
+	rr := completeProviderLogin(t, handler, provider, authURL, cookies, "session-7")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var payload struct {
+		Identity store.Identity `json:"identity"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Identity.Subject != "ext-7" || payload.Identity.Email != "" {
+		t.Fatalf("unexpected identity (unverified email must not be stored): %+v", payload.Identity)
+	}
+	if st.owners[provider.Issuer()+"|ext-7"] != 7 {
+		t.Fatalf("expected identity linked to user 7, got %v", st.owners)
+	}
+}
+
+func TestIdentityCallbackRequiresBindingCookie(t *testing.T) {
+	provider := oidctest.NewServer("vinylhound", "s3cret")
+	defer provider.Close()
+	provider.SetUser(oidctest.User{Subject: "attacker"})
+
+	st := newStubIdentityStore()
+	handler := newIdentityTestServer(t, provider, st)
+
+	// A login started in one browser cannot be completed in another, such as
+	// a victim's browser sent to the attacker's callback URL.
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/test/login", nil))
+	rr = completeProviderLogin(t, handler, provider, rr.Header().Get("Location"), nil, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	otherBrowser := []*http.Cookie{{Name: oidcBindingCookie, Value: "someone-else"}}
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/test/login", nil))
+	rr = completeProviderLogin(t, handler, provider, rr.Header().Get("Location"), otherBrowser, "")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if st.signIns != 0 {
+		t.Fatalf("expected no sign-in, got %d", st.signIns)
+	}
+}
+
+func TestIdentityLinkCallbackRequiresLinkingSession(t *testing.T) {
+	provider := oidctest.NewServer("vinylhound", "s3cret")
+	defer provider.Close()
+	provider.SetUser(oidctest.User{Subject: "victim"})
+
+	st := newStubIdentityStore()
+	handler := newIdentityTestServer(t, provider, st)
+
+	for _, token := range []string{"", "session-8"} {
+		authURL, cookies := startIdentityLink(t, handler, "session-7")
+		rr := completeProviderLogin(t, handler, provider, authURL, cookies, token)
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("token %q: expected status 401, got %d: %s", token, rr.Code, rr.Body.String())
+		}
+	}
+	if len(st.owners) != 0 {
+		t.Fatalf("expected nothing linked, got %v", st.owners)
+	}
+}
+
+func TestIdentityErrorResponses(t *testing.T) {
+	provider := oidctest.NewServer("vinylhound", "s3cret")
+	defer provider.Close()
+	handler := newIdentityTestServer(t, provider, newStubIdentityStore())
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		want   int
+	}{
+		{name: "unknown provider", method: http.MethodGet, path: "/api/v1/auth/oidc/github/login", want: http.StatusNotFound},
+		{name: "provider error", method: http.MethodGet, path: "/api/v1/auth/oidc/test/callback?error=access_denied&state=x", want: http.StatusBadRequest},
+		{name: "missing code", method: http.MethodGet, path: "/api/v1/auth/oidc/test/callback?state=x", want: http.StatusBadRequest},
+		{name: "unknown state", method: http.MethodGet, path: "/api/v1/auth/oidc/test/callback?code=c&state=x", want: http.StatusBadRequest},
+		{name: "link without session", method: http.MethodPost, path: "/api/v1/me/identities/test", want: http.StatusUnauthorized},
+		{name: "link with bad session", method: http.MethodPost, path: "/api/v1/me/identities/test", token: "expired", want: http.StatusUnauthorized},
+		{name: "unlink last method", method: http.MethodDelete, path: "/api/v1/me/identities/3", token: "session-7", want: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewReader(nil))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestListIdentityProviders(t *testing.T) {
+	provider := oidctest.NewServer("vinylhound", "s3cret")
+	defer provider.Close()
+	handler := newIdentityTestServer(t, provider, newStubIdentityStore())
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/providers", nil))
+
+	var payload struct {
+		Providers []string `json:"providers"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(payload.Providers) != 1 || payload.Providers[0] != "test" {
+		t.Fatalf("unexpected providers: %v", payload.Providers)
+	}
+}
@@ -0,0 +1,37 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"vinylhound/internal/store"
+)
+
+func lookupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, store.ErrInvalidExternalID):
+		return http.StatusBadRequest
+	case errors.Is(err, store.ErrExternalIDNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// handleLookup resolves a provider ID, e.g. ?provider=spotify&id=..., to the
+// local artists, albums, tracks and releases imported with it.
+func (s *Server) handleLookup(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	provider, externalID := query.Get("provider"), query.Get("id")
+	if provider == "" || externalID == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "provider and id are required"})
+		return
+	}
+
+	ids, err := s.lookup.Lookup(r.Context(), provider, externalID, query.Get("type"))
+	if err != nil {
+		writeJSON(w, lookupErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"results": ids})
+}
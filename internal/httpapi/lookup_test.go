@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vinylhound/internal/store"
+)
+
+type stubLookupService struct {
+	lastType string
+}
+
+func (s *stubLookupService) Lookup(_ context.Context, provider, externalID, entityType string) ([]store.ExternalID, error) {
+	s.lastType = entityType
+	if entityType != "" && !store.IsEntityType(entityType) {
+		return nil, store.ErrInvalidExternalID
+	}
+	if provider != "spotify" || externalID != "0ETFjACtuP2ADo6LFhL6HN" {
+		return nil, store.ErrExternalIDNotFound
+	}
+	return []store.ExternalID{{EntityType: store.EntityAlbum, LocalID: 7, Provider: provider, ExternalID: externalID}}, nil
+}
+
+func TestHandleLookup(t *testing.T) {
+	lookupStub := &stubLookupService{}
+	server := newTestServer(t, nil, nil, nil)
+	server.lookup = lookupStub
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/lookup?provider=spotify&id=0ETFjACtuP2ADo6LFhL6HN&type=album", nil)
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Results []store.ExternalID `json:"results"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Results) != 1 || resp.Results[0].LocalID != 7 || lookupStub.lastType != store.EntityAlbum {
+		t.Fatalf("unexpected results %+v for type %q", resp.Results, lookupStub.lastType)
+	}
+
+	for target, want := range map[string]int{
+		"/api/v1/lookup?provider=spotify":                    http.StatusBadRequest,
+		"/api/v1/lookup?provider=spotify&id=x&type=playlist": http.StatusBadRequest,
+		"/api/v1/lookup?provider=apple_music&id=1441164426":  http.StatusNotFound,
+	} {
+		rr = httptest.NewRecorder()
+		server.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		if rr.Code != want {
+			t.Errorf("GET %s: expected %d, got %d", target, want, rr.Code)
+		}
+	}
+}
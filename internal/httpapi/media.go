@@ -0,0 +1,182 @@
+package httpapi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"vinylhound/internal/media"
+	"vinylhound/internal/store"
+)
+
+// mediaCacheControl lets browsers and proxies keep images for good: a URL
+// names the hash of the content, so the content behind it never changes.
+const mediaCacheControl = "public, max-age=31536000, immutable"
+
+// mediaErrorStatus maps media and photo errors to HTTP statuses.
+func mediaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, store.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, store.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, store.ErrMediaNotFound), errors.Is(err, media.ErrNotFound),
+		errors.Is(err, store.ErrAlbumNotFound), errors.Is(err, store.ErrCollectionNotFound),
+		errors.Is(err, store.ErrPhotoNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, media.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, media.ErrUnsupported):
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// handleGetMedia serves an original image, or a thumbnail when the path
+// names a size.
+func (s *Server) handleGetMedia(w http.ResponseWriter, r *http.Request) {
+	hash, size := r.PathValue("hash"), r.PathValue("size")
+
+	etag := `"` + hash + `"`
+	if size != "" {
+		etag = `"` + hash + "-" + size + `"`
+	}
+	if r.Header.Get("If-None-Match") == etag {
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", mediaCacheControl)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	image, body, err := s.media.Open(r.Context(), hash, size)
+	if err != nil {
+		writeJSON(w, mediaErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", image.ContentType)
+	w.Header().Set("Cache-Control", mediaCacheControl)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if image.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(image.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		io.Copy(w, body)
+	}
+}
+
+// handleGetAlbumCover redirects to the cover image of an album; ?size=
+// picks a thumbnail.
+func (s *Server) handleGetAlbumCover(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid album ID"})
+		return
+	}
+	size := r.URL.Query().Get("size")
+	if _, ok := media.SizeNamed(size); size != "" && !ok {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "size must be small, medium or large"})
+		return
+	}
+
+	cover, err := s.media.AlbumCover(r.Context(), id)
+	if err != nil {
+		writeJSON(w, mediaErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	// The cover of an album can change, so the redirect is only cached briefly.
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	http.Redirect(w, r, store.MediaPath(cover.Hash, size), http.StatusFound)
+}
+
+func (s *Server) handleListCollectionPhotos(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	collectionID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid collection ID"})
+		return
+	}
+
+	photos, err := s.media.ListCollectionPhotos(r.Context(), token, collectionID)
+	if err != nil {
+		writeJSON(w, mediaErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string][]store.CollectionPhoto{"photos": photos})
+}
+
+// handleAddCollectionPhoto takes a multipart form with the image in the
+// photo field and an optional caption.
+func (s *Server) handleAddCollectionPhoto(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	collectionID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid collection ID"})
+		return
+	}
+
+	// Leave room for the multipart headers and the caption.
+	r.Body = http.MaxBytesReader(w, r.Body, s.media.MaxBytes()+64<<10)
+	file, _, err := r.FormFile("photo")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, errorResponse{Error: media.ErrTooLarge.Error()})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "expected a multipart form with a photo file"})
+		return
+	}
+	defer file.Close()
+
+	photo, err := s.media.AddCollectionPhoto(r.Context(), token, collectionID, file, r.FormValue("caption"))
+	if err != nil {
+		writeJSON(w, mediaErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, photo)
+}
+
+func (s *Server) handleDeleteCollectionPhoto(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	collectionID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid collection ID"})
+		return
+	}
+	photoID, err := strconv.ParseInt(r.PathValue("photoId"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid photo ID"})
+		return
+	}
+
+	if err := s.media.DeleteCollectionPhoto(r.Context(), token, collectionID, photoID); err != nil {
+		writeJSON(w, mediaErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,233 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"vinylhound/internal/media"
+	"vinylhound/internal/store"
+)
+
+const testHash = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+type stubMediaService struct {
+	content []byte
+	err     error
+
+	opened      int
+	lastSize    string
+	lastToken   string
+	lastCaption string
+	uploaded    []byte
+}
+
+func (s *stubMediaService) Open(_ context.Context, hash, size string) (store.Media, io.ReadCloser, error) {
+	s.opened++
+	s.lastSize = size
+	if s.err != nil {
+		return store.Media{}, nil, s.err
+	}
+	if hash != testHash {
+		return store.Media{}, nil, store.ErrMediaNotFound
+	}
+	contentType, length := "image/png", int64(len(s.content))
+	if size != "" {
+		contentType, length = "image/jpeg", 0
+	}
+	return store.Media{Hash: hash, ContentType: contentType, Size: length}, io.NopCloser(bytes.NewReader(s.content)), nil
+}
+
+func (s *stubMediaService) AlbumCover(_ context.Context, albumID int64) (store.Media, error) {
+	if s.err != nil {
+		return store.Media{}, s.err
+	}
+	return store.Media{Hash: testHash}, nil
+}
+
+func (s *stubMediaService) MaxBytes() int64 {
+	return 1 << 10
+}
+
+func (s *stubMediaService) AddCollectionPhoto(_ context.Context, token string, collectionID int64, r io.Reader, caption string) (store.CollectionPhoto, error) {
+	s.lastToken = token
+	s.lastCaption = caption
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return store.CollectionPhoto{}, err
+	}
+	s.uploaded = data
+	return store.CollectionPhoto{ID: 1, CollectionID: collectionID, Caption: caption, URL: store.MediaPath(testHash, "")}, s.err
+}
+
+func (s *stubMediaService) ListCollectionPhotos(_ context.Context, token string, collectionID int64) ([]store.CollectionPhoto, error) {
+	s.lastToken = token
+	return []store.CollectionPhoto{}, s.err
+}
+
+func (s *stubMediaService) DeleteCollectionPhoto(_ context.Context, token string, collectionID, photoID int64) error {
+	s.lastToken = token
+	return s.err
+}
+
+func TestHandleGetMediaCaching(t *testing.T) {
+	mediaStub := &stubMediaService{content: []byte("png bytes")}
+	server := newTestServer(t, nil, nil, nil)
+	server.media = mediaStub
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/media/"+testHash, nil)
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || rr.Body.String() != "png bytes" {
+		t.Fatalf("expected the original, got %d: %q", rr.Code, rr.Body.String())
+	}
+	for header, want := range map[string]string{
+		"Content-Type":   "image/png",
+		"Content-Length": "9",
+		"Cache-Control":  mediaCacheControl,
+		"ETag":           `"` + testHash + `"`,
+	} {
+		if got := rr.Header().Get(header); got != want {
+			t.Errorf("%s = %q, want %q", header, got, want)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/media/"+testHash+"/small", nil)
+	rr = httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK || mediaStub.lastSize != "small" || rr.Header().Get("Content-Type") != "image/jpeg" {
+		t.Fatalf("expected the small thumbnail, got %d %q", rr.Code, rr.Header().Get("Content-Type"))
+	}
+	if etag := rr.Header().Get("ETag"); etag != `"`+testHash+`-small"` {
+		t.Fatalf("unexpected thumbnail ETag %q", etag)
+	}
+
+	// A revalidation is answered without reading the image.
+	opened := mediaStub.opened
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/media/"+testHash+"/small", nil)
+	req.Header.Set("If-None-Match", `"`+testHash+`-small"`)
+	rr = httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotModified || mediaStub.opened != opened {
+		t.Fatalf("expected 304 without opening the image, got %d", rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/media/unknown", nil)
+	rr = httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown image, got %d", rr.Code)
+	}
+}
+
+func TestHandleGetAlbumCover(t *testing.T) {
+	server := newTestServer(t, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/albums/3/cover?size=medium", nil)
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusFound || rr.Header().Get("Location") != "/api/v1/media/"+testHash+"/medium" {
+		t.Fatalf("unexpected redirect %d to %q", rr.Code, rr.Header().Get("Location"))
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/albums/3/cover?size=huge", nil)
+	rr = httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown size, got %d", rr.Code)
+	}
+
+	server.media = &stubMediaService{err: store.ErrMediaNotFound}
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/albums/3/cover", nil)
+	rr = httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an album without cover, got %d", rr.Code)
+	}
+}
+
+func photoUpload(t *testing.T, content []byte, caption string) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if caption != "" {
+		form.WriteField("caption", caption)
+	}
+	part, err := form.CreateFormFile("photo", "sleeve.jpg")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	part.Write(content)
+	form.Close()
+	return &body, form.FormDataContentType()
+}
+
+func TestHandleAddCollectionPhoto(t *testing.T) {
+	mediaStub := &stubMediaService{}
+	server := newTestServer(t, nil, nil, nil)
+	server.media = mediaStub
+
+	body, contentType := photoUpload(t, []byte("jpeg bytes"), "Gatefold")
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/collections/5/photos", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer token")
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if string(mediaStub.uploaded) != "jpeg bytes" || mediaStub.lastCaption != "Gatefold" || mediaStub.lastToken != "token" {
+		t.Fatalf("unexpected upload: %+v", mediaStub)
+	}
+
+	tests := []struct {
+		name  string
+		body  []byte
+		token string
+		err   error
+		want  int
+	}{
+		{"missing token", []byte("x"), "", nil, http.StatusUnauthorized},
+		{"too large", bytes.Repeat([]byte("x"), 80<<10), "token", nil, http.StatusRequestEntityTooLarge},
+		{"not an image", []byte("x"), "token", media.ErrUnsupported, http.StatusUnsupportedMediaType},
+		{"someone else's item", []byte("x"), "token", store.ErrForbidden, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		server.media = &stubMediaService{err: tt.err}
+		body, contentType := photoUpload(t, tt.body, "")
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/collections/5/photos", body)
+		req.Header.Set("Content-Type", contentType)
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rr := httptest.NewRecorder()
+		server.Routes().ServeHTTP(rr, req)
+
+		if rr.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, rr.Code)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/collections/5/photos", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer token")
+	rr = httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without a multipart form, got %d", rr.Code)
+	}
+}
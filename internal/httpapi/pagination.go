@@ -0,0 +1,55 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"vinylhound/internal/store"
+)
+
+// parsePage reads the cursor and limit query parameters of a paginated list.
+func parsePage(r *http.Request) (store.Page, error) {
+	query := r.URL.Query()
+	page := store.Page{Cursor: query.Get("cursor")}
+	if limit := query.Get("limit"); limit != "" {
+		size, err := strconv.Atoi(limit)
+		if err != nil || size < 1 || size > store.MaxPageSize {
+			return store.Page{}, fmt.Errorf("limit must be between 1 and %d", store.MaxPageSize)
+		}
+		page.Size = size
+	}
+	return page, nil
+}
+
+// setNextPage advertises the next page of a list. The Link header repeats
+// the request with the cursor replaced, so filters carry over; X-Next-Cursor
+// carries the bare cursor for lists whose body is a JSON array. Nothing is
+// set on the last page.
+func setNextPage(w http.ResponseWriter, r *http.Request, next string) {
+	if next == "" {
+		return
+	}
+	query := r.URL.Query()
+	query.Set("cursor", next)
+	link := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, link.String()))
+	w.Header().Set("X-Next-Cursor", next)
+}
+
+// listErrorStatus maps the errors shared by paginated lists and their query
+// specs to HTTP statuses.
+func listErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, store.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, store.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, store.ErrInvalidPage), errors.Is(err, store.ErrInvalidQuery):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
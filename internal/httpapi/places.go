@@ -38,12 +38,19 @@ func (s *Server) handleListVenues(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	venues, err := s.places.ListVenues(r.Context(), token)
+	page, err := parsePage(r)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	venues, next, err := s.places.ListVenues(r.Context(), token, page)
+	if err != nil {
+		writeJSON(w, listErrorStatus(err), errorResponse{Error: err.Error()})
 		return
 	}
 
+	setNextPage(w, r, next)
 	writeJSON(w, http.StatusOK, venues)
 }
 
@@ -145,12 +152,19 @@ func (s *Server) handleListRetailers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	retailers, err := s.places.ListRetailers(r.Context(), token)
+	page, err := parsePage(r)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
+	}
+
+	retailers, next, err := s.places.ListRetailers(r.Context(), token, page)
+	if err != nil {
+		writeJSON(w, listErrorStatus(err), errorResponse{Error: err.Error()})
 		return
 	}
 
+	setNextPage(w, r, next)
 	writeJSON(w, http.StatusOK, retailers)
 }
 
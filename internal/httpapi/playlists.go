@@ -87,16 +87,24 @@ func (s *Server) listPlaylists(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	playlists, err := s.playlists.List(r.Context(), token)
+	page, err := parsePage(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	playlists, next, err := s.playlists.List(r.Context(), token, page)
+	if err != nil {
+		http.Error(w, err.Error(), listErrorStatus(err))
 		return
 	}
 
+	setNextPage(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(struct {
-		Playlists []*models.Playlist `json:"playlists"`
-	}{Playlists: playlists})
+		Playlists  []*models.Playlist `json:"playlists"`
+		NextCursor string             `json:"next_cursor,omitempty"`
+	}{Playlists: playlists, NextCursor: next})
 }
 
 func (s *Server) getPlaylist(w http.ResponseWriter, r *http.Request, id int64) {
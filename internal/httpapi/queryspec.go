@@ -0,0 +1,54 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"vinylhound/internal/queryspec"
+)
+
+// parseListQuery reads the sort, filters and fields of a list request and
+// checks them with check, which returns the response keys selected by
+// fields. params names the query parameters the handler reads itself,
+// besides the cursor and limit of the page.
+func parseListQuery(r *http.Request, check func(queryspec.Spec) ([]string, error), params ...string) (queryspec.Spec, []string, error) {
+	spec, err := queryspec.Parse(r.URL.RawQuery, append([]string{"cursor", "limit"}, params...)...)
+	if err != nil {
+		return queryspec.Spec{}, nil, err
+	}
+	keys, err := check(spec)
+	if err != nil {
+		return queryspec.Spec{}, nil, err
+	}
+	return spec, keys, nil
+}
+
+// project narrows each item to the response keys selected with fields=,
+// always keeping id. Without keys the items are returned unchanged.
+func project[T any](items []T, keys []string) (any, error) {
+	if len(keys) == 0 {
+		return items, nil
+	}
+
+	projected := make([]map[string]json.RawMessage, 0, len(items))
+	for _, item := range items {
+		raw, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("project item: %w", err)
+		}
+		var all map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &all); err != nil {
+			return nil, fmt.Errorf("project item: %w", err)
+		}
+
+		selected := map[string]json.RawMessage{"id": all["id"]}
+		for _, key := range keys {
+			if value, ok := all[key]; ok {
+				selected[key] = value
+			}
+		}
+		projected = append(projected, selected)
+	}
+	return projected, nil
+}
@@ -0,0 +1,142 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"vinylhound/internal/store"
+	"vinylhound/shared/go/models"
+)
+
+type releaseRequest struct {
+	Format        models.ReleaseFormat `json:"format"`
+	Label         string               `json:"label"`
+	CatalogNumber string               `json:"catalog_number"`
+	Country       string               `json:"country"`
+	PressingYear  int                  `json:"pressing_year"`
+	Barcode       string               `json:"barcode"`
+	ColorVariant  string               `json:"color_variant"`
+	Description   string               `json:"description"`
+}
+
+func (req releaseRequest) release() models.Release {
+	return models.Release{
+		Format:        req.Format,
+		Label:         req.Label,
+		CatalogNumber: req.CatalogNumber,
+		Country:       req.Country,
+		PressingYear:  req.PressingYear,
+		Barcode:       req.Barcode,
+		ColorVariant:  req.ColorVariant,
+		Description:   req.Description,
+	}
+}
+
+// releaseErrorStatus maps release workflow errors to HTTP statuses.
+func releaseErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, store.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, store.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, store.ErrInvalidRelease):
+		return http.StatusBadRequest
+	case errors.Is(err, store.ErrAlbumNotFound), errors.Is(err, store.ErrReleaseNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *Server) handleListReleases(w http.ResponseWriter, r *http.Request) {
+	albumID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid album ID"})
+		return
+	}
+
+	releases, err := s.albums.ListReleases(r.Context(), albumID)
+	if err != nil {
+		writeJSON(w, releaseErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"releases": releases})
+}
+
+func (s *Server) handleCreateRelease(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	albumID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid album ID"})
+		return
+	}
+
+	var req releaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+
+	release := req.release()
+	release.AlbumID = albumID
+
+	created, err := s.albums.CreateRelease(r.Context(), token, release)
+	if err != nil {
+		writeJSON(w, releaseErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) handleGetRelease(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid release ID"})
+		return
+	}
+
+	release, err := s.albums.GetRelease(r.Context(), id)
+	if err != nil {
+		writeJSON(w, releaseErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, release)
+}
+
+func (s *Server) handleUpdateRelease(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid release ID"})
+		return
+	}
+
+	var req releaseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+
+	updated, err := s.albums.UpdateRelease(r.Context(), token, id, req.release())
+	if err != nil {
+		writeJSON(w, releaseErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updated)
+}
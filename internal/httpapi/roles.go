@@ -0,0 +1,57 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"vinylhound/internal/store"
+	"vinylhound/shared/go/models"
+)
+
+type setRoleRequest struct {
+	Role string `json:"role"`
+}
+
+func (s *Server) handleSetUserRole(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid user id"})
+		return
+	}
+
+	var req setRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+
+	role := models.Role(req.Role)
+	if err := s.users.SetRole(r.Context(), token, userID, role); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, store.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		case errors.Is(err, store.ErrForbidden):
+			status = http.StatusForbidden
+		case errors.Is(err, store.ErrInvalidRole):
+			status = http.StatusBadRequest
+		case errors.Is(err, store.ErrUserNotFound):
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		UserID int64       `json:"user_id"`
+		Role   models.Role `json:"role"`
+	}{UserID: userID, Role: role})
+}
@@ -23,7 +23,7 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Query        string `json:"query"`
 		Type         string `json:"type"`     // "artist", "album", "track", or "all"
-		Provider     string `json:"provider"` // "spotify", "apple_music", or "all"
+		Provider     string `json:"provider"` // a registered provider, or "all"
 		Limit        int    `json:"limit"`
 		StoreResults bool   `json:"store_results"`
 	}
@@ -48,6 +48,7 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 		Provider:     req.Provider,
 		Limit:        req.Limit,
 		StoreResults: req.StoreResults,
+		Token:        extractBearerToken(r.Header.Get("Authorization")),
 	}
 
 	results, err := s.searchService.Search(r.Context(), opts)
@@ -76,7 +77,7 @@ func (s *Server) handleImportAlbum(w http.ResponseWriter, r *http.Request) {
 
 	var req struct {
 		AlbumID  string `json:"album_id"`
-		Provider string `json:"provider"` // "spotify" or "apple_music"
+		Provider string `json:"provider"` // a registered provider
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -97,17 +98,7 @@ func (s *Server) handleImportAlbum(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var provider musicapi.MusicProvider
-	switch req.Provider {
-	case "spotify":
-		provider = musicapi.ProviderSpotify
-	case "apple_music":
-		provider = musicapi.ProviderAppleMusic
-	default:
-		log.Printf("ImportAlbum: invalid provider %q", req.Provider)
-		http.Error(w, "Invalid provider. Must be 'spotify' or 'apple_music'", http.StatusBadRequest)
-		return
-	}
+	provider := musicapi.MusicProvider(req.Provider)
 
 	log.Printf("ImportAlbum: attempting import album=%s provider=%s", req.AlbumID, provider)
 
@@ -118,6 +109,16 @@ func (s *Server) handleImportAlbum(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
+		if errors.Is(err, store.ErrForbidden) {
+			log.Printf("ImportAlbum: import by non-curator rejected for album=%s provider=%s", req.AlbumID, provider)
+			http.Error(w, "Curator role required", http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, musicapi.ErrProviderNotRegistered) {
+			log.Printf("ImportAlbum: invalid provider %q", req.Provider)
+			http.Error(w, "Invalid provider", http.StatusBadRequest)
+			return
+		}
 		log.Printf("ImportAlbum: ERROR - failed importing album=%s provider=%s: %v", req.AlbumID, provider, err)
 
 		// Return the actual error message to help with debugging
@@ -165,22 +166,52 @@ func extractBearerToken(header string) string {
 	return strings.TrimSpace(parts[1])
 }
 
-// handleProviders returns list of available music providers
+// handleLookupISRC finds the tracks recorded under an ISRC at the providers
+// that support ISRC lookups
+func (s *Server) handleLookupISRC(w http.ResponseWriter, r *http.Request) {
+	tracks, err := s.searchService.LookupISRC(r.Context(), r.PathValue("isrc"))
+	if err != nil {
+		if errors.Is(err, searchservice.ErrInvalidISRC) {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+	if tracks == nil {
+		tracks = []searchservice.TrackResult{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"tracks": tracks})
+}
+
+// handleLookupBarcode finds the albums released with a UPC or EAN at the
+// providers that support barcode lookups
+func (s *Server) handleLookupBarcode(w http.ResponseWriter, r *http.Request) {
+	albums, err := s.searchService.LookupBarcode(r.Context(), r.PathValue("barcode"))
+	if err != nil {
+		if errors.Is(err, searchservice.ErrInvalidBarcode) {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+		return
+	}
+	if albums == nil {
+		albums = []searchservice.AlbumResult{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"albums": albums})
+}
+
+// handleProviders returns the registered music providers and what they support
 func (s *Server) handleProviders(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	providers := []map[string]interface{}{
-		{
-			"id":   "spotify",
-			"name": "Spotify",
-		},
-		{
-			"id":   "apple_music",
-			"name": "Apple Music",
-		},
+	providers := s.searchService.Providers()
+	if providers == nil {
+		providers = []musicapi.ProviderInfo{}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -189,7 +220,29 @@ func (s *Server) handleProviders(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleGetArtist retrieves full artist details from Spotify
+// handleGetDiscogsMaster returns a Discogs master release with the versions
+// it was pressed as
+func (s *Server) handleGetDiscogsMaster(w http.ResponseWriter, r *http.Request) {
+	master, err := s.searchService.GetDiscogsMaster(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, providerErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, master)
+}
+
+// handleGetDiscogsLabel returns a Discogs record label with its releases
+func (s *Server) handleGetDiscogsLabel(w http.ResponseWriter, r *http.Request) {
+	label, err := s.searchService.GetDiscogsLabel(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, providerErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, label)
+}
+
+// handleGetArtist retrieves full artist details and albums from a provider,
+// Spotify unless ?provider= names another
 func (s *Server) handleGetArtist(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -202,9 +255,9 @@ func (s *Server) handleGetArtist(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	artist, albums, err := s.searchService.GetArtistWithAlbums(r.Context(), artistID)
+	artist, albums, err := s.searchService.GetArtistWithAlbums(r.Context(), providerParam(r), artistID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), providerErrorStatus(err))
 		return
 	}
 
@@ -228,22 +281,14 @@ func (s *Server) handleArtists(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleListArtists retrieves all artists from the database
-func (s *Server) handleListArtists(w http.ResponseWriter, r *http.Request) {
-	artists, err := s.searchService.GetAllArtists(r.Context())
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+// handleSaveArtist saves an artist to the database; curators only
+func (s *Server) handleSaveArtist(w http.ResponseWriter, r *http.Request) {
+	token := extractBearerToken(r.Header.Get("Authorization"))
+	if token == "" {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"artists": artists,
-	})
-}
-
-// handleSaveArtist saves an artist to the database
-func (s *Server) handleSaveArtist(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		ExternalID  string   `json:"external_id"`
 		Name        string   `json:"name"`
@@ -276,8 +321,15 @@ func (s *Server) handleSaveArtist(w http.ResponseWriter, r *http.Request) {
 		ExternalURL: req.ExternalURL,
 	}
 
-	if err := s.searchService.SaveArtist(r.Context(), artist); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err := s.searchService.SaveArtist(r.Context(), token, artist); err != nil {
+		switch {
+		case errors.Is(err, store.ErrUnauthorized):
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		case errors.Is(err, store.ErrForbidden):
+			http.Error(w, "Curator role required", http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -287,7 +339,8 @@ func (s *Server) handleSaveArtist(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleGetAlbumDetails retrieves full album details including all tracks from Spotify
+// handleGetAlbumDetails retrieves full album details including all tracks
+// from a provider, Spotify unless ?provider= names another
 func (s *Server) handleGetAlbumDetails(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -300,9 +353,9 @@ func (s *Server) handleGetAlbumDetails(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	album, tracks, err := s.searchService.GetAlbumWithTracks(r.Context(), albumID)
+	album, tracks, err := s.searchService.GetAlbumWithTracks(r.Context(), providerParam(r), albumID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), providerErrorStatus(err))
 		return
 	}
 
@@ -312,3 +365,22 @@ func (s *Server) handleGetAlbumDetails(w http.ResponseWriter, r *http.Request) {
 		"tracks": tracks,
 	})
 }
+
+// providerParam reads the provider a details request is for.
+func providerParam(r *http.Request) musicapi.MusicProvider {
+	if provider := r.URL.Query().Get("provider"); provider != "" {
+		return musicapi.MusicProvider(provider)
+	}
+	return musicapi.ProviderSpotify
+}
+
+func providerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, musicapi.ErrProviderNotRegistered), errors.Is(err, musicapi.ErrCapabilityUnsupported):
+		return http.StatusBadRequest
+	case errors.Is(err, musicapi.ErrRateLimited):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
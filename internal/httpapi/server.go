@@ -4,13 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
+	"net/netip"
 	"strconv"
 	"strings"
+	"time"
 
 	"vinylhound/internal/app/artists"
+	"vinylhound/internal/app/identities"
 	"vinylhound/internal/app/songs"
 	"vinylhound/internal/musicapi"
+	"vinylhound/internal/queryspec"
 	"vinylhound/internal/searchservice"
 	"vinylhound/internal/store"
 	"vinylhound/shared/go/models"
@@ -19,40 +24,78 @@ import (
 // UserService captures the user-facing operations needed by the HTTP handlers.
 type UserService interface {
 	Signup(ctx context.Context, username, password string, content []string) error
-	Authenticate(ctx context.Context, username, password string) (string, error)
+	Authenticate(ctx context.Context, username, password string, meta store.SessionMetadata) (store.SessionTokens, error)
+	Refresh(ctx context.Context, refreshToken string) (store.SessionTokens, error)
 	Content(ctx context.Context, token string) ([]string, error)
 	UpdateContent(ctx context.Context, token string, content []string) error
+	Logout(ctx context.Context, token string) error
+	Sessions(ctx context.Context, token string) ([]store.Session, error)
+	RevokeSession(ctx context.Context, token string, sessionID int64) error
+	RevokeOtherSessions(ctx context.Context, token string) (int64, error)
+	ChangePassword(ctx context.Context, token, currentPassword, newPassword string) (int64, error)
+	RequestPasswordReset(ctx context.Context, username string) error
+	ResetPassword(ctx context.Context, resetToken, newPassword string) error
+	DeleteAccount(ctx context.Context, token, password string, mode store.AccountDeletionMode) error
+	SetRole(ctx context.Context, token string, userID int64, role models.Role) error
+	CreateAPIToken(ctx context.Context, token, name string, scopes []string, expiresAt *time.Time) (store.APIToken, string, error)
+	APITokens(ctx context.Context, token string) ([]store.APIToken, error)
+	RevokeAPIToken(ctx context.Context, token string, tokenID int64) error
+	APITokenScopes(ctx context.Context, token string) ([]string, error)
+	CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string, meta store.SessionMetadata) (store.SessionTokens, error)
+	TwoFactorStatus(ctx context.Context, token string) (store.TwoFactorStatus, error)
+	BeginTOTPEnrollment(ctx context.Context, token string) (store.TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(ctx context.Context, token, code string) ([]string, error)
+	RegenerateRecoveryCodes(ctx context.Context, token, code string) ([]string, error)
+	DisableTwoFactor(ctx context.Context, token, code string) error
+}
+
+// IdentityService covers sign-in and account linking through external
+// OpenID Connect providers.
+type IdentityService interface {
+	Providers() []string
+	BeginLogin(ctx context.Context, provider string) (identities.Authorization, error)
+	BeginLink(ctx context.Context, token, provider string) (identities.Authorization, error)
+	Complete(ctx context.Context, callback identities.Callback, meta store.SessionMetadata) (identities.CallbackResult, error)
+	Identities(ctx context.Context, token string) ([]store.Identity, error)
+	Unlink(ctx context.Context, token string, identityID int64) error
 }
 
 // ArtistService describes artist catalogue workflows.
 type ArtistService interface {
-	List(ctx context.Context, filter artists.Filter) ([]artists.Artist, error)
+	List(ctx context.Context, filter artists.Filter, page store.Page) ([]store.ArtistListing, string, error)
+	Get(ctx context.Context, id int64) (store.ArtistDiscography, error)
 }
 
 // AlbumService exposes album-specific workflows.
 type AlbumService interface {
 	Create(ctx context.Context, token string, album store.Album) (store.Album, error)
-	ListByUser(ctx context.Context, token string) ([]store.Album, error)
-	List(ctx context.Context, filter store.AlbumFilter) ([]store.Album, error)
+	ListByUser(ctx context.Context, token string, spec queryspec.Spec, page store.Page) ([]store.Album, string, error)
+	List(ctx context.Context, filter store.AlbumFilter, spec queryspec.Spec, page store.Page) ([]store.Album, string, error)
 	Get(ctx context.Context, id int64) (store.Album, error)
+	Update(ctx context.Context, token string, id int64, patch store.AlbumPatch) (store.Album, error)
+	Delete(ctx context.Context, token string, id int64) error
+	CreateRelease(ctx context.Context, token string, release models.Release) (models.Release, error)
+	UpdateRelease(ctx context.Context, token string, id int64, release models.Release) (models.Release, error)
+	GetRelease(ctx context.Context, id int64) (models.Release, error)
+	ListReleases(ctx context.Context, albumID int64) ([]models.Release, error)
 }
 
 // SongService coordinates track-level operations.
 type SongService interface {
 	ListByAlbum(ctx context.Context, albumID int64) ([]songs.Song, error)
-	Search(ctx context.Context, filter store.SongFilter) ([]store.Song, error)
+	Search(ctx context.Context, filter store.SongFilter, page store.Page) ([]store.Song, string, error)
 	Get(ctx context.Context, id int64) (store.Song, error)
 }
 
 // RatingsService describes preference-related workflows.
 type RatingsService interface {
-	Upsert(ctx context.Context, token string, albumID int64, rating *int, favorited bool) error
-	ListByUser(ctx context.Context, token string) ([]store.AlbumPreference, error)
+	Upsert(ctx context.Context, token string, albumID int64, releaseID *int64, rating *int, favorited bool) error
+	ListByUser(ctx context.Context, token string, page store.Page) ([]store.AlbumPreference, string, error)
 }
 
 // PlaylistService coordinates playlist-related operations.
 type PlaylistService interface {
-	List(ctx context.Context, token string) ([]*models.Playlist, error)
+	List(ctx context.Context, token string, page store.Page) ([]*models.Playlist, string, error)
 	Get(ctx context.Context, id int64) (*models.Playlist, error)
 	Create(ctx context.Context, token string, playlist *models.Playlist) (*models.Playlist, error)
 	Update(ctx context.Context, token string, id int64, playlist *models.Playlist) (*models.Playlist, error)
@@ -65,7 +108,8 @@ type PlaylistService interface {
 type FavoritesService interface {
 	FavoriteTrack(ctx context.Context, token string, trackID int64) (*models.Favorite, bool, error)
 	UnfavoriteTrack(ctx context.Context, token string, trackID int64) error
-	ListTrackFavorites(ctx context.Context, token string) ([]*models.Favorite, error)
+	List(ctx context.Context, token string, page store.Page) ([]*models.Favorite, string, error)
+	ListTrackFavorites(ctx context.Context, token string, page store.Page) ([]*models.Favorite, string, error)
 }
 
 // SearchService provides unified search across music providers.
@@ -73,21 +117,25 @@ type SearchService interface {
 	Search(ctx context.Context, opts searchservice.SearchOptions) (*searchservice.SearchResults, error)
 	ImportAlbumForUser(ctx context.Context, token string, albumID string, provider musicapi.MusicProvider) (int64, error)
 	ImportAlbum(ctx context.Context, albumID string, provider musicapi.MusicProvider) error
-	GetArtistWithAlbums(ctx context.Context, artistID string) (*musicapi.Artist, []musicapi.Album, error)
-	GetAlbumWithTracks(ctx context.Context, albumID string) (*musicapi.Album, []musicapi.Track, error)
-	GetAllArtists(ctx context.Context) ([]musicapi.Artist, error)
-	SaveArtist(ctx context.Context, artist musicapi.Artist) error
+	GetArtistWithAlbums(ctx context.Context, provider musicapi.MusicProvider, artistID string) (*musicapi.Artist, []musicapi.Album, error)
+	GetAlbumWithTracks(ctx context.Context, provider musicapi.MusicProvider, albumID string) (*musicapi.Album, []musicapi.Track, error)
+	LookupISRC(ctx context.Context, isrc string) ([]searchservice.TrackResult, error)
+	LookupBarcode(ctx context.Context, barcode string) ([]searchservice.AlbumResult, error)
+	GetDiscogsMaster(ctx context.Context, masterID string) (*musicapi.DiscogsMaster, error)
+	GetDiscogsLabel(ctx context.Context, labelID string) (*musicapi.DiscogsLabel, error)
+	Providers() []musicapi.ProviderInfo
+	SaveArtist(ctx context.Context, token string, artist musicapi.Artist) error
 }
 
 // PlaceService coordinates place-related operations (venues and retailers)
 type PlaceService interface {
 	CreateVenue(ctx context.Context, token string, venue *models.Venue) (*models.Venue, error)
-	ListVenues(ctx context.Context, token string) ([]*models.Venue, error)
+	ListVenues(ctx context.Context, token string, page store.Page) ([]*models.Venue, string, error)
 	GetVenue(ctx context.Context, id int64) (*models.Venue, error)
 	UpdateVenue(ctx context.Context, token string, id int64, venue *models.Venue) (*models.Venue, error)
 	DeleteVenue(ctx context.Context, token string, id int64) error
 	CreateRetailer(ctx context.Context, token string, retailer *models.Retailer) (*models.Retailer, error)
-	ListRetailers(ctx context.Context, token string) ([]*models.Retailer, error)
+	ListRetailers(ctx context.Context, token string, page store.Page) ([]*models.Retailer, string, error)
 	GetRetailer(ctx context.Context, id int64) (*models.Retailer, error)
 	UpdateRetailer(ctx context.Context, token string, id int64, retailer *models.Retailer) (*models.Retailer, error)
 	DeleteRetailer(ctx context.Context, token string, id int64) error
@@ -96,20 +144,55 @@ type PlaceService interface {
 // ConcertService coordinates concert-related operations
 type ConcertService interface {
 	Create(ctx context.Context, token string, concert *models.Concert) (*models.Concert, error)
-	List(ctx context.Context, token string) ([]*models.ConcertWithDetails, error)
+	List(ctx context.Context, token string, spec queryspec.Spec, page store.Page) ([]*models.ConcertWithDetails, string, error)
 	Get(ctx context.Context, id int64) (*models.ConcertWithDetails, error)
 	Update(ctx context.Context, token string, id int64, concert *models.Concert) (*models.Concert, error)
 	Delete(ctx context.Context, token string, id int64) error
-	ListUpcoming(ctx context.Context, token string) ([]*models.ConcertWithDetails, error)
-	ListByVenue(ctx context.Context, venueID int64) ([]*models.ConcertWithDetails, error)
-	ListByArtist(ctx context.Context, token string, artistName string) ([]*models.ConcertWithDetails, error)
+	ListByVenue(ctx context.Context, venueID int64, spec queryspec.Spec, page store.Page) ([]*models.ConcertWithDetails, string, error)
 	MarkAttended(ctx context.Context, token string, concertID int64, rating *int) error
 }
 
+// DuplicateService exposes the catalog duplicate review queue.
+type DuplicateService interface {
+	Scan(ctx context.Context, token string) (int, error)
+	List(ctx context.Context, token string, status string, page store.Page) ([]store.DuplicateCandidate, string, error)
+	Dismiss(ctx context.Context, token string, id int64) error
+	Merge(ctx context.Context, token string, id int64, keepID int64) (store.Album, error)
+	MergeAlbums(ctx context.Context, token string, keepID, mergeID int64) (store.Album, error)
+}
+
+// CatalogService runs ranked search across the catalog.
+type CatalogService interface {
+	Search(ctx context.Context, search store.CatalogSearch) ([]store.CatalogSearchResult, error)
+}
+
+// GenreService exposes the genre taxonomy.
+type GenreService interface {
+	List(ctx context.Context) ([]store.Genre, error)
+	Get(ctx context.Context, id int64) (store.Genre, error)
+	Create(ctx context.Context, token string, genre store.Genre) (store.Genre, error)
+	Update(ctx context.Context, token string, id int64, patch store.GenrePatch) (store.Genre, error)
+}
+
+// MediaService stores and serves cover art and collection photos.
+type MediaService interface {
+	Open(ctx context.Context, hash, size string) (store.Media, io.ReadCloser, error)
+	AlbumCover(ctx context.Context, albumID int64) (store.Media, error)
+	MaxBytes() int64
+	AddCollectionPhoto(ctx context.Context, token string, collectionID int64, r io.Reader, caption string) (store.CollectionPhoto, error)
+	ListCollectionPhotos(ctx context.Context, token string, collectionID int64) ([]store.CollectionPhoto, error)
+	DeleteCollectionPhoto(ctx context.Context, token string, collectionID, photoID int64) error
+}
+
+// LookupService resolves provider IDs to local artists, albums and tracks.
+type LookupService interface {
+	Lookup(ctx context.Context, provider, externalID, entityType string) ([]store.ExternalID, error)
+}
+
 // CollectionService coordinates album collection operations (wishlist and owned)
 type CollectionService interface {
 	Add(ctx context.Context, token string, collection *models.AlbumCollection) (*models.AlbumCollection, error)
-	List(ctx context.Context, token string, filter models.CollectionFilter) ([]*models.AlbumCollectionWithDetails, error)
+	List(ctx context.Context, token string, filter models.CollectionFilter, spec queryspec.Spec, page store.Page) ([]*models.AlbumCollectionWithDetails, string, error)
 	Get(ctx context.Context, id int64) (*models.AlbumCollectionWithDetails, error)
 	Update(ctx context.Context, token string, id int64, collection *models.AlbumCollection) (*models.AlbumCollection, error)
 	Remove(ctx context.Context, token string, id int64) error
@@ -130,6 +213,14 @@ type Server struct {
 	places        PlaceService
 	concerts      ConcertService
 	collections   CollectionService
+	identities    IdentityService
+	duplicates    DuplicateService
+	catalog       CatalogService
+	genres        GenreService
+	media         MediaService
+	lookup        LookupService
+
+	trustedProxies []netip.Prefix
 }
 
 // New configures a Server with the given Store implementation.
@@ -145,6 +236,12 @@ func New(
 	places PlaceService,
 	concerts ConcertService,
 	collections CollectionService,
+	identities IdentityService,
+	duplicates DuplicateService,
+	catalog CatalogService,
+	genres GenreService,
+	media MediaService,
+	lookup LookupService,
 ) *Server {
 	return &Server{
 		users:         users,
@@ -158,6 +255,12 @@ func New(
 		places:        places,
 		concerts:      concerts,
 		collections:   collections,
+		identities:    identities,
+		duplicates:    duplicates,
+		catalog:       catalog,
+		genres:        genres,
+		media:         media,
+		lookup:        lookup,
 	}
 }
 
@@ -174,12 +277,69 @@ func (s *Server) Routes() http.Handler {
 	// API v1 routes (standardized)
 	mux.HandleFunc("/api/v1/auth/signup", s.handleSignup)
 	mux.HandleFunc("/api/v1/auth/login", s.handleLogin)
+	mux.HandleFunc("/api/v1/auth/logout", s.handleLogout)
+	mux.HandleFunc("/api/v1/auth/refresh", s.handleRefresh)
 	mux.HandleFunc("/api/v1/users/profile", s.handleContent) // me/content -> users/profile
 	mux.HandleFunc("/api/v1/me/albums", s.handleAlbums)
 	mux.HandleFunc("/api/v1/me/albums/preferences", s.handleAlbumPreferences)
 	mux.HandleFunc("/api/v1/me/albums/", s.handleAlbumPreference)
 	mux.HandleFunc("/api/v1/albums", s.handleAlbumsList)
 	mux.HandleFunc("/api/v1/albums/", s.handleAlbum) // Changed from /api/album
+	mux.HandleFunc("PATCH /api/v1/albums/{id}", s.handleUpdateAlbum)
+	mux.HandleFunc("DELETE /api/v1/albums/{id}", s.handleDeleteAlbum)
+	mux.HandleFunc("GET /api/v1/albums/{id}/tracks", s.handleListAlbumTracks)
+	mux.HandleFunc("GET /api/v1/albums/{id}/releases", s.handleListReleases)
+	mux.HandleFunc("GET /api/v1/albums/{id}/cover", s.handleGetAlbumCover)
+	mux.HandleFunc("POST /api/v1/albums/{id}/releases", s.handleCreateRelease)
+	mux.HandleFunc("GET /api/v1/releases/{id}", s.handleGetRelease)
+	mux.HandleFunc("PUT /api/v1/releases/{id}", s.handleUpdateRelease)
+
+	// Catalog search and duplicate review routes
+	mux.HandleFunc("GET /api/v1/catalog/search", s.handleCatalogSearch)
+	mux.HandleFunc("POST /api/v1/catalog/duplicates/scan", s.handleScanDuplicates)
+	mux.HandleFunc("GET /api/v1/catalog/duplicates", s.handleListDuplicates)
+	mux.HandleFunc("POST /api/v1/catalog/duplicates/{id}/merge", s.handleMergeDuplicate)
+	mux.HandleFunc("POST /api/v1/catalog/duplicates/{id}/dismiss", s.handleDismissDuplicate)
+	mux.HandleFunc("POST /api/v1/catalog/merges", s.handleMergeAlbums)
+	mux.HandleFunc("GET /api/v1/catalog/genres", s.handleListGenres)
+	mux.HandleFunc("POST /api/v1/catalog/genres", s.handleCreateGenre)
+	mux.HandleFunc("GET /api/v1/catalog/genres/{id}", s.handleGetGenre)
+	mux.HandleFunc("PATCH /api/v1/catalog/genres/{id}", s.handleUpdateGenre)
+
+	// External identity provider routes
+	mux.HandleFunc("GET /api/v1/auth/oidc/providers", s.handleListIdentityProviders)
+	mux.HandleFunc("GET /api/v1/auth/oidc/{provider}/login", s.handleIdentityLogin)
+	mux.HandleFunc("GET /api/v1/auth/oidc/{provider}/callback", s.handleIdentityCallback)
+	mux.HandleFunc("GET /api/v1/me/identities", s.handleListIdentities)
+	mux.HandleFunc("POST /api/v1/me/identities/{provider}", s.handleLinkIdentity)
+	mux.HandleFunc("DELETE /api/v1/me/identities/{id}", s.handleUnlinkIdentity)
+
+	// Session routes
+	mux.HandleFunc("GET /api/v1/me/sessions", s.handleListSessions)
+	mux.HandleFunc("DELETE /api/v1/me/sessions/{id}", s.handleRevokeSession)
+	mux.HandleFunc("POST /api/v1/me/sessions/revoke-others", s.handleRevokeOtherSessions)
+
+	// Account routes
+	mux.HandleFunc("PUT /api/v1/me/password", s.handleChangePassword)
+	mux.HandleFunc("DELETE /api/v1/me", s.handleDeleteAccount)
+	mux.HandleFunc("POST /api/v1/auth/password-reset", s.handleRequestPasswordReset)
+	mux.HandleFunc("POST /api/v1/auth/password-reset/confirm", s.handleConfirmPasswordReset)
+
+	// Two-factor authentication routes
+	mux.HandleFunc("POST /api/v1/auth/login/2fa", s.handleTwoFactorLogin)
+	mux.HandleFunc("GET /api/v1/me/2fa", s.handleTwoFactorStatus)
+	mux.HandleFunc("POST /api/v1/me/2fa/totp", s.handleBeginTOTPEnrollment)
+	mux.HandleFunc("POST /api/v1/me/2fa/totp/confirm", s.handleConfirmTOTPEnrollment)
+	mux.HandleFunc("POST /api/v1/me/2fa/recovery-codes", s.handleRegenerateRecoveryCodes)
+	mux.HandleFunc("POST /api/v1/me/2fa/disable", s.handleDisableTwoFactor)
+
+	// Personal access token routes
+	mux.HandleFunc("POST /api/v1/me/tokens", s.handleCreateAPIToken)
+	mux.HandleFunc("GET /api/v1/me/tokens", s.handleListAPITokens)
+	mux.HandleFunc("DELETE /api/v1/me/tokens/{id}", s.handleRevokeAPIToken)
+
+	// Admin routes
+	mux.HandleFunc("PUT /api/v1/admin/users/{id}/role", s.handleSetUserRole)
 
 	// Playlist routes
 	mux.HandleFunc("/api/v1/playlists", s.handlePlaylists)
@@ -204,10 +364,16 @@ func (s *Server) Routes() http.Handler {
 	// Search routes
 	mux.HandleFunc("/api/v1/search", s.handleSearch)
 	mux.HandleFunc("/api/v1/import/album", s.handleImportAlbum)
+	mux.HandleFunc("GET /api/v1/search/isrc/{isrc}", s.handleLookupISRC)
+	mux.HandleFunc("GET /api/v1/search/barcode/{barcode}", s.handleLookupBarcode)
 	mux.HandleFunc("/api/v1/providers", s.handleProviders)
+	mux.HandleFunc("GET /api/v1/providers/discogs/masters/{id}", s.handleGetDiscogsMaster)
+	mux.HandleFunc("GET /api/v1/providers/discogs/labels/{id}", s.handleGetDiscogsLabel)
+	mux.HandleFunc("GET /api/v1/lookup", s.handleLookup)
 	mux.HandleFunc("/api/v1/artist", s.handleGetArtist)
 	mux.HandleFunc("/api/v1/album/details", s.handleGetAlbumDetails)
 	mux.HandleFunc("/api/v1/artists", s.handleArtists)
+	mux.HandleFunc("GET /api/v1/artists/{id}", s.handleGetCatalogArtist)
 
 	// Venue routes
 	mux.HandleFunc("POST /api/v1/venues", s.handleCreateVenue)
@@ -235,6 +401,13 @@ func (s *Server) Routes() http.Handler {
 	mux.HandleFunc("/api/v1/collections", s.handleCollections)
 	mux.HandleFunc("/api/v1/collections/", s.handleCollection)
 	mux.HandleFunc("/api/v1/collections/stats", s.handleCollectionStats)
+	mux.HandleFunc("GET /api/v1/collections/{id}/photos", s.handleListCollectionPhotos)
+	mux.HandleFunc("POST /api/v1/collections/{id}/photos", s.handleAddCollectionPhoto)
+	mux.HandleFunc("DELETE /api/v1/collections/{id}/photos/{photoId}", s.handleDeleteCollectionPhoto)
+
+	// Cover art and photos
+	mux.HandleFunc("GET /api/v1/media/{hash}", s.handleGetMedia)
+	mux.HandleFunc("GET /api/v1/media/{hash}/{size}", s.handleGetMedia)
 
 	// Legacy routes (for backward compatibility) - TODO: Remove after frontend migration
 	mux.HandleFunc("/api/signup", s.handleSignup)
@@ -246,7 +419,7 @@ func (s *Server) Routes() http.Handler {
 	mux.HandleFunc("/api/albums", s.handleAlbumsList)
 	mux.HandleFunc("/api/album", s.handleAlbum)
 
-	return mux
+	return s.withAPITokenScopes(mux)
 }
 
 type signupRequest struct {
@@ -261,7 +434,9 @@ type loginRequest struct {
 }
 
 type tokenResponse struct {
-	Token string `json:"token"`
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at,omitzero"`
 }
 
 type errorResponse struct {
@@ -269,17 +444,19 @@ type errorResponse struct {
 }
 
 type albumRequest struct {
-	Artist      string   `json:"artist"`
-	Title       string   `json:"title"`
-	ReleaseYear int      `json:"releaseYear"`
-	Tracks      []string `json:"trackList"`
-	Genres      []string `json:"genreList"`
-	Rating      int      `json:"rating"`
+	Artist      string               `json:"artist"`
+	Artists     []store.ArtistCredit `json:"artists"`
+	Title       string               `json:"title"`
+	ReleaseYear int                  `json:"releaseYear"`
+	Tracks      []store.Track        `json:"trackList"`
+	Genres      []string             `json:"genreList"`
+	Rating      int                  `json:"rating"`
 }
 
 type albumPreferenceRequest struct {
-	Rating    *int `json:"rating"`
-	Favorited bool `json:"favorited"`
+	ReleaseID *int64 `json:"releaseId"`
+	Rating    *int   `json:"rating"`
+	Favorited bool   `json:"favorited"`
 }
 
 func (s *Server) handleSignup(w http.ResponseWriter, r *http.Request) {
@@ -319,17 +496,13 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := s.users.Authenticate(r.Context(), req.Username, req.Password)
+	tokens, err := s.users.Authenticate(r.Context(), req.Username, req.Password, s.sessionMetadata(r))
 	if err != nil {
-		status := http.StatusUnauthorized
-		if !errors.Is(err, store.ErrInvalidCredentials) {
-			status = http.StatusInternalServerError
-		}
-		writeJSON(w, status, errorResponse{Error: err.Error()})
+		writeLoginError(w, err)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, tokenResponse{Token: token})
+	writeJSON(w, http.StatusOK, newTokenResponse(tokens))
 }
 
 func (s *Server) handleContent(w http.ResponseWriter, r *http.Request) {
@@ -384,18 +557,33 @@ func (s *Server) handleAlbums(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
-		albums, err := s.albums.ListByUser(r.Context(), token)
+		page, err := parsePage(r)
 		if err != nil {
-			status := http.StatusUnauthorized
-			if !errors.Is(err, store.ErrUnauthorized) {
-				status = http.StatusInternalServerError
-			}
-			writeJSON(w, status, errorResponse{Error: err.Error()})
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		spec, keys, err := parseListQuery(r, store.AlbumQuery)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+			return
+		}
+
+		albums, next, err := s.albums.ListByUser(r.Context(), token, spec, page)
+		if err != nil {
+			writeJSON(w, listErrorStatus(err), errorResponse{Error: err.Error()})
 			return
 		}
+		projected, err := project(albums, keys)
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
+			return
+		}
+		setNextPage(w, r, next)
 		writeJSON(w, http.StatusOK, struct {
-			Albums []store.Album `json:"albums"`
-		}{Albums: albums})
+			Albums     any    `json:"albums"`
+			NextCursor string `json:"next_cursor,omitempty"`
+		}{Albums: projected, NextCursor: next})
 	case http.MethodPost:
 		var req albumRequest
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -405,6 +593,7 @@ func (s *Server) handleAlbums(w http.ResponseWriter, r *http.Request) {
 
 		album := store.Album{
 			Artist:      req.Artist,
+			Artists:     req.Artists,
 			Title:       req.Title,
 			ReleaseYear: req.ReleaseYear,
 			Tracks:      req.Tracks,
@@ -418,6 +607,8 @@ func (s *Server) handleAlbums(w http.ResponseWriter, r *http.Request) {
 			switch {
 			case errors.Is(err, store.ErrUnauthorized):
 				status = http.StatusUnauthorized
+			case errors.Is(err, store.ErrForbidden):
+				status = http.StatusForbidden
 			case errors.Is(err, store.ErrInvalidAlbum):
 				status = http.StatusBadRequest
 			}
@@ -443,19 +634,23 @@ func (s *Server) handleAlbumPreferences(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	prefs, err := s.ratings.ListByUser(r.Context(), token)
+	page, err := parsePage(r)
 	if err != nil {
-		status := http.StatusUnauthorized
-		if !errors.Is(err, store.ErrUnauthorized) {
-			status = http.StatusInternalServerError
-		}
-		writeJSON(w, status, errorResponse{Error: err.Error()})
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
 		return
 	}
 
+	prefs, next, err := s.ratings.ListByUser(r.Context(), token, page)
+	if err != nil {
+		writeJSON(w, listErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	setNextPage(w, r, next)
 	writeJSON(w, http.StatusOK, struct {
 		Preferences []store.AlbumPreference `json:"preferences"`
-	}{Preferences: prefs})
+		NextCursor  string                  `json:"next_cursor,omitempty"`
+	}{Preferences: prefs, NextCursor: next})
 }
 
 func (s *Server) handleAlbumPreference(w http.ResponseWriter, r *http.Request) {
@@ -497,14 +692,14 @@ func (s *Server) handleAlbumPreference(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := s.ratings.Upsert(r.Context(), token, albumID, req.Rating, req.Favorited); err != nil {
+		if err := s.ratings.Upsert(r.Context(), token, albumID, req.ReleaseID, req.Rating, req.Favorited); err != nil {
 			status := http.StatusInternalServerError
 			switch {
 			case errors.Is(err, store.ErrUnauthorized):
 				status = http.StatusUnauthorized
-			case errors.Is(err, store.ErrInvalidAlbum):
+			case errors.Is(err, store.ErrInvalidAlbum), errors.Is(err, store.ErrInvalidRelease):
 				status = http.StatusBadRequest
-			case errors.Is(err, store.ErrAlbumNotFound):
+			case errors.Is(err, store.ErrAlbumNotFound), errors.Is(err, store.ErrReleaseNotFound):
 				status = http.StatusNotFound
 			}
 			writeJSON(w, status, errorResponse{Error: err.Error()})
@@ -512,7 +707,7 @@ func (s *Server) handleAlbumPreference(w http.ResponseWriter, r *http.Request) {
 		}
 		w.WriteHeader(http.StatusNoContent)
 	case http.MethodDelete:
-		if err := s.ratings.Upsert(r.Context(), token, albumID, nil, false); err != nil {
+		if err := s.ratings.Upsert(r.Context(), token, albumID, nil, nil, false); err != nil {
 			status := http.StatusInternalServerError
 			switch {
 			case errors.Is(err, store.ErrUnauthorized):
@@ -535,6 +730,8 @@ func (s *Server) handleAlbumsList(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// artist, title and genre match substrings; every other filter,
+	// including year= and rating=, is part of the query spec.
 	query := r.URL.Query()
 	filter := store.AlbumFilter{
 		Artist: query.Get("artist"),
@@ -542,33 +739,33 @@ func (s *Server) handleAlbumsList(w http.ResponseWriter, r *http.Request) {
 		Genre:  query.Get("genre"),
 	}
 
-	if yearStr := query.Get("year"); yearStr != "" {
-		year, err := strconv.Atoi(yearStr)
-		if err != nil {
-			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid year parameter"})
-			return
-		}
-		filter.ReleaseYear = year
+	page, err := parsePage(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
 	}
-
-	if ratingStr := query.Get("rating"); ratingStr != "" {
-		rating, err := strconv.Atoi(ratingStr)
-		if err != nil {
-			writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid rating parameter"})
-			return
-		}
-		filter.Rating = rating
+	spec, keys, err := parseListQuery(r, store.AlbumQuery, "artist", "title", "genre")
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: err.Error()})
+		return
 	}
 
-	albums, err := s.albums.List(r.Context(), filter)
+	albums, next, err := s.albums.List(r.Context(), filter, spec, page)
+	if err != nil {
+		writeJSON(w, listErrorStatus(err), errorResponse{Error: err.Error()})
+		return
+	}
+	projected, err := project(albums, keys)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, errorResponse{Error: err.Error()})
 		return
 	}
 
+	setNextPage(w, r, next)
 	writeJSON(w, http.StatusOK, struct {
-		Albums []store.Album `json:"albums"`
-	}{Albums: albums})
+		Albums     any    `json:"albums"`
+		NextCursor string `json:"next_cursor,omitempty"`
+	}{Albums: projected, NextCursor: next})
 }
 
 func (s *Server) handleAlbum(w http.ResponseWriter, r *http.Request) {
@@ -7,35 +7,228 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"reflect"
 	"testing"
 	"time"
 
 	"vinylhound/internal/app/artists"
+	"vinylhound/internal/app/identities"
 	"vinylhound/internal/app/songs"
 	"vinylhound/internal/musicapi"
+	"vinylhound/internal/queryspec"
 	"vinylhound/internal/searchservice"
 	"vinylhound/internal/store"
 	"vinylhound/shared/go/models"
 )
 
-type stubUserService struct{}
+type stubUserService struct {
+	sessionsResponse []store.Session
+	sessionsErr      error
+
+	logoutErr error
+
+	revokeErr       error
+	lastRevokedID   int64
+	revokeOthersN   int64
+	revokeOthersErr error
+
+	refreshResponse store.SessionTokens
+	refreshErr      error
+	lastRefresh     string
+
+	changePasswordErr error
+	resetRequests     []string
+	resetErr          error
+	deleteErr         error
+	lastDeleteMode    store.AccountDeletionMode
+
+	authenticateErr error
+
+	setRoleErr   error
+	lastRoleUser int64
+	lastRole     models.Role
+
+	createdAPIToken   store.APIToken
+	createAPITokenErr error
+	lastScopes        []string
+	apiTokens         []store.APIToken
+	revokeTokenErr    error
+	lastTokenID       int64
+	apiTokenScopes    []string
+	apiTokenErr       error
+
+	twoFactorErr   error
+	lastChallenge  string
+	lastCode       string
+	recoveryCodes  []string
+	enrollment     store.TOTPEnrollment
+	twoFactorState store.TwoFactorStatus
+
+	lastMeta  store.SessionMetadata
+	lastToken string
+}
 
-func (stubUserService) Signup(context.Context, string, string, []string) error {
+func (s *stubUserService) Signup(context.Context, string, string, []string) error {
 	return nil
 }
 
-func (stubUserService) Authenticate(context.Context, string, string) (string, error) {
-	return "", nil
+func (s *stubUserService) Authenticate(ctx context.Context, username, password string, meta store.SessionMetadata) (store.SessionTokens, error) {
+	s.lastMeta = meta
+	if s.authenticateErr != nil {
+		return store.SessionTokens{}, s.authenticateErr
+	}
+	return store.SessionTokens{AccessToken: "session-token", RefreshToken: "refresh-token"}, nil
+}
+
+func (s *stubUserService) Refresh(ctx context.Context, refreshToken string) (store.SessionTokens, error) {
+	s.lastRefresh = refreshToken
+	if s.refreshErr != nil {
+		return store.SessionTokens{}, s.refreshErr
+	}
+	return s.refreshResponse, nil
 }
 
-func (stubUserService) Content(context.Context, string) ([]string, error) {
+func (s *stubUserService) Content(context.Context, string) ([]string, error) {
 	return nil, nil
 }
 
-func (stubUserService) UpdateContent(context.Context, string, []string) error {
+func (s *stubUserService) UpdateContent(context.Context, string, []string) error {
+	return nil
+}
+
+func (s *stubUserService) Logout(ctx context.Context, token string) error {
+	s.lastToken = token
+	return s.logoutErr
+}
+
+func (s *stubUserService) Sessions(ctx context.Context, token string) ([]store.Session, error) {
+	s.lastToken = token
+	if s.sessionsErr != nil {
+		return nil, s.sessionsErr
+	}
+	return s.sessionsResponse, nil
+}
+
+func (s *stubUserService) RevokeSession(ctx context.Context, token string, sessionID int64) error {
+	s.lastToken = token
+	s.lastRevokedID = sessionID
+	return s.revokeErr
+}
+
+func (s *stubUserService) RevokeOtherSessions(ctx context.Context, token string) (int64, error) {
+	s.lastToken = token
+	if s.revokeOthersErr != nil {
+		return 0, s.revokeOthersErr
+	}
+	return s.revokeOthersN, nil
+}
+
+func (s *stubUserService) ChangePassword(ctx context.Context, token, currentPassword, newPassword string) (int64, error) {
+	s.lastToken = token
+	if s.changePasswordErr != nil {
+		return 0, s.changePasswordErr
+	}
+	return 2, nil
+}
+
+func (s *stubUserService) RequestPasswordReset(ctx context.Context, username string) error {
+	s.resetRequests = append(s.resetRequests, username)
 	return nil
 }
 
+func (s *stubUserService) ResetPassword(ctx context.Context, resetToken, newPassword string) error {
+	return s.resetErr
+}
+
+func (s *stubUserService) SetRole(ctx context.Context, token string, userID int64, role models.Role) error {
+	s.lastToken = token
+	s.lastRoleUser = userID
+	s.lastRole = role
+	return s.setRoleErr
+}
+
+func (s *stubUserService) DeleteAccount(ctx context.Context, token, password string, mode store.AccountDeletionMode) error {
+	s.lastToken = token
+	s.lastDeleteMode = mode
+	return s.deleteErr
+}
+
+func (s *stubUserService) CreateAPIToken(ctx context.Context, token, name string, scopes []string, expiresAt *time.Time) (store.APIToken, string, error) {
+	s.lastToken = token
+	s.lastScopes = scopes
+	if s.createAPITokenErr != nil {
+		return store.APIToken{}, "", s.createAPITokenErr
+	}
+	created := s.createdAPIToken
+	created.Name = name
+	created.Scopes = scopes
+	created.ExpiresAt = expiresAt
+	return created, "vhp_secret", nil
+}
+
+func (s *stubUserService) APITokens(ctx context.Context, token string) ([]store.APIToken, error) {
+	s.lastToken = token
+	return s.apiTokens, nil
+}
+
+func (s *stubUserService) RevokeAPIToken(ctx context.Context, token string, tokenID int64) error {
+	s.lastToken = token
+	s.lastTokenID = tokenID
+	return s.revokeTokenErr
+}
+
+func (s *stubUserService) APITokenScopes(ctx context.Context, token string) ([]string, error) {
+	if s.apiTokenErr != nil {
+		return nil, s.apiTokenErr
+	}
+	return s.apiTokenScopes, nil
+}
+
+func (s *stubUserService) CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string, meta store.SessionMetadata) (store.SessionTokens, error) {
+	s.lastChallenge = challengeToken
+	s.lastCode = code
+	s.lastMeta = meta
+	if s.twoFactorErr != nil {
+		return store.SessionTokens{}, s.twoFactorErr
+	}
+	return store.SessionTokens{AccessToken: "session-token", RefreshToken: "refresh-token"}, nil
+}
+
+func (s *stubUserService) TwoFactorStatus(ctx context.Context, token string) (store.TwoFactorStatus, error) {
+	s.lastToken = token
+	return s.twoFactorState, s.twoFactorErr
+}
+
+func (s *stubUserService) BeginTOTPEnrollment(ctx context.Context, token string) (store.TOTPEnrollment, error) {
+	s.lastToken = token
+	return s.enrollment, s.twoFactorErr
+}
+
+func (s *stubUserService) ConfirmTOTPEnrollment(ctx context.Context, token, code string) ([]string, error) {
+	s.lastToken = token
+	s.lastCode = code
+	if s.twoFactorErr != nil {
+		return nil, s.twoFactorErr
+	}
+	return s.recoveryCodes, nil
+}
+
+func (s *stubUserService) RegenerateRecoveryCodes(ctx context.Context, token, code string) ([]string, error) {
+	s.lastToken = token
+	s.lastCode = code
+	if s.twoFactorErr != nil {
+		return nil, s.twoFactorErr
+	}
+	return s.recoveryCodes, nil
+}
+
+func (s *stubUserService) DisableTwoFactor(ctx context.Context, token, code string) error {
+	s.lastToken = token
+	s.lastCode = code
+	return s.twoFactorErr
+}
+
 type stubAlbumService struct {
 	albumsResponse []store.Album
 	albumsErr      error
@@ -49,7 +242,18 @@ type stubAlbumService struct {
 	singleAlbum store.Album
 	singleErr   error
 
-	lastToken string
+	patch     store.AlbumPatch
+	deletedID int64
+	editErr   error
+
+	releases       []models.Release
+	createdRelease models.Release
+	releaseErr     error
+
+	nextCursor string
+	lastPage   store.Page
+	lastSpec   queryspec.Spec
+	lastToken  string
 }
 
 func (s *stubAlbumService) Create(ctx context.Context, token string, album store.Album) (store.Album, error) {
@@ -61,19 +265,23 @@ func (s *stubAlbumService) Create(ctx context.Context, token string, album store
 	return s.createdAlbum, nil
 }
 
-func (s *stubAlbumService) ListByUser(ctx context.Context, token string) ([]store.Album, error) {
+func (s *stubAlbumService) ListByUser(ctx context.Context, token string, spec queryspec.Spec, page store.Page) ([]store.Album, string, error) {
 	s.lastToken = token
+	s.lastSpec = spec
+	s.lastPage = page
 	if s.albumsErr != nil {
-		return nil, s.albumsErr
+		return nil, "", s.albumsErr
 	}
-	return s.albumsResponse, nil
+	return s.albumsResponse, s.nextCursor, nil
 }
 
-func (s *stubAlbumService) List(ctx context.Context, filter store.AlbumFilter) ([]store.Album, error) {
+func (s *stubAlbumService) List(ctx context.Context, filter store.AlbumFilter, spec queryspec.Spec, page store.Page) ([]store.Album, string, error) {
+	s.lastSpec = spec
+	s.lastPage = page
 	if s.listAlbumsErr != nil {
-		return nil, s.listAlbumsErr
+		return nil, "", s.listAlbumsErr
 	}
-	return s.listAlbumsResponse, nil
+	return s.listAlbumsResponse, s.nextCursor, nil
 }
 
 func (s *stubAlbumService) Get(ctx context.Context, id int64) (store.Album, error) {
@@ -83,21 +291,81 @@ func (s *stubAlbumService) Get(ctx context.Context, id int64) (store.Album, erro
 	return s.singleAlbum, nil
 }
 
+func (s *stubAlbumService) Update(ctx context.Context, token string, id int64, patch store.AlbumPatch) (store.Album, error) {
+	s.lastToken = token
+	s.patch = patch
+	if s.editErr != nil {
+		return store.Album{}, s.editErr
+	}
+	album := s.singleAlbum
+	album.ID = id
+	if patch.Title != nil {
+		album.Title = *patch.Title
+	}
+	return album, nil
+}
+
+func (s *stubAlbumService) Delete(ctx context.Context, token string, id int64) error {
+	s.lastToken = token
+	s.deletedID = id
+	return s.editErr
+}
+
+func (s *stubAlbumService) CreateRelease(ctx context.Context, token string, release models.Release) (models.Release, error) {
+	s.lastToken = token
+	s.createdRelease = release
+	if s.releaseErr != nil {
+		return models.Release{}, s.releaseErr
+	}
+	release.ID = 1
+	return release, nil
+}
+
+func (s *stubAlbumService) UpdateRelease(ctx context.Context, token string, id int64, release models.Release) (models.Release, error) {
+	s.lastToken = token
+	if s.releaseErr != nil {
+		return models.Release{}, s.releaseErr
+	}
+	release.ID = id
+	return release, nil
+}
+
+func (s *stubAlbumService) GetRelease(ctx context.Context, id int64) (models.Release, error) {
+	if s.releaseErr != nil {
+		return models.Release{}, s.releaseErr
+	}
+	for _, release := range s.releases {
+		if release.ID == id {
+			return release, nil
+		}
+	}
+	return models.Release{}, store.ErrReleaseNotFound
+}
+
+func (s *stubAlbumService) ListReleases(ctx context.Context, albumID int64) ([]models.Release, error) {
+	if s.releaseErr != nil {
+		return nil, s.releaseErr
+	}
+	return s.releases, nil
+}
+
 type stubRatingsService struct {
 	preferencesResponse []store.AlbumPreference
 	preferencesErr      error
 
 	upsertErr     error
 	lastAlbumID   int64
+	lastReleaseID *int64
 	lastRating    *int
 	lastFavorited bool
 
 	lastToken string
 }
 
-func (s *stubRatingsService) Upsert(ctx context.Context, token string, albumID int64, rating *int, favorited bool) error {
+func (s *stubRatingsService) Upsert(ctx context.Context, token string, albumID int64, releaseID *int64, rating *int, favorited bool) error {
 	s.lastToken = token
 	s.lastAlbumID = albumID
+	s.lastReleaseID = releaseID
 	s.lastFavorited = favorited
 	if rating != nil {
 		val := *rating
@@ -111,18 +379,20 @@ func (s *stubRatingsService) Upsert(ctx context.Context, token string, albumID i
 	return nil
 }
 
-func (s *stubRatingsService) ListByUser(ctx context.Context, token string) ([]store.AlbumPreference, error) {
+func (s *stubRatingsService) ListByUser(ctx context.Context, token string, page store.Page) ([]store.AlbumPreference, string, error) {
 	s.lastToken = token
 	if s.preferencesErr != nil {
-		return nil, s.preferencesErr
+		return nil, "", s.preferencesErr
 	}
-	return s.preferencesResponse, nil
+	return s.preferencesResponse, "", nil
 }
 
 type stubPlaylistService struct{}
 
-func (stubPlaylistService) List(context.Context, string) ([]*models.Playlist, error) { return nil, nil }
-func (stubPlaylistService) Get(context.Context, int64) (*models.Playlist, error)     { return nil, nil }
+func (stubPlaylistService) List(context.Context, string, store.Page) ([]*models.Playlist, string, error) {
+	return nil, "", nil
+}
+func (stubPlaylistService) Get(context.Context, int64) (*models.Playlist, error) { return nil, nil }
 func (stubPlaylistService) Create(context.Context, string, *models.Playlist) (*models.Playlist, error) {
 	return nil, nil
 }
@@ -177,18 +447,43 @@ func (s *stubFavoritesService) UnfavoriteTrack(ctx context.Context, token string
 	return nil
 }
 
-func (s *stubFavoritesService) ListTrackFavorites(ctx context.Context, token string) ([]*models.Favorite, error) {
+func (s *stubFavoritesService) List(ctx context.Context, token string, page store.Page) ([]*models.Favorite, string, error) {
+	return s.ListTrackFavorites(ctx, token, page)
+}
+
+func (s *stubFavoritesService) ListTrackFavorites(ctx context.Context, token string, page store.Page) ([]*models.Favorite, string, error) {
 	s.lastListToken = token
 	if s.listErr != nil {
-		return nil, s.listErr
+		return nil, "", s.listErr
 	}
-	return s.listResponse, nil
+	return s.listResponse, "", nil
 }
 
 type noopArtistService struct{}
 
-func (noopArtistService) List(context.Context, artists.Filter) ([]artists.Artist, error) {
-	return nil, nil
+func (noopArtistService) List(context.Context, artists.Filter, store.Page) ([]store.ArtistListing, string, error) {
+	return nil, "", nil
+}
+
+func (noopArtistService) Get(context.Context, int64) (store.ArtistDiscography, error) {
+	return store.ArtistDiscography{}, store.ErrArtistNotFound
+}
+
+type stubArtistService struct {
+	noopArtistService
+	list        []store.ArtistListing
+	next        string
+	discography store.ArtistDiscography
+	lastID      int64
+}
+
+func (s *stubArtistService) List(context.Context, artists.Filter, store.Page) ([]store.ArtistListing, string, error) {
+	return s.list, s.next, nil
+}
+
+func (s *stubArtistService) Get(_ context.Context, id int64) (store.ArtistDiscography, error) {
+	s.lastID = id
+	return s.discography, nil
 }
 
 type noopSongService struct{}
@@ -197,14 +492,119 @@ func (noopSongService) ListByAlbum(context.Context, int64) ([]songs.Song, error)
 	return nil, nil
 }
 
-func (noopSongService) Search(context.Context, store.SongFilter) ([]store.Song, error) {
-	return nil, nil
+func (noopSongService) Search(context.Context, store.SongFilter, store.Page) ([]store.Song, string, error) {
+	return nil, "", nil
 }
 
 func (noopSongService) Get(context.Context, int64) (store.Song, error) {
 	return store.Song{}, nil
 }
 
+type stubSongService struct {
+	noopSongService
+	tracks      []songs.Song
+	err         error
+	lastAlbumID int64
+}
+
+func (s *stubSongService) ListByAlbum(_ context.Context, albumID int64) ([]songs.Song, error) {
+	s.lastAlbumID = albumID
+	return s.tracks, s.err
+}
+
+type stubCatalogService struct {
+	results    []store.CatalogSearchResult
+	err        error
+	lastSearch store.CatalogSearch
+}
+
+func (s *stubCatalogService) Search(ctx context.Context, search store.CatalogSearch) ([]store.CatalogSearchResult, error) {
+	s.lastSearch = search
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.results, nil
+}
+
+type stubGenreService struct {
+	genres []store.Genre
+	err    error
+
+	lastToken   string
+	lastID      int64
+	lastCreated store.Genre
+	lastPatch   store.GenrePatch
+}
+
+func (s *stubGenreService) List(context.Context) ([]store.Genre, error) {
+	return s.genres, s.err
+}
+
+func (s *stubGenreService) Get(_ context.Context, id int64) (store.Genre, error) {
+	s.lastID = id
+	for _, genre := range s.genres {
+		if genre.ID == id {
+			return genre, s.err
+		}
+	}
+	return store.Genre{}, store.ErrGenreNotFound
+}
+
+func (s *stubGenreService) Create(_ context.Context, token string, genre store.Genre) (store.Genre, error) {
+	s.lastToken = token
+	s.lastCreated = genre
+	genre.ID = 99
+	return genre, s.err
+}
+
+func (s *stubGenreService) Update(_ context.Context, token string, id int64, patch store.GenrePatch) (store.Genre, error) {
+	s.lastToken = token
+	s.lastID = id
+	s.lastPatch = patch
+	return store.Genre{ID: id}, s.err
+}
+
+type stubDuplicateService struct {
+	candidates []store.DuplicateCandidate
+	merged     store.Album
+	err        error
+
+	lastToken   string
+	lastID      int64
+	lastKeepID  int64
+	lastMergeID int64
+}
+
+func (s *stubDuplicateService) Scan(_ context.Context, token string) (int, error) {
+	s.lastToken = token
+	return len(s.candidates), s.err
+}
+
+func (s *stubDuplicateService) List(_ context.Context, token string, _ string, _ store.Page) ([]store.DuplicateCandidate, string, error) {
+	s.lastToken = token
+	return s.candidates, "", s.err
+}
+
+func (s *stubDuplicateService) Dismiss(_ context.Context, token string, id int64) error {
+	s.lastToken = token
+	s.lastID = id
+	return s.err
+}
+
+func (s *stubDuplicateService) Merge(_ context.Context, token string, id int64, keepID int64) (store.Album, error) {
+	s.lastToken = token
+	s.lastID = id
+	s.lastKeepID = keepID
+	return s.merged, s.err
+}
+
+func (s *stubDuplicateService) MergeAlbums(_ context.Context, token string, keepID, mergeID int64) (store.Album, error) {
+	s.lastToken = token
+	s.lastKeepID = keepID
+	s.lastMergeID = mergeID
+	return s.merged, s.err
+}
+
 type noopSearchService struct{}
 
 func (noopSearchService) Search(context.Context, searchservice.SearchOptions) (*searchservice.SearchResults, error) {
@@ -215,20 +615,121 @@ func (noopSearchService) ImportAlbum(context.Context, string, musicapi.MusicProv
 	return nil
 }
 
-func (noopSearchService) ImportAlbumForUser(context.Context, string, string, musicapi.MusicProvider) error {
-	return nil
+func (noopSearchService) ImportAlbumForUser(context.Context, string, string, musicapi.MusicProvider) (int64, error) {
+	return 0, nil
 }
 
-func (noopSearchService) GetArtistWithAlbums(context.Context, string) (*musicapi.Artist, []musicapi.Album, error) {
+func (noopSearchService) GetArtistWithAlbums(context.Context, musicapi.MusicProvider, string) (*musicapi.Artist, []musicapi.Album, error) {
 	return nil, nil, nil
 }
 
-func (noopSearchService) GetAlbumWithTracks(context.Context, string) (*musicapi.Album, []musicapi.Track, error) {
+func (noopSearchService) GetAlbumWithTracks(context.Context, musicapi.MusicProvider, string) (*musicapi.Album, []musicapi.Track, error) {
 	return nil, nil, nil
 }
 
+func (noopSearchService) LookupISRC(context.Context, string) ([]searchservice.TrackResult, error) {
+	return nil, nil
+}
+
+func (noopSearchService) LookupBarcode(context.Context, string) ([]searchservice.AlbumResult, error) {
+	return nil, nil
+}
+
+func (noopSearchService) GetDiscogsMaster(context.Context, string) (*musicapi.DiscogsMaster, error) {
+	return nil, nil
+}
+
+func (noopSearchService) GetDiscogsLabel(context.Context, string) (*musicapi.DiscogsLabel, error) {
+	return nil, nil
+}
+
+func (noopSearchService) Providers() []musicapi.ProviderInfo {
+	return nil
+}
+
+func (noopSearchService) SaveArtist(context.Context, string, musicapi.Artist) error {
+	return nil
+}
+
+type noopPlaceService struct{}
+
+func (noopPlaceService) CreateVenue(context.Context, string, *models.Venue) (*models.Venue, error) {
+	return nil, nil
+}
+func (noopPlaceService) ListVenues(context.Context, string, store.Page) ([]*models.Venue, string, error) {
+	return nil, "", nil
+}
+func (noopPlaceService) GetVenue(context.Context, int64) (*models.Venue, error) { return nil, nil }
+func (noopPlaceService) UpdateVenue(context.Context, string, int64, *models.Venue) (*models.Venue, error) {
+	return nil, nil
+}
+func (noopPlaceService) DeleteVenue(context.Context, string, int64) error { return nil }
+func (noopPlaceService) CreateRetailer(context.Context, string, *models.Retailer) (*models.Retailer, error) {
+	return nil, nil
+}
+func (noopPlaceService) ListRetailers(context.Context, string, store.Page) ([]*models.Retailer, string, error) {
+	return nil, "", nil
+}
+func (noopPlaceService) GetRetailer(context.Context, int64) (*models.Retailer, error) {
+	return nil, nil
+}
+func (noopPlaceService) UpdateRetailer(context.Context, string, int64, *models.Retailer) (*models.Retailer, error) {
+	return nil, nil
+}
+func (noopPlaceService) DeleteRetailer(context.Context, string, int64) error { return nil }
+
+type noopConcertService struct{}
+
+func (noopConcertService) Create(context.Context, string, *models.Concert) (*models.Concert, error) {
+	return nil, nil
+}
+func (noopConcertService) List(context.Context, string, queryspec.Spec, store.Page) ([]*models.ConcertWithDetails, string, error) {
+	return nil, "", nil
+}
+func (noopConcertService) Get(context.Context, int64) (*models.ConcertWithDetails, error) {
+	return nil, nil
+}
+func (noopConcertService) Update(context.Context, string, int64, *models.Concert) (*models.Concert, error) {
+	return nil, nil
+}
+func (noopConcertService) Delete(context.Context, string, int64) error { return nil }
+func (noopConcertService) ListByVenue(context.Context, int64, queryspec.Spec, store.Page) ([]*models.ConcertWithDetails, string, error) {
+	return nil, "", nil
+}
+func (noopConcertService) MarkAttended(context.Context, string, int64, *int) error { return nil }
+
+type noopCollectionService struct{}
+
+func (noopCollectionService) Add(context.Context, string, *models.AlbumCollection) (*models.AlbumCollection, error) {
+	return nil, nil
+}
+func (noopCollectionService) List(context.Context, string, models.CollectionFilter, queryspec.Spec, store.Page) ([]*models.AlbumCollectionWithDetails, string, error) {
+	return nil, "", nil
+}
+func (noopCollectionService) Get(context.Context, int64) (*models.AlbumCollectionWithDetails, error) {
+	return nil, nil
+}
+func (noopCollectionService) Update(context.Context, string, int64, *models.AlbumCollection) (*models.AlbumCollection, error) {
+	return nil, nil
+}
+func (noopCollectionService) Remove(context.Context, string, int64) error { return nil }
+func (noopCollectionService) Move(context.Context, string, int64, models.CollectionType) error {
+	return nil
+}
+func (noopCollectionService) GetStats(context.Context, string) (*models.CollectionStats, error) {
+	return nil, nil
+}
+
 func newTestServer(t *testing.T, album *stubAlbumService, ratings *stubRatingsService, favorites *stubFavoritesService) *Server {
 	t.Helper()
+	return newTestServerWithUsers(t, &stubUserService{}, album, ratings, favorites)
+}
+
+func newTestServerWithUsers(t *testing.T, users *stubUserService, album *stubAlbumService, ratings *stubRatingsService, favorites *stubFavoritesService) *Server {
+	t.Helper()
+	if users == nil {
+		users = &stubUserService{}
+	}
 	if album == nil {
 		album = &stubAlbumService{}
 	}
@@ -239,7 +740,7 @@ func newTestServer(t *testing.T, album *stubAlbumService, ratings *stubRatingsSe
 		favorites = &stubFavoritesService{}
 	}
 	return New(
-		&stubUserService{},
+		users,
 		noopArtistService{},
 		album,
 		noopSongService{},
@@ -247,6 +748,15 @@ func newTestServer(t *testing.T, album *stubAlbumService, ratings *stubRatingsSe
 		stubPlaylistService{},
 		favorites,
 		noopSearchService{},
+		noopPlaceService{},
+		noopConcertService{},
+		noopCollectionService{},
+		identities.New(newStubIdentityStore(), nil),
+		&stubDuplicateService{},
+		&stubCatalogService{},
+		&stubGenreService{},
+		&stubMediaService{},
+		&stubLookupService{},
 	)
 }
 
@@ -306,7 +816,7 @@ func TestHandleAlbumsPostSuccess(t *testing.T) {
 		Artist:      "Artist",
 		Title:       "Title",
 		ReleaseYear: 2024,
-		Tracks:      []string{"Song"},
+		Tracks:      []store.Track{{Title: "Song"}},
 		Genres:      []string{"Indie"},
 		Rating:      5,
 	}
@@ -338,7 +848,7 @@ func TestHandleAlbumsPostValidationError(t *testing.T) {
 		Artist:      "Artist",
 		Title:       "Title",
 		ReleaseYear: 2024,
-		Tracks:      []string{"Song"},
+		Tracks:      []store.Track{{Title: "Song"}},
 		Genres:      []string{"Indie"},
 		Rating:      0,
 	}
@@ -354,6 +864,24 @@ func TestHandleAlbumsPostValidationError(t *testing.T) {
 	}
 }
 
+func TestHandleAlbumsPostForbidden(t *testing.T) {
+	albumStub := &stubAlbumService{
+		createErr: store.ErrForbidden,
+	}
+	server := newTestServer(t, albumStub, nil, nil)
+
+	b, _ := json.Marshal(albumRequest{Artist: "Artist", Title: "Title", ReleaseYear: 2024})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/me/albums", bytes.NewReader(b))
+	req.Header.Set("Authorization", "Bearer token")
+
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403, got %d", rr.Code)
+	}
+}
+
 func TestHandleAlbumsPostMissingToken(t *testing.T) {
 	server := newTestServer(t, &stubAlbumService{}, nil, nil)
 	req := httptest.NewRequest(http.MethodPost, "/api/me/albums", bytes.NewReader([]byte(`{}`)))
@@ -376,7 +904,7 @@ func TestHandleAlbumsPostUnexpectedError(t *testing.T) {
 		Artist:      "Artist",
 		Title:       "Title",
 		ReleaseYear: 2024,
-		Tracks:      []string{"Song"},
+		Tracks:      []store.Track{{Title: "Song"}},
 		Genres:      []string{"Indie"},
 		Rating:      5,
 	}
@@ -561,52 +1089,157 @@ func TestHandleAlbumsListSuccess(t *testing.T) {
 	}
 }
 
-func TestHandleAlbumsListBadRating(t *testing.T) {
-	server := newTestServer(t, &stubAlbumService{}, nil, nil)
-	req := httptest.NewRequest(http.MethodGet, "/api/albums?rating=bad", nil)
-	rr := httptest.NewRecorder()
-	server.Routes().ServeHTTP(rr, req)
-
-	if rr.Code != http.StatusBadRequest {
-		t.Fatalf("expected status 400, got %d", rr.Code)
-	}
-}
-
-func TestHandleAlbumSuccess(t *testing.T) {
+func TestHandleAlbumsListPagination(t *testing.T) {
 	albumStub := &stubAlbumService{
-		singleAlbum: store.Album{ID: 5, Artist: "Artist"},
+		listAlbumsResponse: []store.Album{{ID: 10, Title: "Geogaddi"}},
+		nextCursor:         "next-page",
 	}
 	server := newTestServer(t, albumStub, nil, nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/album?id=5", nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/albums?artist=boards&limit=1&cursor=this-page", nil)
 	rr := httptest.NewRecorder()
 	server.Routes().ServeHTTP(rr, req)
 
 	if rr.Code != http.StatusOK {
 		t.Fatalf("expected status 200, got %d", rr.Code)
 	}
-
-	var album store.Album
-	if err := json.NewDecoder(rr.Body).Decode(&album); err != nil {
-		t.Fatalf("decode: %v", err)
+	if want := (store.Page{Cursor: "this-page", Size: 1}); albumStub.lastPage != want {
+		t.Fatalf("expected page %#v, got %#v", want, albumStub.lastPage)
 	}
-	if album.ID != 5 {
-		t.Fatalf("expected id 5, got %d", album.ID)
+	if want := `</api/v1/albums?artist=boards&cursor=next-page&limit=1>; rel="next"`; rr.Header().Get("Link") != want {
+		t.Fatalf("unexpected Link header %q", rr.Header().Get("Link"))
+	}
+	var payload struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.NextCursor != "next-page" {
+		t.Fatalf("expected next_cursor, got %q", payload.NextCursor)
 	}
 }
 
-func TestHandleAlbumNotFound(t *testing.T) {
+func TestHandleAlbumsListInvalidPage(t *testing.T) {
+	for name, tc := range map[string]struct {
+		url string
+		err error
+	}{
+		"bad limit":      {url: "/api/v1/albums?limit=abc"},
+		"limit too high": {url: "/api/v1/albums?limit=1000"},
+		"bad cursor":     {url: "/api/v1/albums?cursor=x", err: store.ErrInvalidPage},
+	} {
+		server := newTestServer(t, &stubAlbumService{listAlbumsErr: tc.err}, nil, nil)
+		rr := httptest.NewRecorder()
+		server.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", name, rr.Code)
+		}
+		if rr.Header().Get("Link") != "" {
+			t.Errorf("%s: unexpected Link header", name)
+		}
+	}
+}
+
+func TestHandleAlbumsListQuerySpec(t *testing.T) {
 	albumStub := &stubAlbumService{
-		singleErr: store.ErrAlbumNotFound,
+		listAlbumsResponse: []store.Album{{ID: 10, Artist: "Boards of Canada", Title: "Geogaddi", ReleaseYear: 2002}},
 	}
 	server := newTestServer(t, albumStub, nil, nil)
 
-	req := httptest.NewRequest(http.MethodGet, "/api/album?id=999", nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/albums?artist=boards&sort=-release_year,title&year%3E=1970&fields=title,year", nil)
 	rr := httptest.NewRecorder()
 	server.Routes().ServeHTTP(rr, req)
 
-	if rr.Code != http.StatusNotFound {
-		t.Fatalf("expected status 404, got %d", rr.Code)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	want := queryspec.Spec{
+		Sort:    []queryspec.Sort{{Field: "release_year", Desc: true}, {Field: "title"}},
+		Filters: []queryspec.Filter{{Field: "year", Op: queryspec.Gte, Value: "1970"}},
+		Fields:  []string{"title", "year"},
+	}
+	if !reflect.DeepEqual(albumStub.lastSpec, want) {
+		t.Fatalf("unexpected spec %#v", albumStub.lastSpec)
+	}
+
+	var payload struct {
+		Albums []map[string]any `json:"albums"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	wantAlbum := map[string]any{"id": float64(10), "title": "Geogaddi", "releaseYear": float64(2002)}
+	if len(payload.Albums) != 1 || !reflect.DeepEqual(payload.Albums[0], wantAlbum) {
+		t.Fatalf("unexpected projected albums: %#v", payload.Albums)
+	}
+}
+
+func TestHandleAlbumsListInvalidQuerySpec(t *testing.T) {
+	for name, url := range map[string]string{
+		"unknown sort":    "/api/v1/albums?sort=user_id",
+		"unknown filter":  "/api/v1/albums?owner%3E=1",
+		"bad filter type": "/api/v1/albums?year%3E=seventies",
+		"unknown field":   "/api/v1/albums?fields=title,owner",
+	} {
+		albumStub := &stubAlbumService{}
+		server := newTestServer(t, albumStub, nil, nil)
+		rr := httptest.NewRecorder()
+		server.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, url, nil))
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", name, rr.Code)
+		}
+	}
+}
+
+func TestHandleAlbumsListBadRating(t *testing.T) {
+	server := newTestServer(t, &stubAlbumService{}, nil, nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/albums?rating=bad", nil)
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rr.Code)
+	}
+}
+
+func TestHandleAlbumSuccess(t *testing.T) {
+	albumStub := &stubAlbumService{
+		singleAlbum: store.Album{ID: 5, Artist: "Artist"},
+	}
+	server := newTestServer(t, albumStub, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/album?id=5", nil)
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+
+	var album store.Album
+	if err := json.NewDecoder(rr.Body).Decode(&album); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if album.ID != 5 {
+		t.Fatalf("expected id 5, got %d", album.ID)
+	}
+}
+
+func TestHandleAlbumNotFound(t *testing.T) {
+	albumStub := &stubAlbumService{
+		singleErr: store.ErrAlbumNotFound,
+	}
+	server := newTestServer(t, albumStub, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/album?id=999", nil)
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404, got %d", rr.Code)
 	}
 }
 
@@ -791,3 +1424,1156 @@ func TestHandleFavoriteTrackPutInvalidID(t *testing.T) {
 		t.Fatalf("expected error message, got empty string")
 	}
 }
+
+func TestHandleLoginRecordsSessionMetadata(t *testing.T) {
+	usersStub := &stubUserService{}
+	server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+	server.SetTrustedProxies([]netip.Prefix{netip.MustParsePrefix("192.0.2.0/24"), netip.MustParsePrefix("10.0.0.0/8")})
+
+	// The client made up the first hop; the gateway at 10.0.0.1 appended the
+	// address it saw before the request reached the API from 192.0.2.1.
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader([]byte(`{"username":"demo","password":"demo123"}`)))
+	req.Header.Set("User-Agent", "vinylhound-ios/2.1")
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.9, 10.0.0.1")
+	rr := httptest.NewRecorder()
+
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	if usersStub.lastMeta.UserAgent != "vinylhound-ios/2.1" {
+		t.Fatalf("expected user agent to be recorded, got %q", usersStub.lastMeta.UserAgent)
+	}
+	if usersStub.lastMeta.IPAddress != "203.0.113.9" {
+		t.Fatalf("expected forwarded client IP, got %q", usersStub.lastMeta.IPAddress)
+	}
+}
+
+func TestHandleLoginIgnoresUntrustedForwardedFor(t *testing.T) {
+	usersStub := &stubUserService{}
+	server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader([]byte(`{"username":"demo","password":"demo123"}`)))
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	rr := httptest.NewRecorder()
+
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	if usersStub.lastMeta.IPAddress != "192.0.2.1" {
+		t.Fatalf("expected the connecting address, got %q", usersStub.lastMeta.IPAddress)
+	}
+}
+
+func TestHandleLoginThrottled(t *testing.T) {
+	usersStub := &stubUserService{authenticateErr: &store.LoginThrottleError{RetryAfter: 1500 * time.Millisecond}}
+	server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+	for _, path := range []string{"/api/v1/auth/login", "/api/login"} {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader([]byte(`{"username":"demo","password":"guess"}`)))
+		rr := httptest.NewRecorder()
+
+		server.Routes().ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusTooManyRequests {
+			t.Fatalf("%s: expected status 429, got %d", path, rr.Code)
+		}
+		if got := rr.Header().Get("Retry-After"); got != "2" {
+			t.Fatalf("%s: expected Retry-After 2, got %q", path, got)
+		}
+	}
+}
+
+func TestHandleLoginInvalidCredentials(t *testing.T) {
+	usersStub := &stubUserService{authenticateErr: store.ErrInvalidCredentials}
+	server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader([]byte(`{"username":"demo","password":"guess"}`)))
+	rr := httptest.NewRecorder()
+
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "" {
+		t.Fatalf("expected no Retry-After header on plain failure")
+	}
+}
+
+func TestHandleLoginTwoFactorChallenge(t *testing.T) {
+	expiresAt := time.Now().Add(5 * time.Minute).UTC().Truncate(time.Second)
+	usersStub := &stubUserService{authenticateErr: &store.TwoFactorRequiredError{ChallengeToken: "challenge", ExpiresAt: expiresAt}}
+	server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewReader([]byte(`{"username":"demo","password":"demo123"}`)))
+	rr := httptest.NewRecorder()
+
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", rr.Code)
+	}
+	var payload struct {
+		TwoFactorRequired bool      `json:"two_factor_required"`
+		ChallengeToken    string    `json:"challenge_token"`
+		ExpiresAt         time.Time `json:"expires_at"`
+		Methods           []string  `json:"methods"`
+		Token             string    `json:"token"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if !payload.TwoFactorRequired || payload.ChallengeToken != "challenge" || !payload.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("unexpected challenge response %+v", payload)
+	}
+	if payload.Token != "" {
+		t.Fatalf("expected no session token before the second factor")
+	}
+	if len(payload.Methods) != 2 {
+		t.Fatalf("expected totp and recovery_code methods, got %v", payload.Methods)
+	}
+}
+
+func TestHandleTwoFactorLogin(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		err        error
+		wantStatus int
+	}{
+		{"success", `{"challenge_token":"challenge","code":"123456"}`, nil, http.StatusOK},
+		{"missing code", `{"challenge_token":"challenge"}`, nil, http.StatusBadRequest},
+		{"wrong code", `{"challenge_token":"challenge","code":"000000"}`, store.ErrInvalidTwoFactorCode, http.StatusUnauthorized},
+		{"expired challenge", `{"challenge_token":"stale","code":"123456"}`, store.ErrInvalidTwoFactorChallenge, http.StatusUnauthorized},
+		{"locked out", `{"challenge_token":"challenge","code":"123456"}`, &store.LoginThrottleError{RetryAfter: time.Minute, Locked: true}, http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usersStub := &stubUserService{twoFactorErr: tt.err}
+			server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login/2fa", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("User-Agent", "vinylhound-ios/2.1")
+			rr := httptest.NewRecorder()
+
+			server.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if usersStub.lastChallenge != "challenge" || usersStub.lastCode != "123456" {
+				t.Fatalf("unexpected challenge %q and code %q", usersStub.lastChallenge, usersStub.lastCode)
+			}
+			if usersStub.lastMeta.UserAgent != "vinylhound-ios/2.1" {
+				t.Fatalf("expected session metadata to be passed through")
+			}
+			var payload tokenResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if payload.Token != "session-token" || payload.RefreshToken != "refresh-token" {
+				t.Fatalf("unexpected tokens %+v", payload)
+			}
+		})
+	}
+}
+
+func TestHandleConfirmTOTPEnrollment(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"enabled", nil, http.StatusOK},
+		{"wrong code", store.ErrInvalidTwoFactorCode, http.StatusForbidden},
+		{"not enrolling", store.ErrTwoFactorNotEnrolling, http.StatusConflict},
+		{"unauthorized", store.ErrUnauthorized, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usersStub := &stubUserService{twoFactorErr: tt.err, recoveryCodes: []string{"abcde-fghij"}}
+			server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/me/2fa/totp/confirm", bytes.NewReader([]byte(`{"code":"123456"}`)))
+			req.Header.Set("Authorization", "Bearer session")
+			rr := httptest.NewRecorder()
+
+			server.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var payload struct {
+				RecoveryCodes []string `json:"recovery_codes"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if len(payload.RecoveryCodes) != 1 || payload.RecoveryCodes[0] != "abcde-fghij" {
+				t.Fatalf("unexpected recovery codes %v", payload.RecoveryCodes)
+			}
+		})
+	}
+}
+
+func TestHandleDisableTwoFactor(t *testing.T) {
+	usersStub := &stubUserService{}
+	server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/me/2fa/disable", bytes.NewReader([]byte(`{"code":"abcde-fghij"}`)))
+	req.Header.Set("Authorization", "Bearer session")
+	rr := httptest.NewRecorder()
+
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", rr.Code)
+	}
+	if usersStub.lastToken != "session" || usersStub.lastCode != "abcde-fghij" {
+		t.Fatalf("unexpected token %q and code %q", usersStub.lastToken, usersStub.lastCode)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/me/2fa/disable", bytes.NewReader([]byte(`{}`)))
+	req.Header.Set("Authorization", "Bearer session")
+	rr = httptest.NewRecorder()
+
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 without a code, got %d", rr.Code)
+	}
+}
+
+func TestHandleRefresh(t *testing.T) {
+	usersStub := &stubUserService{refreshResponse: store.SessionTokens{AccessToken: "new-access", RefreshToken: "new-refresh"}}
+	server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewReader([]byte(`{"refresh_token":"old-refresh"}`)))
+	rr := httptest.NewRecorder()
+
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	if usersStub.lastRefresh != "old-refresh" {
+		t.Fatalf("expected refresh token to be forwarded, got %q", usersStub.lastRefresh)
+	}
+
+	var resp tokenResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Token != "new-access" || resp.RefreshToken != "new-refresh" {
+		t.Fatalf("unexpected token pair: %+v", resp)
+	}
+}
+
+func TestHandleRefreshErrorMapping(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{name: "missing token", body: `{}`, want: http.StatusBadRequest},
+		{name: "invalid token", body: `{"refresh_token":"x"}`, err: store.ErrInvalidRefreshToken, want: http.StatusUnauthorized},
+		{name: "reused token", body: `{"refresh_token":"x"}`, err: store.ErrRefreshTokenReused, want: http.StatusUnauthorized},
+		{name: "unexpected", body: `{"refresh_token":"x"}`, err: errors.New("boom"), want: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServerWithUsers(t, &stubUserService{refreshErr: tt.err}, nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewReader([]byte(tt.body)))
+			rr := httptest.NewRecorder()
+
+			server.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleLogout(t *testing.T) {
+	usersStub := &stubUserService{}
+	server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer tok")
+	rr := httptest.NewRecorder()
+
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", rr.Code)
+	}
+	if usersStub.lastToken != "tok" {
+		t.Fatalf("expected token 'tok', got %q", usersStub.lastToken)
+	}
+}
+
+func TestHandleLogoutUnknownSession(t *testing.T) {
+	server := newTestServerWithUsers(t, &stubUserService{logoutErr: store.ErrUnauthorized}, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", nil)
+	req.Header.Set("Authorization", "Bearer stale")
+	rr := httptest.NewRecorder()
+
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status 401, got %d", rr.Code)
+	}
+}
+
+func TestHandleListSessions(t *testing.T) {
+	usersStub := &stubUserService{
+		sessionsResponse: []store.Session{
+			{ID: 1, UserAgent: "laptop", Current: true},
+			{ID: 2, UserAgent: "phone"},
+		},
+	}
+	server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/sessions", nil)
+	req.Header.Set("Authorization", "Bearer tok")
+	rr := httptest.NewRecorder()
+
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	var payload struct {
+		Sessions []store.Session `json:"sessions"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(payload.Sessions) != 2 || !payload.Sessions[0].Current {
+		t.Fatalf("unexpected sessions payload: %+v", payload.Sessions)
+	}
+}
+
+func TestHandleRevokeSession(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		err        error
+		wantStatus int
+	}{
+		{"revoked", "/api/v1/me/sessions/9", nil, http.StatusNoContent},
+		{"notfound", "/api/v1/me/sessions/9", store.ErrSessionNotFound, http.StatusNotFound},
+		{"unauthorized", "/api/v1/me/sessions/9", store.ErrUnauthorized, http.StatusUnauthorized},
+		{"badid", "/api/v1/me/sessions/abc", nil, http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			usersStub := &stubUserService{revokeErr: tc.err}
+			server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodDelete, tc.path, nil)
+			req.Header.Set("Authorization", "Bearer tok")
+			rr := httptest.NewRecorder()
+
+			server.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, rr.Code)
+			}
+			if tc.wantStatus == http.StatusNoContent && usersStub.lastRevokedID != 9 {
+				t.Fatalf("expected session 9 to be revoked, got %d", usersStub.lastRevokedID)
+			}
+		})
+	}
+}
+
+func TestHandleRevokeOtherSessions(t *testing.T) {
+	usersStub := &stubUserService{revokeOthersN: 4}
+	server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/me/sessions/revoke-others", nil)
+	req.Header.Set("Authorization", "Bearer tok")
+	rr := httptest.NewRecorder()
+
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rr.Code)
+	}
+	var payload struct {
+		Revoked int64 `json:"revoked"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Revoked != 4 {
+		t.Fatalf("expected 4 revoked sessions, got %d", payload.Revoked)
+	}
+}
+
+func TestHandleChangePassword(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "success", want: http.StatusOK},
+		{name: "wrong current password", err: store.ErrInvalidCredentials, want: http.StatusForbidden},
+		{name: "no password and stale sign-in", err: store.ErrRecentSignInRequired, want: http.StatusForbidden},
+		{name: "empty new password", err: store.ErrPasswordRequired, want: http.StatusBadRequest},
+		{name: "expired session", err: store.ErrUnauthorized, want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServerWithUsers(t, &stubUserService{changePasswordErr: tt.err}, nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/me/password", bytes.NewReader([]byte(`{"current_password":"old","new_password":"new"}`)))
+			req.Header.Set("Authorization", "Bearer tok")
+			rr := httptest.NewRecorder()
+
+			server.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHandleRequestPasswordResetAlwaysAccepted(t *testing.T) {
+	usersStub := &stubUserService{}
+	server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/password-reset", bytes.NewReader([]byte(`{"username":"nobody"}`)))
+	rr := httptest.NewRecorder()
+
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("expected status 202, got %d", rr.Code)
+	}
+	if len(usersStub.resetRequests) != 1 || usersStub.resetRequests[0] != "nobody" {
+		t.Fatalf("expected reset request for 'nobody', got %v", usersStub.resetRequests)
+	}
+}
+
+func TestHandleConfirmPasswordResetInvalidToken(t *testing.T) {
+	server := newTestServerWithUsers(t, &stubUserService{resetErr: store.ErrInvalidResetToken}, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/password-reset/confirm", bytes.NewReader([]byte(`{"token":"used","new_password":"new"}`)))
+	rr := httptest.NewRecorder()
+
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rr.Code)
+	}
+}
+
+func TestHandleDeleteAccount(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		want     int
+		wantMode store.AccountDeletionMode
+	}{
+		{name: "defaults to delete", body: `{"password":"pw"}`, want: http.StatusNoContent, wantMode: store.DeleteAccountCascade},
+		{name: "anonymize", body: `{"password":"pw","mode":"anonymize"}`, want: http.StatusNoContent, wantMode: store.DeleteAccountAnonymize},
+		{name: "wrong password", body: `{"password":"bad"}`, err: store.ErrInvalidCredentials, want: http.StatusForbidden, wantMode: store.DeleteAccountCascade},
+		{name: "no password and stale sign-in", body: `{}`, err: store.ErrRecentSignInRequired, want: http.StatusForbidden, wantMode: store.DeleteAccountCascade},
+		{name: "bad mode", body: `{"password":"pw","mode":"archive"}`, err: store.ErrInvalidDeletionMode, want: http.StatusBadRequest, wantMode: "archive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usersStub := &stubUserService{deleteErr: tt.err}
+			server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/me", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Authorization", "Bearer tok")
+			rr := httptest.NewRecorder()
+
+			server.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, rr.Code)
+			}
+			if usersStub.lastDeleteMode != tt.wantMode {
+				t.Fatalf("expected mode %q, got %q", tt.wantMode, usersStub.lastDeleteMode)
+			}
+		})
+	}
+}
+
+func TestHandleSetUserRole(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		body string
+		err  error
+		want int
+	}{
+		{name: "assigned", path: "/api/v1/admin/users/7/role", body: `{"role":"curator"}`, want: http.StatusOK},
+		{name: "not admin", path: "/api/v1/admin/users/7/role", body: `{"role":"curator"}`, err: store.ErrForbidden, want: http.StatusForbidden},
+		{name: "unknown role", path: "/api/v1/admin/users/7/role", body: `{"role":"owner"}`, err: store.ErrInvalidRole, want: http.StatusBadRequest},
+		{name: "unknown user", path: "/api/v1/admin/users/7/role", body: `{"role":"curator"}`, err: store.ErrUserNotFound, want: http.StatusNotFound},
+		{name: "bad id", path: "/api/v1/admin/users/x/role", body: `{"role":"curator"}`, want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usersStub := &stubUserService{setRoleErr: tt.err}
+			server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPut, tt.path, bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Authorization", "Bearer tok")
+			rr := httptest.NewRecorder()
+
+			server.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, rr.Code)
+			}
+			if tt.want == http.StatusOK && (usersStub.lastRoleUser != 7 || usersStub.lastRole != models.RoleCurator) {
+				t.Fatalf("expected user 7 to become curator, got %d/%q", usersStub.lastRoleUser, usersStub.lastRole)
+			}
+		})
+	}
+}
+
+func TestHandleCreateAPIToken(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{name: "created", body: `{"name":"cli","scopes":["catalog:read"],"expires_in_days":30}`, want: http.StatusCreated},
+		{name: "unknown scope", body: `{"name":"cli","scopes":["admin"]}`, err: store.ErrInvalidAPIToken, want: http.StatusBadRequest},
+		{name: "negative expiry", body: `{"name":"cli","scopes":["catalog:read"],"expires_in_days":-1}`, want: http.StatusBadRequest},
+		{name: "unauthorized", body: `{"name":"cli","scopes":["catalog:read"]}`, err: store.ErrUnauthorized, want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usersStub := &stubUserService{createAPITokenErr: tt.err, createdAPIToken: store.APIToken{ID: 3, Prefix: "vhp_abcdefgh"}}
+			server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/me/tokens", bytes.NewReader([]byte(tt.body)))
+			req.Header.Set("Authorization", "Bearer tok")
+			rr := httptest.NewRecorder()
+
+			server.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, rr.Code)
+			}
+			if tt.want != http.StatusCreated {
+				return
+			}
+			var payload struct {
+				ID        int64      `json:"id"`
+				Token     string     `json:"token"`
+				ExpiresAt *time.Time `json:"expires_at"`
+			}
+			if err := json.NewDecoder(rr.Body).Decode(&payload); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+			if payload.ID != 3 || payload.Token != "vhp_secret" || payload.ExpiresAt == nil {
+				t.Fatalf("unexpected token payload: %+v", payload)
+			}
+		})
+	}
+}
+
+func TestHandleRevokeAPIToken(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		err  error
+		want int
+	}{
+		{name: "revoked", path: "/api/v1/me/tokens/5", want: http.StatusNoContent},
+		{name: "not found", path: "/api/v1/me/tokens/5", err: store.ErrAPITokenNotFound, want: http.StatusNotFound},
+		{name: "bad id", path: "/api/v1/me/tokens/x", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usersStub := &stubUserService{revokeTokenErr: tt.err}
+			server := newTestServerWithUsers(t, usersStub, nil, nil, nil)
+
+			req := httptest.NewRequest(http.MethodDelete, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer tok")
+			rr := httptest.NewRecorder()
+
+			server.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, rr.Code)
+			}
+			if tt.want == http.StatusNoContent && usersStub.lastTokenID != 5 {
+				t.Fatalf("expected token 5 to be revoked, got %d", usersStub.lastTokenID)
+			}
+		})
+	}
+}
+
+func TestAPITokenScopeEnforcement(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		scopes []string
+		err    error
+		want   int
+	}{
+		{name: "read scope allows listing", method: http.MethodGet, path: "/api/v1/me/albums/preferences", scopes: []string{"ratings:read"}, want: http.StatusOK},
+		{name: "read scope cannot write", method: http.MethodPut, path: "/api/v1/me/albums/42/preference", scopes: []string{"ratings:read"}, want: http.StatusForbidden},
+		{name: "other resource scope", method: http.MethodGet, path: "/api/v1/me/albums/preferences", scopes: []string{"catalog:read"}, want: http.StatusForbidden},
+		{name: "account endpoints refused", method: http.MethodGet, path: "/api/v1/me/sessions", scopes: []string{"catalog:read"}, want: http.StatusForbidden},
+		{name: "token management refused", method: http.MethodPost, path: "/api/v1/me/tokens", scopes: []string{"catalog:write"}, want: http.StatusForbidden},
+		{name: "revoked token", method: http.MethodGet, path: "/api/v1/me/albums/preferences", err: store.ErrUnauthorized, want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usersStub := &stubUserService{apiTokenScopes: tt.scopes, apiTokenErr: tt.err}
+			ratingsStub := &stubRatingsService{}
+			server := newTestServerWithUsers(t, usersStub, nil, ratingsStub, nil)
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewReader([]byte(`{"rating":4}`)))
+			req.Header.Set("Authorization", "Bearer vhp_token")
+			rr := httptest.NewRecorder()
+
+			server.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, rr.Code, rr.Body.String())
+			}
+			if tt.want == http.StatusOK && ratingsStub.lastToken != "vhp_token" {
+				t.Fatalf("expected handler to receive the token, got %q", ratingsStub.lastToken)
+			}
+		})
+	}
+}
+
+func TestHandleListArtistsKeepsProviderFields(t *testing.T) {
+	artistStub := &stubArtistService{
+		list: []store.ArtistListing{{
+			Artist:      store.Artist{ID: 3, Name: "Can", ImageURL: "https://i.scdn.co/image/can.jpg", Genres: []string{"krautrock"}},
+			ExternalID:  "4l8xPGtl6DHR2uvunqrl8r",
+			Provider:    "spotify",
+			Popularity:  61,
+			ExternalURL: "https://open.spotify.com/artist/4l8xPGtl6DHR2uvunqrl8r",
+		}},
+		next: "next-page",
+	}
+	server := newTestServer(t, nil, nil, nil)
+	server.artists = artistStub
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/artists", nil)
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var got struct {
+		Artists    []map[string]any `json:"artists"`
+		NextCursor string           `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.NextCursor != "next-page" || len(got.Artists) != 1 {
+		t.Fatalf("unexpected response %s", rr.Body.String())
+	}
+	want := map[string]any{
+		"id":           float64(3),
+		"name":         "Can",
+		"external_id":  "4l8xPGtl6DHR2uvunqrl8r",
+		"provider":     "spotify",
+		"image_url":    "https://i.scdn.co/image/can.jpg",
+		"genres":       []any{"krautrock"},
+		"popularity":   float64(61),
+		"external_url": "https://open.spotify.com/artist/4l8xPGtl6DHR2uvunqrl8r",
+	}
+	if !reflect.DeepEqual(got.Artists[0], want) {
+		t.Fatalf("got artist %v, want %v", got.Artists[0], want)
+	}
+}
+
+func TestHandleGetCatalogArtist(t *testing.T) {
+	artistStub := &stubArtistService{
+		discography: store.ArtistDiscography{
+			Artist: store.Artist{ID: 5, Name: "De La Soul"},
+			Albums: []store.DiscographyAlbum{
+				{Album: store.Album{ID: 1, Artist: "De La Soul", Title: "3 Feet High and Rising"}, Role: store.ArtistRolePrimary},
+			},
+		},
+	}
+	server := newTestServer(t, nil, nil, nil)
+	server.artists = artistStub
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/artists/5", nil)
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if artistStub.lastID != 5 {
+		t.Fatalf("expected artist 5 to be requested, got %d", artistStub.lastID)
+	}
+
+	var got store.ArtistDiscography
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if got.Artist.Name != "De La Soul" || len(got.Albums) != 1 || got.Albums[0].Role != store.ArtistRolePrimary {
+		t.Fatalf("unexpected discography %+v", got)
+	}
+}
+
+func TestHandleGetCatalogArtistErrors(t *testing.T) {
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/api/v1/artists/abc", http.StatusBadRequest},
+		{"/api/v1/artists/0", http.StatusBadRequest},
+		{"/api/v1/artists/404", http.StatusNotFound},
+	}
+
+	server := newTestServer(t, nil, nil, nil)
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		rr := httptest.NewRecorder()
+		server.Routes().ServeHTTP(rr, req)
+
+		if rr.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.want, rr.Code)
+		}
+	}
+}
+
+func TestHandleUpdateAlbum(t *testing.T) {
+	albumStub := &stubAlbumService{singleAlbum: store.Album{Artist: "Pink Floyd", Title: "Dark Side of the Mon", ReleaseYear: 1973}}
+	server := newTestServer(t, albumStub, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/albums/4", bytes.NewBufferString(`{"title":"The Dark Side of the Moon"}`))
+	req.Header.Set("Authorization", "Bearer curator-token")
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if albumStub.lastToken != "curator-token" {
+		t.Fatalf("expected token to be forwarded, got %q", albumStub.lastToken)
+	}
+	patch := albumStub.patch
+	if patch.Title == nil || *patch.Title != "The Dark Side of the Moon" {
+		t.Fatalf("expected title in patch, got %+v", patch)
+	}
+	if patch.Artist != nil || patch.ReleaseYear != nil || patch.Genres != nil || patch.Rating != nil || patch.Artists != nil {
+		t.Fatalf("expected omitted fields to stay nil, got %+v", patch)
+	}
+
+	var album store.Album
+	if err := json.NewDecoder(rr.Body).Decode(&album); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if album.ID != 4 || album.Title != "The Dark Side of the Moon" {
+		t.Fatalf("unexpected album %+v", album)
+	}
+}
+
+func TestHandleDeleteAlbum(t *testing.T) {
+	albumStub := &stubAlbumService{}
+	server := newTestServer(t, albumStub, nil, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/albums/4", nil)
+	req.Header.Set("Authorization", "Bearer curator-token")
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if albumStub.deletedID != 4 {
+		t.Fatalf("expected album 4 to be deleted, got %d", albumStub.deletedID)
+	}
+}
+
+func TestHandleAlbumEditErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		err    error
+		want   int
+	}{
+		{"missing token", http.MethodPatch, "/api/v1/albums/4", "", nil, http.StatusUnauthorized},
+		{"not owner", http.MethodPatch, "/api/v1/albums/4", "token", store.ErrForbidden, http.StatusForbidden},
+		{"invalid album", http.MethodPatch, "/api/v1/albums/4", "token", store.ErrInvalidAlbum, http.StatusBadRequest},
+		{"unknown album", http.MethodDelete, "/api/v1/albums/4", "token", store.ErrAlbumNotFound, http.StatusNotFound},
+		{"collected by others", http.MethodDelete, "/api/v1/albums/4", "token", store.ErrAlbumInUse, http.StatusConflict},
+		{"bad id", http.MethodDelete, "/api/v1/albums/abc", "token", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, &stubAlbumService{editErr: tt.err}, nil, nil)
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(`{"rating":4}`))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			server.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleCreateRelease(t *testing.T) {
+	albumStub := &stubAlbumService{}
+	server := newTestServer(t, albumStub, nil, nil)
+
+	body := bytes.NewBufferString(`{"format":"vinyl","label":"Harvest","catalog_number":"SHVL 804","pressing_year":1973,"color_variant":"black"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/albums/7/releases", body)
+	req.Header.Set("Authorization", "Bearer curator-token")
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if albumStub.lastToken != "curator-token" {
+		t.Fatalf("expected token to be forwarded, got %q", albumStub.lastToken)
+	}
+	if albumStub.createdRelease.AlbumID != 7 || albumStub.createdRelease.Format != models.ReleaseFormatVinyl ||
+		albumStub.createdRelease.CatalogNumber != "SHVL 804" || albumStub.createdRelease.PressingYear != 1973 {
+		t.Fatalf("unexpected release passed to service: %+v", albumStub.createdRelease)
+	}
+}
+
+func TestHandleReleaseErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		err    error
+		want   int
+	}{
+		{"missing token", http.MethodPost, "/api/v1/albums/7/releases", "", nil, http.StatusUnauthorized},
+		{"not curator", http.MethodPost, "/api/v1/albums/7/releases", "token", store.ErrForbidden, http.StatusForbidden},
+		{"invalid release", http.MethodPut, "/api/v1/releases/3", "token", store.ErrInvalidRelease, http.StatusBadRequest},
+		{"unknown album", http.MethodGet, "/api/v1/albums/9/releases", "", store.ErrAlbumNotFound, http.StatusNotFound},
+		{"unknown release", http.MethodGet, "/api/v1/releases/3", "", nil, http.StatusNotFound},
+		{"bad id", http.MethodGet, "/api/v1/releases/abc", "", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, &stubAlbumService{releaseErr: tt.err}, nil, nil)
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(`{"format":"cd"}`))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			server.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestHandleListAlbumTracks(t *testing.T) {
+	songStub := &stubSongService{tracks: []songs.Song{
+		{ID: 40, AlbumID: 4, DiscNumber: 1, Side: "A", TrackNumber: 1, Title: "Speak to Me"},
+		{ID: 41, AlbumID: 4, DiscNumber: 1, Side: "B", TrackNumber: 1, Title: "Money"},
+	}}
+	server := newTestServer(t, nil, nil, nil)
+	server.songs = songStub
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/albums/4/tracks", nil)
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if songStub.lastAlbumID != 4 {
+		t.Fatalf("expected album 4 to be requested, got %d", songStub.lastAlbumID)
+	}
+
+	var payload struct {
+		Tracks []songs.Song `json:"tracks"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(payload.Tracks) != 2 || payload.Tracks[1].Side != "B" || payload.Tracks[1].Title != "Money" {
+		t.Fatalf("unexpected tracks %+v", payload.Tracks)
+	}
+
+	songStub.err = store.ErrAlbumNotFound
+	rr = httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/albums/9/tracks", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for unknown album, got %d", rr.Code)
+	}
+}
+
+func TestHandleCatalogSearch(t *testing.T) {
+	albumID := int64(4)
+	catalogStub := &stubCatalogService{results: []store.CatalogSearchResult{
+		{Type: store.SearchTypeArtist, ID: 2, Title: "Beyoncé", Rank: 1.1, Highlight: "<mark>Beyoncé</mark>"},
+		{Type: store.SearchTypeSong, ID: 9, Title: "Halo", Artist: "Beyoncé", AlbumID: &albumID, Album: "I Am... Sasha Fierce", Rank: 0.4, Highlight: "Halo"},
+	}}
+	server := newTestServer(t, nil, nil, nil)
+	server.catalog = catalogStub
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/catalog/search?q=beyonce&type=artist,song&limit=5", nil)
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	want := store.CatalogSearch{Query: "beyonce", Types: []string{"artist", "song"}, Limit: 5}
+	if !reflect.DeepEqual(catalogStub.lastSearch, want) {
+		t.Fatalf("search = %+v, want %+v", catalogStub.lastSearch, want)
+	}
+
+	var body struct {
+		Results []store.CatalogSearchResult `json:"results"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Results) != 2 || body.Results[0].Highlight != "<mark>Beyoncé</mark>" || *body.Results[1].AlbumID != 4 {
+		t.Fatalf("unexpected results %+v", body.Results)
+	}
+}
+
+func TestHandleCatalogSearchErrors(t *testing.T) {
+	server := newTestServer(t, nil, nil, nil)
+	server.catalog = &stubCatalogService{err: store.ErrInvalidSearch}
+
+	for _, target := range []string{"/api/v1/catalog/search?q=", "/api/v1/catalog/search?q=x&limit=ten"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rr := httptest.NewRecorder()
+		server.Routes().ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status 400, got %d", target, rr.Code)
+		}
+	}
+}
+
+func TestHandleGenres(t *testing.T) {
+	rock := int64(1)
+	genreStub := &stubGenreService{genres: []store.Genre{
+		{ID: 1, Name: "Rock", Aliases: []string{"Rock and Roll"}},
+		{ID: 2, Name: "Post-Punk", ParentID: &rock, Aliases: []string{}},
+	}}
+	server := newTestServer(t, nil, nil, nil)
+	server.genres = genreStub
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/catalog/genres", nil)
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var body struct {
+		Genres []store.Genre `json:"genres"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Genres) != 2 || body.Genres[1].ParentID == nil || *body.Genres[1].ParentID != 1 {
+		t.Fatalf("unexpected genres %+v", body.Genres)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/catalog/genres", bytes.NewBufferString(`{"name": "Coldwave", "parentId": 2, "aliases": ["Cold Wave"]}`))
+	req.Header.Set("Authorization", "Bearer curator-token")
+	rr = httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	created := genreStub.lastCreated
+	if created.Name != "Coldwave" || created.ParentID == nil || *created.ParentID != 2 || !reflect.DeepEqual(created.Aliases, []string{"Cold Wave"}) || genreStub.lastToken != "curator-token" {
+		t.Fatalf("unexpected create call: %+v", genreStub)
+	}
+
+	// A parentId of 0 moves the genre to the top level; omitted fields stay nil.
+	req = httptest.NewRequest(http.MethodPatch, "/api/v1/catalog/genres/2", bytes.NewBufferString(`{"parentId": 0}`))
+	req.Header.Set("Authorization", "Bearer curator-token")
+	rr = httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	patch := genreStub.lastPatch
+	if genreStub.lastID != 2 || patch.ParentID == nil || *patch.ParentID != 0 || patch.Name != nil || patch.Aliases != nil {
+		t.Fatalf("unexpected patch call: %+v", genreStub)
+	}
+}
+
+func TestHandleGenreErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		err    error
+		want   int
+	}{
+		{"missing token", http.MethodPost, "/api/v1/catalog/genres", "", `{"name": "Rock"}`, nil, http.StatusUnauthorized},
+		{"not curator", http.MethodPost, "/api/v1/catalog/genres", "token", `{"name": "Rock"}`, store.ErrForbidden, http.StatusForbidden},
+		{"cycle", http.MethodPatch, "/api/v1/catalog/genres/1", "token", `{"parentId": 2}`, store.ErrInvalidGenre, http.StatusBadRequest},
+		{"taken alias", http.MethodPatch, "/api/v1/catalog/genres/1", "token", `{"aliases": ["Rap"]}`, store.ErrGenreConflict, http.StatusConflict},
+		{"bad id", http.MethodPatch, "/api/v1/catalog/genres/x", "token", `{}`, nil, http.StatusBadRequest},
+		{"unknown genre", http.MethodGet, "/api/v1/catalog/genres/7", "", "", nil, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		server := newTestServer(t, nil, nil, nil)
+		server.genres = &stubGenreService{err: tt.err}
+
+		req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		rr := httptest.NewRecorder()
+		server.Routes().ServeHTTP(rr, req)
+
+		if rr.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.want, rr.Code)
+		}
+	}
+}
+
+func TestHandleMergeDuplicate(t *testing.T) {
+	dupStub := &stubDuplicateService{merged: store.Album{ID: 3, Title: "Abbey Road"}}
+	server := newTestServer(t, nil, nil, nil)
+	server.duplicates = dupStub
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/catalog/duplicates/12/merge", bytes.NewBufferString(`{"keepAlbumId": 8}`))
+	req.Header.Set("Authorization", "Bearer curator-token")
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if dupStub.lastID != 12 || dupStub.lastKeepID != 8 || dupStub.lastToken != "curator-token" {
+		t.Fatalf("unexpected merge call: %+v", dupStub)
+	}
+
+	// Without a body the older album is kept.
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/catalog/duplicates/13/merge", nil)
+	req.Header.Set("Authorization", "Bearer curator-token")
+	rr = httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200 without body, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if dupStub.lastID != 13 || dupStub.lastKeepID != 0 {
+		t.Fatalf("expected default keep album, got %+v", dupStub)
+	}
+}
+
+func TestHandleMergeAlbums(t *testing.T) {
+	dupStub := &stubDuplicateService{merged: store.Album{ID: 3}}
+	server := newTestServer(t, nil, nil, nil)
+	server.duplicates = dupStub
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/catalog/merges", bytes.NewBufferString(`{"keepAlbumId": 3, "mergeAlbumId": 9}`))
+	req.Header.Set("Authorization", "Bearer curator-token")
+	rr := httptest.NewRecorder()
+	server.Routes().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if dupStub.lastKeepID != 3 || dupStub.lastMergeID != 9 {
+		t.Fatalf("unexpected merge call: %+v", dupStub)
+	}
+}
+
+func TestHandleDuplicateErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		err    error
+		want   int
+	}{
+		{"missing token", http.MethodGet, "/api/v1/catalog/duplicates", "", nil, http.StatusUnauthorized},
+		{"not curator", http.MethodPost, "/api/v1/catalog/duplicates/scan", "token", store.ErrForbidden, http.StatusForbidden},
+		{"reviewed candidate", http.MethodPost, "/api/v1/catalog/duplicates/4/dismiss", "token", store.ErrDuplicateNotFound, http.StatusNotFound},
+		{"self merge", http.MethodPost, "/api/v1/catalog/duplicates/4/merge", "token", store.ErrInvalidMerge, http.StatusBadRequest},
+		{"bad id", http.MethodPost, "/api/v1/catalog/duplicates/abc/dismiss", "token", nil, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, nil, nil, nil)
+			server.duplicates = &stubDuplicateService{err: tt.err}
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rr := httptest.NewRecorder()
+			server.Routes().ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Fatalf("expected status %d, got %d: %s", tt.want, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"vinylhound/internal/store"
+)
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	if err := s.users.Logout(r.Context(), token); err != nil {
+		status := http.StatusUnauthorized
+		if !errors.Is(err, store.ErrUnauthorized) {
+			status = http.StatusInternalServerError
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+	if strings.TrimSpace(req.RefreshToken) == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "refresh_token is required"})
+		return
+	}
+
+	tokens, err := s.users.Refresh(r.Context(), req.RefreshToken)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrInvalidRefreshToken) || errors.Is(err, store.ErrRefreshTokenReused) {
+			status = http.StatusUnauthorized
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newTokenResponse(tokens))
+}
+
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	sessions, err := s.users.Sessions(r.Context(), token)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if !errors.Is(err, store.ErrUnauthorized) {
+			status = http.StatusInternalServerError
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Sessions []store.Session `json:"sessions"`
+	}{Sessions: sessions})
+}
+
+func (s *Server) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	sessionID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid session id"})
+		return
+	}
+
+	if err := s.users.RevokeSession(r.Context(), token, sessionID); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, store.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		case errors.Is(err, store.ErrSessionNotFound):
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	revoked, err := s.users.RevokeOtherSessions(r.Context(), token)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if !errors.Is(err, store.ErrUnauthorized) {
+			status = http.StatusInternalServerError
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Revoked int64 `json:"revoked"`
+	}{Revoked: revoked})
+}
+
+func newTokenResponse(tokens store.SessionTokens) tokenResponse {
+	return tokenResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.ExpiresAt,
+	}
+}
+
+// sessionMetadata captures the client details stored alongside a new session.
+func (s *Server) sessionMetadata(r *http.Request) store.SessionMetadata {
+	return store.SessionMetadata{
+		UserAgent: r.UserAgent(),
+		IPAddress: s.clientIP(r),
+	}
+}
+
+// SetTrustedProxies lists the proxies, such as the gateway, whose
+// X-Forwarded-For header is believed. Without any the header is ignored.
+func (s *Server) SetTrustedProxies(proxies []netip.Prefix) {
+	s.trustedProxies = proxies
+}
+
+// clientIP returns the originating client address. When the request comes
+// from a trusted proxy the X-Forwarded-For hops are walked from the nearest
+// one back, and the first address that is not itself a trusted proxy is the
+// client; anything before it could have been sent by the client.
+func (s *Server) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !s.trustedProxy(host) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		if !s.trustedProxy(hop) {
+			return hop
+		}
+		host = hop
+	}
+	return host
+}
+
+func (s *Server) trustedProxy(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range s.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,6 +2,7 @@ package httpapi
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -33,18 +34,27 @@ func (s *Server) handleSongs(w http.ResponseWriter, r *http.Request) {
 		filter.AlbumID = &albumID
 	}
 
-	songs, err := s.songs.Search(r.Context(), filter)
+	page, err := parsePage(r)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	songs, next, err := s.songs.Search(r.Context(), filter, page)
+	if err != nil {
+		http.Error(w, err.Error(), listErrorStatus(err))
 		return
 	}
 
 	response := struct {
-		Songs []store.Song `json:"songs"`
+		Songs      []store.Song `json:"songs"`
+		NextCursor string       `json:"next_cursor,omitempty"`
 	}{
-		Songs: songs,
+		Songs:      songs,
+		NextCursor: next,
 	}
 
+	setNextPage(w, r, next)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
@@ -82,3 +92,25 @@ func (s *Server) handleSong(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(song)
 }
+
+// handleListAlbumTracks returns an album's tracklist in disc, side and
+// position order.
+func (s *Server) handleListAlbumTracks(w http.ResponseWriter, r *http.Request) {
+	albumID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid album ID"})
+		return
+	}
+
+	tracks, err := s.songs.ListByAlbum(r.Context(), albumID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, store.ErrAlbumNotFound) {
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"tracks": tracks})
+}
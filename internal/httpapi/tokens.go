@@ -0,0 +1,205 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"vinylhound/internal/store"
+	"vinylhound/shared/go/auth"
+)
+
+type createAPITokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+type createAPITokenResponse struct {
+	store.APIToken
+	Token string `json:"token"`
+}
+
+// scopedResource maps a path prefix to the resource whose read or write
+// scope a personal access token needs. Longer prefixes come first.
+type scopedResource struct {
+	prefix   string
+	resource string
+	readOnly bool
+}
+
+var scopedResources = []scopedResource{
+	{prefix: "/api/v1/me/albums/", resource: "ratings"},
+	{prefix: "/api/me/albums/", resource: "ratings"},
+	{prefix: "/api/v1/me/albums", resource: "catalog"},
+	{prefix: "/api/me/albums", resource: "catalog"},
+	{prefix: "/api/v1/albums", resource: "catalog"},
+	{prefix: "/api/v1/album/", resource: "catalog"},
+	{prefix: "/api/v1/releases", resource: "catalog"},
+	{prefix: "/api/v1/catalog/", resource: "catalog"},
+	{prefix: "/api/album", resource: "catalog"},
+	{prefix: "/api/v1/songs", resource: "catalog"},
+	{prefix: "/api/v1/artist", resource: "catalog"},
+	{prefix: "/api/v1/import/", resource: "catalog"},
+	{prefix: "/api/v1/providers", resource: "catalog"},
+	{prefix: "/api/v1/lookup", resource: "catalog", readOnly: true},
+	{prefix: "/api/v1/search", resource: "catalog", readOnly: true},
+	{prefix: "/api/v1/media/", resource: "catalog", readOnly: true},
+	{prefix: "/api/v1/collections", resource: "collections"},
+	{prefix: "/api/v1/playlists", resource: "playlists"},
+	{prefix: "/api/v1/me/favorites/", resource: "favorites"},
+	{prefix: "/api/me/favorites/", resource: "favorites"},
+	{prefix: "/api/v1/favorites", resource: "favorites"},
+	{prefix: "/api/v1/concerts", resource: "concerts"},
+	{prefix: "/api/v1/venues", resource: "places"},
+	{prefix: "/api/v1/retailers", resource: "places"},
+}
+
+// requiredScope returns the scope a personal access token needs for the
+// request, or "" when such tokens may not use the endpoint at all (sign-in,
+// sessions, account and token management).
+func requiredScope(r *http.Request) string {
+	for _, sr := range scopedResources {
+		if !strings.HasPrefix(r.URL.Path, sr.prefix) {
+			continue
+		}
+		switch {
+		case sr.readOnly, r.Method == http.MethodGet, r.Method == http.MethodHead:
+			return sr.resource + ":read"
+		default:
+			return sr.resource + ":write"
+		}
+	}
+	return ""
+}
+
+// withAPITokenScopes rejects personal access tokens that lack the scope of
+// the requested endpoint. Session tokens pass through unchanged; handlers
+// resolve both kinds of token to the same user.
+func (s *Server) withAPITokenScopes(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := extractToken(r)
+		if !auth.IsAPIToken(token) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scope := requiredScope(r)
+		if scope == "" {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "personal access tokens cannot use this endpoint"})
+			return
+		}
+
+		scopes, err := s.users.APITokenScopes(r.Context(), token)
+		if err != nil {
+			status := http.StatusUnauthorized
+			if !errors.Is(err, store.ErrUnauthorized) {
+				status = http.StatusInternalServerError
+			}
+			writeJSON(w, status, errorResponse{Error: err.Error()})
+			return
+		}
+		if !slices.Contains(scopes, scope) {
+			writeJSON(w, http.StatusForbidden, errorResponse{Error: "token lacks scope " + scope})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) handleCreateAPIToken(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	var req createAPITokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+	if req.ExpiresInDays < 0 {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "expires_in_days must not be negative"})
+		return
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresInDays > 0 {
+		at := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		expiresAt = &at
+	}
+
+	created, secret, err := s.users.CreateAPIToken(r.Context(), token, req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, store.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		case errors.Is(err, store.ErrInvalidAPIToken):
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, createAPITokenResponse{APIToken: created, Token: secret})
+}
+
+func (s *Server) handleListAPITokens(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	tokens, err := s.users.APITokens(r.Context(), token)
+	if err != nil {
+		status := http.StatusUnauthorized
+		if !errors.Is(err, store.ErrUnauthorized) {
+			status = http.StatusInternalServerError
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+	if tokens == nil {
+		tokens = []store.APIToken{}
+	}
+
+	writeJSON(w, http.StatusOK, struct {
+		Tokens []store.APIToken `json:"tokens"`
+	}{Tokens: tokens})
+}
+
+func (s *Server) handleRevokeAPIToken(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	tokenID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid token id"})
+		return
+	}
+
+	if err := s.users.RevokeAPIToken(r.Context(), token, tokenID); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, store.ErrUnauthorized):
+			status = http.StatusUnauthorized
+		case errors.Is(err, store.ErrAPITokenNotFound):
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, errorResponse{Error: err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
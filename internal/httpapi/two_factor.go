@@ -0,0 +1,191 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"vinylhound/internal/store"
+)
+
+// twoFactorMethods lists the second factors a login challenge accepts.
+var twoFactorMethods = []string{"totp", "recovery_code"}
+
+type twoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	Methods           []string  `json:"methods"`
+}
+
+type twoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
+
+type twoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// writeLoginError answers a failed password or second-factor login step.
+func writeLoginError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var (
+		throttle  *store.LoginThrottleError
+		challenge *store.TwoFactorRequiredError
+	)
+	switch {
+	case errors.As(err, &challenge):
+		writeJSON(w, http.StatusAccepted, twoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge.ChallengeToken,
+			ExpiresAt:         challenge.ExpiresAt,
+			Methods:           twoFactorMethods,
+		})
+		return
+	case errors.As(err, &throttle):
+		status = http.StatusTooManyRequests
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttle.RetryAfter.Seconds()))))
+	case errors.Is(err, store.ErrInvalidCredentials),
+		errors.Is(err, store.ErrInvalidTwoFactorChallenge),
+		errors.Is(err, store.ErrInvalidTwoFactorCode):
+		status = http.StatusUnauthorized
+	}
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// twoFactorErrorStatus maps 2FA management errors to HTTP statuses.
+func twoFactorErrorStatus(w http.ResponseWriter, err error) int {
+	var throttle *store.LoginThrottleError
+	switch {
+	case errors.As(err, &throttle):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(throttle.RetryAfter.Seconds()))))
+		return http.StatusTooManyRequests
+	case errors.Is(err, store.ErrUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, store.ErrInvalidTwoFactorCode):
+		return http.StatusForbidden
+	case errors.Is(err, store.ErrTwoFactorEnabled),
+		errors.Is(err, store.ErrTwoFactorNotEnabled),
+		errors.Is(err, store.ErrTwoFactorNotEnrolling):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// handleTwoFactorLogin finishes a login that answered 202 with a challenge.
+func (s *Server) handleTwoFactorLogin(w http.ResponseWriter, r *http.Request) {
+	var req twoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+	if req.ChallengeToken == "" || req.Code == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "challenge_token and code are required"})
+		return
+	}
+
+	tokens, err := s.users.CompleteTwoFactorLogin(r.Context(), req.ChallengeToken, req.Code, s.sessionMetadata(r))
+	if err != nil {
+		writeLoginError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newTokenResponse(tokens))
+}
+
+func (s *Server) handleTwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	status, err := s.users.TwoFactorStatus(r.Context(), token)
+	if err != nil {
+		writeJSON(w, twoFactorErrorStatus(w, err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleBeginTOTPEnrollment returns a new secret for the user to add to an
+// authenticator app. 2FA is enabled by confirming a code from it.
+func (s *Server) handleBeginTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	enrollment, err := s.users.BeginTOTPEnrollment(r.Context(), token)
+	if err != nil {
+		writeJSON(w, twoFactorErrorStatus(w, err), errorResponse{Error: err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, enrollment)
+}
+
+func (s *Server) handleConfirmTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	s.withTwoFactorCode(w, r, func(token, code string) {
+		codes, err := s.users.ConfirmTOTPEnrollment(r.Context(), token, code)
+		if err != nil {
+			writeJSON(w, twoFactorErrorStatus(w, err), errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+	})
+}
+
+func (s *Server) handleRegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	s.withTwoFactorCode(w, r, func(token, code string) {
+		codes, err := s.users.RegenerateRecoveryCodes(r.Context(), token, code)
+		if err != nil {
+			writeJSON(w, twoFactorErrorStatus(w, err), errorResponse{Error: err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, recoveryCodesResponse{RecoveryCodes: codes})
+	})
+}
+
+func (s *Server) handleDisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	s.withTwoFactorCode(w, r, func(token, code string) {
+		if err := s.users.DisableTwoFactor(r.Context(), token, code); err != nil {
+			writeJSON(w, twoFactorErrorStatus(w, err), errorResponse{Error: err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// withTwoFactorCode reads the bearer token and {"code": ...} body shared by
+// the 2FA management endpoints before calling next.
+func (s *Server) withTwoFactorCode(w http.ResponseWriter, r *http.Request, next func(token, code string)) {
+	token := extractToken(r)
+	if token == "" {
+		writeJSON(w, http.StatusUnauthorized, errorResponse{Error: "missing bearer token"})
+		return
+	}
+
+	var req twoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "invalid JSON payload"})
+		return
+	}
+	if req.Code == "" {
+		writeJSON(w, http.StatusBadRequest, errorResponse{Error: "code is required"})
+		return
+	}
+
+	next(token, req.Code)
+}
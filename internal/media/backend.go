@@ -0,0 +1,116 @@
+// Package media keeps images for the catalog and collections: cover art
+// downloaded from providers and photos users upload of their own copies.
+// Originals and their thumbnails are stored in a pluggable Backend under the
+// SHA-256 hash of the original, so they can be served with long-lived
+// caching headers.
+package media
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// ErrNotFound signals a key the backend does not hold.
+var ErrNotFound = errors.New("media object not found")
+
+// Backend stores blobs by slash-separated key. Implementations must make a
+// Put visible to Open only once it is complete.
+type Backend interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// FSBackend keeps blobs as files below a root directory.
+type FSBackend struct {
+	root string
+}
+
+// NewFSBackend returns a Backend storing files below root, creating the
+// directory if needed.
+func NewFSBackend(root string) (*FSBackend, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("create media directory: %w", err)
+	}
+	return &FSBackend{root: root}, nil
+}
+
+// Put writes the blob to a temporary file and renames it into place.
+func (b *FSBackend) Put(ctx context.Context, key string, r io.Reader) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	target, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("create media directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("create media file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write media file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write media file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("store media file: %w", err)
+	}
+	return nil
+}
+
+// Open returns the blob stored under key.
+func (b *FSBackend) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	target, err := b.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(target)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open media file: %w", err)
+	}
+	return f, nil
+}
+
+// Delete removes the blob stored under key; a missing blob is not an error.
+func (b *FSBackend) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	target, err := b.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("delete media file: %w", err)
+	}
+	return nil
+}
+
+// path maps a key below the root, rejecting keys that would leave it.
+func (b *FSBackend) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if key == "" || clean != "/"+key {
+		return "", fmt.Errorf("invalid media key %q", key)
+	}
+	return filepath.Join(b.root, filepath.FromSlash(clean[1:])), nil
+}
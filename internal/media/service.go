@@ -0,0 +1,266 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // decoders for uploads and provider covers
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"vinylhound/internal/store"
+)
+
+var (
+	// ErrUnsupported signals data that is not a JPEG, PNG or GIF image.
+	ErrUnsupported = errors.New("unsupported image: use JPEG, PNG or GIF")
+	// ErrTooLarge signals an image over the size or pixel limit.
+	ErrTooLarge = errors.New("image too large")
+)
+
+const (
+	// DefaultMaxBytes bounds the size of an uploaded or downloaded image.
+	DefaultMaxBytes = 10 << 20
+	// maxPixels bounds width×height before an image is decoded, so small
+	// files cannot expand into huge bitmaps. 16 megapixels keep a decoded
+	// image and its flattened copy to about 128 MB.
+	maxPixels        = 16_000_000
+	thumbnailType    = "image/jpeg"
+	thumbnailQuality = 85
+)
+
+var hashPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// Store captures the records kept for stored images.
+type Store interface {
+	SaveMedia(ctx context.Context, media store.Media) (store.Media, error)
+	MediaByHash(ctx context.Context, hash string) (store.Media, error)
+	MediaBySourceURL(ctx context.Context, url string) (store.Media, error)
+	SetAlbumCover(ctx context.Context, albumID, mediaID int64) error
+	AlbumCover(ctx context.Context, albumID int64) (store.Media, error)
+	CheckCollectionOwner(ctx context.Context, token string, collectionID int64) (int64, error)
+	AddCollectionPhoto(ctx context.Context, token string, collectionID, mediaID int64, caption string) (store.CollectionPhoto, error)
+	ListCollectionPhotos(ctx context.Context, token string, collectionID int64) ([]store.CollectionPhoto, error)
+	DeleteCollectionPhoto(ctx context.Context, token string, collectionID, photoID int64) error
+}
+
+// Service stores, thumbnails and serves images.
+type Service struct {
+	store    Store
+	backend  Backend
+	client   *http.Client
+	maxBytes int64
+}
+
+// New constructs a media Service. A nil client downloads covers with a
+// 15 second timeout.
+func New(store Store, backend Backend, client *http.Client) *Service {
+	if client == nil {
+		client = &http.Client{Timeout: 15 * time.Second}
+	}
+	return &Service{store: store, backend: backend, client: client, maxBytes: DefaultMaxBytes}
+}
+
+// MaxBytes returns the largest image the service accepts.
+func (s *Service) MaxBytes() int64 {
+	return s.maxBytes
+}
+
+// Save stores the image read from r with its thumbnails. sourceURL records
+// where a downloaded cover came from and uploadedBy who uploaded a photo;
+// both may be empty.
+func (s *Service) Save(ctx context.Context, r io.Reader, sourceURL string, uploadedBy *int64) (store.Media, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.maxBytes+1))
+	if err != nil {
+		return store.Media{}, fmt.Errorf("read image: %w", err)
+	}
+	if int64(len(data)) > s.maxBytes {
+		return store.Media{}, fmt.Errorf("%w: over %d bytes", ErrTooLarge, s.maxBytes)
+	}
+
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return store.Media{}, ErrUnsupported
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return store.Media{}, ErrUnsupported
+	}
+	if config.Width*config.Height > maxPixels {
+		return store.Media{}, fmt.Errorf("%w: %dx%d pixels", ErrTooLarge, config.Width, config.Height)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return store.Media{}, ErrUnsupported
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	// Files go in before the record, so a recorded image is always servable.
+	if err := s.backend.Put(ctx, originalKey(hash), bytes.NewReader(data)); err != nil {
+		return store.Media{}, err
+	}
+	flat := flatten(img)
+	for _, size := range Sizes {
+		if err := s.putThumbnail(ctx, hash, size, flat); err != nil {
+			return store.Media{}, err
+		}
+	}
+
+	return s.store.SaveMedia(ctx, store.Media{
+		Hash:        hash,
+		ContentType: "image/" + format,
+		Width:       config.Width,
+		Height:      config.Height,
+		Size:        int64(len(data)),
+		SourceURL:   sourceURL,
+		UploadedBy:  uploadedBy,
+	})
+}
+
+// Fetch downloads and stores the image at url, unless it was fetched before.
+func (s *Service) Fetch(ctx context.Context, url string) (store.Media, error) {
+	media, err := s.store.MediaBySourceURL(ctx, url)
+	if err == nil || !errors.Is(err, store.ErrMediaNotFound) {
+		return media, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return store.Media{}, fmt.Errorf("build image request: %w", err)
+	}
+	req.Header.Set("Accept", "image/jpeg, image/png, image/gif")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return store.Media{}, fmt.Errorf("download image: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return store.Media{}, fmt.Errorf("download image: %s returned %s", url, resp.Status)
+	}
+	if resp.ContentLength > s.maxBytes {
+		return store.Media{}, fmt.Errorf("%w: over %d bytes", ErrTooLarge, s.maxBytes)
+	}
+	return s.Save(ctx, resp.Body, url, nil)
+}
+
+// ImportAlbumCover downloads the provider cover at url and makes it the
+// album's cover.
+func (s *Service) ImportAlbumCover(ctx context.Context, albumID int64, url string) error {
+	media, err := s.Fetch(ctx, url)
+	if err != nil {
+		return err
+	}
+	return s.store.SetAlbumCover(ctx, albumID, media.ID)
+}
+
+// AlbumCover returns the cover image of an album.
+func (s *Service) AlbumCover(ctx context.Context, albumID int64) (store.Media, error) {
+	if err := ctx.Err(); err != nil {
+		return store.Media{}, err
+	}
+	return s.store.AlbumCover(ctx, albumID)
+}
+
+// Open returns an image and its content: the original when size is empty,
+// otherwise the named thumbnail. Thumbnails missing from the backend, e.g.
+// of a size added later, are made from the original on first request.
+func (s *Service) Open(ctx context.Context, hash, size string) (store.Media, io.ReadCloser, error) {
+	if !hashPattern.MatchString(hash) {
+		return store.Media{}, nil, store.ErrMediaNotFound
+	}
+	thumb, ok := SizeNamed(size)
+	if size != "" && !ok {
+		return store.Media{}, nil, store.ErrMediaNotFound
+	}
+
+	media, err := s.store.MediaByHash(ctx, hash)
+	if err != nil {
+		return store.Media{}, nil, err
+	}
+	if size == "" {
+		body, err := s.backend.Open(ctx, originalKey(hash))
+		return media, body, err
+	}
+
+	media.ContentType = thumbnailType
+	media.Size = 0
+	body, err := s.backend.Open(ctx, thumbnailKey(hash, thumb))
+	if !errors.Is(err, ErrNotFound) {
+		return media, body, err
+	}
+
+	original, err := s.backend.Open(ctx, originalKey(hash))
+	if err != nil {
+		return store.Media{}, nil, err
+	}
+	img, _, err := image.Decode(original)
+	original.Close()
+	if err != nil {
+		return store.Media{}, nil, fmt.Errorf("decode stored image: %w", err)
+	}
+	if err := s.putThumbnail(ctx, hash, thumb, flatten(img)); err != nil {
+		return store.Media{}, nil, err
+	}
+	body, err = s.backend.Open(ctx, thumbnailKey(hash, thumb))
+	return media, body, err
+}
+
+// AddCollectionPhoto stores a photo of the copy behind a collection item of
+// the token's user.
+func (s *Service) AddCollectionPhoto(ctx context.Context, token string, collectionID int64, r io.Reader, caption string) (store.CollectionPhoto, error) {
+	userID, err := s.store.CheckCollectionOwner(ctx, token, collectionID)
+	if err != nil {
+		return store.CollectionPhoto{}, err
+	}
+	media, err := s.Save(ctx, r, "", &userID)
+	if err != nil {
+		return store.CollectionPhoto{}, err
+	}
+	return s.store.AddCollectionPhoto(ctx, token, collectionID, media.ID, caption)
+}
+
+// ListCollectionPhotos returns the photos of a collection item of the
+// token's user.
+func (s *Service) ListCollectionPhotos(ctx context.Context, token string, collectionID int64) ([]store.CollectionPhoto, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s.store.ListCollectionPhotos(ctx, token, collectionID)
+}
+
+// DeleteCollectionPhoto removes a photo from a collection item of the
+// token's user.
+func (s *Service) DeleteCollectionPhoto(ctx context.Context, token string, collectionID, photoID int64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.store.DeleteCollectionPhoto(ctx, token, collectionID, photoID)
+}
+
+func (s *Service) putThumbnail(ctx context.Context, hash string, size Size, flat *image.RGBA) error {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumbnail(flat, size.Max), &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+		return fmt.Errorf("encode %s thumbnail: %w", size.Name, err)
+	}
+	return s.backend.Put(ctx, thumbnailKey(hash, size), &buf)
+}
+
+// originalKey and thumbnailKey spread files over directories by the first
+// two hex digits of the hash.
+func originalKey(hash string) string {
+	return "originals/" + hash[:2] + "/" + hash
+}
+
+func thumbnailKey(hash string, size Size) string {
+	return "thumbnails/" + size.Name + "/" + hash[:2] + "/" + hash + ".jpg"
+}
@@ -0,0 +1,253 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"vinylhound/internal/store"
+)
+
+type memoryStore struct {
+	media  []store.Media
+	covers map[int64]int64
+}
+
+func (m *memoryStore) SaveMedia(_ context.Context, media store.Media) (store.Media, error) {
+	for _, existing := range m.media {
+		if existing.Hash == media.Hash {
+			return existing, nil
+		}
+	}
+	media.ID = int64(len(m.media) + 1)
+	m.media = append(m.media, media)
+	return media, nil
+}
+
+func (m *memoryStore) MediaByHash(_ context.Context, hash string) (store.Media, error) {
+	for _, media := range m.media {
+		if media.Hash == hash {
+			return media, nil
+		}
+	}
+	return store.Media{}, store.ErrMediaNotFound
+}
+
+func (m *memoryStore) MediaBySourceURL(_ context.Context, url string) (store.Media, error) {
+	for _, media := range m.media {
+		if media.SourceURL == url {
+			return media, nil
+		}
+	}
+	return store.Media{}, store.ErrMediaNotFound
+}
+
+func (m *memoryStore) SetAlbumCover(_ context.Context, albumID, mediaID int64) error {
+	if m.covers == nil {
+		m.covers = map[int64]int64{}
+	}
+	m.covers[albumID] = mediaID
+	return nil
+}
+
+func (m *memoryStore) AlbumCover(context.Context, int64) (store.Media, error) {
+	return store.Media{}, store.ErrMediaNotFound
+}
+
+func (m *memoryStore) CheckCollectionOwner(context.Context, string, int64) (int64, error) {
+	return 1, nil
+}
+
+func (m *memoryStore) AddCollectionPhoto(context.Context, string, int64, int64, string) (store.CollectionPhoto, error) {
+	return store.CollectionPhoto{}, nil
+}
+
+func (m *memoryStore) ListCollectionPhotos(context.Context, string, int64) ([]store.CollectionPhoto, error) {
+	return nil, nil
+}
+
+func (m *memoryStore) DeleteCollectionPhoto(context.Context, string, int64, int64) error {
+	return nil
+}
+
+func newTestService(t *testing.T) (*Service, *memoryStore, *FSBackend) {
+	t.Helper()
+	backend, err := NewFSBackend(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFSBackend: %v", err)
+	}
+	st := &memoryStore{}
+	return New(st, backend, nil), st, backend
+}
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSaveStoresOriginalAndThumbnails(t *testing.T) {
+	svc, st, backend := newTestService(t)
+	ctx := context.Background()
+	data := encodePNG(t, 800, 400)
+
+	saved, err := svc.Save(ctx, bytes.NewReader(data), "", nil)
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if saved.ContentType != "image/png" || saved.Width != 800 || saved.Height != 400 || saved.Size != int64(len(data)) {
+		t.Fatalf("unexpected record %+v", saved)
+	}
+
+	original, err := backend.Open(ctx, originalKey(saved.Hash))
+	if err != nil {
+		t.Fatalf("open original: %v", err)
+	}
+	stored, _ := io.ReadAll(original)
+	original.Close()
+	if !bytes.Equal(stored, data) {
+		t.Fatal("original was not stored as uploaded")
+	}
+
+	for _, size := range Sizes {
+		_, body, err := svc.Open(ctx, saved.Hash, size.Name)
+		if err != nil {
+			t.Fatalf("open %s: %v", size.Name, err)
+		}
+		thumb, err := jpeg.Decode(body)
+		body.Close()
+		if err != nil {
+			t.Fatalf("decode %s: %v", size.Name, err)
+		}
+		if b := thumb.Bounds(); b.Dx() != size.Max || b.Dy() != size.Max/2 {
+			t.Fatalf("%s thumbnail is %dx%d", size.Name, b.Dx(), b.Dy())
+		}
+	}
+
+	// The same image is stored once.
+	again, err := svc.Save(ctx, bytes.NewReader(data), "", nil)
+	if err != nil || again.ID != saved.ID || len(st.media) != 1 {
+		t.Fatalf("expected the existing record, got %+v (%v)", again, err)
+	}
+}
+
+func TestOpenRebuildsMissingThumbnails(t *testing.T) {
+	svc, _, backend := newTestService(t)
+	ctx := context.Background()
+
+	saved, err := svc.Save(ctx, bytes.NewReader(encodePNG(t, 100, 200)), "", nil)
+	if err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	small, _ := SizeNamed("small")
+	if err := backend.Delete(ctx, thumbnailKey(saved.Hash, small)); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	media, body, err := svc.Open(ctx, saved.Hash, "small")
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	thumb, err := jpeg.Decode(body)
+	body.Close()
+	if err != nil || media.ContentType != "image/jpeg" {
+		t.Fatalf("expected a JPEG thumbnail, got %q (%v)", media.ContentType, err)
+	}
+	if b := thumb.Bounds(); b.Dx() != 75 || b.Dy() != 150 {
+		t.Fatalf("thumbnail is %dx%d", b.Dx(), b.Dy())
+	}
+
+	for _, tt := range []struct{ hash, size string }{
+		{saved.Hash, "huge"},
+		{"../" + saved.Hash[3:], ""},
+		{saved.Hash[:63] + "x", ""},
+	} {
+		if _, _, err := svc.Open(ctx, tt.hash, tt.size); !errors.Is(err, store.ErrMediaNotFound) {
+			t.Errorf("Open(%q, %q): expected ErrMediaNotFound, got %v", tt.hash, tt.size, err)
+		}
+	}
+}
+
+func TestSaveRejectsInvalidImages(t *testing.T) {
+	svc, _, _ := newTestService(t)
+	svc.maxBytes = 1 << 10
+
+	if _, err := svc.Save(context.Background(), bytes.NewReader([]byte("not an image")), "", nil); !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+	if _, err := svc.Save(context.Background(), bytes.NewReader(make([]byte, 2<<10)), "", nil); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge, got %v", err)
+	}
+
+	// A GIF header claiming 5000×5000 pixels is refused before decoding.
+	header := []byte("GIF89a\x88\x13\x88\x13\x00\x00\x00")
+	if _, err := svc.Save(context.Background(), bytes.NewReader(header), "", nil); !errors.Is(err, ErrTooLarge) {
+		t.Fatalf("expected ErrTooLarge for 25 megapixels, got %v", err)
+	}
+}
+
+func TestImportAlbumCoverDownloadsOnce(t *testing.T) {
+	svc, st, _ := newTestService(t)
+	data := encodePNG(t, 64, 64)
+	requests := 0
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/cover.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(data)
+	}))
+	defer provider.Close()
+
+	ctx := context.Background()
+	for _, albumID := range []int64{7, 8} {
+		if err := svc.ImportAlbumCover(ctx, albumID, provider.URL+"/cover.png"); err != nil {
+			t.Fatalf("ImportAlbumCover(%d): %v", albumID, err)
+		}
+	}
+	if requests != 1 || len(st.media) != 1 || st.covers[7] != 1 || st.covers[8] != 1 {
+		t.Fatalf("expected one download shared by both albums, got %d requests and covers %v", requests, st.covers)
+	}
+	if st.media[0].SourceURL != provider.URL+"/cover.png" {
+		t.Fatalf("source URL not recorded: %+v", st.media[0])
+	}
+
+	if err := svc.ImportAlbumCover(ctx, 9, provider.URL+"/missing.png"); err == nil {
+		t.Fatal("expected an error for a missing cover")
+	}
+}
+
+func TestFSBackendRejectsKeysOutsideRoot(t *testing.T) {
+	backend, err := NewFSBackend(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFSBackend: %v", err)
+	}
+	ctx := context.Background()
+	for _, key := range []string{"", "../escape", "a/../../escape", "/abs", "a//b"} {
+		if err := backend.Put(ctx, key, bytes.NewReader(nil)); err == nil {
+			t.Errorf("Put(%q): expected an error", key)
+		}
+	}
+	if _, err := backend.Open(ctx, "originals/none"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package media
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Size is a thumbnail size: the image is scaled down to fit a square of Max
+// pixels, keeping its aspect ratio.
+type Size struct {
+	Name string
+	Max  int
+}
+
+// Sizes lists the thumbnails made for every stored image.
+var Sizes = []Size{
+	{Name: "small", Max: 150},
+	{Name: "medium", Max: 300},
+	{Name: "large", Max: 600},
+}
+
+// SizeNamed returns the thumbnail size called name.
+func SizeNamed(name string) (Size, bool) {
+	for _, size := range Sizes {
+		if size.Name == name {
+			return size, true
+		}
+	}
+	return Size{}, false
+}
+
+// flatten copies img onto an opaque RGBA canvas. Transparent areas are
+// composed onto white, since thumbnails are JPEG. Every thumbnail of an
+// image is scaled from the one flattened copy.
+func flatten(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+	return flat
+}
+
+// thumbnail scales the flattened src down to fit bound×bound by averaging the
+// source pixels under each target pixel. Images that already fit are
+// returned as they are.
+func thumbnail(src *image.RGBA, bound int) *image.RGBA {
+	srcW, srcH := src.Rect.Dx(), src.Rect.Dy()
+	dstW, dstH := srcW, srcH
+	if srcW > bound || srcH > bound {
+		if srcW >= srcH {
+			dstW, dstH = bound, max(srcH*bound/srcW, 1)
+		} else {
+			dstW, dstH = max(srcW*bound/srcH, 1), bound
+		}
+	}
+	if dstW == srcW && dstH == srcH {
+		return src
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := y * srcH / dstH
+		y1 := max((y+1)*srcH/dstH, y0+1)
+		for x := 0; x < dstW; x++ {
+			x0 := x * srcW / dstW
+			x1 := max((x+1)*srcW/dstW, x0+1)
+
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					px := row[sx*4 : sx*4+3]
+					r += int(px[0])
+					g += int(px[1])
+					b += int(px[2])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = 0xff
+		}
+	}
+	return dst
+}
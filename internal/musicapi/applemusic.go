@@ -94,6 +94,7 @@ type appleMusicAlbumAttributes struct {
 	ReleaseDate  string                `json:"releaseDate"`
 	GenreNames   []string              `json:"genreNames"`
 	TrackCount   int                   `json:"trackCount"`
+	UPC          string                `json:"upc"`
 	Artwork      appleMusicArtwork     `json:"artwork"`
 	URL          string                `json:"url"`
 }
@@ -382,6 +383,66 @@ func (c *AppleMusicClient) GetTrack(ctx context.Context, trackID string) (*Track
 	return &track, nil
 }
 
+// GetArtistAlbums retrieves the albums of an artist
+func (c *AppleMusicClient) GetArtistAlbums(ctx context.Context, artistID string) ([]Album, error) {
+	var result struct {
+		Data []appleMusicAlbum `json:"data"`
+	}
+	params := url.Values{
+		"limit": []string{"100"},
+	}
+	if err := c.doRequest(ctx, "catalog/us/artists/"+artistID+"/albums", params, &result); err != nil {
+		return nil, err
+	}
+
+	albums := make([]Album, 0, len(result.Data))
+	for _, aa := range result.Data {
+		albums = append(albums, c.convertAlbum(aa))
+	}
+
+	return albums, nil
+}
+
+// LookupISRC retrieves the songs recorded under an ISRC
+func (c *AppleMusicClient) LookupISRC(ctx context.Context, isrc string) ([]Track, error) {
+	var result struct {
+		Data []appleMusicSong `json:"data"`
+	}
+	params := url.Values{
+		"filter[isrc]": []string{isrc},
+	}
+	if err := c.doRequest(ctx, "catalog/us/songs", params, &result); err != nil {
+		return nil, err
+	}
+
+	tracks := make([]Track, 0, len(result.Data))
+	for _, as := range result.Data {
+		tracks = append(tracks, c.convertTrack(as))
+	}
+
+	return tracks, nil
+}
+
+// LookupBarcode retrieves the albums released with a UPC
+func (c *AppleMusicClient) LookupBarcode(ctx context.Context, barcode string) ([]Album, error) {
+	var result struct {
+		Data []appleMusicAlbum `json:"data"`
+	}
+	params := url.Values{
+		"filter[upc]": []string{barcode},
+	}
+	if err := c.doRequest(ctx, "catalog/us/albums", params, &result); err != nil {
+		return nil, err
+	}
+
+	albums := make([]Album, 0, len(result.Data))
+	for _, aa := range result.Data {
+		albums = append(albums, c.convertAlbum(aa))
+	}
+
+	return albums, nil
+}
+
 // Helper functions to convert Apple Music types to common types
 
 func (c *AppleMusicClient) convertArtist(aa appleMusicArtist) Artist {
@@ -419,6 +480,7 @@ func (c *AppleMusicClient) convertAlbum(aa appleMusicAlbum) Album {
 		Genre:       genre,
 		CoverURL:    coverURL,
 		TrackCount:  aa.Attributes.TrackCount,
+		UPC:         aa.Attributes.UPC,
 		ExternalURL: aa.Attributes.URL,
 	}
 }
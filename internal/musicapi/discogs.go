@@ -0,0 +1,781 @@
+package musicapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	discogsBaseURL = "https://api.discogs.com/"
+	discogsWebURL  = "https://www.discogs.com/"
+
+	// discogsInterval keeps requests within the 60 a minute allowed to
+	// authenticated clients.
+	discogsInterval = time.Second
+	// discogsMaxWait is how long a request queues for a slot before it fails
+	// with ErrRateLimited.
+	discogsMaxWait = 10 * time.Second
+	// discogsMaxPerPage is the largest page the API returns.
+	discogsMaxPerPage = 100
+)
+
+// DiscogsClient implements the MusicAPIClient interface for the Discogs
+// database. Albums are releases, i.e. specific pressings; tracks have no
+// IDs of their own and come with their release. Requests are spaced a
+// second apart across goroutines.
+type DiscogsClient struct {
+	token      string
+	userAgent  string
+	baseURL    string
+	httpClient *http.Client
+	limiter    rateLimiter
+}
+
+// NewDiscogsClient creates a Discogs client authenticated with a personal
+// access token. Discogs rejects requests without a User-Agent naming the
+// application, such as "Vinylhound/1.0".
+func NewDiscogsClient(token, userAgent string) (*DiscogsClient, error) {
+	if token == "" || userAgent == "" {
+		return nil, errors.New("discogs client: token and user agent are required")
+	}
+	return &DiscogsClient{
+		token:     token,
+		userAgent: userAgent,
+		baseURL:   discogsBaseURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		limiter: rateLimiter{interval: discogsInterval, maxWait: discogsMaxWait},
+	}, nil
+}
+
+// Discogs API response structures
+type discogsArtistCredit struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	ANV  string `json:"anv"`
+	Join string `json:"join"`
+}
+
+type discogsImage struct {
+	Type string `json:"type"`
+	URI  string `json:"uri"`
+}
+
+type discogsSearchResult struct {
+	ID         int      `json:"id"`
+	Type       string   `json:"type"`
+	Title      string   `json:"title"`
+	Year       string   `json:"year"`
+	Country    string   `json:"country"`
+	Format     []string `json:"format"`
+	Label      []string `json:"label"`
+	Catno      string   `json:"catno"`
+	Barcode    []string `json:"barcode"`
+	Genre      []string `json:"genre"`
+	Style      []string `json:"style"`
+	CoverImage string   `json:"cover_image"`
+	Thumb      string   `json:"thumb"`
+}
+
+type discogsArtist struct {
+	ID      int            `json:"id"`
+	Name    string         `json:"name"`
+	Profile string         `json:"profile"`
+	Images  []discogsImage `json:"images"`
+}
+
+type discogsFormat struct {
+	Name         string   `json:"name"`
+	Qty          string   `json:"qty"`
+	Text         string   `json:"text"`
+	Descriptions []string `json:"descriptions"`
+}
+
+type discogsTrack struct {
+	Position string                `json:"position"`
+	Type     string                `json:"type_"`
+	Title    string                `json:"title"`
+	Duration string                `json:"duration"`
+	Artists  []discogsArtistCredit `json:"artists"`
+}
+
+type discogsRelease struct {
+	ID          int                   `json:"id"`
+	Title       string                `json:"title"`
+	Artists     []discogsArtistCredit `json:"artists"`
+	Year        int                   `json:"year"`
+	Released    string                `json:"released"`
+	Country     string                `json:"country"`
+	Genres      []string              `json:"genres"`
+	Styles      []string              `json:"styles"`
+	MasterID    int                   `json:"master_id"`
+	MainRelease int                   `json:"main_release"`
+	Labels      []struct {
+		Name  string `json:"name"`
+		Catno string `json:"catno"`
+	} `json:"labels"`
+	Formats     []discogsFormat `json:"formats"`
+	Identifiers []struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"identifiers"`
+	Tracklist []discogsTrack `json:"tracklist"`
+	Images    []discogsImage `json:"images"`
+}
+
+type discogsLabel struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Profile     string `json:"profile"`
+	ParentLabel *struct {
+		Name string `json:"name"`
+	} `json:"parent_label"`
+	Sublabels []struct {
+		Name string `json:"name"`
+	} `json:"sublabels"`
+}
+
+// discogsListing is an entry of the release lists of artists, labels and
+// masters. Format is a summary such as "LP, Album, RE".
+type discogsListing struct {
+	ID           int      `json:"id"`
+	Type         string   `json:"type"`
+	MainRelease  int      `json:"main_release"`
+	Title        string   `json:"title"`
+	Artist       string   `json:"artist"`
+	Role         string   `json:"role"`
+	Year         int      `json:"year"`
+	Released     string   `json:"released"`
+	Format       string   `json:"format"`
+	MajorFormats []string `json:"major_formats"`
+	Label        string   `json:"label"`
+	Catno        string   `json:"catno"`
+	Country      string   `json:"country"`
+	Thumb        string   `json:"thumb"`
+}
+
+// DiscogsMaster is a Discogs master release: one album with the versions it
+// was pressed as.
+type DiscogsMaster struct {
+	ID            string   `json:"id"`
+	Title         string   `json:"title"`
+	Artist        string   `json:"artist"`
+	ArtistID      string   `json:"artist_id,omitempty"`
+	Year          int      `json:"year,omitempty"`
+	Genres        []string `json:"genres,omitempty"`
+	Styles        []string `json:"styles,omitempty"`
+	MainReleaseID string   `json:"main_release_id,omitempty"`
+	CoverURL      string   `json:"cover_url,omitempty"`
+	ExternalURL   string   `json:"external_url"`
+	Versions      []Album  `json:"versions"`
+}
+
+// DiscogsLabel is a record label with the releases in its catalog.
+type DiscogsLabel struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Profile     string   `json:"profile,omitempty"`
+	ParentLabel string   `json:"parent_label,omitempty"`
+	Sublabels   []string `json:"sublabels,omitempty"`
+	ExternalURL string   `json:"external_url"`
+	Releases    []Album  `json:"releases"`
+}
+
+// doRequest performs a rate limited request to the Discogs API. A 429,
+// which Discogs answers when the per-minute allowance is used up, is
+// retried once after the next slot.
+func (c *DiscogsClient) doRequest(ctx context.Context, endpoint string, params url.Values, result interface{}) error {
+	apiURL := c.baseURL + endpoint
+	if len(params) > 0 {
+		apiURL += "?" + params.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+		if err != nil {
+			return fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Authorization", "Discogs token="+c.token)
+		req.Header.Set("User-Agent", c.userAgent)
+		req.Header.Set("Accept", "application/vnd.discogs.v2.discogs+json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("send request: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			resp.Body.Close()
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return fmt.Errorf("discogs api error: %s - %s", resp.Status, string(body))
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+		return nil
+	}
+}
+
+func (c *DiscogsClient) search(ctx context.Context, params url.Values, limit int) ([]discogsSearchResult, error) {
+	if limit <= 0 || limit > discogsMaxPerPage {
+		limit = discogsMaxPerPage
+	}
+	params.Set("per_page", strconv.Itoa(limit))
+
+	var result struct {
+		Results []discogsSearchResult `json:"results"`
+	}
+	if err := c.doRequest(ctx, "database/search", params, &result); err != nil {
+		return nil, err
+	}
+	return result.Results, nil
+}
+
+// SearchArtists searches for artists on Discogs
+func (c *DiscogsClient) SearchArtists(ctx context.Context, query string, limit int) ([]Artist, error) {
+	results, err := c.search(ctx, url.Values{"q": []string{query}, "type": []string{"artist"}}, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	artists := make([]Artist, 0, len(results))
+	for _, dr := range results {
+		id := strconv.Itoa(dr.ID)
+		artists = append(artists, Artist{
+			ExternalID:  id,
+			Name:        discogsName(dr.Title),
+			Provider:    ProviderDiscogs,
+			ImageURL:    dr.CoverImage,
+			ExternalURL: discogsWebURL + "artist/" + id,
+		})
+	}
+
+	return artists, nil
+}
+
+// SearchAlbums searches for releases on Discogs
+func (c *DiscogsClient) SearchAlbums(ctx context.Context, query string, limit int) ([]Album, error) {
+	results, err := c.search(ctx, url.Values{"q": []string{query}, "type": []string{"release"}}, limit)
+	if err != nil {
+		return nil, err
+	}
+	return c.convertSearchResults(results), nil
+}
+
+// SearchTracks returns no tracks; Discogs does not search tracks.
+func (c *DiscogsClient) SearchTracks(ctx context.Context, query string, limit int) ([]Track, error) {
+	return []Track{}, nil
+}
+
+// Search performs a combined search for artists and releases, which takes
+// two request slots
+func (c *DiscogsClient) Search(ctx context.Context, query string, limit int) (*SearchResults, error) {
+	artists, err := c.SearchArtists(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	albums, err := c.SearchAlbums(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchResults{Artists: artists, Albums: albums, Tracks: []Track{}}, nil
+}
+
+// GetArtist retrieves full artist details by Discogs ID
+func (c *DiscogsClient) GetArtist(ctx context.Context, artistID string) (*Artist, error) {
+	var da discogsArtist
+	if err := c.doRequest(ctx, "artists/"+url.PathEscape(artistID), nil, &da); err != nil {
+		return nil, err
+	}
+
+	id := strconv.Itoa(da.ID)
+	return &Artist{
+		ExternalID:  id,
+		Name:        discogsName(da.Name),
+		Provider:    ProviderDiscogs,
+		ImageURL:    discogsPrimaryImage(da.Images),
+		Biography:   da.Profile,
+		ExternalURL: discogsWebURL + "artist/" + id,
+	}, nil
+}
+
+// GetArtistAlbums retrieves the releases an artist is the main artist of,
+// earliest first. Masters are listed as their main release.
+func (c *DiscogsClient) GetArtistAlbums(ctx context.Context, artistID string) ([]Album, error) {
+	params := url.Values{
+		"sort":       []string{"year"},
+		"sort_order": []string{"asc"},
+		"per_page":   []string{strconv.Itoa(discogsMaxPerPage)},
+	}
+
+	var result struct {
+		Releases []discogsListing `json:"releases"`
+	}
+	if err := c.doRequest(ctx, "artists/"+url.PathEscape(artistID)+"/releases", params, &result); err != nil {
+		return nil, err
+	}
+
+	albums := make([]Album, 0, len(result.Releases))
+	for _, dl := range result.Releases {
+		if dl.Role != "Main" {
+			continue
+		}
+		if dl.Type == "master" {
+			if dl.MainRelease == 0 {
+				continue
+			}
+			dl.ID = dl.MainRelease
+		}
+		album := c.convertListing(dl)
+		album.ArtistID = artistID
+		albums = append(albums, album)
+	}
+
+	return albums, nil
+}
+
+// GetAlbum retrieves a release with its tracklist by Discogs release ID.
+// The album is dated by its master, the first time it came out; the
+// pressing keeps the release's own year.
+func (c *DiscogsClient) GetAlbum(ctx context.Context, albumID string) (*Album, []Track, error) {
+	var dr discogsRelease
+	if err := c.doRequest(ctx, "releases/"+url.PathEscape(albumID), nil, &dr); err != nil {
+		return nil, nil, err
+	}
+
+	album := c.convertRelease(dr)
+	if dr.MasterID != 0 {
+		// Without its master a release is still complete, so a failed
+		// lookup keeps the pressing year.
+		var master discogsRelease
+		if err := c.doRequest(ctx, "masters/"+strconv.Itoa(dr.MasterID), nil, &master); err == nil && master.Year > 0 {
+			album.ReleaseYear = master.Year
+		}
+	}
+
+	tracks := c.convertTracklist(dr.Tracklist, album)
+	album.TrackCount = len(tracks)
+	return &album, tracks, nil
+}
+
+// GetTrack is not supported; Discogs tracks have no IDs and are fetched
+// with their release
+func (c *DiscogsClient) GetTrack(ctx context.Context, trackID string) (*Track, error) {
+	return nil, fmt.Errorf("%w: discogs tracks are fetched with their release", ErrCapabilityUnsupported)
+}
+
+// LookupBarcode retrieves the releases printed with a barcode (UPC or EAN)
+func (c *DiscogsClient) LookupBarcode(ctx context.Context, barcode string) ([]Album, error) {
+	results, err := c.search(ctx, url.Values{"barcode": []string{barcode}, "type": []string{"release"}}, discogsMaxPerPage)
+	if err != nil {
+		return nil, err
+	}
+	return c.convertSearchResults(results), nil
+}
+
+// GetMaster retrieves a master release with its versions by Discogs master
+// ID, oldest version first
+func (c *DiscogsClient) GetMaster(ctx context.Context, masterID string) (*DiscogsMaster, error) {
+	var dm discogsRelease
+	if err := c.doRequest(ctx, "masters/"+url.PathEscape(masterID), nil, &dm); err != nil {
+		return nil, err
+	}
+
+	params := url.Values{
+		"sort":       []string{"released"},
+		"sort_order": []string{"asc"},
+		"per_page":   []string{strconv.Itoa(discogsMaxPerPage)},
+	}
+	var result struct {
+		Versions []discogsListing `json:"versions"`
+	}
+	if err := c.doRequest(ctx, "masters/"+url.PathEscape(masterID)+"/versions", params, &result); err != nil {
+		return nil, err
+	}
+
+	id := strconv.Itoa(dm.ID)
+	artist, artistID := discogsCredit(dm.Artists)
+	master := &DiscogsMaster{
+		ID:          id,
+		Title:       dm.Title,
+		Artist:      artist,
+		ArtistID:    artistID,
+		Year:        dm.Year,
+		Genres:      dm.Genres,
+		Styles:      dm.Styles,
+		CoverURL:    discogsPrimaryImage(dm.Images),
+		ExternalURL: discogsWebURL + "master/" + id,
+		Versions:    make([]Album, 0, len(result.Versions)),
+	}
+	if dm.MainRelease != 0 {
+		master.MainReleaseID = strconv.Itoa(dm.MainRelease)
+	}
+	for _, dl := range result.Versions {
+		album := c.convertListing(dl)
+		album.Artist, album.ArtistID = artist, artistID
+		master.Versions = append(master.Versions, album)
+	}
+
+	return master, nil
+}
+
+// GetLabel retrieves a record label with the first page of its releases by
+// Discogs label ID
+func (c *DiscogsClient) GetLabel(ctx context.Context, labelID string) (*DiscogsLabel, error) {
+	var dl discogsLabel
+	if err := c.doRequest(ctx, "labels/"+url.PathEscape(labelID), nil, &dl); err != nil {
+		return nil, err
+	}
+
+	params := url.Values{"per_page": []string{strconv.Itoa(discogsMaxPerPage)}}
+	var result struct {
+		Releases []discogsListing `json:"releases"`
+	}
+	if err := c.doRequest(ctx, "labels/"+url.PathEscape(labelID)+"/releases", params, &result); err != nil {
+		return nil, err
+	}
+
+	id := strconv.Itoa(dl.ID)
+	label := &DiscogsLabel{
+		ID:          id,
+		Name:        discogsName(dl.Name),
+		Profile:     dl.Profile,
+		ExternalURL: discogsWebURL + "label/" + id,
+		Releases:    make([]Album, 0, len(result.Releases)),
+	}
+	if dl.ParentLabel != nil {
+		label.ParentLabel = discogsName(dl.ParentLabel.Name)
+	}
+	for _, sub := range dl.Sublabels {
+		label.Sublabels = append(label.Sublabels, discogsName(sub.Name))
+	}
+	for _, entry := range result.Releases {
+		entry.Label = label.Name
+		label.Releases = append(label.Releases, c.convertListing(entry))
+	}
+
+	return label, nil
+}
+
+// Helper functions to convert Discogs types to common types
+
+func (c *DiscogsClient) convertSearchResults(results []discogsSearchResult) []Album {
+	albums := make([]Album, 0, len(results))
+	for _, dr := range results {
+		id := strconv.Itoa(dr.ID)
+		// Search titles read "Artist - Title".
+		artist, title, found := strings.Cut(dr.Title, " - ")
+		if !found {
+			artist, title = "", dr.Title
+		}
+		year, _ := strconv.Atoi(dr.Year)
+
+		pressing := &Pressing{
+			Format:  discogsFormatOf(dr.Format...),
+			Country: dr.Country,
+			Year:    year,
+			Barcode: discogsBarcode(dr.Barcode...),
+		}
+		if len(dr.Label) > 0 {
+			pressing.Label = discogsName(dr.Label[0])
+		}
+		pressing.CatalogNumber = discogsCatalogNumber(dr.Catno)
+		if len(dr.Format) > 1 {
+			pressing.Description = strings.Join(dr.Format[1:], ", ")
+		}
+
+		albums = append(albums, Album{
+			ExternalID:  id,
+			Title:       title,
+			Artist:      discogsName(artist),
+			Provider:    ProviderDiscogs,
+			ReleaseYear: year,
+			Genre:       strings.Join(append(append([]string{}, dr.Genre...), dr.Style...), ", "),
+			CoverURL:    dr.CoverImage,
+			UPC:         pressing.Barcode,
+			ExternalURL: discogsWebURL + "release/" + id,
+			Pressing:    pressing,
+		})
+	}
+	return albums
+}
+
+func (c *DiscogsClient) convertRelease(dr discogsRelease) Album {
+	id := strconv.Itoa(dr.ID)
+	artist, artistID := discogsCredit(dr.Artists)
+
+	pressing := &Pressing{
+		Format:  "other",
+		Country: dr.Country,
+		Year:    dr.Year,
+	}
+	if len(dr.Formats) > 0 {
+		format := dr.Formats[0]
+		pressing.Format = discogsFormatOf(format.Name)
+		pressing.ColorVariant = format.Text
+		description := strings.Join(format.Descriptions, ", ")
+		if qty, _ := strconv.Atoi(format.Qty); qty > 1 {
+			description = strings.TrimSuffix(fmt.Sprintf("%d × %s, %s", qty, format.Name, description), ", ")
+		}
+		pressing.Description = description
+	}
+	if len(dr.Labels) > 0 {
+		pressing.Label = discogsName(dr.Labels[0].Name)
+		pressing.CatalogNumber = discogsCatalogNumber(dr.Labels[0].Catno)
+	}
+	for _, identifier := range dr.Identifiers {
+		if identifier.Type == "Barcode" {
+			if barcode := discogsBarcode(identifier.Value); barcode != "" {
+				pressing.Barcode = barcode
+				break
+			}
+		}
+	}
+
+	return Album{
+		ExternalID:  id,
+		Title:       dr.Title,
+		Artist:      artist,
+		ArtistID:    artistID,
+		Provider:    ProviderDiscogs,
+		ReleaseYear: dr.Year,
+		ReleaseDate: dr.Released,
+		Genre:       strings.Join(append(append([]string{}, dr.Genres...), dr.Styles...), ", "),
+		CoverURL:    discogsPrimaryImage(dr.Images),
+		UPC:         pressing.Barcode,
+		ExternalURL: discogsWebURL + "release/" + id,
+		Pressing:    pressing,
+	}
+}
+
+// convertListing converts an entry of a release list. Masters' versions
+// name their formats, other lists only have the summary, and masters
+// listed for an artist have no pressing at all.
+func (c *DiscogsClient) convertListing(dl discogsListing) Album {
+	id := strconv.Itoa(dl.ID)
+	year := dl.Year
+	if year == 0 {
+		year, _ = strconv.Atoi(dl.Released[:min(4, len(dl.Released))])
+	}
+
+	album := Album{
+		ExternalID:  id,
+		Title:       dl.Title,
+		Artist:      discogsName(dl.Artist),
+		Provider:    ProviderDiscogs,
+		ReleaseYear: year,
+		CoverURL:    dl.Thumb,
+		ExternalURL: discogsWebURL + "release/" + id,
+	}
+
+	formats := dl.MajorFormats
+	if len(formats) == 0 && dl.Format != "" {
+		formats = strings.Split(dl.Format, ", ")
+	}
+	if len(formats) > 0 {
+		album.Pressing = &Pressing{
+			Format:        discogsFormatOf(formats...),
+			Label:         discogsName(dl.Label),
+			CatalogNumber: discogsCatalogNumber(dl.Catno),
+			Country:       dl.Country,
+			Year:          year,
+			Description:   dl.Format,
+		}
+	}
+	return album
+}
+
+// convertTracklist converts the tracks of a release, skipping headings.
+// Positions such as "A1" or "2-3" give the disc: sides A and B are the
+// first record, C and D the second, and so on.
+func (c *DiscogsClient) convertTracklist(tracklist []discogsTrack, album Album) []Track {
+	tracks := []Track{}
+	disc, number := 0, 0
+	for _, dt := range tracklist {
+		if dt.Type != "" && dt.Type != "track" {
+			continue
+		}
+		if d := discogsDisc(dt.Position); d != disc {
+			disc, number = d, 0
+		}
+		number++
+
+		artist, artistID := discogsCredit(dt.Artists)
+		if artist == "" {
+			artist, artistID = album.Artist, album.ArtistID
+		}
+		tracks = append(tracks, Track{
+			Title:       dt.Title,
+			Artist:      artist,
+			ArtistID:    artistID,
+			Album:       album.Title,
+			AlbumID:     album.ExternalID,
+			Provider:    ProviderDiscogs,
+			Duration:    discogsDuration(dt.Duration),
+			TrackNumber: number,
+			DiscNumber:  disc,
+		})
+	}
+	return tracks
+}
+
+// discogsFormatNames maps Discogs format names, as written on releases and
+// abbreviated in summaries, to pressing formats.
+var discogsFormatNames = map[string]string{
+	"Vinyl":      "vinyl",
+	"LP":         "vinyl",
+	`7"`:         "vinyl",
+	`10"`:        "vinyl",
+	`12"`:        "vinyl",
+	"Flexi-disc": "vinyl",
+	"Lathe Cut":  "vinyl",
+	"Acetate":    "vinyl",
+	"CD":         "cd",
+	"CDr":        "cd",
+	"SACD":       "cd",
+	"Cassette":   "cassette",
+	"Cass":       "cassette",
+	"File":       "digital",
+}
+
+// discogsFormatOf returns the pressing format of the first name that has
+// one, or "other". Summaries count multiple discs as in "2xLP".
+func discogsFormatOf(names ...string) string {
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if count, rest, found := strings.Cut(name, "x"); found {
+			if _, err := strconv.Atoi(count); err == nil {
+				name = rest
+			}
+		}
+		if format, ok := discogsFormatNames[name]; ok {
+			return format
+		}
+	}
+	return "other"
+}
+
+// discogsName strips the suffix Discogs adds to tell apart artists and
+// labels of the same name, as in "Nirvana (2)", and the asterisk marking
+// a name variation in search titles.
+func discogsName(name string) string {
+	name = strings.TrimSuffix(strings.TrimSpace(name), "*")
+	if i := strings.LastIndex(name, " ("); i > 0 && strings.HasSuffix(name, ")") {
+		if _, err := strconv.Atoi(name[i+2 : len(name)-1]); err == nil {
+			name = name[:i]
+		}
+	}
+	return name
+}
+
+// discogsCredit joins artist credits the way Discogs prints them, e.g.
+// "Simon & Garfunkel", preferring the name as credited, and returns the
+// first credited artist's ID.
+func discogsCredit(credits []discogsArtistCredit) (string, string) {
+	if len(credits) == 0 {
+		return "", ""
+	}
+	var name strings.Builder
+	for i, credit := range credits {
+		if credit.ANV != "" {
+			name.WriteString(credit.ANV)
+		} else {
+			name.WriteString(discogsName(credit.Name))
+		}
+		if i == len(credits)-1 {
+			break
+		}
+		switch join := strings.TrimSpace(credit.Join); join {
+		case "", ",":
+			name.WriteString(", ")
+		default:
+			name.WriteString(" " + join + " ")
+		}
+	}
+	return name.String(), strconv.Itoa(credits[0].ID)
+}
+
+// discogsCatalogNumber drops the "none" Discogs records for releases
+// without a catalog number.
+func discogsCatalogNumber(catno string) string {
+	if strings.EqualFold(strings.TrimSpace(catno), "none") {
+		return ""
+	}
+	return strings.TrimSpace(catno)
+}
+
+// discogsBarcode returns the first value that is a UPC or EAN once spaces
+// and dashes are removed. Discogs barcodes are transcribed from covers and
+// also hold label codes and matrix text.
+func discogsBarcode(values ...string) string {
+	for _, value := range values {
+		code := strings.NewReplacer(" ", "", "-", "").Replace(value)
+		if len(code) < 8 || len(code) > 14 {
+			continue
+		}
+		if strings.Trim(code, "0123456789") == "" {
+			return code
+		}
+	}
+	return ""
+}
+
+func discogsPrimaryImage(images []discogsImage) string {
+	for _, image := range images {
+		if image.Type == "primary" {
+			return image.URI
+		}
+	}
+	if len(images) > 0 {
+		return images[0].URI
+	}
+	return ""
+}
+
+// discogsDisc reads the disc number of a track position.
+func discogsDisc(position string) int {
+	position = strings.TrimSpace(position)
+	if prefix, _, found := strings.Cut(position, "-"); found {
+		if disc, err := strconv.Atoi(strings.TrimLeft(prefix, "ABCDEFGHIJKLMNOPQRSTUVWXYZ")); err == nil && disc > 0 {
+			return disc
+		}
+	}
+	if position != "" && position[0] >= 'A' && position[0] <= 'Z' {
+		return int(position[0]-'A')/2 + 1
+	}
+	return 1
+}
+
+// discogsDuration converts "4:20" or "1:02:03" to seconds.
+func discogsDuration(duration string) int {
+	seconds := 0
+	for _, part := range strings.Split(strings.TrimSpace(duration), ":") {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds
+}
@@ -0,0 +1,292 @@
+package musicapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// discogsStandIn serves the responses recorded in testdata/discogs and
+// records the requests it was sent.
+type discogsStandIn struct {
+	t *testing.T
+
+	mu       sync.Mutex
+	requests []*http.Request
+	// throttled answers the next requests with 429.
+	throttled int
+}
+
+func (s *discogsStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	throttled := s.throttled > 0
+	if throttled {
+		s.throttled--
+	}
+	s.mu.Unlock()
+
+	if got := r.Header.Get("Authorization"); got != "Discogs token=test-token" {
+		http.Error(w, `{"message": "You must authenticate to access this resource."}`, http.StatusUnauthorized)
+		return
+	}
+	if throttled {
+		http.Error(w, `{"message": "You are making requests too quickly."}`, http.StatusTooManyRequests)
+		return
+	}
+
+	// /releases/1 is served from release.json, /labels/1/releases from
+	// label_releases.json and searches from search_<type>.json.
+	entity, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	_, sub, _ := strings.Cut(rest, "/")
+	fixture := strings.TrimSuffix(entity, "s")
+	if entity == "database" {
+		fixture = "search_" + r.URL.Query().Get("type")
+	} else if sub != "" {
+		fixture += "_" + sub
+	}
+
+	data, err := os.ReadFile(filepath.Join("testdata", "discogs", fixture+".json"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func newDiscogsTestClient(t *testing.T) (*DiscogsClient, *discogsStandIn) {
+	t.Helper()
+	standIn := &discogsStandIn{t: t}
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	client, err := NewDiscogsClient("test-token", "Vinylhound/1.0")
+	if err != nil {
+		t.Fatalf("NewDiscogsClient: %v", err)
+	}
+	client.baseURL = server.URL + "/"
+	client.limiter.interval = 25 * time.Millisecond
+	return client, standIn
+}
+
+func TestNewDiscogsClientRequiresToken(t *testing.T) {
+	if _, err := NewDiscogsClient("", "Vinylhound/1.0"); err == nil {
+		t.Fatal("expected an error without token")
+	}
+}
+
+func TestDiscogsSearch(t *testing.T) {
+	client, standIn := newDiscogsTestClient(t)
+
+	start := time.Now()
+	results, err := client.Search(context.Background(), "abbey road", 250)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < client.limiter.interval {
+		t.Errorf("two requests took %v, want at least %v", elapsed, client.limiter.interval)
+	}
+
+	if len(standIn.requests) != 2 {
+		t.Fatalf("expected an artist and a release search, got %d requests", len(standIn.requests))
+	}
+	for _, r := range standIn.requests {
+		if got := r.Header.Get("User-Agent"); got != "Vinylhound/1.0" {
+			t.Errorf("unexpected User-Agent %q", got)
+		}
+		if got := r.URL.Query().Get("per_page"); got != "100" {
+			t.Errorf("expected the page capped at 100, got %q", got)
+		}
+	}
+
+	if len(results.Artists) != 2 || results.Artists[1].Name != "The Beatles" || results.Artists[0].ExternalURL != "https://www.discogs.com/artist/82730" {
+		t.Fatalf("unexpected artists %+v", results.Artists)
+	}
+	if results.Tracks == nil || len(results.Tracks) != 0 {
+		t.Errorf("expected no tracks, got %+v", results.Tracks)
+	}
+
+	if len(results.Albums) != 2 {
+		t.Fatalf("unexpected albums %+v", results.Albums)
+	}
+	original := results.Albums[0]
+	if original.Artist != "The Beatles" || original.Title != "Abbey Road" || original.ReleaseYear != 1969 ||
+		original.Genre != "Rock, Pop Rock" || original.UPC != "" {
+		t.Errorf("unexpected album %+v", original)
+	}
+	want := Pressing{Format: "vinyl", Label: "Apple Records", CatalogNumber: "PCS 7088", Country: "UK", Year: 1969, Description: "LP, Album, Stereo"}
+	if original.Pressing == nil || *original.Pressing != want {
+		t.Errorf("got pressing %+v, want %+v", original.Pressing, want)
+	}
+
+	reissue := results.Albums[1]
+	if reissue.Artist != "The Beatles" || reissue.Pressing.Format != "cd" || reissue.UPC != "602508007364" {
+		t.Errorf("expected the name variation stripped and the barcode read, got %+v", reissue)
+	}
+}
+
+func TestDiscogsGetArtistAndAlbums(t *testing.T) {
+	client, _ := newDiscogsTestClient(t)
+
+	artist, err := client.GetArtist(context.Background(), "82730")
+	if err != nil {
+		t.Fatalf("GetArtist: %v", err)
+	}
+	if artist.ImageURL != "https://i.discogs.com/beatles.jpg" || artist.Biography == "" || artist.Provider != ProviderDiscogs {
+		t.Errorf("unexpected artist %+v", artist)
+	}
+
+	albums, err := client.GetArtistAlbums(context.Background(), "82730")
+	if err != nil {
+		t.Fatalf("GetArtistAlbums: %v", err)
+	}
+	if len(albums) != 2 {
+		t.Fatalf("expected the main releases only, got %+v", albums)
+	}
+	if albums[0].ExternalID != "2434234" || albums[0].Pressing != nil || albums[0].ArtistID != "82730" {
+		t.Errorf("expected a master listed as its main release, got %+v", albums[0])
+	}
+	if albums[1].Pressing == nil || albums[1].Pressing.Format != "cd" || albums[1].Pressing.Label != "Apple Records" {
+		t.Errorf("unexpected release %+v", albums[1])
+	}
+}
+
+func TestDiscogsGetAlbum(t *testing.T) {
+	client, standIn := newDiscogsTestClient(t)
+
+	album, tracks, err := client.GetAlbum(context.Background(), "2434234")
+	if err != nil {
+		t.Fatalf("GetAlbum: %v", err)
+	}
+	if len(standIn.requests) != 2 || standIn.requests[1].URL.Path != "/masters/24047" {
+		t.Fatalf("expected the master to be fetched for the original year, got %d requests", len(standIn.requests))
+	}
+
+	if album.ReleaseYear != 1969 || album.ReleaseDate != "1975-05-00" || album.TrackCount != 4 ||
+		album.CoverURL != "https://i.discogs.com/abbey-road.jpg" || album.UPC != "5099969945717" {
+		t.Errorf("unexpected album %+v", album)
+	}
+	want := Pressing{
+		Format:        "vinyl",
+		Label:         "Apple Records",
+		CatalogNumber: "PCS 7088",
+		Country:       "UK",
+		Year:          1975,
+		Barcode:       "5099969945717",
+		ColorVariant:  "Green Apple",
+		Description:   "LP, Album, Reissue, Stereo",
+	}
+	if album.Pressing == nil || *album.Pressing != want {
+		t.Errorf("got pressing %+v, want %+v", album.Pressing, want)
+	}
+
+	if len(tracks) != 4 {
+		t.Fatalf("expected the heading skipped, got %+v", tracks)
+	}
+	if tracks[0].Title != "Come Together" || tracks[0].Duration != 260 || tracks[0].Artist != "The Beatles" || tracks[0].AlbumID != "2434234" {
+		t.Errorf("unexpected first track %+v", tracks[0])
+	}
+	if tracks[1].Artist != "George Harrison" {
+		t.Errorf("expected the track credit, got %+v", tracks[1])
+	}
+	if b1 := tracks[2]; b1.DiscNumber != 1 || b1.TrackNumber != 3 {
+		t.Errorf("expected side B on the first record, got %+v", b1)
+	}
+}
+
+func TestDiscogsMasterAndLabel(t *testing.T) {
+	client, _ := newDiscogsTestClient(t)
+	ctx := context.Background()
+
+	master, err := client.GetMaster(ctx, "24047")
+	if err != nil {
+		t.Fatalf("GetMaster: %v", err)
+	}
+	if master.Year != 1969 || master.MainReleaseID != "2434234" || len(master.Versions) != 2 {
+		t.Fatalf("unexpected master %+v", master)
+	}
+	if v := master.Versions[1]; v.Artist != "The Beatles" || v.ReleaseYear != 2019 || v.Pressing.Format != "cd" || v.Pressing.CatalogNumber != "" {
+		t.Errorf("unexpected version %+v", v)
+	}
+
+	label, err := client.GetLabel(ctx, "25483")
+	if err != nil {
+		t.Fatalf("GetLabel: %v", err)
+	}
+	if label.ParentLabel != "Apple Corps Ltd." || len(label.Sublabels) != 1 || len(label.Releases) != 2 {
+		t.Fatalf("unexpected label %+v", label)
+	}
+	if single := label.Releases[1]; single.Pressing.Label != "Apple Records" || single.Pressing.CatalogNumber != "R 5722" || single.Pressing.Format != "vinyl" {
+		t.Errorf("unexpected label release %+v", single.Pressing)
+	}
+}
+
+func TestDiscogsLookups(t *testing.T) {
+	client, standIn := newDiscogsTestClient(t)
+
+	albums, err := client.LookupBarcode(context.Background(), "602508007364")
+	if err != nil {
+		t.Fatalf("LookupBarcode: %v", err)
+	}
+	query := standIn.requests[0].URL.Query()
+	if query.Get("barcode") != "602508007364" || query.Get("type") != "release" || query.Get("q") != "" {
+		t.Errorf("unexpected barcode query %v", query)
+	}
+	if len(albums) != 2 {
+		t.Errorf("unexpected barcode albums %+v", albums)
+	}
+
+	if _, err := client.GetTrack(context.Background(), "A1"); !errors.Is(err, ErrCapabilityUnsupported) {
+		t.Errorf("expected ErrCapabilityUnsupported, got %v", err)
+	}
+}
+
+func TestDiscogsRetriesTooManyRequests(t *testing.T) {
+	client, standIn := newDiscogsTestClient(t)
+
+	standIn.throttled = 1
+	if _, err := client.GetArtist(context.Background(), "82730"); err != nil {
+		t.Fatalf("expected a 429 to be retried, got %v", err)
+	}
+
+	standIn.throttled = 2
+	if _, err := client.GetArtist(context.Background(), "82730"); err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("expected a second 429 to fail, got %v", err)
+	}
+	if len(standIn.requests) != 4 {
+		t.Errorf("expected 4 requests, got %d", len(standIn.requests))
+	}
+
+	client.token = "wrong"
+	if _, err := client.GetArtist(context.Background(), "82730"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected an unauthenticated request to fail, got %v", err)
+	}
+}
+
+func TestDiscogsHelpers(t *testing.T) {
+	for name, want := range map[string]string{"Nirvana (2)": "Nirvana", "Beatles*": "Beatles", "Sunn O)))": "Sunn O)))", "Plan (B)": "Plan (B)"} {
+		if got := discogsName(name); got != want {
+			t.Errorf("discogsName(%q) = %q, want %q", name, got, want)
+		}
+	}
+	for position, want := range map[string]int{"A1": 1, "B3": 1, "C1": 2, "2-4": 2, "CD2-1": 2, "7": 1} {
+		if got := discogsDisc(position); got != want {
+			t.Errorf("discogsDisc(%q) = %d, want %d", position, got, want)
+		}
+	}
+	credit, _ := discogsCredit([]discogsArtistCredit{{ID: 1, Name: "Simon", Join: "&"}, {ID: 2, Name: "Garfunkel (3)"}})
+	if credit != "Simon & Garfunkel" {
+		t.Errorf("unexpected credit %q", credit)
+	}
+	if got := discogsDuration("1:02:03"); got != 3723 {
+		t.Errorf("discogsDuration = %d", got)
+	}
+}
@@ -9,8 +9,10 @@ import (
 type MusicProvider string
 
 const (
-	ProviderSpotify    MusicProvider = "spotify"
-	ProviderAppleMusic MusicProvider = "apple_music"
+	ProviderSpotify     MusicProvider = "spotify"
+	ProviderAppleMusic  MusicProvider = "apple_music"
+	ProviderMusicBrainz MusicProvider = "musicbrainz"
+	ProviderDiscogs     MusicProvider = "discogs"
 )
 
 // Artist represents an artist from an external music service
@@ -37,7 +39,22 @@ type Album struct {
 	Genre        string        `json:"genre,omitempty"`
 	CoverURL     string        `json:"cover_url,omitempty"`
 	TrackCount   int           `json:"track_count,omitempty"`
+	UPC          string        `json:"upc,omitempty"`
 	ExternalURL  string        `json:"external_url,omitempty"`
+	Pressing     *Pressing     `json:"pressing,omitempty"`
+}
+
+// Pressing describes the physical edition an album was listed as, for
+// providers that catalog pressings rather than streams
+type Pressing struct {
+	Format        string `json:"format"` // vinyl, cd, cassette, digital or other
+	Label         string `json:"label,omitempty"`
+	CatalogNumber string `json:"catalog_number,omitempty"`
+	Country       string `json:"country,omitempty"`
+	Year          int    `json:"year,omitempty"`
+	Barcode       string `json:"barcode,omitempty"`
+	ColorVariant  string `json:"color_variant,omitempty"`
+	Description   string `json:"description,omitempty"`
 }
 
 // Track represents a track/song from an external music service
@@ -0,0 +1,558 @@
+package musicapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	musicBrainzBaseURL = "https://musicbrainz.org/ws/2/"
+	musicBrainzWebURL  = "https://musicbrainz.org/"
+	coverArtArchiveURL = "https://coverartarchive.org/release/"
+
+	// musicBrainzInterval keeps requests within the web service's limit of
+	// one per second and client IP.
+	musicBrainzInterval = time.Second
+	// musicBrainzMaxWait is how long a request queues for a slot before it
+	// fails with ErrRateLimited.
+	musicBrainzMaxWait = 10 * time.Second
+	// musicBrainzMaxLimit is the largest page the web service returns.
+	musicBrainzMaxLimit = 100
+)
+
+// MusicBrainzClient implements the MusicAPIClient interface for the
+// MusicBrainz web service. Albums are releases, tracks are recordings.
+// Requests are spaced a second apart across goroutines and identify the
+// application by User-Agent as the service requires.
+type MusicBrainzClient struct {
+	userAgent  string
+	baseURL    string
+	httpClient *http.Client
+	limiter    rateLimiter
+}
+
+// NewMusicBrainzClient creates a MusicBrainz client. The service blocks
+// anonymous clients, so the application name, version and a contact URL or
+// e-mail address are required; they are sent as
+// "Vinylhound/1.0 ( admin@example.com )".
+func NewMusicBrainzClient(app, version, contact string) (*MusicBrainzClient, error) {
+	if app == "" || version == "" || contact == "" {
+		return nil, errors.New("musicbrainz client: application name, version and contact are required")
+	}
+	return &MusicBrainzClient{
+		userAgent: fmt.Sprintf("%s/%s ( %s )", app, version, contact),
+		baseURL:   musicBrainzBaseURL,
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		limiter: rateLimiter{interval: musicBrainzInterval, maxWait: musicBrainzMaxWait},
+	}, nil
+}
+
+// MusicBrainz API response structures
+type musicBrainzArtistCredit struct {
+	Name       string `json:"name"`
+	JoinPhrase string `json:"joinphrase"`
+	Artist     struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"artist"`
+}
+
+// musicBrainzTag is a genre with its vote count.
+type musicBrainzTag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type musicBrainzArtist struct {
+	ID     string           `json:"id"`
+	Name   string           `json:"name"`
+	Genres []musicBrainzTag `json:"genres"`
+}
+
+type musicBrainzReleaseGroup struct {
+	ID               string                    `json:"id"`
+	Title            string                    `json:"title"`
+	PrimaryType      string                    `json:"primary-type"`
+	FirstReleaseDate string                    `json:"first-release-date"`
+	ArtistCredit     []musicBrainzArtistCredit `json:"artist-credit"`
+	Genres           []musicBrainzTag          `json:"genres"`
+	Releases         []musicBrainzRelease      `json:"releases"`
+}
+
+type musicBrainzRelease struct {
+	ID              string                    `json:"id"`
+	Title           string                    `json:"title"`
+	Status          string                    `json:"status"`
+	Date            string                    `json:"date"`
+	Country         string                    `json:"country"`
+	Barcode         string                    `json:"barcode"`
+	TrackCount      int                       `json:"track-count"`
+	ArtistCredit    []musicBrainzArtistCredit `json:"artist-credit"`
+	ReleaseGroup    *musicBrainzReleaseGroup  `json:"release-group,omitempty"`
+	Genres          []musicBrainzTag          `json:"genres"`
+	Media           []musicBrainzMedium       `json:"media"`
+	CoverArtArchive struct {
+		Front bool `json:"front"`
+	} `json:"cover-art-archive"`
+}
+
+type musicBrainzMedium struct {
+	Position   int                `json:"position"`
+	Format     string             `json:"format"`
+	TrackCount int                `json:"track-count"`
+	Tracks     []musicBrainzTrack `json:"tracks"`
+}
+
+type musicBrainzTrack struct {
+	ID        string               `json:"id"`
+	Position  int                  `json:"position"`
+	Title     string               `json:"title"`
+	Length    int                  `json:"length"`
+	Recording musicBrainzRecording `json:"recording"`
+}
+
+type musicBrainzRecording struct {
+	ID           string                    `json:"id"`
+	Title        string                    `json:"title"`
+	Length       int                       `json:"length"`
+	ArtistCredit []musicBrainzArtistCredit `json:"artist-credit"`
+	ISRCs        []string                  `json:"isrcs"`
+	Releases     []musicBrainzRelease      `json:"releases"`
+}
+
+// MusicBrainzReleaseGroup is a MusicBrainz release group: one album with
+// all its releases, such as the original pressing, reissues and
+// remasters.
+type MusicBrainzReleaseGroup struct {
+	ID               string   `json:"id"`
+	Title            string   `json:"title"`
+	Artist           string   `json:"artist"`
+	ArtistID         string   `json:"artist_id,omitempty"`
+	PrimaryType      string   `json:"primary_type,omitempty"`
+	FirstReleaseDate string   `json:"first_release_date,omitempty"`
+	Genres           []string `json:"genres,omitempty"`
+	ExternalURL      string   `json:"external_url"`
+	Releases         []Album  `json:"releases"`
+}
+
+// doRequest performs a rate limited request to the MusicBrainz web service.
+// A 503, which the service answers when a client is too fast, is retried
+// once after the next slot.
+func (c *MusicBrainzClient) doRequest(ctx context.Context, endpoint string, params url.Values, result interface{}) error {
+	query := url.Values{}
+	for key, values := range params {
+		query[key] = values
+	}
+	query.Set("fmt", "json")
+	apiURL := c.baseURL + endpoint + "?" + query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx); err != nil {
+			return err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+		if err != nil {
+			return fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("User-Agent", c.userAgent)
+		req.Header.Set("Accept", "application/json")
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("send request: %w", err)
+		}
+
+		if resp.StatusCode == http.StatusServiceUnavailable && attempt == 0 {
+			resp.Body.Close()
+			continue
+		}
+		if resp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			return fmt.Errorf("musicbrainz api error: %s - %s", resp.Status, string(body))
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(result)
+		resp.Body.Close()
+		if err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+		return nil
+	}
+}
+
+func musicBrainzSearchParams(query string, limit int) url.Values {
+	if limit <= 0 || limit > musicBrainzMaxLimit {
+		limit = musicBrainzMaxLimit
+	}
+	return url.Values{
+		"query": []string{query},
+		"limit": []string{strconv.Itoa(limit)},
+	}
+}
+
+// SearchArtists searches for artists on MusicBrainz
+func (c *MusicBrainzClient) SearchArtists(ctx context.Context, query string, limit int) ([]Artist, error) {
+	var result struct {
+		Artists []musicBrainzArtist `json:"artists"`
+	}
+	if err := c.doRequest(ctx, "artist", musicBrainzSearchParams(query, limit), &result); err != nil {
+		return nil, err
+	}
+
+	artists := make([]Artist, 0, len(result.Artists))
+	for _, ma := range result.Artists {
+		artists = append(artists, c.convertArtist(ma))
+	}
+
+	return artists, nil
+}
+
+// SearchAlbums searches for releases on MusicBrainz
+func (c *MusicBrainzClient) SearchAlbums(ctx context.Context, query string, limit int) ([]Album, error) {
+	return c.searchReleases(ctx, query, limit)
+}
+
+// SearchTracks searches for recordings on MusicBrainz
+func (c *MusicBrainzClient) SearchTracks(ctx context.Context, query string, limit int) ([]Track, error) {
+	var result struct {
+		Recordings []musicBrainzRecording `json:"recordings"`
+	}
+	if err := c.doRequest(ctx, "recording", musicBrainzSearchParams(query, limit), &result); err != nil {
+		return nil, err
+	}
+
+	return c.convertRecordings(result.Recordings), nil
+}
+
+// Search performs a combined search across all types. MusicBrainz searches
+// one entity type per request, so this takes three request slots.
+func (c *MusicBrainzClient) Search(ctx context.Context, query string, limit int) (*SearchResults, error) {
+	artists, err := c.SearchArtists(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	albums, err := c.SearchAlbums(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	tracks, err := c.SearchTracks(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SearchResults{Artists: artists, Albums: albums, Tracks: tracks}, nil
+}
+
+// GetArtist retrieves full artist details by MBID
+func (c *MusicBrainzClient) GetArtist(ctx context.Context, artistID string) (*Artist, error) {
+	var ma musicBrainzArtist
+	params := url.Values{"inc": []string{"genres"}}
+	if err := c.doRequest(ctx, "artist/"+url.PathEscape(artistID), params, &ma); err != nil {
+		return nil, err
+	}
+
+	artist := c.convertArtist(ma)
+	return &artist, nil
+}
+
+// GetArtistAlbums retrieves the official albums and EPs of an artist, one
+// release per release group, earliest first as MusicBrainz lists them
+func (c *MusicBrainzClient) GetArtistAlbums(ctx context.Context, artistID string) ([]Album, error) {
+	params := url.Values{
+		"artist": []string{artistID},
+		"type":   []string{"album|ep"},
+		"status": []string{"official"},
+		"inc":    []string{"artist-credits+release-groups"},
+		"limit":  []string{strconv.Itoa(musicBrainzMaxLimit)},
+	}
+
+	var result struct {
+		Releases []musicBrainzRelease `json:"releases"`
+	}
+	if err := c.doRequest(ctx, "release", params, &result); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	albums := make([]Album, 0, len(result.Releases))
+	for _, mr := range result.Releases {
+		if mr.ReleaseGroup != nil {
+			if seen[mr.ReleaseGroup.ID] {
+				continue
+			}
+			seen[mr.ReleaseGroup.ID] = true
+		}
+		albums = append(albums, c.convertRelease(mr))
+	}
+
+	return albums, nil
+}
+
+// GetAlbum retrieves a release with its tracklist by MBID. Tracks are
+// identified by their recording.
+func (c *MusicBrainzClient) GetAlbum(ctx context.Context, albumID string) (*Album, []Track, error) {
+	params := url.Values{
+		"inc": []string{"artist-credits+recordings+isrcs+genres+release-groups"},
+	}
+
+	var mr musicBrainzRelease
+	if err := c.doRequest(ctx, "release/"+url.PathEscape(albumID), params, &mr); err != nil {
+		return nil, nil, err
+	}
+
+	album := c.convertRelease(mr)
+
+	tracks := []Track{}
+	for _, medium := range mr.Media {
+		for _, mt := range medium.Tracks {
+			track := c.convertRecording(mt.Recording)
+			if mt.Title != "" {
+				track.Title = mt.Title
+			}
+			if mt.Length > 0 {
+				track.Duration = mt.Length / 1000
+			}
+			if track.Artist == "" {
+				track.Artist, track.ArtistID = album.Artist, album.ArtistID
+			}
+			track.TrackNumber = mt.Position
+			track.DiscNumber = medium.Position
+			track.Album = album.Title
+			track.AlbumID = album.ExternalID
+			tracks = append(tracks, track)
+		}
+	}
+
+	return &album, tracks, nil
+}
+
+// GetTrack retrieves full recording details by MBID
+func (c *MusicBrainzClient) GetTrack(ctx context.Context, trackID string) (*Track, error) {
+	params := url.Values{
+		"inc": []string{"artist-credits+isrcs+releases"},
+	}
+
+	var mr musicBrainzRecording
+	if err := c.doRequest(ctx, "recording/"+url.PathEscape(trackID), params, &mr); err != nil {
+		return nil, err
+	}
+
+	track := c.convertRecording(mr)
+	return &track, nil
+}
+
+// GetReleaseGroup retrieves a release group with its releases by MBID
+func (c *MusicBrainzClient) GetReleaseGroup(ctx context.Context, releaseGroupID string) (*MusicBrainzReleaseGroup, error) {
+	params := url.Values{
+		"inc": []string{"artist-credits+releases+genres"},
+	}
+
+	var mg musicBrainzReleaseGroup
+	if err := c.doRequest(ctx, "release-group/"+url.PathEscape(releaseGroupID), params, &mg); err != nil {
+		return nil, err
+	}
+
+	artist, artistID := musicBrainzCredit(mg.ArtistCredit)
+	group := &MusicBrainzReleaseGroup{
+		ID:               mg.ID,
+		Title:            mg.Title,
+		Artist:           artist,
+		ArtistID:         artistID,
+		PrimaryType:      mg.PrimaryType,
+		FirstReleaseDate: mg.FirstReleaseDate,
+		Genres:           musicBrainzNames(mg.Genres),
+		ExternalURL:      musicBrainzWebURL + "release-group/" + mg.ID,
+		Releases:         make([]Album, 0, len(mg.Releases)),
+	}
+	for _, mr := range mg.Releases {
+		mr.ReleaseGroup = &mg
+		if len(mr.ArtistCredit) == 0 {
+			mr.ArtistCredit = mg.ArtistCredit
+		}
+		group.Releases = append(group.Releases, c.convertRelease(mr))
+	}
+
+	return group, nil
+}
+
+// LookupISRC retrieves the recordings registered under an ISRC
+func (c *MusicBrainzClient) LookupISRC(ctx context.Context, isrc string) ([]Track, error) {
+	params := url.Values{
+		"inc": []string{"artist-credits+releases"},
+	}
+
+	var result struct {
+		ISRC       string                 `json:"isrc"`
+		Recordings []musicBrainzRecording `json:"recordings"`
+	}
+	if err := c.doRequest(ctx, "isrc/"+url.PathEscape(isrc), params, &result); err != nil {
+		return nil, err
+	}
+
+	tracks := c.convertRecordings(result.Recordings)
+	for i := range tracks {
+		if tracks[i].ISRC == "" {
+			tracks[i].ISRC = result.ISRC
+		}
+	}
+	return tracks, nil
+}
+
+// LookupBarcode retrieves the releases printed with a barcode (UPC or EAN)
+func (c *MusicBrainzClient) LookupBarcode(ctx context.Context, barcode string) ([]Album, error) {
+	digits := DigitsOnly(barcode)
+	if digits == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidBarcode, barcode)
+	}
+	return c.searchReleases(ctx, "barcode:"+digits, musicBrainzMaxLimit)
+}
+
+func (c *MusicBrainzClient) searchReleases(ctx context.Context, query string, limit int) ([]Album, error) {
+	var result struct {
+		Releases []musicBrainzRelease `json:"releases"`
+	}
+	if err := c.doRequest(ctx, "release", musicBrainzSearchParams(query, limit), &result); err != nil {
+		return nil, err
+	}
+
+	albums := make([]Album, 0, len(result.Releases))
+	for _, mr := range result.Releases {
+		albums = append(albums, c.convertRelease(mr))
+	}
+
+	return albums, nil
+}
+
+// Helper functions to convert MusicBrainz types to common types
+
+func (c *MusicBrainzClient) convertArtist(ma musicBrainzArtist) Artist {
+	return Artist{
+		ExternalID:  ma.ID,
+		Name:        ma.Name,
+		Provider:    ProviderMusicBrainz,
+		Genres:      musicBrainzNames(ma.Genres),
+		ExternalURL: musicBrainzWebURL + "artist/" + ma.ID,
+	}
+}
+
+func (c *MusicBrainzClient) convertRelease(mr musicBrainzRelease) Album {
+	artist, artistID := musicBrainzCredit(mr.ArtistCredit)
+
+	genres := musicBrainzNames(mr.Genres)
+	date := mr.Date
+	if mr.ReleaseGroup != nil {
+		if len(genres) == 0 {
+			genres = musicBrainzNames(mr.ReleaseGroup.Genres)
+		}
+		if date == "" {
+			date = mr.ReleaseGroup.FirstReleaseDate
+		}
+	}
+
+	releaseYear := 0
+	if len(date) >= 4 {
+		fmt.Sscanf(date[:4], "%d", &releaseYear)
+	}
+
+	trackCount := mr.TrackCount
+	if trackCount == 0 {
+		for _, medium := range mr.Media {
+			trackCount += max(medium.TrackCount, len(medium.Tracks))
+		}
+	}
+
+	coverURL := ""
+	if mr.CoverArtArchive.Front {
+		coverURL = coverArtArchiveURL + mr.ID + "/front-500"
+	}
+
+	return Album{
+		ExternalID:  mr.ID,
+		Title:       mr.Title,
+		Artist:      artist,
+		ArtistID:    artistID,
+		Provider:    ProviderMusicBrainz,
+		ReleaseYear: releaseYear,
+		ReleaseDate: date,
+		Genre:       strings.Join(genres, ", "),
+		CoverURL:    coverURL,
+		TrackCount:  trackCount,
+		UPC:         mr.Barcode,
+		ExternalURL: musicBrainzWebURL + "release/" + mr.ID,
+	}
+}
+
+func (c *MusicBrainzClient) convertRecordings(recordings []musicBrainzRecording) []Track {
+	tracks := make([]Track, 0, len(recordings))
+	for _, mr := range recordings {
+		tracks = append(tracks, c.convertRecording(mr))
+	}
+	return tracks
+}
+
+func (c *MusicBrainzClient) convertRecording(mr musicBrainzRecording) Track {
+	artist, artistID := musicBrainzCredit(mr.ArtistCredit)
+
+	albumName := ""
+	albumID := ""
+	if len(mr.Releases) > 0 {
+		albumName = mr.Releases[0].Title
+		albumID = mr.Releases[0].ID
+	}
+
+	isrc := ""
+	if len(mr.ISRCs) > 0 {
+		isrc = mr.ISRCs[0]
+	}
+
+	return Track{
+		ExternalID:  mr.ID,
+		Title:       mr.Title,
+		Artist:      artist,
+		ArtistID:    artistID,
+		Album:       albumName,
+		AlbumID:     albumID,
+		Provider:    ProviderMusicBrainz,
+		Duration:    mr.Length / 1000,
+		ISRC:        isrc,
+		ExternalURL: musicBrainzWebURL + "recording/" + mr.ID,
+	}
+}
+
+// musicBrainzCredit joins an artist credit the way MusicBrainz prints it,
+// e.g. "Simon & Garfunkel", and returns the first credited artist's MBID.
+func musicBrainzCredit(credits []musicBrainzArtistCredit) (string, string) {
+	if len(credits) == 0 {
+		return "", ""
+	}
+	var name strings.Builder
+	for _, credit := range credits {
+		name.WriteString(credit.Name)
+		name.WriteString(credit.JoinPhrase)
+	}
+	return name.String(), credits[0].Artist.ID
+}
+
+// musicBrainzNames lists genre or tag names, most voted first.
+func musicBrainzNames(tags []musicBrainzTag) []string {
+	sorted := append([]musicBrainzTag(nil), tags...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Count > sorted[j].Count })
+	names := make([]string, 0, len(sorted))
+	for _, tag := range sorted {
+		names = append(names, tag.Name)
+	}
+	return names
+}
@@ -0,0 +1,266 @@
+package musicapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const beatlesMBID = "b10bbbfc-cf9e-42e0-be17-e2c3e1d2600d"
+
+// musicBrainzStandIn serves the responses recorded in testdata/musicbrainz
+// and records the requests it was sent.
+type musicBrainzStandIn struct {
+	t *testing.T
+
+	mu       sync.Mutex
+	requests []*http.Request
+	// unavailable answers the next requests with 503.
+	unavailable int
+}
+
+func (s *musicBrainzStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r)
+	unavailable := s.unavailable > 0
+	if unavailable {
+		s.unavailable--
+	}
+	s.mu.Unlock()
+
+	if unavailable {
+		http.Error(w, "Your requests are exceeding the allowable rate limit.", http.StatusServiceUnavailable)
+		return
+	}
+	if got := r.URL.Query().Get("fmt"); got != "json" {
+		s.t.Errorf("%s: expected fmt=json, got %q", r.URL.Path, got)
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/ws/2/")
+	entity, id, _ := strings.Cut(path, "/")
+	fixture := entity
+	switch {
+	case entity == "release" && id == "" && r.URL.Query().Get("artist") != "":
+		fixture = "artist_releases"
+	case id == "":
+		fixture = entity + "_search"
+	}
+
+	data, err := os.ReadFile(filepath.Join("testdata", "musicbrainz", strings.ReplaceAll(fixture, "-", "_")+".json"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+}
+
+func newMusicBrainzTestClient(t *testing.T) (*MusicBrainzClient, *musicBrainzStandIn) {
+	t.Helper()
+	standIn := &musicBrainzStandIn{t: t}
+	server := httptest.NewServer(standIn)
+	t.Cleanup(server.Close)
+
+	client, err := NewMusicBrainzClient("Vinylhound", "1.0", "admin@example.com")
+	if err != nil {
+		t.Fatalf("NewMusicBrainzClient: %v", err)
+	}
+	client.baseURL = server.URL + "/ws/2/"
+	client.limiter.interval = 25 * time.Millisecond
+	return client, standIn
+}
+
+func TestNewMusicBrainzClientRequiresContact(t *testing.T) {
+	if _, err := NewMusicBrainzClient("Vinylhound", "1.0", ""); err == nil {
+		t.Fatal("expected an error without contact")
+	}
+}
+
+func TestMusicBrainzSearchIsRateLimited(t *testing.T) {
+	client, standIn := newMusicBrainzTestClient(t)
+
+	start := time.Now()
+	results, err := client.Search(context.Background(), "abbey road", 250)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	// The first request goes out at once, the others wait for their slot.
+	if elapsed := time.Since(start); elapsed < 2*client.limiter.interval {
+		t.Errorf("three requests took %v, want at least %v", elapsed, 2*client.limiter.interval)
+	}
+
+	if len(standIn.requests) != 3 {
+		t.Fatalf("expected one request per entity type, got %d", len(standIn.requests))
+	}
+	for _, r := range standIn.requests {
+		if got := r.Header.Get("User-Agent"); got != "Vinylhound/1.0 ( admin@example.com )" {
+			t.Errorf("unexpected User-Agent %q", got)
+		}
+		if got := r.URL.Query().Get("limit"); got != "100" {
+			t.Errorf("expected the limit capped at 100, got %q", got)
+		}
+	}
+
+	if len(results.Artists) != 2 || results.Artists[0].ExternalID != beatlesMBID || results.Artists[0].Provider != ProviderMusicBrainz {
+		t.Fatalf("unexpected artists %+v", results.Artists)
+	}
+	if len(results.Artists[0].Genres) != 0 {
+		t.Errorf("expected folksonomy tags to be ignored, got %v", results.Artists[0].Genres)
+	}
+
+	if len(results.Albums) != 1 {
+		t.Fatalf("unexpected albums %+v", results.Albums)
+	}
+	album := results.Albums[0]
+	if album.Artist != "The Beatles" || album.ArtistID != beatlesMBID || album.UPC != "602508007364" ||
+		album.ReleaseYear != 2019 || album.TrackCount != 17 || album.CoverURL != "" {
+		t.Errorf("unexpected album %+v", album)
+	}
+
+	if len(results.Tracks) != 1 {
+		t.Fatalf("unexpected tracks %+v", results.Tracks)
+	}
+	track := results.Tracks[0]
+	if track.ISRC != "GBAYE0601690" || track.Duration != 182 || track.Album != "Abbey Road" ||
+		track.ExternalURL != "https://musicbrainz.org/recording/"+track.ExternalID {
+		t.Errorf("unexpected track %+v", track)
+	}
+}
+
+func TestMusicBrainzGetArtistAndAlbums(t *testing.T) {
+	client, standIn := newMusicBrainzTestClient(t)
+
+	artist, err := client.GetArtist(context.Background(), beatlesMBID)
+	if err != nil {
+		t.Fatalf("GetArtist: %v", err)
+	}
+	if artist.Name != "The Beatles" || strings.Join(artist.Genres, ",") != "rock,pop,psychedelic pop" {
+		t.Errorf("expected genres most voted first, got %+v", artist)
+	}
+
+	albums, err := client.GetArtistAlbums(context.Background(), beatlesMBID)
+	if err != nil {
+		t.Fatalf("GetArtistAlbums: %v", err)
+	}
+	if len(albums) != 2 || albums[0].ReleaseYear != 1969 || albums[1].Title != "Let It Be" {
+		t.Fatalf("expected one release per release group, got %+v", albums)
+	}
+	if albums[0].CoverURL != coverArtArchiveURL+albums[0].ExternalID+"/front-500" || albums[1].CoverURL != "" {
+		t.Errorf("expected Cover Art Archive fronts only where present, got %q and %q", albums[0].CoverURL, albums[1].CoverURL)
+	}
+
+	query := standIn.requests[1].URL.Query()
+	if query.Get("artist") != beatlesMBID || query.Get("status") != "official" || query.Get("type") != "album|ep" {
+		t.Errorf("unexpected browse query %v", query)
+	}
+}
+
+func TestMusicBrainzGetAlbum(t *testing.T) {
+	client, standIn := newMusicBrainzTestClient(t)
+
+	album, tracks, err := client.GetAlbum(context.Background(), "0b8d5e62-6b2a-4d4c-9c3e-5d6b8a3e6f10")
+	if err != nil {
+		t.Fatalf("GetAlbum: %v", err)
+	}
+	if got := standIn.requests[0].URL.Query().Get("inc"); !strings.Contains(got, "recordings") || !strings.Contains(got, "isrcs") {
+		t.Errorf("expected recordings and ISRCs included, got %q", got)
+	}
+
+	if album.Genre != "rock, pop rock" || album.ReleaseDate != "2019-09-27" || album.TrackCount != 3 ||
+		album.CoverURL != "https://coverartarchive.org/release/0b8d5e62-6b2a-4d4c-9c3e-5d6b8a3e6f10/front-500" {
+		t.Errorf("unexpected album %+v", album)
+	}
+
+	if len(tracks) != 3 {
+		t.Fatalf("expected 3 tracks, got %+v", tracks)
+	}
+	if tracks[1].Title != "Something" || tracks[1].Duration != 182 || tracks[1].TrackNumber != 2 || tracks[1].ISRC != "GBAYE0601690" {
+		t.Errorf("expected the recording length for a track without one, got %+v", tracks[1])
+	}
+	last := tracks[2]
+	if last.DiscNumber != 2 || last.TrackNumber != 1 || last.Artist != "The Beatles" || last.AlbumID != album.ExternalID {
+		t.Errorf("unexpected last track %+v", last)
+	}
+}
+
+func TestMusicBrainzLookups(t *testing.T) {
+	client, standIn := newMusicBrainzTestClient(t)
+	ctx := context.Background()
+
+	tracks, err := client.LookupISRC(ctx, "GBAYE0601690")
+	if err != nil {
+		t.Fatalf("LookupISRC: %v", err)
+	}
+	if len(tracks) != 1 || tracks[0].ISRC != "GBAYE0601690" || tracks[0].Artist != "The Beatles" {
+		t.Errorf("unexpected ISRC tracks %+v", tracks)
+	}
+
+	albums, err := client.LookupBarcode(ctx, "602508007364")
+	if err != nil {
+		t.Fatalf("LookupBarcode: %v", err)
+	}
+	if got := standIn.requests[1].URL.Query().Get("query"); got != "barcode:602508007364" {
+		t.Errorf("unexpected barcode query %q", got)
+	}
+	if len(albums) != 1 || albums[0].UPC != "602508007364" {
+		t.Errorf("unexpected barcode albums %+v", albums)
+	}
+	if _, err := client.LookupBarcode(ctx, "6025-0800-7364 OR artist:x"); err != nil {
+		t.Fatalf("LookupBarcode: %v", err)
+	}
+	if got := standIn.requests[2].URL.Query().Get("query"); got != "barcode:602508007364" {
+		t.Errorf("expected the barcode stripped to digits, got query %q", got)
+	}
+	if _, err := client.LookupBarcode(ctx, "artist:x"); !errors.Is(err, ErrInvalidBarcode) {
+		t.Errorf("expected ErrInvalidBarcode, got %v", err)
+	}
+
+	group, err := client.GetReleaseGroup(ctx, "9162580e-5df4-32de-80cc-f45a8d8a9b1d")
+	if err != nil {
+		t.Fatalf("GetReleaseGroup: %v", err)
+	}
+	if group.PrimaryType != "Album" || group.Genres[0] != "rock" || len(group.Releases) != 2 {
+		t.Fatalf("unexpected release group %+v", group)
+	}
+	if reissue := group.Releases[1]; reissue.Artist != "The Beatles" || reissue.ReleaseYear != 2019 || reissue.UPC != "602508007364" {
+		t.Errorf("expected releases credited like their group, got %+v", reissue)
+	}
+}
+
+func TestMusicBrainzRetriesServiceUnavailable(t *testing.T) {
+	client, standIn := newMusicBrainzTestClient(t)
+
+	standIn.unavailable = 1
+	if _, err := client.GetArtist(context.Background(), beatlesMBID); err != nil {
+		t.Fatalf("expected a 503 to be retried, got %v", err)
+	}
+
+	standIn.unavailable = 2
+	if _, err := client.GetArtist(context.Background(), beatlesMBID); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("expected a second 503 to fail, got %v", err)
+	}
+	if len(standIn.requests) != 4 {
+		t.Errorf("expected 4 requests, got %d", len(standIn.requests))
+	}
+}
+
+func TestMusicBrainzWaitHonorsContext(t *testing.T) {
+	client, _ := newMusicBrainzTestClient(t)
+	client.limiter.interval = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := client.limiter.wait(ctx); err != nil {
+		t.Fatalf("expected the first slot at once, got %v", err)
+	}
+	if err := client.limiter.wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected the deadline to end the wait, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package musicapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited signals a request that would have waited longer than its
+// client allows for a slot within the provider's rate limit.
+var ErrRateLimited = errors.New("provider rate limit queue full")
+
+// rateLimiter spaces requests an interval apart across goroutines. Slots
+// are handed out in call order. A caller that would wait more than maxWait,
+// or past its context's deadline, fails at once instead of queueing, which
+// bounds the queue under load.
+type rateLimiter struct {
+	interval time.Duration
+	maxWait  time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait blocks until the next request slot.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := l.next
+	if at.Before(now) {
+		at = now
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(at) {
+		l.mu.Unlock()
+		return context.DeadlineExceeded
+	}
+	if l.maxWait > 0 && at.Sub(now) > l.maxWait {
+		l.mu.Unlock()
+		return fmt.Errorf("%w: next slot in %v", ErrRateLimited, at.Sub(now).Round(time.Millisecond))
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		l.release(at)
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// release gives back the slot at of a cancelled caller if it is still the
+// last one handed out. Later callers already hold the slots after it, so a
+// slot in the middle of the queue passes unused.
+func (l *rateLimiter) release(at time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.next.Equal(at.Add(l.interval)) {
+		l.next = at
+	}
+}
@@ -0,0 +1,61 @@
+package musicapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterReleasesCancelledSlot(t *testing.T) {
+	limiter := &rateLimiter{interval: time.Hour}
+	if err := limiter.wait(context.Background()); err != nil {
+		t.Fatalf("expected the first slot at once, got %v", err)
+	}
+	queued := limiter.next
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- limiter.wait(ctx) }()
+	for {
+		limiter.mu.Lock()
+		reserved := limiter.next.After(queued)
+		limiter.mu.Unlock()
+		if reserved {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the cancellation, got %v", err)
+	}
+	if !limiter.next.Equal(queued) {
+		t.Errorf("expected the cancelled slot to be given back, next slot at %v instead of %v", limiter.next, queued)
+	}
+}
+
+func TestRateLimiterFailsFast(t *testing.T) {
+	limiter := &rateLimiter{interval: time.Hour, maxWait: time.Minute}
+	if err := limiter.wait(context.Background()); err != nil {
+		t.Fatalf("expected the first slot at once, got %v", err)
+	}
+	queued := limiter.next
+
+	start := time.Now()
+	if err := limiter.wait(context.Background()); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited beyond maxWait, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	limiter.maxWait = 0
+	if err := limiter.wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected a slot after the deadline to fail, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("expected both to fail without waiting, took %v", elapsed)
+	}
+	if !limiter.next.Equal(queued) {
+		t.Error("expected rejected callers not to reserve a slot")
+	}
+}
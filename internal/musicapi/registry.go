@@ -0,0 +1,260 @@
+package musicapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Capability flags what a registered provider can be asked for beyond
+// fetching artists, albums and tracks by ID.
+type Capability uint8
+
+const (
+	// CapSearch providers take part in catalog search.
+	CapSearch Capability = 1 << iota
+	// CapArtistAlbums providers list the albums of an artist.
+	CapArtistAlbums
+	// CapISRCLookup providers find tracks by ISRC.
+	CapISRCLookup
+	// CapBarcodeLookup providers find albums by UPC or EAN barcode.
+	CapBarcodeLookup
+)
+
+var capabilityNames = []struct {
+	capability Capability
+	name       string
+}{
+	{CapSearch, "search"},
+	{CapArtistAlbums, "artist_albums"},
+	{CapISRCLookup, "isrc_lookup"},
+	{CapBarcodeLookup, "barcode_lookup"},
+}
+
+// Has reports whether c includes every flag of other.
+func (c Capability) Has(other Capability) bool {
+	return c&other == other
+}
+
+// Names lists the flags set in c.
+func (c Capability) Names() []string {
+	names := []string{}
+	for _, entry := range capabilityNames {
+		if c.Has(entry.capability) {
+			names = append(names, entry.name)
+		}
+	}
+	return names
+}
+
+// MarshalJSON encodes c as the list of its flag names.
+func (c Capability) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Names())
+}
+
+// ArtistAlbumsClient is implemented by clients with CapArtistAlbums.
+type ArtistAlbumsClient interface {
+	// GetArtistAlbums retrieves the albums of an artist by artist ID
+	GetArtistAlbums(ctx context.Context, artistID string) ([]Album, error)
+}
+
+// ISRCLookupClient is implemented by clients with CapISRCLookup.
+type ISRCLookupClient interface {
+	// LookupISRC retrieves the tracks recorded under an ISRC
+	LookupISRC(ctx context.Context, isrc string) ([]Track, error)
+}
+
+// BarcodeLookupClient is implemented by clients with CapBarcodeLookup.
+type BarcodeLookupClient interface {
+	// LookupBarcode retrieves the albums released with a UPC or EAN
+	LookupBarcode(ctx context.Context, barcode string) ([]Album, error)
+}
+
+var (
+	// ErrProviderNotRegistered signals a provider that is unknown or not
+	// configured.
+	ErrProviderNotRegistered = errors.New("provider not registered")
+	// ErrCapabilityUnsupported signals a provider asked for something it
+	// was not registered for.
+	ErrCapabilityUnsupported = errors.New("provider capability unsupported")
+	// ErrInvalidBarcode indicates a barcode without digits.
+	ErrInvalidBarcode = errors.New("invalid barcode")
+)
+
+// DigitsOnly drops everything but ASCII digits, so barcodes written with
+// spaces or dashes compare equal and cannot add terms to a provider's search
+// query. Leading zeros are kept; callers comparing UPC-A and EAN-13 forms
+// trim them.
+func DigitsOnly(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+}
+
+// ProviderInfo describes a registered provider.
+type ProviderInfo struct {
+	ID           MusicProvider `json:"id"`
+	Name         string        `json:"name"`
+	Capabilities Capability    `json:"capabilities"`
+}
+
+// Provider is a client registered with its description.
+type Provider struct {
+	ProviderInfo
+	Client MusicAPIClient
+}
+
+// ProviderResults holds what one provider returned during a fan-out.
+type ProviderResults struct {
+	Provider ProviderInfo
+	Results  *SearchResults
+	Err      error
+}
+
+// Registry keeps the configured providers in registration order. Providers
+// are registered at startup; lookups are safe for concurrent use.
+type Registry struct {
+	mu        sync.RWMutex
+	providers []Provider
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a provider. The client must implement the interfaces its
+// capabilities call for, and a provider ID can be registered once.
+func (r *Registry) Register(info ProviderInfo, client MusicAPIClient) error {
+	if info.ID == "" || client == nil {
+		return fmt.Errorf("register provider %q: id and client are required", info.ID)
+	}
+	if info.Name == "" {
+		info.Name = string(info.ID)
+	}
+	if _, ok := client.(ArtistAlbumsClient); info.Capabilities.Has(CapArtistAlbums) && !ok {
+		return fmt.Errorf("register provider %s: %w: client cannot list artist albums", info.ID, ErrCapabilityUnsupported)
+	}
+	if _, ok := client.(ISRCLookupClient); info.Capabilities.Has(CapISRCLookup) && !ok {
+		return fmt.Errorf("register provider %s: %w: client cannot look up ISRCs", info.ID, ErrCapabilityUnsupported)
+	}
+	if _, ok := client.(BarcodeLookupClient); info.Capabilities.Has(CapBarcodeLookup) && !ok {
+		return fmt.Errorf("register provider %s: %w: client cannot look up barcodes", info.ID, ErrCapabilityUnsupported)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, p := range r.providers {
+		if p.ID == info.ID {
+			return fmt.Errorf("register provider %s: already registered", info.ID)
+		}
+	}
+	r.providers = append(r.providers, Provider{ProviderInfo: info, Client: client})
+	return nil
+}
+
+// Providers describes the registered providers in registration order.
+func (r *Registry) Providers() []ProviderInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	infos := make([]ProviderInfo, 0, len(r.providers))
+	for _, p := range r.providers {
+		infos = append(infos, p.ProviderInfo)
+	}
+	return infos
+}
+
+// WithCapability returns the providers registered with capability, in
+// registration order.
+func (r *Registry) WithCapability(capability Capability) []Provider {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var providers []Provider
+	for _, p := range r.providers {
+		if p.Capabilities.Has(capability) {
+			providers = append(providers, p)
+		}
+	}
+	return providers
+}
+
+// Get returns a registered provider.
+func (r *Registry) Get(id MusicProvider) (Provider, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.providers {
+		if p.ID == id {
+			return p, nil
+		}
+	}
+	return Provider{}, fmt.Errorf("%w: %s", ErrProviderNotRegistered, id)
+}
+
+// Client returns the client of a registered provider.
+func (r *Registry) Client(id MusicProvider) (MusicAPIClient, error) {
+	p, err := r.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return p.Client, nil
+}
+
+// ArtistAlbums returns the client of a provider registered with
+// CapArtistAlbums.
+func (r *Registry) ArtistAlbums(id MusicProvider) (ArtistAlbumsClient, error) {
+	p, err := r.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Capabilities.Has(CapArtistAlbums) {
+		return nil, fmt.Errorf("%w: %s cannot list artist albums", ErrCapabilityUnsupported, id)
+	}
+	return p.Client.(ArtistAlbumsClient), nil
+}
+
+// ISRCLookup returns the client of a provider registered with
+// CapISRCLookup.
+func (r *Registry) ISRCLookup(id MusicProvider) (ISRCLookupClient, error) {
+	p, err := r.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Capabilities.Has(CapISRCLookup) {
+		return nil, fmt.Errorf("%w: %s cannot look up ISRCs", ErrCapabilityUnsupported, id)
+	}
+	return p.Client.(ISRCLookupClient), nil
+}
+
+// FanOut calls fn concurrently for every provider with capability, or only
+// for provider when it is not empty, and returns what each returned in
+// registration order so callers merge deterministically.
+func (r *Registry) FanOut(ctx context.Context, capability Capability, provider MusicProvider, fn func(ctx context.Context, client MusicAPIClient) (*SearchResults, error)) []ProviderResults {
+	var providers []Provider
+	for _, p := range r.WithCapability(capability) {
+		if provider == "" || p.ID == provider {
+			providers = append(providers, p)
+		}
+	}
+
+	results := make([]ProviderResults, len(providers))
+	var wg sync.WaitGroup
+	for i, p := range providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, err := fn(ctx, p.Client)
+			if found == nil && err == nil {
+				found = &SearchResults{}
+			}
+			results[i] = ProviderResults{Provider: p.ProviderInfo, Results: found, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
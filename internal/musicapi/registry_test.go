@@ -0,0 +1,113 @@
+package musicapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+type fakeClient struct {
+	results *SearchResults
+	err     error
+}
+
+func (c *fakeClient) SearchArtists(context.Context, string, int) ([]Artist, error) { return nil, c.err }
+func (c *fakeClient) SearchAlbums(context.Context, string, int) ([]Album, error)   { return nil, c.err }
+func (c *fakeClient) SearchTracks(context.Context, string, int) ([]Track, error)   { return nil, c.err }
+func (c *fakeClient) Search(context.Context, string, int) (*SearchResults, error) {
+	return c.results, c.err
+}
+func (c *fakeClient) GetArtist(context.Context, string) (*Artist, error) { return nil, c.err }
+func (c *fakeClient) GetAlbum(context.Context, string) (*Album, []Track, error) {
+	return nil, nil, c.err
+}
+func (c *fakeClient) GetTrack(context.Context, string) (*Track, error) { return nil, c.err }
+
+type fakeISRCClient struct {
+	fakeClient
+}
+
+func (c *fakeISRCClient) LookupISRC(context.Context, string) ([]Track, error) { return nil, c.err }
+
+func TestRegistryRegister(t *testing.T) {
+	registry := NewRegistry()
+
+	if err := registry.Register(ProviderInfo{ID: "plain", Capabilities: CapSearch | CapISRCLookup}, &fakeClient{}); !errors.Is(err, ErrCapabilityUnsupported) {
+		t.Fatalf("expected ErrCapabilityUnsupported for a client without LookupISRC, got %v", err)
+	}
+	if err := registry.Register(ProviderInfo{ID: "isrc", Name: "ISRC", Capabilities: CapSearch | CapISRCLookup}, &fakeISRCClient{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := registry.Register(ProviderInfo{ID: "isrc"}, &fakeClient{}); err == nil {
+		t.Fatal("expected a provider to be registered once")
+	}
+	if err := registry.Register(ProviderInfo{ID: "plain"}, &fakeClient{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	providers := registry.Providers()
+	if len(providers) != 2 || providers[0].ID != "isrc" || providers[1].Name != "plain" {
+		t.Fatalf("unexpected providers %+v", providers)
+	}
+	encoded, err := json.Marshal(providers[0])
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if want := `{"id":"isrc","name":"ISRC","capabilities":["search","isrc_lookup"]}`; string(encoded) != want {
+		t.Errorf("got %s, want %s", encoded, want)
+	}
+
+	if _, err := registry.ISRCLookup("isrc"); err != nil {
+		t.Errorf("ISRCLookup: %v", err)
+	}
+	if _, err := registry.ISRCLookup("plain"); !errors.Is(err, ErrCapabilityUnsupported) {
+		t.Errorf("expected ErrCapabilityUnsupported, got %v", err)
+	}
+	if _, err := registry.ArtistAlbums("isrc"); !errors.Is(err, ErrCapabilityUnsupported) {
+		t.Errorf("expected ErrCapabilityUnsupported, got %v", err)
+	}
+	if _, err := registry.Client("deezer"); !errors.Is(err, ErrProviderNotRegistered) {
+		t.Errorf("expected ErrProviderNotRegistered, got %v", err)
+	}
+}
+
+func TestRegistryFanOut(t *testing.T) {
+	registry := NewRegistry()
+	failure := errors.New("unavailable")
+	for _, p := range []struct {
+		id     MusicProvider
+		caps   Capability
+		client MusicAPIClient
+	}{
+		{"first", CapSearch, &fakeClient{results: &SearchResults{Albums: []Album{{ExternalID: "a"}}}}},
+		{"lookup-only", CapISRCLookup, &fakeISRCClient{}},
+		{"failing", CapSearch, &fakeClient{err: failure}},
+		{"empty", CapSearch, &fakeClient{}},
+	} {
+		if err := registry.Register(ProviderInfo{ID: p.id, Capabilities: p.caps}, p.client); err != nil {
+			t.Fatalf("Register %s: %v", p.id, err)
+		}
+	}
+
+	search := func(ctx context.Context, client MusicAPIClient) (*SearchResults, error) {
+		return client.Search(ctx, "abbey road", 10)
+	}
+	results := registry.FanOut(context.Background(), CapSearch, "", search)
+	if len(results) != 3 {
+		t.Fatalf("expected the 3 search providers, got %+v", results)
+	}
+	if results[0].Provider.ID != "first" || len(results[0].Results.Albums) != 1 {
+		t.Errorf("unexpected first result %+v", results[0])
+	}
+	if results[1].Provider.ID != "failing" || !errors.Is(results[1].Err, failure) {
+		t.Errorf("expected the failing provider's error, got %+v", results[1])
+	}
+	if results[2].Provider.ID != "empty" || results[2].Results == nil {
+		t.Errorf("expected empty results for a provider returning none, got %+v", results[2])
+	}
+
+	if results := registry.FanOut(context.Background(), CapSearch, "empty", search); len(results) != 1 || results[0].Provider.ID != "empty" {
+		t.Errorf("expected only the named provider, got %+v", results)
+	}
+}
@@ -69,6 +69,7 @@ type spotifyAlbum struct {
 	ReleaseDate  string                `json:"release_date"`
 	TotalTracks  int                   `json:"total_tracks"`
 	Images       []spotifyImage        `json:"images"`
+	ExternalIDs  spotifyExternalIDs    `json:"external_ids"`
 	ExternalURLs spotifyExternalURLs   `json:"external_urls"`
 	Tracks       *spotifyTracksPage    `json:"tracks,omitempty"`
 }
@@ -81,7 +82,7 @@ type spotifyTrack struct {
 	Duration     int                   `json:"duration_ms"`
 	TrackNumber  int                   `json:"track_number"`
 	DiscNumber   int                   `json:"disc_number"`
-	ISRC         string                `json:"external_ids.isrc,omitempty"`
+	ExternalIDs  spotifyExternalIDs    `json:"external_ids"`
 	PreviewURL   string                `json:"preview_url,omitempty"`
 	ExternalURLs spotifyExternalURLs   `json:"external_urls"`
 }
@@ -102,6 +103,13 @@ type spotifyImage struct {
 	Width  int    `json:"width"`
 }
 
+// spotifyExternalIDs holds industry codes; search results for albums are
+// simplified objects without them.
+type spotifyExternalIDs struct {
+	ISRC string `json:"isrc"`
+	UPC  string `json:"upc"`
+}
+
 type spotifyExternalURLs struct {
 	Spotify string `json:"spotify"`
 }
@@ -347,6 +355,20 @@ func (c *SpotifyClient) GetArtistAlbums(ctx context.Context, artistID string) ([
 	return albums, nil
 }
 
+// LookupISRC retrieves the tracks recorded under an ISRC
+func (c *SpotifyClient) LookupISRC(ctx context.Context, isrc string) ([]Track, error) {
+	return c.SearchTracks(ctx, "isrc:"+isrc, 50)
+}
+
+// LookupBarcode retrieves the albums released with a UPC
+func (c *SpotifyClient) LookupBarcode(ctx context.Context, barcode string) ([]Album, error) {
+	digits := DigitsOnly(barcode)
+	if digits == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidBarcode, barcode)
+	}
+	return c.SearchAlbums(ctx, "upc:"+digits, 50)
+}
+
 // GetAlbum retrieves full album details including tracks by ID
 func (c *SpotifyClient) GetAlbum(ctx context.Context, albumID string) (*Album, []Track, error) {
 	var sa spotifyAlbum
@@ -429,6 +451,7 @@ func (c *SpotifyClient) convertAlbum(sa spotifyAlbum) Album {
 		ReleaseDate: sa.ReleaseDate,
 		CoverURL:    coverURL,
 		TrackCount:  sa.TotalTracks,
+		UPC:         sa.ExternalIDs.UPC,
 		ExternalURL: sa.ExternalURLs.Spotify,
 	}
 }
@@ -459,7 +482,7 @@ func (c *SpotifyClient) convertTrack(st spotifyTrack) Track {
 		Duration:    st.Duration / 1000, // Convert ms to seconds
 		TrackNumber: st.TrackNumber,
 		DiscNumber:  st.DiscNumber,
-		ISRC:        st.ISRC,
+		ISRC:        st.ExternalIDs.ISRC,
 		ExternalURL: st.ExternalURLs.Spotify,
 		PreviewURL:  st.PreviewURL,
 	}
@@ -0,0 +1,91 @@
+// Package notify delivers account messages such as password reset links.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// PasswordReset carries what a user needs to complete a password reset.
+type PasswordReset struct {
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Notifier delivers account messages to users. Production deployments plug in
+// an email or push implementation; the senders in this package are for local
+// development.
+type Notifier interface {
+	SendPasswordReset(ctx context.Context, msg PasswordReset) error
+}
+
+// LogSender writes messages to a logger.
+type LogSender struct {
+	logger *log.Logger
+}
+
+// NewLogSender returns a Notifier that logs messages. A nil logger uses the
+// standard logger.
+func NewLogSender(logger *log.Logger) *LogSender {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return &LogSender{logger: logger}
+}
+
+// SendPasswordReset logs the reset token for the user.
+func (s *LogSender) SendPasswordReset(ctx context.Context, msg PasswordReset) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	s.logger.Printf("password reset for %s (user %d): token %s expires %s",
+		msg.Username, msg.UserID, msg.Token, msg.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// FileSender appends messages as JSON lines to an outbox file.
+type FileSender struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileSender returns a Notifier that appends to the file at path.
+func NewFileSender(path string) *FileSender {
+	return &FileSender{path: path}
+}
+
+// SendPasswordReset appends the reset message to the outbox file.
+func (s *FileSender) SendPasswordReset(ctx context.Context, msg PasswordReset) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(struct {
+		Kind string `json:"kind"`
+		PasswordReset
+	}{Kind: "password_reset", PasswordReset: msg})
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open outbox: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write outbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileSenderAppendsJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	sender := NewFileSender(path)
+
+	for _, username := range []string{"alice", "bob"} {
+		msg := PasswordReset{Username: username, Token: "tok-" + username, ExpiresAt: time.Now().Add(time.Hour)}
+		if err := sender.SendPasswordReset(context.Background(), msg); err != nil {
+			t.Fatalf("SendPasswordReset: %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open outbox: %v", err)
+	}
+	defer f.Close()
+
+	var got []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var line struct {
+			Kind     string `json:"kind"`
+			Username string `json:"username"`
+			Token    string `json:"token"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("decode line: %v", err)
+		}
+		if line.Kind != "password_reset" || line.Token != "tok-"+line.Username {
+			t.Fatalf("unexpected line: %+v", line)
+		}
+		got = append(got, line.Username)
+	}
+
+	if len(got) != 2 || got[0] != "alice" || got[1] != "bob" {
+		t.Fatalf("expected messages for alice and bob, got %v", got)
+	}
+}
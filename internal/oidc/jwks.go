@@ -0,0 +1,92 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// publicKeys converts the RSA and EC signing keys of the set, skipping
+// encryption keys and key types that cannot verify ID tokens.
+func (s jsonWebKeySet) publicKeys() map[string]any {
+	keys := make(map[string]any, len(s.Keys))
+	for _, k := range s.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			if key, ok := k.rsaPublicKey(); ok {
+				keys[k.Kid] = key
+			}
+		case "EC":
+			if key, ok := k.ecdsaPublicKey(); ok {
+				keys[k.Kid] = key
+			}
+		}
+	}
+	return keys
+}
+
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, bool) {
+	n, ok := decodeBigInt(k.N)
+	if !ok {
+		return nil, false
+	}
+	e, ok := decodeBigInt(k.E)
+	if !ok || !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, false
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, true
+}
+
+func (k jsonWebKey) ecdsaPublicKey() (*ecdsa.PublicKey, bool) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, false
+	}
+	x, ok := decodeBigInt(k.X)
+	if !ok {
+		return nil, false
+	}
+	y, ok := decodeBigInt(k.Y)
+	if !ok {
+		return nil, false
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, false
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, true
+}
+
+func decodeBigInt(value string) (*big.Int, bool) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(raw) == 0 {
+		return nil, false
+	}
+	return new(big.Int).SetBytes(raw), true
+}
@@ -0,0 +1,389 @@
+// Package oidc implements the relying-party side of the OpenID Connect
+// authorization code flow with PKCE, for signing users in with external
+// identity providers.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultScopes are requested when a provider does not configure its own.
+var DefaultScopes = []string{"openid", "email", "profile"}
+
+var (
+	// ErrUnknownProvider signals a provider name that is not configured.
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	// ErrDiscovery indicates the provider metadata could not be loaded.
+	ErrDiscovery = errors.New("oidc discovery failed")
+	// ErrExchange indicates the provider rejected the authorization code.
+	ErrExchange = errors.New("oidc code exchange failed")
+	// ErrInvalidIDToken indicates an ID token that failed verification.
+	ErrInvalidIDToken = errors.New("invalid id token")
+)
+
+// Config describes one identity provider.
+type Config struct {
+	Name         string // short name used in URLs, e.g. "google"
+	Issuer       string // issuer URL; metadata is discovered below it
+	ClientID     string
+	ClientSecret string   // empty for public clients
+	RedirectURL  string   // callback registered with the provider
+	Scopes       []string // defaults to DefaultScopes
+}
+
+// Identity is what a verified ID token says about the user.
+type Identity struct {
+	Issuer            string
+	Subject           string
+	Email             string
+	EmailVerified     bool
+	Name              string
+	PreferredUsername string
+}
+
+// Request holds the per-login secrets generated before redirecting to the
+// provider. State and Nonce travel through the browser; CodeVerifier must
+// stay on the server until the code is exchanged. Binding is kept by the
+// browser that started the login, in a cookie, so that a callback carrying
+// the state is only accepted from that browser.
+type Request struct {
+	State        string
+	Nonce        string
+	CodeVerifier string
+	Binding      string
+}
+
+// NewRequest generates fresh state, nonce, PKCE verifier and binding values.
+func NewRequest() (Request, error) {
+	var req Request
+	for _, field := range []*string{&req.State, &req.Nonce, &req.CodeVerifier, &req.Binding} {
+		value, err := randomToken()
+		if err != nil {
+			return Request{}, err
+		}
+		*field = value
+	}
+	return req, nil
+}
+
+// CodeChallenge derives the S256 PKCE challenge for a verifier.
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+type metadata struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	CodeChallengeMethods  []string `json:"code_challenge_methods_supported"`
+}
+
+// Provider talks to a single identity provider. Metadata is discovered on
+// first use and signing keys are refetched when an unknown key ID appears,
+// so providers can be configured before they are reachable.
+type Provider struct {
+	cfg    Config
+	client *http.Client
+
+	mu   sync.Mutex
+	meta *metadata
+	keys map[string]any
+}
+
+// NewProvider creates a provider. A nil client uses a client with a 10 second timeout.
+func NewProvider(cfg Config, client *http.Client) *Provider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = DefaultScopes
+	}
+	return &Provider{cfg: cfg, client: client}
+}
+
+// Name returns the configured provider name.
+func (p *Provider) Name() string {
+	return p.cfg.Name
+}
+
+// Issuer returns the configured issuer URL.
+func (p *Provider) Issuer() string {
+	return p.cfg.Issuer
+}
+
+// AuthCodeURL returns the provider URL the user is sent to in order to sign in.
+func (p *Provider) AuthCodeURL(ctx context.Context, req Request) (string, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {req.State},
+		"nonce":                 {req.Nonce},
+		"code_challenge":        {CodeChallenge(req.CodeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	sep := "?"
+	if strings.Contains(meta.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return meta.AuthorizationEndpoint + sep + params.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the verified identity
+// from the ID token. The nonce and verifier must be the ones of the Request
+// that produced the code.
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (Identity, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	if p.cfg.ClientSecret == "" {
+		form.Set("client_id", p.cfg.ClientID)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, meta.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	httpReq.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	httpReq.Header.Set("Accept", "application/json")
+	if p.cfg.ClientSecret != "" {
+		httpReq.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrExchange, err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return Identity{}, fmt.Errorf("%w: decode token response: %v", ErrExchange, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Identity{}, fmt.Errorf("%w: %s %s", ErrExchange, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return Identity{}, fmt.Errorf("%w: no id_token in response", ErrExchange)
+	}
+
+	return p.verifyIDToken(ctx, body.IDToken, nonce)
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	AuthorizedParty   string `json:"azp"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+}
+
+func (p *Provider) verifyIDToken(ctx context.Context, raw, nonce string) (Identity, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(raw, &claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(p.cfg.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return Identity{}, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if claims.Subject == "" {
+		return Identity{}, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+	if claims.Nonce != nonce {
+		return Identity{}, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.cfg.ClientID {
+		return Identity{}, fmt.Errorf("%w: token was issued to %q", ErrInvalidIDToken, claims.AuthorizedParty)
+	}
+
+	return Identity{
+		Issuer:            claims.Issuer,
+		Subject:           claims.Subject,
+		Email:             claims.Email,
+		EmailVerified:     claims.EmailVerified,
+		Name:              claims.Name,
+		PreferredUsername: claims.PreferredUsername,
+	}, nil
+}
+
+// discover loads and caches the provider metadata. Failures are not cached.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.meta != nil {
+		return p.meta, nil
+	}
+
+	var meta metadata
+	wellKnown := strings.TrimSuffix(p.cfg.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, &meta); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDiscovery, err)
+	}
+	if meta.Issuer != p.cfg.Issuer {
+		return nil, fmt.Errorf("%w: metadata issuer %q does not match %q", ErrDiscovery, meta.Issuer, p.cfg.Issuer)
+	}
+	if meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: metadata is missing endpoints", ErrDiscovery)
+	}
+	if len(meta.CodeChallengeMethods) > 0 && !slices.Contains(meta.CodeChallengeMethods, "S256") {
+		return nil, fmt.Errorf("%w: provider does not support S256 PKCE", ErrDiscovery)
+	}
+
+	p.meta = &meta
+	return p.meta, nil
+}
+
+// key returns the signing key with the given ID, refetching the key set once
+// when it is not known yet.
+func (p *Provider) key(ctx context.Context, kid string) (any, error) {
+	meta, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := lookupKey(p.keys, kid); ok {
+		return key, nil
+	}
+
+	var set jsonWebKeySet
+	if err := p.getJSON(ctx, meta.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("fetch signing keys: %w", err)
+	}
+	p.keys = set.publicKeys()
+
+	if key, ok := lookupKey(p.keys, kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds a key by ID. Tokens without a key ID are accepted only when
+// the set holds a single key.
+func lookupKey(keys map[string]any, kid string) (any, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	key, ok := keys[kid]
+	return key, ok
+}
+
+func (p *Provider) getJSON(ctx context.Context, target string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: status %d", target, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
+
+// Registry holds the configured providers by name.
+type Registry struct {
+	providers map[string]*Provider
+	names     []string
+}
+
+// NewRegistry creates providers for the given configurations. Names must be
+// unique and every provider needs an issuer, client ID and redirect URL.
+func NewRegistry(configs []Config, client *http.Client) (*Registry, error) {
+	r := &Registry{providers: make(map[string]*Provider, len(configs))}
+	for _, cfg := range configs {
+		if cfg.Name == "" || cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+			return nil, fmt.Errorf("oidc provider %q needs a name, issuer, client ID and redirect URL", cfg.Name)
+		}
+		if _, exists := r.providers[cfg.Name]; exists {
+			return nil, fmt.Errorf("oidc provider %q configured twice", cfg.Name)
+		}
+		r.providers[cfg.Name] = NewProvider(cfg, client)
+		r.names = append(r.names, cfg.Name)
+	}
+	return r, nil
+}
+
+// Provider returns the named provider or ErrUnknownProvider.
+func (r *Registry) Provider(name string) (*Provider, error) {
+	if r != nil {
+		if p, ok := r.providers[name]; ok {
+			return p, nil
+		}
+	}
+	return nil, ErrUnknownProvider
+}
+
+// Names lists the configured providers in configuration order.
+func (r *Registry) Names() []string {
+	if r == nil {
+		return nil
+	}
+	return slices.Clone(r.names)
+}
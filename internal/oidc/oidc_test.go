@@ -0,0 +1,202 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"vinylhound/internal/oidc"
+	"vinylhound/internal/oidc/oidctest"
+)
+
+const redirectURL = "http://localhost:8080/api/v1/auth/oidc/test/callback"
+
+func newProvider(t *testing.T, server *oidctest.Server) *oidc.Provider {
+	t.Helper()
+	return oidc.NewProvider(oidc.Config{
+		Name:         "test",
+		Issuer:       server.Issuer(),
+		ClientID:     server.ClientID,
+		ClientSecret: server.ClientSecret,
+		RedirectURL:  redirectURL,
+	}, nil)
+}
+
+// signIn runs the browser leg of the flow and returns the code.
+func signIn(t *testing.T, server *oidctest.Server, provider *oidc.Provider, req oidc.Request) string {
+	t.Helper()
+	authURL, err := provider.AuthCodeURL(context.Background(), req)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	code, state, err := server.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if state != req.State {
+		t.Fatalf("expected state %q, got %q", req.State, state)
+	}
+	return code
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	for _, secret := range []string{"s3cret", ""} {
+		name := "confidential"
+		if secret == "" {
+			name = "public"
+		}
+		t.Run(name, func(t *testing.T) {
+			server := oidctest.NewServer("vinylhound", secret)
+			defer server.Close()
+			server.SetUser(oidctest.User{Subject: "abc123", Email: "ada@example.com", EmailVerified: true, PreferredUsername: "ada"})
+
+			provider := newProvider(t, server)
+			req, err := oidc.NewRequest()
+			if err != nil {
+				t.Fatalf("NewRequest: %v", err)
+			}
+
+			authURL, err := provider.AuthCodeURL(context.Background(), req)
+			if err != nil {
+				t.Fatalf("AuthCodeURL: %v", err)
+			}
+			parsed, _ := url.Parse(authURL)
+			if got := parsed.Query().Get("code_challenge"); got != oidc.CodeChallenge(req.CodeVerifier) {
+				t.Fatalf("expected S256 challenge in authorization URL, got %q", got)
+			}
+
+			code := signIn(t, server, provider, req)
+			identity, err := provider.Exchange(context.Background(), code, req.CodeVerifier, req.Nonce)
+			if err != nil {
+				t.Fatalf("Exchange: %v", err)
+			}
+			if identity.Issuer != server.Issuer() || identity.Subject != "abc123" || identity.Email != "ada@example.com" || identity.PreferredUsername != "ada" {
+				t.Fatalf("unexpected identity: %+v", identity)
+			}
+		})
+	}
+}
+
+func TestExchangeRejectsWrongVerifier(t *testing.T) {
+	server := oidctest.NewServer("vinylhound", "s3cret")
+	defer server.Close()
+
+	provider := newProvider(t, server)
+	req, _ := oidc.NewRequest()
+	code := signIn(t, server, provider, req)
+
+	other, _ := oidc.NewRequest()
+	if _, err := provider.Exchange(context.Background(), code, other.CodeVerifier, req.Nonce); !errors.Is(err, oidc.ErrExchange) {
+		t.Fatalf("expected ErrExchange, got %v", err)
+	}
+}
+
+func TestExchangeCodeIsSingleUse(t *testing.T) {
+	server := oidctest.NewServer("vinylhound", "s3cret")
+	defer server.Close()
+
+	provider := newProvider(t, server)
+	req, _ := oidc.NewRequest()
+	code := signIn(t, server, provider, req)
+
+	if _, err := provider.Exchange(context.Background(), code, req.CodeVerifier, req.Nonce); err != nil {
+		t.Fatalf("first Exchange: %v", err)
+	}
+	if _, err := provider.Exchange(context.Background(), code, req.CodeVerifier, req.Nonce); !errors.Is(err, oidc.ErrExchange) {
+		t.Fatalf("expected ErrExchange on reuse, got %v", err)
+	}
+}
+
+func TestExchangeRejectsInvalidIDTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(jwt.MapClaims)
+		nonce  string
+	}{
+		{name: "wrong audience", tamper: func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{name: "wrong issuer", tamper: func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{name: "expired", tamper: func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{name: "missing subject", tamper: func(c jwt.MapClaims) { delete(c, "sub") }},
+		{name: "foreign azp", tamper: func(c jwt.MapClaims) {
+			c["aud"] = []string{"vinylhound", "other"}
+			c["azp"] = "other"
+		}},
+		{name: "nonce mismatch", nonce: "replayed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := oidctest.NewServer("vinylhound", "s3cret")
+			defer server.Close()
+			server.TamperIDTokens(tt.tamper)
+
+			provider := newProvider(t, server)
+			req, _ := oidc.NewRequest()
+			code := signIn(t, server, provider, req)
+
+			nonce := req.Nonce
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+			if _, err := provider.Exchange(context.Background(), code, req.CodeVerifier, nonce); !errors.Is(err, oidc.ErrInvalidIDToken) {
+				t.Fatalf("expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestExchangeFollowsKeyRotation(t *testing.T) {
+	server := oidctest.NewServer("vinylhound", "s3cret")
+	defer server.Close()
+
+	provider := newProvider(t, server)
+	for _, keyID := range []string{"", "rotated"} {
+		if keyID != "" {
+			server.RotateKey(keyID)
+		}
+		req, _ := oidc.NewRequest()
+		code := signIn(t, server, provider, req)
+		if _, err := provider.Exchange(context.Background(), code, req.CodeVerifier, req.Nonce); err != nil {
+			t.Fatalf("Exchange with key %q: %v", keyID, err)
+		}
+	}
+}
+
+func TestDiscoveryRequiresMatchingIssuer(t *testing.T) {
+	server := oidctest.NewServer("vinylhound", "s3cret")
+	defer server.Close()
+
+	provider := oidc.NewProvider(oidc.Config{
+		Name:        "test",
+		Issuer:      server.Issuer() + "/",
+		ClientID:    "vinylhound",
+		RedirectURL: redirectURL,
+	}, nil)
+	req, _ := oidc.NewRequest()
+	if _, err := provider.AuthCodeURL(context.Background(), req); !errors.Is(err, oidc.ErrDiscovery) {
+		t.Fatalf("expected ErrDiscovery, got %v", err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	cfg := oidc.Config{Name: "google", Issuer: "https://accounts.google.com", ClientID: "id", RedirectURL: redirectURL}
+
+	registry, err := oidc.NewRegistry([]oidc.Config{cfg}, nil)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	if _, err := registry.Provider("google"); err != nil {
+		t.Fatalf("expected google provider, got %v", err)
+	}
+	if _, err := registry.Provider("github"); !errors.Is(err, oidc.ErrUnknownProvider) {
+		t.Fatalf("expected ErrUnknownProvider, got %v", err)
+	}
+
+	if _, err := oidc.NewRegistry([]oidc.Config{cfg, cfg}, nil); err == nil {
+		t.Fatal("expected duplicate provider names to be rejected")
+	}
+}
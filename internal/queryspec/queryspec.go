@@ -0,0 +1,163 @@
+// Package queryspec parses the sort, filter and projection parameters shared
+// by list endpoints:
+//
+//	?sort=-release_year,title&year>=1970&genre!=jazz&fields=title,artist
+//
+// A Spec only records what was asked for. Each list checks the field names
+// against its own whitelist and compiles them to SQL in internal/store.
+package queryspec
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrInvalid indicates a malformed query, or one naming a field or operator
+// the list does not support.
+var ErrInvalid = errors.New("invalid query")
+
+// Op is a filter comparison operator.
+type Op string
+
+// Supported filter operators.
+const (
+	Eq  Op = "="
+	Ne  Op = "!="
+	Lt  Op = "<"
+	Lte Op = "<="
+	Gt  Op = ">"
+	Gte Op = ">="
+)
+
+// operators in the order they are matched, longest first so that >= is not
+// read as > followed by a value starting with =.
+var operators = []Op{Gte, Lte, Ne, Eq, Gt, Lt}
+
+// Sort orders a list by one field.
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Filter compares a field with a value. The value is kept as text until the
+// list knows the type of the field.
+type Filter struct {
+	Field string
+	Op    Op
+	Value string
+}
+
+// Spec is the sort order, filters and projection of a list request. Filters
+// are combined with AND.
+type Spec struct {
+	Sort    []Sort
+	Filters []Filter
+	Fields  []string
+}
+
+// Parse reads a spec from a raw URL query. sort and fields hold comma
+// separated field names, a leading - sorting descending; every other term is
+// a filter. Terms of the form name=value whose name is in params are read by
+// the caller, such as cursor or a substring search, and are skipped; the same
+// name with another operator is still a filter.
+//
+// The raw query is parsed rather than url.Values because a term such as
+// year>=1970 would otherwise be split at its =.
+func Parse(rawQuery string, params ...string) (Spec, error) {
+	skip := make(map[string]bool, len(params))
+	for _, param := range params {
+		skip[param] = true
+	}
+
+	var spec Spec
+	seen := map[string]bool{}
+	for _, term := range strings.Split(rawQuery, "&") {
+		if term == "" {
+			continue
+		}
+		term, err := url.QueryUnescape(term)
+		if err != nil {
+			return Spec{}, fmt.Errorf("%w: malformed query", ErrInvalid)
+		}
+
+		name, op, value, ok := splitTerm(term)
+		if !ok {
+			if skip[term] {
+				continue
+			}
+			return Spec{}, fmt.Errorf("%w: cannot read %q", ErrInvalid, term)
+		}
+		if op == Eq && skip[name] {
+			continue
+		}
+
+		switch name {
+		case "sort", "fields":
+			if op != Eq {
+				return Spec{}, fmt.Errorf("%w: %s takes a list of fields", ErrInvalid, name)
+			}
+			if seen[name] {
+				return Spec{}, fmt.Errorf("%w: %s given more than once", ErrInvalid, name)
+			}
+			seen[name] = true
+			names, err := splitFields(name, value)
+			if err != nil {
+				return Spec{}, err
+			}
+			if name == "fields" {
+				spec.Fields = names
+				continue
+			}
+			for _, field := range names {
+				desc := strings.HasPrefix(field, "-")
+				spec.Sort = append(spec.Sort, Sort{Field: strings.TrimPrefix(field, "-"), Desc: desc})
+			}
+		default:
+			spec.Filters = append(spec.Filters, Filter{Field: name, Op: op, Value: value})
+		}
+	}
+	return spec, nil
+}
+
+// splitTerm splits a term at its operator. Names are lower case letters,
+// digits and underscores.
+func splitTerm(term string) (string, Op, string, bool) {
+	end := strings.IndexFunc(term, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_')
+	})
+	if end <= 0 {
+		return "", "", "", false
+	}
+	name, rest := term[:end], term[end:]
+	for _, op := range operators {
+		if strings.HasPrefix(rest, string(op)) {
+			return name, op, rest[len(op):], true
+		}
+	}
+	return "", "", "", false
+}
+
+// splitFields splits the comma separated value of sort or fields, rejecting
+// empty and repeated names.
+func splitFields(param, value string) ([]string, error) {
+	names := strings.Split(value, ",")
+	seen := make(map[string]bool, len(names))
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		field := strings.TrimPrefix(name, "-")
+		if field == "" {
+			return nil, fmt.Errorf("%w: empty field in %s", ErrInvalid, param)
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("%w: %s repeats %s", ErrInvalid, param, field)
+		}
+		if param == "fields" && field != name {
+			return nil, fmt.Errorf("%w: fields cannot be descending", ErrInvalid)
+		}
+		seen[field] = true
+		names[i] = name
+	}
+	return names, nil
+}
@@ -0,0 +1,60 @@
+package queryspec
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	spec, err := Parse("sort=-release_year,title&year>=1970&genre!=jazz&rating%3C=4&artist=Can&fields=title,artist&cursor=abc&upcoming", "cursor", "artist", "upcoming")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := Spec{
+		Sort: []Sort{{Field: "release_year", Desc: true}, {Field: "title"}},
+		Filters: []Filter{
+			{Field: "year", Op: Gte, Value: "1970"},
+			{Field: "genre", Op: Ne, Value: "jazz"},
+			{Field: "rating", Op: Lte, Value: "4"},
+		},
+		Fields: []string{"title", "artist"},
+	}
+	if !reflect.DeepEqual(spec, want) {
+		t.Fatalf("unexpected spec:\n got %#v\nwant %#v", spec, want)
+	}
+}
+
+func TestParseKeepsOperatorsOfSkippedNames(t *testing.T) {
+	spec, err := Parse("artist>=M&title=%3Ex", "artist")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	want := []Filter{
+		{Field: "artist", Op: Gte, Value: "M"},
+		{Field: "title", Op: Eq, Value: ">x"},
+	}
+	if !reflect.DeepEqual(spec.Filters, want) {
+		t.Fatalf("unexpected filters: %#v", spec.Filters)
+	}
+}
+
+func TestParseRejectsMalformedQueries(t *testing.T) {
+	for name, raw := range map[string]string{
+		"bare name":         "upcoming",
+		"no name":           "=1970",
+		"upper case":        "Year=1970",
+		"bad escape":        "year=%zz",
+		"empty sort field":  "sort=title,",
+		"repeated sort":     "sort=title&sort=-title",
+		"sort repeats":      "sort=title,-title",
+		"sort comparison":   "sort>=title",
+		"descending fields": "fields=-title",
+	} {
+		if _, err := Parse(raw); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid, got %v", name, err)
+		}
+	}
+}
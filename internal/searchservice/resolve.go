@@ -0,0 +1,448 @@
+package searchservice
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"vinylhound/internal/musicapi"
+	"vinylhound/internal/store"
+)
+
+// durationTolerance is how many seconds two providers' durations of the same
+// track may differ by.
+const durationTolerance = 3
+
+// ArtistResult is an artist found at one or more providers. The embedded
+// artist is the best-ranked copy with gaps filled from the others. LocalID
+// is the catalog artist it was saved as, or 0.
+type ArtistResult struct {
+	musicapi.Artist
+	LocalID int64               `json:"local_id,omitempty"`
+	Links   []store.ProviderRef `json:"links"`
+}
+
+// AlbumResult is an album found at one or more providers. LocalID is the
+// catalog album it was imported as, or 0.
+type AlbumResult struct {
+	musicapi.Album
+	LocalID int64               `json:"local_id,omitempty"`
+	Links   []store.ProviderRef `json:"links"`
+}
+
+// TrackResult is a track found at one or more providers. LocalID is the
+// song it was imported as, or 0.
+type TrackResult struct {
+	musicapi.Track
+	LocalID int64               `json:"local_id,omitempty"`
+	Links   []store.ProviderRef `json:"links"`
+}
+
+// candidate is one provider item with the signals entity resolution
+// compares.
+type candidate struct {
+	ref      store.ProviderRef
+	code     string // ISRC or UPC
+	matchID  int64  // recorded provider match, or 0
+	localID  int64  // local entity of the item or its recorded match, or 0
+	title    string // normalized title, or name for artists
+	artist   string // normalized artist
+	nameOnly bool   // only the name can be compared, as for artists
+	duration int
+	tracks   int
+	year     int
+}
+
+// resolveResults merges the per-provider results into one item per artist,
+// album and track.
+func (s *Service) resolveResults(ctx context.Context, found []*musicapi.SearchResults) *SearchResults {
+	var (
+		artists [][]musicapi.Artist
+		albums  [][]musicapi.Album
+		tracks  [][]musicapi.Track
+	)
+	for _, results := range found {
+		artists = append(artists, results.Artists)
+		albums = append(albums, results.Albums)
+		tracks = append(tracks, results.Tracks)
+	}
+
+	return &SearchResults{
+		Artists: resolveEntities(ctx, s, store.EntityArtist, interleave(artists), artistCandidate,
+			func(items []musicapi.Artist, links []store.ProviderRef, localID int64) ArtistResult {
+				return ArtistResult{Artist: mergeArtists(items), LocalID: localID, Links: links}
+			}),
+		Albums: resolveEntities(ctx, s, store.EntityAlbum, interleave(albums), albumCandidate,
+			func(items []musicapi.Album, links []store.ProviderRef, localID int64) AlbumResult {
+				return AlbumResult{Album: mergeAlbums(items), LocalID: localID, Links: links}
+			}),
+		Tracks: resolveEntities(ctx, s, store.EntityTrack, interleave(tracks), trackCandidate,
+			func(items []musicapi.Track, links []store.ProviderRef, localID int64) TrackResult {
+				return TrackResult{Track: mergeTracks(items), LocalID: localID, Links: links}
+			}),
+	}
+}
+
+// resolveEntities clusters the items of one entity type, builds a result
+// from each cluster with merge and records clusters that link provider IDs
+// not linked before. Clusters of candidates compared by name alone are only
+// recorded when they include an imported entity, since different artists
+// can share a name. Links list the cluster's own items first, then IDs
+// recorded for it earlier.
+func resolveEntities[T, R any](ctx context.Context, s *Service, entityType string, items []T,
+	candidateOf func(T) candidate, merge func([]T, []store.ProviderRef, int64) R) []R {
+	cands := make([]candidate, len(items))
+	refs := make([]store.ProviderRef, len(items))
+	for i, item := range items {
+		cands[i] = candidateOf(item)
+		refs[i] = cands[i].ref
+	}
+
+	recorded := make(map[store.ProviderRef]store.ProviderMatch)
+	for _, match := range s.providerMatches(ctx, entityType, refs) {
+		for _, ref := range match.Refs {
+			recorded[refKey(ref)] = match
+		}
+	}
+	imported := make(map[store.ProviderRef]int64)
+	for _, id := range s.externalIDs(ctx, entityType, refs) {
+		imported[store.ProviderRef{Provider: id.Provider, ExternalID: id.ExternalID}] = id.LocalID
+	}
+	for i := range cands {
+		key := refKey(cands[i].ref)
+		if match, ok := recorded[key]; ok {
+			cands[i].matchID, cands[i].localID = match.ID, match.LocalID
+		}
+		if localID, ok := imported[key]; ok {
+			cands[i].localID = localID
+		}
+	}
+
+	results := make([]R, 0, len(items))
+	for _, group := range cluster(cands) {
+		var (
+			members []T
+			links   []store.ProviderRef
+			localID int64
+		)
+		nameOnly := true
+		seen := make(map[store.ProviderRef]bool)
+		matchIDs := make(map[int64]bool)
+		for _, i := range group {
+			members = append(members, items[i])
+			if key := refKey(cands[i].ref); !seen[key] {
+				seen[key] = true
+				links = append(links, cands[i].ref)
+			}
+			matchIDs[cands[i].matchID] = true
+			nameOnly = nameOnly && cands[i].nameOnly
+			if localID == 0 {
+				localID = cands[i].localID
+			}
+		}
+		own := len(links)
+		for _, i := range group {
+			if cands[i].matchID == 0 {
+				continue
+			}
+			for _, ref := range recorded[refKey(cands[i].ref)].Refs {
+				if key := refKey(ref); !seen[key] {
+					seen[key] = true
+					links = append(links, ref)
+				}
+			}
+		}
+
+		// Nothing to record when every item is already in the same match.
+		if own > 1 && (len(matchIDs) > 1 || matchIDs[0]) && (!nameOnly || localID != 0) {
+			s.recordMatch(ctx, entityType, links[:own])
+		}
+		results = append(results, merge(members, links, localID))
+	}
+	return results
+}
+
+// cluster groups candidates that name the same entity and returns the
+// groups as candidate indexes, in order of first appearance. Candidates
+// sharing an ISRC or UPC, or a recorded match, are joined first. Groups are
+// then joined when their first candidates look alike and no provider
+// appears in both. Groups of different local entities are never joined.
+func cluster(cands []candidate) [][]int {
+	parent := make([]int, len(cands))
+	localID := make([]int64, len(cands))
+	providers := make([]map[string]bool, len(cands))
+	for i, c := range cands {
+		parent[i] = i
+		localID[i] = c.localID
+		providers[i] = map[string]bool{c.ref.Provider: true}
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+	// union keeps the lower index as the root, so a root is always the
+	// first candidate of its group.
+	union := func(a, b int) {
+		a, b = find(a), find(b)
+		if a == b || (localID[a] != 0 && localID[b] != 0 && localID[a] != localID[b]) {
+			return
+		}
+		if b < a {
+			a, b = b, a
+		}
+		parent[b] = a
+		if localID[a] == 0 {
+			localID[a] = localID[b]
+		}
+		for provider := range providers[b] {
+			providers[a][provider] = true
+		}
+	}
+
+	byCode := make(map[string]int)
+	byMatch := make(map[int64]int)
+	for i, c := range cands {
+		if c.code != "" {
+			if j, ok := byCode[c.code]; ok {
+				union(j, i)
+			} else {
+				byCode[c.code] = i
+			}
+		}
+		if c.matchID != 0 {
+			if j, ok := byMatch[c.matchID]; ok {
+				union(j, i)
+			} else {
+				byMatch[c.matchID] = i
+			}
+		}
+	}
+
+	for i := range cands {
+		if find(i) != i {
+			continue
+		}
+		for j := 0; j < i; j++ {
+			if find(j) != j || !alike(cands[j], cands[i]) || overlaps(providers[j], providers[i]) {
+				continue
+			}
+			union(j, i)
+			if find(i) == j {
+				break
+			}
+		}
+	}
+
+	var groups [][]int
+	index := make(map[int]int)
+	for i := range cands {
+		root := find(i)
+		g, ok := index[root]
+		if !ok {
+			g = len(groups)
+			index[root] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], i)
+	}
+	return groups
+}
+
+// alike reports whether two candidates without a shared code look like the
+// same entity: the same normalized title and artist, and durations, track
+// counts and release years that agree where both are known.
+func alike(a, b candidate) bool {
+	if a.title == "" || a.title != b.title || a.artist != b.artist {
+		return false
+	}
+	if a.duration > 0 && b.duration > 0 && abs(a.duration-b.duration) > durationTolerance {
+		return false
+	}
+	if a.tracks > 0 && b.tracks > 0 && a.tracks != b.tracks {
+		return false
+	}
+	if a.year > 0 && b.year > 0 && abs(a.year-b.year) > 1 {
+		return false
+	}
+	return true
+}
+
+func overlaps(a, b map[string]bool) bool {
+	for key := range a {
+		if b[key] {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func artistCandidate(artist musicapi.Artist) candidate {
+	return candidate{
+		ref:      store.ProviderRef{Provider: string(artist.Provider), ExternalID: artist.ExternalID, ExternalURL: artist.ExternalURL},
+		title:    store.NormalizeCatalogName(artist.Name),
+		nameOnly: true,
+	}
+}
+
+func albumCandidate(album musicapi.Album) candidate {
+	code := ""
+	if upc := strings.TrimLeft(musicapi.DigitsOnly(album.UPC), "0"); upc != "" {
+		code = "upc:" + upc
+	}
+	year := album.ReleaseYear
+	if year == 0 {
+		year = parseYear(album.ReleaseDate)
+	}
+	return candidate{
+		ref:    store.ProviderRef{Provider: string(album.Provider), ExternalID: album.ExternalID, ExternalURL: album.ExternalURL},
+		code:   code,
+		title:  store.NormalizeAlbumTitle(album.Title),
+		artist: store.NormalizeCatalogName(album.Artist),
+		tracks: album.TrackCount,
+		year:   year,
+	}
+}
+
+func trackCandidate(track musicapi.Track) candidate {
+	code := ""
+	if isrc := normalizeISRC(track.ISRC); isrc != "" {
+		code = "isrc:" + isrc
+	}
+	return candidate{
+		ref:      store.ProviderRef{Provider: string(track.Provider), ExternalID: track.ExternalID, ExternalURL: track.ExternalURL},
+		code:     code,
+		title:    store.NormalizeAlbumTitle(track.Title),
+		artist:   store.NormalizeCatalogName(track.Artist),
+		duration: track.Duration,
+	}
+}
+
+// normalizeISRC upper-cases an ISRC and drops the dashes it is often
+// printed with.
+func normalizeISRC(value string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(value), "-", ""))
+}
+
+// mergeArtists returns the first artist with empty fields filled from the
+// others.
+func mergeArtists(items []musicapi.Artist) musicapi.Artist {
+	merged := items[0]
+	for _, other := range items[1:] {
+		fill(&merged.ImageURL, other.ImageURL)
+		fill(&merged.Biography, other.Biography)
+		fill(&merged.Popularity, other.Popularity)
+		if len(merged.Genres) == 0 {
+			merged.Genres = other.Genres
+		}
+	}
+	return merged
+}
+
+// mergeAlbums returns the first album with empty fields filled from the
+// others.
+func mergeAlbums(items []musicapi.Album) musicapi.Album {
+	merged := items[0]
+	for _, other := range items[1:] {
+		fill(&merged.ReleaseYear, other.ReleaseYear)
+		fill(&merged.ReleaseDate, other.ReleaseDate)
+		fill(&merged.Genre, other.Genre)
+		fill(&merged.CoverURL, other.CoverURL)
+		fill(&merged.TrackCount, other.TrackCount)
+		fill(&merged.UPC, other.UPC)
+	}
+	return merged
+}
+
+// mergeTracks returns the first track with empty fields filled from the
+// others.
+func mergeTracks(items []musicapi.Track) musicapi.Track {
+	merged := items[0]
+	for _, other := range items[1:] {
+		fill(&merged.Album, other.Album)
+		fill(&merged.Duration, other.Duration)
+		fill(&merged.TrackNumber, other.TrackNumber)
+		fill(&merged.DiscNumber, other.DiscNumber)
+		fill(&merged.ISRC, other.ISRC)
+		fill(&merged.PreviewURL, other.PreviewURL)
+	}
+	return merged
+}
+
+func fill[T comparable](dst *T, value T) {
+	var zero T
+	if *dst == zero {
+		*dst = value
+	}
+}
+
+// interleave orders the items of several providers by rank, taking the first
+// of each provider, then the second of each, and so on, so the best matches
+// of every provider come first.
+func interleave[T any](lists [][]T) []T {
+	var items []T
+	for rank := 0; ; rank++ {
+		added := false
+		for _, list := range lists {
+			if rank < len(list) {
+				items = append(items, list[rank])
+				added = true
+			}
+		}
+		if !added {
+			return items
+		}
+	}
+}
+
+// refKey identifies a provider item regardless of its URL.
+func refKey(ref store.ProviderRef) store.ProviderRef {
+	return store.ProviderRef{Provider: ref.Provider, ExternalID: ref.ExternalID}
+}
+
+// providerMatches returns the recorded matches of refs. Without a store, or
+// when the lookup fails, results are resolved without them; externalIDs
+// works the same for the local entities of refs.
+func (s *Service) providerMatches(ctx context.Context, entityType string, refs []store.ProviderRef) []store.ProviderMatch {
+	if s.store == nil || len(refs) == 0 {
+		return nil
+	}
+	matches, err := s.store.ProviderMatches(ctx, entityType, refs)
+	if err != nil {
+		log.Printf("Failed to look up %s matches: %v", entityType, err)
+		return nil
+	}
+	return matches
+}
+
+func (s *Service) externalIDs(ctx context.Context, entityType string, refs []store.ProviderRef) []store.ExternalID {
+	if s.store == nil || len(refs) == 0 {
+		return nil
+	}
+	ids, err := s.store.ExternalIDs(ctx, entityType, refs)
+	if err != nil {
+		log.Printf("Failed to look up %s external ids: %v", entityType, err)
+		return nil
+	}
+	return ids
+}
+
+// recordMatch records that refs name the same entity; failures are logged,
+// since results are still correct for this search.
+func (s *Service) recordMatch(ctx context.Context, entityType string, refs []store.ProviderRef) {
+	if s.store == nil {
+		return
+	}
+	if _, err := s.store.RecordProviderMatch(ctx, entityType, refs); err != nil {
+		log.Printf("Failed to record %s match of %v: %v", entityType, refs, err)
+	}
+}
@@ -0,0 +1,139 @@
+package searchservice
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"vinylhound/internal/musicapi"
+	"vinylhound/internal/store"
+)
+
+func TestResolveResultsMergesProviders(t *testing.T) {
+	spotify := &musicapi.SearchResults{
+		Albums: []musicapi.Album{
+			{ExternalID: "sp-abbey", Provider: musicapi.ProviderSpotify, Title: "Abbey Road (Remastered)", Artist: "The Beatles", ReleaseYear: 1969, TrackCount: 17},
+			{ExternalID: "sp-help", Provider: musicapi.ProviderSpotify, Title: "Help!", Artist: "The Beatles", UPC: "00602547"},
+			{ExternalID: "sp-anniv", Provider: musicapi.ProviderSpotify, Title: "Abbey Road", Artist: "Beatles", TrackCount: 40},
+		},
+		Tracks: []musicapi.Track{
+			{ExternalID: "sp-t1", Provider: musicapi.ProviderSpotify, Title: "Something", Artist: "The Beatles", ISRC: "GBAYE0601690", Duration: 182},
+			{ExternalID: "sp-t2", Provider: musicapi.ProviderSpotify, Title: "Yesterday", Artist: "The Beatles", Duration: 125},
+		},
+	}
+	apple := &musicapi.SearchResults{
+		Albums: []musicapi.Album{
+			{ExternalID: "am-abbey", Provider: musicapi.ProviderAppleMusic, Title: "Abbey Road", Artist: "Beatles", ReleaseDate: "1969-09-26", TrackCount: 17, CoverURL: "https://example.com/abbey.jpg"},
+			{ExternalID: "am-other", Provider: musicapi.ProviderAppleMusic, Title: "Rubber Soul", Artist: "The Beatles"},
+			{ExternalID: "am-help", Provider: musicapi.ProviderAppleMusic, Title: "Help! (Deluxe)", Artist: "Various", UPC: "602547"},
+		},
+		Tracks: []musicapi.Track{
+			{ExternalID: "am-t1", Provider: musicapi.ProviderAppleMusic, Title: "Something (2019 Mix)", Artist: "Beatles", ISRC: "GB-AYE-06-01690"},
+			{ExternalID: "am-t2", Provider: musicapi.ProviderAppleMusic, Title: "Yesterday", Artist: "The Beatles", Duration: 140},
+		},
+	}
+
+	results := (&Service{}).resolveResults(context.Background(), []*musicapi.SearchResults{spotify, apple})
+
+	albums := make(map[string][]string)
+	for _, album := range results.Albums {
+		for _, link := range album.Links {
+			albums[album.ExternalID] = append(albums[album.ExternalID], link.ExternalID)
+		}
+	}
+	if len(results.Albums) != 4 {
+		t.Fatalf("expected 4 albums, got %d: %v", len(results.Albums), albums)
+	}
+	if got := albums["sp-abbey"]; len(got) != 2 || got[1] != "am-abbey" {
+		t.Errorf("expected Abbey Road merged by title, artist and track count, got %v", got)
+	}
+	if got := albums["sp-help"]; len(got) != 2 || got[1] != "am-help" {
+		t.Errorf("expected Help! merged by UPC, got %v", got)
+	}
+	if got := albums["sp-anniv"]; len(got) != 1 {
+		t.Errorf("expected the 40 track edition to stay apart, got %v", got)
+	}
+	if first := results.Albums[0]; first.CoverURL != "https://example.com/abbey.jpg" || first.ReleaseYear != 1969 {
+		t.Errorf("expected missing fields filled from Apple Music, got %+v", first.Album)
+	}
+
+	if len(results.Tracks) != 3 {
+		t.Fatalf("expected 3 tracks, got %+v", results.Tracks)
+	}
+	if first := results.Tracks[0]; len(first.Links) != 2 || first.Links[1].ExternalID != "am-t1" {
+		t.Errorf("expected Something merged by ISRC, got %+v", first.Links)
+	}
+	for _, track := range results.Tracks[1:] {
+		if len(track.Links) != 1 {
+			t.Errorf("expected Yesterday versions 15 seconds apart to stay apart, got %+v", track.Links)
+		}
+	}
+}
+
+func TestClusterKeepsDifferentLocalAlbumsApart(t *testing.T) {
+	cands := []candidate{
+		{title: "abbey road", artist: "beatles", localID: 1},
+		{title: "abbey road", artist: "beatles", localID: 2},
+		{title: "abbey road", artist: "beatles"},
+	}
+	cands[0].ref.Provider = "spotify"
+	cands[1].ref.Provider = "apple_music"
+	cands[2].ref.Provider = "apple_music"
+
+	// The unimported copy joins the first album it looks like.
+	groups := cluster(cands)
+	if len(groups) != 2 || len(groups[0]) != 2 || groups[0][1] != 2 || len(groups[1]) != 1 {
+		t.Fatalf("unexpected groups %v", groups)
+	}
+}
+
+func TestResolveResultsRecordsArtistsSharingOnlyANameWhenImported(t *testing.T) {
+	found := []*musicapi.SearchResults{
+		{Artists: []musicapi.Artist{{ExternalID: "sp-can", Provider: musicapi.ProviderSpotify, Name: "Can"}}},
+		{Artists: []musicapi.Artist{{ExternalID: "am-can", Provider: musicapi.ProviderAppleMusic, Name: "CAN"}}},
+	}
+	errRecorded := errors.New("match recorded")
+
+	resolve := func(t *testing.T, imported *sqlmock.Rows) (*SearchResults, sqlmock.Sqlmock) {
+		t.Helper()
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("sqlmock.New: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM provider_matches m`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "local_id", "provider", "external_id", "external_url"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM external_ids`)).
+			WillReturnRows(imported)
+		// Recording a match starts with a transaction.
+		mock.ExpectBegin().WillReturnError(errRecorded)
+
+		results := (&Service{store: store.New(db)}).resolveResults(context.Background(), found)
+		if len(results.Artists) != 1 || len(results.Artists[0].Links) != 2 {
+			t.Fatalf("expected the artists merged by name, got %+v", results.Artists)
+		}
+		return results, mock
+	}
+	columns := []string{"entity_type", "local_id", "provider", "external_id", "url"}
+
+	t.Run("unimported", func(t *testing.T) {
+		_, mock := resolve(t, sqlmock.NewRows(columns))
+		if err := mock.ExpectationsWereMet(); err == nil {
+			t.Fatal("expected artists sharing only a name not to be recorded")
+		}
+	})
+
+	t.Run("imported", func(t *testing.T) {
+		results, mock := resolve(t, sqlmock.NewRows(columns).AddRow(store.EntityArtist, int64(12), "spotify", "sp-can", ""))
+		if results.Artists[0].LocalID != 12 {
+			t.Errorf("expected local artist 12, got %d", results.Artists[0].LocalID)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Fatalf("expected the match with the imported artist to be recorded: %v", err)
+		}
+	})
+}
@@ -9,130 +9,156 @@ import (
 	"log"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"vinylhound/internal/musicapi"
 	"vinylhound/internal/store"
+	"vinylhound/shared/go/models"
 )
 
+var (
+	// ErrInvalidISRC indicates an empty ISRC.
+	ErrInvalidISRC = errors.New("invalid isrc")
+	// ErrInvalidBarcode indicates a barcode without digits.
+	ErrInvalidBarcode = musicapi.ErrInvalidBarcode
+)
+
+// CoverImporter copies provider cover art into local media storage.
+type CoverImporter interface {
+	ImportAlbumCover(ctx context.Context, albumID int64, url string) error
+}
+
 // Service provides unified search across multiple music providers and stores results
 type Service struct {
-	db               *sql.DB
-	spotifyClient    musicapi.MusicAPIClient
-	appleMusicClient musicapi.MusicAPIClient
-	store            *store.Store
+	db        *sql.DB
+	providers *musicapi.Registry
+	store     *store.Store
+	covers    CoverImporter
 }
 
-// NewService creates a new search service
-func NewService(db *sql.DB, spotifyClient, appleMusicClient musicapi.MusicAPIClient, st *store.Store) *Service {
+// NewService creates a new search service over the registered providers; a
+// nil registry has none. Imported albums get their cover art through covers;
+// with nil covers they are imported without one.
+func NewService(db *sql.DB, providers *musicapi.Registry, st *store.Store, covers CoverImporter) *Service {
+	if providers == nil {
+		providers = musicapi.NewRegistry()
+	}
 	return &Service{
-		db:               db,
-		spotifyClient:    spotifyClient,
-		appleMusicClient: appleMusicClient,
-		store:            st,
+		db:        db,
+		providers: providers,
+		store:     st,
+		covers:    covers,
 	}
 }
 
+// Providers describes the registered providers.
+func (s *Service) Providers() []musicapi.ProviderInfo {
+	return s.providers.Providers()
+}
+
 // SearchOptions defines search parameters
 type SearchOptions struct {
 	Query        string
 	Type         string // "artist", "album", "track", or "all"
-	Provider     string // "spotify", "apple_music", or "all"
+	Provider     string // a registered provider, or "all"
 	Limit        int
-	StoreResults bool // Whether to store results in database
+	StoreResults bool   // Whether to store results in database
+	Token        string // Session token; storing results requires a curator
 }
 
-// SearchResults contains aggregated results from all providers
+// SearchResults contains results from all providers, with the copies of an
+// artist, album or track found at several providers merged into one item
 type SearchResults struct {
-	Artists []musicapi.Artist `json:"artists"`
-	Albums  []musicapi.Album  `json:"albums"`
-	Tracks  []musicapi.Track  `json:"tracks"`
+	Artists []ArtistResult `json:"artists"`
+	Albums  []AlbumResult  `json:"albums"`
+	Tracks  []TrackResult  `json:"tracks"`
 }
 
-// Search performs a unified search across all configured providers
+// Search performs a unified search across all registered search providers
+// and merges results that name the same artist, album or track
 func (s *Service) Search(ctx context.Context, opts SearchOptions) (*SearchResults, error) {
 	if opts.Limit == 0 {
 		opts.Limit = 20
 	}
 
-	results := &SearchResults{
-		Artists: []musicapi.Artist{},
-		Albums:  []musicapi.Album{},
-		Tracks:  []musicapi.Track{},
+	provider := musicapi.MusicProvider(opts.Provider)
+	if opts.Provider == "all" {
+		provider = ""
 	}
+	found := s.collect(s.providers.FanOut(ctx, musicapi.CapSearch, provider, func(ctx context.Context, client musicapi.MusicAPIClient) (*musicapi.SearchResults, error) {
+		return s.searchProvider(ctx, client, opts)
+	}), "search")
 
-	// Use WaitGroup to search providers concurrently
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-	errChan := make(chan error, 2)
-
-	// Search Spotify
-	if opts.Provider == "" || opts.Provider == "all" || opts.Provider == "spotify" {
-		if s.spotifyClient != nil {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				spotifyResults, err := s.searchProvider(ctx, s.spotifyClient, opts)
-				if err != nil {
-					log.Printf("Spotify search error: %v", err)
-					errChan <- fmt.Errorf("spotify: %w", err)
-					return
-				}
-
-				mu.Lock()
-				results.Artists = append(results.Artists, spotifyResults.Artists...)
-				results.Albums = append(results.Albums, spotifyResults.Albums...)
-				results.Tracks = append(results.Tracks, spotifyResults.Tracks...)
-				mu.Unlock()
-			}()
-		}
-	}
+	results := s.resolveResults(ctx, found)
 
-	// Search Apple Music
-	if opts.Provider == "" || opts.Provider == "all" || opts.Provider == "apple_music" {
-		if s.appleMusicClient != nil {
-			wg.Add(1)
-			go func() {
-				defer wg.Done()
-				appleResults, err := s.searchProvider(ctx, s.appleMusicClient, opts)
-				if err != nil {
-					log.Printf("Apple Music search error: %v", err)
-					errChan <- fmt.Errorf("apple music: %w", err)
-					return
-				}
-
-				mu.Lock()
-				results.Artists = append(results.Artists, appleResults.Artists...)
-				results.Albums = append(results.Albums, appleResults.Albums...)
-				results.Tracks = append(results.Tracks, appleResults.Tracks...)
-				mu.Unlock()
-			}()
+	// Store results if requested by a curator; other callers still get results
+	if opts.StoreResults {
+		if err := s.requireCurator(ctx, opts.Token); err != nil {
+			log.Printf("Search: not storing results: %v", err)
+		} else if err := s.storeResults(ctx, results); err != nil {
+			log.Printf("Failed to store search results: %v", err)
 		}
 	}
 
-	wg.Wait()
-	close(errChan)
+	return results, nil
+}
 
-	// Collect any errors (but don't fail if one provider fails)
-	var errs []error
-	for err := range errChan {
-		errs = append(errs, err)
+// LookupISRC finds the tracks recorded under an ISRC at every provider
+// registered for ISRC lookups, merged like search results.
+func (s *Service) LookupISRC(ctx context.Context, isrc string) ([]TrackResult, error) {
+	isrc = normalizeISRC(isrc)
+	if isrc == "" {
+		return nil, ErrInvalidISRC
 	}
 
-	// Store results if requested
-	if opts.StoreResults {
-		if err := s.storeResults(ctx, results); err != nil {
-			log.Printf("Failed to store search results: %v", err)
+	found := s.collect(s.providers.FanOut(ctx, musicapi.CapISRCLookup, "", func(ctx context.Context, client musicapi.MusicAPIClient) (*musicapi.SearchResults, error) {
+		tracks, err := client.(musicapi.ISRCLookupClient).LookupISRC(ctx, isrc)
+		if err != nil {
+			return nil, err
 		}
+		return &musicapi.SearchResults{Tracks: tracks}, nil
+	}), "ISRC lookup")
+
+	return s.resolveResults(ctx, found).Tracks, nil
+}
+
+// LookupBarcode finds the albums released with a UPC or EAN at every
+// provider registered for barcode lookups, merged like search results.
+func (s *Service) LookupBarcode(ctx context.Context, barcode string) ([]AlbumResult, error) {
+	barcode = musicapi.DigitsOnly(barcode)
+	if barcode == "" {
+		return nil, ErrInvalidBarcode
 	}
 
-	return results, nil
+	found := s.collect(s.providers.FanOut(ctx, musicapi.CapBarcodeLookup, "", func(ctx context.Context, client musicapi.MusicAPIClient) (*musicapi.SearchResults, error) {
+		albums, err := client.(musicapi.BarcodeLookupClient).LookupBarcode(ctx, barcode)
+		if err != nil {
+			return nil, err
+		}
+		return &musicapi.SearchResults{Albums: albums}, nil
+	}), "barcode lookup")
+
+	return s.resolveResults(ctx, found).Albums, nil
+}
+
+// collect keeps the results of a fan-out in provider order; a failing
+// provider is logged and contributes nothing.
+func (s *Service) collect(results []musicapi.ProviderResults, action string) []*musicapi.SearchResults {
+	found := make([]*musicapi.SearchResults, 0, len(results))
+	for _, result := range results {
+		if result.Err != nil {
+			log.Printf("%s %s error: %v", result.Provider.Name, action, result.Err)
+			found = append(found, &musicapi.SearchResults{})
+			continue
+		}
+		found = append(found, result.Results)
+	}
+	return found
 }
 
 // searchProvider performs search on a specific provider
-func (s *Service) searchProvider(ctx context.Context, client musicapi.MusicAPIClient, opts SearchOptions) (*SearchResults, error) {
-	results := &SearchResults{
+func (s *Service) searchProvider(ctx context.Context, client musicapi.MusicAPIClient, opts SearchOptions) (*musicapi.SearchResults, error) {
+	results := &musicapi.SearchResults{
 		Artists: []musicapi.Artist{},
 		Albums:  []musicapi.Album{},
 		Tracks:  []musicapi.Track{},
@@ -177,21 +203,21 @@ func (s *Service) searchProvider(ctx context.Context, client musicapi.MusicAPICl
 func (s *Service) storeResults(ctx context.Context, results *SearchResults) error {
 	// Store artists
 	for _, artist := range results.Artists {
-		if err := s.storeArtist(ctx, artist); err != nil {
+		if err := s.storeArtist(ctx, artist.Artist); err != nil {
 			log.Printf("Failed to store artist %s: %v", artist.Name, err)
 		}
 	}
 
 	// Store albums
 	for _, album := range results.Albums {
-		if err := s.storeAlbum(ctx, album); err != nil {
+		if err := s.storeAlbum(ctx, album.Album); err != nil {
 			log.Printf("Failed to store album %s: %v", album.Title, err)
 		}
 	}
 
 	// Store tracks
 	for _, track := range results.Tracks {
-		if err := s.storeTrack(ctx, track); err != nil {
+		if err := s.storeTrack(ctx, track.Track); err != nil {
 			log.Printf("Failed to store track %s: %v", track.Title, err)
 		}
 	}
@@ -201,17 +227,19 @@ func (s *Service) storeResults(ctx context.Context, results *SearchResults) erro
 
 // storeArtist stores an artist in the database if it doesn't exist
 func (s *Service) storeArtist(ctx context.Context, artist musicapi.Artist) error {
+	artist.Genres = s.resolveGenres(ctx, artist.Genres)
+
 	// Check if artist already exists by external_id and provider
 	if artist.ExternalID != "" && artist.Provider != "" {
-		var exists bool
+		var artistID int64
 		err := s.db.QueryRowContext(ctx,
-			`SELECT EXISTS(SELECT 1 FROM artists WHERE external_id = $1 AND provider = $2)`,
-			artist.ExternalID, artist.Provider).Scan(&exists)
-		if err != nil {
+			`SELECT id FROM artists WHERE external_id = $1 AND provider = $2 ORDER BY id LIMIT 1`,
+			artist.ExternalID, artist.Provider).Scan(&artistID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("check artist exists: %w", err)
 		}
 
-		if exists {
+		if artistID != 0 {
 			// Update existing artist with latest data
 			genresJSON, err := json.Marshal(artist.Genres)
 			if err != nil {
@@ -240,6 +268,7 @@ func (s *Service) storeArtist(ctx context.Context, artist musicapi.Artist) error
 			if err != nil {
 				log.Printf("Failed to update artist %s: %v", artist.Name, err)
 			}
+			s.saveExternalID(ctx, store.EntityArtist, artistID, artist.Provider, artist.ExternalID, artist.ExternalURL)
 			return nil
 		}
 	}
@@ -250,7 +279,8 @@ func (s *Service) storeArtist(ctx context.Context, artist musicapi.Artist) error
 		return fmt.Errorf("marshal genres: %w", err)
 	}
 
-	_, err = s.db.ExecContext(ctx, `
+	var artistID int64
+	err = s.db.QueryRowContext(ctx, `
 		INSERT INTO artists (name, biography, image_url, external_id, provider, genres, popularity, external_url, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7, $8, $9, $9)
 		ON CONFLICT (name) DO UPDATE SET
@@ -261,7 +291,8 @@ func (s *Service) storeArtist(ctx context.Context, artist musicapi.Artist) error
 			genres = EXCLUDED.genres,
 			popularity = EXCLUDED.popularity,
 			external_url = EXCLUDED.external_url,
-			updated_at = EXCLUDED.updated_at`,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id`,
 		artist.Name,
 		artist.Biography,
 		artist.ImageURL,
@@ -271,11 +302,12 @@ func (s *Service) storeArtist(ctx context.Context, artist musicapi.Artist) error
 		artist.Popularity,
 		artist.ExternalURL,
 		time.Now().UTC(),
-	)
+	).Scan(&artistID)
 
 	if err != nil {
 		return fmt.Errorf("insert artist: %w", err)
 	}
+	s.saveExternalID(ctx, store.EntityArtist, artistID, artist.Provider, artist.ExternalID, artist.ExternalURL)
 
 	log.Printf("Stored artist: %s (from %s)", artist.Name, artist.Provider)
 	return nil
@@ -296,7 +328,7 @@ func (s *Service) storeTrack(ctx context.Context, track musicapi.Track) error {
 // ImportAlbum fetches album details from a provider. Without user context the
 // album is not persisted, but the fetch can be used to validate connectivity.
 func (s *Service) ImportAlbum(ctx context.Context, albumID string, provider musicapi.MusicProvider) error {
-	client, err := s.clientForProvider(provider)
+	client, err := s.providers.Client(provider)
 	if err != nil {
 		return err
 	}
@@ -312,8 +344,8 @@ func (s *Service) ImportAlbum(ctx context.Context, albumID string, provider musi
 	return nil
 }
 
-// ImportAlbumForUser fetches a full album and stores it (and its tracks) for the authenticated user.
-// Returns the database album ID and any error encountered.
+// ImportAlbumForUser fetches a full album and stores it (and its tracks) for the authenticated user,
+// who must be a curator. Returns the database album ID and any error encountered.
 func (s *Service) ImportAlbumForUser(ctx context.Context, token string, albumID string, provider musicapi.MusicProvider) (int64, error) {
 	token = strings.TrimSpace(token)
 	if token == "" {
@@ -324,12 +356,12 @@ func (s *Service) ImportAlbumForUser(ctx context.Context, token string, albumID
 		return 0, errors.New("store not configured")
 	}
 
-	userID, err := s.store.UserIDByToken(ctx, token)
+	userID, err := s.store.RequireRole(ctx, token, models.RoleCurator)
 	if err != nil {
 		return 0, err
 	}
 
-	client, err := s.clientForProvider(provider)
+	client, err := s.providers.Client(provider)
 	if err != nil {
 		return 0, err
 	}
@@ -341,11 +373,14 @@ func (s *Service) ImportAlbumForUser(ctx context.Context, token string, albumID
 
 	log.Printf("ImportAlbumForUser: fetched album=%s provider=%s tracks=%d user=%d", album.Title, provider, len(tracks), userID)
 
-	storedAlbumID, err := s.storeAlbumForUser(ctx, userID, *album, tracks)
+	// An album imported before, possibly from another provider's copy that
+	// search matched with this one, is updated instead of added again.
+	storedAlbumID, err := s.storeAlbumForUser(ctx, userID, s.importedAlbum(ctx, provider, albumID), *album)
 	if err != nil {
 		log.Printf("ImportAlbumForUser: failed storing album user=%d album=%s: %v", userID, album.Title, err)
 		return 0, err
 	}
+	s.saveExternalID(ctx, store.EntityAlbum, storedAlbumID, provider, albumID, album.ExternalURL)
 
 	for _, track := range tracks {
 		if err := s.storeTrackForUser(ctx, storedAlbumID, *album, track); err != nil {
@@ -353,6 +388,22 @@ func (s *Service) ImportAlbumForUser(ctx context.Context, token string, albumID
 		}
 	}
 
+	// Providers that catalog pressings, such as Discogs, import the edition
+	// as a release of the album as well.
+	if album.Pressing != nil {
+		if err := s.storePressing(ctx, token, storedAlbumID, provider, albumID, *album); err != nil {
+			log.Printf("Failed to store pressing %s of album id=%d: %v", albumID, storedAlbumID, err)
+		}
+	}
+
+	// A missing cover does not fail the import; the album keeps any cover it
+	// already has.
+	if s.covers != nil && album.CoverURL != "" {
+		if err := s.covers.ImportAlbumCover(ctx, storedAlbumID, album.CoverURL); err != nil {
+			log.Printf("Failed to import cover for album id=%d: %v", storedAlbumID, err)
+		}
+	}
+
 	log.Printf("Imported album: %s by %s with %d tracks for user %d (database ID: %d)", album.Title, album.Artist, len(tracks), userID, storedAlbumID)
 	return storedAlbumID, nil
 }
@@ -373,43 +424,26 @@ func nullIfZero(i int) sql.NullInt32 {
 	return sql.NullInt32{Int32: int32(i), Valid: true}
 }
 
-func (s *Service) clientForProvider(provider musicapi.MusicProvider) (musicapi.MusicAPIClient, error) {
-	switch provider {
-	case musicapi.ProviderSpotify:
-		if s.spotifyClient == nil {
-			return nil, fmt.Errorf("provider %s not configured", provider)
-		}
-		return s.spotifyClient, nil
-	case musicapi.ProviderAppleMusic:
-		if s.appleMusicClient == nil {
-			return nil, fmt.Errorf("provider %s not configured", provider)
-		}
-		return s.appleMusicClient, nil
-	default:
-		return nil, fmt.Errorf("unsupported provider: %s", provider)
-	}
-}
-
-func (s *Service) storeAlbumForUser(ctx context.Context, userID int64, album musicapi.Album, tracks []musicapi.Track) (int64, error) {
+// storeAlbumForUser updates matchedID, or the user's album of the same
+// artist and title, or inserts a new album.
+func (s *Service) storeAlbumForUser(ctx context.Context, userID, matchedID int64, album musicapi.Album) (int64, error) {
 	if userID <= 0 {
 		return 0, fmt.Errorf("invalid user id %d", userID)
 	}
 
-	var albumID int64
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id
-		FROM albums
-		WHERE user_id = $1 AND artist = $2 AND title = $3
-	`, userID, album.Artist, album.Title).Scan(&albumID)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return 0, fmt.Errorf("lookup album: %w", err)
+	albumID := matchedID
+	if albumID == 0 {
+		err := s.db.QueryRowContext(ctx, `
+			SELECT id
+			FROM albums
+			WHERE user_id = $1 AND artist = $2 AND title = $3
+		`, userID, album.Artist, album.Title).Scan(&albumID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return 0, fmt.Errorf("lookup album: %w", err)
+		}
 	}
 
-	trackTitlesJSON, err := json.Marshal(extractTrackTitles(tracks))
-	if err != nil {
-		return 0, fmt.Errorf("marshal track titles: %w", err)
-	}
-	genresJSON, err := json.Marshal(extractGenres(album))
+	genresJSON, err := json.Marshal(s.resolveGenres(ctx, extractGenres(album)))
 	if err != nil {
 		return 0, fmt.Errorf("marshal genres: %w", err)
 	}
@@ -419,11 +453,10 @@ func (s *Service) storeAlbumForUser(ctx context.Context, userID int64, album mus
 		log.Printf("storeAlbumForUser: updating existing album id=%d user=%d title=%q", albumID, userID, album.Title)
 		if _, err := s.db.ExecContext(ctx, `
 			UPDATE albums
-			SET tracks = $1::jsonb,
-			    genres = $2::jsonb,
-			    release_year = $3
-			WHERE id = $4
-		`, string(trackTitlesJSON), string(genresJSON), releaseYear, albumID); err != nil {
+			SET genres = $1::jsonb,
+			    release_year = $2
+			WHERE id = $3
+		`, string(genresJSON), releaseYear, albumID); err != nil {
 			log.Printf("Failed to update album metadata (id=%d): %v", albumID, err)
 		}
 		return albumID, nil
@@ -432,17 +465,86 @@ func (s *Service) storeAlbumForUser(ctx context.Context, userID int64, album mus
 	const defaultRating = 3
 	log.Printf("storeAlbumForUser: inserting album user=%d title=%q rating=%d", userID, album.Title, defaultRating)
 	err = s.db.QueryRowContext(ctx, `
-		INSERT INTO albums (user_id, artist, title, release_year, tracks, genres, rating)
-		VALUES ($1, $2, $3, $4, $5::jsonb, $6::jsonb, $7)
+		INSERT INTO albums (user_id, artist, title, release_year, genres, rating)
+		VALUES ($1, $2, $3, $4, $5::jsonb, $6)
 		RETURNING id
-	`, userID, album.Artist, album.Title, releaseYear, string(trackTitlesJSON), string(genresJSON), defaultRating).Scan(&albumID)
+	`, userID, album.Artist, album.Title, releaseYear, string(genresJSON), defaultRating).Scan(&albumID)
 	if err != nil {
 		return 0, fmt.Errorf("insert album: %w", err)
 	}
 
+	if err := s.store.LinkAlbumArtists(ctx, albumID, album.Artist); err != nil {
+		log.Printf("Failed to link artists for album id=%d: %v", albumID, err)
+	}
+
 	return albumID, nil
 }
 
+// importedAlbum returns the local album a provider album was imported as,
+// directly or through a match with another provider's copy, or 0.
+func (s *Service) importedAlbum(ctx context.Context, provider musicapi.MusicProvider, albumID string) int64 {
+	ids, err := s.store.LookupExternalID(ctx, string(provider), albumID, store.EntityAlbum)
+	if err != nil {
+		if !errors.Is(err, store.ErrExternalIDNotFound) {
+			log.Printf("Failed to look up album %s at %s: %v", albumID, provider, err)
+		}
+		return 0
+	}
+	return ids[0].LocalID
+}
+
+// saveExternalID records the provider ID an entity was imported or saved
+// from; failures are logged, since the entity itself is stored.
+func (s *Service) saveExternalID(ctx context.Context, entityType string, localID int64, provider musicapi.MusicProvider, externalID, url string) {
+	if s.store == nil || externalID == "" || provider == "" {
+		return
+	}
+	err := s.store.SaveExternalID(ctx, store.ExternalID{
+		EntityType: entityType,
+		LocalID:    localID,
+		Provider:   string(provider),
+		ExternalID: externalID,
+		URL:        url,
+	})
+	if err != nil {
+		log.Printf("Failed to record %s id=%d as %s at %s: %v", entityType, localID, externalID, provider, err)
+	}
+}
+
+// storePressing records the pressing of a provider album as a release of
+// the local album. The release imported from the same provider ID before is
+// updated instead, wherever a merge has moved it since.
+func (s *Service) storePressing(ctx context.Context, token string, albumID int64, provider musicapi.MusicProvider, externalID string, album musicapi.Album) error {
+	pressing := album.Pressing
+	release := models.Release{
+		AlbumID:       albumID,
+		Format:        models.ReleaseFormat(pressing.Format),
+		Label:         pressing.Label,
+		CatalogNumber: pressing.CatalogNumber,
+		Country:       pressing.Country,
+		PressingYear:  pressing.Year,
+		Barcode:       pressing.Barcode,
+		ColorVariant:  pressing.ColorVariant,
+		Description:   pressing.Description,
+	}
+
+	ids, err := s.store.ExternalIDs(ctx, store.EntityRelease, []store.ProviderRef{{Provider: string(provider), ExternalID: externalID}})
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		_, err := s.store.UpdateRelease(ctx, token, ids[0].LocalID, release)
+		return err
+	}
+
+	created, err := s.store.CreateRelease(ctx, token, release)
+	if err != nil {
+		return err
+	}
+	s.saveExternalID(ctx, store.EntityRelease, created.ID, provider, externalID, album.ExternalURL)
+	return nil
+}
+
 func (s *Service) storeTrackForUser(ctx context.Context, albumID int64, album musicapi.Album, track musicapi.Track) error {
 	title := strings.TrimSpace(track.Title)
 	if title == "" {
@@ -456,40 +558,40 @@ func (s *Service) storeTrackForUser(ctx context.Context, albumID int64, album mu
 		artist = album.Artist
 	}
 
-	var exists bool
-	if err := s.db.QueryRowContext(ctx, `
-		SELECT EXISTS(
-			SELECT 1 FROM songs
-			WHERE album_id = $1 AND title = $2 AND artist = $3
-		)
-	`, albumID, title, artist).Scan(&exists); err != nil {
-		return fmt.Errorf("check track exists: %w", err)
-	}
-	if exists {
+	var songID int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id FROM songs
+		WHERE album_id = $1 AND title = $2 AND artist = $3
+		ORDER BY id
+		LIMIT 1
+	`, albumID, title, artist).Scan(&songID)
+	if err == nil {
+		s.saveExternalID(ctx, store.EntityTrack, songID, track.Provider, track.ExternalID, track.ExternalURL)
 		return nil
 	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("check track exists: %w", err)
+	}
 
-	_, err := s.db.ExecContext(ctx, `
-		INSERT INTO songs (title, artist, album_id, duration, track_num)
-		VALUES ($1, $2, $3, $4, $5)
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO songs (title, artist, album_id, duration, disc_number, track_num)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		ON CONFLICT DO NOTHING
-	`, title, artist, albumID, nullIfZero(track.Duration), nullIfZero(track.TrackNumber))
+		RETURNING id
+	`, title, artist, albumID, nullIfZero(track.Duration), max(track.DiscNumber, 1), nullIfZero(track.TrackNumber)).Scan(&songID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("insert track: %w", err)
 	}
 
-	return nil
-}
-
-func extractTrackTitles(tracks []musicapi.Track) []string {
-	var titles []string
-	for _, track := range tracks {
-		title := strings.TrimSpace(track.Title)
-		if title != "" {
-			titles = append(titles, title)
-		}
+	if err := s.store.LinkSongArtists(ctx, songID, artist); err != nil {
+		return fmt.Errorf("link track artists: %w", err)
 	}
-	return titles
+	s.saveExternalID(ctx, store.EntityTrack, songID, track.Provider, track.ExternalID, track.ExternalURL)
+
+	return nil
 }
 
 func extractGenres(album musicapi.Album) []string {
@@ -506,6 +608,21 @@ func extractGenres(album musicapi.Album) []string {
 	return genres
 }
 
+// resolveGenres maps provider genre names onto the genre taxonomy, adding
+// the ones it does not know yet. The names are kept as given when there is
+// no store or the mapping fails.
+func (s *Service) resolveGenres(ctx context.Context, names []string) []string {
+	if s.store == nil || len(names) == 0 {
+		return names
+	}
+	resolved, err := s.store.ResolveGenres(ctx, names)
+	if err != nil {
+		log.Printf("Failed to map genres %q: %v", names, err)
+		return names
+	}
+	return resolved
+}
+
 func resolveReleaseYear(album musicapi.Album) int {
 	if album.ReleaseYear > 0 {
 		return album.ReleaseYear
@@ -527,26 +644,26 @@ func parseYear(value string) int {
 	return year
 }
 
-// GetArtistWithAlbums fetches full artist details and all their albums from Spotify
-func (s *Service) GetArtistWithAlbums(ctx context.Context, artistID string) (*musicapi.Artist, []musicapi.Album, error) {
-	if s.spotifyClient == nil {
-		return nil, nil, fmt.Errorf("spotify client not available")
+// GetArtistWithAlbums fetches full artist details and all their albums from
+// a provider registered for artist albums
+func (s *Service) GetArtistWithAlbums(ctx context.Context, provider musicapi.MusicProvider, artistID string) (*musicapi.Artist, []musicapi.Album, error) {
+	albumsClient, err := s.providers.ArtistAlbums(provider)
+	if err != nil {
+		return nil, nil, err
 	}
-
-	// Type assert to get the concrete type with the GetArtistAlbums method
-	spotifyClient, ok := s.spotifyClient.(*musicapi.SpotifyClient)
-	if !ok {
-		return nil, nil, fmt.Errorf("spotify client type assertion failed")
+	client, err := s.providers.Client(provider)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Get artist details
-	artist, err := spotifyClient.GetArtist(ctx, artistID)
+	artist, err := client.GetArtist(ctx, artistID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("get artist: %w", err)
 	}
 
 	// Get all albums for this artist
-	albums, err := spotifyClient.GetArtistAlbums(ctx, artistID)
+	albums, err := albumsClient.GetArtistAlbums(ctx, artistID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("get artist albums: %w", err)
 	}
@@ -554,13 +671,14 @@ func (s *Service) GetArtistWithAlbums(ctx context.Context, artistID string) (*mu
 	return artist, albums, nil
 }
 
-// GetAlbumWithTracks fetches full album details with all tracks from Spotify
-func (s *Service) GetAlbumWithTracks(ctx context.Context, albumID string) (*musicapi.Album, []musicapi.Track, error) {
-	if s.spotifyClient == nil {
-		return nil, nil, fmt.Errorf("spotify client not available")
+// GetAlbumWithTracks fetches full album details with all tracks from a provider
+func (s *Service) GetAlbumWithTracks(ctx context.Context, provider musicapi.MusicProvider, albumID string) (*musicapi.Album, []musicapi.Track, error) {
+	client, err := s.providers.Client(provider)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	album, tracks, err := s.spotifyClient.GetAlbum(ctx, albumID)
+	album, tracks, err := client.GetAlbum(ctx, albumID)
 	if err != nil {
 		return nil, nil, fmt.Errorf("get album: %w", err)
 	}
@@ -568,62 +686,67 @@ func (s *Service) GetAlbumWithTracks(ctx context.Context, albumID string) (*musi
 	return album, tracks, nil
 }
 
-// GetAllArtists retrieves all artists from the database
-func (s *Service) GetAllArtists(ctx context.Context) ([]musicapi.Artist, error) {
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT
-			COALESCE(external_id, '') as external_id,
-			name,
-			COALESCE(provider, '') as provider,
-			COALESCE(image_url, '') as image_url,
-			COALESCE(biography, '') as biography,
-			COALESCE(genres, '[]'::jsonb) as genres,
-			COALESCE(popularity, 0) as popularity,
-			COALESCE(external_url, '') as external_url
-		FROM artists
-		ORDER BY name ASC
-	`)
+// GetDiscogsMaster fetches a Discogs master release with the versions it
+// was pressed as
+func (s *Service) GetDiscogsMaster(ctx context.Context, masterID string) (*musicapi.DiscogsMaster, error) {
+	client, err := s.discogs()
 	if err != nil {
-		return nil, fmt.Errorf("query artists: %w", err)
-	}
-	defer rows.Close()
-
-	var artists []musicapi.Artist
-	for rows.Next() {
-		var artist musicapi.Artist
-		var genresJSON string
-
-		err := rows.Scan(
-			&artist.ExternalID,
-			&artist.Name,
-			&artist.Provider,
-			&artist.ImageURL,
-			&artist.Biography,
-			&genresJSON,
-			&artist.Popularity,
-			&artist.ExternalURL,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("scan artist: %w", err)
-		}
+		return nil, err
+	}
 
-		// Parse genres JSON
-		if err := json.Unmarshal([]byte(genresJSON), &artist.Genres); err != nil {
-			log.Printf("Failed to parse genres for artist %s: %v", artist.Name, err)
-			artist.Genres = []string{}
-		}
+	master, err := client.GetMaster(ctx, masterID)
+	if err != nil {
+		return nil, fmt.Errorf("get master: %w", err)
+	}
+	return master, nil
+}
 
-		artists = append(artists, artist)
+// GetDiscogsLabel fetches a Discogs record label with its releases
+func (s *Service) GetDiscogsLabel(ctx context.Context, labelID string) (*musicapi.DiscogsLabel, error) {
+	client, err := s.discogs()
+	if err != nil {
+		return nil, err
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows error: %w", err)
+	label, err := client.GetLabel(ctx, labelID)
+	if err != nil {
+		return nil, fmt.Errorf("get label: %w", err)
 	}
+	return label, nil
+}
 
-	return artists, nil
+// discogs returns the registered Discogs client, whose masters and labels
+// no other provider has.
+func (s *Service) discogs() (*musicapi.DiscogsClient, error) {
+	client, err := s.providers.Client(musicapi.ProviderDiscogs)
+	if err != nil {
+		return nil, err
+	}
+	discogs, ok := client.(*musicapi.DiscogsClient)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not the Discogs client", musicapi.ErrCapabilityUnsupported, musicapi.ProviderDiscogs)
+	}
+	return discogs, nil
 }
 
-// SaveArtist stores an artist in the database (public wrapper for storeArtist)
-func (s *Service) SaveArtist(ctx context.Context, artist musicapi.Artist) error {
+// SaveArtist stores an artist in the database on behalf of a curator
+// (public wrapper for storeArtist)
+func (s *Service) SaveArtist(ctx context.Context, token string, artist musicapi.Artist) error {
+	if err := s.requireCurator(ctx, token); err != nil {
+		return err
+	}
 	return s.storeArtist(ctx, artist)
 }
+
+// requireCurator checks that the token belongs to a user allowed to change the shared catalog
+func (s *Service) requireCurator(ctx context.Context, token string) error {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return store.ErrUnauthorized
+	}
+	if s.store == nil {
+		return errors.New("store not configured")
+	}
+	_, err := s.store.RequireRole(ctx, token, models.RoleCurator)
+	return err
+}
@@ -0,0 +1,100 @@
+package searchservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"vinylhound/internal/musicapi"
+)
+
+type isrcClient struct {
+	musicapi.MusicAPIClient
+	tracks []musicapi.Track
+	err    error
+	isrc   string
+}
+
+func (c *isrcClient) LookupISRC(_ context.Context, isrc string) ([]musicapi.Track, error) {
+	c.isrc = isrc
+	return c.tracks, c.err
+}
+
+func TestLookupISRCMergesProviders(t *testing.T) {
+	spotify := &isrcClient{tracks: []musicapi.Track{
+		{ExternalID: "sp-t1", Provider: musicapi.ProviderSpotify, Title: "Something", Artist: "The Beatles", ISRC: "GBAYE0601690"},
+	}}
+	apple := &isrcClient{tracks: []musicapi.Track{
+		{ExternalID: "am-t1", Provider: musicapi.ProviderAppleMusic, Title: "Something (2019 Mix)", Artist: "Beatles", ISRC: "GBAYE0601690", Duration: 182},
+	}}
+	failing := &isrcClient{err: errors.New("unavailable")}
+
+	registry := musicapi.NewRegistry()
+	for _, p := range []struct {
+		id     musicapi.MusicProvider
+		client *isrcClient
+	}{{musicapi.ProviderSpotify, spotify}, {musicapi.ProviderAppleMusic, apple}, {"failing", failing}} {
+		if err := registry.Register(musicapi.ProviderInfo{ID: p.id, Capabilities: musicapi.CapISRCLookup}, p.client); err != nil {
+			t.Fatalf("Register %s: %v", p.id, err)
+		}
+	}
+	s := NewService(nil, registry, nil, nil)
+
+	tracks, err := s.LookupISRC(context.Background(), " gb-aye-06-01690")
+	if err != nil {
+		t.Fatalf("LookupISRC: %v", err)
+	}
+	if spotify.isrc != "GBAYE0601690" {
+		t.Errorf("expected the normalized ISRC to be looked up, got %q", spotify.isrc)
+	}
+	if len(tracks) != 1 || len(tracks[0].Links) != 2 || tracks[0].Duration != 182 {
+		t.Fatalf("expected one track merged from both providers, got %+v", tracks)
+	}
+
+	if _, err := s.LookupISRC(context.Background(), " - "); !errors.Is(err, ErrInvalidISRC) {
+		t.Fatalf("expected ErrInvalidISRC, got %v", err)
+	}
+}
+
+type barcodeClient struct {
+	musicapi.MusicAPIClient
+	albums  []musicapi.Album
+	barcode string
+}
+
+func (c *barcodeClient) LookupBarcode(_ context.Context, barcode string) ([]musicapi.Album, error) {
+	c.barcode = barcode
+	return c.albums, nil
+}
+
+func TestLookupBarcodeMergesProviders(t *testing.T) {
+	musicBrainz := &barcodeClient{albums: []musicapi.Album{
+		{ExternalID: "mb-abbey", Provider: musicapi.ProviderMusicBrainz, Title: "Abbey Road", Artist: "The Beatles", UPC: "602508007364", TrackCount: 17},
+	}}
+	spotify := &barcodeClient{albums: []musicapi.Album{
+		{ExternalID: "sp-abbey", Provider: musicapi.ProviderSpotify, Title: "Abbey Road (Remastered)", Artist: "The Beatles", UPC: "0602508007364"},
+	}}
+
+	registry := musicapi.NewRegistry()
+	for id, client := range map[musicapi.MusicProvider]*barcodeClient{musicapi.ProviderMusicBrainz: musicBrainz, musicapi.ProviderSpotify: spotify} {
+		if err := registry.Register(musicapi.ProviderInfo{ID: id, Capabilities: musicapi.CapBarcodeLookup}, client); err != nil {
+			t.Fatalf("Register %s: %v", id, err)
+		}
+	}
+	s := NewService(nil, registry, nil, nil)
+
+	albums, err := s.LookupBarcode(context.Background(), "6 02508 00736 4")
+	if err != nil {
+		t.Fatalf("LookupBarcode: %v", err)
+	}
+	if musicBrainz.barcode != "602508007364" {
+		t.Errorf("expected the barcode digits to be looked up, got %q", musicBrainz.barcode)
+	}
+	if len(albums) != 1 || len(albums[0].Links) != 2 {
+		t.Fatalf("expected one album merged by UPC, got %+v", albums)
+	}
+
+	if _, err := s.LookupBarcode(context.Background(), "n/a"); !errors.Is(err, ErrInvalidBarcode) {
+		t.Fatalf("expected ErrInvalidBarcode, got %v", err)
+	}
+}
@@ -0,0 +1,373 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordResetTTL bounds how long an emailed reset link stays usable.
+const passwordResetTTL = time.Hour
+
+// recentSignInWindow is how fresh the session of an account without a
+// password must be to stand in for the password check.
+const recentSignInWindow = 10 * time.Minute
+
+var (
+	// ErrPasswordRequired signals an empty replacement password.
+	ErrPasswordRequired = errors.New("new password is required")
+	// ErrUserNotFound signals an unknown username.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrInvalidResetToken indicates an unknown, used or expired reset token.
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+	// ErrInvalidDeletionMode signals an unsupported account deletion mode.
+	ErrInvalidDeletionMode = errors.New("deletion mode must be delete or anonymize")
+	// ErrRecentSignInRequired signals an account without a password whose
+	// session is too old to confirm a sensitive change.
+	ErrRecentSignInRequired = errors.New("sign in again through a linked identity to confirm this change")
+)
+
+// AccountDeletionMode selects what happens to a user's content on deletion.
+type AccountDeletionMode string
+
+const (
+	// DeleteAccountCascade removes the user row and everything that references it.
+	DeleteAccountCascade AccountDeletionMode = "delete"
+	// DeleteAccountAnonymize removes private data but keeps public playlists,
+	// catalogue entries and ratings under a placeholder username.
+	DeleteAccountAnonymize AccountDeletionMode = "anonymize"
+)
+
+// PasswordReset is a freshly issued reset token and the account it belongs to.
+type PasswordReset struct {
+	UserID    int64
+	Username  string
+	Token     string
+	ExpiresAt time.Time
+}
+
+// ChangePassword replaces the password of the user owning the token after
+// verifying the current one, and revokes every other session of that user.
+// Accounts without a password set one the same way after a recent sign-in
+// (see verifyPasswordTx). It reports how many sessions were revoked.
+func (s *Store) ChangePassword(ctx context.Context, token, currentPassword, newPassword string) (int64, error) {
+	if newPassword == "" {
+		return 0, ErrPasswordRequired
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	userID, err := s.userIDForTokenTx(ctx, tx, token)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := verifyPasswordTx(ctx, tx, userID, token, currentPassword); err != nil {
+		return 0, err
+	}
+
+	if err := setPasswordTx(ctx, tx, userID, newPassword); err != nil {
+		return 0, err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM sessions
+		WHERE user_id = $1 AND token <> $2
+	`, userID, token)
+	if err != nil {
+		return 0, fmt.Errorf("revoke sessions: %w", err)
+	}
+	revoked, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("check revoked sessions: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	tx = nil
+
+	s.sessions.InvalidateUser(userID)
+	return revoked, nil
+}
+
+// CreatePasswordReset issues a single-use reset token for the named user.
+// Only the token hash is stored; the caller is responsible for delivering the
+// plaintext token to the user.
+func (s *Store) CreatePasswordReset(ctx context.Context, username string) (PasswordReset, error) {
+	username = strings.TrimSpace(username)
+
+	var userID int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id
+		FROM users
+		WHERE username = $1 AND deleted_at IS NULL
+	`, username).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return PasswordReset{}, ErrUserNotFound
+		}
+		return PasswordReset{}, fmt.Errorf("lookup user: %w", err)
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return PasswordReset{}, fmt.Errorf("create reset token: %w", err)
+	}
+	expiresAt := time.Now().Add(passwordResetTTL)
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, hashToken(token), expiresAt); err != nil {
+		return PasswordReset{}, fmt.Errorf("store reset token: %w", err)
+	}
+
+	return PasswordReset{
+		UserID:    userID,
+		Username:  username,
+		Token:     token,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// ResetPassword consumes a reset token, sets the new password and signs the
+// user out everywhere.
+func (s *Store) ResetPassword(ctx context.Context, resetToken, newPassword string) error {
+	if newPassword == "" {
+		return ErrPasswordRequired
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var userID int64
+	err = tx.QueryRowContext(ctx, `
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`, hashToken(resetToken)).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidResetToken
+		}
+		return fmt.Errorf("consume reset token: %w", err)
+	}
+
+	if err := setPasswordTx(ctx, tx, userID, newPassword); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM sessions
+		WHERE user_id = $1
+	`, userID); err != nil {
+		return fmt.Errorf("revoke sessions: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM password_reset_tokens
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID); err != nil {
+		return fmt.Errorf("discard outstanding reset tokens: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	tx = nil
+
+	s.sessions.InvalidateUser(userID)
+	return nil
+}
+
+// DeleteAccount removes the account owning the token after re-checking the
+// password, or the recent sign-in of an account without one. In cascade mode every row referencing the user is removed; in
+// anonymize mode private data is removed and the account is renamed so
+// public playlists and ratings remain without identifying the user.
+func (s *Store) DeleteAccount(ctx context.Context, token, password string, mode AccountDeletionMode) error {
+	if mode != DeleteAccountCascade && mode != DeleteAccountAnonymize {
+		return ErrInvalidDeletionMode
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	userID, err := s.userIDForTokenTx(ctx, tx, token)
+	if err != nil {
+		return err
+	}
+
+	if err := verifyPasswordTx(ctx, tx, userID, token, password); err != nil {
+		return err
+	}
+
+	if mode == DeleteAccountCascade {
+		err = deleteAccountTx(ctx, tx, userID)
+	} else {
+		err = anonymizeAccountTx(ctx, tx, userID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	tx = nil
+
+	s.sessions.InvalidateUser(userID)
+	return nil
+}
+
+func deleteAccountTx(ctx context.Context, tx *sql.Tx, userID int64) error {
+	// Playlists predate the migrations and may lack a cascading foreign key.
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM playlists
+		WHERE user_id = $1
+	`, userID); err != nil {
+		return fmt.Errorf("delete playlists: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM users
+		WHERE id = $1
+	`, userID); err != nil {
+		return fmt.Errorf("delete user: %w", err)
+	}
+	return nil
+}
+
+func anonymizeAccountTx(ctx context.Context, tx *sql.Tx, userID int64) error {
+	placeholder := fmt.Sprintf("deleted-user-%d", userID)
+
+	for _, stmt := range []struct {
+		what  string
+		query string
+	}{
+		{"sessions", `DELETE FROM sessions WHERE user_id = $1`},
+		{"reset tokens", `DELETE FROM password_reset_tokens WHERE user_id = $1`},
+		{"api tokens", `DELETE FROM api_tokens WHERE user_id = $1`},
+		{"identities", `DELETE FROM user_identities WHERE user_id = $1`},
+		{"content", `DELETE FROM user_content WHERE user_id = $1`},
+		{"favorites", `DELETE FROM favorites WHERE user_id = $1`},
+		{"collections", `DELETE FROM album_collections WHERE user_id = $1`},
+		{"concerts", `DELETE FROM concerts WHERE user_id = $1`},
+		{"venues", `DELETE FROM venues WHERE user_id = $1`},
+		{"retailers", `DELETE FROM retailers WHERE user_id = $1`},
+		{"private playlists", `DELETE FROM playlists WHERE user_id = $1 AND (is_public = FALSE OR is_favorite = TRUE)`},
+	} {
+		if _, err := tx.ExecContext(ctx, stmt.query, userID); err != nil {
+			return fmt.Errorf("delete %s: %w", stmt.what, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE playlists
+		SET owner = $2, updated_at = NOW()
+		WHERE user_id = $1
+	`, userID, placeholder); err != nil {
+		return fmt.Errorf("anonymize playlists: %w", err)
+	}
+
+	if err := clearTwoFactorTx(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	// An empty hash can never match in bcrypt, so the account cannot log in.
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET username = $2, password_hash = '', deleted_at = NOW()
+		WHERE id = $1
+	`, userID, placeholder); err != nil {
+		return fmt.Errorf("anonymize user: %w", err)
+	}
+	return nil
+}
+
+// verifyPasswordTx re-authenticates the user before a sensitive change.
+// Accounts created through an identity provider have no password; for them
+// the session token must come from a sign-in within recentSignInWindow, so
+// the user re-authenticates by signing in through the linked identity again.
+func verifyPasswordTx(ctx context.Context, tx *sql.Tx, userID int64, token, password string) error {
+	var hash []byte
+	err := tx.QueryRowContext(ctx, `
+		SELECT password_hash
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(&hash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUnauthorized
+		}
+		return fmt.Errorf("lookup user: %w", err)
+	}
+
+	if len(hash) == 0 {
+		var recent bool
+		err := tx.QueryRowContext(ctx, `
+			SELECT created_at > NOW() - make_interval(secs => $2)
+			FROM sessions
+			WHERE token = $1
+		`, token, recentSignInWindow.Seconds()).Scan(&recent)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrUnauthorized
+			}
+			return fmt.Errorf("lookup session: %w", err)
+		}
+		if !recent {
+			return ErrRecentSignInRequired
+		}
+		return nil
+	}
+
+	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+func setPasswordTx(ctx context.Context, tx *sql.Tx, userID int64, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET password_hash = $2
+		WHERE id = $1
+	`, userID, hash); err != nil {
+		return fmt.Errorf("update password: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,225 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const userIDForTokenTxQuery = `
+		SELECT user_id
+		FROM sessions
+		WHERE token = $1
+		  AND expires_at > NOW()
+	`
+
+const passwordHashQuery = `
+		SELECT password_hash
+		FROM users
+		WHERE id = $1
+	`
+
+const recentSignInQuery = `
+			SELECT created_at > NOW() - make_interval(secs => $2)
+			FROM sessions
+			WHERE token = $1
+		`
+
+func TestChangePasswordWrongCurrentPassword(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(userIDForTokenTxQuery)).
+		WithArgs("token").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(42)))
+	mock.ExpectQuery(regexp.QuoteMeta(passwordHashQuery)).
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"password_hash"}).AddRow(hash))
+	mock.ExpectRollback()
+
+	if _, err := s.ChangePassword(context.Background(), "token", "wrong", "next"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestChangePasswordRevokesOtherSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(userIDForTokenTxQuery)).
+		WithArgs("token").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(42)))
+	mock.ExpectQuery(regexp.QuoteMeta(passwordHashQuery)).
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"password_hash"}).AddRow(hash))
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE users
+		SET password_hash = $2
+		WHERE id = $1
+	`)).
+		WithArgs(int64(42), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`
+		DELETE FROM sessions
+		WHERE user_id = $1 AND token <> $2
+	`)).
+		WithArgs(int64(42), "token").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	revoked, err := s.ChangePassword(context.Background(), "token", "correct", "next")
+	if err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+	if revoked != 2 {
+		t.Fatalf("expected 2 revoked sessions, got %d", revoked)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestChangePasswordSetsFirstPasswordAfterRecentSignIn(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	// The account was created through an identity provider.
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(userIDForTokenTxQuery)).
+		WithArgs("token").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(42)))
+	mock.ExpectQuery(regexp.QuoteMeta(passwordHashQuery)).
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"password_hash"}).AddRow([]byte{}))
+	mock.ExpectQuery(regexp.QuoteMeta(recentSignInQuery)).
+		WithArgs("token", recentSignInWindow.Seconds()).
+		WillReturnRows(sqlmock.NewRows([]string{"recent"}).AddRow(true))
+	mock.ExpectExec(regexp.QuoteMeta(`SET password_hash = $2`)).
+		WithArgs(int64(42), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`WHERE user_id = $1 AND token <> $2`)).
+		WithArgs(int64(42), "token").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if _, err := s.ChangePassword(context.Background(), "token", "", "first-password"); err != nil {
+		t.Fatalf("ChangePassword: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeleteAccountWithoutPasswordRequiresRecentSignIn(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(userIDForTokenTxQuery)).
+		WithArgs("token").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(42)))
+	mock.ExpectQuery(regexp.QuoteMeta(passwordHashQuery)).
+		WithArgs(int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"password_hash"}).AddRow([]byte{}))
+	mock.ExpectQuery(regexp.QuoteMeta(recentSignInQuery)).
+		WithArgs("token", recentSignInWindow.Seconds()).
+		WillReturnRows(sqlmock.NewRows([]string{"recent"}).AddRow(false))
+	mock.ExpectRollback()
+
+	if err := s.DeleteAccount(context.Background(), "token", "", DeleteAccountCascade); !errors.Is(err, ErrRecentSignInRequired) {
+		t.Fatalf("expected ErrRecentSignInRequired, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestResetPasswordInvalidToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		UPDATE password_reset_tokens
+		SET used_at = NOW()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+		RETURNING user_id
+	`)).
+		WithArgs(hashToken("used")).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectRollback()
+
+	if err := s.ResetPassword(context.Background(), "used", "next"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("expected ErrInvalidResetToken, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeleteAccountRejectsUnknownMode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	if err := s.DeleteAccount(context.Background(), "token", "pw", "archive"); !errors.Is(err, ErrInvalidDeletionMode) {
+		t.Fatalf("expected ErrInvalidDeletionMode, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -7,6 +7,10 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
+
+	"vinylhound/internal/queryspec"
+	"vinylhound/shared/go/models"
 )
 
 var (
@@ -14,29 +18,42 @@ var (
 	ErrInvalidAlbum = errors.New("invalid album")
 	// ErrAlbumNotFound signals a missing album record.
 	ErrAlbumNotFound = errors.New("album not found")
+	// ErrAlbumInUse signals an album that other users still have in their
+	// collections.
+	ErrAlbumInUse = errors.New("album is in other users' collections")
 )
 
-// Album models a music record owned by a specific user.
+// Album models a music record owned by a specific user. Artist is the
+// display credit; Artists lists the normalized artists behind it and is only
+// filled in when a single album is loaded. Tracks is read from the album's
+// songs.
 type Album struct {
-	ID            int64    `json:"id"`
-	Artist        string   `json:"artist"`
-	Title         string   `json:"title"`
-	ReleaseYear   int      `json:"releaseYear"`
-	Tracks        []string `json:"trackList"`
-	Genres        []string `json:"genreList"`
-	Rating        int      `json:"rating"`
-	AverageRating float64  `json:"averageRating,omitempty"`
-	RatingCount   int      `json:"ratingCount,omitempty"`
+	ID            int64          `json:"id"`
+	Artist        string         `json:"artist"`
+	Artists       []ArtistCredit `json:"artists,omitempty"`
+	Title         string         `json:"title"`
+	ReleaseYear   int            `json:"releaseYear"`
+	Tracks        []Track        `json:"trackList"`
+	Genres        []string       `json:"genreList"`
+	Rating        int            `json:"rating"`
+	AverageRating float64        `json:"averageRating,omitempty"`
+	RatingCount   int            `json:"ratingCount,omitempty"`
 }
 
-// AlbumPreference captures a user's personal rating and favorite flag for an album.
+// AlbumPreference captures a user's personal rating and favorite flag for an
+// album, optionally for a specific release of it.
 type AlbumPreference struct {
-	Album     Album `json:"album"`
-	Rating    *int  `json:"rating,omitempty"`
-	Favorited bool  `json:"favorited"`
+	Album     Album     `json:"album"`
+	ReleaseID *int64    `json:"releaseId,omitempty"`
+	Rating    *int      `json:"rating,omitempty"`
+	Favorited bool      `json:"favorited"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
-// CreateAlbum inserts a new album for the user represented by the session token.
+// CreateAlbum inserts a new album for the user represented by the session
+// token. Albums are shared catalog entries, so the user must be a curator.
+// The album is credited to album.Artists when given, otherwise to the
+// artists parsed from the display credit.
 func (s *Store) CreateAlbum(token string, album Album) (Album, error) {
 	if err := validateAlbum(album); err != nil {
 		return Album{}, err
@@ -45,71 +62,140 @@ func (s *Store) CreateAlbum(token string, album Album) (Album, error) {
 	album.Artist = strings.TrimSpace(album.Artist)
 	album.Title = strings.TrimSpace(album.Title)
 
-	ctx := context.Background()
-
-	userID, err := s.userIDForToken(ctx, token)
+	credits, err := normalizeArtistCredits(album.Artist, album.Artists)
+	if err != nil {
+		return Album{}, err
+	}
+	tracks, err := normalizeTracks(album.Tracks)
 	if err != nil {
 		return Album{}, err
 	}
 
-	tracksJSON, err := json.Marshal(album.Tracks)
+	ctx := context.Background()
+
+	userID, err := s.RequireRole(ctx, token, models.RoleCurator)
 	if err != nil {
-		return Album{}, fmt.Errorf("prepare tracks payload: %w", err)
+		return Album{}, err
 	}
+
 	genresJSON, err := json.Marshal(album.Genres)
 	if err != nil {
 		return Album{}, fmt.Errorf("prepare genres payload: %w", err)
 	}
 
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Album{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
 	var id int64
-	err = s.db.QueryRowContext(ctx, `
-		INSERT INTO albums (user_id, artist, title, release_year, tracks, genres, rating)
-		VALUES ($1, $2, $3, $4, $5::jsonb, $6::jsonb, $7)
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO albums (user_id, artist, title, release_year, genres, rating)
+		VALUES ($1, $2, $3, $4, $5::jsonb, $6)
 		RETURNING id
-	`, userID, album.Artist, album.Title, album.ReleaseYear, string(tracksJSON), string(genresJSON), album.Rating).Scan(&id)
+	`, userID, album.Artist, album.Title, album.ReleaseYear, string(genresJSON), album.Rating).Scan(&id)
 	if err != nil {
 		return Album{}, fmt.Errorf("insert album: %w", err)
 	}
 
+	album.Artists, err = linkArtistCreditsTx(ctx, tx, albumCreditTable, id, credits)
+	if err != nil {
+		return Album{}, err
+	}
+	album.Tracks, err = insertAlbumTracksTx(ctx, tx, id, tracks)
+	if err != nil {
+		return Album{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Album{}, fmt.Errorf("commit tx: %w", err)
+	}
+
 	album.ID = id
 	return album, nil
 }
 
-// AlbumsByToken lists albums for the authenticated user.
-func (s *Store) AlbumsByToken(token string) ([]Album, error) {
+// albumList orders album lists newest release first and whitelists the
+// fields of their query specs.
+var albumList = listSpec[Album]{
+	keyset: keyset{name: "albums", columns: []keyColumn{
+		{expr: "release_year", desc: true, kind: keyInt},
+		{expr: "id", kind: keyInt},
+	}},
+	key:   func(a Album) []any { return []any{a.ReleaseYear, a.ID} },
+	id:    keyColumn{expr: "id", kind: keyInt},
+	idKey: func(a Album) any { return a.ID },
+	fields: map[string]listField[Album]{
+		"artist":         {expr: "artist", kind: keyString, key: func(a Album) any { return a.Artist }, json: "artist"},
+		"title":          {expr: "title", kind: keyString, key: func(a Album) any { return a.Title }, json: "title"},
+		"release_year":   {expr: "release_year", kind: keyInt, key: func(a Album) any { return a.ReleaseYear }, json: "releaseYear"},
+		"year":           {expr: "release_year", kind: keyInt, key: func(a Album) any { return a.ReleaseYear }, json: "releaseYear"},
+		"rating":         {expr: "rating", kind: keyInt, key: func(a Album) any { return a.Rating }, json: "rating"},
+		"artists":        {json: "artists"},
+		"genres":         {json: "genreList"},
+		"tracks":         {json: "trackList"},
+		"average_rating": {json: "averageRating"},
+		"rating_count":   {json: "ratingCount"},
+	},
+}
+
+// AlbumQuery checks the query spec of an album list against its whitelist and
+// returns the response keys its fields= selects.
+func AlbumQuery(spec queryspec.Spec) ([]string, error) {
+	return albumList.check(spec)
+}
+
+// AlbumsByToken lists one page of albums for the authenticated user,
+// filtered and sorted by spec.
+func (s *Store) AlbumsByToken(token string, spec queryspec.Spec, page Page) ([]Album, string, error) {
 	ctx := context.Background()
 
 	userID, err := s.userIDForToken(ctx, token)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+
+	listQ, args, err := albumList.compile(spec, []any{userID})
+	if err != nil {
+		return nil, "", err
+	}
+	pageQ, err := listQ.keyset.page(page)
+	if err != nil {
+		return nil, "", err
 	}
 
+	after, args := pageQ.where(args)
+	tail, args := pageQ.orderLimit(args)
 	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, artist, title, release_year, tracks, genres, rating
+		SELECT id, artist, title, release_year, genres, rating
 		FROM albums
-		WHERE user_id = $1
-		ORDER BY release_year DESC, id ASC
-	`, userID)
+		WHERE user_id = $1 AND `+listQ.where()+` AND `+after+`
+		`+tail, args...)
 	if err != nil {
-		return nil, fmt.Errorf("select albums: %w", err)
+		return nil, "", fmt.Errorf("select albums: %w", err)
 	}
 	defer rows.Close()
 
 	albums, err := scanAlbumRows(rows)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("iterate albums: %w", err)
 	}
+	albums, next := finishPage(pageQ, albums, listQ.key)
 
 	albums, err = s.applyAlbumRatingStats(ctx, albums)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate albums: %w", err)
+	albums, err = s.applyAlbumTracks(ctx, albums)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return albums, nil
+	return albums, next, nil
 }
 
 // AlbumFilter constrains the results returned by ListAlbums.
@@ -121,12 +207,13 @@ type AlbumFilter struct {
 	Rating      int
 }
 
-// ListAlbums returns albums matching the provided filter.
-func (s *Store) ListAlbums(filter AlbumFilter) ([]Album, error) {
+// ListAlbums returns one page of albums matching the provided filter and
+// spec, sorted by spec.
+func (s *Store) ListAlbums(filter AlbumFilter, spec queryspec.Spec, page Page) ([]Album, string, error) {
 	ctx := context.Background()
 
 	query := `
-		SELECT id, artist, title, release_year, tracks, genres, rating
+		SELECT id, artist, title, release_year, genres, rating
 		FROM albums
 	`
 
@@ -152,41 +239,50 @@ func (s *Store) ListAlbums(filter AlbumFilter) ([]Album, error) {
 		clauses = append(clauses, fmt.Sprintf("rating = $%d", len(args)))
 	}
 	if genre := strings.TrimSpace(filter.Genre); genre != "" {
-		genreJSON, err := json.Marshal([]string{genre})
-		if err != nil {
-			return nil, fmt.Errorf("marshal genre filter: %w", err)
-		}
-		args = append(args, string(genreJSON))
-		clauses = append(clauses, fmt.Sprintf("genres @> $%d::jsonb", len(args)))
+		args = append(args, genre)
+		clauses = append(clauses, genreMatch("genres", len(args)))
 	}
 
-	if len(clauses) > 0 {
-		query += " WHERE " + strings.Join(clauses, " AND ")
+	listQ, args, err := albumList.compile(spec, args)
+	if err != nil {
+		return nil, "", err
 	}
+	pageQ, err := listQ.keyset.page(page)
+	if err != nil {
+		return nil, "", err
+	}
+	clauses = append(clauses, listQ.conditions...)
 
-	query += " ORDER BY release_year DESC, id ASC"
+	after, args := pageQ.where(args)
+	clauses = append(clauses, after)
+	tail, args := pageQ.orderLimit(args)
+	query += " WHERE " + strings.Join(clauses, " AND ") + " " + tail
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("select albums: %w", err)
+		return nil, "", fmt.Errorf("select albums: %w", err)
 	}
 	defer rows.Close()
 
 	albums, err := scanAlbumRows(rows)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("iterate albums: %w", err)
+	}
+	albums, next := finishPage(pageQ, albums, listQ.key)
 
 	albums, err = s.applyAlbumRatingStats(ctx, albums)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate albums: %w", err)
+	albums, err = s.applyAlbumTracks(ctx, albums)
+	if err != nil {
+		return nil, "", err
 	}
 
-	return albums, nil
+	return albums, next, nil
 }
 
 // AlbumByID returns a single album by its identifier.
@@ -194,7 +290,7 @@ func (s *Store) AlbumByID(id int64) (Album, error) {
 	ctx := context.Background()
 
 	row := s.db.QueryRowContext(ctx, `
-		SELECT id, artist, title, release_year, tracks, genres, rating
+		SELECT id, artist, title, release_year, genres, rating
 		FROM albums
 		WHERE id = $1
 	`, id)
@@ -211,11 +307,278 @@ func (s *Store) AlbumByID(id int64) (Album, error) {
 	if err != nil {
 		return Album{}, err
 	}
+	albums, err = s.applyAlbumTracks(ctx, albums)
+	if err != nil {
+		return Album{}, err
+	}
+
+	albums[0].Artists, err = s.albumArtistCredits(ctx, id)
+	if err != nil {
+		return Album{}, err
+	}
 	return albums[0], nil
 }
 
-// UpsertAlbumPreference sets or updates the calling user's rating/favorite for an album.
-func (s *Store) UpsertAlbumPreference(token string, albumID int64, rating *int, favorited bool) error {
+// AlbumPatch lists the album fields to change; nil fields are left as they
+// are. Artists replaces the artist credits and, like CreateAlbum, defaults to
+// the credits parsed from Artist.
+type AlbumPatch struct {
+	Artist      *string
+	Artists     *[]ArtistCredit
+	Title       *string
+	ReleaseYear *int
+	Genres      *[]string
+	Rating      *int
+}
+
+func (p AlbumPatch) empty() bool {
+	return p.Artist == nil && p.Artists == nil && p.Title == nil &&
+		p.ReleaseYear == nil && p.Genres == nil && p.Rating == nil
+}
+
+// UpdateAlbum applies a partial update to an album. Curators may edit the
+// albums they created and admins any album. Changing the artist re-links the
+// album's artist credits and moves the album's songs credited to the old
+// artist to the new one. A new title or artist is carried over to playlist
+// entries naming the old album and artist exactly. The tracklist is edited
+// through the album's songs.
+func (s *Store) UpdateAlbum(ctx context.Context, token string, id int64, patch AlbumPatch) (Album, error) {
+	if patch.empty() {
+		return Album{}, fmt.Errorf("%w: no fields to update", ErrInvalidAlbum)
+	}
+
+	userID, role, err := s.albumEditor(ctx, token)
+	if err != nil {
+		return Album{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Album{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	current, err := lockEditableAlbumTx(ctx, tx, id, userID, role)
+	if err != nil {
+		return Album{}, err
+	}
+
+	updated := current
+	if patch.Artist != nil {
+		updated.Artist = strings.TrimSpace(*patch.Artist)
+	}
+	if patch.Title != nil {
+		updated.Title = strings.TrimSpace(*patch.Title)
+	}
+	if patch.ReleaseYear != nil {
+		updated.ReleaseYear = *patch.ReleaseYear
+	}
+	if patch.Genres != nil {
+		updated.Genres = *patch.Genres
+	}
+	if updated.Genres == nil {
+		updated.Genres = []string{}
+	}
+	if patch.Rating != nil {
+		updated.Rating = *patch.Rating
+	}
+	if err := validateAlbum(updated); err != nil {
+		return Album{}, err
+	}
+
+	genresJSON, err := json.Marshal(updated.Genres)
+	if err != nil {
+		return Album{}, fmt.Errorf("prepare genres payload: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE albums
+		SET artist = $2, title = $3, release_year = $4, genres = $5::jsonb, rating = $6
+		WHERE id = $1
+	`, id, updated.Artist, updated.Title, updated.ReleaseYear, string(genresJSON), updated.Rating); err != nil {
+		return Album{}, fmt.Errorf("update album: %w", err)
+	}
+
+	if patch.Artists != nil || updated.Artist != current.Artist {
+		var explicit []ArtistCredit
+		if patch.Artists != nil {
+			explicit = *patch.Artists
+		}
+		credits, err := normalizeArtistCredits(updated.Artist, explicit)
+		if err != nil {
+			return Album{}, err
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM album_artists WHERE album_id = $1`, id); err != nil {
+			return Album{}, fmt.Errorf("unlink album artists: %w", err)
+		}
+		if _, err := linkArtistCreditsTx(ctx, tx, albumCreditTable, id, credits); err != nil {
+			return Album{}, err
+		}
+	}
+
+	if updated.Artist != current.Artist {
+		if err := recreditAlbumSongsTx(ctx, tx, id, current.Artist, updated.Artist); err != nil {
+			return Album{}, err
+		}
+	}
+
+	if updated.Title != current.Title || updated.Artist != current.Artist {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE playlist_songs
+			SET album = $1, artist = $2
+			WHERE album = $3 AND artist = $4
+		`, updated.Title, updated.Artist, current.Title, current.Artist); err != nil {
+			return Album{}, fmt.Errorf("rename playlist entries: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Album{}, fmt.Errorf("commit tx: %w", err)
+	}
+	return s.AlbumByID(id)
+}
+
+// recreditAlbumSongsTx moves the album's songs credited to the old album
+// artist to the new one and re-links their artist credits. Songs with a
+// credit of their own, such as guest tracks, keep it.
+func recreditAlbumSongsTx(ctx context.Context, tx *sql.Tx, albumID int64, oldArtist, newArtist string) error {
+	rows, err := tx.QueryContext(ctx, `
+		UPDATE songs
+		SET artist = $3
+		WHERE album_id = $1 AND artist = $2
+		RETURNING id
+	`, albumID, oldArtist, newArtist)
+	if err != nil {
+		return fmt.Errorf("update album songs artist: %w", err)
+	}
+	var songIDs []int64
+	for rows.Next() {
+		var songID int64
+		if err := rows.Scan(&songID); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan album song: %w", err)
+		}
+		songIDs = append(songIDs, songID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate album songs: %w", err)
+	}
+
+	credits := ParseArtistCredit(newArtist)
+	for _, songID := range songIDs {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM song_artists WHERE song_id = $1`, songID); err != nil {
+			return fmt.Errorf("unlink song artists: %w", err)
+		}
+		if _, err := linkArtistCreditsTx(ctx, tx, songCreditTable, songID, credits); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteAlbum removes an album. Curators may delete the albums they created
+// as long as no other user has them in a collection; admins may delete any
+// album. The album's songs are deleted with it, which removes their track
+// favorites; releases, ratings, album favorites, collection items and
+// duplicate candidates go with the album through their foreign keys.
+// Playlist entries keep their own copy of the album title and stay.
+func (s *Store) DeleteAlbum(ctx context.Context, token string, id int64) error {
+	userID, role, err := s.albumEditor(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockEditableAlbumTx(ctx, tx, id, userID, role); err != nil {
+		return err
+	}
+
+	if !role.Allows(models.RoleAdmin) {
+		var collected bool
+		if err := tx.QueryRowContext(ctx, `
+			SELECT EXISTS(SELECT 1 FROM album_collections WHERE album_id = $1 AND user_id <> $2)
+		`, id, userID).Scan(&collected); err != nil {
+			return fmt.Errorf("check album collections: %w", err)
+		}
+		if collected {
+			return ErrAlbumInUse
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM songs WHERE album_id = $1`, id); err != nil {
+		return fmt.Errorf("delete album songs: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM albums WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("delete album: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// albumEditor resolves the user behind the token and checks that they may
+// edit catalog albums at all.
+func (s *Store) albumEditor(ctx context.Context, token string) (int64, models.Role, error) {
+	userID, err := s.userIDForToken(ctx, token)
+	if err != nil {
+		return 0, "", err
+	}
+	role, err := s.roleForUser(ctx, userID)
+	if err != nil {
+		return 0, "", err
+	}
+	if !role.Allows(models.RoleCurator) {
+		return 0, "", ErrForbidden
+	}
+	return userID, role, nil
+}
+
+// lockEditableAlbumTx locks an album for the rest of the transaction and
+// returns it when the user created it or is an admin.
+func lockEditableAlbumTx(ctx context.Context, tx *sql.Tx, id, userID int64, role models.Role) (Album, error) {
+	var ownerID int64
+	row := tx.QueryRowContext(ctx, `
+		SELECT user_id, id, artist, title, release_year, genres, rating
+		FROM albums
+		WHERE id = $1
+		FOR UPDATE
+	`, id)
+	album, err := scanAlbumRow(ownedAlbumScanner{row: row, ownerID: &ownerID})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Album{}, ErrAlbumNotFound
+		}
+		return Album{}, err
+	}
+	if ownerID != userID && !role.Allows(models.RoleAdmin) {
+		return Album{}, ErrForbidden
+	}
+	return album, nil
+}
+
+// ownedAlbumScanner reads the owner column in front of the columns
+// scanAlbumRow expects.
+type ownedAlbumScanner struct {
+	row     albumScanner
+	ownerID *int64
+}
+
+func (s ownedAlbumScanner) Scan(dest ...any) error {
+	return s.row.Scan(append([]any{s.ownerID}, dest...)...)
+}
+
+// UpsertAlbumPreference sets or updates the calling user's rating/favorite for
+// an album. releaseID optionally names the release the rating is for and must
+// belong to the album.
+func (s *Store) UpsertAlbumPreference(token string, albumID int64, releaseID *int64, rating *int, favorited bool) error {
 	if rating != nil {
 		if err := validateAlbumRating(*rating); err != nil {
 			return err
@@ -256,38 +619,56 @@ func (s *Store) UpsertAlbumPreference(token string, albumID int64, rating *int,
 		ratingArg = *rating
 	}
 
+	if err := s.checkReleaseOfAlbum(ctx, releaseID, albumID); err != nil {
+		return err
+	}
+
 	if _, err := s.db.ExecContext(ctx, `
-		INSERT INTO user_album_preferences (user_id, album_id, rating, favorited, updated_at)
-		VALUES ($1, $2, $3, $4, NOW())
+		INSERT INTO user_album_preferences (user_id, album_id, release_id, rating, favorited, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
 		ON CONFLICT (user_id, album_id)
-		DO UPDATE SET rating = EXCLUDED.rating, favorited = EXCLUDED.favorited, updated_at = NOW()
-	`, userID, albumID, ratingArg, favorited); err != nil {
+		DO UPDATE SET release_id = EXCLUDED.release_id, rating = EXCLUDED.rating, favorited = EXCLUDED.favorited, updated_at = NOW()
+	`, userID, albumID, releaseID, ratingArg, favorited); err != nil {
 		return fmt.Errorf("upsert album preference: %w", err)
 	}
 
 	return nil
 }
 
-// AlbumPreferencesByToken returns the user's albums with their ratings/favorites.
-func (s *Store) AlbumPreferencesByToken(token string) ([]AlbumPreference, error) {
+// albumPreferenceKeyset orders preferences most recently changed first.
+var albumPreferenceKeyset = keyset{name: "album-preferences", columns: []keyColumn{
+	{expr: "p.updated_at", desc: true, kind: keyTime},
+	{expr: "p.album_id", kind: keyInt},
+}}
+
+// AlbumPreferencesByToken returns one page of the user's albums with their
+// ratings/favorites.
+func (s *Store) AlbumPreferencesByToken(token string, page Page) ([]AlbumPreference, string, error) {
 	ctx := context.Background()
 
+	pageQ, err := albumPreferenceKeyset.page(page)
+	if err != nil {
+		return nil, "", err
+	}
+
 	userID, err := s.userIDForToken(ctx, token)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
+	args := []any{userID}
+	after, args := pageQ.where(args)
+	tail, args := pageQ.orderLimit(args)
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT
-			a.id, a.artist, a.title, a.release_year, a.tracks, a.genres, a.rating,
-			p.rating, p.favorited
+			a.id, a.artist, a.title, a.release_year, a.genres, a.rating,
+			p.release_id, p.rating, p.favorited, p.updated_at
 		FROM user_album_preferences p
 		JOIN albums a ON a.id = p.album_id
-		WHERE p.user_id = $1
-		ORDER BY p.updated_at DESC
-	`, userID)
+		WHERE p.user_id = $1 AND `+after+`
+		`+tail, args...)
 	if err != nil {
-		return nil, fmt.Errorf("select album preferences: %w", err)
+		return nil, "", fmt.Errorf("select album preferences: %w", err)
 	}
 	defer rows.Close()
 
@@ -295,10 +676,11 @@ func (s *Store) AlbumPreferencesByToken(token string) ([]AlbumPreference, error)
 	for rows.Next() {
 		var (
 			a          Album
-			tracksJSON []byte
 			genresJSON []byte
+			releaseID  sql.NullInt64
 			rating     sql.NullInt64
 			fav        bool
+			updatedAt  time.Time
 		)
 
 		if err := rows.Scan(
@@ -306,25 +688,27 @@ func (s *Store) AlbumPreferencesByToken(token string) ([]AlbumPreference, error)
 			&a.Artist,
 			&a.Title,
 			&a.ReleaseYear,
-			&tracksJSON,
 			&genresJSON,
 			&a.Rating,
+			&releaseID,
 			&rating,
 			&fav,
+			&updatedAt,
 		); err != nil {
-			return nil, fmt.Errorf("scan album preference: %w", err)
+			return nil, "", fmt.Errorf("scan album preference: %w", err)
 		}
 
-		if err := json.Unmarshal(tracksJSON, &a.Tracks); err != nil {
-			return nil, fmt.Errorf("decode tracks: %w", err)
-		}
 		if err := json.Unmarshal(genresJSON, &a.Genres); err != nil {
-			return nil, fmt.Errorf("decode genres: %w", err)
+			return nil, "", fmt.Errorf("decode genres: %w", err)
 		}
 
 		pref := AlbumPreference{
 			Album:     a,
 			Favorited: fav,
+			UpdatedAt: updatedAt,
+		}
+		if releaseID.Valid {
+			pref.ReleaseID = &releaseID.Int64
 		}
 		if rating.Valid {
 			val := int(rating.Int64)
@@ -333,8 +717,11 @@ func (s *Store) AlbumPreferencesByToken(token string) ([]AlbumPreference, error)
 		preferences = append(preferences, pref)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate album preferences: %w", err)
+		return nil, "", fmt.Errorf("iterate album preferences: %w", err)
 	}
+	preferences, next := finishPage(pageQ, preferences, func(p AlbumPreference) []any {
+		return []any{p.UpdatedAt, p.Album.ID}
+	})
 
 	albums := make([]Album, len(preferences))
 	for i := range preferences {
@@ -343,15 +730,20 @@ func (s *Store) AlbumPreferencesByToken(token string) ([]AlbumPreference, error)
 
 	albums, err = s.applyAlbumRatingStats(ctx, albums)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	albums, err = s.applyAlbumTracks(ctx, albums)
+	if err != nil {
+		return nil, "", err
 	}
 
 	for i := range preferences {
 		preferences[i].Album.AverageRating = albums[i].AverageRating
 		preferences[i].Album.RatingCount = albums[i].RatingCount
+		preferences[i].Album.Tracks = albums[i].Tracks
 	}
 
-	return preferences, nil
+	return preferences, next, nil
 }
 
 func validateAlbum(album Album) error {
@@ -485,12 +877,11 @@ type albumScanner interface {
 func scanAlbumRow(scanner albumScanner) (Album, error) {
 	var (
 		a           Album
-		tracksJSON  []byte
 		genresJSON  []byte
 		releaseYear sql.NullInt64
 	)
 
-	if err := scanner.Scan(&a.ID, &a.Artist, &a.Title, &releaseYear, &tracksJSON, &genresJSON, &a.Rating); err != nil {
+	if err := scanner.Scan(&a.ID, &a.Artist, &a.Title, &releaseYear, &genresJSON, &a.Rating); err != nil {
 		return Album{}, fmt.Errorf("scan album: %w", err)
 	}
 
@@ -499,9 +890,6 @@ func scanAlbumRow(scanner albumScanner) (Album, error) {
 		a.ReleaseYear = int(releaseYear.Int64)
 	}
 
-	if err := json.Unmarshal(tracksJSON, &a.Tracks); err != nil {
-		return Album{}, fmt.Errorf("decode tracks: %w", err)
-	}
 	if err := json.Unmarshal(genresJSON, &a.Genres); err != nil {
 		return Album{}, fmt.Errorf("decode genres: %w", err)
 	}
@@ -1,12 +1,16 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+
+	"vinylhound/internal/queryspec"
 )
 
 const ratingStatsQuery = `
@@ -16,6 +20,13 @@ const ratingStatsQuery = `
 		GROUP BY album_id
 	`
 
+const touchSessionQuery = `
+		UPDATE sessions
+		SET last_seen_at = NOW(),
+		    expires_at = GREATEST(expires_at, NOW() + make_interval(secs => $2))
+		WHERE token = $1 AND last_seen_at < NOW() - INTERVAL '1 minute'
+	`
+
 func TestValidateAlbum(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -28,7 +39,7 @@ func TestValidateAlbum(t *testing.T) {
 				Artist:      "Aphex Twin",
 				Title:       "Selected Ambient Works",
 				ReleaseYear: 1992,
-				Tracks:      []string{"Xtal"},
+				Tracks:      []Track{{Title: "Xtal"}},
 				Genres:      []string{"Ambient"},
 				Rating:      5,
 			},
@@ -85,19 +96,41 @@ func TestCreateAlbumSuccess(t *testing.T) {
 		WithArgs("token").
 		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(42)))
 
+	mock.ExpectExec(regexp.QuoteMeta(touchSessionQuery)).
+		WithArgs("token", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	expectRoleLookup(mock, 42, "curator")
+
+	mock.ExpectBegin()
 	mock.ExpectQuery(regexp.QuoteMeta(`
-		INSERT INTO albums (user_id, artist, title, release_year, tracks, genres, rating)
-		VALUES ($1, $2, $3, $4, $5::jsonb, $6::jsonb, $7)
+		INSERT INTO albums (user_id, artist, title, release_year, genres, rating)
+		VALUES ($1, $2, $3, $4, $5::jsonb, $6)
 		RETURNING id
 	`)).
-		WithArgs(int64(42), "Artist", "Title", 1999, `["Track 1"]`, `["Electronic"]`, 4).
+		WithArgs(int64(42), "Artist", "Title", 1999, `["Electronic"]`, 4).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(99)))
+	expectArtistLookup(mock, "Artist", 5)
+	expectCreditLink(mock, "album_artists", 99, 5, ArtistRolePrimary, 0)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE albums SET artist_id = $2 WHERE id = $1`)).
+		WithArgs(int64(99), int64(5)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO songs (title, artist, album_id, artist_id, disc_number, side, track_num, duration)`)).
+		WithArgs(int64(99), "Track 1", 1, "A", 1, 312).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(700)))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO songs (title, artist, album_id, artist_id, disc_number, side, track_num, duration)`)).
+		WithArgs(int64(99), "Track 2", 1, "A", 2, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(701)))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO song_artists (song_id, artist_id, role, position)`)).
+		WithArgs(int64(99)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
 
 	album := Album{
 		Artist:      "  Artist ",
 		Title:       " Title  ",
 		ReleaseYear: 1999,
-		Tracks:      []string{"Track 1"},
+		Tracks:      []Track{{Title: "Track 1", Side: "a", Duration: 312}, {Title: " Track 2 ", Side: "A"}},
 		Genres:      []string{"Electronic"},
 		Rating:      4,
 	}
@@ -113,6 +146,12 @@ func TestCreateAlbumSuccess(t *testing.T) {
 	if got.Artist != "Artist" || got.Title != "Title" {
 		t.Fatalf("expected trimmed artist/title, got %q / %q", got.Artist, got.Title)
 	}
+	if len(got.Artists) != 1 || got.Artists[0].ArtistID != 5 {
+		t.Fatalf("expected album credited to artist 5, got %+v", got.Artists)
+	}
+	if len(got.Tracks) != 2 || got.Tracks[1].SongID != 701 || got.Tracks[1].Position != 2 || got.Tracks[1].Side != "A" {
+		t.Fatalf("unexpected tracklist %+v", got.Tracks)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("unmet expectations: %v", err)
@@ -151,6 +190,33 @@ func TestCreateAlbumUnauthorized(t *testing.T) {
 	}
 }
 
+func TestCreateAlbumRequiresCurator(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 42)
+	expectRoleLookup(mock, 42, "user")
+
+	_, err = s.CreateAlbum("token", Album{
+		Artist:      "Artist",
+		Title:       "Title",
+		ReleaseYear: 2000,
+		Rating:      3,
+	})
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
 func TestListAlbumsWithFilters(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -161,28 +227,32 @@ func TestListAlbumsWithFilters(t *testing.T) {
 	s := New(db)
 
 	expectedQuery := regexp.QuoteMeta(`
-		SELECT id, artist, title, release_year, tracks, genres, rating
-		FROM albums WHERE artist ILIKE $1 AND rating = $2 ORDER BY release_year DESC, id ASC
+		SELECT id, artist, title, release_year, genres, rating
+		FROM albums WHERE artist ILIKE $1 AND rating = $2 AND TRUE ORDER BY release_year DESC, id ASC LIMIT $3
 	`)
 
 	mock.ExpectQuery(expectedQuery).
-		WithArgs("%Boards%", 5).
+		WithArgs("%Boards%", 5, DefaultPageSize+1).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "artist", "title", "release_year", "tracks", "genres", "rating",
-		}).AddRow(int64(1), "Boards of Canada", "Geogaddi", 2002, `["Music Is Math"]`, `["Electronic"]`, 5))
+			"id", "artist", "title", "release_year", "genres", "rating",
+		}).AddRow(int64(1), "Boards of Canada", "Geogaddi", 2002, `["Electronic"]`, 5))
 
 	mock.ExpectQuery(regexp.QuoteMeta(ratingStatsQuery)).
 		WithArgs(int64(1)).
 		WillReturnRows(sqlmock.NewRows([]string{"album_id", "average_rating", "rating_count"}).
 			AddRow(int64(1), 4.2, int64(18)))
+	expectAlbumTracks(mock, []int64{1}, trackRow{albumID: 1, songID: 10, disc: 1, position: 1, title: "Music Is Math"})
 
-	albums, err := s.ListAlbums(AlbumFilter{
+	albums, next, err := s.ListAlbums(AlbumFilter{
 		Artist: "Boards",
 		Rating: 5,
-	})
+	}, queryspec.Spec{}, Page{})
 	if err != nil {
 		t.Fatalf("ListAlbums error: %v", err)
 	}
+	if next != "" {
+		t.Fatalf("expected last page, got cursor %q", next)
+	}
 
 	if len(albums) != 1 || albums[0].Title != "Geogaddi" {
 		t.Fatalf("unexpected albums: %#v", albums)
@@ -190,6 +260,9 @@ func TestListAlbumsWithFilters(t *testing.T) {
 	if albums[0].AverageRating != 4.2 || albums[0].RatingCount != 18 {
 		t.Fatalf("unexpected rating stats: %#v", albums[0])
 	}
+	if len(albums[0].Tracks) != 1 || albums[0].Tracks[0].Title != "Music Is Math" {
+		t.Fatalf("unexpected tracks: %#v", albums[0].Tracks)
+	}
 
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("unmet expectations: %v", err)
@@ -206,7 +279,7 @@ func TestAlbumByIDNotFound(t *testing.T) {
 	s := New(db)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, artist, title, release_year, tracks, genres, rating
+		SELECT id, artist, title, release_year, genres, rating
 		FROM albums
 		WHERE id = $1
 	`)).
@@ -240,6 +313,10 @@ func TestUpsertAlbumPreferenceInsert(t *testing.T) {
 		WithArgs("token").
 		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(42)))
 
+	mock.ExpectExec(regexp.QuoteMeta(touchSessionQuery)).
+		WithArgs("token", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
 	mock.ExpectQuery(regexp.QuoteMeta(`
 		SELECT id
 		FROM albums
@@ -249,16 +326,16 @@ func TestUpsertAlbumPreferenceInsert(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(10)))
 
 	mock.ExpectExec(regexp.QuoteMeta(`
-		INSERT INTO user_album_preferences (user_id, album_id, rating, favorited, updated_at)
-		VALUES ($1, $2, $3, $4, NOW())
+		INSERT INTO user_album_preferences (user_id, album_id, release_id, rating, favorited, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
 		ON CONFLICT (user_id, album_id)
-		DO UPDATE SET rating = EXCLUDED.rating, favorited = EXCLUDED.favorited, updated_at = NOW()
+		DO UPDATE SET release_id = EXCLUDED.release_id, rating = EXCLUDED.rating, favorited = EXCLUDED.favorited, updated_at = NOW()
 	`)).
-		WithArgs(int64(42), int64(10), 5, true).
+		WithArgs(int64(42), int64(10), nil, 5, true).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	rating := 5
-	if err := s.UpsertAlbumPreference("token", 10, &rating, true); err != nil {
+	if err := s.UpsertAlbumPreference("token", 10, nil, &rating, true); err != nil {
 		t.Fatalf("UpsertAlbumPreference: %v", err)
 	}
 
@@ -284,6 +361,10 @@ func TestUpsertAlbumPreferenceDelete(t *testing.T) {
 		WithArgs("token").
 		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(42)))
 
+	mock.ExpectExec(regexp.QuoteMeta(touchSessionQuery)).
+		WithArgs("token", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
 	mock.ExpectQuery(regexp.QuoteMeta(`
 		SELECT id
 		FROM albums
@@ -299,7 +380,7 @@ func TestUpsertAlbumPreferenceDelete(t *testing.T) {
 		WithArgs(int64(42), int64(10)).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	if err := s.UpsertAlbumPreference("token", 10, nil, false); err != nil {
+	if err := s.UpsertAlbumPreference("token", 10, nil, nil, false); err != nil {
 		t.Fatalf("UpsertAlbumPreference delete: %v", err)
 	}
 
@@ -318,7 +399,7 @@ func TestUpsertAlbumPreferenceInvalidRating(t *testing.T) {
 	s := New(db)
 
 	rating := 6
-	if err := s.UpsertAlbumPreference("token", 10, &rating, true); !errors.Is(err, ErrInvalidAlbum) {
+	if err := s.UpsertAlbumPreference("token", 10, nil, &rating, true); !errors.Is(err, ErrInvalidAlbum) {
 		t.Fatalf("expected ErrInvalidAlbum, got %v", err)
 	}
 }
@@ -340,6 +421,10 @@ func TestUpsertAlbumPreferenceAlbumNotFound(t *testing.T) {
 		WithArgs("token").
 		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(42)))
 
+	mock.ExpectExec(regexp.QuoteMeta(touchSessionQuery)).
+		WithArgs("token", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
 	mock.ExpectQuery(regexp.QuoteMeta(`
 		SELECT id
 		FROM albums
@@ -349,7 +434,7 @@ func TestUpsertAlbumPreferenceAlbumNotFound(t *testing.T) {
 		WillReturnError(sql.ErrNoRows)
 
 	rating := 4
-	if err := s.UpsertAlbumPreference("token", 10, &rating, false); !errors.Is(err, ErrAlbumNotFound) {
+	if err := s.UpsertAlbumPreference("token", 10, nil, &rating, false); !errors.Is(err, ErrAlbumNotFound) {
 		t.Fatalf("expected ErrAlbumNotFound, got %v", err)
 	}
 
@@ -375,36 +460,42 @@ func TestAlbumPreferencesByToken(t *testing.T) {
 		WithArgs("token").
 		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(42)))
 
+	mock.ExpectExec(regexp.QuoteMeta(touchSessionQuery)).
+		WithArgs("token", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
 	mock.ExpectQuery(regexp.QuoteMeta(`
 		SELECT
-			a.id, a.artist, a.title, a.release_year, a.tracks, a.genres, a.rating,
-			p.rating, p.favorited
+			a.id, a.artist, a.title, a.release_year, a.genres, a.rating,
+			p.release_id, p.rating, p.favorited, p.updated_at
 		FROM user_album_preferences p
 		JOIN albums a ON a.id = p.album_id
-		WHERE p.user_id = $1
-		ORDER BY p.updated_at DESC
+		WHERE p.user_id = $1 AND TRUE
+		ORDER BY p.updated_at DESC, p.album_id ASC LIMIT $2
 	`)).
-		WithArgs(int64(42)).
+		WithArgs(int64(42), DefaultPageSize+1).
 		WillReturnRows(sqlmock.NewRows([]string{
-			"id", "artist", "title", "release_year", "tracks", "genres", "rating", "user_rating", "favorited",
+			"id", "artist", "title", "release_year", "genres", "rating", "release_id", "user_rating", "favorited", "updated_at",
 		}).AddRow(
 			int64(1),
 			"Artist",
 			"Title",
 			2000,
-			`["Track"]`,
 			`["Genre"]`,
 			4,
+			nil,
 			int64(5),
 			true,
+			time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
 		))
 
 	mock.ExpectQuery(regexp.QuoteMeta(ratingStatsQuery)).
 		WithArgs(int64(1)).
 		WillReturnRows(sqlmock.NewRows([]string{"album_id", "average_rating", "rating_count"}).
 			AddRow(int64(1), 4.6, int64(12)))
+	expectAlbumTracks(mock, []int64{1}, trackRow{albumID: 1, songID: 3, disc: 1, position: 1, title: "Track"})
 
-	prefs, err := s.AlbumPreferencesByToken("token")
+	prefs, _, err := s.AlbumPreferencesByToken("token", Page{})
 	if err != nil {
 		t.Fatalf("AlbumPreferencesByToken: %v", err)
 	}
@@ -423,3 +514,272 @@ func TestAlbumPreferencesByToken(t *testing.T) {
 		t.Fatalf("unmet expectations: %v", err)
 	}
 }
+
+func expectLockAlbum(mock sqlmock.Sqlmock, id, ownerID int64, artist, title string) {
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT user_id, id, artist, title, release_year, genres, rating
+		FROM albums
+		WHERE id = $1
+		FOR UPDATE
+	`)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "id", "artist", "title", "release_year", "genres", "rating"}).
+			AddRow(ownerID, id, artist, title, 1973, `["Rock"]`, 5))
+}
+
+// expectAlbumReload expects the AlbumByID lookups that follow an update.
+func expectAlbumReload(mock sqlmock.Sqlmock, id int64, artist, title string) {
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM albums
+		WHERE id = $1`)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "artist", "title", "release_year", "genres", "rating"}).
+			AddRow(id, artist, title, 1973, `["Rock"]`, 5))
+	mock.ExpectQuery(regexp.QuoteMeta(ratingStatsQuery)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"album_id", "average_rating", "rating_count"}))
+	expectAlbumTracks(mock, []int64{id})
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM album_artists aa`)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "role"}).AddRow(int64(3), artist, ArtistRolePrimary))
+}
+
+func TestUpdateAlbumPatchesTitle(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 42)
+	expectRoleLookup(mock, 42, "curator")
+	mock.ExpectBegin()
+	expectLockAlbum(mock, 7, 42, "Pink Floyd", "Dark Side of the Mon")
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE albums
+		SET artist = $2, title = $3, release_year = $4, genres = $5::jsonb, rating = $6
+		WHERE id = $1
+	`)).
+		WithArgs(int64(7), "Pink Floyd", "The Dark Side of the Moon", 1973, `["Rock"]`, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`
+			UPDATE playlist_songs
+			SET album = $1, artist = $2
+			WHERE album = $3 AND artist = $4
+		`)).
+		WithArgs("The Dark Side of the Moon", "Pink Floyd", "Dark Side of the Mon", "Pink Floyd").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	expectAlbumReload(mock, 7, "Pink Floyd", "The Dark Side of the Moon")
+
+	title := " The Dark Side of the Moon "
+	album, err := s.UpdateAlbum(context.Background(), "token", 7, AlbumPatch{Title: &title})
+	if err != nil {
+		t.Fatalf("UpdateAlbum: %v", err)
+	}
+	if album.Title != "The Dark Side of the Moon" {
+		t.Fatalf("unexpected album %+v", album)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUpdateAlbumRelinksArtist(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 1)
+	expectRoleLookup(mock, 1, "admin")
+	mock.ExpectBegin()
+	expectLockAlbum(mock, 7, 42, "Pink Floid", "The Wall")
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE albums`)).
+		WithArgs(int64(7), "Pink Floyd", "The Wall", 1973, `["Rock"]`, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM album_artists WHERE album_id = $1`)).
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectArtistLookup(mock, "Pink Floyd", 3)
+	expectCreditLink(mock, "album_artists", 7, 3, ArtistRolePrimary, 0)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE albums SET artist_id = $2 WHERE id = $1`)).
+		WithArgs(int64(7), int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Only the songs credited to the old album artist follow it.
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE album_id = $1 AND artist = $2`)).
+		WithArgs(int64(7), "Pink Floid", "Pink Floyd").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(70)))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM song_artists WHERE song_id = $1`)).
+		WithArgs(int64(70)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectArtistLookup(mock, "Pink Floyd", 3)
+	expectCreditLink(mock, "song_artists", 70, 3, ArtistRolePrimary, 0)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE songs SET artist_id = $2 WHERE id = $1`)).
+		WithArgs(int64(70), int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`WHERE album = $3 AND artist = $4`)).
+		WithArgs("The Wall", "Pink Floyd", "The Wall", "Pink Floid").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectAlbumReload(mock, 7, "Pink Floyd", "The Wall")
+
+	artist := "Pink Floyd"
+	album, err := s.UpdateAlbum(context.Background(), "token", 7, AlbumPatch{Artist: &artist})
+	if err != nil {
+		t.Fatalf("UpdateAlbum: %v", err)
+	}
+	if album.Artist != "Pink Floyd" {
+		t.Fatalf("unexpected album %+v", album)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUpdateAlbumRejectsOtherCurators(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 5)
+	expectRoleLookup(mock, 5, "curator")
+	mock.ExpectBegin()
+	expectLockAlbum(mock, 7, 42, "Pink Floyd", "The Wall")
+	mock.ExpectRollback()
+
+	rating := 4
+	if _, err := s.UpdateAlbum(context.Background(), "token", 7, AlbumPatch{Rating: &rating}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUpdateAlbumValidation(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	if _, err := s.UpdateAlbum(context.Background(), "token", 7, AlbumPatch{}); !errors.Is(err, ErrInvalidAlbum) {
+		t.Fatalf("expected ErrInvalidAlbum for an empty patch, got %v", err)
+	}
+
+	expectSessionLookup(mock, "token", 42)
+	expectRoleLookup(mock, 42, "curator")
+	mock.ExpectBegin()
+	expectLockAlbum(mock, 7, 42, "Pink Floyd", "The Wall")
+	mock.ExpectRollback()
+
+	blank := "  "
+	if _, err := s.UpdateAlbum(context.Background(), "token", 7, AlbumPatch{Title: &blank}); !errors.Is(err, ErrInvalidAlbum) {
+		t.Fatalf("expected ErrInvalidAlbum for a blank title, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeleteAlbum(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 42)
+	expectRoleLookup(mock, 42, "curator")
+	mock.ExpectBegin()
+	expectLockAlbum(mock, 7, 42, "Pink Floyd", "The Wall")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM album_collections WHERE album_id = $1 AND user_id <> $2)`)).
+		WithArgs(int64(7), int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM songs WHERE album_id = $1`)).
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 12))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM albums WHERE id = $1`)).
+		WithArgs(int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := s.DeleteAlbum(context.Background(), "token", 7); err != nil {
+		t.Fatalf("DeleteAlbum: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeleteAlbumCollectedByOthers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 42)
+	expectRoleLookup(mock, 42, "curator")
+	mock.ExpectBegin()
+	expectLockAlbum(mock, 7, 42, "Pink Floyd", "The Wall")
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM album_collections`)).
+		WithArgs(int64(7), int64(42)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	if err := s.DeleteAlbum(context.Background(), "token", 7); !errors.Is(err, ErrAlbumInUse) {
+		t.Fatalf("expected ErrAlbumInUse, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeleteAlbumNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 1)
+	expectRoleLookup(mock, 1, "admin")
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE`)).
+		WithArgs(int64(7)).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	if err := s.DeleteAlbum(context.Background(), "token", 7); !errors.Is(err, ErrAlbumNotFound) {
+		t.Fatalf("expected ErrAlbumNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,208 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"vinylhound/shared/go/auth"
+)
+
+// apiTokenPrefixLen is how much of a token is kept in clear for display.
+const apiTokenPrefixLen = 12
+
+var (
+	// ErrAPITokenNotFound signals a missing or foreign personal access token.
+	ErrAPITokenNotFound = errors.New("api token not found")
+	// ErrInvalidAPIToken indicates invalid personal access token settings.
+	ErrInvalidAPIToken = errors.New("invalid api token")
+)
+
+// APIToken describes a personal access token. The secret itself is only
+// returned once, when the token is created.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// CreateAPIToken issues a personal access token for the user owning the
+// session token and returns it with its secret. Tokens cannot be created with
+// another personal access token.
+func (s *Store) CreateAPIToken(ctx context.Context, token, name string, scopes []string, expiresAt *time.Time) (APIToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return APIToken{}, "", fmt.Errorf("%w: name is required", ErrInvalidAPIToken)
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return APIToken{}, "", err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return APIToken{}, "", fmt.Errorf("%w: expiry must be in the future", ErrInvalidAPIToken)
+	}
+
+	userID, err := s.userIDForSession(ctx, token)
+	if err != nil {
+		return APIToken{}, "", err
+	}
+
+	secret, err := auth.NewAPIToken()
+	if err != nil {
+		return APIToken{}, "", fmt.Errorf("create api token: %w", err)
+	}
+
+	created := APIToken{
+		Name:      name,
+		Prefix:    secret[:apiTokenPrefixLen],
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.db.QueryRowContext(ctx, `
+		INSERT INTO api_tokens (user_id, name, token_hash, token_prefix, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, userID, name, auth.HashAPIToken(secret), created.Prefix, strings.Join(scopes, " "), expiresAt).Scan(&created.ID, &created.CreatedAt); err != nil {
+		return APIToken{}, "", fmt.Errorf("store api token: %w", err)
+	}
+
+	return created, secret, nil
+}
+
+// APITokensByToken lists the personal access tokens of the user owning the
+// session token, newest first. Expired tokens are included so they can be
+// cleaned up.
+func (s *Store) APITokensByToken(ctx context.Context, token string) ([]APIToken, error) {
+	userID, err := s.userIDForSession(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, token_prefix, scopes, created_at, last_used_at, expires_at
+		FROM api_tokens
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select api tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []APIToken
+	for rows.Next() {
+		var (
+			t         APIToken
+			scopes    string
+			lastUsed  sql.NullTime
+			expiresAt sql.NullTime
+		)
+		if err := rows.Scan(&t.ID, &t.Name, &t.Prefix, &scopes, &t.CreatedAt, &lastUsed, &expiresAt); err != nil {
+			return nil, fmt.Errorf("scan api token: %w", err)
+		}
+		t.Scopes = auth.ParseScopes(scopes)
+		if lastUsed.Valid {
+			t.LastUsedAt = &lastUsed.Time
+		}
+		if expiresAt.Valid {
+			t.ExpiresAt = &expiresAt.Time
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate api tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// RevokeAPIToken deletes one of the authenticated user's personal access tokens.
+func (s *Store) RevokeAPIToken(ctx context.Context, token string, tokenID int64) error {
+	userID, err := s.userIDForSession(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM api_tokens
+		WHERE id = $1 AND user_id = $2
+	`, tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("revoke api token: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check revoked api token: %w", err)
+	}
+	if affected == 0 {
+		return ErrAPITokenNotFound
+	}
+	return nil
+}
+
+// APITokenScopes returns the scopes granted to a valid personal access token.
+func (s *Store) APITokenScopes(ctx context.Context, token string) ([]string, error) {
+	_, scopes, err := s.lookupAPIToken(ctx, token)
+	return scopes, err
+}
+
+func (s *Store) userIDForAPIToken(ctx context.Context, token string) (int64, error) {
+	userID, _, err := s.lookupAPIToken(ctx, token)
+	return userID, err
+}
+
+// lookupAPIToken resolves an unexpired personal access token and records it
+// as used. Like session activity, last_used_at is only written once a minute.
+func (s *Store) lookupAPIToken(ctx context.Context, token string) (int64, []string, error) {
+	var (
+		id     int64
+		userID int64
+		scopes string
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, user_id, scopes
+		FROM api_tokens
+		WHERE token_hash = $1
+		  AND (expires_at IS NULL OR expires_at > NOW())
+	`, auth.HashAPIToken(token)).Scan(&id, &userID, &scopes)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil, ErrUnauthorized
+		}
+		return 0, nil, fmt.Errorf("lookup api token: %w", err)
+	}
+
+	_, _ = s.db.ExecContext(ctx, `
+		UPDATE api_tokens
+		SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, id)
+
+	return userID, auth.ParseScopes(scopes), nil
+}
+
+// normalizeScopes validates requested scopes and returns them sorted without duplicates.
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIToken)
+	}
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if !auth.ValidScope(scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIToken, scope)
+		}
+		normalized = append(normalized, scope)
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized), nil
+}
@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"vinylhound/shared/go/auth"
+)
+
+func TestCreateAPITokenRejectsUnknownScope(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	_, _, err = s.CreateAPIToken(context.Background(), "tok", "cli", []string{"catalog:read", "admin"}, nil)
+	if !errors.Is(err, ErrInvalidAPIToken) {
+		t.Fatalf("expected ErrInvalidAPIToken, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCreateAPITokenStoresHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM sessions`)).
+		WithArgs("tok").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(7)))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE sessions`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO api_tokens`)).
+		WithArgs(int64(7), "cli", sqlmock.AnyArg(), sqlmock.AnyArg(), "catalog:read collections:write", nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(int64(1), time.Now()))
+
+	created, secret, err := s.CreateAPIToken(context.Background(), "tok", " cli ", []string{"collections:write", "catalog:read", "catalog:read"}, nil)
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	if !auth.IsAPIToken(secret) || created.Prefix != secret[:apiTokenPrefixLen] {
+		t.Fatalf("unexpected secret %q with prefix %q", secret, created.Prefix)
+	}
+	if len(created.Scopes) != 2 {
+		t.Fatalf("expected deduplicated scopes, got %v", created.Scopes)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUserIDForTokenAcceptsAPIToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	token := "vhp_example"
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM api_tokens`)).
+		WithArgs(auth.HashAPIToken(token)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "scopes"}).AddRow(int64(2), int64(7), "catalog:read"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE api_tokens`)).
+		WithArgs(int64(2)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	userID, err := s.userIDForToken(context.Background(), token)
+	if err != nil {
+		t.Fatalf("userIDForToken: %v", err)
+	}
+	if userID != 7 {
+		t.Fatalf("expected user 7, got %d", userID)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRevokeOtherSessionsRejectsAPIToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM sessions`)).
+		WithArgs("vhp_example").
+		WillReturnError(sql.ErrNoRows)
+
+	if _, err := s.RevokeOtherSessions(context.Background(), "vhp_example"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
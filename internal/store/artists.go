@@ -0,0 +1,458 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ErrArtistNotFound signals a missing artist record.
+var ErrArtistNotFound = errors.New("artist not found")
+
+// Roles an artist can be credited with on an album or song.
+const (
+	ArtistRolePrimary  = "primary"
+	ArtistRoleFeatured = "featured"
+)
+
+// Artist is a normalized artist that albums and songs are credited to.
+type Artist struct {
+	ID        int64    `json:"id"`
+	Name      string   `json:"name"`
+	Biography string   `json:"biography,omitempty"`
+	ImageURL  string   `json:"imageUrl,omitempty"`
+	Genres    []string `json:"genres,omitempty"`
+}
+
+// ArtistListing is an artist as returned by ListArtists, with the provider
+// details recorded when it was last imported.
+type ArtistListing struct {
+	Artist
+	ExternalID  string `json:"externalId,omitempty"`
+	Provider    string `json:"provider,omitempty"`
+	Popularity  int    `json:"popularity,omitempty"`
+	ExternalURL string `json:"externalUrl,omitempty"`
+}
+
+// ArtistCredit names an artist credited on an album or song.
+type ArtistCredit struct {
+	ArtistID int64  `json:"artistId,omitempty"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+}
+
+// ArtistFilter constrains the results returned by ListArtists.
+type ArtistFilter struct {
+	Name string
+}
+
+// DiscographyAlbum is an album an artist is credited on, with their role.
+type DiscographyAlbum struct {
+	Album
+	Role string `json:"role"`
+}
+
+// DiscographyTrack is a song an artist is credited on outside their own albums.
+type DiscographyTrack struct {
+	SongID     int64  `json:"songId"`
+	Title      string `json:"title"`
+	Artist     string `json:"artist"`
+	AlbumID    *int64 `json:"albumId,omitempty"`
+	AlbumTitle string `json:"albumTitle,omitempty"`
+	Role       string `json:"role"`
+}
+
+// ArtistDiscography is an artist with the albums and songs they are credited on.
+type ArtistDiscography struct {
+	Artist      Artist             `json:"artist"`
+	Albums      []DiscographyAlbum `json:"albums"`
+	Appearances []DiscographyTrack `json:"appearances"`
+}
+
+var (
+	featuringPattern = regexp.MustCompile(`(?i)(?:\s+|\s*[(\[])(?:feat\.?|ft\.?|featuring)\s+`)
+	guestSeparator   = regexp.MustCompile(`\s*[,&]\s*`)
+)
+
+// ParseArtistCredit splits a display credit such as "Primary feat. Guest &
+// Other" into its primary artist and featured guests. The primary part is
+// kept whole, so duo names like "Simon & Garfunkel" stay one artist;
+// collaborations with several primary artists must be credited explicitly.
+func ParseArtistCredit(credit string) []ArtistCredit {
+	credit = strings.TrimSpace(credit)
+	if credit == "" {
+		return nil
+	}
+
+	loc := featuringPattern.FindStringIndex(credit)
+	if loc == nil {
+		return []ArtistCredit{{Name: credit, Role: ArtistRolePrimary}}
+	}
+
+	credits := []ArtistCredit{{Name: strings.TrimSpace(credit[:loc[0]]), Role: ArtistRolePrimary}}
+	guests := strings.TrimRight(credit[loc[1]:], ")] \t")
+	for _, name := range guestSeparator.Split(guests, -1) {
+		if name = strings.TrimSpace(name); name != "" {
+			credits = append(credits, ArtistCredit{Name: name, Role: ArtistRoleFeatured})
+		}
+	}
+	return credits
+}
+
+// normalizeArtistCredits trims explicit credits, defaulting the role to
+// primary. Without explicit credits the display credit is parsed instead.
+func normalizeArtistCredits(display string, credits []ArtistCredit) ([]ArtistCredit, error) {
+	if len(credits) == 0 {
+		return ParseArtistCredit(display), nil
+	}
+
+	normalized := make([]ArtistCredit, 0, len(credits))
+	for _, credit := range credits {
+		credit.Name = strings.TrimSpace(credit.Name)
+		if credit.Name == "" {
+			continue
+		}
+		switch credit.Role {
+		case "":
+			credit.Role = ArtistRolePrimary
+		case ArtistRolePrimary, ArtistRoleFeatured:
+		default:
+			return nil, fmt.Errorf("%w: unknown artist role %q", ErrInvalidAlbum, credit.Role)
+		}
+		credit.ArtistID = 0
+		normalized = append(normalized, credit)
+	}
+	return normalized, nil
+}
+
+// artistKeyset orders artists by name.
+var artistKeyset = keyset{name: "artists", columns: []keyColumn{
+	{expr: "name", kind: keyString},
+	{expr: "id", kind: keyInt},
+}}
+
+// ListArtists returns one page of artists matching the provided filter.
+func (s *Store) ListArtists(ctx context.Context, filter ArtistFilter, page Page) ([]ArtistListing, string, error) {
+	pageQ, err := artistKeyset.page(page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	query := `
+		SELECT id, name, COALESCE(biography, ''), COALESCE(image_url, ''), COALESCE(genres, '[]'::jsonb),
+			COALESCE(external_id, ''), COALESCE(provider, ''), COALESCE(popularity, 0), COALESCE(external_url, '')
+		FROM artists
+	`
+	var args []any
+	clauses := []string{}
+	if name := strings.TrimSpace(filter.Name); name != "" {
+		args = append(args, "%"+name+"%")
+		clauses = append(clauses, "name ILIKE $1")
+	}
+	after, args := pageQ.where(args)
+	clauses = append(clauses, after)
+	tail, args := pageQ.orderLimit(args)
+	query += " WHERE " + strings.Join(clauses, " AND ") + " " + tail
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("select artists: %w", err)
+	}
+	defer rows.Close()
+
+	var artists []ArtistListing
+	for rows.Next() {
+		var (
+			artist     ArtistListing
+			genresJSON []byte
+		)
+		if err := rows.Scan(&artist.ID, &artist.Name, &artist.Biography, &artist.ImageURL, &genresJSON,
+			&artist.ExternalID, &artist.Provider, &artist.Popularity, &artist.ExternalURL); err != nil {
+			return nil, "", fmt.Errorf("scan artist: %w", err)
+		}
+		if err := json.Unmarshal(genresJSON, &artist.Genres); err != nil {
+			return nil, "", fmt.Errorf("decode artist genres: %w", err)
+		}
+		artists = append(artists, artist)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("iterate artists: %w", err)
+	}
+	artists, next := finishPage(pageQ, artists, func(a ArtistListing) []any { return []any{a.Name, a.ID} })
+	return artists, next, nil
+}
+
+// ArtistByID returns a single artist by its identifier.
+func (s *Store) ArtistByID(ctx context.Context, id int64) (Artist, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT id, name, COALESCE(biography, ''), COALESCE(image_url, ''), COALESCE(genres, '[]'::jsonb)
+		FROM artists
+		WHERE id = $1
+	`, id)
+
+	artist, err := scanArtistRow(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Artist{}, ErrArtistNotFound
+		}
+		return Artist{}, err
+	}
+	return artist, nil
+}
+
+// ArtistDiscography returns an artist with every album they are credited on,
+// oldest first, and the songs they appear on in other artists' albums.
+func (s *Store) ArtistDiscography(ctx context.Context, id int64) (ArtistDiscography, error) {
+	artist, err := s.ArtistByID(ctx, id)
+	if err != nil {
+		return ArtistDiscography{}, err
+	}
+
+	albums, roles, err := s.discographyAlbums(ctx, id)
+	if err != nil {
+		return ArtistDiscography{}, err
+	}
+	albums, err = s.applyAlbumRatingStats(ctx, albums)
+	if err != nil {
+		return ArtistDiscography{}, err
+	}
+	albums, err = s.applyAlbumTracks(ctx, albums)
+	if err != nil {
+		return ArtistDiscography{}, err
+	}
+
+	discography := ArtistDiscography{
+		Artist:      artist,
+		Albums:      make([]DiscographyAlbum, len(albums)),
+		Appearances: []DiscographyTrack{},
+	}
+	for i := range albums {
+		discography.Albums[i] = DiscographyAlbum{Album: albums[i], Role: roles[i]}
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT s.id, s.title, s.artist, s.album_id, COALESCE(a.title, ''), sa.role
+		FROM song_artists sa
+		JOIN songs s ON s.id = sa.song_id
+		LEFT JOIN albums a ON a.id = s.album_id
+		WHERE sa.artist_id = $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM album_artists aa
+			WHERE aa.album_id = s.album_id AND aa.artist_id = sa.artist_id
+		  )
+		ORDER BY a.release_year ASC NULLS LAST, s.album_id, s.track_num, s.id
+	`, id)
+	if err != nil {
+		return ArtistDiscography{}, fmt.Errorf("select artist appearances: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			track   DiscographyTrack
+			albumID sql.NullInt64
+		)
+		if err := rows.Scan(&track.SongID, &track.Title, &track.Artist, &albumID, &track.AlbumTitle, &track.Role); err != nil {
+			return ArtistDiscography{}, fmt.Errorf("scan artist appearance: %w", err)
+		}
+		if albumID.Valid {
+			track.AlbumID = &albumID.Int64
+		}
+		discography.Appearances = append(discography.Appearances, track)
+	}
+	if err := rows.Err(); err != nil {
+		return ArtistDiscography{}, fmt.Errorf("iterate artist appearances: %w", err)
+	}
+
+	return discography, nil
+}
+
+func (s *Store) discographyAlbums(ctx context.Context, artistID int64) ([]Album, []string, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT a.id, a.artist, a.title, a.release_year, a.genres, a.rating, aa.role
+		FROM album_artists aa
+		JOIN albums a ON a.id = aa.album_id
+		WHERE aa.artist_id = $1
+		ORDER BY a.release_year ASC, a.id ASC
+	`, artistID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("select artist albums: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		albums []Album
+		roles  []string
+	)
+	for rows.Next() {
+		var role string
+		album, err := scanAlbumRow(roleScanner{albumScanner: rows, role: &role})
+		if err != nil {
+			return nil, nil, err
+		}
+		albums = append(albums, album)
+		roles = append(roles, role)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("iterate artist albums: %w", err)
+	}
+	return albums, roles, nil
+}
+
+// roleScanner reads an album row followed by a credit role column.
+type roleScanner struct {
+	albumScanner
+	role *string
+}
+
+func (r roleScanner) Scan(dest ...any) error {
+	return r.albumScanner.Scan(append(dest, r.role)...)
+}
+
+// albumArtistCredits returns the artists credited on an album in credit order.
+func (s *Store) albumArtistCredits(ctx context.Context, albumID int64) ([]ArtistCredit, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT ar.id, ar.name, aa.role
+		FROM album_artists aa
+		JOIN artists ar ON ar.id = aa.artist_id
+		WHERE aa.album_id = $1
+		ORDER BY aa.role = 'featured', aa.position, ar.name
+	`, albumID)
+	if err != nil {
+		return nil, fmt.Errorf("select album artists: %w", err)
+	}
+	defer rows.Close()
+
+	var credits []ArtistCredit
+	for rows.Next() {
+		var credit ArtistCredit
+		if err := rows.Scan(&credit.ArtistID, &credit.Name, &credit.Role); err != nil {
+			return nil, fmt.Errorf("scan album artist: %w", err)
+		}
+		credits = append(credits, credit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate album artists: %w", err)
+	}
+	return credits, nil
+}
+
+// LinkAlbumArtists replaces the artists credited on an album with those
+// parsed from the display credit.
+func (s *Store) LinkAlbumArtists(ctx context.Context, albumID int64, credit string) error {
+	return s.relinkArtistCredits(ctx, albumCreditTable, albumID, ParseArtistCredit(credit))
+}
+
+// LinkSongArtists replaces the artists credited on a song with those parsed
+// from the display credit.
+func (s *Store) LinkSongArtists(ctx context.Context, songID int64, credit string) error {
+	return s.relinkArtistCredits(ctx, songCreditTable, songID, ParseArtistCredit(credit))
+}
+
+// creditTable names the join table crediting artists on albums or songs.
+type creditTable struct {
+	owner     string
+	join      string
+	ownerID   string
+	ownerKind string
+}
+
+var (
+	albumCreditTable = creditTable{owner: "albums", join: "album_artists", ownerID: "album_id", ownerKind: "album"}
+	songCreditTable  = creditTable{owner: "songs", join: "song_artists", ownerID: "song_id", ownerKind: "song"}
+)
+
+func (s *Store) relinkArtistCredits(ctx context.Context, table creditTable, ownerID int64, credits []ArtistCredit) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE %s = $1`, table.join, table.ownerID), ownerID); err != nil {
+		return fmt.Errorf("clear %s artists: %w", table.ownerKind, err)
+	}
+	if _, err := linkArtistCreditsTx(ctx, tx, table, ownerID, credits); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	return nil
+}
+
+// linkArtistCreditsTx credits the artists on an album or song, creating
+// artists that are not known yet, and points its artist_id at the first
+// primary artist. It returns the credits with their artist IDs filled in.
+func linkArtistCreditsTx(ctx context.Context, tx *sql.Tx, table creditTable, ownerID int64, credits []ArtistCredit) ([]ArtistCredit, error) {
+	var primaryID sql.NullInt64
+	linked := make([]ArtistCredit, 0, len(credits))
+	for position, credit := range credits {
+		artistID, err := ensureArtistTx(ctx, tx, credit.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO %s (%s, artist_id, role, position)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (%s, artist_id) DO NOTHING
+		`, table.join, table.ownerID, table.ownerID), ownerID, artistID, credit.Role, position); err != nil {
+			return nil, fmt.Errorf("link %s artist: %w", table.ownerKind, err)
+		}
+		if credit.Role == ArtistRolePrimary && !primaryID.Valid {
+			primaryID = sql.NullInt64{Int64: artistID, Valid: true}
+		}
+		credit.ArtistID = artistID
+		linked = append(linked, credit)
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`UPDATE %s SET artist_id = $2 WHERE id = $1`, table.owner), ownerID, primaryID); err != nil {
+		return nil, fmt.Errorf("set %s artist: %w", table.ownerKind, err)
+	}
+	return linked, nil
+}
+
+// ensureArtistTx returns the artist with the given name, matched
+// case-insensitively, creating it when missing.
+func ensureArtistTx(ctx context.Context, tx *sql.Tx, name string) (int64, error) {
+	var id int64
+	err := tx.QueryRowContext(ctx, `
+		SELECT id FROM artists WHERE lower(name) = lower($1) ORDER BY id LIMIT 1
+	`, name).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("lookup artist: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO artists (name, created_at, updated_at)
+		VALUES ($1, NOW(), NOW())
+		ON CONFLICT (name) DO UPDATE SET updated_at = artists.updated_at
+		RETURNING id
+	`, name).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("insert artist: %w", err)
+	}
+	return id, nil
+}
+
+func scanArtistRow(scanner albumScanner) (Artist, error) {
+	var (
+		artist     Artist
+		genresJSON []byte
+	)
+	if err := scanner.Scan(&artist.ID, &artist.Name, &artist.Biography, &artist.ImageURL, &genresJSON); err != nil {
+		return Artist{}, fmt.Errorf("scan artist: %w", err)
+	}
+	if err := json.Unmarshal(genresJSON, &artist.Genres); err != nil {
+		return Artist{}, fmt.Errorf("decode artist genres: %w", err)
+	}
+	return artist, nil
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func expectArtistLookup(mock sqlmock.Sqlmock, name string, id int64) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM artists WHERE lower(name) = lower($1)`)).
+		WithArgs(name).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(id))
+}
+
+func expectCreditLink(mock sqlmock.Sqlmock, table string, ownerID, artistID int64, role string, position int) {
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO `+table)).
+		WithArgs(ownerID, artistID, role, position).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestParseArtistCredit(t *testing.T) {
+	tests := []struct {
+		credit string
+		want   []ArtistCredit
+	}{
+		{"", nil},
+		{"  Daft Punk ", []ArtistCredit{{Name: "Daft Punk", Role: ArtistRolePrimary}}},
+		{"Simon & Garfunkel", []ArtistCredit{{Name: "Simon & Garfunkel", Role: ArtistRolePrimary}}},
+		{"Gorillaz feat. De La Soul", []ArtistCredit{
+			{Name: "Gorillaz", Role: ArtistRolePrimary},
+			{Name: "De La Soul", Role: ArtistRoleFeatured},
+		}},
+		{"Mark Ronson (ft. Amy Winehouse, Tiggers & Co)", []ArtistCredit{
+			{Name: "Mark Ronson", Role: ArtistRolePrimary},
+			{Name: "Amy Winehouse", Role: ArtistRoleFeatured},
+			{Name: "Tiggers", Role: ArtistRoleFeatured},
+			{Name: "Co", Role: ArtistRoleFeatured},
+		}},
+		{"Santana Featuring Rob Thomas", []ArtistCredit{
+			{Name: "Santana", Role: ArtistRolePrimary},
+			{Name: "Rob Thomas", Role: ArtistRoleFeatured},
+		}},
+	}
+
+	for _, tt := range tests {
+		if got := ParseArtistCredit(tt.credit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseArtistCredit(%q) = %+v, want %+v", tt.credit, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeArtistCreditsRejectsUnknownRole(t *testing.T) {
+	_, err := normalizeArtistCredits("A", []ArtistCredit{{Name: "A", Role: "producer"}})
+	if !errors.Is(err, ErrInvalidAlbum) {
+		t.Fatalf("expected ErrInvalidAlbum, got %v", err)
+	}
+}
+
+func TestArtistDiscography(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM artists`)).
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "biography", "image_url", "genres"}).
+			AddRow(int64(5), "De La Soul", "", "", []byte(`["Hip Hop"]`)))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM album_artists aa`)).
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "artist", "title", "release_year", "genres", "rating", "role"}).
+			AddRow(int64(1), "De La Soul", "3 Feet High and Rising", 1989, []byte(`[]`), 5, ArtistRolePrimary))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM user_album_preferences`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"album_id", "average_rating", "rating_count"}))
+	expectAlbumTracks(mock, []int64{1})
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM song_artists sa`)).
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "artist", "album_id", "album", "role"}).
+			AddRow(int64(30), "Feel Good Inc.", "Gorillaz feat. De La Soul", int64(2), "Demon Days", ArtistRoleFeatured))
+
+	got, err := s.ArtistDiscography(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("ArtistDiscography: %v", err)
+	}
+	if got.Artist.Name != "De La Soul" || len(got.Artist.Genres) != 1 {
+		t.Fatalf("unexpected artist %+v", got.Artist)
+	}
+	if len(got.Albums) != 1 || got.Albums[0].Role != ArtistRolePrimary || got.Albums[0].Title != "3 Feet High and Rising" {
+		t.Fatalf("unexpected albums %+v", got.Albums)
+	}
+	if len(got.Appearances) != 1 || got.Appearances[0].Role != ArtistRoleFeatured || *got.Appearances[0].AlbumID != 2 {
+		t.Fatalf("unexpected appearances %+v", got.Appearances)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestArtistDiscographyNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM artists`)).
+		WithArgs(int64(404)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "biography", "image_url", "genres"}))
+
+	if _, err := s.ArtistDiscography(context.Background(), 404); !errors.Is(err, ErrArtistNotFound) {
+		t.Fatalf("expected ErrArtistNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestLinkSongArtistsCreatesMissingArtists(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM song_artists WHERE song_id = $1`)).
+		WithArgs(int64(30)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectArtistLookup(mock, "Gorillaz", 4)
+	expectCreditLink(mock, "song_artists", 30, 4, ArtistRolePrimary, 0)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id FROM artists WHERE lower(name) = lower($1)`)).
+		WithArgs("De La Soul").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO artists (name, created_at, updated_at)`)).
+		WithArgs("De La Soul").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(5)))
+	expectCreditLink(mock, "song_artists", 30, 5, ArtistRoleFeatured, 1)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE songs SET artist_id = $2 WHERE id = $1`)).
+		WithArgs(int64(30), int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if err := s.LinkSongArtists(context.Background(), 30, "Gorillaz feat. De La Soul"); err != nil {
+		t.Fatalf("LinkSongArtists: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
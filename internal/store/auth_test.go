@@ -2,8 +2,8 @@ package store
 
 import (
 	"context"
+	"errors"
 	"testing"
-	"time"
 )
 
 func TestStore_CreateUser(t *testing.T) {
@@ -11,43 +11,42 @@ func TestStore_CreateUser(t *testing.T) {
 	defer cleanupTestStore(t, store)
 
 	tests := []struct {
-		name          string
-		username      string
-		passwordHash  string
-		wantErr       bool
-		errContains   string
+		name        string
+		username    string
+		password    string
+		wantErr     bool
+		errContains string
 	}{
 		{
-			name:         "valid user creation",
-			username:     "testuser",
-			passwordHash: "$2a$10$hashedpassword",
-			wantErr:      false,
+			name:     "valid user creation",
+			username: "testuser",
+			password: "password123",
+			wantErr:  false,
 		},
 		{
-			name:         "duplicate username",
-			username:     "testuser",
-			passwordHash: "$2a$10$hashedpassword2",
-			wantErr:      true,
-			errContains:  "already exists",
+			name:        "duplicate username",
+			username:    "testuser",
+			password:    "password456",
+			wantErr:     true,
+			errContains: "already exists",
 		},
 		{
-			name:         "empty username",
-			username:     "",
-			passwordHash: "$2a$10$hashedpassword",
-			wantErr:      true,
+			name:     "empty username",
+			username: "",
+			password: "password123",
+			wantErr:  true,
 		},
 		{
-			name:         "empty password hash",
-			username:     "user2",
-			passwordHash: "",
-			wantErr:      true,
+			name:     "empty password",
+			username: "user2",
+			password: "",
+			wantErr:  true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ctx := context.Background()
-			userID, err := store.CreateUser(ctx, tt.username, tt.passwordHash)
+			err := store.CreateUser(tt.username, tt.password, nil)
 
 			if tt.wantErr {
 				if err == nil {
@@ -63,114 +62,95 @@ func TestStore_CreateUser(t *testing.T) {
 
 			if err != nil {
 				t.Errorf("CreateUser() unexpected error = %v", err)
-				return
-			}
-
-			if userID <= 0 {
-				t.Errorf("CreateUser() returned invalid userID = %v", userID)
 			}
 		})
 	}
 }
 
-func TestStore_ValidateCredentials(t *testing.T) {
+func TestStore_Authenticate(t *testing.T) {
 	store := setupTestStore(t)
 	defer cleanupTestStore(t, store)
 
-	ctx := context.Background()
-
 	// Create test user with known password
 	username := "testuser"
 	password := "correctpassword"
-	passwordHash := hashPassword(t, password)
 
-	userID, err := store.CreateUser(ctx, username, passwordHash)
-	if err != nil {
+	if err := store.CreateUser(username, password, nil); err != nil {
 		t.Fatalf("Failed to create test user: %v", err)
 	}
 
 	tests := []struct {
-		name        string
-		username    string
-		password    string
-		wantUserID  int64
-		wantToken   bool
-		wantErr     bool
+		name      string
+		username  string
+		password  string
+		wantToken bool
+		wantErr   bool
 	}{
 		{
-			name:       "valid credentials",
-			username:   username,
-			password:   password,
-			wantUserID: userID,
-			wantToken:  true,
-			wantErr:    false,
+			name:      "valid credentials",
+			username:  username,
+			password:  password,
+			wantToken: true,
+			wantErr:   false,
 		},
 		{
-			name:       "invalid password",
-			username:   username,
-			password:   "wrongpassword",
-			wantUserID: 0,
-			wantToken:  false,
-			wantErr:    true,
+			name:      "invalid password",
+			username:  username,
+			password:  "wrongpassword",
+			wantToken: false,
+			wantErr:   true,
 		},
 		{
-			name:       "invalid username",
-			username:   "nonexistent",
-			password:   password,
-			wantUserID: 0,
-			wantToken:  false,
-			wantErr:    true,
+			name:      "invalid username",
+			username:  "nonexistent",
+			password:  password,
+			wantToken: false,
+			wantErr:   true,
 		},
 		{
-			name:       "empty username",
-			username:   "",
-			password:   password,
-			wantUserID: 0,
-			wantToken:  false,
-			wantErr:    true,
+			name:      "empty username",
+			username:  "",
+			password:  password,
+			wantToken: false,
+			wantErr:   true,
 		},
 		{
-			name:       "empty password",
-			username:   username,
-			password:   "",
-			wantUserID: 0,
-			wantToken:  false,
-			wantErr:    true,
+			name:      "empty password",
+			username:  username,
+			password:  "",
+			wantToken: false,
+			wantErr:   true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			token, gotUserID, err := store.ValidateCredentials(ctx, tt.username, tt.password)
+			tokens, err := store.Authenticate(tt.username, tt.password, SessionMetadata{UserAgent: "test"})
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("ValidateCredentials() expected error, got nil")
+					t.Errorf("Authenticate() expected error, got nil")
 				}
 				return
 			}
 
 			if err != nil {
-				t.Errorf("ValidateCredentials() unexpected error = %v", err)
+				t.Errorf("Authenticate() unexpected error = %v", err)
 				return
 			}
 
-			if gotUserID != tt.wantUserID {
-				t.Errorf("ValidateCredentials() userID = %v, want %v", gotUserID, tt.wantUserID)
+			if tt.wantToken && (tokens.AccessToken == "" || tokens.RefreshToken == "") {
+				t.Errorf("Authenticate() expected access and refresh tokens, got %+v", tokens)
 			}
 
-			if tt.wantToken && token == "" {
-				t.Errorf("ValidateCredentials() expected token, got empty string")
-			}
-
-			if !tt.wantToken && token != "" {
-				t.Errorf("ValidateCredentials() expected no token, got %v", token)
+			if !tt.wantToken && tokens.AccessToken != "" {
+				t.Errorf("Authenticate() expected no token, got %v", tokens.AccessToken)
 			}
 		})
 	}
 }
 
-func TestStore_ValidateToken(t *testing.T) {
+func TestStore_UserIDByToken(t *testing.T) {
 	store := setupTestStore(t)
 	defer cleanupTestStore(t, store)
 
@@ -179,131 +159,143 @@ func TestStore_ValidateToken(t *testing.T) {
 	// Create test user and get token
 	username := "testuser"
 	password := "password123"
-	passwordHash := hashPassword(t, password)
 
-	userID, err := store.CreateUser(ctx, username, passwordHash)
-	if err != nil {
+	if err := store.CreateUser(username, password, nil); err != nil {
 		t.Fatalf("Failed to create test user: %v", err)
 	}
 
-	token, _, err := store.ValidateCredentials(ctx, username, password)
+	tokens, err := store.Authenticate(username, password, SessionMetadata{})
 	if err != nil {
 		t.Fatalf("Failed to get token: %v", err)
 	}
+	token := tokens.AccessToken
 
 	tests := []struct {
-		name       string
-		token      string
-		wantUserID int64
-		wantErr    bool
+		name    string
+		token   string
+		wantErr bool
 	}{
 		{
-			name:       "valid token",
-			token:      token,
-			wantUserID: userID,
-			wantErr:    false,
+			name:    "valid token",
+			token:   token,
+			wantErr: false,
 		},
 		{
-			name:       "invalid token",
-			token:      "invalid-token-12345",
-			wantUserID: 0,
-			wantErr:    true,
+			name:    "invalid token",
+			token:   "invalid-token-12345",
+			wantErr: true,
 		},
 		{
-			name:       "empty token",
-			token:      "",
-			wantUserID: 0,
-			wantErr:    true,
+			name:    "empty token",
+			token:   "",
+			wantErr: true,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotUserID, err := store.ValidateToken(ctx, tt.token)
+			gotUserID, err := store.UserIDByToken(ctx, tt.token)
 
 			if tt.wantErr {
 				if err == nil {
-					t.Errorf("ValidateToken() expected error, got nil")
+					t.Errorf("UserIDByToken() expected error, got nil")
 				}
 				return
 			}
 
 			if err != nil {
-				t.Errorf("ValidateToken() unexpected error = %v", err)
+				t.Errorf("UserIDByToken() unexpected error = %v", err)
 				return
 			}
 
-			if gotUserID != tt.wantUserID {
-				t.Errorf("ValidateToken() userID = %v, want %v", gotUserID, tt.wantUserID)
+			if gotUserID <= 0 {
+				t.Errorf("UserIDByToken() returned invalid userID = %v", gotUserID)
 			}
 		})
 	}
 }
 
-func TestStore_CreateSession(t *testing.T) {
+func TestStore_SessionLifecycle(t *testing.T) {
 	store := setupTestStore(t)
 	defer cleanupTestStore(t, store)
 
 	ctx := context.Background()
 
-	// Create test user
-	userID, err := store.CreateUser(ctx, "testuser", hashPassword(t, "password"))
-	if err != nil {
+	if err := store.CreateUser("testuser", "password", nil); err != nil {
 		t.Fatalf("Failed to create test user: %v", err)
 	}
 
-	tests := []struct {
-		name      string
-		token     string
-		userID    int64
-		expiresAt time.Time
-		wantErr   bool
-	}{
-		{
-			name:      "valid session",
-			token:     "valid-token-123",
-			userID:    userID,
-			expiresAt: time.Now().Add(24 * time.Hour),
-			wantErr:   false,
-		},
-		{
-			name:      "duplicate token",
-			token:     "valid-token-123",
-			userID:    userID,
-			expiresAt: time.Now().Add(24 * time.Hour),
-			wantErr:   true,
-		},
-		{
-			name:      "invalid user ID",
-			token:     "another-token",
-			userID:    99999,
-			expiresAt: time.Now().Add(24 * time.Hour),
-			wantErr:   true,
-		},
-		{
-			name:      "expired session",
-			token:     "expired-token",
-			userID:    userID,
-			expiresAt: time.Now().Add(-1 * time.Hour),
-			wantErr:   false, // Creation should succeed, validation should fail
-		},
+	firstTokens, err := store.Authenticate("testuser", "password", SessionMetadata{UserAgent: "laptop", IPAddress: "10.0.0.1"})
+	if err != nil {
+		t.Fatalf("Failed to get first token: %v", err)
+	}
+	secondTokens, err := store.Authenticate("testuser", "password", SessionMetadata{UserAgent: "phone", IPAddress: "10.0.0.2"})
+	if err != nil {
+		t.Fatalf("Failed to get second token: %v", err)
 	}
+	first, second := firstTokens.AccessToken, secondTokens.AccessToken
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			err := store.CreateSession(ctx, tt.token, tt.userID, tt.expiresAt)
+	sessions, err := store.SessionsByToken(ctx, first)
+	if err != nil {
+		t.Fatalf("SessionsByToken() unexpected error = %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("SessionsByToken() returned %d sessions, want 2", len(sessions))
+	}
 
-			if tt.wantErr {
-				if err == nil {
-					t.Errorf("CreateSession() expected error, got nil")
-				}
-				return
-			}
+	revoked, err := store.RevokeOtherSessions(ctx, first)
+	if err != nil {
+		t.Fatalf("RevokeOtherSessions() unexpected error = %v", err)
+	}
+	if revoked != 1 {
+		t.Errorf("RevokeOtherSessions() revoked %d sessions, want 1", revoked)
+	}
+	if _, err := store.UserIDByToken(ctx, second); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("UserIDByToken() for revoked session error = %v, want ErrUnauthorized", err)
+	}
 
-			if err != nil {
-				t.Errorf("CreateSession() unexpected error = %v", err)
-			}
-		})
+	if err := store.Logout(ctx, first); err != nil {
+		t.Fatalf("Logout() unexpected error = %v", err)
+	}
+	if _, err := store.UserIDByToken(ctx, first); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("UserIDByToken() after logout error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestStore_RefreshRotation(t *testing.T) {
+	store := setupTestStore(t)
+	defer cleanupTestStore(t, store)
+
+	ctx := context.Background()
+
+	if err := store.CreateUser("testuser", "password", nil); err != nil {
+		t.Fatalf("Failed to create test user: %v", err)
+	}
+
+	initial, err := store.Authenticate("testuser", "password", SessionMetadata{})
+	if err != nil {
+		t.Fatalf("Authenticate() unexpected error = %v", err)
+	}
+
+	rotated, err := store.RefreshSession(ctx, initial.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshSession() unexpected error = %v", err)
+	}
+	if rotated.AccessToken == initial.AccessToken || rotated.RefreshToken == initial.RefreshToken {
+		t.Fatalf("RefreshSession() did not rotate tokens")
+	}
+	if _, err := store.UserIDByToken(ctx, initial.AccessToken); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("UserIDByToken() for replaced access token error = %v, want ErrUnauthorized", err)
+	}
+
+	if _, err := store.RefreshSession(ctx, initial.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("RefreshSession() with reused token error = %v, want ErrRefreshTokenReused", err)
+	}
+	if _, err := store.UserIDByToken(ctx, rotated.AccessToken); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("UserIDByToken() after reuse error = %v, want ErrUnauthorized", err)
+	}
+	if _, err := store.RefreshSession(ctx, rotated.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("RefreshSession() after family revocation error = %v, want ErrInvalidRefreshToken", err)
 	}
 }
 
@@ -320,11 +312,6 @@ func cleanupTestStore(t *testing.T, store *Store) {
 	// Clean up test data
 }
 
-func hashPassword(t *testing.T, password string) string {
-	// Use bcrypt or test hash
-	return "$2a$10$" + password
-}
-
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && s[:len(substr)] == substr ||
 		   len(s) > len(substr) && contains(s[1:], substr)
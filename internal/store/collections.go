@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"vinylhound/internal/queryspec"
 	"vinylhound/shared/go/models"
 )
 
@@ -42,6 +43,9 @@ func (s *Store) AddToCollection(ctx context.Context, token string, collection *m
 	if !albumExists {
 		return nil, errors.New("album not found")
 	}
+	if err := s.checkReleaseOfAlbum(ctx, collection.ReleaseID, collection.AlbumID); err != nil {
+		return nil, err
+	}
 
 	now := time.Now().UTC()
 	collection.UserID = userID
@@ -67,10 +71,10 @@ func (s *Store) AddToCollection(ctx context.Context, token string, collection *m
 	}
 
 	err = s.db.QueryRowContext(ctx, `
-		INSERT INTO album_collections (user_id, album_id, collection_type, notes, date_added, date_acquired, purchase_price, condition, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+		INSERT INTO album_collections (user_id, album_id, release_id, collection_type, notes, date_added, date_acquired, purchase_price, condition, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
 		RETURNING id, date_added, created_at, updated_at`,
-		userID, collection.AlbumID, collection.CollectionType, notes, now, dateAcquired, purchasePrice, condition, now,
+		userID, collection.AlbumID, collection.ReleaseID, collection.CollectionType, notes, now, dateAcquired, purchasePrice, condition, now,
 	).Scan(&collection.ID, &collection.DateAdded, &collection.CreatedAt, &collection.UpdatedAt)
 
 	if err != nil {
@@ -83,32 +87,84 @@ func (s *Store) AddToCollection(ctx context.Context, token string, collection *m
 	return collection, nil
 }
 
-// ListCollection returns all items in a user's collection with optional filtering
-func (s *Store) ListCollection(ctx context.Context, token string, filter models.CollectionFilter) ([]*models.AlbumCollectionWithDetails, error) {
+// collectionList lists collection items most recently added first and
+// whitelists the fields of their query specs.
+var collectionList = listSpec[*models.AlbumCollectionWithDetails]{
+	keyset: keyset{name: "collection", columns: []keyColumn{
+		{expr: "ac.date_added", desc: true, kind: keyTime},
+		{expr: "ac.id", desc: true, kind: keyInt},
+	}},
+	key: func(item *models.AlbumCollectionWithDetails) []any {
+		return []any{item.DateAdded, item.ID}
+	},
+	id:    keyColumn{expr: "ac.id", desc: true, kind: keyInt},
+	idKey: func(item *models.AlbumCollectionWithDetails) any { return item.ID },
+	fields: map[string]listField[*models.AlbumCollectionWithDetails]{
+		"type": {expr: "ac.collection_type", kind: keyString, json: "collection_type",
+			key: func(item *models.AlbumCollectionWithDetails) any { return string(item.CollectionType) }},
+		"condition": {expr: "COALESCE(ac.condition, '')", kind: keyString, json: "condition",
+			key: func(item *models.AlbumCollectionWithDetails) any {
+				if item.Condition == nil {
+					return ""
+				}
+				return string(*item.Condition)
+			}},
+		"date_added": {expr: "ac.date_added", kind: keyTime, json: "date_added",
+			key: func(item *models.AlbumCollectionWithDetails) any { return item.DateAdded }},
+		"purchase_price": {expr: "COALESCE(ac.purchase_price, 0)", kind: keyFloat, json: "purchase_price",
+			key: func(item *models.AlbumCollectionWithDetails) any {
+				if item.PurchasePrice == nil {
+					return 0.0
+				}
+				return *item.PurchasePrice
+			}},
+		"title": {expr: "a.title", kind: keyString, json: "album_title",
+			key: func(item *models.AlbumCollectionWithDetails) any { return item.AlbumTitle }},
+		"artist": {expr: "a.artist", kind: keyString, json: "album_artist",
+			key: func(item *models.AlbumCollectionWithDetails) any { return item.AlbumArtist }},
+		"release_year": {expr: "a.release_year", kind: keyInt, json: "album_release_year",
+			key: func(item *models.AlbumCollectionWithDetails) any { return item.AlbumReleaseYear }},
+		"year": {expr: "a.release_year", kind: keyInt, json: "album_release_year",
+			key: func(item *models.AlbumCollectionWithDetails) any { return item.AlbumReleaseYear }},
+		"genre": {expr: "COALESCE(a.genres->>0, '')", kind: keyString, json: "album_genre",
+			key: func(item *models.AlbumCollectionWithDetails) any { return item.AlbumGenre }},
+		"album_id":      {json: "album_id"},
+		"release_id":    {json: "release_id"},
+		"release":       {json: "release"},
+		"notes":         {json: "notes"},
+		"date_acquired": {json: "date_acquired"},
+		"cover_url":     {json: "album_cover_url"},
+	},
+}
+
+// CollectionQuery checks the query spec of a collection against its whitelist and
+// returns the response keys its fields= selects.
+func CollectionQuery(spec queryspec.Spec) ([]string, error) {
+	return collectionList.check(spec)
+}
+
+// ListCollection returns one page of a user's collection, filtered by
+// substring and by spec, and sorted by spec.
+func (s *Store) ListCollection(ctx context.Context, token string, filter models.CollectionFilter, spec queryspec.Spec, page Page) ([]*models.AlbumCollectionWithDetails, string, error) {
 	userID, err := s.UserIDByToken(ctx, token)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	query := `
 		SELECT
-			ac.id, ac.user_id, ac.album_id, ac.collection_type,
+			ac.id, ac.user_id, ac.album_id, ac.release_id, ac.collection_type,
 			COALESCE(ac.notes, ''), ac.date_added, ac.date_acquired, ac.purchase_price, ac.condition,
 			ac.created_at, ac.updated_at,
-			a.title, a.artist, a.release_year, COALESCE(a.genre, ''), COALESCE(a.cover_url, '')
+			a.title, a.artist, a.release_year, COALESCE(a.genres->>0, ''), COALESCE(cm.hash, '')
 		FROM album_collections ac
 		JOIN albums a ON ac.album_id = a.id
+		LEFT JOIN media cm ON cm.id = a.cover_media_id
 		WHERE ac.user_id = $1`
 
 	args := []interface{}{userID}
 	argPos := 2
 
-	if filter.CollectionType != nil {
-		query += fmt.Sprintf(" AND ac.collection_type = $%d", argPos)
-		args = append(args, string(*filter.CollectionType))
-		argPos++
-	}
-
 	if filter.Artist != "" {
 		query += fmt.Sprintf(" AND LOWER(a.artist) LIKE $%d", argPos)
 		args = append(args, "%"+strings.ToLower(filter.Artist)+"%")
@@ -116,73 +172,60 @@ func (s *Store) ListCollection(ctx context.Context, token string, filter models.
 	}
 
 	if filter.Genre != "" {
-		query += fmt.Sprintf(" AND LOWER(a.genre) LIKE $%d", argPos)
-		args = append(args, "%"+strings.ToLower(filter.Genre)+"%")
-		argPos++
-	}
-
-	if filter.YearFrom != nil {
-		query += fmt.Sprintf(" AND a.release_year >= $%d", argPos)
-		args = append(args, *filter.YearFrom)
-		argPos++
-	}
-
-	if filter.YearTo != nil {
-		query += fmt.Sprintf(" AND a.release_year <= $%d", argPos)
-		args = append(args, *filter.YearTo)
-		argPos++
-	}
-
-	if filter.Condition != nil {
-		query += fmt.Sprintf(" AND ac.condition = $%d", argPos)
-		args = append(args, string(*filter.Condition))
+		query += " AND " + genreMatch("a.genres", argPos)
+		args = append(args, filter.Genre)
 		argPos++
 	}
 
 	if filter.SearchTerm != "" {
 		query += fmt.Sprintf(" AND (LOWER(a.title) LIKE $%d OR LOWER(a.artist) LIKE $%d OR LOWER(ac.notes) LIKE $%d)", argPos, argPos, argPos)
 		args = append(args, "%"+strings.ToLower(filter.SearchTerm)+"%")
-		argPos++
 	}
 
-	query += " ORDER BY ac.date_added DESC"
-
-	if filter.Limit > 0 {
-		query += fmt.Sprintf(" LIMIT $%d", argPos)
-		args = append(args, filter.Limit)
-		argPos++
+	listQ, args, err := collectionList.compile(spec, args)
+	if err != nil {
+		return nil, "", err
 	}
-
-	if filter.Offset > 0 {
-		query += fmt.Sprintf(" OFFSET $%d", argPos)
-		args = append(args, filter.Offset)
+	pageQ, err := listQ.keyset.page(page)
+	if err != nil {
+		return nil, "", err
 	}
 
+	after, args := pageQ.where(args)
+	tail, args := pageQ.orderLimit(args)
+	query += " AND " + listQ.where() + " AND " + after + " " + tail
+
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("list collection: %w", err)
+		return nil, "", fmt.Errorf("list collection: %w", err)
 	}
 	defer rows.Close()
 
 	var items []*models.AlbumCollectionWithDetails
 	for rows.Next() {
 		var item models.AlbumCollectionWithDetails
-		var notes, condition, coverURL sql.NullString
+		var notes, condition, coverHash sql.NullString
 		var dateAcquired sql.NullTime
 		var purchasePrice sql.NullFloat64
+		var releaseID sql.NullInt64
 
 		err := rows.Scan(
-			&item.ID, &item.UserID, &item.AlbumID, &item.CollectionType,
+			&item.ID, &item.UserID, &item.AlbumID, &releaseID, &item.CollectionType,
 			&notes, &item.DateAdded, &dateAcquired, &purchasePrice, &condition,
 			&item.CreatedAt, &item.UpdatedAt,
-			&item.AlbumTitle, &item.AlbumArtist, &item.AlbumReleaseYear, &item.AlbumGenre, &coverURL,
+			&item.AlbumTitle, &item.AlbumArtist, &item.AlbumReleaseYear, &item.AlbumGenre, &coverHash,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("scan collection item: %w", err)
+			return nil, "", fmt.Errorf("scan collection item: %w", err)
 		}
 
 		item.Notes = notes.String
-		item.AlbumCoverURL = coverURL.String
+		if coverHash.String != "" {
+			item.AlbumCoverURL = MediaPath(coverHash.String, "")
+		}
+		if releaseID.Valid {
+			item.ReleaseID = &releaseID.Int64
+		}
 		if dateAcquired.Valid {
 			item.DateAcquired = &dateAcquired.Time
 		}
@@ -198,32 +241,39 @@ func (s *Store) ListCollection(ctx context.Context, token string, filter models.
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate collection: %w", err)
+		return nil, "", fmt.Errorf("iterate collection: %w", err)
+	}
+
+	items, next := finishPage(pageQ, items, listQ.key)
+	if err := s.attachReleases(ctx, items); err != nil {
+		return nil, "", err
 	}
 
-	return items, nil
+	return items, next, nil
 }
 
 // GetCollectionItem returns a single collection item by ID
 func (s *Store) GetCollectionItem(ctx context.Context, id int64) (*models.AlbumCollectionWithDetails, error) {
 	var item models.AlbumCollectionWithDetails
-	var notes, condition, coverURL sql.NullString
+	var notes, condition, coverHash sql.NullString
 	var dateAcquired sql.NullTime
 	var purchasePrice sql.NullFloat64
+	var releaseID sql.NullInt64
 
 	err := s.db.QueryRowContext(ctx, `
 		SELECT
-			ac.id, ac.user_id, ac.album_id, ac.collection_type,
+			ac.id, ac.user_id, ac.album_id, ac.release_id, ac.collection_type,
 			COALESCE(ac.notes, ''), ac.date_added, ac.date_acquired, ac.purchase_price, ac.condition,
 			ac.created_at, ac.updated_at,
-			a.title, a.artist, a.release_year, COALESCE(a.genre, ''), COALESCE(a.cover_url, '')
+			a.title, a.artist, a.release_year, COALESCE(a.genres->>0, ''), COALESCE(cm.hash, '')
 		FROM album_collections ac
 		JOIN albums a ON ac.album_id = a.id
+		LEFT JOIN media cm ON cm.id = a.cover_media_id
 		WHERE ac.id = $1`, id).Scan(
-		&item.ID, &item.UserID, &item.AlbumID, &item.CollectionType,
+		&item.ID, &item.UserID, &item.AlbumID, &releaseID, &item.CollectionType,
 		&notes, &item.DateAdded, &dateAcquired, &purchasePrice, &condition,
 		&item.CreatedAt, &item.UpdatedAt,
-		&item.AlbumTitle, &item.AlbumArtist, &item.AlbumReleaseYear, &item.AlbumGenre, &coverURL,
+		&item.AlbumTitle, &item.AlbumArtist, &item.AlbumReleaseYear, &item.AlbumGenre, &coverHash,
 	)
 
 	if errors.Is(err, sql.ErrNoRows) {
@@ -234,7 +284,12 @@ func (s *Store) GetCollectionItem(ctx context.Context, id int64) (*models.AlbumC
 	}
 
 	item.Notes = notes.String
-	item.AlbumCoverURL = coverURL.String
+	if coverHash.String != "" {
+		item.AlbumCoverURL = MediaPath(coverHash.String, "")
+	}
+	if releaseID.Valid {
+		item.ReleaseID = &releaseID.Int64
+	}
 	if dateAcquired.Valid {
 		item.DateAcquired = &dateAcquired.Time
 	}
@@ -246,6 +301,10 @@ func (s *Store) GetCollectionItem(ctx context.Context, id int64) (*models.AlbumC
 		item.Condition = &cond
 	}
 
+	if err := s.attachReleases(ctx, []*models.AlbumCollectionWithDetails{&item}); err != nil {
+		return nil, err
+	}
+
 	return &item, nil
 }
 
@@ -261,8 +320,8 @@ func (s *Store) UpdateCollectionItem(ctx context.Context, token string, id int64
 	}
 
 	// Verify ownership
-	var ownerID int64
-	err = s.db.QueryRowContext(ctx, `SELECT user_id FROM album_collections WHERE id = $1`, id).Scan(&ownerID)
+	var ownerID, albumID int64
+	err = s.db.QueryRowContext(ctx, `SELECT user_id, album_id FROM album_collections WHERE id = $1`, id).Scan(&ownerID, &albumID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, ErrCollectionNotFound
 	}
@@ -272,6 +331,9 @@ func (s *Store) UpdateCollectionItem(ctx context.Context, token string, id int64
 	if ownerID != userID {
 		return nil, errors.New("not authorized to modify this collection item")
 	}
+	if err := s.checkReleaseOfAlbum(ctx, collection.ReleaseID, albumID); err != nil {
+		return nil, err
+	}
 
 	var notes, condition sql.NullString
 	var dateAcquired sql.NullTime
@@ -292,11 +354,14 @@ func (s *Store) UpdateCollectionItem(ctx context.Context, token string, id int64
 
 	res, err := s.db.ExecContext(ctx, `
 		UPDATE album_collections
-		SET notes = $1, date_acquired = $2, purchase_price = $3, condition = $4, updated_at = $5
-		WHERE id = $6 AND user_id = $7`,
-		notes, dateAcquired, purchasePrice, condition, time.Now().UTC(), id, userID)
+		SET notes = $1, date_acquired = $2, purchase_price = $3, condition = $4, release_id = $5, updated_at = $6
+		WHERE id = $7 AND user_id = $8`,
+		notes, dateAcquired, purchasePrice, condition, collection.ReleaseID, time.Now().UTC(), id, userID)
 
 	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrAlreadyInCollection
+		}
 		return nil, fmt.Errorf("update collection item: %w", err)
 	}
 
@@ -310,11 +375,12 @@ func (s *Store) UpdateCollectionItem(ctx context.Context, token string, id int64
 
 	// Return updated collection item
 	var updated models.AlbumCollection
+	var releaseID sql.NullInt64
 	err = s.db.QueryRowContext(ctx, `
-		SELECT id, user_id, album_id, collection_type, COALESCE(notes, ''), date_added, date_acquired, purchase_price, condition, created_at, updated_at
+		SELECT id, user_id, album_id, release_id, collection_type, COALESCE(notes, ''), date_added, date_acquired, purchase_price, condition, created_at, updated_at
 		FROM album_collections
 		WHERE id = $1`, id).Scan(
-		&updated.ID, &updated.UserID, &updated.AlbumID, &updated.CollectionType,
+		&updated.ID, &updated.UserID, &updated.AlbumID, &releaseID, &updated.CollectionType,
 		&notes, &updated.DateAdded, &dateAcquired, &purchasePrice, &condition,
 		&updated.CreatedAt, &updated.UpdatedAt,
 	)
@@ -323,6 +389,9 @@ func (s *Store) UpdateCollectionItem(ctx context.Context, token string, id int64
 	}
 
 	updated.Notes = notes.String
+	if releaseID.Valid {
+		updated.ReleaseID = &releaseID.Int64
+	}
 	if dateAcquired.Valid {
 		updated.DateAcquired = &dateAcquired.Time
 	}
@@ -409,6 +478,7 @@ func (s *Store) GetCollectionStats(ctx context.Context, token string) (*models.C
 	stats := &models.CollectionStats{
 		ByGenre:     make(map[string]int),
 		ByCondition: make(map[string]int),
+		ByFormat:    make(map[string]int),
 	}
 
 	// Count totals by collection type
@@ -425,11 +495,13 @@ func (s *Store) GetCollectionStats(ctx context.Context, token string) (*models.C
 
 	// Get genre breakdown for owned albums
 	genreRows, err := s.db.QueryContext(ctx, `
-		SELECT a.genre, COUNT(*) as count
+		SELECT COALESCE(g.name, e.name) AS genre, COUNT(DISTINCT ac.id) as count
 		FROM album_collections ac
 		JOIN albums a ON ac.album_id = a.id
-		WHERE ac.user_id = $1 AND ac.collection_type = 'owned' AND a.genre != ''
-		GROUP BY a.genre
+		CROSS JOIN LATERAL jsonb_array_elements_text(a.genres) AS e(name)
+		LEFT JOIN genres g ON g.id = genre_id_for(e.name)
+		WHERE ac.user_id = $1 AND ac.collection_type = 'owned' AND e.name != ''
+		GROUP BY 1
 		ORDER BY count DESC`, userID)
 	if err != nil {
 		return nil, fmt.Errorf("get genre stats: %w", err)
@@ -466,5 +538,71 @@ func (s *Store) GetCollectionStats(ctx context.Context, token string) (*models.C
 		stats.ByCondition[condition] = count
 	}
 
+	// Get format breakdown for owned releases
+	formatRows, err := s.db.QueryContext(ctx, `
+		SELECT r.format, COUNT(*) as count
+		FROM album_collections ac
+		JOIN album_releases r ON ac.release_id = r.id
+		WHERE ac.user_id = $1 AND ac.collection_type = 'owned'
+		GROUP BY r.format
+		ORDER BY count DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get format stats: %w", err)
+	}
+	defer formatRows.Close()
+
+	for formatRows.Next() {
+		var format string
+		var count int
+		if err := formatRows.Scan(&format, &count); err != nil {
+			return nil, fmt.Errorf("scan format stat: %w", err)
+		}
+		stats.ByFormat[format] = count
+	}
+
 	return stats, nil
 }
+
+// attachReleases loads the releases collection items refer to
+func (s *Store) attachReleases(ctx context.Context, items []*models.AlbumCollectionWithDetails) error {
+	var ids []any
+	seen := make(map[int64]bool)
+	for _, item := range items {
+		if item.ReleaseID != nil && !seen[*item.ReleaseID] {
+			seen[*item.ReleaseID] = true
+			ids = append(ids, *item.ReleaseID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT %s FROM album_releases WHERE id IN (%s)`,
+		releaseColumns, strings.Join(buildPlaceholders(len(ids), 1), ", ")), ids...)
+	if err != nil {
+		return fmt.Errorf("select collection releases: %w", err)
+	}
+	defer rows.Close()
+
+	releases := make(map[int64]models.Release, len(ids))
+	for rows.Next() {
+		release, err := scanRelease(rows)
+		if err != nil {
+			return err
+		}
+		releases[release.ID] = release
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate collection releases: %w", err)
+	}
+
+	for _, item := range items {
+		if item.ReleaseID == nil {
+			continue
+		}
+		if release, ok := releases[*item.ReleaseID]; ok {
+			item.Release = &release
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 
+	"vinylhound/internal/queryspec"
 	"vinylhound/shared/go/models"
 )
 
@@ -40,48 +41,14 @@ func (s *Store) CreateConcert(ctx context.Context, token string, concert *models
 	return concert, nil
 }
 
-// ListConcertsByUser returns all concerts for a user, optionally with venue details
-func (s *Store) ListConcertsByUser(ctx context.Context, token string, includeVenue bool) ([]*models.ConcertWithDetails, error) {
+// ListConcertsByUser returns one page of a user's concerts with venue
+// details, filtered by spec and sorted by it or newest first
+func (s *Store) ListConcertsByUser(ctx context.Context, token string, includeVenue bool, spec queryspec.Spec, page Page) ([]*models.ConcertWithDetails, string, error) {
 	userID, err := s.UserIDByToken(ctx, token)
 	if err != nil {
-		return nil, err
-	}
-
-	query := `
-		SELECT
-			c.id, c.user_id, c.venue_id, c.artist_name, c.name, c.date,
-			c.ticket_price, c.notes, c.attended, c.rating,
-			c.created_at, c.updated_at,
-			v.name as venue_name, v.address as venue_address,
-			v.city as venue_city, v.state as venue_state
-		FROM concerts c
-		INNER JOIN venues v ON c.venue_id = v.id
-		WHERE c.user_id = $1
-		ORDER BY c.date DESC
-	`
-
-	rows, err := s.db.QueryContext(ctx, query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var concerts []*models.ConcertWithDetails
-	for rows.Next() {
-		var c models.ConcertWithDetails
-		err := rows.Scan(
-			&c.ID, &c.UserID, &c.VenueID, &c.ArtistName, &c.Name, &c.Date,
-			&c.TicketPrice, &c.Notes, &c.Attended, &c.Rating,
-			&c.CreatedAt, &c.UpdatedAt,
-			&c.VenueName, &c.VenueAddress, &c.VenueCity, &c.VenueState,
-		)
-		if err != nil {
-			return nil, err
-		}
-		concerts = append(concerts, &c)
+		return nil, "", err
 	}
-
-	return concerts, rows.Err()
+	return s.listConcerts(ctx, "c.user_id = $1", []any{userID}, spec, page)
 }
 
 // GetConcert retrieves a single concert by ID with venue details
@@ -178,96 +145,107 @@ func (s *Store) DeleteConcert(ctx context.Context, token string, id int64) error
 	return nil
 }
 
-// ListUpcomingConcerts returns future concerts for a user
-func (s *Store) ListUpcomingConcerts(ctx context.Context, token string) ([]*models.ConcertWithDetails, error) {
+// ListConcertsByVenue returns one page of concerts at a specific venue,
+// filtered and sorted by spec
+func (s *Store) ListConcertsByVenue(ctx context.Context, venueID int64, spec queryspec.Spec, page Page) ([]*models.ConcertWithDetails, string, error) {
+	return s.listConcerts(ctx, "c.venue_id = $1", []any{venueID}, spec, page)
+}
+
+// MarkConcertAttended marks a concert as attended and optionally adds a rating
+func (s *Store) MarkConcertAttended(ctx context.Context, token string, concertID int64, rating *int) error {
 	userID, err := s.UserIDByToken(ctx, token)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	query := `
-		SELECT
-			c.id, c.user_id, c.venue_id, c.artist_name, c.name, c.date,
-			c.ticket_price, c.notes, c.attended, c.rating,
-			c.created_at, c.updated_at,
-			v.name as venue_name, v.address as venue_address,
-			v.city as venue_city, v.state as venue_state
-		FROM concerts c
-		INNER JOIN venues v ON c.venue_id = v.id
-		WHERE c.user_id = $1 AND c.date >= CURRENT_TIMESTAMP
-		ORDER BY c.date ASC
+		UPDATE concerts
+		SET attended = TRUE, rating = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND user_id = $3
 	`
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
+	result, err := s.db.ExecContext(ctx, query, rating, concertID, userID)
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
 
-	var concerts []*models.ConcertWithDetails
-	for rows.Next() {
-		var c models.ConcertWithDetails
-		err := rows.Scan(
-			&c.ID, &c.UserID, &c.VenueID, &c.ArtistName, &c.Name, &c.Date,
-			&c.TicketPrice, &c.Notes, &c.Attended, &c.Rating,
-			&c.CreatedAt, &c.UpdatedAt,
-			&c.VenueName, &c.VenueAddress, &c.VenueCity, &c.VenueState,
-		)
-		if err != nil {
-			return nil, err
-		}
-		concerts = append(concerts, &c)
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrConcertNotFound
 	}
 
-	return concerts, rows.Err()
+	return nil
 }
 
-// ListConcertsByVenue returns all concerts at a specific venue
-func (s *Store) ListConcertsByVenue(ctx context.Context, venueID int64) ([]*models.ConcertWithDetails, error) {
-	query := `
-		SELECT
-			c.id, c.user_id, c.venue_id, c.artist_name, c.name, c.date,
-			c.ticket_price, c.notes, c.attended, c.rating,
-			c.created_at, c.updated_at,
-			v.name as venue_name, v.address as venue_address,
-			v.city as venue_city, v.state as venue_state
-		FROM concerts c
-		INNER JOIN venues v ON c.venue_id = v.id
-		WHERE c.venue_id = $1
-		ORDER BY c.date DESC
-	`
-
-	rows, err := s.db.QueryContext(ctx, query, venueID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var concerts []*models.ConcertWithDetails
-	for rows.Next() {
-		var c models.ConcertWithDetails
-		err := rows.Scan(
-			&c.ID, &c.UserID, &c.VenueID, &c.ArtistName, &c.Name, &c.Date,
-			&c.TicketPrice, &c.Notes, &c.Attended, &c.Rating,
-			&c.CreatedAt, &c.UpdatedAt,
-			&c.VenueName, &c.VenueAddress, &c.VenueCity, &c.VenueState,
-		)
-		if err != nil {
-			return nil, err
-		}
-		concerts = append(concerts, &c)
-	}
+// concertList lists concerts newest first and whitelists the fields of their
+// query specs.
+var concertList = listSpec[*models.ConcertWithDetails]{
+	keyset: keyset{name: "concerts", columns: []keyColumn{
+		{expr: "c.date", desc: true, kind: keyTime},
+		{expr: "c.id", desc: true, kind: keyInt},
+	}},
+	key:   func(c *models.ConcertWithDetails) []any { return []any{c.Date, c.ID} },
+	id:    keyColumn{expr: "c.id", desc: true, kind: keyInt},
+	idKey: func(c *models.ConcertWithDetails) any { return c.ID },
+	fields: map[string]listField[*models.ConcertWithDetails]{
+		"date": {expr: "c.date", kind: keyTime, json: "date",
+			key: func(c *models.ConcertWithDetails) any { return c.Date }},
+		"artist": {expr: "c.artist_name", kind: keyString, json: "artist_name",
+			key: func(c *models.ConcertWithDetails) any { return c.ArtistName }},
+		"name": {expr: "c.name", kind: keyString, json: "name",
+			key: func(c *models.ConcertWithDetails) any { return c.Name }},
+		"rating": {expr: "COALESCE(c.rating, 0)", kind: keyInt, json: "rating",
+			key: func(c *models.ConcertWithDetails) any {
+				if c.Rating == nil {
+					return 0
+				}
+				return *c.Rating
+			}},
+		"attended": {expr: "c.attended", kind: keyBool, json: "attended",
+			key: func(c *models.ConcertWithDetails) any { return c.Attended }},
+		"ticket_price": {expr: "COALESCE(c.ticket_price, 0)", kind: keyFloat, json: "ticket_price",
+			key: func(c *models.ConcertWithDetails) any {
+				if c.TicketPrice == nil {
+					return 0.0
+				}
+				return *c.TicketPrice
+			}},
+		"venue_id": {expr: "c.venue_id", kind: keyInt, json: "venue_id",
+			key: func(c *models.ConcertWithDetails) any { return c.VenueID }},
+		"venue": {expr: "v.name", kind: keyString, json: "venue_name",
+			key: func(c *models.ConcertWithDetails) any { return c.VenueName }},
+		"city": {expr: "v.city", kind: keyString, json: "venue_city",
+			key: func(c *models.ConcertWithDetails) any { return c.VenueCity }},
+		"state": {expr: "v.state", kind: keyString, json: "venue_state",
+			key: func(c *models.ConcertWithDetails) any { return c.VenueState }},
+		"address": {json: "venue_address"},
+		"notes":   {json: "notes"},
+	},
+}
 
-	return concerts, rows.Err()
+// ConcertQuery checks the query spec of a concert list against its whitelist and
+// returns the response keys its fields= selects.
+func ConcertQuery(spec queryspec.Spec) ([]string, error) {
+	return concertList.check(spec)
 }
 
-// ListConcertsByArtist returns all concerts for a specific artist
-func (s *Store) ListConcertsByArtist(ctx context.Context, token string, artistName string) ([]*models.ConcertWithDetails, error) {
-	userID, err := s.UserIDByToken(ctx, token)
+// listConcerts returns one page of concerts with venue details matching
+// condition, whose placeholders are bound to args, and spec.
+func (s *Store) listConcerts(ctx context.Context, condition string, args []any, spec queryspec.Spec, page Page) ([]*models.ConcertWithDetails, string, error) {
+	listQ, args, err := concertList.compile(spec, args)
 	if err != nil {
-		return nil, err
+		return nil, "", err
+	}
+	pageQ, err := listQ.keyset.page(page)
+	if err != nil {
+		return nil, "", err
 	}
 
+	after, args := pageQ.where(args)
+	tail, args := pageQ.orderLimit(args)
 	query := `
 		SELECT
 			c.id, c.user_id, c.venue_id, c.artist_name, c.name, c.date,
@@ -277,13 +255,12 @@ func (s *Store) ListConcertsByArtist(ctx context.Context, token string, artistNa
 			v.city as venue_city, v.state as venue_state
 		FROM concerts c
 		INNER JOIN venues v ON c.venue_id = v.id
-		WHERE c.user_id = $1 AND LOWER(c.artist_name) = LOWER($2)
-		ORDER BY c.date DESC
-	`
+		WHERE ` + condition + ` AND ` + listQ.where() + ` AND ` + after + `
+		` + tail
 
-	rows, err := s.db.QueryContext(ctx, query, userID, artistName)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -297,39 +274,14 @@ func (s *Store) ListConcertsByArtist(ctx context.Context, token string, artistNa
 			&c.VenueName, &c.VenueAddress, &c.VenueCity, &c.VenueState,
 		)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		concerts = append(concerts, &c)
 	}
-
-	return concerts, rows.Err()
-}
-
-// MarkConcertAttended marks a concert as attended and optionally adds a rating
-func (s *Store) MarkConcertAttended(ctx context.Context, token string, concertID int64, rating *int) error {
-	userID, err := s.UserIDByToken(ctx, token)
-	if err != nil {
-		return err
-	}
-
-	query := `
-		UPDATE concerts
-		SET attended = TRUE, rating = $1, updated_at = CURRENT_TIMESTAMP
-		WHERE id = $2 AND user_id = $3
-	`
-
-	result, err := s.db.ExecContext(ctx, query, rating, concertID, userID)
-	if err != nil {
-		return err
-	}
-
-	rows, err := result.RowsAffected()
-	if err != nil {
-		return err
-	}
-	if rows == 0 {
-		return ErrConcertNotFound
+	if err := rows.Err(); err != nil {
+		return nil, "", err
 	}
 
-	return nil
+	concerts, next := finishPage(pageQ, concerts, listQ.key)
+	return concerts, next, nil
 }
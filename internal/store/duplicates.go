@@ -0,0 +1,690 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"vinylhound/shared/go/models"
+)
+
+var (
+	// ErrDuplicateNotFound signals a missing or already reviewed duplicate candidate.
+	ErrDuplicateNotFound = errors.New("duplicate candidate not found")
+	// ErrInvalidMerge indicates a merge or review request that cannot be
+	// carried out.
+	ErrInvalidMerge = errors.New("invalid album merge")
+)
+
+// Duplicate candidate review states.
+const (
+	DuplicateStatusPending   = "pending"
+	DuplicateStatusDismissed = "dismissed"
+)
+
+// DuplicateThreshold is the minimum score for a pair of albums to be queued
+// for review.
+const DuplicateThreshold = 0.7
+
+// DuplicateCandidate is a pair of albums that may be the same record. Album
+// is the older of the two and is kept by default when the pair is merged.
+type DuplicateCandidate struct {
+	ID        int64     `json:"id"`
+	Album     Album     `json:"album"`
+	Duplicate Album     `json:"duplicate"`
+	Score     float64   `json:"score"`
+	Reasons   []string  `json:"reasons"`
+	Status    string    `json:"status"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// duplicateProfile holds the normalized signals compared between albums.
+type duplicateProfile struct {
+	id        int64
+	artistKey string
+	titleKey  string
+	year      int
+	tracks    map[string]struct{}
+	barcodes  []string
+	// providerIDs are "provider:external_id" keys of the album and of the
+	// provider items search recorded as the same album.
+	providerIDs []string
+}
+
+var (
+	// editionSuffixPattern matches bracketed edition notes such as
+	// "(Remastered 2011)" or "[Deluxe Edition]".
+	editionSuffixPattern = regexp.MustCompile(`(?i)\s*[\(\[][^\)\]]*\b(remaster(ed)?|deluxe|edition|anniversary|expanded|bonus|reissue|mono|stereo)\b[^\)\]]*[\)\]]`)
+	// editionDashPattern matches trailing edition notes such as
+	// " - 2009 Remaster".
+	editionDashPattern = regexp.MustCompile(`(?i)\s+-\s+[^-]*\b(remaster(ed)?|deluxe|edition|anniversary|expanded|reissue)\b.*$`)
+	nonAlphanumeric    = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+)
+
+// NormalizeCatalogName lowercases a name and strips punctuation, "&" versus
+// "and" differences and a leading "the" so that spelling variants compare
+// equal.
+func NormalizeCatalogName(name string) string {
+	name = strings.ToLower(strings.ReplaceAll(name, "&", " and "))
+	name = strings.TrimSpace(nonAlphanumeric.ReplaceAllString(name, " "))
+	return strings.TrimPrefix(name, "the ")
+}
+
+// NormalizeAlbumTitle is NormalizeCatalogName after dropping edition notes.
+func NormalizeAlbumTitle(title string) string {
+	title = editionSuffixPattern.ReplaceAllString(title, "")
+	title = editionDashPattern.ReplaceAllString(title, "")
+	return NormalizeCatalogName(title)
+}
+
+// scoreDuplicate rates how likely two albums are the same record, from 0 to
+// 1, and lists the signals that matched. A shared barcode or provider ID is
+// conclusive; otherwise title (0.45), artist (0.3), release year (0.1) and
+// track overlap (0.15) add up.
+func scoreDuplicate(a, b duplicateProfile) (float64, []string) {
+	if shares(a.barcodes, b.barcodes) {
+		return 1, []string{"barcode"}
+	}
+	if shares(a.providerIDs, b.providerIDs) {
+		return 1, []string{"provider id"}
+	}
+
+	var (
+		score   float64
+		reasons []string
+	)
+	switch {
+	case a.titleKey != "" && a.titleKey == b.titleKey:
+		score += 0.45
+		reasons = append(reasons, "title")
+	default:
+		if similarity := jaccard(wordSet(a.titleKey), wordSet(b.titleKey)); similarity >= 0.75 {
+			score += 0.3 * similarity
+			reasons = append(reasons, "similar title")
+		}
+	}
+	if a.artistKey != "" && a.artistKey == b.artistKey {
+		score += 0.3
+		reasons = append(reasons, "artist")
+	}
+	switch diff := a.year - b.year; {
+	case diff == 0:
+		score += 0.1
+		reasons = append(reasons, "release year")
+	case diff == 1 || diff == -1:
+		score += 0.05
+		reasons = append(reasons, "adjacent release year")
+	}
+	if len(a.tracks) > 0 && len(b.tracks) > 0 {
+		if overlap := jaccard(a.tracks, b.tracks); overlap > 0 {
+			score += 0.15 * overlap
+			reasons = append(reasons, fmt.Sprintf("track overlap %d%%", int(math.Round(overlap*100))))
+		}
+	}
+	return math.Round(score*1000) / 1000, reasons
+}
+
+func shares(a, b []string) bool {
+	for _, value := range a {
+		for _, other := range b {
+			if value == other {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func wordSet(value string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, word := range strings.Fields(value) {
+		set[word] = struct{}{}
+	}
+	return set
+}
+
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for key := range a {
+		if _, ok := b[key]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// ScanAlbumDuplicates scores albums against each other and replaces the
+// pending review queue with the pairs scoring at least DuplicateThreshold.
+// Only albums by the same normalized artist or sharing a release barcode or
+// provider ID are compared. Dismissed pairs are not suggested again. The user must be a
+// curator. It returns the number of pending candidates.
+func (s *Store) ScanAlbumDuplicates(ctx context.Context, token string) (int, error) {
+	if _, err := s.RequireRole(ctx, token, models.RoleCurator); err != nil {
+		return 0, err
+	}
+
+	profiles, err := s.loadDuplicateProfiles(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	groups := make(map[string][]int)
+	for i, profile := range profiles {
+		if profile.artistKey != "" {
+			groups["artist:"+profile.artistKey] = append(groups["artist:"+profile.artistKey], i)
+		}
+		for _, code := range profile.barcodes {
+			groups["barcode:"+code] = append(groups["barcode:"+code], i)
+		}
+		for _, key := range profile.providerIDs {
+			groups["provider:"+key] = append(groups["provider:"+key], i)
+		}
+	}
+
+	type pair struct{ a, b int }
+	seen := make(map[pair]struct{})
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM album_duplicate_candidates WHERE status = 'pending'`); err != nil {
+		return 0, fmt.Errorf("clear duplicate candidates: %w", err)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	found := 0
+	for _, key := range keys {
+		members := groups[key]
+		for i := 0; i < len(members); i++ {
+			for j := i + 1; j < len(members); j++ {
+				p := pair{a: members[i], b: members[j]}
+				if _, ok := seen[p]; ok {
+					continue
+				}
+				seen[p] = struct{}{}
+
+				score, reasons := scoreDuplicate(profiles[p.a], profiles[p.b])
+				if score < DuplicateThreshold {
+					continue
+				}
+				reasonsJSON, err := json.Marshal(reasons)
+				if err != nil {
+					return 0, fmt.Errorf("marshal duplicate reasons: %w", err)
+				}
+				res, err := tx.ExecContext(ctx, `
+					INSERT INTO album_duplicate_candidates (album_id, duplicate_id, score, reasons)
+					VALUES ($1, $2, $3, $4::jsonb)
+					ON CONFLICT (album_id, duplicate_id) DO NOTHING
+				`, profiles[p.a].id, profiles[p.b].id, score, string(reasonsJSON))
+				if err != nil {
+					return 0, fmt.Errorf("insert duplicate candidate: %w", err)
+				}
+				if n, err := res.RowsAffected(); err == nil {
+					found += int(n)
+				}
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit tx: %w", err)
+	}
+	return found, nil
+}
+
+// loadDuplicateProfiles reads the comparison signals of every album, ordered
+// by album ID so that pairs come out oldest first.
+func (s *Store) loadDuplicateProfiles(ctx context.Context) ([]duplicateProfile, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id, artist, title, release_year FROM albums ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("select albums: %w", err)
+	}
+	defer rows.Close()
+
+	var profiles []duplicateProfile
+	index := make(map[int64]int)
+	for rows.Next() {
+		var (
+			profile       duplicateProfile
+			artist, title string
+		)
+		if err := rows.Scan(&profile.id, &artist, &title, &profile.year); err != nil {
+			return nil, fmt.Errorf("scan album: %w", err)
+		}
+		profile.artistKey = NormalizeCatalogName(artist)
+		profile.titleKey = NormalizeAlbumTitle(title)
+		profile.tracks = make(map[string]struct{})
+		index[profile.id] = len(profiles)
+		profiles = append(profiles, profile)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate albums: %w", err)
+	}
+
+	trackRows, err := s.db.QueryContext(ctx, `SELECT album_id, title FROM songs WHERE album_id IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("select album songs: %w", err)
+	}
+	defer trackRows.Close()
+	for trackRows.Next() {
+		var (
+			albumID int64
+			title   string
+		)
+		if err := trackRows.Scan(&albumID, &title); err != nil {
+			return nil, fmt.Errorf("scan album song: %w", err)
+		}
+		if i, ok := index[albumID]; ok {
+			if key := NormalizeAlbumTitle(title); key != "" {
+				profiles[i].tracks[key] = struct{}{}
+			}
+		}
+	}
+	if err := trackRows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate album songs: %w", err)
+	}
+
+	barcodeRows, err := s.db.QueryContext(ctx, `SELECT album_id, barcode FROM album_releases WHERE barcode IS NOT NULL`)
+	if err != nil {
+		return nil, fmt.Errorf("select release barcodes: %w", err)
+	}
+	defer barcodeRows.Close()
+	for barcodeRows.Next() {
+		var (
+			albumID int64
+			barcode string
+		)
+		if err := barcodeRows.Scan(&albumID, &barcode); err != nil {
+			return nil, fmt.Errorf("scan release barcode: %w", err)
+		}
+		if i, ok := index[albumID]; ok {
+			profiles[i].barcodes = append(profiles[i].barcodes, barcode)
+		}
+	}
+	if err := barcodeRows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate release barcodes: %w", err)
+	}
+
+	// Copies imported from different providers share the IDs search
+	// recorded as the same album.
+	idRows, err := s.db.QueryContext(ctx, `
+		SELECT e.local_id, e.provider, e.external_id
+		FROM external_ids e
+		WHERE e.entity_type = 'album'
+		UNION
+		SELECT e.local_id, o.provider, o.external_id
+		FROM external_ids e
+		JOIN provider_match_links l ON l.entity_type = e.entity_type AND l.provider = e.provider AND l.external_id = e.external_id
+		JOIN provider_match_links o ON o.match_id = l.match_id
+		WHERE e.entity_type = 'album'
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("select album external ids: %w", err)
+	}
+	defer idRows.Close()
+	for idRows.Next() {
+		var (
+			albumID              int64
+			provider, externalID string
+		)
+		if err := idRows.Scan(&albumID, &provider, &externalID); err != nil {
+			return nil, fmt.Errorf("scan album external id: %w", err)
+		}
+		if i, ok := index[albumID]; ok {
+			profiles[i].providerIDs = append(profiles[i].providerIDs, provider+":"+externalID)
+		}
+	}
+	if err := idRows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate album external ids: %w", err)
+	}
+
+	return profiles, nil
+}
+
+// duplicateKeyset lists candidates highest score first.
+var duplicateKeyset = keyset{name: "duplicates", columns: []keyColumn{
+	{expr: "c.score", desc: true, kind: keyFloat},
+	{expr: "c.id", kind: keyInt},
+}}
+
+// ListDuplicateCandidates returns one page of the candidates with the given
+// status (pending when empty), highest score first, with both albums'
+// tracklists. The user must be a curator.
+func (s *Store) ListDuplicateCandidates(ctx context.Context, token string, status string, page Page) ([]DuplicateCandidate, string, error) {
+	if status == "" {
+		status = DuplicateStatusPending
+	}
+	if status != DuplicateStatusPending && status != DuplicateStatusDismissed {
+		return nil, "", fmt.Errorf("%w: unknown status %q", ErrInvalidMerge, status)
+	}
+
+	pageQ, err := duplicateKeyset.page(page)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if _, err := s.RequireRole(ctx, token, models.RoleCurator); err != nil {
+		return nil, "", err
+	}
+
+	args := []any{status}
+	after, args := pageQ.where(args)
+	tail, args := pageQ.orderLimit(args)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT c.id, c.score, c.reasons, c.status, c.created_at,
+		       a.id, a.artist, a.title, a.release_year, a.genres, a.rating,
+		       d.id, d.artist, d.title, d.release_year, d.genres, d.rating
+		FROM album_duplicate_candidates c
+		JOIN albums a ON a.id = c.album_id
+		JOIN albums d ON d.id = c.duplicate_id
+		WHERE c.status = $1 AND `+after+`
+		`+tail, args...)
+	if err != nil {
+		return nil, "", fmt.Errorf("select duplicate candidates: %w", err)
+	}
+	defer rows.Close()
+
+	candidates := []DuplicateCandidate{}
+	for rows.Next() {
+		var (
+			candidate   DuplicateCandidate
+			reasonsJSON []byte
+			albumGenres []byte
+			dupGenres   []byte
+		)
+		if err := rows.Scan(
+			&candidate.ID, &candidate.Score, &reasonsJSON, &candidate.Status, &candidate.CreatedAt,
+			&candidate.Album.ID, &candidate.Album.Artist, &candidate.Album.Title, &candidate.Album.ReleaseYear, &albumGenres, &candidate.Album.Rating,
+			&candidate.Duplicate.ID, &candidate.Duplicate.Artist, &candidate.Duplicate.Title, &candidate.Duplicate.ReleaseYear, &dupGenres, &candidate.Duplicate.Rating,
+		); err != nil {
+			return nil, "", fmt.Errorf("scan duplicate candidate: %w", err)
+		}
+		if err := json.Unmarshal(reasonsJSON, &candidate.Reasons); err != nil {
+			return nil, "", fmt.Errorf("decode duplicate reasons: %w", err)
+		}
+		if err := json.Unmarshal(albumGenres, &candidate.Album.Genres); err != nil {
+			return nil, "", fmt.Errorf("decode genres: %w", err)
+		}
+		if err := json.Unmarshal(dupGenres, &candidate.Duplicate.Genres); err != nil {
+			return nil, "", fmt.Errorf("decode genres: %w", err)
+		}
+		candidates = append(candidates, candidate)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("iterate duplicate candidates: %w", err)
+	}
+	candidates, next := finishPage(pageQ, candidates, func(c DuplicateCandidate) []any {
+		return []any{c.Score, c.ID}
+	})
+
+	albums := make([]Album, 0, 2*len(candidates))
+	for _, candidate := range candidates {
+		albums = append(albums, candidate.Album, candidate.Duplicate)
+	}
+	albums, err = s.applyAlbumTracks(ctx, albums)
+	if err != nil {
+		return nil, "", err
+	}
+	for i := range candidates {
+		candidates[i].Album.Tracks = albums[2*i].Tracks
+		candidates[i].Duplicate.Tracks = albums[2*i+1].Tracks
+	}
+	return candidates, next, nil
+}
+
+// DismissDuplicateCandidate marks a pending candidate as not a duplicate.
+// The user must be a curator.
+func (s *Store) DismissDuplicateCandidate(ctx context.Context, token string, id int64) error {
+	userID, err := s.RequireRole(ctx, token, models.RoleCurator)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE album_duplicate_candidates
+		SET status = 'dismissed', reviewed_by = $2, reviewed_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+	`, id, userID)
+	if err != nil {
+		return fmt.Errorf("dismiss duplicate candidate: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrDuplicateNotFound
+	}
+	return nil
+}
+
+// MergeDuplicateCandidate merges a pending candidate pair. keepID selects
+// the album that survives and must be one of the pair; zero keeps the older
+// album. The user must be a curator.
+func (s *Store) MergeDuplicateCandidate(ctx context.Context, token string, id int64, keepID int64) (Album, error) {
+	userID, err := s.RequireRole(ctx, token, models.RoleCurator)
+	if err != nil {
+		return Album{}, err
+	}
+
+	var albumID, duplicateID int64
+	err = s.db.QueryRowContext(ctx, `
+		SELECT album_id, duplicate_id
+		FROM album_duplicate_candidates
+		WHERE id = $1 AND status = 'pending'
+	`, id).Scan(&albumID, &duplicateID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Album{}, ErrDuplicateNotFound
+		}
+		return Album{}, fmt.Errorf("lookup duplicate candidate: %w", err)
+	}
+
+	switch keepID {
+	case 0, albumID:
+		return s.mergeAlbums(ctx, userID, albumID, duplicateID)
+	case duplicateID:
+		return s.mergeAlbums(ctx, userID, duplicateID, albumID)
+	default:
+		return Album{}, fmt.Errorf("%w: album %d is not part of candidate %d", ErrInvalidMerge, keepID, id)
+	}
+}
+
+// MergeAlbums folds the album mergeID into keepID and deletes it. The user
+// must be a curator.
+func (s *Store) MergeAlbums(ctx context.Context, token string, keepID, mergeID int64) (Album, error) {
+	userID, err := s.RequireRole(ctx, token, models.RoleCurator)
+	if err != nil {
+		return Album{}, err
+	}
+	return s.mergeAlbums(ctx, userID, keepID, mergeID)
+}
+
+// mergedSongPairs pairs each song of the merged album ($2) with the kept
+// album's ($1) song of the same title.
+const mergedSongPairs = `
+	SELECT DISTINCT ON (m.id) m.id AS merged_id, k.id AS kept_id
+	FROM songs m
+	JOIN songs k ON k.album_id = $1 AND lower(btrim(k.title)) = lower(btrim(m.title))
+	WHERE m.album_id = $2
+	ORDER BY m.id, k.disc_number, k.track_num, k.id`
+
+// mergeAlbums moves everything that refers to mergeID over to keepID in one
+// transaction:
+//   - releases move as they are;
+//   - ratings, favorites and collection items move, and where the user
+//     already has one on the kept album the two are combined or the merged
+//     one is dropped, keeping its photos;
+//   - songs with the same title as a kept song are replaced by it, including
+//     in track favorites; the remaining songs are appended to the tracklist;
+//   - playlist entries naming the merged album by title and artist take
+//     the kept album's title and artist;
+//   - genres are combined, and the merged album's cover is kept if the
+//     kept album has none;
+//   - provider IDs of the merged album and of replaced songs move to the
+//     kept album and songs.
+//
+// Concerts refer to artists rather than albums and are not affected.
+func (s *Store) mergeAlbums(ctx context.Context, userID, keepID, mergeID int64) (Album, error) {
+	if keepID <= 0 || mergeID <= 0 || keepID == mergeID {
+		return Album{}, fmt.Errorf("%w: an album cannot be merged into itself", ErrInvalidMerge)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Album{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, artist, title
+		FROM albums
+		WHERE id IN ($1, $2)
+		ORDER BY id
+		FOR UPDATE
+	`, keepID, mergeID)
+	if err != nil {
+		return Album{}, fmt.Errorf("lock albums: %w", err)
+	}
+	titles := make(map[int64][2]string)
+	for rows.Next() {
+		var (
+			id            int64
+			artist, title string
+		)
+		if err := rows.Scan(&id, &artist, &title); err != nil {
+			rows.Close()
+			return Album{}, fmt.Errorf("scan album: %w", err)
+		}
+		titles[id] = [2]string{artist, title}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return Album{}, fmt.Errorf("iterate albums: %w", err)
+	}
+	if len(titles) != 2 {
+		return Album{}, ErrAlbumNotFound
+	}
+	merged := titles[mergeID]
+
+	pair := []any{keepID, mergeID}
+	steps := []struct {
+		name  string
+		query string
+		args  []any
+	}{
+		{"move releases", `UPDATE album_releases SET album_id = $1 WHERE album_id = $2`, pair},
+		{"combine ratings", `
+			UPDATE user_album_preferences k
+			SET rating = COALESCE(k.rating, m.rating),
+			    favorited = k.favorited OR m.favorited,
+			    release_id = COALESCE(k.release_id, m.release_id),
+			    updated_at = GREATEST(k.updated_at, m.updated_at)
+			FROM user_album_preferences m
+			WHERE k.album_id = $1 AND m.album_id = $2 AND m.user_id = k.user_id`, pair},
+		{"drop combined ratings", `
+			DELETE FROM user_album_preferences m
+			USING user_album_preferences k
+			WHERE m.album_id = $2 AND k.album_id = $1 AND k.user_id = m.user_id`, pair},
+		{"move ratings", `UPDATE user_album_preferences SET album_id = $1 WHERE album_id = $2`, pair},
+		{"drop duplicate favorites", `
+			DELETE FROM favorites m
+			USING favorites k
+			WHERE m.album_id = $2 AND k.album_id = $1 AND k.user_id = m.user_id`, pair},
+		{"move favorites", `UPDATE favorites SET album_id = $1 WHERE album_id = $2`, pair},
+		{"keep photos of duplicate collection items", `
+			INSERT INTO collection_photos (collection_id, media_id, caption, created_at)
+			SELECT k.id, p.media_id, p.caption, p.created_at
+			FROM collection_photos p
+			JOIN album_collections m ON m.id = p.collection_id
+			JOIN album_collections k ON k.album_id = $1 AND k.user_id = m.user_id
+			  AND k.collection_type = m.collection_type
+			  AND k.release_id IS NOT DISTINCT FROM m.release_id
+			WHERE m.album_id = $2
+			ON CONFLICT DO NOTHING`, pair},
+		{"drop duplicate collection items", `
+			DELETE FROM album_collections m
+			USING album_collections k
+			WHERE m.album_id = $2 AND k.album_id = $1 AND k.user_id = m.user_id
+			  AND k.collection_type = m.collection_type
+			  AND k.release_id IS NOT DISTINCT FROM m.release_id`, pair},
+		{"move collection items", `UPDATE album_collections SET album_id = $1 WHERE album_id = $2`, pair},
+		{"drop duplicate track favorites", `
+			DELETE FROM favorites f
+			USING (` + mergedSongPairs + `) p, favorites k
+			WHERE f.song_id = p.merged_id AND k.song_id = p.kept_id AND k.user_id = f.user_id`, pair},
+		{"move track favorites", `
+			UPDATE favorites f
+			SET song_id = p.kept_id
+			FROM (` + mergedSongPairs + `) p
+			WHERE f.song_id = p.merged_id`, pair},
+		{"move external ids of duplicate songs", `
+			UPDATE external_ids e
+			SET local_id = p.kept_id
+			FROM (` + mergedSongPairs + `) p
+			WHERE e.entity_type = 'track' AND e.local_id = p.merged_id`, pair},
+		{"drop duplicate songs", `
+			DELETE FROM songs s
+			USING (` + mergedSongPairs + `) p
+			WHERE s.id = p.merged_id`, pair},
+		{"move songs", `
+			UPDATE songs s
+			SET album_id = $1, track_num = n.base + n.rn
+			FROM (
+				SELECT m.id,
+				       ROW_NUMBER() OVER (PARTITION BY m.disc_number, m.side ORDER BY m.track_num NULLS LAST, m.id) AS rn,
+				       COALESCE((
+				           SELECT MAX(k.track_num) FROM songs k
+				           WHERE k.album_id = $1 AND k.disc_number = m.disc_number AND k.side IS NOT DISTINCT FROM m.side
+				       ), 0) AS base
+				FROM songs m
+				WHERE m.album_id = $2
+			) n
+			WHERE s.id = n.id`, pair},
+		{"rename playlist entries", `
+			UPDATE playlist_songs
+			SET (album, artist) = (SELECT title, artist FROM albums WHERE id = $1)
+			WHERE album = $2 AND artist = $3`, []any{keepID, merged[1], merged[0]}},
+		{"combine genres and cover", `
+			UPDATE albums k
+			SET cover_media_id = COALESCE(k.cover_media_id, m.cover_media_id),
+			    genres = (
+				SELECT COALESCE(jsonb_agg(g ORDER BY g), '[]'::jsonb)
+				FROM (
+					SELECT jsonb_array_elements_text(k.genres) AS g
+					UNION
+					SELECT jsonb_array_elements_text(m.genres)
+				) combined
+			)
+			FROM albums m
+			WHERE k.id = $1 AND m.id = $2`, pair},
+		{"move external ids", `UPDATE external_ids SET local_id = $1 WHERE entity_type = 'album' AND local_id = $2`, pair},
+		{"record merge", `
+			INSERT INTO album_merges (kept_album_id, merged_album_id, merged_artist, merged_title, merged_by)
+			VALUES ($1, $2, $3, $4, $5)`, []any{keepID, mergeID, merged[0], merged[1], userID}},
+		{"delete merged album", `DELETE FROM albums WHERE id = $1`, []any{mergeID}},
+	}
+	for _, step := range steps {
+		if _, err := tx.ExecContext(ctx, step.query, step.args...); err != nil {
+			return Album{}, fmt.Errorf("%s: %w", step.name, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Album{}, fmt.Errorf("commit tx: %w", err)
+	}
+	return s.AlbumByID(keepID)
+}
@@ -0,0 +1,249 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestNormalizeAlbumTitle(t *testing.T) {
+	tests := map[string]string{
+		"Abbey Road (Remastered 2019)":        "abbey road",
+		"Abbey Road - 2009 Remaster":          "abbey road",
+		"The Wall [Deluxe Edition]":           "wall",
+		"Rumours (Super Deluxe)":              "rumours",
+		"Simon & Garfunkel's Greatest Hits":   "simon and garfunkel s greatest hits",
+		"Live at Leeds (Live)":                "live at leeds live",
+		"  OK   Computer OKNOTOK 1997-2017 ":  "ok computer oknotok 1997 2017",
+		"Kind of Blue (50th Anniversary)":     "kind of blue",
+		"Pet Sounds (Stereo & Mono Versions)": "pet sounds",
+	}
+	for title, want := range tests {
+		if got := NormalizeAlbumTitle(title); got != want {
+			t.Errorf("NormalizeAlbumTitle(%q) = %q, want %q", title, got, want)
+		}
+	}
+}
+
+func TestScoreDuplicate(t *testing.T) {
+	tracks := func(titles ...string) map[string]struct{} {
+		set := make(map[string]struct{})
+		for _, title := range titles {
+			set[title] = struct{}{}
+		}
+		return set
+	}
+
+	original := duplicateProfile{id: 1, artistKey: "beatles", titleKey: "abbey road", year: 1969, tracks: tracks("come together", "something")}
+	remaster := duplicateProfile{id: 2, artistKey: "beatles", titleKey: "abbey road", year: 1969, tracks: tracks("come together", "something", "her majesty")}
+
+	score, reasons := scoreDuplicate(original, remaster)
+	if score != 0.95 {
+		t.Errorf("expected score 0.95, got %v", score)
+	}
+	if want := []string{"title", "artist", "release year", "track overlap 67%"}; !reflect.DeepEqual(reasons, want) {
+		t.Errorf("reasons = %v, want %v", reasons, want)
+	}
+
+	otherAlbum := duplicateProfile{id: 3, artistKey: "beatles", titleKey: "let it be", year: 1970}
+	if score, _ := scoreDuplicate(original, otherAlbum); score >= DuplicateThreshold {
+		t.Errorf("different albums by the same artist scored %v", score)
+	}
+
+	pressingA := duplicateProfile{id: 4, artistKey: "x", titleKey: "a", barcodes: []string{"5099902987613"}}
+	pressingB := duplicateProfile{id: 5, artistKey: "y", titleKey: "b", barcodes: []string{"5099902987613"}}
+	if score, reasons := scoreDuplicate(pressingA, pressingB); score != 1 || reasons[0] != "barcode" {
+		t.Errorf("shared barcode scored %v %v", score, reasons)
+	}
+
+	importA := duplicateProfile{id: 6, artistKey: "x", titleKey: "a", providerIDs: []string{"spotify:sp1", "apple_music:am1"}}
+	importB := duplicateProfile{id: 7, artistKey: "y", titleKey: "b", providerIDs: []string{"apple_music:am1"}}
+	if score, reasons := scoreDuplicate(importA, importB); score != 1 || reasons[0] != "provider id" {
+		t.Errorf("shared provider ID scored %v %v", score, reasons)
+	}
+}
+
+func TestScanAlbumDuplicates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 9)
+	expectRoleLookup(mock, 9, "curator")
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, artist, title, release_year FROM albums ORDER BY id`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "artist", "title", "release_year"}).
+			AddRow(int64(1), "The Beatles", "Abbey Road", 1969).
+			AddRow(int64(2), "Beatles", "Abbey Road (Remastered)", 1969).
+			AddRow(int64(3), "The Beatles", "Let It Be", 1970).
+			AddRow(int64(4), "Pink Floyd", "Abbey Road", 1969))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT album_id, title FROM songs WHERE album_id IS NOT NULL`)).
+		WillReturnRows(sqlmock.NewRows([]string{"album_id", "title"}).
+			AddRow(int64(1), "Come Together").
+			AddRow(int64(2), "Come Together"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT album_id, barcode FROM album_releases WHERE barcode IS NOT NULL`)).
+		WillReturnRows(sqlmock.NewRows([]string{"album_id", "barcode"}))
+	// Let It Be was imported from Spotify, and the Pink Floyd album from
+	// an Apple Music copy that search recorded as the same album.
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM external_ids e`)).
+		WillReturnRows(sqlmock.NewRows([]string{"local_id", "provider", "external_id"}).
+			AddRow(int64(3), "spotify", "sp-let-it-be").
+			AddRow(int64(4), "apple_music", "am-let-it-be").
+			AddRow(int64(4), "spotify", "sp-let-it-be"))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM album_duplicate_candidates WHERE status = 'pending'`)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO album_duplicate_candidates (album_id, duplicate_id, score, reasons)`)).
+		WithArgs(int64(1), int64(2), 1.0, `["title","artist","release year","track overlap 100%"]`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO album_duplicate_candidates (album_id, duplicate_id, score, reasons)`)).
+		WithArgs(int64(3), int64(4), 1.0, `["provider id"]`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	found, err := s.ScanAlbumDuplicates(context.Background(), "token")
+	if err != nil {
+		t.Fatalf("ScanAlbumDuplicates: %v", err)
+	}
+	if found != 2 {
+		t.Fatalf("expected 2 candidates, got %d", found)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMergeAlbums(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 9)
+	expectRoleLookup(mock, 9, "curator")
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FOR UPDATE`)).
+		WithArgs(int64(1), int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "artist", "title"}).
+			AddRow(int64(1), "The Beatles", "Abbey Road").
+			AddRow(int64(2), "Beatles", "Abbey Road (Remastered)"))
+
+	pair := []any{int64(1), int64(2)}
+	steps := []struct {
+		query string
+		args  []any
+	}{
+		{`UPDATE album_releases SET album_id = $1 WHERE album_id = $2`, pair},
+		{`UPDATE user_album_preferences k`, pair},
+		{`DELETE FROM user_album_preferences m`, pair},
+		{`UPDATE user_album_preferences SET album_id = $1 WHERE album_id = $2`, pair},
+		{`DELETE FROM favorites m`, pair},
+		{`UPDATE favorites SET album_id = $1 WHERE album_id = $2`, pair},
+		{`INSERT INTO collection_photos`, pair},
+		{`DELETE FROM album_collections m`, pair},
+		{`UPDATE album_collections SET album_id = $1 WHERE album_id = $2`, pair},
+		{`DELETE FROM favorites f`, pair},
+		{`UPDATE favorites f`, pair},
+		{`UPDATE external_ids e`, pair},
+		{`DELETE FROM songs s`, pair},
+		{`UPDATE songs s`, pair},
+		// Entries spelled "Beatles" take the kept album's "The Beatles".
+		{`SET (album, artist) = (SELECT title, artist FROM albums WHERE id = $1)`, []any{int64(1), "Abbey Road (Remastered)", "Beatles"}},
+		{`UPDATE albums k`, pair},
+		{`UPDATE external_ids SET local_id`, pair},
+		{`INSERT INTO album_merges`, []any{int64(1), int64(2), "Beatles", "Abbey Road (Remastered)", int64(9)}},
+		{`DELETE FROM albums WHERE id = $1`, []any{int64(2)}},
+	}
+	for _, step := range steps {
+		args := make([]driver.Value, len(step.args))
+		for i, arg := range step.args {
+			args[i] = arg
+		}
+		mock.ExpectExec(regexp.QuoteMeta(step.query)).
+			WithArgs(args...).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM albums
+		WHERE id = $1`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "artist", "title", "release_year", "genres", "rating"}).
+			AddRow(int64(1), "The Beatles", "Abbey Road", 1969, `["Rock"]`, 5))
+	mock.ExpectQuery(regexp.QuoteMeta(ratingStatsQuery)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"album_id", "average_rating", "rating_count"}))
+	expectAlbumTracks(mock, []int64{1})
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM album_artists aa`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "role"}))
+
+	album, err := s.MergeAlbums(context.Background(), "token", 1, 2)
+	if err != nil {
+		t.Fatalf("MergeAlbums: %v", err)
+	}
+	if album.ID != 1 || album.Title != "Abbey Road" {
+		t.Fatalf("unexpected surviving album %+v", album)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMergeAlbumsRejectsSelfMerge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 9)
+	expectRoleLookup(mock, 9, "curator")
+
+	if _, err := s.MergeAlbums(context.Background(), "token", 4, 4); !errors.Is(err, ErrInvalidMerge) {
+		t.Fatalf("expected ErrInvalidMerge, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMergeDuplicateCandidateRejectsForeignAlbum(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 9)
+	expectRoleLookup(mock, 9, "curator")
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM album_duplicate_candidates`)).
+		WithArgs(int64(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"album_id", "duplicate_id"}).AddRow(int64(1), int64(2)))
+
+	if _, err := s.MergeDuplicateCandidate(context.Background(), "token", 7, 5); !errors.Is(err, ErrInvalidMerge) {
+		t.Fatalf("expected ErrInvalidMerge, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
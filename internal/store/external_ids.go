@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// Entity types of external IDs and provider matches. Tracks are kept as
+// songs; releases, the pressings of an album, only have external IDs.
+const (
+	EntityArtist  = "artist"
+	EntityAlbum   = "album"
+	EntityTrack   = "track"
+	EntityRelease = "release"
+)
+
+var (
+	// ErrExternalIDNotFound signals a provider ID that no local entity has.
+	ErrExternalIDNotFound = errors.New("external id not found")
+	// ErrInvalidExternalID indicates an incomplete provider ID mapping.
+	ErrInvalidExternalID = errors.New("invalid external id")
+)
+
+// ExternalID links a local artist, album, track or release to its ID at a
+// provider.
+type ExternalID struct {
+	EntityType string `json:"entityType"`
+	LocalID    int64  `json:"localId"`
+	Provider   string `json:"provider"`
+	ExternalID string `json:"externalId"`
+	URL        string `json:"url,omitempty"`
+}
+
+// IsEntityType reports whether value names an entity type.
+func IsEntityType(value string) bool {
+	return value == EntityArtist || value == EntityAlbum || value == EntityTrack || value == EntityRelease
+}
+
+// SaveExternalID records the provider ID of a local entity. A provider ID
+// belongs to one entity; saving it again moves it and keeps a known URL
+// when none is given.
+func (s *Store) SaveExternalID(ctx context.Context, id ExternalID) error {
+	if !IsEntityType(id.EntityType) || id.LocalID <= 0 || id.Provider == "" || id.ExternalID == "" {
+		return fmt.Errorf("%w: %s %d at %q as %q", ErrInvalidExternalID, id.EntityType, id.LocalID, id.Provider, id.ExternalID)
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO external_ids (entity_type, local_id, provider, external_id, url)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		ON CONFLICT (entity_type, provider, external_id) DO UPDATE
+		SET local_id = EXCLUDED.local_id,
+		    url = COALESCE(EXCLUDED.url, external_ids.url),
+		    updated_at = NOW()
+	`, id.EntityType, id.LocalID, id.Provider, id.ExternalID, id.URL)
+	if err != nil {
+		return fmt.Errorf("save external id: %w", err)
+	}
+	return nil
+}
+
+// ExternalIDs returns the mappings of refs that local entities of one type
+// have, in no particular order.
+func (s *Store) ExternalIDs(ctx context.Context, entityType string, refs []ProviderRef) ([]ExternalID, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	providers, ids := splitRefs(refs)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT entity_type, local_id, provider, external_id, COALESCE(url, '')
+		FROM external_ids
+		WHERE entity_type = $1
+		  AND (provider, external_id) IN (SELECT * FROM unnest($2::text[], $3::text[]))
+	`, entityType, pq.Array(providers), pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("select external ids: %w", err)
+	}
+	return scanExternalIDs(rows)
+}
+
+// LookupExternalID returns the local entities a provider ID stands for,
+// optionally of one entity type. Besides the entities imported with that ID
+// it finds the ones imported from another provider's copy that search
+// matched with it; the mapping returned is then that copy's.
+func (s *Store) LookupExternalID(ctx context.Context, provider, externalID, entityType string) ([]ExternalID, error) {
+	if provider == "" || externalID == "" || (entityType != "" && !IsEntityType(entityType)) {
+		return nil, fmt.Errorf("%w: provider %q, id %q, type %q", ErrInvalidExternalID, provider, externalID, entityType)
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT DISTINCT ON (e.entity_type, e.local_id)
+		       e.entity_type, e.local_id, e.provider, e.external_id, COALESCE(e.url, '')
+		FROM external_ids e
+		WHERE ($3 = '' OR e.entity_type = $3)
+		  AND (
+			(e.provider = $1 AND e.external_id = $2)
+			OR (e.entity_type, e.provider, e.external_id) IN (
+				SELECT o.entity_type, o.provider, o.external_id
+				FROM provider_match_links l
+				JOIN provider_match_links o ON o.match_id = l.match_id
+				WHERE l.provider = $1 AND l.external_id = $2
+			)
+		  )
+		ORDER BY e.entity_type, e.local_id, (e.provider = $1 AND e.external_id = $2) DESC, e.provider
+	`, provider, externalID, entityType)
+	if err != nil {
+		return nil, fmt.Errorf("lookup external id: %w", err)
+	}
+	ids, err := scanExternalIDs(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, ErrExternalIDNotFound
+	}
+	return ids, nil
+}
+
+func scanExternalIDs(rows *sql.Rows) ([]ExternalID, error) {
+	defer rows.Close()
+
+	var ids []ExternalID
+	for rows.Next() {
+		var id ExternalID
+		if err := rows.Scan(&id.EntityType, &id.LocalID, &id.Provider, &id.ExternalID, &id.URL); err != nil {
+			return nil, fmt.Errorf("scan external id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate external ids: %w", err)
+	}
+	return ids, nil
+}
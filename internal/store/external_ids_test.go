@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSaveExternalIDRejectsIncompleteMappings(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	for _, id := range []ExternalID{
+		{EntityType: "song", LocalID: 1, Provider: "spotify", ExternalID: "x"},
+		{EntityType: EntityAlbum, Provider: "spotify", ExternalID: "x"},
+		{EntityType: EntityAlbum, LocalID: 1, ExternalID: "x"},
+	} {
+		if err := s.SaveExternalID(context.Background(), id); !errors.Is(err, ErrInvalidExternalID) {
+			t.Errorf("SaveExternalID(%+v): expected ErrInvalidExternalID, got %v", id, err)
+		}
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO external_ids (entity_type, local_id, provider, external_id, url)`)).
+		WithArgs(EntityTrack, int64(3), "spotify", "6rqhFgbbKwnb9MLmUQDhG6", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	if err := s.SaveExternalID(context.Background(), ExternalID{
+		EntityType: EntityTrack, LocalID: 3, Provider: "spotify", ExternalID: "6rqhFgbbKwnb9MLmUQDhG6",
+	}); err != nil {
+		t.Fatalf("SaveExternalID: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestLookupExternalID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	columns := []string{"entity_type", "local_id", "provider", "external_id", "url"}
+	// The Apple Music copy was matched with the Spotify album imported as 7.
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM external_ids e`)).
+		WithArgs("apple_music", "1441164426", EntityAlbum).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(EntityAlbum, int64(7), "spotify", "0ETFjACtuP2ADo6LFhL6HN", "https://open.spotify.com/album/0ETFjACtuP2ADo6LFhL6HN"))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM external_ids e`)).
+		WithArgs("apple_music", "unknown", "").
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	ids, err := s.LookupExternalID(context.Background(), "apple_music", "1441164426", EntityAlbum)
+	if err != nil {
+		t.Fatalf("LookupExternalID: %v", err)
+	}
+	if len(ids) != 1 || ids[0].LocalID != 7 || ids[0].Provider != "spotify" {
+		t.Fatalf("unexpected ids %+v", ids)
+	}
+
+	if _, err := s.LookupExternalID(context.Background(), "apple_music", "unknown", ""); !errors.Is(err, ErrExternalIDNotFound) {
+		t.Fatalf("expected ErrExternalIDNotFound, got %v", err)
+	}
+	if _, err := s.LookupExternalID(context.Background(), "apple_music", "1441164426", "playlist"); !errors.Is(err, ErrInvalidExternalID) {
+		t.Fatalf("expected ErrInvalidExternalID, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -252,52 +252,48 @@ func (s *Store) RemoveFavorite(ctx context.Context, token string, songID *int64,
 	return nil
 }
 
-// ListFavorites returns all favorites for a user.
-func (s *Store) ListFavorites(ctx context.Context, token string) ([]*models.Favorite, error) {
-	userID, err := s.UserIDByToken(ctx, token)
-	if err != nil {
-		return nil, err
-	}
+// favoriteColumns are the sort key of both favorite lists: newest first.
+var favoriteColumns = []keyColumn{
+	{expr: "created_at", desc: true, kind: keyTime},
+	{expr: "id", desc: true, kind: keyInt},
+}
 
-	rows, err := s.db.QueryContext(ctx, `
-		SELECT id, user_id, song_id, album_id, created_at
-		FROM favorites
-		WHERE user_id = $1
-		ORDER BY created_at DESC`, userID)
-	if err != nil {
-		return nil, fmt.Errorf("list favorites: %w", err)
-	}
-	defer rows.Close()
+var (
+	favoriteKeyset      = keyset{name: "favorites", columns: favoriteColumns}
+	favoriteTrackKeyset = keyset{name: "favorite-tracks", columns: favoriteColumns}
+)
 
-	var favorites []*models.Favorite
-	for rows.Next() {
-		var fav models.Favorite
-		if err := rows.Scan(&fav.ID, &fav.UserID, &fav.SongID, &fav.AlbumID, &fav.CreatedAt); err != nil {
-			return nil, fmt.Errorf("scan favorite: %w", err)
-		}
-		favorites = append(favorites, &fav)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate favorites: %w", err)
-	}
+// ListFavorites returns one page of favorites for a user.
+func (s *Store) ListFavorites(ctx context.Context, token string, page Page) ([]*models.Favorite, string, error) {
+	return s.listFavorites(ctx, token, favoriteKeyset, "", page)
+}
 
-	return favorites, nil
+// ListFavoriteTracks returns one page of favorited tracks (songs) for the user.
+func (s *Store) ListFavoriteTracks(ctx context.Context, token string, page Page) ([]*models.Favorite, string, error) {
+	return s.listFavorites(ctx, token, favoriteTrackKeyset, " AND song_id IS NOT NULL", page)
 }
 
-// ListFavoriteTracks returns all favorited tracks (songs) for the user.
-func (s *Store) ListFavoriteTracks(ctx context.Context, token string) ([]*models.Favorite, error) {
+func (s *Store) listFavorites(ctx context.Context, token string, keys keyset, condition string, page Page) ([]*models.Favorite, string, error) {
+	pageQ, err := keys.page(page)
+	if err != nil {
+		return nil, "", err
+	}
+
 	userID, err := s.UserIDByToken(ctx, token)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
+	args := []any{userID}
+	after, args := pageQ.where(args)
+	tail, args := pageQ.orderLimit(args)
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT id, user_id, song_id, album_id, created_at
 		FROM favorites
-		WHERE user_id = $1 AND song_id IS NOT NULL
-		ORDER BY created_at DESC`, userID)
+		WHERE user_id = $1`+condition+` AND `+after+`
+		`+tail, args...)
 	if err != nil {
-		return nil, fmt.Errorf("list favorite tracks: %w", err)
+		return nil, "", fmt.Errorf("list favorites: %w", err)
 	}
 	defer rows.Close()
 
@@ -305,15 +301,18 @@ func (s *Store) ListFavoriteTracks(ctx context.Context, token string) ([]*models
 	for rows.Next() {
 		var fav models.Favorite
 		if err := rows.Scan(&fav.ID, &fav.UserID, &fav.SongID, &fav.AlbumID, &fav.CreatedAt); err != nil {
-			return nil, fmt.Errorf("scan favorite track: %w", err)
+			return nil, "", fmt.Errorf("scan favorite: %w", err)
 		}
 		favorites = append(favorites, &fav)
 	}
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate favorite tracks: %w", err)
+		return nil, "", fmt.Errorf("iterate favorites: %w", err)
 	}
 
-	return favorites, nil
+	favorites, next := finishPage(pageQ, favorites, func(f *models.Favorite) []any {
+		return []any{f.CreatedAt, f.ID}
+	})
+	return favorites, next, nil
 }
 
 // IsFavorite checks if a song or album is favorited by the user.
@@ -0,0 +1,403 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"vinylhound/shared/go/models"
+)
+
+var (
+	// ErrGenreNotFound signals a missing genre.
+	ErrGenreNotFound = errors.New("genre not found")
+	// ErrInvalidGenre indicates an empty name, an unknown parent or a parent
+	// that would make a genre its own ancestor.
+	ErrInvalidGenre = errors.New("invalid genre")
+	// ErrGenreConflict signals a genre name or alias that already names
+	// another genre.
+	ErrGenreConflict = errors.New("genre name or alias already in use")
+)
+
+// Genre is an entry of the genre taxonomy. Albums and artists keep genres as
+// free text; a genre gives a name its canonical spelling, its place under a
+// parent genre and the aliases it is also known by. Names are matched by key
+// (see genre_key in migration 0028), so spellings that differ only in case,
+// accents or punctuation need no alias.
+type Genre struct {
+	ID       int64    `json:"id"`
+	Name     string   `json:"name"`
+	ParentID *int64   `json:"parentId,omitempty"`
+	Aliases  []string `json:"aliases"`
+}
+
+// GenrePatch holds the fields of a genre to change; nil fields are kept. A
+// ParentID of zero moves the genre to the top level, and Aliases replaces
+// the whole alias list.
+type GenrePatch struct {
+	Name     *string
+	ParentID *int64
+	Aliases  *[]string
+}
+
+// genreMatch returns a condition that holds when the genres JSONB array in
+// column names the genre bound to the placeholder, one of its sub-genres, or
+// an alias of either.
+func genreMatch(column string, placeholder int) string {
+	return fmt.Sprintf(
+		"EXISTS (SELECT 1 FROM jsonb_array_elements_text(%s) AS genre(name) WHERE genre_key(genre.name) IN (SELECT genre_subtree_keys($%d)))",
+		column, placeholder)
+}
+
+// ListGenres returns the whole taxonomy ordered by name. Clients build the
+// hierarchy from ParentID.
+func (s *Store) ListGenres(ctx context.Context) ([]Genre, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT g.id, g.name, g.parent_id,
+			COALESCE(jsonb_agg(a.alias ORDER BY a.alias) FILTER (WHERE a.alias IS NOT NULL), '[]'::jsonb)
+		FROM genres g
+		LEFT JOIN genre_aliases a ON a.genre_id = g.id
+		GROUP BY g.id
+		ORDER BY g.name, g.id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list genres: %w", err)
+	}
+	defer rows.Close()
+
+	var genres []Genre
+	for rows.Next() {
+		genre, err := scanGenre(rows)
+		if err != nil {
+			return nil, err
+		}
+		genres = append(genres, genre)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate genres: %w", err)
+	}
+	return genres, nil
+}
+
+// GenreByID returns a single genre with its aliases.
+func (s *Store) GenreByID(ctx context.Context, id int64) (Genre, error) {
+	return s.genreByID(ctx, s.db, id)
+}
+
+// CreateGenre adds a genre to the taxonomy. Only curators and admins may
+// change it.
+func (s *Store) CreateGenre(ctx context.Context, token string, genre Genre) (Genre, error) {
+	if _, err := s.RequireRole(ctx, token, models.RoleCurator); err != nil {
+		return Genre{}, err
+	}
+	genre.Name = strings.TrimSpace(genre.Name)
+	if genre.Name == "" {
+		return Genre{}, fmt.Errorf("%w: name is required", ErrInvalidGenre)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Genre{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkGenreNameTx(ctx, tx, genre.Name, 0); err != nil {
+		return Genre{}, err
+	}
+	if genre.ParentID != nil {
+		if err := checkGenreParentTx(ctx, tx, 0, *genre.ParentID); err != nil {
+			return Genre{}, err
+		}
+	}
+
+	var id int64
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO genres (name, parent_id) VALUES ($1, $2) RETURNING id
+	`, genre.Name, genre.ParentID).Scan(&id); err != nil {
+		if isUniqueViolation(err) {
+			return Genre{}, ErrGenreConflict
+		}
+		return Genre{}, fmt.Errorf("insert genre: %w", err)
+	}
+	if err := setGenreAliasesTx(ctx, tx, id, genre.Aliases); err != nil {
+		return Genre{}, err
+	}
+
+	created, err := s.genreByID(ctx, tx, id)
+	if err != nil {
+		return Genre{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Genre{}, fmt.Errorf("commit tx: %w", err)
+	}
+	return created, nil
+}
+
+// UpdateGenre renames, moves or re-aliases a genre. Only curators and admins
+// may change the taxonomy.
+func (s *Store) UpdateGenre(ctx context.Context, token string, id int64, patch GenrePatch) (Genre, error) {
+	if _, err := s.RequireRole(ctx, token, models.RoleCurator); err != nil {
+		return Genre{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Genre{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT TRUE FROM genres WHERE id = $1 FOR UPDATE`, id).Scan(&exists); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Genre{}, ErrGenreNotFound
+		}
+		return Genre{}, fmt.Errorf("lock genre: %w", err)
+	}
+
+	if patch.Name != nil {
+		name := strings.TrimSpace(*patch.Name)
+		if name == "" {
+			return Genre{}, fmt.Errorf("%w: name is required", ErrInvalidGenre)
+		}
+		if err := checkGenreNameTx(ctx, tx, name, id); err != nil {
+			return Genre{}, err
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE genres SET name = $2 WHERE id = $1`, id, name); err != nil {
+			if isUniqueViolation(err) {
+				return Genre{}, ErrGenreConflict
+			}
+			return Genre{}, fmt.Errorf("rename genre: %w", err)
+		}
+	}
+	if patch.ParentID != nil {
+		var parent *int64
+		if *patch.ParentID != 0 {
+			if err := checkGenreParentTx(ctx, tx, id, *patch.ParentID); err != nil {
+				return Genre{}, err
+			}
+			parent = patch.ParentID
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE genres SET parent_id = $2 WHERE id = $1`, id, parent); err != nil {
+			return Genre{}, fmt.Errorf("move genre: %w", err)
+		}
+	}
+	if patch.Aliases != nil {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM genre_aliases WHERE genre_id = $1`, id); err != nil {
+			return Genre{}, fmt.Errorf("clear genre aliases: %w", err)
+		}
+		if err := setGenreAliasesTx(ctx, tx, id, *patch.Aliases); err != nil {
+			return Genre{}, err
+		}
+	}
+
+	updated, err := s.genreByID(ctx, tx, id)
+	if err != nil {
+		return Genre{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Genre{}, fmt.Errorf("commit tx: %w", err)
+	}
+	return updated, nil
+}
+
+// ResolveGenres maps genre names from a provider onto the taxonomy and
+// returns their canonical names, without repeats. Names the taxonomy does not
+// know are added to it: under the longest known genre their name ends with,
+// so "uk post-punk" lands below Post-Punk, or at the top level otherwise.
+func (s *Store) ResolveGenres(ctx context.Context, names []string) ([]string, error) {
+	resolved := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		canonical, err := s.resolveGenre(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[canonical] {
+			seen[canonical] = true
+			resolved = append(resolved, canonical)
+		}
+	}
+	return resolved, nil
+}
+
+func (s *Store) resolveGenre(ctx context.Context, name string) (string, error) {
+	id, canonical, err := s.lookupGenre(ctx, name)
+	if err != nil || id != 0 {
+		return canonical, err
+	}
+
+	var parent *int64
+	words := strings.Fields(name)
+	for i := 1; i < len(words) && parent == nil; i++ {
+		parentID, _, err := s.lookupGenre(ctx, strings.Join(words[i:], " "))
+		if err != nil {
+			return "", err
+		}
+		if parentID != 0 {
+			parent = &parentID
+		}
+	}
+
+	// Another import may add the same genre concurrently; the conflict is
+	// resolved by reading the winner back.
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO genres (name, parent_id) VALUES ($1, $2) ON CONFLICT DO NOTHING
+	`, name, parent); err != nil {
+		return "", fmt.Errorf("insert genre: %w", err)
+	}
+	id, canonical, err = s.lookupGenre(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if id == 0 {
+		return "", fmt.Errorf("%w: %q has no letters or digits", ErrInvalidGenre, name)
+	}
+	return canonical, nil
+}
+
+// lookupGenre returns the genre a name or alias refers to, or a zero id.
+func (s *Store) lookupGenre(ctx context.Context, name string) (int64, string, error) {
+	var (
+		id        int64
+		canonical string
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT id, name FROM genres WHERE id = genre_id_for($1)
+	`, name).Scan(&id, &canonical)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, "", nil
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("lookup genre: %w", err)
+	}
+	return id, canonical, nil
+}
+
+func (s *Store) genreByID(ctx context.Context, q queryRower, id int64) (Genre, error) {
+	genre, err := scanGenre(q.QueryRowContext(ctx, `
+		SELECT g.id, g.name, g.parent_id,
+			COALESCE(jsonb_agg(a.alias ORDER BY a.alias) FILTER (WHERE a.alias IS NOT NULL), '[]'::jsonb)
+		FROM genres g
+		LEFT JOIN genre_aliases a ON a.genre_id = g.id
+		WHERE g.id = $1
+		GROUP BY g.id
+	`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Genre{}, ErrGenreNotFound
+	}
+	return genre, err
+}
+
+func scanGenre(scanner interface{ Scan(...any) error }) (Genre, error) {
+	var (
+		genre       Genre
+		parentID    sql.NullInt64
+		aliasesJSON []byte
+	)
+	if err := scanner.Scan(&genre.ID, &genre.Name, &parentID, &aliasesJSON); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Genre{}, err
+		}
+		return Genre{}, fmt.Errorf("scan genre: %w", err)
+	}
+	if parentID.Valid {
+		genre.ParentID = &parentID.Int64
+	}
+	if err := json.Unmarshal(aliasesJSON, &genre.Aliases); err != nil {
+		return Genre{}, fmt.Errorf("decode genre aliases: %w", err)
+	}
+	return genre, nil
+}
+
+// checkGenreNameTx rejects a name that is already an alias of another genre.
+// Clashes with other genre names are caught by the unique index.
+func checkGenreNameTx(ctx context.Context, tx *sql.Tx, name string, id int64) error {
+	var taken bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM genre_aliases WHERE key = genre_key($1) AND genre_id <> $2)
+	`, name, id).Scan(&taken); err != nil {
+		return fmt.Errorf("check genre name: %w", err)
+	}
+	if taken {
+		return ErrGenreConflict
+	}
+	return nil
+}
+
+// checkGenreParentTx ensures the parent exists and is not the genre itself or
+// one of its sub-genres. id is zero for a new genre.
+func checkGenreParentTx(ctx context.Context, tx *sql.Tx, id, parentID int64) error {
+	var (
+		found bool
+		cycle bool
+	)
+	err := tx.QueryRowContext(ctx, `
+		WITH RECURSIVE ancestors(id, parent_id) AS (
+			SELECT id, parent_id FROM genres WHERE id = $1
+			UNION
+			SELECT g.id, g.parent_id FROM genres g JOIN ancestors a ON g.id = a.parent_id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $1),
+			EXISTS (SELECT 1 FROM ancestors WHERE id = $2)
+	`, parentID, id).Scan(&found, &cycle)
+	if err != nil {
+		return fmt.Errorf("check genre parent: %w", err)
+	}
+	if !found {
+		return fmt.Errorf("%w: parent genre %d does not exist", ErrInvalidGenre, parentID)
+	}
+	if cycle {
+		return fmt.Errorf("%w: a genre cannot be placed below itself", ErrInvalidGenre)
+	}
+	return nil
+}
+
+// setGenreAliasesTx adds aliases to a genre. Aliases that only respell the
+// genre's own name are dropped; one naming another genre is a conflict.
+func setGenreAliasesTx(ctx context.Context, tx *sql.Tx, id int64, aliases []string) error {
+	for _, alias := range aliases {
+		alias = strings.TrimSpace(alias)
+		if alias == "" {
+			continue
+		}
+
+		var owner sql.NullInt64
+		if err := tx.QueryRowContext(ctx, `
+			SELECT id FROM genres WHERE genre_key(name) = genre_key($1)
+		`, alias).Scan(&owner); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("check genre alias: %w", err)
+		}
+		if owner.Valid {
+			if owner.Int64 == id {
+				continue
+			}
+			return fmt.Errorf("%w: %q is the name of another genre", ErrGenreConflict, alias)
+		}
+
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO genre_aliases (key, alias, genre_id) VALUES (genre_key($1), $1, $2)
+			ON CONFLICT (key) DO UPDATE SET alias = EXCLUDED.alias
+			WHERE genre_aliases.genre_id = EXCLUDED.genre_id
+		`, alias, id); err != nil {
+			return fmt.Errorf("insert genre alias: %w", err)
+		}
+		var aliasOwner int64
+		if err := tx.QueryRowContext(ctx, `
+			SELECT genre_id FROM genre_aliases WHERE key = genre_key($1)
+		`, alias).Scan(&aliasOwner); err != nil {
+			return fmt.Errorf("check genre alias: %w", err)
+		}
+		if aliasOwner != id {
+			return fmt.Errorf("%w: %q is an alias of another genre", ErrGenreConflict, alias)
+		}
+	}
+	return nil
+}
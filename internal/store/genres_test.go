@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"vinylhound/internal/queryspec"
+)
+
+func expectGenreLookup(mock sqlmock.Sqlmock, name string, id int64, canonical string) {
+	rows := sqlmock.NewRows([]string{"id", "name"})
+	if id != 0 {
+		rows.AddRow(id, canonical)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, name FROM genres WHERE id = genre_id_for($1)`)).
+		WithArgs(name).
+		WillReturnRows(rows)
+}
+
+func TestResolveGenres(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	// Known names and aliases resolve to their canonical name, once.
+	expectGenreLookup(mock, "post punk", 21, "Post-Punk")
+	expectGenreLookup(mock, "Post-Punk", 21, "Post-Punk")
+	expectGenreLookup(mock, "rap", 3, "Hip Hop")
+
+	// An unknown name is added below the longest known genre it ends with.
+	expectGenreLookup(mock, "uk indie post-punk", 0, "")
+	expectGenreLookup(mock, "indie post-punk", 0, "")
+	expectGenreLookup(mock, "post-punk", 21, "Post-Punk")
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO genres (name, parent_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`)).
+		WithArgs("uk indie post-punk", int64(21)).
+		WillReturnResult(sqlmock.NewResult(40, 1))
+	expectGenreLookup(mock, "uk indie post-punk", 40, "uk indie post-punk")
+
+	// Without a known suffix it becomes a top-level genre.
+	expectGenreLookup(mock, "zamrock", 0, "")
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO genres (name, parent_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`)).
+		WithArgs("zamrock", nil).
+		WillReturnResult(sqlmock.NewResult(41, 1))
+	expectGenreLookup(mock, "zamrock", 41, "zamrock")
+
+	got, err := s.ResolveGenres(context.Background(), []string{"post punk", "Post-Punk", " ", "rap", "uk indie post-punk", "zamrock"})
+	if err != nil {
+		t.Fatalf("ResolveGenres: %v", err)
+	}
+	if want := []string{"Post-Punk", "Hip Hop", "uk indie post-punk", "zamrock"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUpdateGenreRejectsCycles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	expectSessionLookup(mock, "token", 1)
+	expectRoleLookup(mock, 1, "curator")
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT TRUE FROM genres WHERE id = $1 FOR UPDATE`)).
+		WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`WITH RECURSIVE ancestors`).
+		WithArgs(int64(21), int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"found", "cycle"}).AddRow(true, true))
+	mock.ExpectRollback()
+
+	parent := int64(21)
+	if _, err := s.UpdateGenre(context.Background(), "token", 1, GenrePatch{ParentID: &parent}); !errors.Is(err, ErrInvalidGenre) {
+		t.Fatalf("expected ErrInvalidGenre, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestListAlbumsMatchesGenreSubtree(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE EXISTS (SELECT 1 FROM jsonb_array_elements_text(genres) AS genre(name) WHERE genre_key(genre.name) IN (SELECT genre_subtree_keys($1)))`)).
+		WithArgs("Punk", 51).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "artist", "title", "release_year", "genres", "rating"}))
+
+	if _, _, err := s.ListAlbums(AlbumFilter{Genre: " Punk "}, queryspec.Spec{}, Page{}); err != nil {
+		t.Fatalf("ListAlbums: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
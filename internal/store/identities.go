@@ -0,0 +1,401 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// OIDCLoginStateTTL bounds how long a user may take at the identity provider.
+const OIDCLoginStateTTL = 10 * time.Minute
+
+var (
+	// ErrInvalidLoginState indicates an unknown, used or expired OIDC login state.
+	ErrInvalidLoginState = errors.New("invalid or expired login state")
+	// ErrIdentityNotFound signals a missing or foreign linked identity.
+	ErrIdentityNotFound = errors.New("identity not found")
+	// ErrIdentityLinked signals an external identity already linked to another account.
+	ErrIdentityLinked = errors.New("identity is linked to another account")
+	// ErrLastLoginMethod prevents unlinking the only way a user can sign in.
+	ErrLastLoginMethod = errors.New("cannot unlink the last sign-in method of an account without a password")
+)
+
+// ExternalIdentity is an identity asserted by an OpenID Connect provider.
+type ExternalIdentity struct {
+	Provider          string
+	Issuer            string
+	Subject           string
+	Email             string
+	PreferredUsername string
+}
+
+// Identity is an external identity linked to a user.
+type Identity struct {
+	ID          int64      `json:"id"`
+	Provider    string     `json:"provider"`
+	Issuer      string     `json:"issuer"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// OIDCLoginState holds the secrets of a login waiting for the provider
+// callback. Binding is the secret held by the browser that started the
+// login. UserID is set when an authenticated user links an identity.
+type OIDCLoginState struct {
+	State        string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	Binding      string
+	UserID       int64
+}
+
+// CreateOIDCLoginState records a pending login. With an empty token the login
+// signs a user in; otherwise it links the identity to the user owning the
+// session token. Personal access tokens cannot link identities.
+func (s *Store) CreateOIDCLoginState(ctx context.Context, token string, state OIDCLoginState) error {
+	var userID sql.NullInt64
+	if token != "" {
+		id, err := s.userIDForSession(ctx, token)
+		if err != nil {
+			return err
+		}
+		userID = sql.NullInt64{Int64: id, Valid: true}
+	}
+
+	// Abandoned logins are cleared whenever a new one starts.
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM oidc_login_states
+		WHERE expires_at <= NOW()
+	`); err != nil {
+		return fmt.Errorf("purge login states: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO oidc_login_states (state_hash, provider, nonce, code_verifier, binding_hash, user_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, hashToken(state.State), state.Provider, state.Nonce, state.CodeVerifier, hashToken(state.Binding), userID, time.Now().Add(OIDCLoginStateTTL)); err != nil {
+		return fmt.Errorf("store login state: %w", err)
+	}
+	return nil
+}
+
+// ConsumeOIDCLoginState returns and deletes the pending login for a state
+// value, so each state can complete at most one login. The binding must be
+// the one the login was started with. A link also needs the session token
+// of the user who started it; with any other the state is used up and
+// ErrUnauthorized returned.
+func (s *Store) ConsumeOIDCLoginState(ctx context.Context, provider, state, binding, token string) (OIDCLoginState, error) {
+	if state == "" || binding == "" {
+		return OIDCLoginState{}, ErrInvalidLoginState
+	}
+
+	var userID sql.NullInt64
+	consumed := OIDCLoginState{State: state, Provider: provider, Binding: binding}
+	err := s.db.QueryRowContext(ctx, `
+		DELETE FROM oidc_login_states
+		WHERE state_hash = $1 AND provider = $2 AND binding_hash = $3 AND expires_at > NOW()
+		RETURNING nonce, code_verifier, user_id
+	`, hashToken(state), provider, hashToken(binding)).Scan(&consumed.Nonce, &consumed.CodeVerifier, &userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return OIDCLoginState{}, ErrInvalidLoginState
+		}
+		return OIDCLoginState{}, fmt.Errorf("consume login state: %w", err)
+	}
+	if userID.Valid {
+		if token == "" {
+			return OIDCLoginState{}, ErrUnauthorized
+		}
+		sessionUserID, err := s.userIDForSession(ctx, token)
+		if err != nil {
+			return OIDCLoginState{}, err
+		}
+		if sessionUserID != userID.Int64 {
+			return OIDCLoginState{}, ErrUnauthorized
+		}
+		consumed.UserID = userID.Int64
+	}
+	return consumed, nil
+}
+
+// AuthenticateExternal signs in the user linked to an external identity,
+// creating an account without a password on first sign-in.
+func (s *Store) AuthenticateExternal(ctx context.Context, identity ExternalIdentity, meta SessionMetadata) (SessionTokens, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var userID int64
+	err = tx.QueryRowContext(ctx, `
+		UPDATE user_identities
+		SET last_login_at = NOW(), email = COALESCE($3, email)
+		WHERE issuer = $1 AND subject = $2
+		RETURNING user_id
+	`, identity.Issuer, identity.Subject, nullIfEmpty(identity.Email)).Scan(&userID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		userID, err = createExternalUserTx(ctx, tx, identity)
+		if err != nil {
+			return SessionTokens{}, err
+		}
+		if _, err := insertIdentityTx(ctx, tx, userID, identity, true); err != nil {
+			return SessionTokens{}, err
+		}
+	case err != nil:
+		return SessionTokens{}, fmt.Errorf("lookup identity: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SessionTokens{}, fmt.Errorf("commit tx: %w", err)
+	}
+	tx = nil
+
+	return s.createSession(ctx, userID, meta)
+}
+
+// LinkIdentity links an external identity to the user recorded on a consumed
+// login state. Linking an identity the user already has is a no-op.
+func (s *Store) LinkIdentity(ctx context.Context, userID int64, identity ExternalIdentity) (Identity, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Identity{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var ownerID int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT user_id
+		FROM user_identities
+		WHERE issuer = $1 AND subject = $2
+	`, identity.Issuer, identity.Subject).Scan(&ownerID)
+	switch {
+	case err == nil && ownerID != userID:
+		return Identity{}, ErrIdentityLinked
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return Identity{}, fmt.Errorf("lookup identity: %w", err)
+	}
+
+	linked, err := insertIdentityTx(ctx, tx, userID, identity, false)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return Identity{}, fmt.Errorf("commit tx: %w", err)
+	}
+	tx = nil
+
+	return linked, nil
+}
+
+// IdentitiesByToken lists the external identities linked to the user owning the token.
+func (s *Store) IdentitiesByToken(ctx context.Context, token string) ([]Identity, error) {
+	userID, err := s.userIDForSession(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, provider, issuer, subject, COALESCE(email, ''), created_at, last_login_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("select identities: %w", err)
+	}
+	defer rows.Close()
+
+	var identities []Identity
+	for rows.Next() {
+		var (
+			identity  Identity
+			lastLogin sql.NullTime
+		)
+		if err := rows.Scan(&identity.ID, &identity.Provider, &identity.Issuer, &identity.Subject, &identity.Email, &identity.CreatedAt, &lastLogin); err != nil {
+			return nil, fmt.Errorf("scan identity: %w", err)
+		}
+		if lastLogin.Valid {
+			identity.LastLoginAt = &lastLogin.Time
+		}
+		identities = append(identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate identities: %w", err)
+	}
+
+	return identities, nil
+}
+
+// UnlinkIdentity removes one of the authenticated user's external identities.
+// Accounts without a password must keep at least one identity.
+func (s *Store) UnlinkIdentity(ctx context.Context, token string, identityID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	userID, err := s.userIDForTokenTx(ctx, tx, token)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM user_identities
+		WHERE id = $1 AND user_id = $2
+	`, identityID, userID)
+	if err != nil {
+		return fmt.Errorf("delete identity: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check deleted identity: %w", err)
+	}
+	if affected == 0 {
+		return ErrIdentityNotFound
+	}
+
+	var canSignIn bool
+	if err := tx.QueryRowContext(ctx, `
+		SELECT u.password_hash <> '' OR EXISTS (
+			SELECT 1 FROM user_identities i WHERE i.user_id = u.id
+		)
+		FROM users u
+		WHERE u.id = $1
+	`, userID).Scan(&canSignIn); err != nil {
+		return fmt.Errorf("check sign-in methods: %w", err)
+	}
+	if !canSignIn {
+		return ErrLastLoginMethod
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	tx = nil
+
+	return nil
+}
+
+// maxUsernameAttempts bounds the numbered suffixes tried for a new external
+// account before falling back to a random one.
+const maxUsernameAttempts = 20
+
+// createExternalUserTx creates a password-less account for a first external
+// sign-in, deriving a free username from the identity.
+func createExternalUserTx(ctx context.Context, tx *sql.Tx, identity ExternalIdentity) (int64, error) {
+	base := externalUsernameBase(identity)
+
+	for attempt := 1; attempt <= maxUsernameAttempts+1; attempt++ {
+		username := base
+		switch {
+		case attempt > maxUsernameAttempts:
+			suffix, err := newToken()
+			if err != nil {
+				return 0, fmt.Errorf("create username: %w", err)
+			}
+			username = base + "-" + strings.ToLower(suffix[:8])
+		case attempt > 1:
+			username = base + strconv.Itoa(attempt)
+		}
+
+		var userID int64
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO users (username, password_hash)
+			VALUES ($1, '')
+			ON CONFLICT (username) DO NOTHING
+			RETURNING id
+		`, username).Scan(&userID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return 0, fmt.Errorf("insert user: %w", err)
+		}
+
+		if err := createFavoritesPlaylistTx(ctx, tx, userID, username); err != nil {
+			return 0, err
+		}
+		return userID, nil
+	}
+
+	return 0, ErrUserExists
+}
+
+// externalUsernameBase picks a username from the preferred username or the
+// local part of the email, keeping letters, digits, dots, dashes and underscores.
+func externalUsernameBase(identity ExternalIdentity) string {
+	candidates := []string{identity.PreferredUsername}
+	if local, _, ok := strings.Cut(identity.Email, "@"); ok {
+		candidates = append(candidates, local)
+	}
+
+	for _, candidate := range candidates {
+		cleaned := strings.Map(func(r rune) rune {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' || r == '_' {
+				return r
+			}
+			return -1
+		}, candidate)
+		if len(cleaned) > 40 {
+			cleaned = cleaned[:40]
+		}
+		if cleaned != "" {
+			return cleaned
+		}
+	}
+	return "user"
+}
+
+func insertIdentityTx(ctx context.Context, tx *sql.Tx, userID int64, identity ExternalIdentity, signedIn bool) (Identity, error) {
+	linked := Identity{
+		Provider: identity.Provider,
+		Issuer:   identity.Issuer,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+	}
+
+	var lastLogin sql.NullTime
+	err := tx.QueryRowContext(ctx, `
+		INSERT INTO user_identities (user_id, provider, issuer, subject, email, last_login_at)
+		VALUES ($1, $2, $3, $4, $5, CASE WHEN $6 THEN NOW() END)
+		ON CONFLICT (issuer, subject) DO UPDATE
+		SET email = COALESCE(EXCLUDED.email, user_identities.email)
+		WHERE user_identities.user_id = EXCLUDED.user_id
+		RETURNING id, created_at, last_login_at
+	`, userID, identity.Provider, identity.Issuer, identity.Subject, nullIfEmpty(identity.Email), signedIn).Scan(&linked.ID, &linked.CreatedAt, &lastLogin)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Linked to another user concurrently.
+			return Identity{}, ErrIdentityLinked
+		}
+		return Identity{}, fmt.Errorf("insert identity: %w", err)
+	}
+	if lastLogin.Valid {
+		linked.LastLoginAt = &lastLogin.Time
+	}
+	return linked, nil
+}
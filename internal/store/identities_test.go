@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestExternalUsernameBase(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity ExternalIdentity
+		want     string
+	}{
+		{name: "preferred username", identity: ExternalIdentity{PreferredUsername: "ada.l", Email: "ada@example.com"}, want: "ada.l"},
+		{name: "email local part", identity: ExternalIdentity{Email: "grace+music@example.com"}, want: "gracemusic"},
+		{name: "unusable names", identity: ExternalIdentity{PreferredUsername: "  !!", Email: "not-an-email"}, want: "user"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := externalUsernameBase(tt.identity); got != tt.want {
+				t.Fatalf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConsumeOIDCLoginStateUnknown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM oidc_login_states`)).
+		WithArgs(hashToken("state"), "google", hashToken("binding")).
+		WillReturnError(sql.ErrNoRows)
+
+	if _, err := s.ConsumeOIDCLoginState(context.Background(), "google", "state", "binding", ""); !errors.Is(err, ErrInvalidLoginState) {
+		t.Fatalf("expected ErrInvalidLoginState, got %v", err)
+	}
+	if _, err := s.ConsumeOIDCLoginState(context.Background(), "google", "state", "", ""); !errors.Is(err, ErrInvalidLoginState) {
+		t.Fatalf("expected ErrInvalidLoginState without a binding, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestConsumeOIDCLoginStateChecksLinkingSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	for _, tt := range []struct {
+		token   string
+		session int64
+		wantErr error
+	}{
+		{token: "", wantErr: ErrUnauthorized},
+		{token: "other", session: 8, wantErr: ErrUnauthorized},
+		{token: "mine", session: 7},
+	} {
+		mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM oidc_login_states`)).
+			WithArgs(hashToken("state"), "google", hashToken("binding")).
+			WillReturnRows(sqlmock.NewRows([]string{"nonce", "code_verifier", "user_id"}).AddRow("nonce", "verifier", int64(7)))
+		if tt.token != "" {
+			expectSessionLookup(mock, tt.token, tt.session)
+		}
+
+		pending, err := s.ConsumeOIDCLoginState(context.Background(), "google", "state", "binding", tt.token)
+		if !errors.Is(err, tt.wantErr) {
+			t.Fatalf("token %q: expected %v, got %v", tt.token, tt.wantErr, err)
+		}
+		if err == nil && pending.UserID != 7 {
+			t.Fatalf("unexpected login state %+v", pending)
+		}
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestAuthenticateExternalCreatesAccount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	identity := ExternalIdentity{Provider: "google", Issuer: "https://accounts.google.com", Subject: "42", PreferredUsername: "ada"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE user_identities`)).
+		WithArgs(identity.Issuer, identity.Subject, nil).
+		WillReturnError(sql.ErrNoRows)
+	// "ada" is taken, "ada2" is free.
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users`)).
+		WithArgs("ada").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO users`)).
+		WithArgs("ada2").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(9)))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO playlists`)).
+		WithArgs("Favorites", sqlmock.AnyArg(), "ada2", int64(9)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO user_identities`)).
+		WithArgs(int64(9), "google", identity.Issuer, identity.Subject, nil, true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "last_login_at"}).AddRow(int64(1), time.Now(), time.Now()))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO sessions`)).
+		WithArgs(sqlmock.AnyArg(), int64(9), sqlmock.AnyArg(), nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(3)))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO refresh_tokens`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	tokens, err := s.AuthenticateExternal(context.Background(), identity, SessionMetadata{})
+	if err != nil {
+		t.Fatalf("AuthenticateExternal: %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("expected session tokens, got %+v", tokens)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"vinylhound/shared/go/config"
+)
+
+// ErrTooManyLoginAttempts signals that login is temporarily blocked for the
+// username or client address. Use errors.As with *LoginThrottleError to read
+// the retry delay.
+var ErrTooManyLoginAttempts = errors.New("too many failed login attempts")
+
+// Login attempt outcomes recorded in the audit table.
+const (
+	// An attempt that passed the throttle and is still being checked.
+	loginOutcomePending            = "pending"
+	loginOutcomeSuccess            = "success"
+	loginOutcomeInvalidCredentials = "invalid_credentials"
+	loginOutcomeThrottled          = "throttled"
+	loginOutcomeLocked             = "locked"
+	// A correct password on an account with 2FA; success is recorded once
+	// the second factor is accepted.
+	loginOutcomeTwoFactorRequired   = "two_factor_required"
+	loginOutcomeInvalidSecondFactor = "invalid_second_factor"
+)
+
+// LoginThrottleError reports how long a client must wait before trying to
+// log in again.
+type LoginThrottleError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottleError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("%s: account temporarily locked, retry in %s", ErrTooManyLoginAttempts, e.RetryAfter.Round(time.Second))
+	}
+	return fmt.Sprintf("%s: retry in %s", ErrTooManyLoginAttempts, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LoginThrottleError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// SetLoginProtection overrides the default failed-login backoff and lockout.
+func (s *Store) SetLoginProtection(policy config.LoginProtectionConfig) {
+	s.loginPolicy = policy
+}
+
+// loginFailures summarises recent failed attempts for a username and address.
+type loginFailures struct {
+	user     int
+	lastUser time.Time
+	ip       int
+	lastIP   time.Time
+}
+
+// beginLoginAttempt checks the throttle for the username and client address
+// and, when login is allowed, records the attempt as pending in the same
+// transaction. Advisory locks on the username and address serialise
+// concurrent attempts and pending attempts count as failures, so parallel
+// requests cannot all pass the check. The returned attempt is settled with
+// finishLoginAttempt; one that never is keeps counting as a failure. A
+// blocked attempt is recorded and returned as a *LoginThrottleError.
+func (s *Store) beginLoginAttempt(ctx context.Context, username string, meta SessionMetadata) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	locks := []string{"login_attempts:username:" + username}
+	if meta.IPAddress != "" {
+		locks = append(locks, "login_attempts:ip:"+meta.IPAddress)
+	}
+	for _, key := range locks {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, key); err != nil {
+			return 0, fmt.Errorf("lock login attempts: %w", err)
+		}
+	}
+
+	failures, err := s.recentLoginFailures(ctx, tx, username, meta.IPAddress)
+	if err != nil {
+		return 0, err
+	}
+
+	throttle := loginThrottle(s.loginPolicy, failures, time.Now())
+	outcome := loginOutcomePending
+	if throttle != nil {
+		outcome = loginOutcomeThrottled
+		if throttle.Locked {
+			outcome = loginOutcomeLocked
+		}
+	}
+
+	var id int64
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO login_attempts (username, ip_address, user_agent, outcome)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, username, nullIfEmpty(meta.IPAddress), nullIfEmpty(meta.UserAgent), outcome).Scan(&id); err != nil {
+		return 0, fmt.Errorf("record login attempt: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit login attempt: %w", err)
+	}
+	if throttle != nil {
+		return 0, throttle
+	}
+	return id, nil
+}
+
+// finishLoginAttempt records the outcome of a pending attempt.
+func (s *Store) finishLoginAttempt(ctx context.Context, id int64, outcome string) error {
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE login_attempts SET outcome = $2 WHERE id = $1
+	`, id, outcome); err != nil {
+		return fmt.Errorf("record login attempt: %w", err)
+	}
+	return nil
+}
+
+// discardLoginAttempt removes a pending attempt that turned out not to be a
+// login, such as a correct code confirming a 2FA change.
+func (s *Store) discardLoginAttempt(ctx context.Context, id int64) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM login_attempts WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("discard login attempt: %w", err)
+	}
+	return nil
+}
+
+// loginFailed audits a rejected password and returns ErrInvalidCredentials.
+func (s *Store) loginFailed(ctx context.Context, attempt int64) error {
+	if err := s.finishLoginAttempt(ctx, attempt, loginOutcomeInvalidCredentials); err != nil {
+		return err
+	}
+	return ErrInvalidCredentials
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// recentLoginFailures counts failed attempts, wrong passwords and wrong
+// second-factor codes alike, and pending ones inside the policy window.
+// Failures for the username only count since its last successful login.
+func (s *Store) recentLoginFailures(ctx context.Context, q queryRower, username, ipAddress string) (loginFailures, error) {
+	var (
+		failures         loginFailures
+		lastUser, lastIP sql.NullTime
+	)
+	err := q.QueryRowContext(ctx, `
+		SELECT
+			COUNT(*) FILTER (WHERE username = $1 AND created_at > COALESCE((
+				SELECT MAX(created_at) FROM login_attempts
+				WHERE username = $1 AND outcome = 'success'
+			), '-infinity')),
+			MAX(created_at) FILTER (WHERE username = $1),
+			COUNT(*) FILTER (WHERE ip_address = $2),
+			MAX(created_at) FILTER (WHERE ip_address = $2)
+		FROM login_attempts
+		WHERE outcome IN ('pending', 'invalid_credentials', 'invalid_second_factor')
+		  AND created_at > NOW() - make_interval(secs => $3)
+		  AND (username = $1 OR ip_address = $2)
+	`, username, nullIfEmpty(ipAddress), s.loginPolicy.Window.Seconds()).Scan(&failures.user, &lastUser, &failures.ip, &lastIP)
+	if err != nil {
+		return loginFailures{}, fmt.Errorf("count login failures: %w", err)
+	}
+
+	failures.lastUser = lastUser.Time
+	failures.lastIP = lastIP.Time
+	return failures, nil
+}
+
+// loginThrottle applies the policy to recent failures. Past FreeAttempts each
+// further username failure doubles the wait from BaseDelay up to MaxDelay;
+// MaxAttempts username failures or MaxAttemptsIP address failures lock login
+// for LockoutDuration after the latest failure.
+func loginThrottle(policy config.LoginProtectionConfig, failures loginFailures, now time.Time) *LoginThrottleError {
+	if failures.ip >= policy.MaxAttemptsIP {
+		if wait := failures.lastIP.Add(policy.LockoutDuration).Sub(now); wait > 0 {
+			return &LoginThrottleError{RetryAfter: wait, Locked: true}
+		}
+	}
+
+	if failures.user >= policy.MaxAttempts {
+		if wait := failures.lastUser.Add(policy.LockoutDuration).Sub(now); wait > 0 {
+			return &LoginThrottleError{RetryAfter: wait, Locked: true}
+		}
+		return nil
+	}
+
+	if failures.user >= policy.FreeAttempts {
+		delay := policy.BaseDelay
+		for i := policy.FreeAttempts; i < failures.user && delay < policy.MaxDelay; i++ {
+			delay *= 2
+		}
+		if delay > policy.MaxDelay {
+			delay = policy.MaxDelay
+		}
+		if wait := failures.lastUser.Add(delay).Sub(now); wait > 0 {
+			return &LoginThrottleError{RetryAfter: wait}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"vinylhound/shared/go/config"
+)
+
+// expectLoginAttempt expects demo's attempt from 203.0.113.9 to be checked
+// against the given failures and recorded with outcome as attempt 1.
+func expectLoginAttempt(mock sqlmock.Sqlmock, user int, lastUser time.Time, ip int, lastIP time.Time, outcome string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock(hashtext($1))`)).
+		WithArgs("login_attempts:username:demo").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock(hashtext($1))`)).
+		WithArgs("login_attempts:ip:203.0.113.9").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM login_attempts`)).
+		WithArgs("demo", "203.0.113.9", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"user", "last_user", "ip", "last_ip"}).
+			AddRow(user, nullTime(lastUser), ip, nullTime(lastIP)))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO login_attempts`)).
+		WithArgs("demo", "203.0.113.9", "curl", outcome).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)))
+	mock.ExpectCommit()
+}
+
+// expectLoginOutcome expects attempt 1 to be settled with outcome.
+func expectLoginOutcome(mock sqlmock.Sqlmock, outcome string) {
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE login_attempts SET outcome = $2 WHERE id = $1`)).
+		WithArgs(int64(1), outcome).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+func TestLoginThrottle(t *testing.T) {
+	policy := config.DefaultLoginProtection()
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		failures   loginFailures
+		wantWait   time.Duration
+		wantLocked bool
+	}{
+		{
+			name:     "no failures",
+			failures: loginFailures{},
+		},
+		{
+			name:     "within free attempts",
+			failures: loginFailures{user: 2, lastUser: now},
+		},
+		{
+			name:     "first backoff",
+			failures: loginFailures{user: 3, lastUser: now},
+			wantWait: time.Second,
+		},
+		{
+			name:     "backoff doubles",
+			failures: loginFailures{user: 5, lastUser: now},
+			wantWait: 4 * time.Second,
+		},
+		{
+			name:     "backoff capped",
+			failures: loginFailures{user: 9, lastUser: now},
+			wantWait: time.Minute,
+		},
+		{
+			name:     "backoff elapsed",
+			failures: loginFailures{user: 5, lastUser: now.Add(-5 * time.Second)},
+		},
+		{
+			name:       "username locked",
+			failures:   loginFailures{user: 10, lastUser: now},
+			wantWait:   15 * time.Minute,
+			wantLocked: true,
+		},
+		{
+			name:     "username lockout expired",
+			failures: loginFailures{user: 10, lastUser: now.Add(-16 * time.Minute)},
+		},
+		{
+			name:       "address locked",
+			failures:   loginFailures{ip: 50, lastIP: now},
+			wantWait:   15 * time.Minute,
+			wantLocked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := loginThrottle(policy, tt.failures, now)
+			if tt.wantWait == 0 {
+				if got != nil {
+					t.Fatalf("expected no throttle, got %v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatalf("expected throttle of %s, got none", tt.wantWait)
+			}
+			if got.RetryAfter != tt.wantWait || got.Locked != tt.wantLocked {
+				t.Fatalf("got wait %s locked %v, want %s locked %v", got.RetryAfter, got.Locked, tt.wantWait, tt.wantLocked)
+			}
+		})
+	}
+}
+
+func TestLoginThrottleWithoutFreeAttempts(t *testing.T) {
+	policy := config.DefaultLoginProtection()
+	policy.FreeAttempts = 0
+	now := time.Now()
+
+	if got := loginThrottle(policy, loginFailures{}, now); got != nil {
+		t.Fatalf("expected the first attempt to go through, got %v", got)
+	}
+	got := loginThrottle(policy, loginFailures{user: 1, lastUser: now}, now)
+	if got == nil || got.RetryAfter != 2*time.Second || got.Locked {
+		t.Fatalf("expected a backoff from the first failure, got %v", got)
+	}
+}
+
+func TestAuthenticateLockedOutSkipsPasswordCheck(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	meta := SessionMetadata{UserAgent: "curl", IPAddress: "203.0.113.9"}
+
+	expectLoginAttempt(mock, 10, time.Now(), 10, time.Now(), loginOutcomeLocked)
+
+	_, err = s.Authenticate("demo", "guess", meta)
+	if !errors.Is(err, ErrTooManyLoginAttempts) {
+		t.Fatalf("expected ErrTooManyLoginAttempts, got %v", err)
+	}
+	var throttle *LoginThrottleError
+	if !errors.As(err, &throttle) || !throttle.Locked || throttle.RetryAfter <= 0 {
+		t.Fatalf("expected lockout with retry delay, got %#v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestAuthenticateRecordsFailedAttempt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	meta := SessionMetadata{UserAgent: "curl", IPAddress: "203.0.113.9"}
+
+	expectLoginAttempt(mock, 0, time.Time{}, 0, time.Time{}, loginOutcomePending)
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, password_hash, totp_enabled_at IS NOT NULL
+		FROM users
+		WHERE username = $1
+	`)).
+		WithArgs("demo").
+		WillReturnError(sql.ErrNoRows)
+	expectLoginOutcome(mock, loginOutcomeInvalidCredentials)
+
+	if _, err := s.Authenticate("demo", "guess", meta); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,205 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// ProviderRef names an item in a music provider's catalog.
+type ProviderRef struct {
+	Provider    string `json:"provider"`
+	ExternalID  string `json:"external_id"`
+	ExternalURL string `json:"external_url,omitempty"`
+}
+
+// ProviderMatch is a group of provider items known to be the same artist,
+// album or track. LocalID is the local entity one of them was imported as,
+// or 0.
+type ProviderMatch struct {
+	ID         int64
+	EntityType string
+	LocalID    int64
+	Refs       []ProviderRef
+}
+
+// matchLocalID selects the local entity of match m from the external IDs
+// of its links.
+const matchLocalID = `(
+	SELECT MIN(e.local_id)
+	FROM provider_match_links o
+	JOIN external_ids e ON e.entity_type = o.entity_type AND e.provider = o.provider AND e.external_id = o.external_id
+	WHERE o.match_id = m.id
+)`
+
+// ProviderMatches returns the recorded matches that contain any of refs,
+// each with all of its refs.
+func (s *Store) ProviderMatches(ctx context.Context, entityType string, refs []ProviderRef) ([]ProviderMatch, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	providers, ids := splitRefs(refs)
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT m.id, `+matchLocalID+`, l.provider, l.external_id, COALESCE(l.external_url, '')
+		FROM provider_matches m
+		JOIN provider_match_links l ON l.match_id = m.id
+		WHERE m.id IN (
+			SELECT match_id FROM provider_match_links
+			WHERE entity_type = $1
+			  AND (provider, external_id) IN (SELECT * FROM unnest($2::text[], $3::text[]))
+		)
+		ORDER BY m.id, l.provider, l.external_id
+	`, entityType, pq.Array(providers), pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("select provider matches: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []ProviderMatch
+	for rows.Next() {
+		var (
+			id      int64
+			localID sql.NullInt64
+			ref     ProviderRef
+		)
+		if err := rows.Scan(&id, &localID, &ref.Provider, &ref.ExternalID, &ref.ExternalURL); err != nil {
+			return nil, fmt.Errorf("scan provider match: %w", err)
+		}
+		if n := len(matches); n == 0 || matches[n-1].ID != id {
+			matches = append(matches, ProviderMatch{ID: id, EntityType: entityType, LocalID: localID.Int64})
+		}
+		last := &matches[len(matches)-1]
+		last.Refs = append(last.Refs, ref)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate provider matches: %w", err)
+	}
+	return matches, nil
+}
+
+// RecordProviderMatch records that refs name the same entity and returns
+// the resulting match. Matches the refs already belong to are joined into
+// the oldest one, except matches of a different local entity, whose refs
+// stay where they are.
+func (s *Store) RecordProviderMatch(ctx context.Context, entityType string, refs []ProviderRef) (ProviderMatch, error) {
+	if len(refs) == 0 {
+		return ProviderMatch{}, fmt.Errorf("record provider match: no provider IDs")
+	}
+	providers, ids := splitRefs(refs)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ProviderMatch{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT m.id, `+matchLocalID+`
+		FROM provider_matches m
+		WHERE m.id IN (
+			SELECT match_id FROM provider_match_links
+			WHERE entity_type = $1
+			  AND (provider, external_id) IN (SELECT * FROM unnest($2::text[], $3::text[]))
+		)
+		ORDER BY m.id
+		FOR UPDATE
+	`, entityType, pq.Array(providers), pq.Array(ids))
+	if err != nil {
+		return ProviderMatch{}, fmt.Errorf("lock provider matches: %w", err)
+	}
+	type existing struct{ id, localID int64 }
+	var found []existing
+	for rows.Next() {
+		var (
+			match  existing
+			linked sql.NullInt64
+		)
+		if err := rows.Scan(&match.id, &linked); err != nil {
+			rows.Close()
+			return ProviderMatch{}, fmt.Errorf("scan provider match: %w", err)
+		}
+		match.localID = linked.Int64
+		found = append(found, match)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return ProviderMatch{}, fmt.Errorf("iterate provider matches: %w", err)
+	}
+
+	var target int64
+	for _, match := range found {
+		if target == 0 {
+			target = match.localID
+		}
+	}
+	var keepID int64
+	var joined []int64
+	for _, match := range found {
+		if match.localID != 0 && match.localID != target {
+			continue
+		}
+		if keepID == 0 {
+			keepID = match.id
+		} else {
+			joined = append(joined, match.id)
+		}
+	}
+
+	if keepID == 0 {
+		if err := tx.QueryRowContext(ctx, `
+			INSERT INTO provider_matches (entity_type) VALUES ($1) RETURNING id
+		`, entityType).Scan(&keepID); err != nil {
+			return ProviderMatch{}, fmt.Errorf("insert provider match: %w", err)
+		}
+	}
+	if len(joined) > 0 {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE provider_match_links SET match_id = $1 WHERE match_id = ANY($2)
+		`, keepID, pq.Array(joined)); err != nil {
+			return ProviderMatch{}, fmt.Errorf("join provider matches: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM provider_matches WHERE id = ANY($1)`, pq.Array(joined)); err != nil {
+			return ProviderMatch{}, fmt.Errorf("delete joined provider matches: %w", err)
+		}
+	}
+	for _, ref := range refs {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO provider_match_links (match_id, entity_type, provider, external_id, external_url)
+			VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+			ON CONFLICT (entity_type, provider, external_id)
+			DO UPDATE SET external_url = COALESCE(EXCLUDED.external_url, provider_match_links.external_url)
+		`, keepID, entityType, ref.Provider, ref.ExternalID, ref.ExternalURL); err != nil {
+			return ProviderMatch{}, fmt.Errorf("insert provider match link: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE provider_matches SET updated_at = NOW() WHERE id = $1`, keepID); err != nil {
+		return ProviderMatch{}, fmt.Errorf("update provider match: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return ProviderMatch{}, fmt.Errorf("commit tx: %w", err)
+	}
+
+	matches, err := s.ProviderMatches(ctx, entityType, refs)
+	if err != nil {
+		return ProviderMatch{}, err
+	}
+	for _, match := range matches {
+		if match.ID == keepID {
+			return match, nil
+		}
+	}
+	return ProviderMatch{ID: keepID, EntityType: entityType, LocalID: target, Refs: refs}, nil
+}
+
+func splitRefs(refs []ProviderRef) (providers, ids []string) {
+	providers = make([]string, len(refs))
+	ids = make([]string, len(refs))
+	for i, ref := range refs {
+		providers[i], ids[i] = ref.Provider, ref.ExternalID
+	}
+	return providers, ids
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestRecordProviderMatchJoinsExistingMatches(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	refs := []ProviderRef{
+		{Provider: "spotify", ExternalID: "sp1", ExternalURL: "https://open.spotify.com/album/sp1"},
+		{Provider: "apple_music", ExternalID: "am1"},
+		{Provider: "apple_music", ExternalID: "am2"},
+	}
+	providers := pq.Array([]string{"spotify", "apple_music", "apple_music"})
+	ids := pq.Array([]string{"sp1", "am1", "am2"})
+
+	mock.ExpectBegin()
+	// Match 4 holds sp1 and is imported as album 7, match 6 holds am1 and is
+	// not imported, match 8 holds am2 and is imported as another album.
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM provider_matches m
+		WHERE m.id IN`)).
+		WithArgs(EntityAlbum, providers, ids).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "local_id"}).
+			AddRow(int64(4), int64(7)).
+			AddRow(int64(6), nil).
+			AddRow(int64(8), int64(9)))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE provider_match_links SET match_id = $1 WHERE match_id = ANY($2)`)).
+		WithArgs(int64(4), pq.Array([]int64{6})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM provider_matches WHERE id = ANY($1)`)).
+		WithArgs(pq.Array([]int64{6})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	for _, ref := range refs {
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO provider_match_links`)).
+			WithArgs(int64(4), EntityAlbum, ref.Provider, ref.ExternalID, ref.ExternalURL).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE provider_matches SET updated_at = NOW() WHERE id = $1`)).
+		WithArgs(int64(4)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM provider_matches m
+		JOIN provider_match_links l ON l.match_id = m.id`)).
+		WithArgs(EntityAlbum, providers, ids).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "local_id", "provider", "external_id", "external_url"}).
+			AddRow(int64(4), int64(7), "apple_music", "am1", "").
+			AddRow(int64(4), int64(7), "spotify", "sp1", "https://open.spotify.com/album/sp1").
+			AddRow(int64(8), int64(9), "apple_music", "am2", ""))
+
+	match, err := s.RecordProviderMatch(context.Background(), EntityAlbum, refs)
+	if err != nil {
+		t.Fatalf("RecordProviderMatch: %v", err)
+	}
+	if match.ID != 4 || match.LocalID != 7 || len(match.Refs) != 2 {
+		t.Fatalf("unexpected match %+v", match)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRecordProviderMatchCreatesMatch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	ref := ProviderRef{Provider: "spotify", ExternalID: "sp1"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM provider_matches m
+		WHERE m.id IN`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "local_id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO provider_matches (entity_type) VALUES ($1) RETURNING id`)).
+		WithArgs(EntityAlbum).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(11)))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO provider_match_links`)).
+		WithArgs(int64(11), EntityAlbum, "spotify", "sp1", "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE provider_matches SET updated_at = NOW() WHERE id = $1`)).
+		WithArgs(int64(11)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM provider_matches m`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "local_id", "provider", "external_id", "external_url"}).
+			AddRow(int64(11), nil, "spotify", "sp1", ""))
+
+	match, err := s.RecordProviderMatch(context.Background(), EntityAlbum, []ProviderRef{ref})
+	if err != nil {
+		t.Fatalf("RecordProviderMatch: %v", err)
+	}
+	if match.ID != 11 || match.LocalID != 0 || len(match.Refs) != 1 {
+		t.Fatalf("unexpected match %+v", match)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
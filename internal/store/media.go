@@ -0,0 +1,272 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrMediaNotFound signals a missing image, or an album without a cover.
+	ErrMediaNotFound = errors.New("media not found")
+	// ErrPhotoNotFound signals a missing collection photo.
+	ErrPhotoNotFound = errors.New("photo not found")
+)
+
+// Media is an image kept in the media backend under the SHA-256 hash of its
+// content. Thumbnails are derived from it and share the hash.
+type Media struct {
+	ID          int64     `json:"id"`
+	Hash        string    `json:"hash"`
+	ContentType string    `json:"contentType"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	Size        int64     `json:"size"`
+	SourceURL   string    `json:"-"`
+	UploadedBy  *int64    `json:"-"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// CollectionPhoto is a user's photo of the physical copy behind a collection
+// item. URL serves the original; thumbnails add a size to it.
+type CollectionPhoto struct {
+	ID           int64     `json:"id"`
+	CollectionID int64     `json:"collection_id"`
+	Caption      string    `json:"caption"`
+	URL          string    `json:"url"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// MediaPath returns the URL path an image is served from: the original when
+// size is empty, otherwise the thumbnail of that size.
+func MediaPath(hash, size string) string {
+	if size == "" {
+		return "/api/v1/media/" + hash
+	}
+	return "/api/v1/media/" + hash + "/" + size
+}
+
+const mediaColumns = `id, hash, content_type, width, height, size_bytes, COALESCE(source_url, ''), uploaded_by, created_at`
+
+// SaveMedia records an image stored in the media backend. Saving an image
+// that is already known returns the existing record, so identical covers and
+// uploads share one file.
+func (s *Store) SaveMedia(ctx context.Context, media Media) (Media, error) {
+	if len(media.Hash) != 64 || media.Width <= 0 || media.Height <= 0 || media.Size <= 0 {
+		return Media{}, fmt.Errorf("save media: incomplete image record for %q", media.Hash)
+	}
+
+	saved, err := scanMedia(s.db.QueryRowContext(ctx, `
+		INSERT INTO media (hash, content_type, width, height, size_bytes, source_url, uploaded_by)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		ON CONFLICT (hash) DO UPDATE SET source_url = COALESCE(media.source_url, EXCLUDED.source_url)
+		RETURNING `+mediaColumns,
+		media.Hash, media.ContentType, media.Width, media.Height, media.Size, media.SourceURL, media.UploadedBy))
+	if err != nil {
+		return Media{}, fmt.Errorf("save media: %w", err)
+	}
+	return saved, nil
+}
+
+// MediaByHash returns the image stored under hash.
+func (s *Store) MediaByHash(ctx context.Context, hash string) (Media, error) {
+	media, err := scanMedia(s.db.QueryRowContext(ctx, `SELECT `+mediaColumns+` FROM media WHERE hash = $1`, strings.ToLower(hash)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Media{}, ErrMediaNotFound
+	}
+	if err != nil {
+		return Media{}, fmt.Errorf("get media: %w", err)
+	}
+	return media, nil
+}
+
+// MediaBySourceURL returns the image downloaded from url, so covers are only
+// fetched once.
+func (s *Store) MediaBySourceURL(ctx context.Context, url string) (Media, error) {
+	media, err := scanMedia(s.db.QueryRowContext(ctx, `
+		SELECT `+mediaColumns+` FROM media WHERE source_url = $1 ORDER BY id LIMIT 1
+	`, url))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Media{}, ErrMediaNotFound
+	}
+	if err != nil {
+		return Media{}, fmt.Errorf("get media by source: %w", err)
+	}
+	return media, nil
+}
+
+// SetAlbumCover makes an image the cover of an album. Imports call it after
+// downloading provider art; it is not a user-facing action, so no token is
+// checked.
+func (s *Store) SetAlbumCover(ctx context.Context, albumID, mediaID int64) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE albums SET cover_media_id = $2 WHERE id = $1`, albumID, mediaID)
+	if err != nil {
+		return fmt.Errorf("set album cover: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrAlbumNotFound
+	}
+	return nil
+}
+
+// AlbumCover returns the cover image of an album.
+func (s *Store) AlbumCover(ctx context.Context, albumID int64) (Media, error) {
+	var (
+		hash        sql.NullString
+		contentType sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx, `
+		SELECT m.hash, m.content_type
+		FROM albums a
+		LEFT JOIN media m ON m.id = a.cover_media_id
+		WHERE a.id = $1
+	`, albumID).Scan(&hash, &contentType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Media{}, ErrAlbumNotFound
+	}
+	if err != nil {
+		return Media{}, fmt.Errorf("get album cover: %w", err)
+	}
+	if !hash.Valid {
+		return Media{}, ErrMediaNotFound
+	}
+	return Media{Hash: hash.String, ContentType: contentType.String}, nil
+}
+
+// AddCollectionPhoto attaches an uploaded image to a collection item of the
+// token's user.
+func (s *Store) AddCollectionPhoto(ctx context.Context, token string, collectionID, mediaID int64, caption string) (CollectionPhoto, error) {
+	if _, err := s.CheckCollectionOwner(ctx, token, collectionID); err != nil {
+		return CollectionPhoto{}, err
+	}
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO collection_photos (collection_id, media_id, caption)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (collection_id, media_id) DO UPDATE SET caption = EXCLUDED.caption
+		RETURNING id
+	`, collectionID, mediaID, strings.TrimSpace(caption)).Scan(&id)
+	if err != nil {
+		return CollectionPhoto{}, fmt.Errorf("add collection photo: %w", err)
+	}
+
+	photos, err := s.collectionPhotos(ctx, `p.id = $1`, id)
+	if err != nil {
+		return CollectionPhoto{}, err
+	}
+	if len(photos) == 0 {
+		return CollectionPhoto{}, ErrPhotoNotFound
+	}
+	return photos[0], nil
+}
+
+// ListCollectionPhotos returns the photos of a collection item of the
+// token's user, oldest first.
+func (s *Store) ListCollectionPhotos(ctx context.Context, token string, collectionID int64) ([]CollectionPhoto, error) {
+	if _, err := s.CheckCollectionOwner(ctx, token, collectionID); err != nil {
+		return nil, err
+	}
+	return s.collectionPhotos(ctx, `p.collection_id = $1`, collectionID)
+}
+
+// DeleteCollectionPhoto removes a photo from a collection item of the
+// token's user. The image stays in the media backend, where other records
+// may share it.
+func (s *Store) DeleteCollectionPhoto(ctx context.Context, token string, collectionID, photoID int64) error {
+	if _, err := s.CheckCollectionOwner(ctx, token, collectionID); err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM collection_photos WHERE id = $1 AND collection_id = $2
+	`, photoID, collectionID)
+	if err != nil {
+		return fmt.Errorf("delete collection photo: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("rows affected: %w", err)
+	}
+	if affected == 0 {
+		return ErrPhotoNotFound
+	}
+	return nil
+}
+
+// CheckCollectionOwner ensures the collection item exists and belongs to
+// the token's user, and returns that user.
+func (s *Store) CheckCollectionOwner(ctx context.Context, token string, collectionID int64) (int64, error) {
+	userID, err := s.UserIDByToken(ctx, token)
+	if err != nil {
+		return 0, err
+	}
+
+	var ownerID int64
+	err = s.db.QueryRowContext(ctx, `SELECT user_id FROM album_collections WHERE id = $1`, collectionID).Scan(&ownerID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrCollectionNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("check ownership: %w", err)
+	}
+	if ownerID != userID {
+		return 0, ErrForbidden
+	}
+	return userID, nil
+}
+
+func (s *Store) collectionPhotos(ctx context.Context, condition string, arg any) ([]CollectionPhoto, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT p.id, p.collection_id, p.caption, m.hash, m.width, m.height, p.created_at
+		FROM collection_photos p
+		JOIN media m ON m.id = p.media_id
+		WHERE `+condition+`
+		ORDER BY p.created_at, p.id
+	`, arg)
+	if err != nil {
+		return nil, fmt.Errorf("list collection photos: %w", err)
+	}
+	defer rows.Close()
+
+	photos := []CollectionPhoto{}
+	for rows.Next() {
+		var (
+			photo CollectionPhoto
+			hash  string
+		)
+		if err := rows.Scan(&photo.ID, &photo.CollectionID, &photo.Caption, &hash, &photo.Width, &photo.Height, &photo.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan collection photo: %w", err)
+		}
+		photo.URL = MediaPath(hash, "")
+		photos = append(photos, photo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate collection photos: %w", err)
+	}
+	return photos, nil
+}
+
+func scanMedia(scanner interface{ Scan(...any) error }) (Media, error) {
+	var (
+		media      Media
+		uploadedBy sql.NullInt64
+	)
+	if err := scanner.Scan(&media.ID, &media.Hash, &media.ContentType, &media.Width, &media.Height,
+		&media.Size, &media.SourceURL, &uploadedBy, &media.CreatedAt); err != nil {
+		return Media{}, err
+	}
+	if uploadedBy.Valid {
+		media.UploadedBy = &uploadedBy.Int64
+	}
+	return media, nil
+}
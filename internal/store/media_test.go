@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCollectionPhotosRequireOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	expectSessionLookup(mock, "token", 1)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT user_id FROM album_collections WHERE id = $1`)).
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(2)))
+
+	if _, err := s.AddCollectionPhoto(context.Background(), "token", 5, 9, "Gatefold"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+
+	expectSessionLookup(mock, "token", 1)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT user_id FROM album_collections WHERE id = $1`)).
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(int64(1)))
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM collection_photos p
+		JOIN media m ON m.id = p.media_id
+		WHERE p.collection_id = $1`)).
+		WithArgs(int64(5)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "collection_id", "caption", "hash", "width", "height", "created_at"}).
+			AddRow(int64(3), int64(5), "Gatefold", "ab12", 1200, 900, time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)))
+
+	photos, err := s.ListCollectionPhotos(context.Background(), "token", 5)
+	if err != nil {
+		t.Fatalf("ListCollectionPhotos: %v", err)
+	}
+	if len(photos) != 1 || photos[0].URL != "/api/v1/media/ab12" || photos[0].Width != 1200 {
+		t.Fatalf("unexpected photos %+v", photos)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestAlbumCover(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	query := regexp.QuoteMeta(`LEFT JOIN media m ON m.id = a.cover_media_id`)
+	mock.ExpectQuery(query).WithArgs(int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"hash", "content_type"}).AddRow("ab12", "image/jpeg"))
+	mock.ExpectQuery(query).WithArgs(int64(2)).
+		WillReturnRows(sqlmock.NewRows([]string{"hash", "content_type"}).AddRow(nil, nil))
+	mock.ExpectQuery(query).WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"hash", "content_type"}))
+
+	if cover, err := s.AlbumCover(context.Background(), 1); err != nil || cover.Hash != "ab12" {
+		t.Fatalf("AlbumCover(1) = %+v, %v", cover, err)
+	}
+	if _, err := s.AlbumCover(context.Background(), 2); !errors.Is(err, ErrMediaNotFound) {
+		t.Fatalf("album without cover: expected ErrMediaNotFound, got %v", err)
+	}
+	if _, err := s.AlbumCover(context.Background(), 3); !errors.Is(err, ErrAlbumNotFound) {
+		t.Fatalf("missing album: expected ErrAlbumNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrInvalidPage indicates a malformed cursor, a cursor used with a list it
+// was not issued for, or a page size out of range.
+var ErrInvalidPage = errors.New("invalid page")
+
+// Page size limits shared by every paginated list.
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// Page selects one page of a list. Cursor is the next cursor returned with
+// the previous page and is empty for the first page; it carries the sort key
+// of the list, so following pages keep the order of the first. Size
+// defaults to DefaultPageSize.
+type Page struct {
+	Cursor string
+	Size   int
+}
+
+// keyset is the sort key of a paginated list: the expressions rows are
+// ordered by, ending in a unique column so that every row has its own
+// position. Expressions must not be NULL. Pages continue after the last row
+// of the previous page instead of skipping an offset, so they stay stable
+// while rows are added and cost the same however deep they go.
+type keyset struct {
+	name    string
+	columns []keyColumn
+}
+
+type keyColumn struct {
+	expr string
+	desc bool
+	kind keyKind
+}
+
+// keyKind is how a sort key value is written into and read back from a
+// cursor.
+type keyKind int
+
+const (
+	keyInt keyKind = iota
+	keyFloat
+	keyString
+	keyBool
+	keyTime
+)
+
+type cursorPayload struct {
+	Keyset string            `json:"k"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// pageQuery is a Page resolved against the keyset of a list.
+type pageQuery struct {
+	keyset keyset
+	size   int
+	after  []any
+}
+
+// page validates p for this keyset and decodes its cursor.
+func (k keyset) page(p Page) (pageQuery, error) {
+	q := pageQuery{keyset: k, size: p.Size}
+	switch {
+	case q.size == 0:
+		q.size = DefaultPageSize
+	case q.size < 0 || q.size > MaxPageSize:
+		return pageQuery{}, fmt.Errorf("%w: page size must be between 1 and %d", ErrInvalidPage, MaxPageSize)
+	}
+	if p.Cursor == "" {
+		return q, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err != nil {
+		return pageQuery{}, fmt.Errorf("%w: malformed cursor", ErrInvalidPage)
+	}
+	var payload cursorPayload
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return pageQuery{}, fmt.Errorf("%w: malformed cursor", ErrInvalidPage)
+	}
+	if payload.Keyset != k.name || len(payload.Values) != len(k.columns) {
+		return pageQuery{}, fmt.Errorf("%w: cursor belongs to another list or sort order", ErrInvalidPage)
+	}
+
+	q.after = make([]any, len(k.columns))
+	for i, column := range k.columns {
+		value, err := column.kind.decode(payload.Values[i])
+		if err != nil {
+			return pageQuery{}, fmt.Errorf("%w: malformed cursor", ErrInvalidPage)
+		}
+		q.after[i] = value
+	}
+	return q, nil
+}
+
+// cursor encodes the sort key values of the last row of a page.
+func (k keyset) cursor(values []any) string {
+	payload := cursorPayload{Keyset: k.name, Values: make([]json.RawMessage, len(values))}
+	for i, value := range values {
+		if t, ok := value.(time.Time); ok {
+			value = t.UTC().Format(time.RFC3339Nano)
+		}
+		encoded, _ := json.Marshal(value)
+		payload.Values[i] = encoded
+	}
+	raw, _ := json.Marshal(payload)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// where returns the condition selecting the rows after the cursor, or TRUE
+// for the first page, and appends its arguments to args. With mixed sort
+// directions a row comparison does not work, so the condition is spelled
+// out: (a > $1) OR (a = $1 AND b < $2) OR ...
+func (q pageQuery) where(args []any) (string, []any) {
+	if q.after == nil {
+		return "TRUE", args
+	}
+
+	placeholders := make([]string, len(q.after))
+	for i, value := range q.after {
+		args = append(args, value)
+		placeholders[i] = fmt.Sprintf("$%d", len(args))
+	}
+
+	terms := make([]string, len(q.keyset.columns))
+	for i, column := range q.keyset.columns {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, fmt.Sprintf("%s = %s", q.keyset.columns[j].expr, placeholders[j]))
+		}
+		op := ">"
+		if column.desc {
+			op = "<"
+		}
+		parts = append(parts, fmt.Sprintf("%s %s %s", column.expr, op, placeholders[i]))
+		terms[i] = "(" + strings.Join(parts, " AND ") + ")"
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args
+}
+
+// orderLimit returns the ORDER BY and LIMIT clauses and appends the limit to
+// args. One row more than the page size is fetched to tell whether another
+// page follows.
+func (q pageQuery) orderLimit(args []any) (string, []any) {
+	order := make([]string, len(q.keyset.columns))
+	for i, column := range q.keyset.columns {
+		direction := "ASC"
+		if column.desc {
+			direction = "DESC"
+		}
+		order[i] = column.expr + " " + direction
+	}
+	args = append(args, q.size+1)
+	return fmt.Sprintf("ORDER BY %s LIMIT $%d", strings.Join(order, ", "), len(args)), args
+}
+
+// finishPage drops the extra row fetched by orderLimit and returns the
+// cursor of the next page, or "" on the last page. key returns the sort key
+// values of an item in keyset order.
+func finishPage[T any](q pageQuery, items []T, key func(T) []any) ([]T, string) {
+	if len(items) <= q.size {
+		return items, ""
+	}
+	items = items[:q.size]
+	return items, q.keyset.cursor(key(items[len(items)-1]))
+}
+
+func (k keyKind) decode(raw json.RawMessage) (any, error) {
+	switch k {
+	case keyInt:
+		var v int64
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	case keyFloat:
+		var v float64
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	case keyString:
+		var v string
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	case keyBool:
+		var v bool
+		err := json.Unmarshal(raw, &v)
+		return v, err
+	case keyTime:
+		var v string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return nil, err
+		}
+		return time.Parse(time.RFC3339Nano, v)
+	default:
+		return nil, fmt.Errorf("unknown key kind %d", k)
+	}
+}
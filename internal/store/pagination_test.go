@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var testKeyset = keyset{name: "test", columns: []keyColumn{
+	{expr: "a", desc: true, kind: keyTime},
+	{expr: "b", kind: keyString},
+	{expr: "c", kind: keyBool},
+	{expr: "d", desc: true, kind: keyFloat},
+	{expr: "id", kind: keyInt},
+}}
+
+func TestKeysetCursorRoundTrip(t *testing.T) {
+	at := time.Date(2024, 3, 9, 18, 30, 0, 123456789, time.FixedZone("CET", 3600))
+	values := []any{at, "Pink Floyd", true, 0.85, int64(42)}
+
+	q, err := testKeyset.page(Page{Cursor: testKeyset.cursor(values), Size: 10})
+	if err != nil {
+		t.Fatalf("page: %v", err)
+	}
+	if q.size != 10 {
+		t.Fatalf("expected size 10, got %d", q.size)
+	}
+	if decoded := q.after[0].(time.Time); !decoded.Equal(at) {
+		t.Fatalf("expected time %v, got %v", at, decoded)
+	}
+	if !reflect.DeepEqual(q.after[1:], values[1:]) {
+		t.Fatalf("unexpected decoded values: %#v", q.after)
+	}
+}
+
+func TestKeysetPageRejectsInvalidPages(t *testing.T) {
+	other := keyset{name: "other", columns: testKeyset.columns}
+	values := []any{time.Now(), "x", false, 1.0, int64(1)}
+
+	for name, page := range map[string]Page{
+		"negative size":  {Size: -1},
+		"size too large": {Size: MaxPageSize + 1},
+		"not base64":     {Cursor: "!!!"},
+		"not json":       {Cursor: "bm90IGpzb24"},
+		"other keyset":   {Cursor: other.cursor(values)},
+		"wrong type":     {Cursor: testKeyset.cursor([]any{"yesterday", "x", false, 1.0, int64(1)})},
+	} {
+		if _, err := testKeyset.page(page); !errors.Is(err, ErrInvalidPage) {
+			t.Errorf("%s: expected ErrInvalidPage, got %v", name, err)
+		}
+	}
+
+	q, err := testKeyset.page(Page{})
+	if err != nil {
+		t.Fatalf("first page: %v", err)
+	}
+	if q.size != DefaultPageSize || q.after != nil {
+		t.Fatalf("unexpected first page: %#v", q)
+	}
+}
+
+func TestPageQueryWhere(t *testing.T) {
+	ks := keyset{name: "test", columns: []keyColumn{
+		{expr: "release_year", desc: true, kind: keyInt},
+		{expr: "id", kind: keyInt},
+	}}
+	q, err := ks.page(Page{Cursor: ks.cursor([]any{int64(1977), int64(8)}), Size: 2})
+	if err != nil {
+		t.Fatalf("page: %v", err)
+	}
+
+	where, args := q.where([]any{"%Bowie%"})
+	if want := "((release_year < $2) OR (release_year = $2 AND id > $3))"; where != want {
+		t.Fatalf("unexpected condition:\n got %s\nwant %s", where, want)
+	}
+	tail, args := q.orderLimit(args)
+	if want := "ORDER BY release_year DESC, id ASC LIMIT $4"; tail != want {
+		t.Fatalf("unexpected tail: %s", tail)
+	}
+	if want := []any{"%Bowie%", int64(1977), int64(8), 3}; !reflect.DeepEqual(args, want) {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+}
+
+func TestListArtistsPages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	columns := []string{"id", "name", "biography", "image_url", "genres", "external_id", "provider", "popularity", "external_url"}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, name, COALESCE(biography, ''), COALESCE(image_url, ''), COALESCE(genres, '[]'::jsonb),
+			COALESCE(external_id, ''), COALESCE(provider, ''), COALESCE(popularity, 0), COALESCE(external_url, '')
+		FROM artists
+		WHERE TRUE ORDER BY name ASC, id ASC LIMIT $1
+	`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(int64(3), "Can", "", "", `["krautrock"]`, "4l8xPGtl6DHR2uvunqrl8r", "spotify", 61, "https://open.spotify.com/artist/4l8xPGtl6DHR2uvunqrl8r").
+			AddRow(int64(1), "Faust", "", "", `[]`, "", "", 0, ""))
+
+	first, next, err := s.ListArtists(context.Background(), ArtistFilter{}, Page{Size: 1})
+	if err != nil {
+		t.Fatalf("ListArtists: %v", err)
+	}
+	if len(first) != 1 || first[0].Name != "Can" || first[0].Provider != "spotify" || first[0].Popularity != 61 {
+		t.Fatalf("unexpected first page: %#v", first)
+	}
+	if next == "" {
+		t.Fatal("expected a next cursor")
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, name, COALESCE(biography, ''), COALESCE(image_url, ''), COALESCE(genres, '[]'::jsonb),
+			COALESCE(external_id, ''), COALESCE(provider, ''), COALESCE(popularity, 0), COALESCE(external_url, '')
+		FROM artists
+		WHERE ((name > $1) OR (name = $1 AND id > $2)) ORDER BY name ASC, id ASC LIMIT $3
+	`)).
+		WithArgs("Can", int64(3), 2).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow(int64(1), "Faust", "", "", `[]`, "", "", 0, ""))
+
+	second, next, err := s.ListArtists(context.Background(), ArtistFilter{}, Page{Cursor: next, Size: 1})
+	if err != nil {
+		t.Fatalf("ListArtists second page: %v", err)
+	}
+	if len(second) != 1 || second[0].Name != "Faust" || next != "" {
+		t.Fatalf("unexpected second page: %#v, cursor %q", second, next)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -18,20 +18,35 @@ var (
 	ErrCannotModifyFavoritesFlag  = errors.New("cannot modify is_favorite flag")
 )
 
-// ListPlaylists returns all playlists for a user (by token).
-func (s *Store) ListPlaylists(ctx context.Context, token string) ([]*models.Playlist, error) {
+// playlistKeyset lists the favorites playlist first, then the newest.
+var playlistKeyset = keyset{name: "playlists", columns: []keyColumn{
+	{expr: "is_favorite", desc: true, kind: keyBool},
+	{expr: "created_at", desc: true, kind: keyTime},
+	{expr: "id", desc: true, kind: keyInt},
+}}
+
+// ListPlaylists returns one page of playlists for a user (by token).
+func (s *Store) ListPlaylists(ctx context.Context, token string, page Page) ([]*models.Playlist, string, error) {
+	pageQ, err := playlistKeyset.page(page)
+	if err != nil {
+		return nil, "", err
+	}
+
 	userID, err := s.UserIDByToken(ctx, token)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
+	args := []any{userID}
+	after, args := pageQ.where(args)
+	tail, args := pageQ.orderLimit(args)
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT id, title, description, owner, user_id, created_at, updated_at, tags, is_public, is_favorite
 		FROM playlists
-		WHERE user_id = $1
-		ORDER BY is_favorite DESC, created_at DESC, id DESC`, userID)
+		WHERE user_id = $1 AND `+after+`
+		`+tail, args...)
 	if err != nil {
-		return nil, fmt.Errorf("list playlists: %w", err)
+		return nil, "", fmt.Errorf("list playlists: %w", err)
 	}
 	defer rows.Close()
 
@@ -41,22 +56,27 @@ func (s *Store) ListPlaylists(ctx context.Context, token string) ([]*models.Play
 		var description sql.NullString
 		if err := rows.Scan(&playlist.ID, &playlist.Title, &description, &playlist.Owner, &playlist.UserID,
 			&playlist.CreatedAt, &playlist.UpdatedAt, pq.Array(&playlist.Tags), &playlist.IsPublic, &playlist.IsFavorite); err != nil {
-			return nil, fmt.Errorf("scan playlist: %w", err)
+			return nil, "", fmt.Errorf("scan playlist: %w", err)
 		}
 		playlist.Description = description.String
+		playlists = append(playlists, &playlist)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("iterate playlists: %w", err)
+	}
+	playlists, next := finishPage(pageQ, playlists, func(p *models.Playlist) []any {
+		return []any{p.IsFavorite, p.CreatedAt, p.ID}
+	})
 
+	for _, playlist := range playlists {
 		songs, err := s.listPlaylistSongs(ctx, playlist.ID)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		playlist.Songs = songs
 		playlist.SongCount = len(songs)
-		playlists = append(playlists, &playlist)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate playlists: %w", err)
 	}
-	return playlists, nil
+	return playlists, next, nil
 }
 
 // GetPlaylist returns a single playlist by ID.
@@ -0,0 +1,176 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"vinylhound/internal/queryspec"
+)
+
+// ErrInvalidQuery indicates a query spec naming a field the list does not
+// whitelist, or a filter value of the wrong type.
+var ErrInvalidQuery = queryspec.ErrInvalid
+
+// listField is a field clients may name in the query spec of a list.
+type listField[T any] struct {
+	// expr is the SQL expression the field sorts and filters by. It must not
+	// be NULL when key is set; fields without expr can only be projected.
+	expr string
+	kind keyKind
+	// key returns the sort key value of an item, matching expr. Fields
+	// without key cannot be sorted by.
+	key func(T) any
+	// json is the response key the field projects to, or "" when it cannot
+	// be selected with fields=.
+	json string
+}
+
+// listSpec whitelists the fields of one list for query specs. Without a sort
+// in the spec the list keeps its default keyset; a custom sort is followed by
+// the unique id column so that every row still has its own position.
+type listSpec[T any] struct {
+	keyset keyset
+	key    func(T) []any
+	id     keyColumn
+	idKey  func(T) any
+	fields map[string]listField[T]
+}
+
+// listQuery is a query spec compiled against a list.
+type listQuery[T any] struct {
+	conditions []string
+	keyset     keyset
+	key        func(T) []any
+}
+
+// where joins the filter conditions with AND, or returns TRUE without
+// filters.
+func (q listQuery[T]) where() string {
+	if len(q.conditions) == 0 {
+		return "TRUE"
+	}
+	return strings.Join(q.conditions, " AND ")
+}
+
+// compile checks spec against the whitelist and appends the filter values to
+// args. Values are always bound as parameters; only whitelisted expressions
+// reach the SQL text.
+func (l listSpec[T]) compile(spec queryspec.Spec, args []any) (listQuery[T], []any, error) {
+	conditions := make([]string, 0, len(spec.Filters))
+	for _, filter := range spec.Filters {
+		field, ok := l.fields[filter.Field]
+		if !ok || field.expr == "" {
+			return listQuery[T]{}, nil, fmt.Errorf("%w: cannot filter by %s", ErrInvalidQuery, filter.Field)
+		}
+		value, err := field.kind.parse(filter.Value)
+		if err != nil {
+			return listQuery[T]{}, nil, fmt.Errorf("%w: %s %s", ErrInvalidQuery, filter.Field, err)
+		}
+		if field.kind == keyBool && filter.Op != queryspec.Eq && filter.Op != queryspec.Ne {
+			return listQuery[T]{}, nil, fmt.Errorf("%w: %s only supports = and !=", ErrInvalidQuery, filter.Field)
+		}
+		args = append(args, value)
+		// Text equality ignores case, as names are typed by hand.
+		if field.kind == keyString && (filter.Op == queryspec.Eq || filter.Op == queryspec.Ne) {
+			conditions = append(conditions, fmt.Sprintf("LOWER(%s) %s LOWER($%d)", field.expr, filter.Op, len(args)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("%s %s $%d", field.expr, filter.Op, len(args)))
+		}
+	}
+
+	q := listQuery[T]{conditions: conditions, keyset: l.keyset, key: l.key}
+	if len(spec.Sort) == 0 {
+		return q, args, nil
+	}
+
+	// The keyset name records the sort, so a cursor cannot be replayed
+	// against another order.
+	names := make([]string, len(spec.Sort))
+	columns := make([]keyColumn, 0, len(spec.Sort)+1)
+	keys := make([]func(T) any, 0, len(spec.Sort)+1)
+	for i, sort := range spec.Sort {
+		field, ok := l.fields[sort.Field]
+		if !ok || field.key == nil {
+			return listQuery[T]{}, nil, fmt.Errorf("%w: cannot sort by %s", ErrInvalidQuery, sort.Field)
+		}
+		names[i] = sort.Field
+		if sort.Desc {
+			names[i] = "-" + sort.Field
+		}
+		columns = append(columns, keyColumn{expr: field.expr, desc: sort.Desc, kind: field.kind})
+		keys = append(keys, field.key)
+	}
+	columns = append(columns, l.id)
+	keys = append(keys, l.idKey)
+
+	q.keyset = keyset{name: l.keyset.name + ":" + strings.Join(names, ","), columns: columns}
+	q.key = func(item T) []any {
+		values := make([]any, len(keys))
+		for i, key := range keys {
+			values[i] = key(item)
+		}
+		return values
+	}
+	return q, args, nil
+}
+
+// check validates spec without a query, so that handlers can reject it
+// before calling a service, and returns the response keys selected by its
+// fields, in order and without repeats.
+func (l listSpec[T]) check(spec queryspec.Spec) ([]string, error) {
+	if _, _, err := l.compile(spec, nil); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(spec.Fields))
+	seen := make(map[string]bool, len(spec.Fields))
+	for _, name := range spec.Fields {
+		field, ok := l.fields[name]
+		if !ok || field.json == "" {
+			return nil, fmt.Errorf("%w: cannot select %s", ErrInvalidQuery, name)
+		}
+		if !seen[field.json] {
+			seen[field.json] = true
+			keys = append(keys, field.json)
+		}
+	}
+	return keys, nil
+}
+
+// parse reads a filter value written in a query string.
+func (k keyKind) parse(value string) (any, error) {
+	switch k {
+	case keyInt:
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.New("must be an integer")
+		}
+		return v, nil
+	case keyFloat:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, errors.New("must be a number")
+		}
+		return v, nil
+	case keyBool:
+		v, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("must be true or false")
+		}
+		return v, nil
+	case keyTime:
+		if v, err := time.Parse(time.RFC3339, value); err == nil {
+			return v, nil
+		}
+		v, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, errors.New("must be a date or an RFC 3339 time")
+		}
+		return v, nil
+	default:
+		return value, nil
+	}
+}
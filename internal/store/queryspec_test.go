@@ -0,0 +1,157 @@
+package store
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"vinylhound/internal/queryspec"
+)
+
+func TestListSpecCompile(t *testing.T) {
+	spec := queryspec.Spec{
+		Sort: []queryspec.Sort{{Field: "release_year", Desc: true}, {Field: "title"}},
+		Filters: []queryspec.Filter{
+			{Field: "year", Op: queryspec.Gte, Value: "1970"},
+			{Field: "artist", Op: queryspec.Eq, Value: "Can"},
+		},
+	}
+
+	q, args, err := albumList.compile(spec, []any{int64(7)})
+	if err != nil {
+		t.Fatalf("compile: %v", err)
+	}
+	if want := "release_year >= $2 AND LOWER(artist) = LOWER($3)"; q.where() != want {
+		t.Fatalf("unexpected conditions:\n got %s\nwant %s", q.where(), want)
+	}
+	if want := []any{int64(7), int64(1970), "Can"}; !reflect.DeepEqual(args, want) {
+		t.Fatalf("unexpected args: %#v", args)
+	}
+
+	if q.keyset.name != "albums:-release_year,title" {
+		t.Fatalf("unexpected keyset name %q", q.keyset.name)
+	}
+	pageQ, err := q.keyset.page(Page{})
+	if err != nil {
+		t.Fatalf("page: %v", err)
+	}
+	if tail, _ := pageQ.orderLimit(nil); tail != "ORDER BY release_year DESC, title ASC, id ASC LIMIT $1" {
+		t.Fatalf("unexpected tail: %s", tail)
+	}
+	album := Album{ID: 3, Title: "Tago Mago", ReleaseYear: 1971}
+	if want := []any{1971, "Tago Mago", int64(3)}; !reflect.DeepEqual(q.key(album), want) {
+		t.Fatalf("unexpected sort key: %#v", q.key(album))
+	}
+
+	q, _, err = albumList.compile(queryspec.Spec{}, nil)
+	if err != nil {
+		t.Fatalf("compile empty spec: %v", err)
+	}
+	if q.where() != "TRUE" || !reflect.DeepEqual(q.keyset, albumList.keyset) {
+		t.Fatalf("expected the default keyset, got %#v", q)
+	}
+}
+
+func TestListSpecRejectsUnlistedFields(t *testing.T) {
+	for name, spec := range map[string]queryspec.Spec{
+		"unknown filter":     {Filters: []queryspec.Filter{{Field: "user_id", Op: queryspec.Eq, Value: "1"}}},
+		"projected filter":   {Filters: []queryspec.Filter{{Field: "tracks", Op: queryspec.Eq, Value: "1"}}},
+		"wrong type":         {Filters: []queryspec.Filter{{Field: "year", Op: queryspec.Gt, Value: "1970s"}}},
+		"unknown sort":       {Sort: []queryspec.Sort{{Field: "id"}}},
+		"projected sort":     {Sort: []queryspec.Sort{{Field: "genres"}}},
+		"unknown projection": {Fields: []string{"user_id"}},
+	} {
+		if _, err := AlbumQuery(spec); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("%s: expected ErrInvalidQuery, got %v", name, err)
+		}
+	}
+
+	if _, _, err := concertList.compile(queryspec.Spec{Filters: []queryspec.Filter{{Field: "attended", Op: queryspec.Gt, Value: "true"}}}, nil); !errors.Is(err, ErrInvalidQuery) {
+		t.Errorf("ordered boolean filter: expected ErrInvalidQuery, got %v", err)
+	}
+
+	keys, err := CollectionQuery(queryspec.Spec{Fields: []string{"title", "year", "release_year", "type"}})
+	if err != nil {
+		t.Fatalf("CollectionQuery: %v", err)
+	}
+	if want := []string{"album_title", "album_release_year", "collection_type"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("unexpected projection: %#v", keys)
+	}
+}
+
+func TestListSpecParsesTimes(t *testing.T) {
+	for value, want := range map[string]time.Time{
+		"2024-05-01":           time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"2024-05-01T20:00:00Z": time.Date(2024, 5, 1, 20, 0, 0, 0, time.UTC),
+	} {
+		got, err := keyTime.parse(value)
+		if err != nil {
+			t.Fatalf("parse %s: %v", value, err)
+		}
+		if !got.(time.Time).Equal(want) {
+			t.Fatalf("parse %s: got %v", value, got)
+		}
+	}
+}
+
+func TestListAlbumsSortedBySpec(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	spec := queryspec.Spec{
+		Sort:    []queryspec.Sort{{Field: "rating", Desc: true}, {Field: "title"}},
+		Filters: []queryspec.Filter{{Field: "year", Op: queryspec.Gte, Value: "1970"}},
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, artist, title, release_year, genres, rating
+		FROM albums WHERE release_year >= $1 AND TRUE ORDER BY rating DESC, title ASC, id ASC LIMIT $2
+	`)).
+		WithArgs(int64(1970), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "artist", "title", "release_year", "genres", "rating"}).
+			AddRow(int64(4), "Can", "Ege Bamyasi", 1972, `[]`, 5).
+			AddRow(int64(3), "Can", "Tago Mago", 1971, `[]`, 5))
+	mock.ExpectQuery(regexp.QuoteMeta(ratingStatsQuery)).
+		WithArgs(int64(4)).
+		WillReturnRows(sqlmock.NewRows([]string{"album_id", "average_rating", "rating_count"}))
+	expectAlbumTracks(mock, []int64{4})
+
+	albums, next, err := s.ListAlbums(AlbumFilter{}, spec, Page{Size: 1})
+	if err != nil {
+		t.Fatalf("ListAlbums: %v", err)
+	}
+	if len(albums) != 1 || albums[0].Title != "Ege Bamyasi" || next == "" {
+		t.Fatalf("unexpected page: %#v, cursor %q", albums, next)
+	}
+	cursor := next
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT id, artist, title, release_year, genres, rating
+		FROM albums WHERE release_year >= $1
+		AND ((rating < $2) OR (rating = $2 AND title > $3) OR (rating = $2 AND title = $3 AND id > $4))
+		ORDER BY rating DESC, title ASC, id ASC LIMIT $5
+	`)).
+		WithArgs(int64(1970), int64(5), "Ege Bamyasi", int64(4), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "artist", "title", "release_year", "genres", "rating"}))
+
+	if _, next, err = s.ListAlbums(AlbumFilter{}, spec, Page{Cursor: cursor, Size: 1}); err != nil || next != "" {
+		t.Fatalf("ListAlbums second page: cursor %q, err %v", next, err)
+	}
+
+	// A cursor only continues the order it was issued for.
+	if _, _, err := s.ListAlbums(AlbumFilter{}, queryspec.Spec{}, Page{Cursor: cursor}); !errors.Is(err, ErrInvalidPage) {
+		t.Fatalf("expected ErrInvalidPage, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"vinylhound/shared/go/models"
+)
+
+var (
+	// ErrInvalidRelease indicates validation failure for release data.
+	ErrInvalidRelease = errors.New("invalid release")
+	// ErrReleaseNotFound signals a missing release record.
+	ErrReleaseNotFound = errors.New("release not found")
+)
+
+const releaseColumns = `
+	id, album_id, format, COALESCE(label, ''), COALESCE(catalog_number, ''), COALESCE(country, ''),
+	pressing_year, COALESCE(barcode, ''), COALESCE(color_variant, ''), COALESCE(description, ''),
+	created_at, updated_at`
+
+// CreateRelease adds a release to an existing album. Releases are shared
+// catalog entries, so the user must be a curator.
+func (s *Store) CreateRelease(ctx context.Context, token string, release models.Release) (models.Release, error) {
+	release, err := normalizeRelease(release)
+	if err != nil {
+		return models.Release{}, err
+	}
+
+	userID, err := s.RequireRole(ctx, token, models.RoleCurator)
+	if err != nil {
+		return models.Release{}, err
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		INSERT INTO album_releases (album_id, format, label, catalog_number, country, pressing_year, barcode, color_variant, description, created_by)
+		SELECT id, $2, $3, $4, $5, $6, $7, $8, $9, $10
+		FROM albums
+		WHERE id = $1
+		RETURNING `+releaseColumns,
+		release.AlbumID, release.Format, nullIfEmpty(release.Label), nullIfEmpty(release.CatalogNumber),
+		nullIfEmpty(release.Country), nullIfZero(release.PressingYear), nullIfEmpty(release.Barcode),
+		nullIfEmpty(release.ColorVariant), nullIfEmpty(release.Description), userID,
+	)
+	created, err := scanRelease(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Release{}, ErrAlbumNotFound
+		}
+		return models.Release{}, err
+	}
+	return created, nil
+}
+
+// UpdateRelease replaces the details of a release; the album it belongs to
+// cannot change. The user must be a curator.
+func (s *Store) UpdateRelease(ctx context.Context, token string, id int64, release models.Release) (models.Release, error) {
+	release.AlbumID = 0
+	release, err := normalizeRelease(release)
+	if err != nil {
+		return models.Release{}, err
+	}
+
+	if _, err := s.RequireRole(ctx, token, models.RoleCurator); err != nil {
+		return models.Release{}, err
+	}
+
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE album_releases
+		SET format = $2, label = $3, catalog_number = $4, country = $5, pressing_year = $6,
+		    barcode = $7, color_variant = $8, description = $9, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+releaseColumns,
+		id, release.Format, nullIfEmpty(release.Label), nullIfEmpty(release.CatalogNumber),
+		nullIfEmpty(release.Country), nullIfZero(release.PressingYear), nullIfEmpty(release.Barcode),
+		nullIfEmpty(release.ColorVariant), nullIfEmpty(release.Description),
+	)
+	updated, err := scanRelease(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Release{}, ErrReleaseNotFound
+		}
+		return models.Release{}, err
+	}
+	return updated, nil
+}
+
+// ReleaseByID returns a single release by its identifier.
+func (s *Store) ReleaseByID(ctx context.Context, id int64) (models.Release, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+releaseColumns+` FROM album_releases WHERE id = $1`, id)
+	release, err := scanRelease(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Release{}, ErrReleaseNotFound
+		}
+		return models.Release{}, err
+	}
+	return release, nil
+}
+
+// ReleasesByAlbum lists the releases of an album, oldest pressing first.
+func (s *Store) ReleasesByAlbum(ctx context.Context, albumID int64) ([]models.Release, error) {
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM albums WHERE id = $1)`, albumID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check album existence: %w", err)
+	}
+	if !exists {
+		return nil, ErrAlbumNotFound
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+releaseColumns+`
+		FROM album_releases
+		WHERE album_id = $1
+		ORDER BY pressing_year ASC NULLS LAST, id ASC
+	`, albumID)
+	if err != nil {
+		return nil, fmt.Errorf("select releases: %w", err)
+	}
+	defer rows.Close()
+
+	releases := []models.Release{}
+	for rows.Next() {
+		release, err := scanRelease(rows)
+		if err != nil {
+			return nil, err
+		}
+		releases = append(releases, release)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate releases: %w", err)
+	}
+	return releases, nil
+}
+
+// checkReleaseOfAlbum verifies that an optional release belongs to the album
+// a collection item or rating refers to.
+func (s *Store) checkReleaseOfAlbum(ctx context.Context, releaseID *int64, albumID int64) error {
+	if releaseID == nil {
+		return nil
+	}
+
+	var owner int64
+	err := s.db.QueryRowContext(ctx, `SELECT album_id FROM album_releases WHERE id = $1`, *releaseID).Scan(&owner)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrReleaseNotFound
+		}
+		return fmt.Errorf("lookup release: %w", err)
+	}
+	if owner != albumID {
+		return fmt.Errorf("%w: release %d is not a release of album %d", ErrInvalidRelease, *releaseID, albumID)
+	}
+	return nil
+}
+
+func normalizeRelease(release models.Release) (models.Release, error) {
+	release.Format = models.ReleaseFormat(strings.ToLower(strings.TrimSpace(string(release.Format))))
+	release.Label = strings.TrimSpace(release.Label)
+	release.CatalogNumber = strings.TrimSpace(release.CatalogNumber)
+	release.Country = strings.TrimSpace(release.Country)
+	release.ColorVariant = strings.TrimSpace(release.ColorVariant)
+	release.Description = strings.TrimSpace(release.Description)
+	release.Barcode = strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(release.Barcode))
+
+	switch release.Format {
+	case models.ReleaseFormatVinyl, models.ReleaseFormatCD, models.ReleaseFormatCassette,
+		models.ReleaseFormatDigital, models.ReleaseFormatOther:
+	case "":
+		return models.Release{}, fmt.Errorf("%w: format is required", ErrInvalidRelease)
+	default:
+		return models.Release{}, fmt.Errorf("%w: unknown format %q", ErrInvalidRelease, release.Format)
+	}
+
+	if release.PressingYear < 0 || release.PressingYear > time.Now().Year()+1 {
+		return models.Release{}, fmt.Errorf("%w: pressing year %d is out of range", ErrInvalidRelease, release.PressingYear)
+	}
+	if release.Barcode != "" && !isBarcode(release.Barcode) {
+		return models.Release{}, fmt.Errorf("%w: barcode must be 8 to 14 digits", ErrInvalidRelease)
+	}
+	if len(release.CatalogNumber) > 100 || len(release.Country) > 100 || len(release.ColorVariant) > 100 {
+		return models.Release{}, fmt.Errorf("%w: catalog number, country and color variant are limited to 100 characters", ErrInvalidRelease)
+	}
+	return release, nil
+}
+
+// isBarcode accepts UPC and EAN codes (8 to 14 digits).
+func isBarcode(code string) bool {
+	if len(code) < 8 || len(code) > 14 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func nullIfZero(value int) interface{} {
+	if value == 0 {
+		return nil
+	}
+	return value
+}
+
+func scanRelease(scanner albumScanner) (models.Release, error) {
+	var (
+		release      models.Release
+		pressingYear sql.NullInt64
+	)
+	if err := scanner.Scan(
+		&release.ID, &release.AlbumID, &release.Format, &release.Label, &release.CatalogNumber, &release.Country,
+		&pressingYear, &release.Barcode, &release.ColorVariant, &release.Description,
+		&release.CreatedAt, &release.UpdatedAt,
+	); err != nil {
+		return models.Release{}, fmt.Errorf("scan release: %w", err)
+	}
+	if pressingYear.Valid {
+		release.PressingYear = int(pressingYear.Int64)
+	}
+	return release, nil
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"vinylhound/shared/go/models"
+)
+
+var releaseRowColumns = []string{
+	"id", "album_id", "format", "label", "catalog_number", "country",
+	"pressing_year", "barcode", "color_variant", "description", "created_at", "updated_at",
+}
+
+func TestCreateRelease(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	now := time.Now()
+
+	expectSessionLookup(mock, "token", 9)
+	expectRoleLookup(mock, 9, "curator")
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO album_releases`)).
+		WithArgs(int64(3), models.ReleaseFormatVinyl, "Harvest", "SHVL 804", nil, 1973, "5099902987613", "black", nil, int64(9)).
+		WillReturnRows(sqlmock.NewRows(releaseRowColumns).
+			AddRow(int64(1), int64(3), "vinyl", "Harvest", "SHVL 804", "", int64(1973), "5099902987613", "black", "", now, now))
+
+	created, err := s.CreateRelease(context.Background(), "token", models.Release{
+		AlbumID:       3,
+		Format:        " Vinyl ",
+		Label:         "Harvest",
+		CatalogNumber: "SHVL 804",
+		PressingYear:  1973,
+		Barcode:       "5 099902 987613",
+		ColorVariant:  "black",
+	})
+	if err != nil {
+		t.Fatalf("CreateRelease: %v", err)
+	}
+	if created.ID != 1 || created.Format != models.ReleaseFormatVinyl || created.PressingYear != 1973 {
+		t.Fatalf("unexpected release %+v", created)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCreateReleaseValidation(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	tests := []models.Release{
+		{AlbumID: 3},
+		{AlbumID: 3, Format: "8-track"},
+		{AlbumID: 3, Format: models.ReleaseFormatCD, Barcode: "12ab"},
+		{AlbumID: 3, Format: models.ReleaseFormatCD, PressingYear: 3000},
+	}
+	for _, release := range tests {
+		if _, err := s.CreateRelease(context.Background(), "token", release); !errors.Is(err, ErrInvalidRelease) {
+			t.Errorf("CreateRelease(%+v): expected ErrInvalidRelease, got %v", release, err)
+		}
+	}
+}
+
+func TestCheckReleaseOfAlbum(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	releaseID := int64(4)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT album_id FROM album_releases WHERE id = $1`)).
+		WithArgs(releaseID).
+		WillReturnRows(sqlmock.NewRows([]string{"album_id"}).AddRow(int64(8)))
+	if err := s.checkReleaseOfAlbum(context.Background(), &releaseID, 3); !errors.Is(err, ErrInvalidRelease) {
+		t.Fatalf("expected ErrInvalidRelease for mismatched album, got %v", err)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT album_id FROM album_releases WHERE id = $1`)).
+		WithArgs(releaseID).
+		WillReturnRows(sqlmock.NewRows([]string{"album_id"}))
+	if err := s.checkReleaseOfAlbum(context.Background(), &releaseID, 3); !errors.Is(err, ErrReleaseNotFound) {
+		t.Fatalf("expected ErrReleaseNotFound, got %v", err)
+	}
+
+	if err := s.checkReleaseOfAlbum(context.Background(), nil, 3); err != nil {
+		t.Fatalf("nil release should be accepted, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"vinylhound/shared/go/models"
+)
+
+var (
+	// ErrForbidden signals an authenticated user whose role does not allow the action.
+	ErrForbidden = errors.New("forbidden")
+	// ErrInvalidRole signals an unknown role name.
+	ErrInvalidRole = errors.New("role must be user, curator or admin")
+)
+
+// RequireRole resolves the user owning the token and checks that their role
+// grants at least the required permissions. It returns ErrUnauthorized for an
+// invalid token and ErrForbidden for an insufficient role.
+func (s *Store) RequireRole(ctx context.Context, token string, required models.Role) (int64, error) {
+	userID, err := s.userIDForToken(ctx, token)
+	if err != nil {
+		return 0, err
+	}
+
+	role, err := s.roleForUser(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if !role.Allows(required) {
+		return 0, ErrForbidden
+	}
+	return userID, nil
+}
+
+// RoleByToken returns the role of the user owning the token.
+func (s *Store) RoleByToken(ctx context.Context, token string) (models.Role, error) {
+	userID, err := s.userIDForToken(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	return s.roleForUser(ctx, userID)
+}
+
+// SetUserRole assigns a role to another user. Only admins may assign roles,
+// and admins cannot change their own role so the last admin cannot lock
+// everyone out.
+func (s *Store) SetUserRole(ctx context.Context, token string, userID int64, role models.Role) error {
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+
+	adminID, err := s.RequireRole(ctx, token, models.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if adminID == userID {
+		return ErrForbidden
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE users
+		SET role = $1
+		WHERE id = $2
+	`, string(role), userID)
+	if err != nil {
+		return fmt.Errorf("update role: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check updated role: %w", err)
+	}
+	if affected == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (s *Store) roleForUser(ctx context.Context, userID int64) (models.Role, error) {
+	var role string
+	err := s.db.QueryRowContext(ctx, `
+		SELECT role
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(&role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrUnauthorized
+		}
+		return "", fmt.Errorf("lookup role: %w", err)
+	}
+	return models.Role(role), nil
+}
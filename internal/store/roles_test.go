@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"vinylhound/shared/go/models"
+)
+
+func expectRoleLookup(mock sqlmock.Sqlmock, userID int64, role string) {
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT role
+		FROM users
+		WHERE id = $1
+	`)).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(role))
+}
+
+func TestRequireRoleHierarchy(t *testing.T) {
+	tests := []struct {
+		role     string
+		required models.Role
+		wantErr  error
+	}{
+		{role: "user", required: models.RoleUser},
+		{role: "user", required: models.RoleCurator, wantErr: ErrForbidden},
+		{role: "curator", required: models.RoleCurator},
+		{role: "curator", required: models.RoleAdmin, wantErr: ErrForbidden},
+		{role: "admin", required: models.RoleCurator},
+		{role: "bogus", required: models.RoleUser, wantErr: ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+"/"+string(tt.required), func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("sqlmock.New: %v", err)
+			}
+			defer db.Close()
+
+			s := New(db)
+			expectSessionLookup(mock, "token", 42)
+			expectRoleLookup(mock, 42, tt.role)
+
+			userID, err := s.RequireRole(context.Background(), "token", tt.required)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr == nil && userID != 42 {
+				t.Fatalf("expected user 42, got %d", userID)
+			}
+
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Fatalf("unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
+func TestSetUserRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 1)
+	expectRoleLookup(mock, 1, "admin")
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE users
+		SET role = $1
+		WHERE id = $2
+	`)).
+		WithArgs("curator", int64(7)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := s.SetUserRole(context.Background(), "token", 7, models.RoleCurator); err != nil {
+		t.Fatalf("SetUserRole: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSetUserRoleRejectsSelfAndUnknownRoles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	if err := s.SetUserRole(context.Background(), "token", 7, "owner"); !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("expected ErrInvalidRole, got %v", err)
+	}
+
+	expectSessionLookup(mock, "token", 1)
+	expectRoleLookup(mock, 1, "admin")
+
+	if err := s.SetUserRole(context.Background(), "token", 1, models.RoleUser); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected ErrForbidden, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidSearch indicates a catalog search request that cannot be run.
+var ErrInvalidSearch = errors.New("invalid search")
+
+// Catalog search result kinds.
+const (
+	SearchTypeAlbum  = "album"
+	SearchTypeSong   = "song"
+	SearchTypeArtist = "artist"
+)
+
+// Catalog search result limits.
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// CatalogSearch describes a ranked search over the catalog. Types restricts
+// the kinds of results; empty means all of them.
+type CatalogSearch struct {
+	Query string
+	Types []string
+	Limit int
+}
+
+// CatalogSearchResult is one album, song or artist matching a search. Title
+// is the album or song title, or the artist name. Highlight is Title,
+// HTML-escaped, with the matched words wrapped in <mark> tags.
+type CatalogSearchResult struct {
+	Type      string  `json:"type"`
+	ID        int64   `json:"id"`
+	Title     string  `json:"title"`
+	Artist    string  `json:"artist,omitempty"`
+	AlbumID   *int64  `json:"albumId,omitempty"`
+	Album     string  `json:"album,omitempty"`
+	Rank      float64 `json:"rank"`
+	Highlight string  `json:"highlight"`
+}
+
+// catalogSearchQueries select the candidates of each result kind from the
+// parsed search q (see SearchCatalog). A row matches on its search_vector
+// or, for typos, when the unaccented search text is a close trigram match
+// for a word sequence of the title or name. The rank adds the full-text rank
+// to the trigram similarity so that exact words beat fuzzy ones.
+var catalogSearchQueries = map[string]string{
+	SearchTypeAlbum: `
+		SELECT 'album' AS kind, a.id, a.title, a.artist, NULL::bigint AS album_id, '' AS album,
+		       ts_rank_cd(a.search_vector, q.tsq) + word_similarity(q.term, catalog_unaccent(a.title)) AS rank,
+		       ` + searchHeadline("a.title") + ` AS highlight
+		FROM albums a, q
+		WHERE a.search_vector @@ q.tsq OR q.term <% catalog_unaccent(a.title) OR q.term <% catalog_unaccent(a.artist)`,
+	SearchTypeSong: `
+		SELECT 'song' AS kind, s.id, s.title, s.artist, s.album_id, COALESCE(al.title, '') AS album,
+		       ts_rank_cd(s.search_vector, q.tsq) + word_similarity(q.term, catalog_unaccent(s.title)) AS rank,
+		       ` + searchHeadline("s.title") + ` AS highlight
+		FROM q, songs s
+		LEFT JOIN albums al ON al.id = s.album_id
+		WHERE s.search_vector @@ q.tsq OR q.term <% catalog_unaccent(s.title)`,
+	SearchTypeArtist: `
+		SELECT 'artist' AS kind, ar.id, ar.name AS title, '' AS artist, NULL::bigint AS album_id, '' AS album,
+		       ts_rank_cd(ar.search_vector, q.tsq) + word_similarity(q.term, catalog_unaccent(ar.name)) AS rank,
+		       ` + searchHeadline("ar.name") + ` AS highlight
+		FROM artists ar, q
+		WHERE ar.search_vector @@ q.tsq OR q.term <% catalog_unaccent(ar.name)`,
+}
+
+// searchHeadlineOptions highlight every match in the (short) titles.
+const searchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, HighlightAll=true"
+
+// searchHeadline returns the highlight expression for a title or name
+// column. The column is HTML-escaped before ts_headline adds the <mark>
+// tags, so that the tags are the only markup in the highlight.
+func searchHeadline(column string) string {
+	escaped := "replace(replace(replace(replace(" + column + `, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;')`
+	return "ts_headline('catalog', " + escaped + ", q.tsq, q.headline)"
+}
+
+var searchTypeOrder = []string{SearchTypeAlbum, SearchTypeSong, SearchTypeArtist}
+
+// SearchCatalog runs a ranked full-text search across albums, songs and
+// artists and returns the best matches of all kinds together, best first.
+// Matching ignores case and accents, accepts words in any order and
+// web-search syntax ("quoted phrases", -excluded), and tolerates typos
+// through trigram similarity.
+func (s *Store) SearchCatalog(ctx context.Context, search CatalogSearch) ([]CatalogSearchResult, error) {
+	query := strings.TrimSpace(search.Query)
+	if query == "" {
+		return nil, fmt.Errorf("%w: query is required", ErrInvalidSearch)
+	}
+
+	limit := search.Limit
+	switch {
+	case limit == 0:
+		limit = DefaultSearchLimit
+	case limit < 0 || limit > MaxSearchLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidSearch, MaxSearchLimit)
+	}
+
+	types := searchTypeOrder
+	if len(search.Types) > 0 {
+		wanted := make(map[string]bool, len(search.Types))
+		for _, kind := range search.Types {
+			kind = strings.ToLower(strings.TrimSpace(kind))
+			if _, ok := catalogSearchQueries[kind]; !ok {
+				return nil, fmt.Errorf("%w: unknown type %q", ErrInvalidSearch, kind)
+			}
+			wanted[kind] = true
+		}
+		types = nil
+		for _, kind := range searchTypeOrder {
+			if wanted[kind] {
+				types = append(types, kind)
+			}
+		}
+	}
+
+	parts := make([]string, len(types))
+	for i, kind := range types {
+		parts[i] = catalogSearchQueries[kind]
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		WITH q AS (
+			SELECT websearch_to_tsquery('catalog', $1) AS tsq, catalog_unaccent($1) AS term, $3::text AS headline
+		)
+		SELECT kind, id, title, artist, album_id, album, rank, highlight
+		FROM (`+strings.Join(parts, "\n\t\tUNION ALL")+`
+		) AS results
+		ORDER BY rank DESC, kind, id
+		LIMIT $2
+	`, query, limit, searchHeadlineOptions)
+	if err != nil {
+		return nil, fmt.Errorf("search catalog: %w", err)
+	}
+	defer rows.Close()
+
+	results := []CatalogSearchResult{}
+	for rows.Next() {
+		var (
+			result  CatalogSearchResult
+			albumID *int64
+		)
+		if err := rows.Scan(&result.Type, &result.ID, &result.Title, &result.Artist, &albumID, &result.Album, &result.Rank, &result.Highlight); err != nil {
+			return nil, fmt.Errorf("scan search result: %w", err)
+		}
+		result.AlbumID = albumID
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate search results: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSearchCatalogRanksMixedResults(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectQuery(`websearch_to_tsquery\('catalog', \$1\)(?s:.*)FROM albums a(?s:.*)UNION ALL(?s:.*)FROM artists ar`).
+		WithArgs("beyonce", 10, searchHeadlineOptions).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "id", "title", "artist", "album_id", "album", "rank", "highlight"}).
+			AddRow("artist", int64(2), "Beyoncé", "", nil, "", 1.1, "<mark>Beyoncé</mark>").
+			AddRow("album", int64(4), "Beyoncé", "Beyoncé", nil, "", 0.9, "<mark>Beyoncé</mark>"))
+
+	results, err := s.SearchCatalog(context.Background(), CatalogSearch{Query: " beyonce ", Types: []string{"Artist", "album"}, Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchCatalog: %v", err)
+	}
+	if len(results) != 2 || results[0].Type != SearchTypeArtist || results[1].ID != 4 || results[0].AlbumID != nil {
+		t.Fatalf("unexpected results %+v", results)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSearchCatalogDefaultsToAllTypes(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM q, songs s`)).
+		WithArgs("abey road", DefaultSearchLimit, searchHeadlineOptions).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "id", "title", "artist", "album_id", "album", "rank", "highlight"}).
+			AddRow("song", int64(12), "Abbey Road Medley", "The Beatles", int64(4), "Abbey Road", 0.5, "Abbey Road Medley"))
+
+	results, err := s.SearchCatalog(context.Background(), CatalogSearch{Query: "abey road"})
+	if err != nil {
+		t.Fatalf("SearchCatalog: %v", err)
+	}
+	if len(results) != 1 || results[0].AlbumID == nil || *results[0].AlbumID != 4 || results[0].Album != "Abbey Road" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSearchCatalogEscapesHighlights(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`ts_headline('catalog', replace(replace(replace(replace(ar.name, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), q.tsq, q.headline)`)).
+		WithArgs("script", DefaultSearchLimit, searchHeadlineOptions).
+		WillReturnRows(sqlmock.NewRows([]string{"kind", "id", "title", "artist", "album_id", "album", "rank", "highlight"}).
+			AddRow("artist", int64(3), "<script>", "", nil, "", 0.7, "&lt;<mark>script</mark>&gt;"))
+
+	if _, err := s.SearchCatalog(context.Background(), CatalogSearch{Query: "script", Types: []string{"artist"}}); err != nil {
+		t.Fatalf("SearchCatalog: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestSearchCatalogValidation(t *testing.T) {
+	s := New(nil)
+
+	tests := []struct {
+		name   string
+		search CatalogSearch
+		want   string
+	}{
+		{"empty query", CatalogSearch{Query: "  "}, "query is required"},
+		{"negative limit", CatalogSearch{Query: "x", Limit: -1}, "limit"},
+		{"limit too large", CatalogSearch{Query: "x", Limit: MaxSearchLimit + 1}, "limit"},
+		{"unknown type", CatalogSearch{Query: "x", Types: []string{"playlist"}}, "unknown type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.SearchCatalog(context.Background(), tt.search)
+			if !errors.Is(err, ErrInvalidSearch) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected ErrInvalidSearch mentioning %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package store
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// sessionTouchInterval is how often activity on a session is written back.
+const sessionTouchInterval = time.Minute
+
+// SessionStore resolves session tokens on every authenticated request and
+// removes sessions once they expire. Sessions are created and rotated by
+// Store inside its own transactions; Store reports tokens that stop being
+// valid through Invalidate and InvalidateUser.
+type SessionStore interface {
+	// Lookup returns the user owning an unexpired session token, or
+	// ErrUnauthorized.
+	Lookup(ctx context.Context, token string) (int64, error)
+	// Touch records activity on a session and slides its expiry to at least
+	// ttl from now. Failures are ignored; the session stays valid until its
+	// current expiry.
+	Touch(ctx context.Context, token string, ttl time.Duration)
+	// Invalidate drops tokens whose sessions were revoked or rotated.
+	Invalidate(tokens ...string)
+	// InvalidateUser drops every token of a user whose sessions were revoked
+	// in bulk.
+	InvalidateUser(userID int64)
+	// DeleteExpired removes expired sessions and reports how many went.
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// PostgresSessionStore reads sessions straight from the sessions table.
+type PostgresSessionStore struct {
+	db *sql.DB
+}
+
+// NewPostgresSessionStore returns a SessionStore backed by the sessions table.
+func NewPostgresSessionStore(db *sql.DB) *PostgresSessionStore {
+	return &PostgresSessionStore{db: db}
+}
+
+func (p *PostgresSessionStore) Lookup(ctx context.Context, token string) (int64, error) {
+	var userID int64
+	err := p.db.QueryRowContext(ctx, `
+		SELECT user_id
+		FROM sessions
+		WHERE token = $1
+		  AND expires_at > NOW()
+	`, token).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUnauthorized
+		}
+		return 0, fmt.Errorf("lookup session: %w", err)
+	}
+	return userID, nil
+}
+
+// Touch is throttled to once per sessionTouchInterval in SQL so token lookups
+// do not turn every read into a write.
+func (p *PostgresSessionStore) Touch(ctx context.Context, token string, ttl time.Duration) {
+	_, _ = p.db.ExecContext(ctx, `
+		UPDATE sessions
+		SET last_seen_at = NOW(),
+		    expires_at = GREATEST(expires_at, NOW() + make_interval(secs => $2))
+		WHERE token = $1 AND last_seen_at < NOW() - INTERVAL '1 minute'
+	`, token, ttl.Seconds())
+}
+
+// Invalidate does nothing: revoked rows are already gone from the table.
+func (p *PostgresSessionStore) Invalidate(...string) {}
+
+// InvalidateUser does nothing: revoked rows are already gone from the table.
+func (p *PostgresSessionStore) InvalidateUser(int64) {}
+
+// DeleteExpired keeps sessions whose access token expired while they still
+// hold an unused, unexpired refresh token, so the client can still refresh.
+func (p *PostgresSessionStore) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := p.db.ExecContext(ctx, `
+		DELETE FROM sessions s
+		WHERE s.expires_at <= NOW()
+		  AND NOT EXISTS (
+			SELECT 1 FROM refresh_tokens r
+			WHERE r.session_id = s.id AND r.used_at IS NULL AND r.expires_at > NOW()
+		  )
+	`)
+	if err != nil {
+		return 0, fmt.Errorf("delete expired sessions: %w", err)
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("check deleted sessions: %w", err)
+	}
+	return deleted, nil
+}
+
+// MemorySessionStore keeps sessions in process memory. It suits tests and
+// single-process tools that do not need sessions to survive a restart;
+// sessions are added with Put.
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]memorySession
+}
+
+type memorySession struct {
+	userID    int64
+	expiresAt time.Time
+}
+
+// NewMemorySessionStore returns an empty in-memory SessionStore.
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]memorySession)}
+}
+
+// Put adds or replaces a session.
+func (m *MemorySessionStore) Put(token string, userID int64, expiresAt time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[token] = memorySession{userID: userID, expiresAt: expiresAt}
+}
+
+func (m *MemorySessionStore) Lookup(_ context.Context, token string) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[token]
+	if !ok || !session.expiresAt.After(time.Now()) {
+		return 0, ErrUnauthorized
+	}
+	return session.userID, nil
+}
+
+func (m *MemorySessionStore) Touch(_ context.Context, token string, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	session, ok := m.sessions[token]
+	if !ok {
+		return
+	}
+	if extended := time.Now().Add(ttl); extended.After(session.expiresAt) {
+		session.expiresAt = extended
+		m.sessions[token] = session
+	}
+}
+
+func (m *MemorySessionStore) Invalidate(tokens ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, token := range tokens {
+		delete(m.sessions, token)
+	}
+}
+
+func (m *MemorySessionStore) InvalidateUser(userID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for token, session := range m.sessions {
+		if session.userID == userID {
+			delete(m.sessions, token)
+		}
+	}
+}
+
+func (m *MemorySessionStore) DeleteExpired(context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	var deleted int64
+	for token, session := range m.sessions {
+		if !session.expiresAt.After(now) {
+			delete(m.sessions, token)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// CachedSessionStore keeps recent token lookups of another SessionStore in a
+// bounded LRU cache. Entries live for at most ttl, which bounds how long a
+// session revoked by another process, or one that expired, is still
+// accepted; revocations made through this store take effect immediately.
+type CachedSessionStore struct {
+	next SessionStore
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type cachedSession struct {
+	token     string
+	userID    int64
+	cachedAt  time.Time
+	touchedAt time.Time
+}
+
+// NewCachedSessionStore caches up to size lookups of next for ttl each.
+func NewCachedSessionStore(next SessionStore, size int, ttl time.Duration) *CachedSessionStore {
+	return &CachedSessionStore{
+		next:    next,
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// Len reports how many tokens are cached.
+func (c *CachedSessionStore) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+func (c *CachedSessionStore) Lookup(ctx context.Context, token string) (int64, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[token]; ok {
+		entry := elem.Value.(*cachedSession)
+		if time.Since(entry.cachedAt) < c.ttl {
+			c.order.MoveToFront(elem)
+			c.mu.Unlock()
+			return entry.userID, nil
+		}
+		c.removeLocked(elem)
+	}
+	c.mu.Unlock()
+
+	// Failed lookups are not cached, so a token is never refused for longer
+	// than the underlying store refuses it.
+	userID, err := c.next.Lookup(ctx, token)
+	if err != nil {
+		return 0, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[token]; ok {
+		c.removeLocked(elem)
+	}
+	c.entries[token] = c.order.PushFront(&cachedSession{token: token, userID: userID, cachedAt: time.Now()})
+	for c.order.Len() > c.size {
+		c.removeLocked(c.order.Back())
+	}
+	return userID, nil
+}
+
+// Touch forwards activity at most once per sessionTouchInterval per token.
+func (c *CachedSessionStore) Touch(ctx context.Context, token string, ttl time.Duration) {
+	c.mu.Lock()
+	if elem, ok := c.entries[token]; ok {
+		entry := elem.Value.(*cachedSession)
+		if time.Since(entry.touchedAt) < sessionTouchInterval {
+			c.mu.Unlock()
+			return
+		}
+		entry.touchedAt = time.Now()
+	}
+	c.mu.Unlock()
+
+	c.next.Touch(ctx, token, ttl)
+}
+
+func (c *CachedSessionStore) Invalidate(tokens ...string) {
+	c.mu.Lock()
+	for _, token := range tokens {
+		if elem, ok := c.entries[token]; ok {
+			c.removeLocked(elem)
+		}
+	}
+	c.mu.Unlock()
+
+	c.next.Invalidate(tokens...)
+}
+
+func (c *CachedSessionStore) InvalidateUser(userID int64) {
+	c.mu.Lock()
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+		if elem.Value.(*cachedSession).userID == userID {
+			c.removeLocked(elem)
+		}
+		elem = next
+	}
+	c.mu.Unlock()
+
+	c.next.InvalidateUser(userID)
+}
+
+func (c *CachedSessionStore) DeleteExpired(ctx context.Context) (int64, error) {
+	return c.next.DeleteExpired(ctx)
+}
+
+func (c *CachedSessionStore) removeLocked(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*cachedSession).token)
+}
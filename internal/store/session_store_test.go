@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// countingSessionStore counts the calls a CachedSessionStore forwards.
+type countingSessionStore struct {
+	*MemorySessionStore
+	lookups int
+	touches int
+}
+
+func (c *countingSessionStore) Lookup(ctx context.Context, token string) (int64, error) {
+	c.lookups++
+	return c.MemorySessionStore.Lookup(ctx, token)
+}
+
+func (c *countingSessionStore) Touch(ctx context.Context, token string, ttl time.Duration) {
+	c.touches++
+	c.MemorySessionStore.Touch(ctx, token, ttl)
+}
+
+func TestMemorySessionStore(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemorySessionStore()
+	m.Put("live", 1, time.Now().Add(time.Hour))
+	m.Put("expired", 1, time.Now().Add(-time.Minute))
+	m.Put("other", 2, time.Now().Add(time.Hour))
+
+	if userID, err := m.Lookup(ctx, "live"); err != nil || userID != 1 {
+		t.Fatalf("Lookup(live) = %d, %v", userID, err)
+	}
+	if _, err := m.Lookup(ctx, "expired"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized for expired session, got %v", err)
+	}
+
+	deleted, err := m.DeleteExpired(ctx)
+	if err != nil || deleted != 1 {
+		t.Fatalf("DeleteExpired = %d, %v; want 1", deleted, err)
+	}
+
+	m.InvalidateUser(1)
+	if _, err := m.Lookup(ctx, "live"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected user's sessions to be gone, got %v", err)
+	}
+	if _, err := m.Lookup(ctx, "other"); err != nil {
+		t.Fatalf("expected other user's session to remain, got %v", err)
+	}
+}
+
+func TestCachedSessionStoreServesRepeatLookups(t *testing.T) {
+	ctx := context.Background()
+	backing := &countingSessionStore{MemorySessionStore: NewMemorySessionStore()}
+	backing.Put("token", 7, time.Now().Add(time.Hour))
+	cache := NewCachedSessionStore(backing, 10, time.Minute)
+
+	for range 3 {
+		userID, err := cache.Lookup(ctx, "token")
+		if err != nil || userID != 7 {
+			t.Fatalf("Lookup = %d, %v", userID, err)
+		}
+		cache.Touch(ctx, "token", time.Hour)
+	}
+	if backing.lookups != 1 {
+		t.Fatalf("expected 1 backing lookup, got %d", backing.lookups)
+	}
+	if backing.touches != 1 {
+		t.Fatalf("expected touches to be throttled to 1, got %d", backing.touches)
+	}
+
+	if _, err := cache.Lookup(ctx, "unknown"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+	if cache.Len() != 1 {
+		t.Fatalf("failed lookups must not be cached, have %d entries", cache.Len())
+	}
+}
+
+func TestCachedSessionStoreInvalidation(t *testing.T) {
+	ctx := context.Background()
+	backing := NewMemorySessionStore()
+	backing.Put("a", 1, time.Now().Add(time.Hour))
+	backing.Put("b", 1, time.Now().Add(time.Hour))
+	backing.Put("c", 2, time.Now().Add(time.Hour))
+	cache := NewCachedSessionStore(backing, 10, time.Hour)
+
+	for _, token := range []string{"a", "b", "c"} {
+		if _, err := cache.Lookup(ctx, token); err != nil {
+			t.Fatalf("Lookup(%s): %v", token, err)
+		}
+	}
+
+	cache.Invalidate("a")
+	if _, err := cache.Lookup(ctx, "a"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected revoked token to be refused, got %v", err)
+	}
+
+	cache.InvalidateUser(1)
+	if _, err := cache.Lookup(ctx, "b"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected user's tokens to be refused, got %v", err)
+	}
+	if _, err := cache.Lookup(ctx, "c"); err != nil {
+		t.Fatalf("expected other user's token to remain, got %v", err)
+	}
+}
+
+func TestCachedSessionStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	backing := &countingSessionStore{MemorySessionStore: NewMemorySessionStore()}
+	for _, token := range []string{"a", "b", "c"} {
+		backing.Put(token, 1, time.Now().Add(time.Hour))
+	}
+	cache := NewCachedSessionStore(backing, 2, time.Hour)
+
+	_, _ = cache.Lookup(ctx, "a")
+	_, _ = cache.Lookup(ctx, "b")
+	_, _ = cache.Lookup(ctx, "a") // a is now the most recently used
+	_, _ = cache.Lookup(ctx, "c") // evicts b
+
+	if cache.Len() != 2 {
+		t.Fatalf("expected 2 cached entries, got %d", cache.Len())
+	}
+	before := backing.lookups
+	_, _ = cache.Lookup(ctx, "a")
+	if backing.lookups != before {
+		t.Fatalf("expected a to still be cached")
+	}
+	_, _ = cache.Lookup(ctx, "b")
+	if backing.lookups != before+1 {
+		t.Fatalf("expected b to have been evicted")
+	}
+}
+
+func TestCachedSessionStoreExpiresEntries(t *testing.T) {
+	ctx := context.Background()
+	backing := &countingSessionStore{MemorySessionStore: NewMemorySessionStore()}
+	backing.Put("token", 1, time.Now().Add(time.Hour))
+	cache := NewCachedSessionStore(backing, 10, time.Millisecond)
+
+	_, _ = cache.Lookup(ctx, "token")
+	time.Sleep(5 * time.Millisecond)
+	_, _ = cache.Lookup(ctx, "token")
+
+	if backing.lookups != 2 {
+		t.Fatalf("expected stale entry to be looked up again, got %d lookups", backing.lookups)
+	}
+}
+
+func TestLogoutInvalidatesCachedSession(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	backing := NewMemorySessionStore()
+	backing.Put("token", 42, time.Now().Add(time.Hour))
+	cache := NewCachedSessionStore(backing, 10, time.Hour)
+
+	s := New(db)
+	s.SetSessionStore(cache)
+
+	if _, err := s.UserIDByToken(context.Background(), "token"); err != nil {
+		t.Fatalf("UserIDByToken: %v", err)
+	}
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM sessions`)).
+		WithArgs("token").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := s.Logout(context.Background(), "token"); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if _, err := s.UserIDByToken(context.Background(), "token"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected logged-out token to be refused, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestPostgresSessionStoreDeleteExpiredKeepsRefreshable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		DELETE FROM sessions s
+		WHERE s.expires_at <= NOW()
+		  AND NOT EXISTS (`)).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+
+	deleted, err := NewPostgresSessionStore(db).DeleteExpired(context.Background())
+	if err != nil {
+		t.Fatalf("DeleteExpired: %v", err)
+	}
+	if deleted != 5 {
+		t.Fatalf("expected 5 deleted sessions, got %d", deleted)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,284 @@
+package store
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrSessionNotFound signals a missing or foreign session record.
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrInvalidRefreshToken indicates an unknown or expired refresh token.
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused indicates an already-rotated refresh token was
+	// presented again; the session it belonged to has been revoked.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// SessionTokens is the credential pair handed out at login and on refresh.
+type SessionTokens struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+// SessionMetadata captures client details recorded when a session is created.
+type SessionMetadata struct {
+	UserAgent string
+	IPAddress string
+}
+
+// Session describes an active login belonging to a user.
+type Session struct {
+	ID         int64     `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	IPAddress  string    `json:"ip_address,omitempty"`
+	Current    bool      `json:"current"`
+}
+
+// Logout ends the session represented by the token.
+func (s *Store) Logout(ctx context.Context, token string) error {
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM sessions
+		WHERE token = $1
+	`, token)
+	if err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check deleted session: %w", err)
+	}
+	if affected == 0 {
+		return ErrUnauthorized
+	}
+
+	s.sessions.Invalidate(token)
+	return nil
+}
+
+// SessionsByToken lists the unexpired sessions of the user owning the token,
+// flagging the session the token belongs to.
+func (s *Store) SessionsByToken(ctx context.Context, token string) ([]Session, error) {
+	userID, err := s.userIDForSession(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, created_at, last_seen_at, expires_at,
+		       COALESCE(user_agent, ''), COALESCE(ip_address, ''), token = $2
+		FROM sessions
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY last_seen_at DESC, id DESC
+	`, userID, token)
+	if err != nil {
+		return nil, fmt.Errorf("select sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var session Session
+		if err := rows.Scan(
+			&session.ID,
+			&session.CreatedAt,
+			&session.LastSeenAt,
+			&session.ExpiresAt,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.Current,
+		); err != nil {
+			return nil, fmt.Errorf("scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession deletes one of the authenticated user's sessions by identifier.
+func (s *Store) RevokeSession(ctx context.Context, token string, sessionID int64) error {
+	userID, err := s.userIDForSession(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM sessions
+		WHERE id = $1 AND user_id = $2
+	`, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("revoke session: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("check revoked session: %w", err)
+	}
+	if affected == 0 {
+		return ErrSessionNotFound
+	}
+
+	// The revoked token is not known here; drop the user's cached tokens and
+	// let the remaining ones be looked up again.
+	s.sessions.InvalidateUser(userID)
+	return nil
+}
+
+// RevokeOtherSessions deletes every session of the authenticated user except
+// the one the token belongs to and reports how many were removed.
+func (s *Store) RevokeOtherSessions(ctx context.Context, token string) (int64, error) {
+	userID, err := s.userIDForSession(ctx, token)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM sessions
+		WHERE user_id = $1 AND token <> $2
+	`, userID, token)
+	if err != nil {
+		return 0, fmt.Errorf("revoke sessions: %w", err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("check revoked sessions: %w", err)
+	}
+
+	s.sessions.InvalidateUser(userID)
+	return affected, nil
+}
+
+// RefreshSession rotates a refresh token: the presented token is marked used,
+// the session receives a new access token and expiry, and a new refresh token
+// is issued in the same family. Presenting a token that was already rotated
+// revokes the whole session and returns ErrRefreshTokenReused.
+func (s *Store) RefreshSession(ctx context.Context, refreshToken string) (SessionTokens, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var (
+		tokenID     int64
+		sessionID   int64
+		used        bool
+		live        bool
+		accessToken string
+	)
+	err = tx.QueryRowContext(ctx, `
+		SELECT r.id, r.session_id, r.used_at IS NOT NULL, r.expires_at > NOW(), s.token
+		FROM refresh_tokens r
+		JOIN sessions s ON s.id = r.session_id
+		WHERE r.token_hash = $1
+		FOR UPDATE
+	`, hashToken(refreshToken)).Scan(&tokenID, &sessionID, &used, &live, &accessToken)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SessionTokens{}, ErrInvalidRefreshToken
+		}
+		return SessionTokens{}, fmt.Errorf("lookup refresh token: %w", err)
+	}
+
+	if used {
+		if _, err := tx.ExecContext(ctx, `
+			DELETE FROM sessions
+			WHERE id = $1
+		`, sessionID); err != nil {
+			return SessionTokens{}, fmt.Errorf("revoke token family: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return SessionTokens{}, fmt.Errorf("commit token family revocation: %w", err)
+		}
+		tx = nil
+		s.sessions.Invalidate(accessToken)
+		return SessionTokens{}, ErrRefreshTokenReused
+	}
+	if !live {
+		return SessionTokens{}, ErrInvalidRefreshToken
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE refresh_tokens
+		SET used_at = NOW()
+		WHERE id = $1
+	`, tokenID); err != nil {
+		return SessionTokens{}, fmt.Errorf("mark refresh token used: %w", err)
+	}
+
+	nextAccess, err := newToken()
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("create token: %w", err)
+	}
+	expiresAt := time.Now().Add(s.accessTTL)
+
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE sessions
+		SET token = $1, expires_at = $2, last_seen_at = NOW()
+		WHERE id = $3
+	`, nextAccess, expiresAt, sessionID); err != nil {
+		return SessionTokens{}, fmt.Errorf("rotate session token: %w", err)
+	}
+
+	nextRefresh, err := s.issueRefreshToken(ctx, tx, sessionID)
+	if err != nil {
+		return SessionTokens{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return SessionTokens{}, fmt.Errorf("commit refresh: %w", err)
+	}
+	tx = nil
+
+	// The rotated-out access token stops working immediately.
+	s.sessions.Invalidate(accessToken)
+
+	return SessionTokens{
+		AccessToken:  nextAccess,
+		RefreshToken: nextRefresh,
+		ExpiresAt:    expiresAt,
+	}, nil
+}
+
+// issueRefreshToken creates a new refresh token for the session. Only the
+// token hash is persisted.
+func (s *Store) issueRefreshToken(ctx context.Context, tx *sql.Tx, sessionID int64) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", fmt.Errorf("create refresh token: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, sessionID, hashToken(token), time.Now().Add(s.refreshTTL)); err != nil {
+		return "", fmt.Errorf("store refresh token: %w", err)
+	}
+
+	return token, nil
+}
+
+// hashToken returns the hex SHA-256 digest stored in place of single-use tokens.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,216 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func expectSessionLookup(mock sqlmock.Sqlmock, token string, userID int64) {
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		SELECT user_id
+		FROM sessions
+		WHERE token = $1
+		  AND expires_at > NOW()
+	`)).
+		WithArgs(token).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(userID))
+
+	mock.ExpectExec(regexp.QuoteMeta(touchSessionQuery)).
+		WithArgs(token, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestLogoutUnknownToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+		DELETE FROM sessions
+		WHERE token = $1
+	`)).
+		WithArgs("stale").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := s.Logout(context.Background(), "stale"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("expected ErrUnauthorized, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRevokeSessionNotOwned(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 42)
+	mock.ExpectExec(regexp.QuoteMeta(`
+		DELETE FROM sessions
+		WHERE id = $1 AND user_id = $2
+	`)).
+		WithArgs(int64(7), int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := s.RevokeSession(context.Background(), "token", 7); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRevokeOtherSessionsKeepsCurrent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "token", 42)
+	mock.ExpectExec(regexp.QuoteMeta(`
+		DELETE FROM sessions
+		WHERE user_id = $1 AND token <> $2
+	`)).
+		WithArgs(int64(42), "token").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+
+	revoked, err := s.RevokeOtherSessions(context.Background(), "token")
+	if err != nil {
+		t.Fatalf("RevokeOtherSessions: %v", err)
+	}
+	if revoked != 3 {
+		t.Fatalf("expected 3 revoked sessions, got %d", revoked)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+const refreshLookupQuery = `
+		SELECT r.id, r.session_id, r.used_at IS NOT NULL, r.expires_at > NOW(), s.token
+		FROM refresh_tokens r
+		JOIN sessions s ON s.id = r.session_id
+		WHERE r.token_hash = $1
+		FOR UPDATE
+	`
+
+func TestRefreshSessionRotatesTokens(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(refreshLookupQuery)).
+		WithArgs(hashToken("refresh")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "session_id", "used", "live", "token"}).AddRow(int64(3), int64(9), false, true, "access"))
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE refresh_tokens
+		SET used_at = NOW()
+		WHERE id = $1
+	`)).
+		WithArgs(int64(3)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`
+		UPDATE sessions
+		SET token = $1, expires_at = $2, last_seen_at = NOW()
+		WHERE id = $3
+	`)).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`
+		INSERT INTO refresh_tokens (session_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`)).
+		WithArgs(int64(9), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectCommit()
+
+	tokens, err := s.RefreshSession(context.Background(), "refresh")
+	if err != nil {
+		t.Fatalf("RefreshSession: %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" || tokens.RefreshToken == "refresh" {
+		t.Fatalf("expected fresh token pair, got %+v", tokens)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRefreshSessionReuseRevokesFamily(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(refreshLookupQuery)).
+		WithArgs(hashToken("stolen")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "session_id", "used", "live", "token"}).AddRow(int64(3), int64(9), true, true, "access"))
+	mock.ExpectExec(regexp.QuoteMeta(`
+			DELETE FROM sessions
+			WHERE id = $1
+		`)).
+		WithArgs(int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	if _, err := s.RefreshSession(context.Background(), "stolen"); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestRefreshSessionExpiredToken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(refreshLookupQuery)).
+		WithArgs(hashToken("old")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "session_id", "used", "live", "token"}).AddRow(int64(3), int64(9), false, false, "access"))
+	mock.ExpectRollback()
+
+	if _, err := s.RefreshSession(context.Background(), "old"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected ErrInvalidRefreshToken, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -3,19 +3,52 @@ package store
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 )
 
 // Song represents a song/track in the database.
 type Song struct {
-	ID       int64  `json:"id"`
-	Title    string `json:"title"`
-	Artist   string `json:"artist"`
-	AlbumID  *int64 `json:"album_id,omitempty"`
-	Album    string `json:"album,omitempty"`
-	Duration int    `json:"duration,omitempty"`
-	TrackNum int    `json:"track_num,omitempty"`
-	Genre    string `json:"genre,omitempty"`
+	ID         int64  `json:"id"`
+	Title      string `json:"title"`
+	Artist     string `json:"artist"`
+	AlbumID    *int64 `json:"album_id,omitempty"`
+	Album      string `json:"album,omitempty"`
+	Duration   int    `json:"duration,omitempty"`
+	DiscNumber int    `json:"disc_number,omitempty"`
+	Side       string `json:"side,omitempty"`
+	TrackNum   int    `json:"track_num,omitempty"`
+	Genre      string `json:"genre,omitempty"`
+}
+
+// Track is a song in its place on an album's tracklist. Position counts from
+// one on each disc, or on each side when the release has sides.
+type Track struct {
+	SongID     int64  `json:"id,omitempty"`
+	DiscNumber int    `json:"discNumber"`
+	Side       string `json:"side,omitempty"`
+	Position   int    `json:"position"`
+	Title      string `json:"title"`
+	Duration   int    `json:"duration,omitempty"`
+}
+
+// UnmarshalJSON also accepts a bare title, the tracklist format used before
+// tracks carried disc and side information.
+func (t *Track) UnmarshalJSON(data []byte) error {
+	var title string
+	if err := json.Unmarshal(data, &title); err == nil {
+		*t = Track{Title: title}
+		return nil
+	}
+
+	type plain Track
+	var track plain
+	if err := json.Unmarshal(data, &track); err != nil {
+		return err
+	}
+	*t = Track(track)
+	return nil
 }
 
 // SongFilter defines criteria for filtering songs.
@@ -27,15 +60,38 @@ type SongFilter struct {
 }
 
 // Search is an alias for ListSongs for API compatibility
-func (s *Store) Search(ctx context.Context, filter SongFilter) ([]Song, error) {
-	return s.ListSongs(ctx, filter)
+func (s *Store) Search(ctx context.Context, filter SongFilter, page Page) ([]Song, string, error) {
+	return s.ListSongs(ctx, filter, page)
 }
 
-// ListSongs returns songs matching the filter.
-func (s *Store) ListSongs(ctx context.Context, filter SongFilter) ([]Song, error) {
+// songKeyset orders songs by album and then tracklist position; songs
+// without an album come first.
+var songKeyset = keyset{name: "songs", columns: []keyColumn{
+	{expr: "COALESCE(s.album_id, 0)", kind: keyInt},
+	{expr: "s.disc_number", kind: keyInt},
+	{expr: "COALESCE(s.side, '')", kind: keyString},
+	{expr: "COALESCE(s.track_num, 0)", kind: keyInt},
+	{expr: "s.id", kind: keyInt},
+}}
+
+func songKey(song Song) []any {
+	var albumID int64
+	if song.AlbumID != nil {
+		albumID = *song.AlbumID
+	}
+	return []any{albumID, song.DiscNumber, song.Side, song.TrackNum, song.ID}
+}
+
+// ListSongs returns one page of songs matching the filter.
+func (s *Store) ListSongs(ctx context.Context, filter SongFilter, page Page) ([]Song, string, error) {
+	pageQ, err := songKeyset.page(page)
+	if err != nil {
+		return nil, "", err
+	}
+
 	query := `
 		SELECT s.id, s.title, s.artist, s.album_id, COALESCE(a.title, '') as album,
-		       COALESCE(s.duration, 0), COALESCE(s.track_num, 0)
+		       COALESCE(s.duration, 0), s.disc_number, COALESCE(s.side, ''), COALESCE(s.track_num, 0)
 		FROM songs s
 		LEFT JOIN albums a ON s.album_id = a.id
 		WHERE 1=1`
@@ -63,14 +119,15 @@ func (s *Store) ListSongs(ctx context.Context, filter SongFilter) ([]Song, error
 	if filter.AlbumID != nil {
 		query += fmt.Sprintf(" AND s.album_id = $%d", argIdx)
 		args = append(args, *filter.AlbumID)
-		argIdx++
 	}
 
-	query += " ORDER BY s.album_id, s.track_num, s.title LIMIT 100"
+	after, args := pageQ.where(args)
+	tail, args := pageQ.orderLimit(args)
+	query += " AND " + after + " " + tail
 
 	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("query songs: %w", err)
+		return nil, "", fmt.Errorf("query songs: %w", err)
 	}
 	defer rows.Close()
 
@@ -81,8 +138,8 @@ func (s *Store) ListSongs(ctx context.Context, filter SongFilter) ([]Song, error
 		var album string
 		var duration, trackNum int
 
-		if err := rows.Scan(&song.ID, &song.Title, &song.Artist, &albumID, &album, &duration, &trackNum); err != nil {
-			return nil, fmt.Errorf("scan song: %w", err)
+		if err := rows.Scan(&song.ID, &song.Title, &song.Artist, &albumID, &album, &duration, &song.DiscNumber, &song.Side, &trackNum); err != nil {
+			return nil, "", fmt.Errorf("scan song: %w", err)
 		}
 
 		if albumID.Valid {
@@ -96,10 +153,11 @@ func (s *Store) ListSongs(ctx context.Context, filter SongFilter) ([]Song, error
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate songs: %w", err)
+		return nil, "", fmt.Errorf("iterate songs: %w", err)
 	}
 
-	return songs, nil
+	songs, next := finishPage(pageQ, songs, songKey)
+	return songs, next, nil
 }
 
 // Get is an alias for GetSong for API compatibility
@@ -116,10 +174,10 @@ func (s *Store) GetSong(ctx context.Context, id int64) (Song, error) {
 
 	err := s.db.QueryRowContext(ctx, `
 		SELECT s.id, s.title, s.artist, s.album_id, COALESCE(a.title, ''),
-		       s.duration, s.track_num
+		       s.duration, s.disc_number, COALESCE(s.side, ''), s.track_num
 		FROM songs s
 		LEFT JOIN albums a ON s.album_id = a.id
-		WHERE s.id = $1`, id).Scan(&song.ID, &song.Title, &song.Artist, &albumID, &album, &duration, &trackNum)
+		WHERE s.id = $1`, id).Scan(&song.ID, &song.Title, &song.Artist, &albumID, &album, &duration, &song.DiscNumber, &song.Side, &trackNum)
 
 	if err == sql.ErrNoRows {
 		return Song{}, fmt.Errorf("song not found")
@@ -141,3 +199,160 @@ func (s *Store) GetSong(ctx context.Context, id int64) (Song, error) {
 
 	return song, nil
 }
+
+// AlbumTracks returns the tracklist of an album in disc, side and position
+// order.
+func (s *Store) AlbumTracks(ctx context.Context, albumID int64) ([]Track, error) {
+	tracks, err := s.fetchAlbumTracks(ctx, []int64{albumID})
+	if err != nil {
+		return nil, err
+	}
+	return tracks[albumID], nil
+}
+
+func (s *Store) fetchAlbumTracks(ctx context.Context, albumIDs []int64) (map[int64][]Track, error) {
+	if len(albumIDs) == 0 {
+		return map[int64][]Track{}, nil
+	}
+
+	args := make([]any, len(albumIDs))
+	for i, id := range albumIDs {
+		args[i] = id
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT album_id, id, disc_number, COALESCE(side, ''), COALESCE(track_num, 0), title, COALESCE(duration, 0)
+		FROM songs
+		WHERE album_id IN (%s)
+		ORDER BY album_id, disc_number, side NULLS FIRST, track_num NULLS LAST, id
+	`, strings.Join(buildPlaceholders(len(albumIDs), 1), ", ")), args...)
+	if err != nil {
+		return nil, fmt.Errorf("select album tracks: %w", err)
+	}
+	defer rows.Close()
+
+	tracks := make(map[int64][]Track, len(albumIDs))
+	for rows.Next() {
+		var (
+			albumID int64
+			track   Track
+		)
+		if err := rows.Scan(&albumID, &track.SongID, &track.DiscNumber, &track.Side, &track.Position, &track.Title, &track.Duration); err != nil {
+			return nil, fmt.Errorf("scan album track: %w", err)
+		}
+		tracks[albumID] = append(tracks[albumID], track)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate album tracks: %w", err)
+	}
+	return tracks, nil
+}
+
+func (s *Store) applyAlbumTracks(ctx context.Context, albums []Album) ([]Album, error) {
+	if len(albums) == 0 {
+		return albums, nil
+	}
+
+	ids := make([]int64, len(albums))
+	for i, album := range albums {
+		ids[i] = album.ID
+	}
+
+	tracks, err := s.fetchAlbumTracks(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range albums {
+		albums[i].Tracks = tracks[albums[i].ID]
+		if albums[i].Tracks == nil {
+			albums[i].Tracks = []Track{}
+		}
+	}
+	return albums, nil
+}
+
+// normalizeTracks validates a tracklist and fills in defaults: tracks without
+// a disc are on disc 1 and tracks without a position follow the previous
+// track on the same disc and side.
+func normalizeTracks(tracks []Track) ([]Track, error) {
+	type slot struct {
+		disc int
+		side string
+	}
+
+	normalized := make([]Track, 0, len(tracks))
+	last := make(map[slot]int)
+	taken := make(map[Track]struct{})
+	for _, track := range tracks {
+		track.SongID = 0
+		track.Title = strings.TrimSpace(track.Title)
+		track.Side = strings.ToUpper(strings.TrimSpace(track.Side))
+		if track.Title == "" {
+			return nil, fmt.Errorf("%w: track title is required", ErrInvalidAlbum)
+		}
+		if track.DiscNumber == 0 {
+			track.DiscNumber = 1
+		}
+		switch {
+		case track.DiscNumber < 0:
+			return nil, fmt.Errorf("%w: disc number must be positive", ErrInvalidAlbum)
+		case track.Position < 0:
+			return nil, fmt.Errorf("%w: track position must be positive", ErrInvalidAlbum)
+		case track.Duration < 0:
+			return nil, fmt.Errorf("%w: track duration must not be negative", ErrInvalidAlbum)
+		case track.Side != "" && (len(track.Side) != 1 || track.Side[0] < 'A' || track.Side[0] > 'Z'):
+			return nil, fmt.Errorf("%w: side must be a single letter such as A or B", ErrInvalidAlbum)
+		}
+
+		key := slot{disc: track.DiscNumber, side: track.Side}
+		if track.Position == 0 {
+			track.Position = last[key] + 1
+		}
+		last[key] = track.Position
+
+		spot := Track{DiscNumber: track.DiscNumber, Side: track.Side, Position: track.Position}
+		if _, ok := taken[spot]; ok {
+			return nil, fmt.Errorf("%w: two tracks at disc %d side %q position %d", ErrInvalidAlbum, track.DiscNumber, track.Side, track.Position)
+		}
+		taken[spot] = struct{}{}
+
+		normalized = append(normalized, track)
+	}
+	return normalized, nil
+}
+
+// insertAlbumTracksTx stores the tracklist of a new album as songs credited to
+// the album's artists. The album's artist credits must already be linked.
+func insertAlbumTracksTx(ctx context.Context, tx *sql.Tx, albumID int64, tracks []Track) ([]Track, error) {
+	if len(tracks) == 0 {
+		return []Track{}, nil
+	}
+
+	stored := make([]Track, len(tracks))
+	for i, track := range tracks {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO songs (title, artist, album_id, artist_id, disc_number, side, track_num, duration)
+			SELECT $2, artist, id, artist_id, $3, $4, $5, $6
+			FROM albums
+			WHERE id = $1
+			RETURNING id
+		`, albumID, track.Title, track.DiscNumber, nullIfEmpty(track.Side), track.Position, nullIfZero(track.Duration)).Scan(&track.SongID)
+		if err != nil {
+			return nil, fmt.Errorf("insert track: %w", err)
+		}
+		stored[i] = track
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO song_artists (song_id, artist_id, role, position)
+		SELECT s.id, aa.artist_id, aa.role, aa.position
+		FROM songs s
+		JOIN album_artists aa ON aa.album_id = s.album_id
+		WHERE s.album_id = $1
+		ON CONFLICT (song_id, artist_id) DO NOTHING
+	`, albumID); err != nil {
+		return nil, fmt.Errorf("link track artists: %w", err)
+	}
+	return stored, nil
+}
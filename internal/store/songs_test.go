@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type trackRow struct {
+	albumID  int64
+	songID   int64
+	disc     int
+	side     string
+	position int
+	title    string
+	duration int
+}
+
+func expectAlbumTracks(mock sqlmock.Sqlmock, albumIDs []int64, tracks ...trackRow) {
+	args := make([]driver.Value, len(albumIDs))
+	for i, id := range albumIDs {
+		args[i] = id
+	}
+	rows := sqlmock.NewRows([]string{"album_id", "id", "disc_number", "side", "track_num", "title", "duration"})
+	for _, track := range tracks {
+		rows.AddRow(track.albumID, track.songID, track.disc, track.side, track.position, track.title, track.duration)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM songs
+		WHERE album_id IN`)).
+		WithArgs(args...).
+		WillReturnRows(rows)
+}
+
+func TestAlbumTracksOrdersSides(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectAlbumTracks(mock, []int64{4},
+		trackRow{albumID: 4, songID: 40, disc: 1, side: "A", position: 1, title: "Speak to Me", duration: 68},
+		trackRow{albumID: 4, songID: 41, disc: 1, side: "B", position: 1, title: "Money", duration: 382},
+	)
+
+	tracks, err := s.AlbumTracks(context.Background(), 4)
+	if err != nil {
+		t.Fatalf("AlbumTracks: %v", err)
+	}
+	want := []Track{
+		{SongID: 40, DiscNumber: 1, Side: "A", Position: 1, Title: "Speak to Me", Duration: 68},
+		{SongID: 41, DiscNumber: 1, Side: "B", Position: 1, Title: "Money", Duration: 382},
+	}
+	if !reflect.DeepEqual(tracks, want) {
+		t.Fatalf("AlbumTracks = %+v, want %+v", tracks, want)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestNormalizeTracks(t *testing.T) {
+	got, err := normalizeTracks([]Track{
+		{Title: " Side A opener ", Side: "a"},
+		{Title: "Side A closer", Side: "A"},
+		{Title: "Side B opener", Side: "B"},
+		{Title: "Bonus", DiscNumber: 2, Position: 5},
+	})
+	if err != nil {
+		t.Fatalf("normalizeTracks: %v", err)
+	}
+	want := []Track{
+		{DiscNumber: 1, Side: "A", Position: 1, Title: "Side A opener"},
+		{DiscNumber: 1, Side: "A", Position: 2, Title: "Side A closer"},
+		{DiscNumber: 1, Side: "B", Position: 1, Title: "Side B opener"},
+		{DiscNumber: 2, Position: 5, Title: "Bonus"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("normalizeTracks = %+v, want %+v", got, want)
+	}
+
+	invalid := [][]Track{
+		{{Title: "  "}},
+		{{Title: "Track", Side: "AB"}},
+		{{Title: "Track", DiscNumber: -1}},
+		{{Title: "One", Position: 1}, {Title: "Two", Position: 1}},
+	}
+	for _, tracks := range invalid {
+		if _, err := normalizeTracks(tracks); !errors.Is(err, ErrInvalidAlbum) {
+			t.Errorf("normalizeTracks(%+v): expected ErrInvalidAlbum, got %v", tracks, err)
+		}
+	}
+}
+
+func TestTrackUnmarshalAcceptsTitles(t *testing.T) {
+	var tracks []Track
+	if err := json.Unmarshal([]byte(`["Xtal", {"title": "Tha", "discNumber": 1, "side": "A", "position": 2}]`), &tracks); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	want := []Track{{Title: "Xtal"}, {Title: "Tha", DiscNumber: 1, Side: "A", Position: 2}}
+	if !reflect.DeepEqual(tracks, want) {
+		t.Fatalf("tracks = %+v, want %+v", tracks, want)
+	}
+}
@@ -13,6 +13,9 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
+
+	"vinylhound/shared/go/auth"
+	"vinylhound/shared/go/config"
 )
 
 var (
@@ -29,11 +32,47 @@ var (
 // Store provides persistence backed by Postgres.
 type Store struct {
 	db *sql.DB
+
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+
+	loginPolicy config.LoginProtectionConfig
+
+	sessions SessionStore
 }
 
 // New sets up a Store using the provided database handle.
 func New(db *sql.DB) *Store {
-	return &Store{db: db}
+	return &Store{
+		db:          db,
+		accessTTL:   config.DefaultAccessTokenTTL,
+		refreshTTL:  config.DefaultRefreshTokenTTL,
+		loginPolicy: config.DefaultLoginProtection(),
+		sessions:    NewPostgresSessionStore(db),
+	}
+}
+
+// SetSessionStore replaces how session tokens are resolved, for example with a
+// CachedSessionStore wrapping the default Postgres lookup.
+func (s *Store) SetSessionStore(sessions SessionStore) {
+	s.sessions = sessions
+}
+
+// DeleteExpiredSessions removes expired sessions and reports how many were
+// deleted. It is run periodically by the session sweeper.
+func (s *Store) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	return s.sessions.DeleteExpired(ctx)
+}
+
+// SetSessionLifetimes overrides the access and refresh token lifetimes used
+// for new and refreshed sessions. Non-positive values keep the current setting.
+func (s *Store) SetSessionLifetimes(access, refresh time.Duration) {
+	if access > 0 {
+		s.accessTTL = access
+	}
+	if refresh > 0 {
+		s.refreshTTL = refresh
+	}
 }
 
 // CreateUser registers a new user with optional starter content.
@@ -81,6 +120,20 @@ func (s *Store) CreateUser(username, password string, content []string) error {
 		}
 	}
 
+	if err := createFavoritesPlaylistTx(ctx, tx, userID, username); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	tx = nil
+
+	return nil
+}
+
+// createFavoritesPlaylistTx gives a new user their private favorites playlist.
+func createFavoritesPlaylistTx(ctx context.Context, tx *sql.Tx, userID int64, username string) error {
 	if _, err := tx.ExecContext(ctx, `
 		INSERT INTO playlists (title, description, owner, user_id, is_favorite, is_public)
 		VALUES ($1, $2, $3, $4, TRUE, FALSE)
@@ -88,60 +141,113 @@ func (s *Store) CreateUser(username, password string, content []string) error {
 		var pgErr *pq.Error
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			// Favorites playlist was created by a trigger; continue.
-		} else {
-			return fmt.Errorf("create favorites playlist: %w", err)
+			return nil
 		}
+		return fmt.Errorf("create favorites playlist: %w", err)
 	}
-
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("commit tx: %w", err)
-	}
-	tx = nil
-
 	return nil
 }
 
-// Authenticate validates credentials and returns a session token.
-func (s *Store) Authenticate(username, password string) (string, error) {
+// Authenticate validates credentials and returns a session token. The supplied
+// metadata is recorded against the session so it can be listed later. Every
+// attempt is audited in login_attempts, and repeated failures for the username
+// or client address return a *LoginThrottleError before the password is checked.
+// When the account has two-factor authentication enabled a correct password
+// yields a *TwoFactorRequiredError instead of a session; the login is finished
+// with CompleteTwoFactorLogin.
+func (s *Store) Authenticate(username, password string, meta SessionMetadata) (SessionTokens, error) {
 	ctx := context.Background()
 
+	attempt, err := s.beginLoginAttempt(ctx, username, meta)
+	if err != nil {
+		return SessionTokens{}, err
+	}
+
 	var (
-		userID int64
-		hash   []byte
+		userID    int64
+		hash      []byte
+		twoFactor bool
 	)
 
-	err := s.db.QueryRowContext(ctx, `
-		SELECT id, password_hash
+	err = s.db.QueryRowContext(ctx, `
+		SELECT id, password_hash, totp_enabled_at IS NOT NULL
 		FROM users
 		WHERE username = $1
-	`, username).Scan(&userID, &hash)
+	`, username).Scan(&userID, &hash, &twoFactor)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
-			return "", ErrInvalidCredentials
+			return SessionTokens{}, s.loginFailed(ctx, attempt)
 		}
-		return "", fmt.Errorf("lookup user: %w", err)
+		return SessionTokens{}, fmt.Errorf("lookup user: %w", err)
 	}
 
 	if err := bcrypt.CompareHashAndPassword(hash, []byte(password)); err != nil {
-		return "", ErrInvalidCredentials
+		return SessionTokens{}, s.loginFailed(ctx, attempt)
+	}
+
+	if twoFactor {
+		challenge, err := s.startTwoFactorChallenge(ctx, userID)
+		if err != nil {
+			return SessionTokens{}, err
+		}
+		if err := s.finishLoginAttempt(ctx, attempt, loginOutcomeTwoFactorRequired); err != nil {
+			return SessionTokens{}, err
+		}
+		return SessionTokens{}, challenge
+	}
+
+	if err := s.finishLoginAttempt(ctx, attempt, loginOutcomeSuccess); err != nil {
+		return SessionTokens{}, err
 	}
 
+	return s.createSession(ctx, userID, meta)
+}
+
+// createSession starts a session for an authenticated user and issues its
+// first refresh token.
+func (s *Store) createSession(ctx context.Context, userID int64, meta SessionMetadata) (SessionTokens, error) {
 	token, err := newToken()
 	if err != nil {
-		return "", fmt.Errorf("create token: %w", err)
+		return SessionTokens{}, fmt.Errorf("create token: %w", err)
+	}
+
+	expiresAt := time.Now().Add(s.accessTTL)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var sessionID int64
+	if err := tx.QueryRowContext(ctx, `
+		INSERT INTO sessions (token, user_id, expires_at, user_agent, ip_address)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, token, userID, expiresAt, nullIfEmpty(meta.UserAgent), nullIfEmpty(meta.IPAddress)).Scan(&sessionID); err != nil {
+		return SessionTokens{}, fmt.Errorf("store session: %w", err)
 	}
 
-	expiresAt := time.Now().Add(30 * 24 * time.Hour)
+	refreshToken, err := s.issueRefreshToken(ctx, tx, sessionID)
+	if err != nil {
+		return SessionTokens{}, err
+	}
 
-	if _, err := s.db.ExecContext(ctx, `
-		INSERT INTO sessions (token, user_id, expires_at)
-		VALUES ($1, $2, $3)
-	`, token, userID, expiresAt); err != nil {
-		return "", fmt.Errorf("store session: %w", err)
+	if err := tx.Commit(); err != nil {
+		return SessionTokens{}, fmt.Errorf("commit session: %w", err)
 	}
+	tx = nil
 
-	return token, nil
+	return SessionTokens{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+	}, nil
 }
 
 // ContentByToken returns user-specific content for a valid token.
@@ -227,28 +333,36 @@ func (s *Store) UserIDByToken(ctx context.Context, token string) (int64, error)
 	return s.userIDForToken(ctx, token)
 }
 
+// userIDForToken resolves a session token or personal access token to its user.
 func (s *Store) userIDForToken(ctx context.Context, token string) (int64, error) {
-	var userID int64
-	err := s.db.QueryRowContext(ctx, `
-		SELECT user_id
-		FROM sessions
-		WHERE token = $1
-	`, token).Scan(&userID)
+	if auth.IsAPIToken(token) {
+		return s.userIDForAPIToken(ctx, token)
+	}
+	return s.userIDForSession(ctx, token)
+}
+
+// userIDForSession resolves a session token only. Session and credential
+// management use it so personal access tokens cannot reach them.
+func (s *Store) userIDForSession(ctx context.Context, token string) (int64, error) {
+	userID, err := s.sessions.Lookup(ctx, token)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return 0, ErrUnauthorized
-		}
-		return 0, fmt.Errorf("lookup session: %w", err)
+		return 0, err
 	}
+
+	s.sessions.Touch(ctx, token, s.accessTTL)
+
 	return userID, nil
 }
 
+// userIDForTokenTx resolves a session token inside a transaction. Personal
+// access tokens are not accepted.
 func (s *Store) userIDForTokenTx(ctx context.Context, tx *sql.Tx, token string) (int64, error) {
 	var userID int64
 	err := tx.QueryRowContext(ctx, `
 		SELECT user_id
 		FROM sessions
 		WHERE token = $1
+		  AND expires_at > NOW()
 	`, token).Scan(&userID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -0,0 +1,527 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"vinylhound/shared/go/auth"
+)
+
+const (
+	// twoFactorChallengeTTL bounds how long a user may take to enter a code
+	// after their password was accepted.
+	twoFactorChallengeTTL = 5 * time.Minute
+	// maxTwoFactorAttempts is how many wrong codes a challenge survives.
+	maxTwoFactorAttempts = 5
+)
+
+var (
+	// ErrTwoFactorRequired signals a correct password for an account with 2FA
+	// enabled. Use errors.As with *TwoFactorRequiredError to read the challenge.
+	ErrTwoFactorRequired = errors.New("two-factor authentication required")
+	// ErrInvalidTwoFactorChallenge indicates an unknown, used or expired login challenge.
+	ErrInvalidTwoFactorChallenge = errors.New("invalid or expired two-factor challenge")
+	// ErrInvalidTwoFactorCode indicates a wrong, reused or expired TOTP or recovery code.
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrTwoFactorEnabled signals an enrollment attempt while 2FA is already on.
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled signals a 2FA operation on an account without it.
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrTwoFactorNotEnrolling signals a confirmation without a pending enrollment.
+	ErrTwoFactorNotEnrolling = errors.New("no two-factor enrollment in progress")
+)
+
+// TwoFactorRequiredError carries the challenge a client redeems with a second
+// factor to finish logging in.
+type TwoFactorRequiredError struct {
+	ChallengeToken string
+	ExpiresAt      time.Time
+}
+
+func (e *TwoFactorRequiredError) Error() string {
+	return ErrTwoFactorRequired.Error()
+}
+
+func (e *TwoFactorRequiredError) Unwrap() error {
+	return ErrTwoFactorRequired
+}
+
+// TOTPEnrollment is a freshly generated secret awaiting confirmation.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// TwoFactorStatus describes the 2FA state of an account.
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// twoFactorUser is the 2FA state needed to check a code.
+type twoFactorUser struct {
+	id       int64
+	username string
+	secret   sql.NullString
+	enabled  bool
+	lastStep sql.NullInt64
+}
+
+// startTwoFactorChallenge records a pending second login step for the user.
+func (s *Store) startTwoFactorChallenge(ctx context.Context, userID int64) (*TwoFactorRequiredError, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, fmt.Errorf("create two-factor challenge: %w", err)
+	}
+	expiresAt := time.Now().Add(twoFactorChallengeTTL)
+
+	if _, err := s.db.ExecContext(ctx, `
+		INSERT INTO two_factor_challenges (token_hash, user_id, expires_at)
+		VALUES ($1, $2, $3)
+	`, hashToken(token), userID, expiresAt); err != nil {
+		return nil, fmt.Errorf("store two-factor challenge: %w", err)
+	}
+
+	return &TwoFactorRequiredError{ChallengeToken: token, ExpiresAt: expiresAt}, nil
+}
+
+// CompleteTwoFactorLogin finishes a login started by Authenticate with a TOTP
+// code or an unused recovery code, and returns the new session. Wrong codes
+// count towards the same backoff and lockout as wrong passwords, and a
+// challenge is discarded after maxTwoFactorAttempts of them.
+func (s *Store) CompleteTwoFactorLogin(ctx context.Context, challengeToken, code string, meta SessionMetadata) (SessionTokens, error) {
+	challengeHash := hashToken(challengeToken)
+
+	var userID int64
+	err := s.db.QueryRowContext(ctx, `
+		SELECT user_id
+		FROM two_factor_challenges
+		WHERE token_hash = $1 AND expires_at > NOW()
+	`, challengeHash).Scan(&userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return SessionTokens{}, ErrInvalidTwoFactorChallenge
+		}
+		return SessionTokens{}, fmt.Errorf("lookup two-factor challenge: %w", err)
+	}
+
+	user, err := s.loadTwoFactorUser(ctx, userID)
+	if err != nil {
+		return SessionTokens{}, err
+	}
+	if !user.enabled {
+		return SessionTokens{}, ErrInvalidTwoFactorChallenge
+	}
+
+	attempt, err := s.beginLoginAttempt(ctx, user.username, meta)
+	if err != nil {
+		return SessionTokens{}, err
+	}
+
+	ok, err := s.checkSecondFactor(ctx, user, code)
+	if err != nil {
+		return SessionTokens{}, err
+	}
+	if !ok {
+		if err := s.countTwoFactorAttempt(ctx, challengeHash); err != nil {
+			return SessionTokens{}, err
+		}
+		return SessionTokens{}, s.secondFactorFailed(ctx, attempt)
+	}
+
+	// Deleting the challenge claims it, so two concurrent requests cannot
+	// both turn it into a session.
+	res, err := s.db.ExecContext(ctx, `
+		DELETE FROM two_factor_challenges
+		WHERE token_hash = $1 AND expires_at > NOW()
+	`, challengeHash)
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("consume two-factor challenge: %w", err)
+	}
+	claimed, err := res.RowsAffected()
+	if err != nil {
+		return SessionTokens{}, fmt.Errorf("check consumed challenge: %w", err)
+	}
+	if claimed == 0 {
+		return SessionTokens{}, ErrInvalidTwoFactorChallenge
+	}
+
+	if err := s.finishLoginAttempt(ctx, attempt, loginOutcomeSuccess); err != nil {
+		return SessionTokens{}, err
+	}
+
+	return s.createSession(ctx, userID, meta)
+}
+
+// countTwoFactorAttempt records a wrong code against a challenge and drops the
+// challenge once it has used up its attempts.
+func (s *Store) countTwoFactorAttempt(ctx context.Context, challengeHash string) error {
+	if _, err := s.db.ExecContext(ctx, `
+		UPDATE two_factor_challenges
+		SET attempts = attempts + 1
+		WHERE token_hash = $1
+	`, challengeHash); err != nil {
+		return fmt.Errorf("count two-factor attempt: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+		DELETE FROM two_factor_challenges
+		WHERE token_hash = $1 AND attempts >= $2
+	`, challengeHash, maxTwoFactorAttempts); err != nil {
+		return fmt.Errorf("discard two-factor challenge: %w", err)
+	}
+	return nil
+}
+
+// secondFactorFailed audits a rejected code and returns ErrInvalidTwoFactorCode.
+func (s *Store) secondFactorFailed(ctx context.Context, attempt int64) error {
+	if err := s.finishLoginAttempt(ctx, attempt, loginOutcomeInvalidSecondFactor); err != nil {
+		return err
+	}
+	return ErrInvalidTwoFactorCode
+}
+
+// TwoFactorStatusByToken reports whether the session's user has 2FA enabled
+// and how many recovery codes are left.
+func (s *Store) TwoFactorStatusByToken(ctx context.Context, token string) (TwoFactorStatus, error) {
+	userID, err := s.userIDForSession(ctx, token)
+	if err != nil {
+		return TwoFactorStatus{}, err
+	}
+
+	var (
+		status    TwoFactorStatus
+		enabledAt sql.NullTime
+	)
+	err = s.db.QueryRowContext(ctx, `
+		SELECT u.totp_enabled_at,
+		       (SELECT COUNT(*) FROM user_recovery_codes r WHERE r.user_id = u.id AND r.used_at IS NULL)
+		FROM users u
+		WHERE u.id = $1
+	`, userID).Scan(&enabledAt, &status.RecoveryCodesRemaining)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return TwoFactorStatus{}, ErrUnauthorized
+		}
+		return TwoFactorStatus{}, fmt.Errorf("lookup two-factor status: %w", err)
+	}
+
+	if enabledAt.Valid {
+		status.Enabled = true
+		status.EnabledAt = &enabledAt.Time
+	}
+	return status, nil
+}
+
+// BeginTOTPEnrollment generates a new TOTP secret for the session's user. 2FA
+// stays off until ConfirmTOTPEnrollment proves the user's authenticator has
+// the secret; starting again replaces a pending secret.
+func (s *Store) BeginTOTPEnrollment(ctx context.Context, token string) (TOTPEnrollment, error) {
+	userID, err := s.userIDForSession(ctx, token)
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return TOTPEnrollment{}, err
+	}
+
+	var username string
+	err = s.db.QueryRowContext(ctx, `
+		UPDATE users
+		SET totp_secret = $2, totp_last_step = NULL
+		WHERE id = $1 AND totp_enabled_at IS NULL
+		RETURNING username
+	`, userID, secret).Scan(&username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return TOTPEnrollment{}, ErrTwoFactorEnabled
+		}
+		return TOTPEnrollment{}, fmt.Errorf("store totp secret: %w", err)
+	}
+
+	return TOTPEnrollment{Secret: secret, URI: auth.TOTPURI(secret, username)}, nil
+}
+
+// ConfirmTOTPEnrollment enables 2FA once the user enters a valid code for the
+// pending secret, and returns a fresh set of recovery codes. The codes are
+// only stored hashed, so this is the one time they can be shown.
+func (s *Store) ConfirmTOTPEnrollment(ctx context.Context, token, code string) ([]string, error) {
+	userID, err := s.userIDForSession(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.loadTwoFactorUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if !user.secret.Valid {
+		return nil, ErrTwoFactorNotEnrolling
+	}
+
+	step, ok := auth.ValidateTOTP(user.secret.String, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE users
+		SET totp_enabled_at = NOW(), totp_last_step = $3
+		WHERE id = $1 AND totp_secret = $2 AND totp_enabled_at IS NULL
+	`, userID, user.secret.String, step)
+	if err != nil {
+		return nil, fmt.Errorf("enable two-factor: %w", err)
+	}
+	enabled, err := res.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("check enabled two-factor: %w", err)
+	}
+	if enabled == 0 {
+		// Enrollment was restarted or confirmed concurrently.
+		return nil, ErrTwoFactorNotEnrolling
+	}
+
+	codes, err := replaceRecoveryCodesTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	tx = nil
+
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code of the session's user
+// after checking a current TOTP or recovery code.
+func (s *Store) RegenerateRecoveryCodes(ctx context.Context, token, code string) ([]string, error) {
+	userID, err := s.userIDForSession(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyEnabledSecondFactor(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	codes, err := replaceRecoveryCodesTx(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit tx: %w", err)
+	}
+	tx = nil
+
+	return codes, nil
+}
+
+// DisableTwoFactor turns 2FA off for the session's user after checking a
+// current TOTP or recovery code, discarding the secret, recovery codes and
+// pending login challenges.
+func (s *Store) DisableTwoFactor(ctx context.Context, token, code string) error {
+	userID, err := s.userIDForSession(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	if err := s.verifyEnabledSecondFactor(ctx, userID, code); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if tx != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err := clearTwoFactorTx(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit tx: %w", err)
+	}
+	tx = nil
+
+	return nil
+}
+
+// verifyEnabledSecondFactor checks a code for a signed-in user managing 2FA.
+// Failures are throttled and audited like login attempts so a stolen session
+// cannot be used to guess codes.
+func (s *Store) verifyEnabledSecondFactor(ctx context.Context, userID int64, code string) error {
+	user, err := s.loadTwoFactorUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.enabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	attempt, err := s.beginLoginAttempt(ctx, user.username, SessionMetadata{})
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.checkSecondFactor(ctx, user, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return s.secondFactorFailed(ctx, attempt)
+	}
+	return s.discardLoginAttempt(ctx, attempt)
+}
+
+func (s *Store) loadTwoFactorUser(ctx context.Context, userID int64) (twoFactorUser, error) {
+	user := twoFactorUser{id: userID}
+	err := s.db.QueryRowContext(ctx, `
+		SELECT username, totp_secret, totp_enabled_at IS NOT NULL, totp_last_step
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(&user.username, &user.secret, &user.enabled, &user.lastStep)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return twoFactorUser{}, ErrUnauthorized
+		}
+		return twoFactorUser{}, fmt.Errorf("lookup two-factor settings: %w", err)
+	}
+	return user, nil
+}
+
+// checkSecondFactor accepts a TOTP code not used before or an unused recovery
+// code, consuming whichever matched.
+func (s *Store) checkSecondFactor(ctx context.Context, user twoFactorUser, code string) (bool, error) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if code == "" {
+		return false, nil
+	}
+
+	if isTOTPCode(code) {
+		step, ok := auth.ValidateTOTP(user.secret.String, code, time.Now())
+		if !ok || (user.lastStep.Valid && step <= user.lastStep.Int64) {
+			return false, nil
+		}
+		// Only move forward, so the same code cannot be accepted twice even
+		// by concurrent requests.
+		res, err := s.db.ExecContext(ctx, `
+			UPDATE users
+			SET totp_last_step = $2
+			WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+		`, user.id, step)
+		if err != nil {
+			return false, fmt.Errorf("record totp step: %w", err)
+		}
+		accepted, err := res.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("check totp step: %w", err)
+		}
+		return accepted == 1, nil
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE user_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, user.id, hashToken(auth.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return false, fmt.Errorf("use recovery code: %w", err)
+	}
+	used, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("check recovery code: %w", err)
+	}
+	return used == 1, nil
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != auth.TOTPDigits {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// replaceRecoveryCodesTx discards the user's recovery codes and stores a new
+// set, returning the plaintext codes.
+func replaceRecoveryCodesTx(ctx context.Context, tx *sql.Tx, userID int64) ([]string, error) {
+	codes, err := auth.NewRecoveryCodes(auth.RecoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM user_recovery_codes
+		WHERE user_id = $1
+	`, userID); err != nil {
+		return nil, fmt.Errorf("discard recovery codes: %w", err)
+	}
+
+	for _, code := range codes {
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO user_recovery_codes (user_id, code_hash)
+			VALUES ($1, $2)
+		`, userID, hashToken(auth.NormalizeRecoveryCode(code))); err != nil {
+			return nil, fmt.Errorf("store recovery code: %w", err)
+		}
+	}
+	return codes, nil
+}
+
+// clearTwoFactorTx removes every trace of 2FA from an account.
+func clearTwoFactorTx(ctx context.Context, tx *sql.Tx, userID int64) error {
+	for _, stmt := range []struct {
+		what  string
+		query string
+	}{
+		{"recovery codes", `DELETE FROM user_recovery_codes WHERE user_id = $1`},
+		{"two-factor challenges", `DELETE FROM two_factor_challenges WHERE user_id = $1`},
+		{"totp secret", `UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL WHERE id = $1`},
+	} {
+		if _, err := tx.ExecContext(ctx, stmt.query, userID); err != nil {
+			return fmt.Errorf("clear %s: %w", stmt.what, err)
+		}
+	}
+	return nil
+}
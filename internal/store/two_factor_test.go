@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"golang.org/x/crypto/bcrypt"
+
+	"vinylhound/shared/go/auth"
+)
+
+const testTOTPSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func expectChallengeLookup(mock sqlmock.Sqlmock, challenge string, userID int64) {
+	mock.ExpectQuery(regexp.QuoteMeta(`FROM two_factor_challenges`)).
+		WithArgs(hashToken(challenge)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(userID))
+}
+
+func expectTwoFactorUser(mock sqlmock.Sqlmock, userID int64, lastStep any) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT username, totp_secret, totp_enabled_at IS NOT NULL, totp_last_step`)).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"username", "totp_secret", "enabled", "totp_last_step"}).
+			AddRow("demo", testTOTPSecret, true, lastStep))
+}
+
+func TestAuthenticateWithTwoFactorReturnsChallenge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	meta := SessionMetadata{UserAgent: "curl", IPAddress: "203.0.113.9"}
+	hash, err := bcrypt.GenerateFromPassword([]byte("demo123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("hash password: %v", err)
+	}
+
+	expectLoginAttempt(mock, 0, time.Time{}, 0, time.Time{}, loginOutcomePending)
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, password_hash, totp_enabled_at IS NOT NULL`)).
+		WithArgs("demo").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "password_hash", "two_factor"}).AddRow(int64(7), hash, true))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO two_factor_challenges`)).
+		WithArgs(sqlmock.AnyArg(), int64(7), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLoginOutcome(mock, loginOutcomeTwoFactorRequired)
+
+	tokens, err := s.Authenticate("demo", "demo123", meta)
+	var challenge *TwoFactorRequiredError
+	if !errors.As(err, &challenge) || !errors.Is(err, ErrTwoFactorRequired) {
+		t.Fatalf("expected *TwoFactorRequiredError, got %v", err)
+	}
+	if challenge.ChallengeToken == "" || !challenge.ExpiresAt.After(time.Now()) {
+		t.Fatalf("unexpected challenge %+v", challenge)
+	}
+	if tokens.AccessToken != "" {
+		t.Fatalf("expected no session before the second factor")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCompleteTwoFactorLoginWithRecoveryCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	meta := SessionMetadata{UserAgent: "curl", IPAddress: "203.0.113.9"}
+
+	expectChallengeLookup(mock, "challenge", 7)
+	expectTwoFactorUser(mock, 7, nil)
+	expectLoginAttempt(mock, 0, time.Time{}, 0, time.Time{}, loginOutcomePending)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE user_recovery_codes`)).
+		WithArgs(int64(7), hashToken("abcdefghij")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM two_factor_challenges`)).
+		WithArgs(hashToken("challenge")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLoginOutcome(mock, loginOutcomeSuccess)
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO sessions`)).
+		WithArgs(sqlmock.AnyArg(), int64(7), sqlmock.AnyArg(), "curl", "203.0.113.9").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(3)))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO refresh_tokens`)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	tokens, err := s.CompleteTwoFactorLogin(context.Background(), "challenge", "ABCDE-FGHIJ", meta)
+	if err != nil {
+		t.Fatalf("CompleteTwoFactorLogin: %v", err)
+	}
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
+		t.Fatalf("expected session tokens, got %+v", tokens)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestCompleteTwoFactorLoginRejectsReplayedCode(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+	meta := SessionMetadata{UserAgent: "curl", IPAddress: "203.0.113.9"}
+
+	now := auth.TOTPStep(time.Now())
+	code, err := auth.TOTPCode(testTOTPSecret, now)
+	if err != nil {
+		t.Fatalf("TOTPCode: %v", err)
+	}
+
+	// The current step was already used by an earlier login.
+	expectChallengeLookup(mock, "challenge", 7)
+	expectTwoFactorUser(mock, 7, now+auth.TOTPSkew)
+	expectLoginAttempt(mock, 0, time.Time{}, 0, time.Time{}, loginOutcomePending)
+	mock.ExpectExec(regexp.QuoteMeta(`SET attempts = attempts + 1`)).
+		WithArgs(hashToken("challenge")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`WHERE token_hash = $1 AND attempts >= $2`)).
+		WithArgs(hashToken("challenge"), maxTwoFactorAttempts).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectLoginOutcome(mock, loginOutcomeInvalidSecondFactor)
+
+	if _, err := s.CompleteTwoFactorLogin(context.Background(), "challenge", code, meta); !errors.Is(err, ErrInvalidTwoFactorCode) {
+		t.Fatalf("expected ErrInvalidTwoFactorCode, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestBeginTOTPEnrollmentWhenEnabled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	defer db.Close()
+
+	s := New(db)
+
+	expectSessionLookup(mock, "session", 7)
+	mock.ExpectQuery(regexp.QuoteMeta(`SET totp_secret = $2, totp_last_step = NULL`)).
+		WithArgs(int64(7), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"username"}))
+
+	if _, err := s.BeginTOTPEnrollment(context.Background(), "session"); !errors.Is(err, ErrTwoFactorEnabled) {
+		t.Fatalf("expected ErrTwoFactorEnabled, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -39,24 +39,37 @@ func (s *Store) CreateVenue(ctx context.Context, token string, venue *models.Ven
 	return venue, nil
 }
 
-// ListVenuesByUser returns all venues for a user
-func (s *Store) ListVenuesByUser(ctx context.Context, token string) ([]*models.Venue, error) {
+// venueKeyset lists venues by name.
+var venueKeyset = keyset{name: "venues", columns: []keyColumn{
+	{expr: "name", kind: keyString},
+	{expr: "id", kind: keyInt},
+}}
+
+// ListVenuesByUser returns one page of venues for a user, by name
+func (s *Store) ListVenuesByUser(ctx context.Context, token string, page Page) ([]*models.Venue, string, error) {
+	pageQ, err := venueKeyset.page(page)
+	if err != nil {
+		return nil, "", err
+	}
+
 	userID, err := s.UserIDByToken(ctx, token)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
+	args := []any{userID}
+	after, args := pageQ.where(args)
+	tail, args := pageQ.orderLimit(args)
 	query := `
 		SELECT id, user_id, name, address, city, state, capacity, description,
 		       created_at, updated_at
 		FROM venues
-		WHERE user_id = $1
-		ORDER BY name ASC
-	`
+		WHERE user_id = $1 AND ` + after + `
+		` + tail
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -66,12 +79,18 @@ func (s *Store) ListVenuesByUser(ctx context.Context, token string) ([]*models.V
 		err := rows.Scan(&v.ID, &v.UserID, &v.Name, &v.Address, &v.City,
 			&v.State, &v.Capacity, &v.Description, &v.CreatedAt, &v.UpdatedAt)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		venues = append(venues, &v)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
 
-	return venues, rows.Err()
+	venues, next := finishPage(pageQ, venues, func(v *models.Venue) []any {
+		return []any{v.Name, v.ID}
+	})
+	return venues, next, nil
 }
 
 // GetVenue retrieves a single venue by ID
@@ -182,24 +201,37 @@ func (s *Store) CreateRetailer(ctx context.Context, token string, retailer *mode
 	return retailer, nil
 }
 
-// ListRetailersByUser returns all retailers for a user
-func (s *Store) ListRetailersByUser(ctx context.Context, token string) ([]*models.Retailer, error) {
+// retailerKeyset lists retailers by name.
+var retailerKeyset = keyset{name: "retailers", columns: []keyColumn{
+	{expr: "name", kind: keyString},
+	{expr: "id", kind: keyInt},
+}}
+
+// ListRetailersByUser returns one page of retailers for a user, by name
+func (s *Store) ListRetailersByUser(ctx context.Context, token string, page Page) ([]*models.Retailer, string, error) {
+	pageQ, err := retailerKeyset.page(page)
+	if err != nil {
+		return nil, "", err
+	}
+
 	userID, err := s.UserIDByToken(ctx, token)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
+	args := []any{userID}
+	after, args := pageQ.where(args)
+	tail, args := pageQ.orderLimit(args)
 	query := `
 		SELECT id, user_id, name, address, city, state, specialty, website, description,
 		       created_at, updated_at
 		FROM retailers
-		WHERE user_id = $1
-		ORDER BY name ASC
-	`
+		WHERE user_id = $1 AND ` + after + `
+		` + tail
 
-	rows, err := s.db.QueryContext(ctx, query, userID)
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 	defer rows.Close()
 
@@ -209,12 +241,18 @@ func (s *Store) ListRetailersByUser(ctx context.Context, token string) ([]*model
 		err := rows.Scan(&r.ID, &r.UserID, &r.Name, &r.Address, &r.City,
 			&r.State, &r.Specialty, &r.Website, &r.Description, &r.CreatedAt, &r.UpdatedAt)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 		retailers = append(retailers, &r)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
 
-	return retailers, rows.Err()
+	retailers, next := finishPage(pageQ, retailers, func(r *models.Retailer) []any {
+		return []any{r.Name, r.ID}
+	})
+	return retailers, next, nil
 }
 
 // GetRetailer retrieves a single retailer by ID
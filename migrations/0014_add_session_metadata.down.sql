@@ -0,0 +1,5 @@
+-- Remove session metadata columns
+DROP INDEX IF EXISTS idx_sessions_user_expires;
+ALTER TABLE sessions DROP COLUMN IF EXISTS ip_address;
+ALTER TABLE sessions DROP COLUMN IF EXISTS user_agent;
+ALTER TABLE sessions DROP COLUMN IF EXISTS last_seen_at;
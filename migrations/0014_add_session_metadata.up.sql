@@ -0,0 +1,11 @@
+-- Track client details and activity for each session so users can review and revoke logins
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_agent TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS ip_address TEXT;
+
+-- Index for listing a user's active sessions
+CREATE INDEX IF NOT EXISTS idx_sessions_user_expires ON sessions(user_id, expires_at);
+
+COMMENT ON COLUMN sessions.last_seen_at IS 'Last time the session token was used (updated at most once a minute)';
+COMMENT ON COLUMN sessions.user_agent IS 'User-Agent header supplied at login';
+COMMENT ON COLUMN sessions.ip_address IS 'Client IP address supplied at login';
@@ -0,0 +1,4 @@
+-- Remove rotating refresh tokens
+DROP INDEX IF EXISTS idx_refresh_tokens_expires_at;
+DROP INDEX IF EXISTS idx_refresh_tokens_session_id;
+DROP TABLE IF EXISTS refresh_tokens;
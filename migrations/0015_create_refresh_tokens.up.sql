@@ -0,0 +1,18 @@
+-- Rotating refresh tokens. Every token issued for a session belongs to the same
+-- family; presenting a token that has already been rotated deletes the session,
+-- which cascades to the rest of the family.
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    session_id BIGINT NOT NULL REFERENCES sessions(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_expires_at ON refresh_tokens(expires_at);
+
+COMMENT ON TABLE refresh_tokens IS 'Single-use refresh tokens; the owning session is the token family';
+COMMENT ON COLUMN refresh_tokens.token_hash IS 'Hex-encoded SHA-256 of the refresh token';
+COMMENT ON COLUMN refresh_tokens.used_at IS 'Set when the token is rotated; reuse after this point revokes the session';
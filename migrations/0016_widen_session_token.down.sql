@@ -0,0 +1,4 @@
+-- Restore the original token column size
+ALTER TABLE sessions ALTER COLUMN token TYPE VARCHAR(512);
+
+COMMENT ON COLUMN sessions.token IS 'Session token (base64 encoded random bytes)';
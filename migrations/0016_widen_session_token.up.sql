@@ -0,0 +1,4 @@
+-- Signed JWT access tokens (RS256 in particular) can exceed 512 characters
+ALTER TABLE sessions ALTER COLUMN token TYPE TEXT;
+
+COMMENT ON COLUMN sessions.token IS 'Session token (opaque random string or signed JWT)';
@@ -0,0 +1,4 @@
+-- Remove password reset tokens and account anonymization marker
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+DROP INDEX IF EXISTS idx_password_reset_tokens_user_id;
+DROP TABLE IF EXISTS password_reset_tokens;
@@ -0,0 +1,17 @@
+-- Single-use password reset tokens; only the SHA-256 hash of each token is stored
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+
+-- Accounts anonymized on deletion keep their row so shared content stays attributed
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+COMMENT ON TABLE password_reset_tokens IS 'Password reset requests issued through the notifier';
+COMMENT ON COLUMN users.deleted_at IS 'Set when the account was deleted with anonymization';
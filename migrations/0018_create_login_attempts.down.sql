@@ -0,0 +1,4 @@
+-- Remove login attempt audit trail
+DROP INDEX IF EXISTS idx_login_attempts_ip_created;
+DROP INDEX IF EXISTS idx_login_attempts_username_created;
+DROP TABLE IF EXISTS login_attempts;
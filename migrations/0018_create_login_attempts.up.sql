@@ -0,0 +1,18 @@
+-- Audit trail of login attempts, also used to throttle password guessing
+CREATE TABLE IF NOT EXISTS login_attempts (
+    id BIGSERIAL PRIMARY KEY,
+    username VARCHAR(255) NOT NULL,
+    ip_address TEXT,
+    user_agent TEXT,
+    outcome VARCHAR(32) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_username_created ON login_attempts(username, created_at);
+CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_created ON login_attempts(ip_address, created_at);
+
+COMMENT ON TABLE login_attempts IS 'Every login attempt with its outcome; failures drive backoff and lockout';
+COMMENT ON COLUMN login_attempts.username IS 'Username as submitted, whether or not the account exists';
+-- Attempts are recorded as pending when they pass the throttle check and
+-- settled once the credentials are checked; pending ones count as failures.
+COMMENT ON COLUMN login_attempts.outcome IS 'pending, success, invalid_credentials, throttled or locked';
@@ -0,0 +1,8 @@
+-- Catalog roles: users manage their own content, curators edit the shared
+-- catalog and admins may also delete from it and assign roles
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user';
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE users ADD CONSTRAINT users_role_check CHECK (role IN ('user', 'curator', 'admin'));
+
+COMMENT ON COLUMN users.role IS 'user, curator or admin; promote the first admin with UPDATE users SET role = ''admin''';
@@ -0,0 +1,3 @@
+-- Remove personal access tokens
+DROP INDEX IF EXISTS idx_api_tokens_user;
+DROP TABLE IF EXISTS api_tokens;
@@ -0,0 +1,19 @@
+-- Long-lived personal access tokens for scripts and integrations
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    token_prefix VARCHAR(16) NOT NULL,
+    scopes TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_id);
+
+COMMENT ON TABLE api_tokens IS 'Personal access tokens; only a SHA-256 hash of each token is stored';
+COMMENT ON COLUMN api_tokens.token_prefix IS 'First characters of the token, shown so users can tell tokens apart';
+COMMENT ON COLUMN api_tokens.scopes IS 'Space-separated scopes such as catalog:read collections:write';
+COMMENT ON COLUMN api_tokens.expires_at IS 'NULL for tokens that never expire';
@@ -0,0 +1,5 @@
+-- Remove external identities and pending OpenID Connect logins
+DROP INDEX IF EXISTS idx_oidc_login_states_expires;
+DROP TABLE IF EXISTS oidc_login_states;
+DROP INDEX IF EXISTS idx_user_identities_user;
+DROP TABLE IF EXISTS user_identities;
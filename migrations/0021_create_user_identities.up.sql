@@ -0,0 +1,34 @@
+-- External OpenID Connect identities linked to local accounts
+CREATE TABLE IF NOT EXISTS user_identities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    issuer TEXT NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(320),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_login_at TIMESTAMPTZ,
+    UNIQUE (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
+
+-- Logins waiting for the provider callback; only a SHA-256 hash of the state is stored
+CREATE TABLE IF NOT EXISTS oidc_login_states (
+    state_hash CHAR(64) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    nonce VARCHAR(128) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    binding_hash CHAR(64) NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_login_states_expires ON oidc_login_states(expires_at);
+
+COMMENT ON TABLE user_identities IS 'Identities asserted by OpenID Connect providers, keyed by issuer and subject';
+COMMENT ON COLUMN user_identities.provider IS 'Configured provider name the identity was linked through';
+COMMENT ON TABLE oidc_login_states IS 'Pending authorization code logins with their nonce and PKCE verifier';
+COMMENT ON COLUMN oidc_login_states.user_id IS 'Set when an authenticated user is linking an identity, NULL for sign-in';
+COMMENT ON COLUMN oidc_login_states.binding_hash IS 'Hash of the secret kept in a cookie by the browser that started the login';
@@ -0,0 +1,11 @@
+-- Remove two-factor authentication
+DROP INDEX IF EXISTS idx_two_factor_challenges_expires;
+DROP TABLE IF EXISTS two_factor_challenges;
+DROP TABLE IF EXISTS user_recovery_codes;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS totp_last_step,
+    DROP COLUMN IF EXISTS totp_enabled_at,
+    DROP COLUMN IF EXISTS totp_secret;
+
+COMMENT ON COLUMN login_attempts.outcome IS 'pending, success, invalid_credentials, throttled or locked';
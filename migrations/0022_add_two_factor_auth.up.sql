@@ -0,0 +1,36 @@
+-- Optional TOTP two-factor authentication with one-time recovery codes
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS totp_secret TEXT,
+    ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+
+COMMENT ON COLUMN users.totp_secret IS 'Base32 TOTP secret; set during enrollment and kept while 2FA is enabled';
+COMMENT ON COLUMN users.totp_enabled_at IS 'When enrollment was confirmed; NULL while 2FA is off or pending';
+COMMENT ON COLUMN users.totp_last_step IS 'Last accepted TOTP time step, so a code cannot be replayed';
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
+
+COMMENT ON TABLE user_recovery_codes IS 'One-time 2FA recovery codes; only a SHA-256 hash of each code is stored';
+
+-- Pending second steps of a password login
+CREATE TABLE IF NOT EXISTS two_factor_challenges (
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_two_factor_challenges_expires ON two_factor_challenges(expires_at);
+
+COMMENT ON TABLE two_factor_challenges IS 'Issued after a correct password when 2FA is enabled; redeemed with a TOTP or recovery code';
+COMMENT ON COLUMN two_factor_challenges.attempts IS 'Wrong codes entered; the challenge is discarded past the limit';
+
+COMMENT ON COLUMN login_attempts.outcome IS 'pending, success, invalid_credentials, two_factor_required, invalid_second_factor, throttled or locked';
@@ -0,0 +1,10 @@
+-- Remove normalized artist credits; the free-text artist columns are untouched
+DROP INDEX IF EXISTS idx_artists_name_lower;
+DROP INDEX IF EXISTS idx_songs_artist_id;
+DROP INDEX IF EXISTS idx_albums_artist_id;
+DROP INDEX IF EXISTS idx_song_artists_artist;
+DROP INDEX IF EXISTS idx_album_artists_artist;
+DROP TABLE IF EXISTS song_artists;
+DROP TABLE IF EXISTS album_artists;
+ALTER TABLE songs DROP COLUMN IF EXISTS artist_id;
+ALTER TABLE albums DROP COLUMN IF EXISTS artist_id;
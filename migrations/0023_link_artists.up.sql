@@ -0,0 +1,94 @@
+-- Normalized artist credits for albums and songs. The free-text artist
+-- columns stay as the display credit; artist_id points at the primary artist
+-- and the join tables hold every credited artist, including featured guests.
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS artist_id BIGINT REFERENCES artists(id) ON DELETE SET NULL;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS artist_id BIGINT REFERENCES artists(id) ON DELETE SET NULL;
+
+CREATE TABLE IF NOT EXISTS album_artists (
+    album_id BIGINT NOT NULL REFERENCES albums(id) ON DELETE CASCADE,
+    artist_id BIGINT NOT NULL REFERENCES artists(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL DEFAULT 'primary' CHECK (role IN ('primary', 'featured')),
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (album_id, artist_id)
+);
+
+CREATE TABLE IF NOT EXISTS song_artists (
+    song_id BIGINT NOT NULL REFERENCES songs(id) ON DELETE CASCADE,
+    artist_id BIGINT NOT NULL REFERENCES artists(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL DEFAULT 'primary' CHECK (role IN ('primary', 'featured')),
+    position INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (song_id, artist_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_album_artists_artist ON album_artists(artist_id);
+CREATE INDEX IF NOT EXISTS idx_song_artists_artist ON song_artists(artist_id);
+CREATE INDEX IF NOT EXISTS idx_albums_artist_id ON albums(artist_id);
+CREATE INDEX IF NOT EXISTS idx_songs_artist_id ON songs(artist_id);
+CREATE INDEX IF NOT EXISTS idx_artists_name_lower ON artists(lower(name));
+
+-- Splits "Primary feat. Guest, Other & Another" the same way as
+-- store.ParseArtistCredit; only needed for the backfill below.
+CREATE OR REPLACE FUNCTION split_artist_credit(credit TEXT)
+RETURNS TABLE (artist_name TEXT, credit_role TEXT, credit_position INTEGER)
+LANGUAGE sql IMMUTABLE AS $$
+    SELECT btrim(regexp_replace(btrim(credit), '(\s+|\s*[\(\[])(feat\.?|ft\.?|featuring)\s+.*$', '', 'i')),
+           'primary', 0
+    UNION ALL
+    SELECT btrim(guest.name), 'featured', guest.ord::INTEGER
+    FROM regexp_split_to_table(
+             regexp_replace(
+                 regexp_replace(btrim(credit), '^.*?(\s+|\s*[\(\[])(feat\.?|ft\.?|featuring)\s+', '', 'i'),
+                 '[\)\]]\s*$', ''),
+             '\s*(,|&)\s*') WITH ORDINALITY AS guest(name, ord)
+    WHERE btrim(credit) ~* '(\s+|\s*[\(\[])(feat\.?|ft\.?|featuring)\s+'
+$$;
+
+-- Create an artist for every credited name not already known; names match
+-- case-insensitively so existing provider-imported artists are reused
+INSERT INTO artists (name, created_at, updated_at)
+SELECT DISTINCT ON (lower(c.artist_name)) c.artist_name, NOW(), NOW()
+FROM (SELECT artist FROM albums UNION SELECT artist FROM songs) credits
+CROSS JOIN LATERAL split_artist_credit(credits.artist) c
+WHERE c.artist_name <> ''
+  AND NOT EXISTS (SELECT 1 FROM artists a WHERE lower(a.name) = lower(c.artist_name))
+ORDER BY lower(c.artist_name), c.artist_name
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO album_artists (album_id, artist_id, role, position)
+SELECT al.id, ar.id, c.credit_role, c.credit_position
+FROM albums al
+CROSS JOIN LATERAL split_artist_credit(al.artist) c
+CROSS JOIN LATERAL (
+    SELECT id FROM artists WHERE lower(name) = lower(c.artist_name) ORDER BY id LIMIT 1
+) ar
+WHERE c.artist_name <> ''
+ON CONFLICT (album_id, artist_id) DO NOTHING;
+
+INSERT INTO song_artists (song_id, artist_id, role, position)
+SELECT s.id, ar.id, c.credit_role, c.credit_position
+FROM songs s
+CROSS JOIN LATERAL split_artist_credit(s.artist) c
+CROSS JOIN LATERAL (
+    SELECT id FROM artists WHERE lower(name) = lower(c.artist_name) ORDER BY id LIMIT 1
+) ar
+WHERE c.artist_name <> ''
+ON CONFLICT (song_id, artist_id) DO NOTHING;
+
+UPDATE albums al
+SET artist_id = aa.artist_id
+FROM album_artists aa
+WHERE aa.album_id = al.id AND aa.role = 'primary' AND al.artist_id IS NULL;
+
+UPDATE songs s
+SET artist_id = sa.artist_id
+FROM song_artists sa
+WHERE sa.song_id = s.id AND sa.role = 'primary' AND s.artist_id IS NULL;
+
+DROP FUNCTION IF EXISTS split_artist_credit(TEXT);
+
+COMMENT ON TABLE album_artists IS 'Artists credited on an album, in credit order';
+COMMENT ON TABLE song_artists IS 'Artists credited on a song, in credit order';
+COMMENT ON COLUMN album_artists.role IS 'primary or featured';
+COMMENT ON COLUMN song_artists.role IS 'primary or featured';
+COMMENT ON COLUMN albums.artist_id IS 'First primary artist; albums.artist remains the display credit';
+COMMENT ON COLUMN songs.artist_id IS 'First primary artist; songs.artist remains the display credit';
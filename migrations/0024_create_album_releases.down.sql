@@ -0,0 +1,15 @@
+-- Remove album releases; collection items fall back to one per album and type
+DROP INDEX IF EXISTS unique_user_album_release_collection;
+DELETE FROM album_collections ac
+USING album_collections keep
+WHERE ac.user_id = keep.user_id
+  AND ac.album_id = keep.album_id
+  AND ac.collection_type = keep.collection_type
+  AND ac.id > keep.id;
+ALTER TABLE album_collections ADD CONSTRAINT unique_user_album_collection UNIQUE (user_id, album_id, collection_type);
+ALTER TABLE user_album_preferences DROP COLUMN IF EXISTS release_id;
+ALTER TABLE album_collections DROP COLUMN IF EXISTS release_id;
+DROP INDEX IF EXISTS idx_album_releases_catalog_number;
+DROP INDEX IF EXISTS idx_album_releases_barcode;
+DROP INDEX IF EXISTS idx_album_releases_album;
+DROP TABLE IF EXISTS album_releases;
@@ -0,0 +1,37 @@
+-- Releases (editions) of an album: a specific pressing, format or variant
+CREATE TABLE IF NOT EXISTS album_releases (
+    id BIGSERIAL PRIMARY KEY,
+    album_id BIGINT NOT NULL REFERENCES albums(id) ON DELETE CASCADE,
+    format VARCHAR(20) NOT NULL CHECK (format IN ('vinyl', 'cd', 'cassette', 'digital', 'other')),
+    label TEXT,
+    catalog_number VARCHAR(100),
+    country VARCHAR(100),
+    pressing_year INTEGER CHECK (pressing_year IS NULL OR pressing_year > 0),
+    barcode VARCHAR(14),
+    color_variant VARCHAR(100),
+    description TEXT,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_album_releases_album ON album_releases(album_id);
+CREATE INDEX IF NOT EXISTS idx_album_releases_barcode ON album_releases(barcode) WHERE barcode IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_album_releases_catalog_number ON album_releases(lower(catalog_number)) WHERE catalog_number IS NOT NULL;
+
+-- Collection items and ratings may name the release they are about
+ALTER TABLE album_collections ADD COLUMN IF NOT EXISTS release_id BIGINT REFERENCES album_releases(id) ON DELETE SET NULL;
+ALTER TABLE user_album_preferences ADD COLUMN IF NOT EXISTS release_id BIGINT REFERENCES album_releases(id) ON DELETE SET NULL;
+
+-- A user can own several pressings of the same album, but each release (or
+-- the album without a release) only once per collection type
+ALTER TABLE album_collections DROP CONSTRAINT IF EXISTS unique_user_album_collection;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_user_album_release_collection
+    ON album_collections(user_id, album_id, collection_type, COALESCE(release_id, 0));
+
+COMMENT ON TABLE album_releases IS 'Editions of an album: pressing, format, label and variant';
+COMMENT ON COLUMN album_releases.pressing_year IS 'Year of this pressing; albums.release_year is the original release';
+COMMENT ON COLUMN album_releases.barcode IS 'UPC or EAN digits without separators';
+COMMENT ON COLUMN album_releases.description IS 'Free-form details such as weight or packaging, e.g. 180g gatefold';
+COMMENT ON COLUMN album_collections.release_id IS 'Specific release owned or wished for; NULL for the album in general';
+COMMENT ON COLUMN user_album_preferences.release_id IS 'Release the rating was given for; NULL for the album in general';
@@ -0,0 +1,20 @@
+-- Restores the JSONB title list from songs. Songs created from it by the up
+-- migration are kept.
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS tracks JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+UPDATE albums a
+SET tracks = t.titles
+FROM (
+    SELECT album_id, jsonb_agg(title ORDER BY disc_number, side NULLS FIRST, track_num NULLS LAST, id) AS titles
+    FROM songs
+    WHERE album_id IS NOT NULL
+    GROUP BY album_id
+) t
+WHERE t.album_id = a.id;
+
+DROP INDEX IF EXISTS idx_songs_album_tracklist;
+
+ALTER TABLE songs DROP CONSTRAINT IF EXISTS songs_side_check;
+ALTER TABLE songs DROP CONSTRAINT IF EXISTS songs_disc_number_check;
+ALTER TABLE songs DROP COLUMN IF EXISTS side;
+ALTER TABLE songs DROP COLUMN IF EXISTS disc_number;
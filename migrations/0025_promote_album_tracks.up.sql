@@ -0,0 +1,49 @@
+-- Songs become the authoritative tracklist of an album. Each song knows its
+-- disc and, for vinyl and cassette, its side; track_num is the position on
+-- that disc or side. The JSONB title list on albums is migrated and dropped.
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS disc_number INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS side VARCHAR(1);
+
+ALTER TABLE songs DROP CONSTRAINT IF EXISTS songs_disc_number_check;
+ALTER TABLE songs ADD CONSTRAINT songs_disc_number_check CHECK (disc_number > 0);
+ALTER TABLE songs DROP CONSTRAINT IF EXISTS songs_side_check;
+ALTER TABLE songs ADD CONSTRAINT songs_side_check CHECK (side ~ '^[A-Z]$');
+
+-- Existing songs that appear in the JSONB list take their position from it.
+UPDATE songs s
+SET track_num = t.ord
+FROM albums a
+CROSS JOIN LATERAL jsonb_array_elements_text(
+    CASE WHEN jsonb_typeof(a.tracks) = 'array' THEN a.tracks ELSE '[]'::jsonb END
+) WITH ORDINALITY AS t(title, ord)
+WHERE s.album_id = a.id
+  AND s.track_num IS NULL
+  AND lower(s.title) = lower(btrim(t.title));
+
+-- Titles without a song become songs credited like their album.
+INSERT INTO songs (title, artist, album_id, artist_id, disc_number, track_num)
+SELECT btrim(t.title), a.artist, a.id, a.artist_id, 1, t.ord
+FROM albums a
+CROSS JOIN LATERAL jsonb_array_elements_text(
+    CASE WHEN jsonb_typeof(a.tracks) = 'array' THEN a.tracks ELSE '[]'::jsonb END
+) WITH ORDINALITY AS t(title, ord)
+WHERE btrim(t.title) <> ''
+  AND NOT EXISTS (
+    SELECT 1 FROM songs s
+    WHERE s.album_id = a.id AND lower(s.title) = lower(btrim(t.title))
+  );
+
+INSERT INTO song_artists (song_id, artist_id, role, position)
+SELECT s.id, aa.artist_id, aa.role, aa.position
+FROM songs s
+JOIN album_artists aa ON aa.album_id = s.album_id
+WHERE NOT EXISTS (SELECT 1 FROM song_artists sa WHERE sa.song_id = s.id)
+ON CONFLICT (song_id, artist_id) DO NOTHING;
+
+ALTER TABLE albums DROP COLUMN IF EXISTS tracks;
+
+CREATE INDEX IF NOT EXISTS idx_songs_album_tracklist ON songs(album_id, disc_number, side, track_num);
+
+COMMENT ON COLUMN songs.disc_number IS 'Disc of the album the track is on, starting at 1';
+COMMENT ON COLUMN songs.side IS 'Vinyl or cassette side (A, B, C, ...); NULL when the release has no sides';
+COMMENT ON COLUMN songs.track_num IS 'Position on the disc, or on the side when side is set';
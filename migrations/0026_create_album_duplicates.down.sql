@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS album_merges;
+DROP TABLE IF EXISTS album_duplicate_candidates;
@@ -0,0 +1,37 @@
+-- Review queue for albums that look like duplicates of each other. Pairs are
+-- stored with the older album first; dismissed pairs are kept so that later
+-- scans do not suggest them again.
+CREATE TABLE IF NOT EXISTS album_duplicate_candidates (
+    id BIGSERIAL PRIMARY KEY,
+    album_id BIGINT NOT NULL REFERENCES albums(id) ON DELETE CASCADE,
+    duplicate_id BIGINT NOT NULL REFERENCES albums(id) ON DELETE CASCADE,
+    score DOUBLE PRECISION NOT NULL CHECK (score >= 0 AND score <= 1),
+    reasons JSONB NOT NULL DEFAULT '[]'::jsonb,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'dismissed')),
+    reviewed_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT album_duplicate_pair_order CHECK (album_id < duplicate_id),
+    CONSTRAINT unique_album_duplicate_pair UNIQUE (album_id, duplicate_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_album_duplicate_candidates_status ON album_duplicate_candidates(status, score DESC);
+CREATE INDEX IF NOT EXISTS idx_album_duplicate_candidates_duplicate ON album_duplicate_candidates(duplicate_id);
+
+-- Audit log of merged albums. The merged album row is deleted, so its
+-- identity is copied here.
+CREATE TABLE IF NOT EXISTS album_merges (
+    id BIGSERIAL PRIMARY KEY,
+    kept_album_id BIGINT REFERENCES albums(id) ON DELETE SET NULL,
+    merged_album_id BIGINT NOT NULL,
+    merged_artist TEXT NOT NULL,
+    merged_title TEXT NOT NULL,
+    merged_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    merged_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_album_merges_kept_album ON album_merges(kept_album_id);
+
+COMMENT ON TABLE album_duplicate_candidates IS 'Scored pairs of albums that may be duplicates, awaiting curator review';
+COMMENT ON COLUMN album_duplicate_candidates.reasons IS 'Signals that contributed to the score, e.g. title, artist, track overlap';
+COMMENT ON TABLE album_merges IS 'Albums merged into another album by a curator';
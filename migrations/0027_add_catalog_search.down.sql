@@ -0,0 +1,14 @@
+-- Remove catalog search columns, indexes and helpers. The extensions stay,
+-- pg_trgm is also used by the indexes from 0007.
+DROP INDEX IF EXISTS idx_artists_name_unaccent_trgm;
+DROP INDEX IF EXISTS idx_songs_title_unaccent_trgm;
+DROP INDEX IF EXISTS idx_albums_artist_unaccent_trgm;
+DROP INDEX IF EXISTS idx_albums_title_unaccent_trgm;
+DROP INDEX IF EXISTS idx_artists_search;
+DROP INDEX IF EXISTS idx_songs_search;
+DROP INDEX IF EXISTS idx_albums_search;
+ALTER TABLE artists DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE songs DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE albums DROP COLUMN IF EXISTS search_vector;
+DROP FUNCTION IF EXISTS catalog_unaccent(TEXT);
+DROP TEXT SEARCH CONFIGURATION IF EXISTS catalog;
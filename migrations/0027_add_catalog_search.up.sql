@@ -0,0 +1,52 @@
+-- Full-text search over albums, songs and artists. The "catalog" text search
+-- configuration strips accents and does no stemming, since titles and names
+-- are mostly proper nouns in many languages. Trigram indexes on the
+-- unaccented, lowercased text back typo-tolerant matching.
+CREATE EXTENSION IF NOT EXISTS unaccent;
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_ts_config WHERE cfgname = 'catalog') THEN
+        CREATE TEXT SEARCH CONFIGURATION catalog (COPY = simple);
+        ALTER TEXT SEARCH CONFIGURATION catalog
+            ALTER MAPPING FOR asciiword, asciihword, hword_asciipart, word, hword, hword_part
+            WITH unaccent, simple;
+    END IF;
+END
+$$;
+
+-- unaccent() is only STABLE because its dictionary could change; pinning the
+-- dictionary makes it safe to index.
+CREATE OR REPLACE FUNCTION catalog_unaccent(value TEXT) RETURNS TEXT
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+    AS $$ SELECT lower(public.unaccent('public.unaccent'::regdictionary, value)) $$;
+
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('catalog', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('catalog', coalesce(artist, '')), 'B')
+    ) STORED;
+
+ALTER TABLE songs ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('catalog', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('catalog', coalesce(artist, '')), 'B')
+    ) STORED;
+
+ALTER TABLE artists ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (setweight(to_tsvector('catalog', coalesce(name, '')), 'A')) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_albums_search ON albums USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS idx_songs_search ON songs USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS idx_artists_search ON artists USING gin (search_vector);
+
+CREATE INDEX IF NOT EXISTS idx_albums_title_unaccent_trgm ON albums USING gin (catalog_unaccent(title) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_albums_artist_unaccent_trgm ON albums USING gin (catalog_unaccent(artist) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_songs_title_unaccent_trgm ON songs USING gin (catalog_unaccent(title) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_artists_name_unaccent_trgm ON artists USING gin (catalog_unaccent(name) gin_trgm_ops);
+
+COMMENT ON COLUMN albums.search_vector IS 'Weighted title (A) and artist (B) terms for catalog search';
+COMMENT ON COLUMN songs.search_vector IS 'Weighted title (A) and artist (B) terms for catalog search';
+COMMENT ON COLUMN artists.search_vector IS 'Artist name terms for catalog search';
+COMMENT ON FUNCTION catalog_unaccent(TEXT) IS 'Immutable lowercase unaccent used by the catalog trigram indexes';
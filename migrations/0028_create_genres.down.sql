@@ -0,0 +1,5 @@
+DROP FUNCTION IF EXISTS genre_subtree_keys(TEXT);
+DROP FUNCTION IF EXISTS genre_id_for(TEXT);
+DROP TABLE IF EXISTS genre_aliases;
+DROP TABLE IF EXISTS genres;
+DROP FUNCTION IF EXISTS genre_key(TEXT);
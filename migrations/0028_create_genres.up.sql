@@ -0,0 +1,113 @@
+-- Genre taxonomy. Albums and artists keep their genres as free text; these
+-- tables give the names a canonical form, a place in a hierarchy
+-- (Rock > Post-Punk) and aliases, so that a filter on a genre also matches
+-- its sub-genres and every spelling of them.
+--
+-- Names are compared by genre_key: lowercase, unaccented, & read as "and",
+-- everything but letters and digits dropped. "Post-Punk", "post punk" and
+-- "postpunk" share one key.
+CREATE OR REPLACE FUNCTION genre_key(value TEXT) RETURNS TEXT
+    LANGUAGE sql IMMUTABLE PARALLEL SAFE STRICT
+    AS $$ SELECT regexp_replace(replace(catalog_unaccent(value), '&', 'and'), '[^[:alnum:]]+', '', 'g') $$;
+
+CREATE TABLE IF NOT EXISTS genres (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL CHECK (genre_key(name) <> ''),
+    parent_id BIGINT REFERENCES genres(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT genre_not_own_parent CHECK (parent_id <> id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_genres_key ON genres (genre_key(name));
+CREATE INDEX IF NOT EXISTS idx_genres_parent ON genres(parent_id);
+
+-- Other names of a genre. Spellings that only differ in case or punctuation
+-- need no alias. A key names at most one genre, either as its name or as an
+-- alias; the application checks the former.
+CREATE TABLE IF NOT EXISTS genre_aliases (
+    key TEXT PRIMARY KEY,
+    alias TEXT NOT NULL,
+    genre_id BIGINT NOT NULL REFERENCES genres(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_genre_aliases_genre ON genre_aliases(genre_id);
+
+-- genre_id_for resolves a free-text genre to the genre it names, if any.
+CREATE OR REPLACE FUNCTION genre_id_for(value TEXT) RETURNS BIGINT
+    LANGUAGE sql STABLE STRICT
+    AS $$
+        SELECT id FROM genres WHERE genre_key(name) = genre_key(value)
+        UNION ALL
+        SELECT genre_id FROM genre_aliases WHERE key = genre_key(value)
+        LIMIT 1
+    $$;
+
+-- genre_subtree_keys returns the keys of a genre, its aliases and all of its
+-- sub-genres and their aliases. A name outside the taxonomy still returns
+-- its own key, so filters on unknown genres match that spelling.
+CREATE OR REPLACE FUNCTION genre_subtree_keys(value TEXT) RETURNS SETOF TEXT
+    LANGUAGE sql STABLE STRICT
+    AS $$
+        WITH RECURSIVE subtree(id) AS (
+            SELECT genre_id_for(value)
+            UNION
+            SELECT g.id FROM genres g JOIN subtree s ON g.parent_id = s.id
+        )
+        SELECT genre_key(value)
+        UNION
+        SELECT genre_key(g.name) FROM genres g JOIN subtree s ON g.id = s.id
+        UNION
+        SELECT a.key FROM genre_aliases a JOIN subtree s ON a.genre_id = s.id
+    $$;
+
+-- Starter taxonomy; curators extend it and imports add provider genres.
+INSERT INTO genres (name) VALUES
+    ('Rock'), ('Electronic'), ('Hip Hop'), ('Jazz'), ('Pop'), ('Soul'),
+    ('Folk'), ('Country'), ('Blues'), ('Classical'), ('Reggae'), ('Metal'),
+    ('Punk'), ('Latin'), ('World')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO genres (name, parent_id)
+SELECT child.name, parent.id
+FROM (VALUES
+    ('Alternative Rock', 'Rock'), ('Indie Rock', 'Rock'), ('Hard Rock', 'Rock'),
+    ('Progressive Rock', 'Rock'), ('Psychedelic Rock', 'Rock'), ('Krautrock', 'Rock'),
+    ('Post-Rock', 'Rock'), ('Shoegaze', 'Rock'), ('Art Rock', 'Rock'),
+    ('Post-Punk', 'Punk'), ('Hardcore', 'Punk'), ('New Wave', 'Punk'),
+    ('Heavy Metal', 'Metal'), ('Black Metal', 'Metal'), ('Doom Metal', 'Metal'),
+    ('Ambient', 'Electronic'), ('Techno', 'Electronic'), ('House', 'Electronic'),
+    ('IDM', 'Electronic'), ('Drum and Bass', 'Electronic'), ('Dubstep', 'Electronic'),
+    ('Synth-Pop', 'Pop'), ('Dream Pop', 'Pop'), ('Indie Pop', 'Pop'),
+    ('Trip Hop', 'Hip Hop'), ('Boom Bap', 'Hip Hop'), ('Trap', 'Hip Hop'),
+    ('Bebop', 'Jazz'), ('Free Jazz', 'Jazz'), ('Jazz Fusion', 'Jazz'), ('Cool Jazz', 'Jazz'),
+    ('R&B', 'Soul'), ('Funk', 'Soul'), ('Disco', 'Soul'),
+    ('Dub', 'Reggae'), ('Ska', 'Reggae'),
+    ('Singer-Songwriter', 'Folk'), ('Americana', 'Country'),
+    ('Bossa Nova', 'Latin'), ('Salsa', 'Latin')
+) AS child(name, parent)
+JOIN genres parent ON genre_key(parent.name) = genre_key(child.parent)
+ON CONFLICT DO NOTHING;
+
+-- Punk and Metal are top-level genres above so their sub-genres can be
+-- seeded; they belong under Rock.
+UPDATE genres SET parent_id = (SELECT id FROM genres WHERE genre_key(name) = 'rock')
+WHERE genre_key(name) IN ('punk', 'metal') AND parent_id IS NULL;
+
+INSERT INTO genre_aliases (key, alias, genre_id)
+SELECT genre_key(alias.alias), alias.alias, g.id
+FROM (VALUES
+    ('Rock and Roll', 'Rock'), ('Rap', 'Hip Hop'),
+    ('Electronica', 'Electronic'), ('Electronic Music', 'Electronic'),
+    ('Alternative', 'Alternative Rock'), ('Alt Rock', 'Alternative Rock'),
+    ('Indie', 'Indie Rock'), ('Prog', 'Progressive Rock'), ('Prog Rock', 'Progressive Rock'),
+    ('Psych', 'Psychedelic Rock'), ('Kosmische', 'Krautrock'),
+    ('Rhythm and Blues', 'R&B'), ('RnB', 'R&B'),
+    ('DnB', 'Drum and Bass'), ('Electropop', 'Synth-Pop'),
+    ('Fusion', 'Jazz Fusion'), ('Bop', 'Bebop'), ('Downtempo', 'Trip Hop'),
+    ('Soul Music', 'Soul'), ('Classical Music', 'Classical')
+) AS alias(alias, genre)
+JOIN genres g ON genre_key(g.name) = genre_key(alias.genre)
+ON CONFLICT DO NOTHING;
+
+COMMENT ON FUNCTION genre_key(TEXT) IS 'Normalized form genre names and aliases are compared by';
+COMMENT ON FUNCTION genre_subtree_keys(TEXT) IS 'Keys matching a genre and all of its sub-genres';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS collection_photos;
+ALTER TABLE albums DROP COLUMN IF EXISTS cover_media_id;
+DROP TABLE IF EXISTS media;
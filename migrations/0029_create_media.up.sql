@@ -0,0 +1,35 @@
+-- Stored images: album covers downloaded on import and photos users upload
+-- of their own copies. Files live in the media backend under their content
+-- hash, so the same image fetched twice is stored once; thumbnails are
+-- derived from the original and not recorded here.
+CREATE TABLE IF NOT EXISTS media (
+    id BIGSERIAL PRIMARY KEY,
+    hash CHAR(64) NOT NULL UNIQUE,
+    content_type TEXT NOT NULL,
+    width INTEGER NOT NULL CHECK (width > 0),
+    height INTEGER NOT NULL CHECK (height > 0),
+    size_bytes BIGINT NOT NULL CHECK (size_bytes > 0),
+    source_url TEXT,
+    uploaded_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_media_source_url ON media(source_url) WHERE source_url IS NOT NULL;
+
+ALTER TABLE albums ADD COLUMN IF NOT EXISTS cover_media_id BIGINT REFERENCES media(id) ON DELETE SET NULL;
+
+-- Photos of the physical copy behind a collection item.
+CREATE TABLE IF NOT EXISTS collection_photos (
+    id BIGSERIAL PRIMARY KEY,
+    collection_id BIGINT NOT NULL REFERENCES album_collections(id) ON DELETE CASCADE,
+    media_id BIGINT NOT NULL REFERENCES media(id) ON DELETE CASCADE,
+    caption TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT unique_collection_photo UNIQUE (collection_id, media_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_collection_photos_media ON collection_photos(media_id);
+
+COMMENT ON TABLE media IS 'Images kept in the media backend, addressed by SHA-256 of their content';
+COMMENT ON COLUMN media.source_url IS 'Provider URL a downloaded cover came from; NULL for uploads';
+COMMENT ON COLUMN albums.cover_media_id IS 'Cover art served from /api/v1/media instead of the provider CDN';
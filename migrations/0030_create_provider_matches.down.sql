@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS provider_match_links;
+DROP TABLE IF EXISTS provider_matches;
@@ -0,0 +1,27 @@
+-- Provider items found to be the same artist, album or track. Search merges
+-- them the same way every time, and importing any of them resolves to the
+-- same local album.
+CREATE TABLE IF NOT EXISTS provider_matches (
+    id BIGSERIAL PRIMARY KEY,
+    entity_type TEXT NOT NULL CHECK (entity_type IN ('artist', 'album', 'track')),
+    album_id BIGINT REFERENCES albums(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_matches_album_id ON provider_matches(album_id) WHERE album_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS provider_match_links (
+    match_id BIGINT NOT NULL REFERENCES provider_matches(id) ON DELETE CASCADE,
+    entity_type TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    external_id TEXT NOT NULL,
+    external_url TEXT,
+    PRIMARY KEY (entity_type, provider, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_match_links_match_id ON provider_match_links(match_id);
+
+COMMENT ON TABLE provider_matches IS 'Groups of provider items resolved to one artist, album or track';
+COMMENT ON COLUMN provider_matches.album_id IS 'Local album an album match was imported as';
+COMMENT ON TABLE provider_match_links IS 'Provider IDs belonging to a match; an ID belongs to at most one match';
@@ -0,0 +1,16 @@
+ALTER TABLE provider_matches ADD COLUMN IF NOT EXISTS album_id BIGINT REFERENCES albums(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_provider_matches_album_id ON provider_matches(album_id) WHERE album_id IS NOT NULL;
+
+UPDATE provider_matches m
+SET album_id = (
+    SELECT MIN(e.local_id)
+    FROM provider_match_links l
+    JOIN external_ids e ON e.entity_type = l.entity_type AND e.provider = l.provider AND e.external_id = l.external_id
+    WHERE l.match_id = m.id AND l.entity_type = 'album'
+);
+
+DROP TRIGGER IF EXISTS songs_external_ids_trigger ON songs;
+DROP TRIGGER IF EXISTS albums_external_ids_trigger ON albums;
+DROP TRIGGER IF EXISTS artists_external_ids_trigger ON artists;
+DROP FUNCTION IF EXISTS delete_external_ids();
+DROP TABLE IF EXISTS external_ids;
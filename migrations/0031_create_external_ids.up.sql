@@ -0,0 +1,60 @@
+-- Provider IDs of local artists, albums and tracks (songs), recorded when
+-- they are imported or saved from a provider.
+CREATE TABLE IF NOT EXISTS external_ids (
+    entity_type TEXT NOT NULL CHECK (entity_type IN ('artist', 'album', 'track')),
+    local_id BIGINT NOT NULL,
+    provider TEXT NOT NULL,
+    external_id TEXT NOT NULL,
+    url TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (entity_type, provider, external_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_external_ids_local ON external_ids(entity_type, local_id);
+
+COMMENT ON TABLE external_ids IS 'Provider IDs of local artists, albums and songs';
+COMMENT ON COLUMN external_ids.local_id IS 'artists.id, albums.id or songs.id, by entity_type';
+
+-- local_id refers to one of three tables, so mappings are removed by
+-- triggers instead of foreign keys.
+CREATE OR REPLACE FUNCTION delete_external_ids()
+RETURNS TRIGGER AS $$
+BEGIN
+    DELETE FROM external_ids WHERE entity_type = TG_ARGV[0] AND local_id = OLD.id;
+    RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER artists_external_ids_trigger
+    AFTER DELETE ON artists
+    FOR EACH ROW
+    EXECUTE FUNCTION delete_external_ids('artist');
+
+CREATE TRIGGER albums_external_ids_trigger
+    AFTER DELETE ON albums
+    FOR EACH ROW
+    EXECUTE FUNCTION delete_external_ids('album');
+
+CREATE TRIGGER songs_external_ids_trigger
+    AFTER DELETE ON songs
+    FOR EACH ROW
+    EXECUTE FUNCTION delete_external_ids('track');
+
+-- Artists saved from providers kept their ID in their own columns.
+INSERT INTO external_ids (entity_type, local_id, provider, external_id, url)
+SELECT 'artist', id, provider, external_id, NULLIF(external_url, '')
+FROM artists
+WHERE COALESCE(external_id, '') <> '' AND COALESCE(provider, '') <> ''
+ON CONFLICT DO NOTHING;
+
+-- Album matches linked to the album they were imported as; the local album
+-- of a match now follows from the external IDs of its links.
+INSERT INTO external_ids (entity_type, local_id, provider, external_id, url)
+SELECT l.entity_type, m.album_id, l.provider, l.external_id, l.external_url
+FROM provider_matches m
+JOIN provider_match_links l ON l.match_id = m.id
+WHERE m.album_id IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE provider_matches DROP COLUMN IF EXISTS album_id;
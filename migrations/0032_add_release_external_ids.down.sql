@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS album_releases_external_ids_trigger ON album_releases;
+
+DELETE FROM external_ids WHERE entity_type = 'release';
+
+ALTER TABLE external_ids DROP CONSTRAINT IF EXISTS external_ids_entity_type_check;
+ALTER TABLE external_ids ADD CONSTRAINT external_ids_entity_type_check
+    CHECK (entity_type IN ('artist', 'album', 'track'));
+
+COMMENT ON TABLE external_ids IS 'Provider IDs of local artists, albums and songs';
+COMMENT ON COLUMN external_ids.local_id IS 'artists.id, albums.id or songs.id, by entity_type';
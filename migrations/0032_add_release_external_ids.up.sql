@@ -0,0 +1,13 @@
+-- Catalog releases imported from providers that list pressings, such as
+-- Discogs, keep their provider ID so importing them again updates them.
+ALTER TABLE external_ids DROP CONSTRAINT IF EXISTS external_ids_entity_type_check;
+ALTER TABLE external_ids ADD CONSTRAINT external_ids_entity_type_check
+    CHECK (entity_type IN ('artist', 'album', 'track', 'release'));
+
+COMMENT ON TABLE external_ids IS 'Provider IDs of local artists, albums, songs and releases';
+COMMENT ON COLUMN external_ids.local_id IS 'artists.id, albums.id, songs.id or album_releases.id, by entity_type';
+
+CREATE TRIGGER album_releases_external_ids_trigger
+    AFTER DELETE ON album_releases
+    FOR EACH ROW
+    EXECUTE FUNCTION delete_external_ids('release');
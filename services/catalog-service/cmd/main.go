@@ -12,8 +12,10 @@ import (
 	"vinylhound/catalog-service/internal/handlers"
 	"vinylhound/catalog-service/internal/repository"
 	"vinylhound/catalog-service/internal/service"
+	"vinylhound/shared/auth"
 	"vinylhound/shared/database"
 	"vinylhound/shared/middleware"
+	"vinylhound/shared/models"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
@@ -32,6 +34,15 @@ func main() {
 	}
 	defer db.Close()
 
+	// Access tokens are verified locally against the user service's keys
+	tokenVerifier, err := auth.NewTokenVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure token verification: %v", err)
+	}
+
+	// Personal access tokens are looked up in the shared database
+	authService := middleware.WithAPITokens(tokenVerifier, auth.NewAPITokenVerifier(db))
+
 	// Initialize repositories
 	albumRepo := repository.NewAlbumRepository(db)
 	artistRepo := repository.NewArtistRepository(db)
@@ -64,13 +75,17 @@ func main() {
 	api.HandleFunc("/songs", songHandler.ListSongs).Methods("GET")
 	api.HandleFunc("/songs/{id}", songHandler.GetSong).Methods("GET")
 
-	// Protected routes (require authentication)
+	// Protected routes (require authentication). The catalog is shared, so
+	// curators edit it and only admins delete from it.
+	curators := middleware.RequireRole(string(models.RoleCurator), string(models.RoleAdmin))
+	admins := middleware.RequireRole(string(models.RoleAdmin))
+
 	protected := api.PathPrefix("/catalog").Subrouter()
-	// Note: In a real microservice, you'd validate tokens with the user service
-	// For now, we'll skip auth middleware for simplicity
-	protected.HandleFunc("/albums", albumHandler.CreateAlbum).Methods("POST")
-	protected.HandleFunc("/albums/{id}", albumHandler.UpdateAlbum).Methods("PUT")
-	protected.HandleFunc("/albums/{id}", albumHandler.DeleteAlbum).Methods("DELETE")
+	protected.Use(middleware.AuthMiddleware(authService))
+	protected.Use(middleware.RequireResourceScope("catalog"))
+	protected.Handle("/albums", curators(http.HandlerFunc(albumHandler.CreateAlbum))).Methods("POST")
+	protected.Handle("/albums/{id}", curators(http.HandlerFunc(albumHandler.UpdateAlbum))).Methods("PUT")
+	protected.Handle("/albums/{id}", admins(http.HandlerFunc(albumHandler.DeleteAlbum))).Methods("DELETE")
 
 	// Health check
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
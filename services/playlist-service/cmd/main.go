@@ -14,6 +14,7 @@ import (
 	"vinylhound/playlist-service/internal/handlers"
 	"vinylhound/playlist-service/internal/repository"
 	"vinylhound/playlist-service/internal/service"
+	"vinylhound/shared/auth"
 	"vinylhound/shared/middleware"
 
 	"github.com/gorilla/mux"
@@ -71,6 +72,12 @@ func main() {
 	}
 	log.Println("Connected to PostgreSQL database")
 
+	// Access tokens are verified locally against the user service's keys
+	tokenVerifier, err := auth.NewTokenVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure token verification: %v", err)
+	}
+
 	// Initialize PostgreSQL repository
 	playlistRepo := repository.NewPostgresRepository(db)
 
@@ -81,7 +88,13 @@ func main() {
 	router.Use(middleware.CORS(middleware.DefaultCORSConfig()))
 
 	api := router.PathPrefix("/api/v1").Subrouter()
-	playlistHandler.Register(api)
+	// Personal access tokens are looked up in the shared database
+	authService := middleware.WithAPITokens(tokenVerifier, auth.NewAPITokenVerifier(db))
+	requireAuth := middleware.AuthMiddleware(authService)
+	requireScope := middleware.RequireResourceScope("playlists")
+	playlistHandler.Register(api, func(next http.Handler) http.Handler {
+		return requireAuth(requireScope(next))
+	})
 
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -22,13 +22,17 @@ func New(svc *service.PlaylistService) *PlaylistHandler {
 	return &PlaylistHandler{svc: svc}
 }
 
-// Register mounts playlist routes on the given router.
-func (h *PlaylistHandler) Register(router *mux.Router) {
+// Register mounts playlist routes on the given router. Mutating routes are
+// wrapped with requireAuth.
+func (h *PlaylistHandler) Register(router *mux.Router, requireAuth mux.MiddlewareFunc) {
 	router.HandleFunc("/playlists", h.list).Methods(http.MethodGet)
-	router.HandleFunc("/playlists", h.create).Methods(http.MethodPost)
 	router.HandleFunc("/playlists/{id}", h.get).Methods(http.MethodGet)
-	router.HandleFunc("/playlists/{id}", h.update).Methods(http.MethodPut)
-	router.HandleFunc("/playlists/{id}", h.delete).Methods(http.MethodDelete)
+
+	protected := router.NewRoute().Subrouter()
+	protected.Use(requireAuth)
+	protected.HandleFunc("/playlists", h.create).Methods(http.MethodPost)
+	protected.HandleFunc("/playlists/{id}", h.update).Methods(http.MethodPut)
+	protected.HandleFunc("/playlists/{id}", h.delete).Methods(http.MethodDelete)
 }
 
 func (h *PlaylistHandler) list(w http.ResponseWriter, r *http.Request) {
@@ -12,6 +12,7 @@ import (
 	"vinylhound/rating-service/internal/handlers"
 	"vinylhound/rating-service/internal/repository"
 	"vinylhound/rating-service/internal/service"
+	"vinylhound/shared/auth"
 	"vinylhound/shared/database"
 	"vinylhound/shared/middleware"
 
@@ -32,6 +33,16 @@ func main() {
 	}
 	defer db.Close()
 
+	// Access tokens are verified locally against the user service's keys
+	tokenVerifier, err := auth.NewTokenVerifierFromEnv()
+	if err != nil {
+		log.Fatalf("Failed to configure token verification: %v", err)
+	}
+
+	// Personal access tokens are looked up in the shared database
+	authService := middleware.WithAPITokens(tokenVerifier, auth.NewAPITokenVerifier(db))
+	requireAuth := []mux.MiddlewareFunc{middleware.AuthMiddleware(authService), middleware.RequireResourceScope("ratings")}
+
 	// Initialize repositories
 	ratingRepo := repository.NewRatingRepository(db)
 	reviewRepo := repository.NewReviewRepository(db)
@@ -64,18 +75,19 @@ func main() {
 
 	// Protected routes (require authentication)
 	protected := api.PathPrefix("/ratings").Subrouter()
-	// Note: In a real microservice, you'd validate tokens with the user service
-	// For now, we'll skip auth middleware for simplicity
+	protected.Use(requireAuth...)
 	protected.HandleFunc("", ratingHandler.CreateRating).Methods("POST")
 	protected.HandleFunc("/{id}", ratingHandler.UpdateRating).Methods("PUT")
 	protected.HandleFunc("/{id}", ratingHandler.DeleteRating).Methods("DELETE")
 
 	protectedReviews := api.PathPrefix("/reviews").Subrouter()
+	protectedReviews.Use(requireAuth...)
 	protectedReviews.HandleFunc("", reviewHandler.CreateReview).Methods("POST")
 	protectedReviews.HandleFunc("/{id}", reviewHandler.UpdateReview).Methods("PUT")
 	protectedReviews.HandleFunc("/{id}", reviewHandler.DeleteReview).Methods("DELETE")
 
 	protectedPrefs := api.PathPrefix("/preferences").Subrouter()
+	protectedPrefs.Use(requireAuth...)
 	protectedPrefs.HandleFunc("", preferenceHandler.GetPreferences).Methods("GET")
 	protectedPrefs.HandleFunc("", preferenceHandler.UpdatePreferences).Methods("PUT")
 
@@ -22,5 +22,6 @@ type UserRepository interface {
 // UserWithPassword represents a user with password hash
 type UserWithPassword struct {
 	*models.User
-	PasswordHash string
+	PasswordHash     string
+	TwoFactorEnabled bool
 }
@@ -59,10 +59,10 @@ func (r *userRepository) CreateUser(ctx context.Context, username, passwordHash
 func (r *userRepository) GetUserByUsername(ctx context.Context, username string) (*UserWithPassword, error) {
 	user := &UserWithPassword{User: &models.User{}}
 	err := r.db.QueryRowContext(ctx, `
-		SELECT id, username, password_hash, created_at, updated_at
+		SELECT id, username, role, password_hash, totp_enabled_at IS NOT NULL, created_at, updated_at
 		FROM users
 		WHERE username = $1
-	`, username).Scan(&user.ID, &user.Username, &user.PasswordHash, &user.CreatedAt, &user.UpdatedAt)
+	`, username).Scan(&user.ID, &user.Username, &user.Role, &user.PasswordHash, &user.TwoFactorEnabled, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -78,10 +78,10 @@ func (r *userRepository) GetUserByUsername(ctx context.Context, username string)
 func (r *userRepository) GetUserByID(ctx context.Context, userID int64) (*models.User, error) {
 	user := &models.User{}
 	err := r.db.QueryRowContext(ctx, `
-		SELECT id, username, created_at, updated_at
+		SELECT id, username, role, created_at, updated_at
 		FROM users
 		WHERE id = $1
-	`, userID).Scan(&user.ID, &user.Username, &user.CreatedAt, &user.UpdatedAt)
+	`, userID).Scan(&user.ID, &user.Username, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
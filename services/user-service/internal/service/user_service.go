@@ -2,30 +2,52 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"time"
 
 	"vinylhound/shared/auth"
+	"vinylhound/shared/config"
 	"vinylhound/shared/models"
 	"vinylhound/user-service/internal/repository"
 )
 
+// ErrTwoFactorRequired is returned by Login for accounts with two-factor
+// authentication enabled. This service cannot check a second factor, so those
+// users must sign in through the main API's two-step login
+var ErrTwoFactorRequired = errors.New("two-factor authentication required")
+
 // UserService handles user-related business logic
 type UserService struct {
-	repo     repository.UserRepository
-	tokenMgr *auth.TokenManager
+	repo      repository.UserRepository
+	tokenMgr  *auth.TokenManager
+	accessTTL time.Duration
 }
 
 // NewUserService creates a new user service
 func NewUserService(repo repository.UserRepository) *UserService {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
+	security, err := config.LoadSecurity()
+	if err != nil {
+		panic(fmt.Sprintf("load security config: %v", err))
+	}
+	if security.JWTAlgorithm == auth.AlgHS256 && security.JWTSecret == "" {
 		panic("JWT_SECRET environment variable is required")
 	}
 
+	keys, err := auth.KeyConfigFromEnv()
+	if err != nil {
+		panic(fmt.Sprintf("load token keys: %v", err))
+	}
+	keys.TTL = security.AccessTokenTTL
+	tokenMgr, err := auth.NewTokenManagerFromConfig(keys)
+	if err != nil {
+		panic(fmt.Sprintf("configure token signing: %v", err))
+	}
+
 	return &UserService{
-		repo:     repo,
-		tokenMgr: auth.NewTokenManager(jwtSecret),
+		repo:      repo,
+		tokenMgr:  tokenMgr,
+		accessTTL: security.AccessTokenTTL,
 	}
 }
 
@@ -66,22 +88,33 @@ func (s *UserService) Login(ctx context.Context, username, password string) (str
 		return "", fmt.Errorf("invalid credentials: %w", err)
 	}
 
-	// Generate session token
-	token, err := s.tokenMgr.GenerateToken()
+	if user.TwoFactorEnabled {
+		return "", ErrTwoFactorRequired
+	}
+
+	// Issue a signed access token carrying the role other services enforce;
+	// the session row lets it be revoked early
+	token, err := s.tokenMgr.GenerateTokenWithRole(user.ID, string(user.Role))
 	if err != nil {
 		return "", fmt.Errorf("generate token: %w", err)
 	}
 
 	// Create session
-	if err := s.repo.CreateSession(ctx, token, user.ID, auth.TokenExpiry()); err != nil {
+	if err := s.repo.CreateSession(ctx, token, user.ID, auth.TokenExpiry(s.accessTTL)); err != nil {
 		return "", fmt.Errorf("create session: %w", err)
 	}
 
 	return token, nil
 }
 
-// ValidateToken validates a session token and returns user ID
+// ValidateToken validates a session token and returns user ID. The signature
+// is checked first so forged tokens never reach the database; the session
+// lookup catches tokens revoked before they expire.
 func (s *UserService) ValidateToken(ctx context.Context, token string) (int64, error) {
+	if _, err := s.tokenMgr.ValidateToken(ctx, token); err != nil {
+		return 0, fmt.Errorf("validate token: %w", err)
+	}
+
 	userID, err := s.repo.GetUserIDByToken(ctx, token)
 	if err != nil {
 		return 0, fmt.Errorf("validate token: %w", err)
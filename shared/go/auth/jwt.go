@@ -1,29 +1,277 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
-// TokenManager handles JWT token operations
+// DefaultIssuer is the iss claim used when no issuer is configured
+const DefaultIssuer = "vinylhound"
+
+var (
+	// ErrInvalidToken is returned for tokens that fail signature or claim checks
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrUnknownKey is returned when a token references a key ID we do not hold
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrNoSigningKey is returned when issuing from a verification-only manager
+	ErrNoSigningKey = errors.New("no signing key configured")
+)
+
+// Claims are the JWT claims carried by access tokens. The subject holds the
+// user ID and role holds the user's catalog role at issue time.
+type Claims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role,omitempty"`
+}
+
+// UserID returns the user ID stored in the subject claim
+func (c *Claims) UserID() (int64, error) {
+	id, err := strconv.ParseInt(c.Subject, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: bad subject", ErrInvalidToken)
+	}
+	return id, nil
+}
+
+// TokenManager issues and verifies signed JWT access tokens. It holds a set of
+// keys indexed by key ID: the active key signs new tokens, and every key in
+// the set is accepted for verification so tokens signed before a rotation
+// stay valid until they expire.
 type TokenManager struct {
-	secretKey []byte
+	mu       sync.RWMutex
+	keys     map[string]Key
+	activeID string
+	issuer   string
+	ttl      time.Duration
 }
 
-// NewTokenManager creates a new token manager
+// NewTokenManager creates a token manager signing with HS256 and the given
+// shared secret
 func NewTokenManager(secretKey string) *TokenManager {
+	tm := newTokenManager()
+	key := NewHMACKey(DefaultKeyID, []byte(secretKey))
+	tm.keys[key.ID] = key
+	tm.activeID = key.ID
+	return tm
+}
+
+// NewTokenManagerWithKeys creates a token manager that signs with active and
+// additionally accepts tokens signed by any of the verification keys
+func NewTokenManagerWithKeys(active Key, verification ...Key) (*TokenManager, error) {
+	tm := newTokenManager()
+	for _, key := range verification {
+		if err := tm.AddKey(key); err != nil {
+			return nil, err
+		}
+	}
+	if err := tm.RotateKey(active); err != nil {
+		return nil, err
+	}
+	return tm, nil
+}
+
+// NewTokenVerifier creates a verification-only token manager, for services
+// that accept tokens but never issue them
+func NewTokenVerifier(keys ...Key) (*TokenManager, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one verification key is required")
+	}
+	tm := newTokenManager()
+	for _, key := range keys {
+		if err := tm.AddKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return tm, nil
+}
+
+func newTokenManager() *TokenManager {
 	return &TokenManager{
-		secretKey: []byte(secretKey),
+		keys:   make(map[string]Key),
+		issuer: DefaultIssuer,
+		ttl:    DefaultTokenTTL,
+	}
+}
+
+// SetIssuer changes the iss claim written to and required of tokens
+func (tm *TokenManager) SetIssuer(issuer string) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if issuer != "" {
+		tm.issuer = issuer
+	}
+}
+
+// SetTTL changes the lifetime of newly issued tokens
+func (tm *TokenManager) SetTTL(ttl time.Duration) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if ttl > 0 {
+		tm.ttl = ttl
+	}
+}
+
+// AddKey registers a key for verification without making it active
+func (tm *TokenManager) AddKey(key Key) error {
+	if err := key.validate(); err != nil {
+		return err
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.keys[key.ID] = key
+	return nil
+}
+
+// RotateKey registers key and makes it the signing key. The previously
+// active key remains available for verification until removed.
+func (tm *TokenManager) RotateKey(key Key) error {
+	if err := key.validate(); err != nil {
+		return err
+	}
+	if !key.CanSign() {
+		return fmt.Errorf("key %q has no private key and cannot sign", key.ID)
+	}
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.keys[key.ID] = key
+	tm.activeID = key.ID
+	return nil
+}
+
+// RemoveKey retires a verification key. The active key cannot be removed.
+func (tm *TokenManager) RemoveKey(id string) error {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	if id == tm.activeID {
+		return fmt.Errorf("key %q is the active signing key", id)
+	}
+	delete(tm.keys, id)
+	return nil
+}
+
+// GenerateToken issues a signed access token without a role claim
+func (tm *TokenManager) GenerateToken(userID int64) (string, error) {
+	return tm.GenerateTokenWithRole(userID, "")
+}
+
+// GenerateTokenWithRole issues a signed access token carrying the user's role.
+// Role changes take effect for services validating locally once the user
+// signs in again.
+func (tm *TokenManager) GenerateTokenWithRole(userID int64, role string) (string, error) {
+	tm.mu.RLock()
+	key, ok := tm.keys[tm.activeID]
+	issuer, ttl := tm.issuer, tm.ttl
+	tm.mu.RUnlock()
+	if !ok {
+		return "", ErrNoSigningKey
+	}
+
+	jti, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   strconv.FormatInt(userID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			ID:        jti,
+		},
+		Role: role,
+	}
+
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+
+	signed, err := token.SignedString(key.signKey)
+	if err != nil {
+		return "", fmt.Errorf("sign token: %w", err)
+	}
+	return signed, nil
+}
+
+// ParseToken verifies the token signature and standard claims and returns
+// its claims. The signing algorithm must match the one registered for the
+// key ID in the token header.
+func (tm *TokenManager) ParseToken(tokenString string) (*Claims, error) {
+	tm.mu.RLock()
+	issuer := tm.issuer
+	tm.mu.RUnlock()
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, tm.keyFunc,
+		jwt.WithValidMethods(supportedAlgorithms),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// ValidateToken verifies a token locally and returns the user ID. It
+// satisfies middleware.AuthService, letting services authenticate requests
+// without a database round trip. Revoked sessions are not detected until the
+// token expires, so keep access token lifetimes short.
+func (tm *TokenManager) ValidateToken(ctx context.Context, token string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	claims, err := tm.ParseToken(token)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserID()
+}
+
+// ValidateTokenWithRole verifies a token locally and returns the user ID and
+// role claim. It satisfies middleware.RoleAuthService.
+func (tm *TokenManager) ValidateTokenWithRole(ctx context.Context, token string) (int64, string, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, "", err
+	}
+	claims, err := tm.ParseToken(token)
+	if err != nil {
+		return 0, "", err
 	}
+	userID, err := claims.UserID()
+	if err != nil {
+		return 0, "", err
+	}
+	return userID, claims.Role, nil
 }
 
-// GenerateToken creates a new session token
-func (tm *TokenManager) GenerateToken() (string, error) {
-	b := make([]byte, 32)
+func (tm *TokenManager) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	tm.mu.RLock()
+	key, ok := tm.keys[kid]
+	tm.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("algorithm %s does not match key %q", token.Method.Alg(), kid)
+	}
+	return key.verifyKey, nil
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
 	if _, err := rand.Read(b); err != nil {
 		return "", fmt.Errorf("generate random bytes: %w", err)
 	}
@@ -44,7 +292,14 @@ func VerifyPassword(password, hash string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
-// TokenExpiry returns the default token expiry time
-func TokenExpiry() time.Time {
-	return time.Now().Add(24 * time.Hour) // 24 hours
+// DefaultTokenTTL is used when no token lifetime is configured
+const DefaultTokenTTL = 24 * time.Hour
+
+// TokenExpiry returns the expiry time for a token issued now with the given
+// lifetime, falling back to DefaultTokenTTL when ttl is not positive
+func TokenExpiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+	return time.Now().Add(ttl)
 }
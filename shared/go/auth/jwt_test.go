@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenManagerRoundTrip(t *testing.T) {
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate rsa key: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		key  Key
+	}{
+		{name: "HS256", key: NewHMACKey("hs", []byte("0123456789abcdef"))},
+		{name: "EdDSA", key: NewEd25519Key("ed", edPrivate)},
+		{name: "RS256", key: NewRSAKey("rsa", rsaPrivate)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm, err := NewTokenManagerWithKeys(tt.key)
+			if err != nil {
+				t.Fatalf("NewTokenManagerWithKeys: %v", err)
+			}
+
+			token, err := tm.GenerateToken(42)
+			if err != nil {
+				t.Fatalf("GenerateToken: %v", err)
+			}
+
+			userID, err := tm.ValidateToken(context.Background(), token)
+			if err != nil {
+				t.Fatalf("ValidateToken: %v", err)
+			}
+			if userID != 42 {
+				t.Fatalf("expected user 42, got %d", userID)
+			}
+		})
+	}
+}
+
+func TestTokenManagerRotation(t *testing.T) {
+	tm := NewTokenManager("old-secret-0123456789")
+	oldToken, err := tm.GenerateToken(7)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	if err := tm.RotateKey(NewEd25519Key("2024-10", edPrivate)); err != nil {
+		t.Fatalf("RotateKey: %v", err)
+	}
+
+	newToken, err := tm.GenerateToken(7)
+	if err != nil {
+		t.Fatalf("GenerateToken after rotation: %v", err)
+	}
+
+	for _, token := range []string{oldToken, newToken} {
+		if _, err := tm.ValidateToken(context.Background(), token); err != nil {
+			t.Fatalf("ValidateToken: %v", err)
+		}
+	}
+
+	if err := tm.RemoveKey("2024-10"); err == nil {
+		t.Fatalf("expected removing the active key to fail")
+	}
+	if err := tm.RemoveKey(DefaultKeyID); err != nil {
+		t.Fatalf("RemoveKey: %v", err)
+	}
+	if _, err := tm.ValidateToken(context.Background(), oldToken); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey for retired key, got %v", err)
+	}
+}
+
+func TestTokenVerifierRejectsForgeries(t *testing.T) {
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	issuer, err := NewTokenManagerWithKeys(NewEd25519Key("k1", edPrivate))
+	if err != nil {
+		t.Fatalf("NewTokenManagerWithKeys: %v", err)
+	}
+	verifier, err := NewTokenVerifier(NewEd25519PublicKey("k1", edPublic))
+	if err != nil {
+		t.Fatalf("NewTokenVerifier: %v", err)
+	}
+
+	token, err := issuer.GenerateToken(9)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if userID, err := verifier.ValidateToken(context.Background(), token); err != nil || userID != 9 {
+		t.Fatalf("expected user 9, got %d (%v)", userID, err)
+	}
+
+	if _, err := verifier.GenerateToken(9); !errors.Is(err, ErrNoSigningKey) {
+		t.Fatalf("expected ErrNoSigningKey from verifier, got %v", err)
+	}
+
+	// An HS256 token signed with the public key bytes under the same key ID
+	// must not be accepted.
+	forger, err := NewTokenManagerWithKeys(NewHMACKey("k1", edPublic))
+	if err != nil {
+		t.Fatalf("NewTokenManagerWithKeys: %v", err)
+	}
+	forged, err := forger.GenerateToken(9)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if _, err := verifier.ValidateToken(context.Background(), forged); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for algorithm confusion, got %v", err)
+	}
+
+	// Flip a character early in the signature; the final base64 character
+	// carries padding bits and may decode to the same bytes.
+	sig := strings.LastIndex(token, ".") + 1
+	flipped := byte('A')
+	if token[sig] == 'A' {
+		flipped = 'B'
+	}
+	tampered := token[:sig] + string(flipped) + token[sig+1:]
+	if _, err := verifier.ValidateToken(context.Background(), tampered); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for tampered signature, got %v", err)
+	}
+}
+
+func TestTokenManagerExpiredToken(t *testing.T) {
+	tm := NewTokenManager("0123456789abcdef")
+	tm.SetTTL(time.Nanosecond)
+
+	token, err := tm.GenerateToken(1)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	if _, err := tm.ValidateToken(context.Background(), token); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken for expired token, got %v", err)
+	}
+}
+
+func TestNewTokenManagerFromConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("generate ed25519 key: %v", err)
+	}
+	privateDER, err := x509.MarshalPKCS8PrivateKey(edPrivate)
+	if err != nil {
+		t.Fatalf("marshal private key: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(edPublic)
+	if err != nil {
+		t.Fatalf("marshal public key: %v", err)
+	}
+
+	privatePath := filepath.Join(dir, "signing.pem")
+	publicPath := filepath.Join(dir, "signing.pub.pem")
+	writePEM(t, privatePath, "PRIVATE KEY", privateDER)
+	writePEM(t, publicPath, "PUBLIC KEY", publicDER)
+
+	issuer, err := NewTokenManagerFromConfig(KeyConfig{
+		Algorithm:      AlgEdDSA,
+		KeyID:          "k1",
+		PrivateKeyFile: privatePath,
+		Issuer:         "vinylhound-test",
+	})
+	if err != nil {
+		t.Fatalf("issuer config: %v", err)
+	}
+	verifier, err := NewTokenManagerFromConfig(KeyConfig{
+		Algorithm:      AlgEdDSA,
+		PublicKeyFiles: map[string]string{"k1": publicPath},
+		Issuer:         "vinylhound-test",
+	})
+	if err != nil {
+		t.Fatalf("verifier config: %v", err)
+	}
+
+	token, err := issuer.GenerateToken(5)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if userID, err := verifier.ValidateToken(context.Background(), token); err != nil || userID != 5 {
+		t.Fatalf("expected user 5, got %d (%v)", userID, err)
+	}
+
+	if _, err := NewTokenManagerFromConfig(KeyConfig{Algorithm: AlgRS256, PrivateKeyFile: privatePath}); err == nil {
+		t.Fatalf("expected algorithm mismatch error")
+	}
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestTokenManagerRoleClaim(t *testing.T) {
+	tm := NewTokenManager("0123456789abcdef")
+
+	token, err := tm.GenerateTokenWithRole(5, "curator")
+	if err != nil {
+		t.Fatalf("GenerateTokenWithRole: %v", err)
+	}
+	userID, role, err := tm.ValidateTokenWithRole(context.Background(), token)
+	if err != nil {
+		t.Fatalf("ValidateTokenWithRole: %v", err)
+	}
+	if userID != 5 || role != "curator" {
+		t.Fatalf("expected user 5 as curator, got %d/%q", userID, role)
+	}
+
+	plain, err := tm.GenerateToken(5)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if _, role, err := tm.ValidateTokenWithRole(context.Background(), plain); err != nil || role != "" {
+		t.Fatalf("expected no role claim, got %q (%v)", role, err)
+	}
+}
+
+func TestNewTokenVerifierFromEnv(t *testing.T) {
+	t.Setenv("JWT_ALGORITHM", "")
+	t.Setenv("JWT_KEY_ID", "k2")
+	t.Setenv("JWT_SECRET", "current-secret")
+	t.Setenv("JWT_PREVIOUS_SECRETS", "k1=old-secret")
+	t.Setenv("JWT_PUBLIC_KEY_FILES", "")
+	t.Setenv("JWT_ISSUER", "")
+
+	verifier, err := NewTokenVerifierFromEnv()
+	if err != nil {
+		t.Fatalf("NewTokenVerifierFromEnv: %v", err)
+	}
+	old, err := NewTokenManagerWithKeys(NewHMACKey("k1", []byte("old-secret")))
+	if err != nil {
+		t.Fatalf("NewTokenManagerWithKeys: %v", err)
+	}
+	token, err := old.GenerateToken(5)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if userID, err := verifier.ValidateToken(context.Background(), token); err != nil || userID != 5 {
+		t.Fatalf("expected a token under a previous secret to verify, got %d (%v)", userID, err)
+	}
+
+	t.Setenv("JWT_PREVIOUS_SECRETS", "k1")
+	if _, err := NewTokenVerifierFromEnv(); err == nil {
+		t.Fatal("expected an error for a malformed JWT_PREVIOUS_SECRETS")
+	}
+}
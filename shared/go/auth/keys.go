@@ -0,0 +1,281 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported JWT signing algorithms
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+	AlgRS256 = "RS256"
+)
+
+// DefaultKeyID is the key ID used when none is configured
+const DefaultKeyID = "default"
+
+var supportedAlgorithms = []string{AlgHS256, AlgEdDSA, AlgRS256}
+
+// Key is a JWT signing or verification key identified by a key ID.
+// Keys built from a public key only can verify but not sign.
+type Key struct {
+	ID        string
+	Algorithm string
+
+	signKey   any
+	verifyKey any
+}
+
+// NewHMACKey creates an HS256 key from a shared secret
+func NewHMACKey(id string, secret []byte) Key {
+	return Key{ID: id, Algorithm: AlgHS256, signKey: secret, verifyKey: secret}
+}
+
+// NewEd25519Key creates an EdDSA signing key
+func NewEd25519Key(id string, private ed25519.PrivateKey) Key {
+	return Key{ID: id, Algorithm: AlgEdDSA, signKey: private, verifyKey: private.Public()}
+}
+
+// NewEd25519PublicKey creates an EdDSA verification-only key
+func NewEd25519PublicKey(id string, public ed25519.PublicKey) Key {
+	return Key{ID: id, Algorithm: AlgEdDSA, verifyKey: public}
+}
+
+// NewRSAKey creates an RS256 signing key
+func NewRSAKey(id string, private *rsa.PrivateKey) Key {
+	return Key{ID: id, Algorithm: AlgRS256, signKey: private, verifyKey: &private.PublicKey}
+}
+
+// NewRSAPublicKey creates an RS256 verification-only key
+func NewRSAPublicKey(id string, public *rsa.PublicKey) Key {
+	return Key{ID: id, Algorithm: AlgRS256, verifyKey: public}
+}
+
+// CanSign reports whether the key holds private material
+func (k Key) CanSign() bool {
+	return k.signKey != nil
+}
+
+func (k Key) validate() error {
+	if k.ID == "" {
+		return errors.New("key ID is required")
+	}
+	if k.verifyKey == nil {
+		return fmt.Errorf("key %q has no key material", k.ID)
+	}
+	if secret, ok := k.verifyKey.([]byte); ok && len(secret) == 0 {
+		return fmt.Errorf("key %q has an empty secret", k.ID)
+	}
+	return nil
+}
+
+func (k Key) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgEdDSA:
+		return jwt.SigningMethodEdDSA
+	case AlgRS256:
+		return jwt.SigningMethodRS256
+	default:
+		return jwt.SigningMethodHS256
+	}
+}
+
+// ParsePrivateKeyPEM builds a signing key from a PEM encoded Ed25519 or RSA
+// private key (PKCS#8, or PKCS#1 for RSA)
+func ParsePrivateKeyPEM(id string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("key %q: no PEM block found", id)
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		private, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return Key{}, fmt.Errorf("key %q: parse PKCS#1 key: %w", id, err)
+		}
+		return NewRSAKey(id, private), nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return Key{}, fmt.Errorf("key %q: parse PKCS#8 key: %w", id, err)
+	}
+	switch private := parsed.(type) {
+	case ed25519.PrivateKey:
+		return NewEd25519Key(id, private), nil
+	case *rsa.PrivateKey:
+		return NewRSAKey(id, private), nil
+	default:
+		return Key{}, fmt.Errorf("key %q: unsupported private key type %T", id, parsed)
+	}
+}
+
+// ParsePublicKeyPEM builds a verification-only key from a PEM encoded
+// Ed25519 or RSA public key
+func ParsePublicKeyPEM(id string, data []byte) (Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return Key{}, fmt.Errorf("key %q: no PEM block found", id)
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return Key{}, fmt.Errorf("key %q: parse public key: %w", id, err)
+	}
+	switch public := parsed.(type) {
+	case ed25519.PublicKey:
+		return NewEd25519PublicKey(id, public), nil
+	case *rsa.PublicKey:
+		return NewRSAPublicKey(id, public), nil
+	default:
+		return Key{}, fmt.Errorf("key %q: unsupported public key type %T", id, parsed)
+	}
+}
+
+// KeyConfig describes the keys a TokenManager is built from
+type KeyConfig struct {
+	Algorithm       string            // HS256 (default), EdDSA or RS256
+	KeyID           string            // ID of the active key
+	Secret          string            // HS256 shared secret
+	PreviousSecrets map[string]string // key ID -> retired HS256 secret still accepted
+	PrivateKeyFile  string            // PEM private key; only issuers need one
+	PublicKeyFiles  map[string]string // key ID -> PEM public key accepted for verification
+	Issuer          string
+	TTL             time.Duration
+}
+
+// KeyConfigFromEnv reads the JWT_ALGORITHM, JWT_KEY_ID, JWT_SECRET,
+// JWT_PREVIOUS_SECRETS, JWT_PRIVATE_KEY_FILE, JWT_PUBLIC_KEY_FILES and
+// JWT_ISSUER settings. TTL is left to the caller.
+func KeyConfigFromEnv() (KeyConfig, error) {
+	publicKeys, err := keyValueEnv("JWT_PUBLIC_KEY_FILES")
+	if err != nil {
+		return KeyConfig{}, err
+	}
+	previousSecrets, err := keyValueEnv("JWT_PREVIOUS_SECRETS")
+	if err != nil {
+		return KeyConfig{}, err
+	}
+	return KeyConfig{
+		Algorithm:       os.Getenv("JWT_ALGORITHM"),
+		KeyID:           os.Getenv("JWT_KEY_ID"),
+		Secret:          os.Getenv("JWT_SECRET"),
+		PreviousSecrets: previousSecrets,
+		PrivateKeyFile:  os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		PublicKeyFiles:  publicKeys,
+		Issuer:          os.Getenv("JWT_ISSUER"),
+	}, nil
+}
+
+// NewTokenVerifierFromEnv builds a verification-only TokenManager from the
+// settings KeyConfigFromEnv reads, for services that check access tokens
+// issued by the user service without a database round trip.
+func NewTokenVerifierFromEnv() (*TokenManager, error) {
+	keys, err := KeyConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	keys.PrivateKeyFile = ""
+	return NewTokenManagerFromConfig(keys)
+}
+
+// keyValueEnv parses a comma-separated list of id=value pairs, such as
+// "2024-10=/etc/keys/old.pem,2025-01=/etc/keys/new.pem".
+func keyValueEnv(key string) (map[string]string, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return nil, nil
+	}
+	values := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		id, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || id == "" || value == "" {
+			return nil, fmt.Errorf("invalid %s entry %q: expected id=value", key, pair)
+		}
+		values[id] = value
+	}
+	return values, nil
+}
+
+// NewTokenManagerFromConfig builds a TokenManager from deployment settings.
+// HS256 managers always sign with Secret. For EdDSA and RS256 a manager
+// without a private key is verification-only.
+func NewTokenManagerFromConfig(cfg KeyConfig) (*TokenManager, error) {
+	kid := cfg.KeyID
+	if kid == "" {
+		kid = DefaultKeyID
+	}
+
+	var (
+		active *Key
+		keys   []Key
+	)
+
+	switch cfg.Algorithm {
+	case "", AlgHS256:
+		if cfg.Secret == "" {
+			return nil, errors.New("HS256 requires a secret")
+		}
+		key := NewHMACKey(kid, []byte(cfg.Secret))
+		active = &key
+		for id, secret := range cfg.PreviousSecrets {
+			keys = append(keys, NewHMACKey(id, []byte(secret)))
+		}
+	case AlgEdDSA, AlgRS256:
+		if cfg.PrivateKeyFile != "" {
+			data, err := os.ReadFile(cfg.PrivateKeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("read private key: %w", err)
+			}
+			key, err := ParsePrivateKeyPEM(kid, data)
+			if err != nil {
+				return nil, err
+			}
+			if key.Algorithm != cfg.Algorithm {
+				return nil, fmt.Errorf("private key is %s, expected %s", key.Algorithm, cfg.Algorithm)
+			}
+			active = &key
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %q", cfg.Algorithm)
+	}
+
+	for id, path := range cfg.PublicKeyFiles {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read public key %q: %w", id, err)
+		}
+		key, err := ParsePublicKeyPEM(id, data)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	var (
+		tm  *TokenManager
+		err error
+	)
+	if active != nil {
+		tm, err = NewTokenManagerWithKeys(*active, keys...)
+	} else {
+		tm, err = NewTokenVerifier(keys...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tm.SetIssuer(cfg.Issuer)
+	tm.SetTTL(cfg.TTL)
+	return tm, nil
+}
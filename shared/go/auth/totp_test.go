@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA-1 test key from RFC 6238 appendix B, base32 encoded.
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC6238Vectors(t *testing.T) {
+	// The RFC lists 8-digit codes; the 6-digit code is the low six digits.
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("TOTPCode(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatalf("NewTOTPSecret: %v", err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	step := TOTPStep(now)
+
+	for offset := int64(-TOTPSkew); offset <= TOTPSkew; offset++ {
+		code, err := TOTPCode(secret, step+offset)
+		if err != nil {
+			t.Fatalf("TOTPCode: %v", err)
+		}
+		matched, ok := ValidateTOTP(secret, code, now)
+		if !ok || matched != step+offset {
+			t.Errorf("offset %d: got (%d, %v), want (%d, true)", offset, matched, ok, step+offset)
+		}
+	}
+
+	stale, _ := TOTPCode(secret, step-TOTPSkew-1)
+	if _, ok := ValidateTOTP(secret, stale, now); ok {
+		t.Error("code outside the window was accepted")
+	}
+	if _, ok := ValidateTOTP(secret, "12345", now); ok {
+		t.Error("short code was accepted")
+	}
+
+	code, _ := TOTPCode(secret, step)
+	if _, ok := ValidateTOTP(secret, code[:3]+" "+code[3:], now); !ok {
+		t.Error("code with a space was rejected")
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("ABCDEF", "ada")
+	if !strings.HasPrefix(uri, "otpauth://totp/Vinylhound:ada?") {
+		t.Fatalf("unexpected uri %q", uri)
+	}
+	for _, want := range []string{"secret=ABCDEF", "issuer=Vinylhound"} {
+		if !strings.Contains(uri, want) {
+			t.Errorf("uri %q missing %q", uri, want)
+		}
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(RecoveryCodeCount)
+	if err != nil {
+		t.Fatalf("NewRecoveryCodes: %v", err)
+	}
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("got %d codes, want %d", len(codes), RecoveryCodeCount)
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("code %q is not formatted xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+
+	if got := NormalizeRecoveryCode(" ABCDE-fghij "); got != "abcdefghij" {
+		t.Errorf("NormalizeRecoveryCode = %q", got)
+	}
+}
@@ -5,6 +5,13 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
+)
+
+// Default token lifetimes used when ACCESS_TOKEN_TTL or REFRESH_TOKEN_TTL are unset.
+const (
+	DefaultAccessTokenTTL  = 24 * time.Hour
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
 )
 
 // Config holds all application configuration
@@ -18,6 +25,9 @@ type Config struct {
 	// Security configuration
 	Security SecurityConfig
 
+	// Login brute-force protection
+	LoginProtection LoginProtectionConfig
+
 	// CORS configuration
 	CORS CORSConfig
 
@@ -44,7 +54,71 @@ type ServerConfig struct {
 
 // SecurityConfig holds security-related settings
 type SecurityConfig struct {
-	JWTSecret string
+	JWTSecret       string
+	AccessTokenTTL  time.Duration // lifetime of a session token, extended on activity
+	RefreshTokenTTL time.Duration // lifetime of each rotating refresh token
+
+	// Signing keys are loaded by auth.KeyConfigFromEnv; these settings are
+	// only read to validate the configuration.
+	JWTAlgorithm      string // HS256, EdDSA or RS256
+	JWTKeyID          string // key ID of the active signing key
+	JWTIssuer         string // iss claim written to and required of tokens
+	JWTPrivateKeyFile string // PEM private key for EdDSA/RS256 issuers
+	JWTPublicKeyFiles string // id=path list of PEM public keys accepted for verification
+}
+
+// LoginProtectionConfig controls failed-login backoff and lockout. Failures
+// are counted per username (reset by a successful login) and per client IP
+// within Window.
+type LoginProtectionConfig struct {
+	Window          time.Duration // how far back failed attempts are counted
+	FreeAttempts    int           // failures allowed before backoff starts; may be 0
+	BaseDelay       time.Duration // first backoff delay, doubled per further failure
+	MaxDelay        time.Duration // backoff cap
+	MaxAttempts     int           // failures per username that trigger a lockout
+	LockoutDuration time.Duration // how long a username or IP stays locked
+	MaxAttemptsIP   int           // failures per IP that trigger a lockout
+}
+
+// DefaultLoginProtection returns the login protection used when nothing is configured
+func DefaultLoginProtection() LoginProtectionConfig {
+	return LoginProtectionConfig{
+		Window:          15 * time.Minute,
+		FreeAttempts:    3,
+		BaseDelay:       time.Second,
+		MaxDelay:        time.Minute,
+		MaxAttempts:     10,
+		LockoutDuration: 15 * time.Minute,
+		MaxAttemptsIP:   50,
+	}
+}
+
+// SessionStoreConfig controls the session token cache and the background
+// removal of expired sessions
+type SessionStoreConfig struct {
+	CacheSize     int           // cached token lookups; 0 disables the cache
+	CacheTTL      time.Duration // how long a cached lookup is trusted
+	SweepInterval time.Duration // time between expired-session sweeps; 0 disables them
+}
+
+// DefaultSessionStore returns the session settings used when nothing is configured
+func DefaultSessionStore() SessionStoreConfig {
+	return SessionStoreConfig{
+		CacheSize:     10000,
+		CacheTTL:      30 * time.Second,
+		SweepInterval: 15 * time.Minute,
+	}
+}
+
+// OIDCProviderConfig describes an external OpenID Connect identity provider
+// users may sign in with
+type OIDCProviderConfig struct {
+	Name         string // short name used in URLs
+	Issuer       string // issuer URL; endpoints are discovered from it
+	ClientID     string
+	ClientSecret string   // empty for public clients
+	RedirectURL  string   // callback URL registered with the provider
+	Scopes       []string // empty for the default openid, email and profile
 }
 
 // CORSConfig holds CORS settings
@@ -77,6 +151,13 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("load security config: %w", err)
 	}
 
+	// Load login protection configuration
+	loginProtection, err := LoadLoginProtection()
+	if err != nil {
+		return nil, fmt.Errorf("load login protection config: %w", err)
+	}
+	cfg.LoginProtection = loginProtection
+
 	// Load CORS configuration
 	cfg.loadCORS()
 
@@ -146,10 +227,138 @@ func (c *Config) loadServer() error {
 }
 
 func (c *Config) loadSecurity() error {
-	c.Security.JWTSecret = os.Getenv("JWT_SECRET")
+	security, err := LoadSecurity()
+	if err != nil {
+		return err
+	}
+	c.Security = security
 	return nil
 }
 
+// LoadSecurity reads the security settings on their own, for binaries that
+// do not use the full service configuration.
+func LoadSecurity() (SecurityConfig, error) {
+	accessTTL, err := getDurationOrDefault("ACCESS_TOKEN_TTL", DefaultAccessTokenTTL)
+	if err != nil {
+		return SecurityConfig{}, err
+	}
+	refreshTTL, err := getDurationOrDefault("REFRESH_TOKEN_TTL", DefaultRefreshTokenTTL)
+	if err != nil {
+		return SecurityConfig{}, err
+	}
+	if refreshTTL < accessTTL {
+		return SecurityConfig{}, fmt.Errorf("REFRESH_TOKEN_TTL (%s) must not be shorter than ACCESS_TOKEN_TTL (%s)", refreshTTL, accessTTL)
+	}
+
+	return SecurityConfig{
+		JWTSecret:         os.Getenv("JWT_SECRET"),
+		AccessTokenTTL:    accessTTL,
+		RefreshTokenTTL:   refreshTTL,
+		JWTAlgorithm:      getEnvOrDefault("JWT_ALGORITHM", "HS256"),
+		JWTKeyID:          getEnvOrDefault("JWT_KEY_ID", "default"),
+		JWTIssuer:         getEnvOrDefault("JWT_ISSUER", "vinylhound"),
+		JWTPrivateKeyFile: os.Getenv("JWT_PRIVATE_KEY_FILE"),
+		JWTPublicKeyFiles: os.Getenv("JWT_PUBLIC_KEY_FILES"),
+	}, nil
+}
+
+// LoadLoginProtection reads the LOGIN_* settings, falling back to
+// DefaultLoginProtection for anything unset
+func LoadLoginProtection() (LoginProtectionConfig, error) {
+	cfg := DefaultLoginProtection()
+
+	var err error
+	if cfg.Window, err = getDurationOrDefault("LOGIN_ATTEMPT_WINDOW", cfg.Window); err != nil {
+		return cfg, err
+	}
+	if cfg.FreeAttempts, err = getNonNegativeIntOrDefault("LOGIN_FREE_ATTEMPTS", cfg.FreeAttempts); err != nil {
+		return cfg, err
+	}
+	if cfg.BaseDelay, err = getDurationOrDefault("LOGIN_BACKOFF_BASE", cfg.BaseDelay); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxDelay, err = getDurationOrDefault("LOGIN_BACKOFF_MAX", cfg.MaxDelay); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxAttempts, err = getIntOrDefault("LOGIN_MAX_ATTEMPTS", cfg.MaxAttempts); err != nil {
+		return cfg, err
+	}
+	if cfg.LockoutDuration, err = getDurationOrDefault("LOGIN_LOCKOUT_DURATION", cfg.LockoutDuration); err != nil {
+		return cfg, err
+	}
+	if cfg.MaxAttemptsIP, err = getIntOrDefault("LOGIN_MAX_ATTEMPTS_PER_IP", cfg.MaxAttemptsIP); err != nil {
+		return cfg, err
+	}
+
+	if cfg.MaxAttempts <= cfg.FreeAttempts {
+		return cfg, fmt.Errorf("LOGIN_MAX_ATTEMPTS (%d) must be greater than LOGIN_FREE_ATTEMPTS (%d)", cfg.MaxAttempts, cfg.FreeAttempts)
+	}
+	return cfg, nil
+}
+
+// LoadSessionStore reads SESSION_CACHE_SIZE, SESSION_CACHE_TTL and
+// SESSION_SWEEP_INTERVAL, falling back to DefaultSessionStore for anything
+// unset. A cache size or sweep interval of 0 turns that feature off.
+func LoadSessionStore() (SessionStoreConfig, error) {
+	cfg := DefaultSessionStore()
+
+	var err error
+	if os.Getenv("SESSION_CACHE_SIZE") == "0" {
+		cfg.CacheSize = 0
+	} else if cfg.CacheSize, err = getIntOrDefault("SESSION_CACHE_SIZE", cfg.CacheSize); err != nil {
+		return cfg, err
+	}
+	if cfg.CacheTTL, err = getDurationOrDefault("SESSION_CACHE_TTL", cfg.CacheTTL); err != nil {
+		return cfg, err
+	}
+	if os.Getenv("SESSION_SWEEP_INTERVAL") == "0" {
+		cfg.SweepInterval = 0
+	} else if cfg.SweepInterval, err = getDurationOrDefault("SESSION_SWEEP_INTERVAL", cfg.SweepInterval); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// LoadOIDCProviders reads the identity providers named in OIDC_PROVIDERS.
+// Each name NAME is configured through OIDC_NAME_ISSUER, OIDC_NAME_CLIENT_ID,
+// OIDC_NAME_CLIENT_SECRET, OIDC_NAME_REDIRECT_URL and optionally
+// OIDC_NAME_SCOPES (space-separated).
+func LoadOIDCProviders() ([]OIDCProviderConfig, error) {
+	var providers []OIDCProviderConfig
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("OIDC_PROVIDERS lists %q twice", name)
+		}
+		seen[name] = true
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		for _, required := range []struct{ key, value string }{
+			{"ISSUER", provider.Issuer},
+			{"CLIENT_ID", provider.ClientID},
+			{"REDIRECT_URL", provider.RedirectURL},
+		} {
+			if required.value == "" {
+				return nil, fmt.Errorf("%s%s is required for OIDC provider %q", prefix, required.key, name)
+			}
+		}
+		providers = append(providers, provider)
+	}
+	return providers, nil
+}
+
 func (c *Config) loadCORS() {
 	originsEnv := os.Getenv("CORS_ALLOWED_ORIGINS")
 	if originsEnv != "" {
@@ -183,11 +392,20 @@ func (c *Config) Validate() error {
 	}
 
 	// Validate security configuration
-	if c.Security.JWTSecret == "" {
-		errors = append(errors, "JWT_SECRET is required")
-	}
-	if len(c.Security.JWTSecret) < 16 {
-		errors = append(errors, "JWT_SECRET must be at least 16 characters")
+	switch c.Security.JWTAlgorithm {
+	case "HS256":
+		if c.Security.JWTSecret == "" {
+			errors = append(errors, "JWT_SECRET is required")
+		}
+		if len(c.Security.JWTSecret) < 16 {
+			errors = append(errors, "JWT_SECRET must be at least 16 characters")
+		}
+	case "EdDSA", "RS256":
+		if c.Security.JWTPrivateKeyFile == "" && c.Security.JWTPublicKeyFiles == "" {
+			errors = append(errors, "JWT_PRIVATE_KEY_FILE or JWT_PUBLIC_KEY_FILES is required for "+c.Security.JWTAlgorithm)
+		}
+	default:
+		errors = append(errors, "JWT_ALGORITHM must be one of: HS256, EdDSA, RS256")
 	}
 
 	// Validate server configuration
@@ -232,3 +450,54 @@ func getEnvOrDefault(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getDurationOrDefault parses a Go duration string (e.g. "15m", "720h") from
+// the environment, falling back to a default when unset.
+func getDurationOrDefault(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid %s: must be positive", key)
+	}
+	return d, nil
+}
+
+// getIntOrDefault parses a positive integer from the environment, falling
+// back to a default when unset
+func getIntOrDefault(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("invalid %s: must be positive", key)
+	}
+	return n, nil
+}
+
+// getNonNegativeIntOrDefault is getIntOrDefault for settings where 0 is a
+// meaningful value
+func getNonNegativeIntOrDefault(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("invalid %s: must not be negative", key)
+	}
+	return n, nil
+}
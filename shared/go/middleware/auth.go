@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
 )
 
@@ -11,6 +12,8 @@ type contextKey string
 const (
 	UserIDKey contextKey = "user_id"
 	TokenKey  contextKey = "token"
+	RoleKey   contextKey = "role"
+	ScopesKey contextKey = "scopes"
 )
 
 // AuthMiddleware validates authentication tokens
@@ -36,27 +39,138 @@ func AuthMiddleware(authService AuthService) func(http.Handler) http.Handler {
 				return
 			}
 
-			// Validate token and get user ID
-			userID, err := authService.ValidateToken(r.Context(), token)
+			// Validate token and get user ID, plus the role when the
+			// service can supply one. Personal access tokens also carry
+			// the scopes they were granted.
+			var (
+				userID int64
+				role   string
+				scopes []string
+				err    error
+			)
+			apiTokens, acceptsAPITokens := authService.(APITokenAuthService)
+			roles, hasRoles := authService.(RoleAuthService)
+			switch {
+			case acceptsAPITokens && apiTokens.IsAPIToken(token):
+				userID, role, scopes, err = apiTokens.ValidateAPIToken(r.Context(), token)
+				if scopes == nil {
+					scopes = []string{}
+				}
+			case hasRoles:
+				userID, role, err = roles.ValidateTokenWithRole(r.Context(), token)
+			default:
+				userID, err = authService.ValidateToken(r.Context(), token)
+			}
 			if err != nil {
 				http.Error(w, "Invalid token", http.StatusUnauthorized)
 				return
 			}
 
-			// Add user ID and token to context
+			// Add user ID, token and role to context
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
 			ctx = context.WithValue(ctx, TokenKey, token)
+			ctx = context.WithValue(ctx, RoleKey, role)
+			if scopes != nil {
+				ctx = context.WithValue(ctx, ScopesKey, scopes)
+			}
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-// AuthService interface for token validation
+// AuthService interface for token validation. auth.TokenManager implements it
+// by verifying signed tokens locally; the user service also checks that the
+// session has not been revoked.
 type AuthService interface {
 	ValidateToken(ctx context.Context, token string) (int64, error)
 }
 
+// RoleAuthService is implemented by auth services that also report the
+// caller's role. AuthMiddleware uses it when available; otherwise requests
+// carry no role and fail every RequireRole check.
+type RoleAuthService interface {
+	AuthService
+	ValidateTokenWithRole(ctx context.Context, token string) (int64, string, error)
+}
+
+// RequireRole rejects requests whose role is not one of allowed. It must run
+// after AuthMiddleware.
+func RequireRole(allowed ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := GetRole(r.Context())
+			for _, a := range allowed {
+				if role != "" && role == a {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "Insufficient role", http.StatusForbidden)
+		})
+	}
+}
+
+// APITokenAuthService validates personal access tokens, which are scoped
+// and long-lived. auth.APITokenVerifier implements it.
+type APITokenAuthService interface {
+	IsAPIToken(token string) bool
+	ValidateAPIToken(ctx context.Context, token string) (int64, string, []string, error)
+}
+
+// WithAPITokens combines a session token validator with a personal access
+// token validator so AuthMiddleware accepts both
+func WithAPITokens(sessions AuthService, apiTokens APITokenAuthService) AuthService {
+	return &combinedAuth{AuthService: sessions, APITokenAuthService: apiTokens}
+}
+
+type combinedAuth struct {
+	AuthService
+	APITokenAuthService
+}
+
+// ValidateTokenWithRole delegates to the session validator, reporting no
+// role when it cannot supply one
+func (c *combinedAuth) ValidateTokenWithRole(ctx context.Context, token string) (int64, string, error) {
+	if roles, ok := c.AuthService.(RoleAuthService); ok {
+		return roles.ValidateTokenWithRole(ctx, token)
+	}
+	userID, err := c.AuthService.ValidateToken(ctx, token)
+	return userID, "", err
+}
+
+// RequireScope rejects personal access tokens that were not granted scope.
+// Session tokens are not scoped and always pass. It must run after
+// AuthMiddleware.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if scopes, ok := GetScopes(r.Context()); ok && !slices.Contains(scopes, scope) {
+				http.Error(w, "Token lacks scope "+scope, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireResourceScope is RequireScope with the scope picked by method:
+// "<resource>:read" for safe methods and "<resource>:write" otherwise
+func RequireResourceScope(resource string) func(http.Handler) http.Handler {
+	read, write := RequireScope(resource+":read"), RequireScope(resource+":write")
+	return func(next http.Handler) http.Handler {
+		readNext, writeNext := read(next), write(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				readNext.ServeHTTP(w, r)
+			default:
+				writeNext.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
 // GetUserID extracts user ID from context
 func GetUserID(ctx context.Context) (int64, bool) {
 	userID, ok := ctx.Value(UserIDKey).(int64)
@@ -68,3 +182,16 @@ func GetToken(ctx context.Context) (string, bool) {
 	token, ok := ctx.Value(TokenKey).(string)
 	return token, ok
 }
+
+// GetRole extracts the caller's role from context
+func GetRole(ctx context.Context) (string, bool) {
+	role, ok := ctx.Value(RoleKey).(string)
+	return role, ok
+}
+
+// GetScopes extracts the scopes of a personal access token from context. The
+// second result is false for session tokens, which are not scoped.
+func GetScopes(ctx context.Context) ([]string, bool) {
+	scopes, ok := ctx.Value(ScopesKey).([]string)
+	return scopes, ok
+}
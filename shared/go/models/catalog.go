@@ -26,6 +26,33 @@ type Artist struct {
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// ReleaseFormat is the physical or digital medium of a release
+type ReleaseFormat string
+
+const (
+	ReleaseFormatVinyl    ReleaseFormat = "vinyl"
+	ReleaseFormatCD       ReleaseFormat = "cd"
+	ReleaseFormatCassette ReleaseFormat = "cassette"
+	ReleaseFormatDigital  ReleaseFormat = "digital"
+	ReleaseFormatOther    ReleaseFormat = "other"
+)
+
+// Release is one edition of an album, e.g. an original pressing or a reissue
+type Release struct {
+	ID            int64         `json:"id" db:"id"`
+	AlbumID       int64         `json:"album_id" db:"album_id"`
+	Format        ReleaseFormat `json:"format" db:"format"`
+	Label         string        `json:"label,omitempty" db:"label"`
+	CatalogNumber string        `json:"catalog_number,omitempty" db:"catalog_number"`
+	Country       string        `json:"country,omitempty" db:"country"`
+	PressingYear  int           `json:"pressing_year,omitempty" db:"pressing_year"`
+	Barcode       string        `json:"barcode,omitempty" db:"barcode"`
+	ColorVariant  string        `json:"color_variant,omitempty" db:"color_variant"`
+	Description   string        `json:"description,omitempty" db:"description"`
+	CreatedAt     time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time     `json:"updated_at" db:"updated_at"`
+}
+
 // Song represents a song
 type Song struct {
 	ID        int64     `json:"id" db:"id"`
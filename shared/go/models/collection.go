@@ -27,6 +27,7 @@ type AlbumCollection struct {
 	ID             int64           `json:"id"`
 	UserID         int64           `json:"user_id"`
 	AlbumID        int64           `json:"album_id"`
+	ReleaseID      *int64          `json:"release_id,omitempty"` // Specific pressing; nil for the album in general
 	CollectionType CollectionType  `json:"collection_type"`
 	Notes          string          `json:"notes,omitempty"`
 	DateAdded      time.Time       `json:"date_added"`
@@ -48,19 +49,16 @@ type AlbumCollectionWithDetails struct {
 	AlbumReleaseYear int   `json:"album_release_year"`
 	AlbumGenre      string `json:"album_genre"`
 	AlbumCoverURL   string `json:"album_cover_url"`
+	Release         *Release `json:"release,omitempty"`
 }
 
-// CollectionFilter for searching collections
+// CollectionFilter for searching collections by substring. Exact matches
+// and ranges, such as the collection type or release year, go in the query
+// spec of the list.
 type CollectionFilter struct {
-	CollectionType *CollectionType
-	Artist         string
-	Genre          string
-	YearFrom       *int
-	YearTo         *int
-	Condition      *AlbumCondition
-	SearchTerm     string // Search in album title, artist, notes
-	Limit          int
-	Offset         int
+	Artist     string
+	Genre      string
+	SearchTerm string // Search in album title, artist, notes
 }
 
 // CollectionStats provides statistics about a user's collection
@@ -70,4 +68,5 @@ type CollectionStats struct {
 	TotalValue    float64 `json:"total_value"` // Sum of purchase prices
 	ByGenre       map[string]int `json:"by_genre,omitempty"`
 	ByCondition   map[string]int `json:"by_condition,omitempty"`
+	ByFormat      map[string]int `json:"by_format,omitempty"` // Owned items with a release, by release format
 }
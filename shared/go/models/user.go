@@ -4,10 +4,41 @@ import (
 	"time"
 )
 
+// Role controls what a user may change in the shared catalog
+type Role string
+
+const (
+	// RoleUser manages their own collections, ratings and playlists
+	RoleUser Role = "user"
+	// RoleCurator may also create, edit and import catalog entries
+	RoleCurator Role = "curator"
+	// RoleAdmin may also delete catalog entries and assign roles
+	RoleAdmin Role = "admin"
+)
+
+// roleRank orders roles so that each one includes the permissions of those below it
+var roleRank = map[Role]int{
+	RoleUser:    1,
+	RoleCurator: 2,
+	RoleAdmin:   3,
+}
+
+// Valid reports whether r is a known role
+func (r Role) Valid() bool {
+	_, ok := roleRank[r]
+	return ok
+}
+
+// Allows reports whether r grants at least the permissions of required
+func (r Role) Allows(required Role) bool {
+	return r.Valid() && roleRank[r] >= roleRank[required]
+}
+
 // User represents a user in the system
 type User struct {
 	ID        int64     `json:"id" db:"id"`
 	Username  string    `json:"username" db:"username"`
+	Role      Role      `json:"role" db:"role"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
 }